@@ -0,0 +1,773 @@
+// Package cli 是不创建图形界面的命令行模式，适合在服务器上通过 SSH 运行。
+// 与图形界面共用同一份配置和 engine、watcher、gitsync：
+//
+//	syncsafe backup --config /srv/syncsafe/config.json --profile work
+//	syncsafe watch --profile work
+//	syncsafe profiles
+//	syncsafe receive --dir /srv/backups
+//	syncsafe verify --profile work
+//	syncsafe scrub --profile work
+//	syncsafe check --profile work
+//
+// 退出码：0 成功，1 部分成功（快照已写入但有文件失败，或校验、巡检发现问题），
+// 2 失败，3 参数或配置错误。加上 --json 时在标准输出写入一个 JSON 对象描述运行结果，
+// 日志改为写入标准错误
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/logging"
+	"syncsafe/notify"
+	"syncsafe/peer"
+	"syncsafe/watcher"
+)
+
+// 命令行输出，带时间前缀
+var logger = log.New(os.Stdout, "", log.LstdFlags)
+
+// 逐行列出的结果（损坏的文件、任务列表等），--json 时改为写入标准错误
+var textOut io.Writer = os.Stdout
+
+// 进程退出码，脚本和 CI 可以据此判断结果
+const (
+	ExitSuccess = 0 // 成功
+	ExitPartial = 1 // 部分成功：快照已写入但有文件复制失败，或校验、巡检发现问题
+	ExitFailure = 2 // 失败或被取消
+	ExitConfig  = 3 // 参数或配置错误
+)
+
+// --json 输出的运行结果
+type result struct {
+	Command  string               `json:"command"`
+	Profile  string               `json:"profile,omitempty"`
+	Status   string               `json:"status"` // success、partial、failure、config-error
+	ExitCode int                  `json:"exitCode"`
+	Error    string               `json:"error,omitempty"`
+	Backup   *history.Record      `json:"backup,omitempty"`
+	Verify   *engine.VerifyResult `json:"verify,omitempty"`
+	Scrub    *engine.ScrubResult  `json:"scrub,omitempty"`
+	Profiles []profileInfo        `json:"profiles,omitempty"`
+	Version  *engine.Diagnostics  `json:"version,omitempty"`
+	Problems []problemInfo        `json:"problems,omitempty"`
+}
+
+// check 命令发现的配置问题
+type problemInfo struct {
+	Setting  engine.Setting `json:"setting"`
+	Message  string         `json:"message"`
+	Blocking bool           `json:"blocking"` // 是否阻止备份
+}
+
+// profiles 命令列出的任务
+type profileInfo struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Current     bool   `json:"current"`
+	Archived    bool   `json:"archived,omitempty"`
+}
+
+// 命令行命令，run 把结果写入 out
+type command struct {
+	usage string
+	run   func(opts options, out *result) error
+}
+
+var commands = map[string]command{
+	"backup":   {"执行一次备份（包括 Git 提交和推送）", runBackup},
+	"watch":    {"监控源文件夹，变化平静后自动备份，直到按 Ctrl+C", runWatch},
+	"profiles": {"列出所有备份任务，* 为当前任务，- 为已归档的任务", runProfiles},
+	"receive":  {"作为局域网接收端，接收其他设备推送的快照，直到按 Ctrl+C", runReceive},
+	"verify":   {"校验最近一个快照能否完整读出，加密的快照完整解密一遍", runVerify},
+	"scrub":    {"数据巡检：读出目标文件夹中的所有快照，发现并尽量修复损坏的文件", runScrub},
+	"version":  {"显示版本、构建信息和配置文件位置，用于问题反馈", runVersion},
+	"check":    {"检查配置：路径、排除规则、计划表达式和凭据，一次列出所有问题", runCheck},
+}
+
+// 命令行参数
+type options struct {
+	config  string
+	profile string
+	dir     string
+	json    bool
+}
+
+var (
+	errUsage  = i18n.Error("参数不正确")
+	errConfig = i18n.Error("配置错误")
+)
+
+// 部分成功：操作完成但有文件出了问题
+type partialError struct {
+	error
+}
+
+func (e partialError) Unwrap() error {
+	return e.error
+}
+
+// 错误对应的退出码
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitSuccess
+	case errors.Is(err, errUsage), errors.Is(err, errConfig), errors.As(err, new(*engine.ProblemsError)):
+		return ExitConfig
+	case errors.As(err, &partialError{}):
+		return ExitPartial
+	default:
+		return ExitFailure
+	}
+}
+
+// 退出码在 JSON 中的名称
+var exitStatus = map[int]string{
+	ExitSuccess: "success",
+	ExitPartial: "partial",
+	ExitFailure: "failure",
+	ExitConfig:  "config-error",
+}
+
+// 是否为命令行命令，否则启动图形界面
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok || name == "help" || name == "-h" || name == "--help"
+}
+
+// 执行命令，args 以命令名开头，返回进程退出码
+func Run(args []string) int {
+	// 用法和参数说明也使用程序设置中的语言
+	applyPreferences()
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return ExitConfig
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(os.Stdout)
+		return 0
+	}
+
+	var opts options
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.StringVar(&opts.config, "config", "", i18n.Sprintf("配置文件 config.json 或其所在目录，默认为 %s", engine.DataDir))
+	flags.StringVar(&opts.profile, "profile", "", i18n.T("备份任务名称，默认为界面中当前选择的任务"))
+	flags.StringVar(&opts.dir, "dir", "", i18n.T("receive 命令保存快照的目录，默认使用界面中设置的目录"))
+	flags.BoolVar(&opts.json, "json", false, i18n.T("在标准输出写入 JSON 格式的运行结果，日志写入标准错误"))
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitSuccess
+		}
+		return ExitConfig
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, i18n.Sprintf("多余的参数: %v", flags.Args()))
+		return ExitConfig
+	}
+	if opts.json {
+		logger.SetOutput(os.Stderr)
+		textOut = os.Stderr
+	}
+	setupLogging(opts)
+
+	out := &result{Command: args[0], Profile: opts.profile}
+	err := cmd.run(opts, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("错误:"), err)
+	}
+	code := exitCode(err)
+	if opts.json {
+		out.ExitCode = code
+		out.Status = exitStatus[code]
+		if err != nil {
+			out.Error = err.Error()
+		}
+		data, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(data))
+	}
+	return code
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, i18n.T("用法: syncsafe <命令> [--config 配置文件] [--profile 任务名称] [--json]"))
+	fmt.Fprintln(w, i18n.T("不带命令时启动图形界面。命令:"))
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", name, i18n.T(commands[name].usage))
+	}
+	fmt.Fprintln(w, i18n.T("退出码: 0 成功，1 部分成功，2 失败，3 参数或配置错误"))
+}
+
+// 按参数设置配置目录
+func setConfigDir(opts options) error {
+	if opts.config == "" {
+		return nil
+	}
+	dir := opts.config
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		if filepath.Base(dir) != "config.json" {
+			return i18n.Errorf("%w: 配置文件必须名为 config.json: %s", errUsage, dir)
+		}
+		dir = filepath.Dir(dir)
+	} else if err != nil {
+		return i18n.Errorf("%w: 配置文件不存在或无法访问: %v", errConfig, err)
+	}
+	engine.DataDir = dir
+	return nil
+}
+
+// 应用程序设置中的界面语言和日志记录级别，与图形界面一致
+func applyPreferences() {
+	preferences, err := engine.LoadPreferences()
+	if err != nil {
+		logger.Print(i18n.Sprintf("读取程序设置失败: %v", err))
+	}
+	i18n.SetLanguage(preferences.Language)
+	logging.SetLevel(preferences.LogLevel)
+}
+
+// 与图形界面写入同一个日志目录，计划任务和通过 SSH 运行的命令也能在日志页中查看。
+// 指定了配置目录时改用其中的程序设置，配置目录无效时由命令本身报告错误
+func setupLogging(opts options) {
+	if setConfigDir(opts) != nil {
+		return
+	}
+	if opts.config != "" {
+		applyPreferences()
+	}
+	if err := logging.Setup(engine.LogDir()); err != nil {
+		logger.Print(i18n.Sprintf("无法写入日志文件: %v", err))
+	}
+}
+
+// 按参数设置配置目录并加载所有任务
+func loadProfiles(opts options) (*engine.Profiles, error) {
+	if err := setConfigDir(opts); err != nil {
+		return nil, err
+	}
+
+	profiles, err := engine.LoadProfiles()
+	if err != nil {
+		// 图形界面会提示从备份恢复，命令行模式不覆盖损坏的配置
+		return nil, i18n.Errorf("%w: 加载配置失败: %v", errConfig, err)
+	}
+	return profiles, nil
+}
+
+// 按名称选择任务
+func loadProfile(opts options) (*engine.Config, error) {
+	profiles, err := loadProfiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	return selectProfile(profiles, opts)
+}
+
+// 从已加载的任务中按名称选择
+func selectProfile(profiles *engine.Profiles, opts options) (*engine.Config, error) {
+	if opts.profile == "" {
+		return profiles.Current(), nil
+	}
+	config := profiles.Get(opts.profile)
+	if config == nil {
+		return nil, i18n.Errorf("%w: 没有名为 %q 的备份任务，可选: %v", errUsage, opts.profile, profiles.Names())
+	}
+	return config, nil
+}
+
+// 创建输出到终端的备份引擎
+func newEngine(config *engine.Config) *engine.Engine {
+	prefix := "[" + config.ProfileName() + "] "
+	return engine.New(config, engine.Hooks{
+		Status: func(message string) { logger.Print(prefix + message) },
+		Output: func(line string) { logger.Print(prefix + line) },
+	})
+}
+
+// 已归档的任务不再备份，计划任务仍然调用时直接返回错误，历史记录和快照可以照常还原和校验
+func checkArchived(config *engine.Config) error {
+	if config.Archived {
+		return i18n.Errorf("%w: 任务 %q 已归档，恢复后才能备份", errUsage, config.ProfileName())
+	}
+	return nil
+}
+
+// 发送推送通知并等待完成，命令行进程可能随后立即退出
+func sendNotify(config *engine.Config, level notify.Level, title, message string) {
+	if !config.Notify.Accepts(level) {
+		return
+	}
+	if err := notify.Send(config.Notify, level, title, message); err != nil {
+		logger.Print(i18n.Sprintf("推送通知失败: %v", err))
+		slog.Warn("推送通知失败", "err", err)
+	}
+}
+
+// 命令行输出复制进度的间隔
+const progressInterval = 5 * time.Second
+
+// 订阅进度事件，定期输出百分比、速度和剩余时间，返回停止输出的函数
+func printProgress(e *engine.Engine) func() {
+	events, unsubscribe := e.Subscribe(256)
+	prefix := "[" + e.Config.ProfileName() + "] "
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var progress engine.Progress
+		var printed time.Time
+		for ev := range events {
+			progress.Update(ev)
+			if ev.Kind == engine.EventScanned {
+				logger.Print(prefix + ev.String())
+			}
+			if (ev.Kind == engine.EventFileCopied || ev.Kind == engine.EventFileSkipped) && time.Since(printed) >= progressInterval {
+				printed = time.Now()
+				logger.Print(prefix + progress.String())
+			}
+		}
+	}()
+	return func() {
+		unsubscribe()
+		<-done
+	}
+}
+
+// 执行一次备份，记录历史并保存配置，返回备份记录。
+// 只有部分文件复制失败或备份后校验不一致时返回 partialError
+func backup(ctx context.Context, e *engine.Engine, attempt int) (*history.Record, error) {
+	config := e.Config
+	stopProgress := printProgress(e)
+	record, err := e.Backup(ctx, attempt)
+	stopProgress()
+	if record != nil {
+		config.History = append(config.History, *record)
+		if record.SizeAnomaly != "" {
+			sendNotify(config, notify.LevelWarning, i18n.T("快照大小异常"), fmt.Sprintf("%s\n%s", config.SourcePath, record.SizeAnomaly))
+		}
+		if err == nil && !record.DryRun && config.Retention.Enabled() {
+			if count, pruneErr := e.ApplyRetention(); errors.Is(pruneErr, engine.ErrRetentionHeld) {
+				logger.Print(pruneErr)
+			} else if errors.Is(pruneErr, engine.ErrRetentionUnverified) {
+				logger.Print(pruneErr)
+				if count > 0 {
+					logger.Print(i18n.Sprintf("已按保留策略清理 %d 个旧快照", count))
+				}
+				sendNotify(config, notify.LevelWarning, i18n.T("推迟清理旧快照"), fmt.Sprintf("%s\n%v", config.SourcePath, pruneErr))
+			} else if pruneErr != nil {
+				logger.Print(i18n.Sprintf("按保留策略清理快照失败: %v", pruneErr))
+				slog.Warn("按保留策略清理快照失败", "err", pruneErr)
+			} else if count > 0 {
+				logger.Print(i18n.Sprintf("已按保留策略清理 %d 个旧快照", count))
+			}
+		}
+		if saveErr := config.Save(); saveErr != nil {
+			logger.Print(i18n.Sprintf("保存历史记录失败: %v", saveErr))
+			slog.Warn("保存历史记录失败", "err", saveErr)
+		}
+		if hookErr := engine.SendWebhooks(config, *record); hookErr != nil {
+			logger.Print(i18n.Sprintf("发送 Webhook 失败: %v", hookErr))
+			slog.Warn("发送 Webhook 失败", "err", hookErr)
+		}
+	}
+	if errors.Is(err, engine.ErrCancelled) {
+		return record, err
+	}
+	if err != nil {
+		slog.Error("备份失败", "profile", config.ProfileName(), "source", config.SourcePath, "err", err)
+		sendNotify(config, notify.LevelError, i18n.T("备份失败"), fmt.Sprintf("%s\n%v", config.SourcePath, err))
+		failed := history.Record{Timestamp: time.Now(), SourcePath: config.SourcePath, ErrorMessage: err.Error(), Attempt: attempt}
+		if record != nil {
+			failed = *record
+		}
+		if mailErr := engine.SendFailureEmail(config, failed); mailErr != nil {
+			logger.Print(i18n.Sprintf("发送失败通知邮件失败: %v", mailErr))
+			slog.Warn("发送失败通知邮件失败", "err", mailErr)
+		}
+		if record != nil && record.FailedFiles > 0 && record.FileCount > 0 {
+			return record, partialError{err}
+		}
+		return record, err
+	}
+	if record != nil {
+		slog.Info("备份完成", "profile", config.ProfileName(), "source", record.SourcePath, "dest", record.DestPath, "files", record.FileCount,
+			"new", record.NewFiles, "modified", record.ModifiedFiles, "deleted", record.DeletedFiles, "dryRun", record.DryRun)
+	}
+	if record != nil && !record.DryRun {
+		summary := i18n.Sprintf("共 %d 个文件，新增 %d、修改 %d、删除 %d",
+			record.FileCount, record.NewFiles, record.ModifiedFiles, record.DeletedFiles)
+		logger.Print("[" + config.ProfileName() + "] " + summary)
+		sendNotify(config, notify.LevelInfo, i18n.T("备份完成"), config.SourcePath+"\n"+summary)
+		if len(record.Mismatches) > 0 {
+			return record, partialError{i18n.Errorf("校验发现 %d 个文件与源文件不一致", len(record.Mismatches))}
+		}
+	}
+	return record, nil
+}
+
+func runBackup(opts options, out *result) error {
+	config, err := loadProfile(opts)
+	if err != nil {
+		return err
+	}
+	out.Profile = config.ProfileName()
+	if err := checkArchived(config); err != nil {
+		return err
+	}
+	// 上次发送失败的 Webhook 先重试一次，没有常驻进程时也不会丢失
+	engine.RetryWebhooks()
+	// Ctrl+C 取消备份，已复制的部分被删除，历史中记录为已取消
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	out.Backup, err = backup(ctx, newEngine(config), 0)
+	return err
+}
+
+func runWatch(opts options, out *result) error {
+	config, err := loadProfile(opts)
+	if err != nil {
+		return err
+	}
+	out.Profile = config.ProfileName()
+	if err := checkArchived(config); err != nil {
+		return err
+	}
+	// 配置有阻止备份的问题时不开始监控
+	if err := config.ValidateForBackup(); err != nil {
+		return err
+	}
+	e := newEngine(config)
+	engine.StartWebhookRetries()
+
+	// 监控事件实时更新索引
+	idx, idxErr := e.SourceIndex()
+	if idxErr != nil {
+		idx = nil
+	}
+
+	var backupMutex sync.Mutex
+	var w *watcher.Watcher
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lost := make(chan struct{})
+	root := filepath.Clean(e.SourcePath())
+	// 记录变化的路径，之后的备份只处理变化的文件
+	e.TrackChanges(root)
+	w, err = watcher.New(root, watcher.Options{
+		Ignore: config.WatchIgnore(root),
+		OnChange: func(path string) {
+			if idx != nil {
+				idx.Update(path)
+			}
+			e.ChangeObserved(path)
+		},
+		OnSettled: func() {
+			// 暂停时段内推迟到时段结束
+			if period, ok := config.ActiveBlackout(time.Now()); ok {
+				end := period.EndAfter(time.Now().In(config.Location()))
+				logger.Print(i18n.Sprintf("处于暂停时段 %s，自动备份推迟到 %s", period.Name, end.Format("2006-01-02 15:04")))
+				slog.Info("处于暂停时段，推迟自动备份", "period", period.Name, "until", end.Format("2006-01-02 15:04"))
+				w.Schedule(time.Until(end))
+				return
+			}
+			backupMutex.Lock()
+			defer backupMutex.Unlock()
+			record, err := backup(ctx, e, 1)
+			if err != nil {
+				logger.Print(i18n.Sprintf("自动备份失败: %v", err))
+			}
+			if record != nil {
+				out.Backup = record
+			}
+		},
+		OnStorm: func() {
+			if idx != nil {
+				idx.SetLive(false)
+			}
+			e.ChangesOverflowed()
+			logger.Print(i18n.T("检测到大量文件变化，暂停逐个处理，变化平静后重新扫描"))
+		},
+		OnRescan: func() {
+			if idx != nil {
+				if err := idx.Rebuild(); err != nil {
+					logger.Print(i18n.Sprintf("重建索引失败: %v", err))
+					slog.Warn("重建索引失败", "err", err)
+					return
+				}
+				idx.SetLive(true)
+			}
+		},
+		OnLost: func() {
+			close(lost)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// 监控开始时重建索引，之后由监控事件实时维护
+	if idx != nil {
+		if err := idx.Rebuild(); err != nil {
+			logger.Print(i18n.Sprintf("重建索引失败: %v", err))
+			slog.Warn("重建索引失败", "err", err)
+		} else {
+			idx.SetLive(true)
+		}
+	}
+	logger.Print(i18n.Sprintf("开始监控 %s，按 Ctrl+C 停止", root))
+
+	// 设置了 Git 提交计划时按计划批量提交，与自动备份互斥
+	stopGit := make(chan struct{})
+	defer close(stopGit)
+	go func() {
+		after := time.Now()
+		for {
+			next, err := config.NextGitCommit(after)
+			if err != nil || next.IsZero() {
+				return
+			}
+			select {
+			case <-time.After(time.Until(next)):
+			case <-stopGit:
+				return
+			}
+			backupMutex.Lock()
+			if err := e.CommitGit(); err != nil {
+				logger.Print(i18n.Sprintf("定时提交 Git 失败: %v", err))
+				slog.Warn("定时提交 Git 失败", "err", err)
+			} else if err := config.Save(); err != nil {
+				logger.Print(i18n.Sprintf("保存配置失败: %v", err))
+				slog.Warn("保存配置失败", "err", err)
+			}
+			backupMutex.Unlock()
+			// 计时器可能略早触发，从计划时间之后算起，避免同一时间提交两次
+			if after = time.Now(); after.Before(next) {
+				after = next
+			}
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case <-signals:
+		w.Close()
+		// 取消进行中的备份并等待其结束
+		cancel()
+		backupMutex.Lock()
+		backupMutex.Unlock()
+		logger.Print(i18n.T("已停止监控"))
+		return nil
+	case <-lost:
+		sendNotify(config, notify.LevelError, i18n.T("源文件夹不可用"), root)
+		return i18n.Errorf("源文件夹已被删除、重命名或卸载: %s", root)
+	}
+}
+
+func runReceive(opts options, out *result) error {
+	if err := setConfigDir(opts); err != nil {
+		return err
+	}
+	settings, err := engine.LoadReceiverSettings()
+	if err != nil {
+		return err
+	}
+	if opts.dir != "" {
+		settings.Dir = opts.dir
+	}
+	if settings.Dir == "" {
+		return i18n.Errorf("%w: 请用 --dir 指定保存快照的目录", errUsage)
+	}
+	// 配对码保存下来，重新启动后发送端不需要重新配对
+	if settings.Code == "" {
+		settings.Code = peer.NewPairingCode()
+	}
+	settings.Enabled = true
+	if err := settings.Save(engine.PeerDir()); err != nil {
+		return err
+	}
+
+	receiver, err := engine.StartReceiver(settings, func(received peer.Received) {
+		logger.Print(i18n.Sprintf("已收到 %s 的快照 %s，共 %d 个文件，保存在 %s", received.Machine, received.Snapshot, received.Files, received.Path))
+		slog.Info("已收到快照", "machine", received.Machine, "snapshot", received.Snapshot, "files", received.Files, "path", received.Path)
+	}, func(remote string, err error) {
+		logger.Print(i18n.Sprintf("接收 %s 的快照失败: %v", remote, err))
+		slog.Warn("接收快照失败", "remote", remote, "err", err)
+	})
+	if err != nil {
+		return err
+	}
+	defer receiver.Close()
+
+	logger.Print(i18n.Sprintf("正在接收快照，保存到 %s，按 Ctrl+C 停止", settings.Dir))
+	for _, addr := range peer.LocalAddresses(settings.Port) {
+		logger.Print(i18n.Sprintf("本机地址: %s", addr))
+	}
+	logger.Print(i18n.Sprintf("配对码: %s", peer.PairingCode(settings.Code, receiver.Fingerprint())))
+	logger.Print(i18n.Sprintf("证书指纹: %s", receiver.Fingerprint()))
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	<-signals
+	logger.Print(i18n.T("已停止接收"))
+	return nil
+}
+
+func runVerify(opts options, out *result) error {
+	config, err := loadProfile(opts)
+	if err != nil {
+		return err
+	}
+	out.Profile = config.ProfileName()
+	record, ok := history.LastSnapshot(config.History)
+	if !ok || !record.HasSnapshot() {
+		return i18n.Errorf("任务 %s 还没有可以校验的快照", config.ProfileName())
+	}
+	logger.Print(i18n.Sprintf("[%s] 校验快照 %s", config.ProfileName(), record.DestPath))
+	result, err := newEngine(config).VerifySnapshot(record)
+	if err != nil {
+		return err
+	}
+	out.Verify = &result
+	for _, line := range result.Damaged {
+		fmt.Fprintln(textOut, i18n.T("损坏:"), line)
+	}
+	for _, relPath := range result.Missing {
+		fmt.Fprintln(textOut, i18n.T("缺失:"), relPath)
+	}
+	logger.Printf("[%s] %s", config.ProfileName(), result)
+	if !result.OK() {
+		return partialError{i18n.Errorf("快照校验未通过")}
+	}
+	return nil
+}
+
+func runScrub(opts options, out *result) error {
+	profiles, err := loadProfiles(opts)
+	if err != nil {
+		return err
+	}
+	config, err := selectProfile(profiles, opts)
+	if err != nil {
+		return err
+	}
+	out.Profile = config.ProfileName()
+	// 同一源文件夹的其他任务的快照也用作修复来源
+	var copies []history.Record
+	for _, other := range profiles.List {
+		if other != config && other.SourcePath == config.SourcePath {
+			copies = append(copies, other.History...)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger.Print(i18n.Sprintf("[%s] 数据巡检 %s", config.ProfileName(), config.DestinationPath))
+	result, err := newEngine(config).Scrub(ctx, config.History, copies)
+	out.Scrub = &result
+	for _, path := range result.Repaired {
+		fmt.Fprintln(textOut, i18n.T("已修复:"), path)
+	}
+	for _, line := range result.Unrepairable {
+		fmt.Fprintln(textOut, i18n.T("无法修复:"), line)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Printf("[%s] %s", config.ProfileName(), result)
+	if !result.OK() {
+		sendNotify(config, notify.LevelError, i18n.T("数据巡检"), config.DestinationPath+"\n"+result.String())
+		return partialError{i18n.Errorf("有无法修复的文件")}
+	}
+	return nil
+}
+
+func runVersion(opts options, out *result) error {
+	// 配置损坏时仍然输出版本和默认任务的路径，这时最需要诊断信息
+	config, err := loadProfile(opts)
+	if err != nil {
+		logger.Printf("%v", err)
+		config = engine.NewConfig()
+	} else {
+		out.Profile = config.ProfileName()
+	}
+	diagnostics := config.Diagnostics()
+	out.Version = &diagnostics
+	fmt.Fprintln(textOut, diagnostics)
+	return nil
+}
+
+func runProfiles(opts options, out *result) error {
+	profiles, err := loadProfiles(opts)
+	if err != nil {
+		return err
+	}
+	for _, config := range profiles.List {
+		current := config == profiles.Current()
+		out.Profiles = append(out.Profiles, profileInfo{
+			Name:        config.ProfileName(),
+			Source:      config.SourcePath,
+			Destination: config.DestinationPath,
+			Current:     current,
+			Archived:    config.Archived,
+		})
+		marker := " "
+		switch {
+		case current:
+			marker = "*"
+		case config.Archived:
+			marker = "-"
+		}
+		fmt.Fprintf(textOut, "%s %s\t%s -> %s\n", marker, config.ProfileName(), config.SourcePath, config.DestinationPath)
+	}
+	return nil
+}
+
+func runCheck(opts options, out *result) error {
+	config, err := loadProfile(opts)
+	if err != nil {
+		return err
+	}
+	out.Profile = config.ProfileName()
+	problems := config.Validate()
+	blocking := 0
+	for _, problem := range problems {
+		out.Problems = append(out.Problems, problemInfo{Setting: problem.Setting, Message: problem.Message, Blocking: problem.Blocking})
+		level := i18n.T("警告")
+		if problem.Blocking {
+			level = i18n.T("错误")
+			blocking++
+		}
+		fmt.Fprintf(textOut, "%s: %s\n", level, problem)
+	}
+	switch {
+	case blocking > 0:
+		return i18n.Errorf("%w: 有 %d 个问题会阻止备份", errConfig, blocking)
+	case len(problems) > 0:
+		return partialError{i18n.Errorf("有 %d 个问题会使部分功能不生效", len(problems))}
+	}
+	logger.Print(i18n.Sprintf("[%s] 配置没有发现问题", config.ProfileName()))
+	return nil
+}
@@ -0,0 +1,44 @@
+package crypt
+
+import (
+	"errors"
+	"io"
+
+	"filippo.io/age"
+
+	"syncsafe/i18n"
+)
+
+// age 格式（age-encryption.org/v1）的密码短语加密，与 age -p 兼容：
+// 没有 SyncSafe 时可以用 age -d 或 rage -d 解密。加密和解密都由 filippo.io/age 完成
+const ageWorkFactor = 15 // scrypt 的 log2(N)，约 32 MB 内存
+
+// 创建 age 格式的加密流，写入的内容加密后写入 dst，Close 写入最后一块但不关闭 dst
+func NewAgeWriter(dst io.Writer, passphrase string) (io.WriteCloser, error) {
+	if passphrase == "" {
+		return nil, i18n.Errorf("密码短语不能为空")
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	recipient.SetWorkFactor(ageWorkFactor)
+	return age.Encrypt(dst, recipient)
+}
+
+// 读取 age -p 加密的内容。密码短语错误时返回 ErrAuth，内容被截断或篡改时在读取中返回错误
+func NewAgeReader(src io.Reader, passphrase string) (io.Reader, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(src, identity)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, ErrAuth
+	}
+	if err != nil {
+		return nil, i18n.Errorf("不是有效的 age 加密文件: %v", err)
+	}
+	return r, nil
+}
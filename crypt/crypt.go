@@ -0,0 +1,258 @@
+// Package crypt 实现备份内容的客户端加密：文件内容在写入目标之前用 AES-256-GCM 分块加密，
+// 文件名可以选择确定性加密。密钥由密码短语通过 Argon2id 派生，盐和参数保存在 Params 中，
+// 与快照一起存放在目标上，只有密码短语不离开本机。
+//
+// 加密文件的格式：6 字节标识、16 字节随机盐，之后是若干个加密块。每个块最多 64 KB 明文，
+// 密钥由主密钥和文件的盐派生，nonce 为块序号，最后一块带有结束标记，截断和重排都会被发现。
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+
+	"syncsafe/i18n"
+)
+
+const (
+	chunkSize  = 64 * 1024
+	tagSize    = 16
+	saltSize   = 16
+	headerSize = len(magic) + saltSize
+	maxNameLen = 255 // 常见文件系统的文件名长度上限
+)
+
+// 加密文件的标识
+const magic = "SSENC\x01"
+
+// 密码短语错误或数据被篡改
+var ErrAuth = i18n.Error("解密失败：密码短语错误或文件已损坏")
+
+// 密钥派生参数，与加密的快照一起保存
+type Params struct {
+	Version int
+	KDF     string // 目前只有 argon2id
+	Time    uint32 // 迭代次数
+	Memory  uint32 // 内存用量（KB）
+	Threads uint8
+	Salt    []byte
+	Check   []byte // 由密钥计算的校验值，用于在加密前发现密码短语错误
+}
+
+// 生成新的随机盐和默认参数（RFC 9106 推荐的第二组参数，64 MB 内存）
+func NewParams() Params {
+	return Params{Version: 1, KDF: "argon2id", Time: 3, Memory: 64 * 1024, Threads: 4, Salt: random(32)}
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// 由密码短语派生的密钥
+type Key struct {
+	content []byte // 派生每个文件的内容密钥
+	nameEnc cipher.AEAD
+	nameMac []byte
+}
+
+// 派生密钥。params.Check 为空时填入校验值，否则校验密码短语是否正确
+func DeriveKey(passphrase string, params *Params) (*Key, error) {
+	if passphrase == "" {
+		return nil, i18n.Errorf("请先设置加密密码短语")
+	}
+	if params.KDF != "argon2id" {
+		return nil, i18n.Errorf("不支持的密钥派生算法: %s", params.KDF)
+	}
+	if params.Time == 0 || params.Threads == 0 || params.Memory < 8*uint32(params.Threads) {
+		return nil, i18n.Errorf("密钥派生参数无效")
+	}
+	master := argon2.IDKey([]byte(passphrase), params.Salt, params.Time, params.Memory, params.Threads, 96)
+	check := mac(master, []byte("syncsafe-check"))
+	if params.Check == nil {
+		params.Check = check
+	} else if !hmac.Equal(params.Check, check) {
+		return nil, i18n.Errorf("密码短语与目标中已有的加密设置不一致")
+	}
+	block, err := aes.NewCipher(master[32:64])
+	if err != nil {
+		return nil, err
+	}
+	nameEnc, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{content: master[:32], nameEnc: nameEnc, nameMac: master[64:]}, nil
+}
+
+func mac(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// 文件盐对应的内容加密器
+func (k *Key) fileAEAD(salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(mac(k.content, salt))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// 第 n 块的 nonce，最后一块的末字节为 1
+func chunkNonce(n uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// 明文大小对应的加密文件大小
+func EncryptedSize(size int64) int64 {
+	chunks := (size + chunkSize - 1) / chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return int64(headerSize) + size + chunks*tagSize
+}
+
+// 加密文件大小对应的明文大小，不是有效的加密文件大小时返回 false
+func PlainSize(size int64) (int64, bool) {
+	body := size - int64(headerSize)
+	if body < tagSize {
+		return 0, false
+	}
+	chunks := (body + chunkSize + tagSize - 1) / (chunkSize + tagSize)
+	plain := body - chunks*tagSize
+	return plain, plain >= 0 && EncryptedSize(plain) == size
+}
+
+// 加密 src 写入 dst
+func (k *Key) Encrypt(dst io.Writer, src io.Reader) error {
+	salt := random(saltSize)
+	aead, err := k.fileAEAD(salt)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(dst, magic); err != nil {
+		return err
+	}
+	if _, err := dst.Write(salt); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize)
+	out := make([]byte, 0, chunkSize+tagSize)
+	for n := uint64(0); ; n++ {
+		read, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		// 读满一块时再看一个字节，判断是否还有下一块
+		last := err != nil
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return peekErr
+			}
+		}
+		out = aead.Seal(out[:0], chunkNonce(n, last), buf[:read], nil)
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// 解密 src 写入 dst，密钥错误、文件被截断或篡改时返回 ErrAuth
+func (k *Key) Decrypt(dst io.Writer, src io.Reader) error {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil || string(header[:len(magic)]) != magic {
+		return i18n.Errorf("不是 SyncSafe 加密的文件")
+	}
+	aead, err := k.fileAEAD(header[len(magic):])
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(src, chunkSize+tagSize)
+	buf := make([]byte, chunkSize+tagSize)
+	out := make([]byte, 0, chunkSize)
+	for n := uint64(0); ; n++ {
+		read, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := err != nil
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return peekErr
+			}
+		}
+		out, err = aead.Open(out[:0], chunkNonce(n, last), buf[:read], nil)
+		if err != nil {
+			return ErrAuth
+		}
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// 文件名编码：小写 base32，在不区分大小写的文件系统上也不会冲突
+var nameEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// 确定性地加密文件名：相同的文件名总是得到相同的结果，快速同步和增量快照可以按名称比较。
+// nonce 由文件名的 HMAC 得到，只会暴露两个文件名是否相同
+func (k *Key) EncryptName(name string) (string, error) {
+	nonce := mac(k.nameMac, []byte(name))[:k.nameEnc.NonceSize()]
+	sealed := k.nameEnc.Seal(nonce, nonce, []byte(name), nil)
+	encoded := nameEncoding.EncodeToString(sealed)
+	if len(encoded) > maxNameLen {
+		return "", i18n.Errorf("文件名过长，加密后超过 %d 个字符: %s", maxNameLen, name)
+	}
+	return encoded, nil
+}
+
+// 解密 EncryptName 的结果
+func (k *Key) DecryptName(encoded string) (string, error) {
+	sealed, err := nameEncoding.DecodeString(strings.ToLower(encoded))
+	size := k.nameEnc.NonceSize()
+	if err != nil || len(sealed) < size+tagSize {
+		return "", i18n.Errorf("不是加密的文件名: %s", encoded)
+	}
+	name, err := k.nameEnc.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return "", ErrAuth
+	}
+	// 防止伪造的文件名跳出快照目录
+	if !hmac.Equal(mac(k.nameMac, name)[:size], sealed[:size]) || bytes.Contains(name, []byte("/")) || string(name) == ".." {
+		return "", ErrAuth
+	}
+	return string(name), nil
+}
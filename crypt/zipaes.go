@@ -0,0 +1,126 @@
+package crypt
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	aeszip "github.com/alexmullins/zip"
+
+	"syncsafe/i18n"
+)
+
+// WinZip AES 加密（AE-2）的 zip，7-Zip、WinZip、WinRAR、PeaZip 等工具可以用密码解压。
+// 加密、认证码和条目格式由 github.com/alexmullins/zip 实现：每个文件用 AES-256 加密，
+// 密钥由密码通过 PBKDF2 派生，末尾附加 HMAC-SHA1 认证码。文件名和大小不加密
+const zipAESMethod = 99
+
+// 条目是否为 WinZip AES 加密
+func IsZipAES(file *zip.File) bool {
+	return file.Method == zipAESMethod
+}
+
+// 写入加密 zip 的写入器，文件条目用 password 加密，目录条目不加密
+type ZipAESWriter struct {
+	zw       *aeszip.Writer
+	password string
+}
+
+func NewZipAESWriter(w io.Writer, password string) (*ZipAESWriter, error) {
+	if password == "" {
+		return nil, i18n.Errorf("密码短语不能为空")
+	}
+	return &ZipAESWriter{zw: aeszip.NewWriter(w), password: password}, nil
+}
+
+// 按 header 的名称、压缩方法、权限和修改时间创建条目，header 通常由 zip.FileInfoHeader 生成。
+// 条目的内容必须在创建下一个条目或 Close 之前写完，认证码在那时写入
+func (w *ZipAESWriter) CreateHeader(header *zip.FileHeader) (io.Writer, error) {
+	h := &aeszip.FileHeader{
+		Name:           header.Name,
+		Method:         header.Method,
+		CreatorVersion: header.CreatorVersion,
+		ExternalAttrs:  header.ExternalAttrs,
+		Extra:          header.Extra,
+	}
+	if !isASCII(h.Name) && utf8.ValidString(h.Name) {
+		h.Flags |= 0x800
+	}
+	if !header.Modified.IsZero() {
+		// DOS 时间只精确到 2 秒且没有时区，另外写入扩展时间戳，archive/zip 等读取时以它为准
+		h.SetModTime(header.Modified)
+		h.Extra = binary.LittleEndian.AppendUint16(h.Extra, 0x5455)
+		h.Extra = binary.LittleEndian.AppendUint16(h.Extra, 5)
+		h.Extra = append(h.Extra, 1)
+		h.Extra = binary.LittleEndian.AppendUint32(h.Extra, uint32(header.Modified.Unix()))
+	}
+	if !strings.HasSuffix(h.Name, "/") {
+		h.SetPassword(w.password)
+	}
+	return w.zw.CreateHeader(h)
+}
+
+// 写完最后一个条目的认证码和中央目录
+func (w *ZipAESWriter) Close() error {
+	return w.zw.Close()
+}
+
+// 读取加密 zip 中条目内容的读取器。条目先整体解密并核对认证码，通过后才交给调用方，
+// 被篡改的内容不会被读出
+type ZipAESReader struct {
+	zr       *aeszip.Reader
+	password string
+}
+
+// r 和 size 与打开同一归档的 zip.NewReader 相同
+func NewZipAESReader(r io.ReaderAt, size int64, password string) (*ZipAESReader, error) {
+	zr, err := aeszip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipAESReader{zr: zr, password: password}, nil
+}
+
+// 打开第 index 个条目，序号与 zip.Reader.File 中的相同，读出的是解压后的内容。
+// 密码错误、内容被篡改时返回 ErrAuth
+func (r *ZipAESReader) Open(index int) (io.ReadCloser, error) {
+	if index < 0 || index >= len(r.zr.File) || !r.zr.File[index].IsEncrypted() {
+		return nil, i18n.Errorf("zip 条目缺少 AES 加密信息")
+	}
+	file := r.zr.File[index]
+	file.SetPassword(r.password)
+	rc, err := file.Open()
+	if err != nil {
+		return nil, zipAESError(err)
+	}
+	return &zipAESReadCloser{rc}, nil
+}
+
+type zipAESReadCloser struct {
+	io.ReadCloser
+}
+
+func (r *zipAESReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	return n, zipAESError(err)
+}
+
+// 密码和认证码错误统一为 ErrAuth
+func zipAESError(err error) error {
+	if errors.Is(err, aeszip.ErrPassword) || errors.Is(err, aeszip.ErrAuthentication) || errors.Is(err, aeszip.ErrDecryption) {
+		return ErrAuth
+	}
+	return err
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,3335 @@
+// 端到端测试：在临时目录中执行完整的备份、导出还原和清理流程，
+// 并通过 faults 包注入磁盘已满、权限不足、网络中断和 Git 推送被拒绝等故障。
+package e2e
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	aeszip "github.com/alexmullins/zip"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/net/webdav"
+
+	"syncsafe/cli"
+	"syncsafe/crypt"
+	"syncsafe/engine"
+	"syncsafe/faults"
+	"syncsafe/gitsync"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/logging"
+	"syncsafe/notify"
+	"syncsafe/peer"
+	"syncsafe/schedule"
+	"syncsafe/storage"
+	"syncsafe/version"
+	"syncsafe/watcher"
+)
+
+// 测试环境：源文件夹、目标文件夹和独立的数据目录
+type env struct {
+	t      *testing.T
+	source string
+	dest   string
+	config *engine.Config
+	engine *engine.Engine
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	root := t.TempDir()
+	engine.DataDir = filepath.Join(root, "data")
+	faults.Set(nil)
+	t.Cleanup(func() { faults.Set(nil) })
+
+	e := &env{t: t, source: filepath.Join(root, "source"), dest: filepath.Join(root, "dest")}
+	if err := os.MkdirAll(e.source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	e.config = engine.NewConfig()
+	e.config.SourcePath = e.source
+	e.config.DestinationPath = e.dest
+	e.engine = engine.New(e.config, engine.Hooks{})
+	return e
+}
+
+// 写入源文件，修改时间逐次递增，保证变化能被检测到
+func (e *env) write(relPath, content string, age time.Duration) {
+	e.t.Helper()
+	path := filepath.Join(e.source, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		e.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		e.t.Fatal(err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// 执行一次备份并追加到历史
+func (e *env) backup() (*history.Record, error) {
+	e.t.Helper()
+	record, err := e.engine.Backup(context.Background(), 0)
+	if record != nil {
+		e.config.History = append(e.config.History, *record)
+	}
+	// 快照目录名精确到秒，两次备份之间等待避免目录重名
+	time.Sleep(1100 * time.Millisecond)
+	return record, err
+}
+
+func (e *env) mustBackup() history.Record {
+	e.t.Helper()
+	record, err := e.backup()
+	if err != nil {
+		e.t.Fatalf("备份失败: %v", err)
+	}
+	if record == nil {
+		e.t.Fatal("备份没有生成记录")
+	}
+	return *record
+}
+
+// 读取快照目录中所有文件的内容
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(relPath)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestBackupDetectsChanges(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a1", 3*time.Hour)
+	e.write("docs/b.txt", "b1", 3*time.Hour)
+	e.write("docs/c.txt", "c1", 3*time.Hour)
+
+	first := e.mustBackup()
+	if first.FileCount != 3 || first.NewFiles != 3 {
+		t.Fatalf("第一次备份: 文件 %d，新增 %d，应为 3 和 3", first.FileCount, first.NewFiles)
+	}
+
+	e.write("a.txt", "a2", 2*time.Hour)
+	e.write("docs/d.txt", "d1", 2*time.Hour)
+	if err := os.Remove(filepath.Join(e.source, "docs", "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	second := e.mustBackup()
+	if second.NewFiles != 1 || second.ModifiedFiles != 1 || second.DeletedFiles != 1 {
+		t.Fatalf("第二次备份: 新增 %d、修改 %d、删除 %d，应各为 1",
+			second.NewFiles, second.ModifiedFiles, second.DeletedFiles)
+	}
+	wantChanges := []history.Change{
+		{Path: "a.txt", Op: history.ChangeModified, Size: 2},
+		{Path: "docs/c.txt", Op: history.ChangeDeleted, Size: 2},
+		{Path: "docs/d.txt", Op: history.ChangeNew, Size: 2},
+	}
+	if !reflect.DeepEqual(second.Changes, wantChanges) {
+		t.Fatalf("第二次备份记录的变化为 %+v，应为 %+v", second.Changes, wantChanges)
+	}
+
+	want := map[string]string{"a.txt": "a2", "docs/b.txt": "b1", "docs/d.txt": "d1"}
+	got := readTree(t, second.DestPath)
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("快照中 %s 的内容为 %q，应为 %q", name, got[name], content)
+		}
+	}
+	if _, ok := got["docs/c.txt"]; ok {
+		t.Error("已删除的文件出现在新快照中")
+	}
+}
+
+func TestChecksumCompare(t *testing.T) {
+	e := newEnv(t)
+	e.config.ChecksumCompare = true
+	e.config.Incremental = true
+	e.write("a.txt", "a1", 3*time.Hour)
+	e.write("b.txt", "b1", 3*time.Hour)
+	first := e.mustBackup()
+
+	// a.txt 内容变化但修改时间不变，b.txt 只有修改时间变化
+	info, err := os.Stat(filepath.Join(e.source, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.write("a.txt", "a2", 0)
+	if err := os.Chtimes(filepath.Join(e.source, "a.txt"), info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	e.write("b.txt", "b1", time.Hour)
+	second := e.mustBackup()
+	if second.ModifiedFiles != 1 {
+		t.Fatalf("修改 %d 个文件，应为 1", second.ModifiedFiles)
+	}
+	if got := readTree(t, second.DestPath)["a.txt"]; got != "a2" {
+		t.Fatalf("快照中 a.txt 的内容为 %q，应为 a2", got)
+	}
+	old, _ := os.Stat(filepath.Join(first.DestPath, "b.txt"))
+	linked, _ := os.Stat(filepath.Join(second.DestPath, "b.txt"))
+	if old == nil || linked == nil || !os.SameFile(old, linked) {
+		t.Fatal("只有修改时间变化的文件应硬链接到上一个快照")
+	}
+}
+
+func TestTrustMetadata(t *testing.T) {
+	e := newEnv(t)
+	e.config.ChecksumCompare = true
+	e.config.TrustMetadata = true
+	e.config.HashDirs = []string{"docs"}
+	e.config.TrustDirs = []string{"docs/cache"}
+	files := []string{"media/v.mp4", "docs/a.txt", "docs/cache/x.tmp"}
+	for _, name := range files {
+		e.write(name, "1", 3*time.Hour)
+	}
+	e.mustBackup()
+
+	// 三个文件的内容都变化但修改时间不变，只有 docs/a.txt 计算哈希
+	for _, name := range files {
+		info, err := os.Stat(filepath.Join(e.source, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.write(name, "2", 0)
+		if err := os.Chtimes(filepath.Join(e.source, name), info.ModTime(), info.ModTime()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := e.mustBackup()
+	if second.ModifiedFiles != 1 {
+		t.Fatalf("修改 %d 个文件，应为 1（只有计算哈希的 docs/a.txt）", second.ModifiedFiles)
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	e := newEnv(t)
+	e.write("vault.kdbx", "v1", time.Hour)
+	e.write("game/world.dat", "w1", time.Hour)
+	e.write("game/other.log", "log", time.Hour)
+	e.write("notes.txt", "notes", time.Hour)
+	if err := e.config.AddSourceFile(filepath.Join(e.source, "vault.kdbx")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.config.AddSourceFile(filepath.Join(e.source, "game", "world.dat")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.config.AddSourceFile(t.TempDir()); err == nil {
+		t.Fatal("添加文件夹应失败")
+	}
+
+	record := e.mustBackup()
+	got := readTree(t, record.DestPath)
+	if len(got) != 2 || got["vault.kdbx"] != "v1" || got["game/world.dat"] != "w1" {
+		t.Fatalf("快照中应只有两个单独的文件，实际: %v", got)
+	}
+
+	// 监控只报告单独的文件的变化。密码管理器通常写入临时文件后重命名
+	changes := make(chan string, 16)
+	w, err := watcher.New(e.source, watcher.Options{
+		Debounce: time.Hour,
+		OnChange: func(path string) { changes <- path },
+		Ignore:   e.config.WatchIgnore(e.source),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	e.write("notes.txt", "changed", 0)
+	e.write("game/other.log", "changed", 0)
+	e.write("vault.kdbx.tmp", "v2", 0)
+	if err := os.Rename(filepath.Join(e.source, "vault.kdbx.tmp"), filepath.Join(e.source, "vault.kdbx")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case path := <-changes:
+		if path != filepath.Join(e.source, "vault.kdbx") {
+			t.Fatalf("收到不相关文件的变化: %s", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到单独的文件的变化")
+	}
+}
+
+func TestWatchNewDirectories(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a", time.Hour)
+	changes := make(chan string, 64)
+	w, err := watcher.New(e.source, watcher.Options{
+		Debounce: time.Hour,
+		OnChange: func(path string) { changes <- path },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	waitFor := func(path string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case got := <-changes:
+				if got == path {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("没有收到 %s 的变化", path)
+			}
+		}
+	}
+	watched := func(dir string) bool {
+		for _, d := range w.Dirs() {
+			if d == dir {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 监控开始后新建的多级目录中的文件
+	nested := filepath.Join(e.source, "new", "deep")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(filepath.Join(e.source, "new"))
+	time.Sleep(200 * time.Millisecond)
+	e.write("new/deep/file.txt", "x", 0)
+	waitFor(filepath.Join(nested, "file.txt"))
+
+	// 移入已有内容的目录：其中的文件报告为变化，之后的写入也能收到
+	outside := filepath.Join(t.TempDir(), "moved")
+	if err := os.MkdirAll(filepath.Join(outside, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "sub", "old.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	moved := filepath.Join(e.source, "moved")
+	if err := os.Rename(outside, moved); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(filepath.Join(moved, "sub", "old.txt"))
+	e.write("moved/sub/new.txt", "y", 0)
+	waitFor(filepath.Join(moved, "sub", "new.txt"))
+
+	// 删除和移出的目录不再监控
+	if err := os.RemoveAll(filepath.Join(e.source, "new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(moved, filepath.Join(t.TempDir(), "gone")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for watched(nested) || watched(filepath.Join(moved, "sub")) {
+		if time.Now().After(deadline) {
+			t.Fatalf("删除和移出的目录应不再监控: %v", w.Dirs())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !watched(e.source) {
+		t.Fatal("源文件夹应仍在监控")
+	}
+}
+
+// 无法创建文件系统快照时（临时目录通常不在 Btrfs、ZFS、LVM 或 APFS 上，或没有权限）
+// 直接读取源文件夹并在结果中提示；源文件夹中遗留的快照目录不备份
+func TestSourceSnapshotFallback(t *testing.T) {
+	e := newEnv(t)
+	var statuses []string
+	e.engine = engine.New(e.config, engine.Hooks{Status: func(message string) { statuses = append(statuses, message) }})
+	e.config.SourceSnapshot = true
+	e.write("a.txt", "a", 0)
+	e.write(".syncsafe-snapshot-20240101-000000/a.txt", "old", 0)
+	e.write(".syncsafe-other/b.txt", "b", 0)
+
+	record := e.mustBackup()
+	got := readTree(t, record.DestPath)
+	if got["a.txt"] != "a" || got[".syncsafe-other/b.txt"] != "b" {
+		t.Fatalf("快照内容不对: %v", got)
+	}
+	if _, ok := got[".syncsafe-snapshot-20240101-000000/a.txt"]; ok {
+		t.Fatal("源文件夹中的文件系统快照不应备份")
+	}
+	last := statuses[len(statuses)-1]
+	if !strings.Contains(last, "创建文件系统快照失败") && !slices.ContainsFunc(statuses, func(s string) bool { return strings.HasSuffix(s, "快照") }) {
+		t.Fatalf("应使用快照或提示没有使用快照，实际 %q", last)
+	}
+}
+
+func TestSelectiveBackup(t *testing.T) {
+	e := newEnv(t)
+	e.config.Incremental = true
+	e.write("a.txt", "a1", time.Hour)
+	e.write("dir/b.txt", "b1", time.Hour)
+	e.write("quiet.txt", "q1", time.Hour)
+	e.engine.TrackChanges(e.source)
+	scanMode := func() string {
+		t.Helper()
+		events, unsubscribe := e.engine.Subscribe(1024)
+		defer unsubscribe()
+		e.mustBackup()
+		for {
+			select {
+			case ev := <-events:
+				if ev.Kind == engine.EventScanStarted {
+					return ev.Message
+				}
+			default:
+				t.Fatal("没有收到开始扫描的事件")
+			}
+		}
+	}
+	// 监控开始后的第一次备份完整遍历
+	if mode := scanMode(); strings.Contains(mode, "按变化") {
+		t.Fatalf("第一次备份应完整遍历，实际 %s", mode)
+	}
+
+	// 只读取记录的变化：没有记录的修改不会被发现，沿用上一个快照
+	e.write("a.txt", "a2", 0)
+	e.write("new/c.txt", "c1", 0)
+	os.Remove(filepath.Join(e.source, "dir", "b.txt"))
+	e.write("quiet.txt", "q2", 0)
+	for _, relPath := range []string{"a.txt", "new", "new/c.txt", "dir/b.txt"} {
+		e.engine.ChangeObserved(filepath.Join(e.source, relPath))
+	}
+	if mode := scanMode(); !strings.Contains(mode, "按变化（4 个路径）") {
+		t.Fatalf("应按变化备份，实际 %s", mode)
+	}
+	record := e.config.History[len(e.config.History)-1]
+	got := readTree(t, record.DestPath)
+	if len(got) != 3 || got["a.txt"] != "a2" || got["new/c.txt"] != "c1" || got["quiet.txt"] != "q1" {
+		t.Fatalf("按变化备份的快照不正确: %v", got)
+	}
+	if record.NewFiles != 1 || record.ModifiedFiles != 1 || record.DeletedFiles != 1 {
+		t.Fatalf("变化统计不正确: 新增 %d、修改 %d、删除 %d", record.NewFiles, record.ModifiedFiles, record.DeletedFiles)
+	}
+
+	// 变化不完整时完整遍历，发现所有修改
+	e.engine.ChangesOverflowed()
+	if mode := scanMode(); strings.Contains(mode, "按变化") {
+		t.Fatalf("事件风暴后应完整遍历，实际 %s", mode)
+	}
+	record = e.config.History[len(e.config.History)-1]
+	if got := readTree(t, record.DestPath); got["quiet.txt"] != "q2" {
+		t.Fatalf("完整遍历应发现所有修改: %v", got)
+	}
+
+	// 排除规则变化后完整遍历
+	e.engine.ChangeObserved(filepath.Join(e.source, "a.txt"))
+	e.config.FilterRules = []string{"*.log"}
+	if mode := scanMode(); strings.Contains(mode, "按变化") {
+		t.Fatalf("排除规则变化后应完整遍历，实际 %s", mode)
+	}
+
+	// 哈希设置变化后完整遍历，未变化的文件要按新的设置重新判断
+	e.engine.ChangeObserved(filepath.Join(e.source, "a.txt"))
+	e.config.HashDirs = []string{"new"}
+	if mode := scanMode(); strings.Contains(mode, "按变化") {
+		t.Fatalf("哈希设置变化后应完整遍历，实际 %s", mode)
+	}
+}
+
+func TestAdaptiveDebounce(t *testing.T) {
+	e := newEnv(t)
+	settled := make(chan struct{}, 16)
+	w, err := watcher.New(e.source, watcher.Options{
+		Debounce:  200 * time.Millisecond,
+		OnSettled: func() { settled <- struct{}{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// 持续写入期间不备份，等待时间随之延长
+	for i := 0; i < 16; i++ {
+		e.write("frame.png", strconv.Itoa(i), 0)
+		time.Sleep(150 * time.Millisecond)
+	}
+	if len(settled) != 0 {
+		t.Fatal("持续写入期间不应触发备份")
+	}
+	if delay := w.Delay(); delay < 400*time.Millisecond {
+		t.Fatalf("持续写入后等待时间应延长，实际为 %v", delay)
+	}
+
+	// 变化平息后只触发一次
+	select {
+	case <-settled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("变化平息后没有触发备份")
+	}
+	time.Sleep(time.Second)
+	if len(settled) != 0 {
+		t.Fatal("变化平息后应只触发一次备份")
+	}
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	e := newEnv(t)
+	e.write("a.txt", "alpha", time.Hour)
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"backup", "--profile", "不存在"}, cli.ExitConfig},
+		{[]string{"backup", "--unknown"}, cli.ExitConfig},
+		{[]string{"verify"}, cli.ExitFailure}, // 还没有快照
+		{[]string{"backup", "--json"}, cli.ExitSuccess},
+		{[]string{"verify", "--json"}, cli.ExitSuccess},
+		{[]string{"version"}, cli.ExitSuccess},
+	} {
+		if got := cli.Run(c.args); got != c.want {
+			t.Errorf("%v: 退出码 %d，应为 %d", c.args, got, c.want)
+		}
+	}
+
+	// 命令行运行的备份与图形界面写入同一个日志目录
+	entries, err := logging.Read(engine.LogDir(), logging.Filter{Search: "备份完成"}, 0)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("日志中应有一条备份完成的记录: %+v，错误 %v", entries, err)
+	}
+}
+
+func TestExportRestoresSnapshot(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "hello", time.Hour)
+	e.write("sub/dir/b.bin", strings.Repeat("x", 100000), time.Hour)
+	record := e.mustBackup()
+
+	var archive bytes.Buffer
+	if err := engine.ExportSnapshot(record.DestPath, &archive, "tar.gz", nil); err != nil {
+		t.Fatalf("导出快照失败: %v", err)
+	}
+
+	// 解压到新的目录，与源文件夹逐个对比
+	gz, err := gzip.NewReader(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimPrefix(header.Name, filepath.Base(record.DestPath)+"/")
+		restored[name] = string(data)
+	}
+
+	for name, content := range readTree(t, e.source) {
+		if restored[name] != content {
+			t.Errorf("还原的 %s 与源文件不同", name)
+		}
+	}
+}
+
+func TestRestoreConflicts(t *testing.T) {
+	e := newEnv(t)
+	e.write("docs/a.txt", "original", 2*time.Hour)
+	e.write("docs/b.txt", "b", 2*time.Hour)
+	record := e.mustBackup()
+
+	e.write("docs/a.txt", "edited", time.Hour)
+	if err := os.Remove(filepath.Join(e.source, "docs", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := e.engine.RestoreConflicts(record.DestPath, []string{"docs", "docs/a.txt"}, e.source)
+	if err != nil || len(conflicts) != 1 || filepath.ToSlash(conflicts[0]) != "docs/a.txt" {
+		t.Fatalf("冲突为 %v，错误 %v，应只有 docs/a.txt", conflicts, err)
+	}
+
+	// 跳过已有文件：只还原被删除的文件
+	result, err := e.engine.Restore(record.DestPath, []string{"docs"}, e.source, false)
+	if err != nil || result.Files != 1 || result.Skipped != 1 {
+		t.Fatalf("跳过已有文件: 还原 %d、跳过 %d，错误 %v", result.Files, result.Skipped, err)
+	}
+	if got := readTree(t, e.source)["docs/a.txt"]; got != "edited" {
+		t.Errorf("跳过时已有文件被覆盖为 %q", got)
+	}
+
+	// 覆盖：恢复为快照中的内容
+	if _, err := e.engine.Restore(record.DestPath, []string{"docs/a.txt"}, e.source, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTree(t, e.source)["docs/a.txt"]; got != "original" {
+		t.Errorf("覆盖后内容为 %q，应为 original", got)
+	}
+}
+
+func TestPruneKeepsFileVersions(t *testing.T) {
+	e := newEnv(t)
+	e.config.KeepVersions = 2
+	var records []history.Record
+	for i, content := range []string{"v1", "v2", "v3"} {
+		e.write("doc.txt", content, time.Duration(3-i)*time.Hour)
+		records = append(records, e.mustBackup())
+	}
+
+	count, err := e.engine.PruneSnapshots(records[:2])
+	if err != nil || count != 2 {
+		t.Fatalf("清理快照: 删除 %d 个，错误 %v", count, err)
+	}
+	for _, record := range records[:2] {
+		if _, err := os.Stat(record.DestPath); !os.IsNotExist(err) {
+			t.Errorf("快照 %s 没有被删除", record.DestPath)
+		}
+	}
+	for _, record := range e.config.History[:2] {
+		if !record.Pruned {
+			t.Error("被清理的快照没有标记为已清理")
+		}
+	}
+
+	// v3 仍在保留的快照中，v2 应被复制到版本库，v1 超出保留数量
+	stored := readTree(t, filepath.Join(e.dest, "source-versions"))
+	var contents []string
+	for _, content := range stored {
+		contents = append(contents, content)
+	}
+	if len(contents) != 1 || contents[0] != "v2" {
+		t.Fatalf("版本库内容为 %v，应只有 v2", contents)
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	// 两个月内每天两个快照，从旧到新
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	var records []history.Record
+	for day := 0; day < 60; day++ {
+		for _, hour := range []int{0, 8} {
+			records = append(records, history.Record{
+				Timestamp: start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour),
+				Success:   true,
+				DestPath:  "snapshot-" + strconv.Itoa(day) + "-" + strconv.Itoa(hour),
+				TotalSize: 100,
+			})
+		}
+	}
+	records = append(records, history.Record{Timestamp: start.AddDate(0, 0, 60), ErrorMessage: "失败"})
+
+	if plan := engine.PlanRetention(records, engine.RetentionPolicy{}, time.Local); len(plan.Snapshots) != 0 {
+		t.Fatalf("没有保留策略时不应清理: %d 个", len(plan.Snapshots))
+	}
+
+	// 最近 3 个快照加最近 7 天每天最后一个：第 59 天两个、第 58-53 天各一个
+	plan := engine.PlanRetention(records, engine.RetentionPolicy{KeepLast: 3, KeepDaily: 7}, time.Local)
+	if len(plan.Snapshots) != 120-8 || plan.Freed != 100*(120-8) {
+		t.Fatalf("应清理 112 个快照，实际 %d 个，释放 %d", len(plan.Snapshots), plan.Freed)
+	}
+	kept := make(map[string]bool)
+	for _, record := range records {
+		kept[record.DestPath] = record.HasSnapshot()
+	}
+	for _, record := range plan.Snapshots {
+		delete(kept, record.DestPath)
+	}
+	for _, name := range []string{"snapshot-59-8", "snapshot-59-0", "snapshot-58-8", "snapshot-53-8"} {
+		if !kept[name] {
+			t.Errorf("%s 应保留", name)
+		}
+	}
+	if kept["snapshot-58-0"] || kept["snapshot-52-8"] {
+		t.Error("同一天较早的快照和 7 天之前的快照应被清理")
+	}
+
+	// 每月保留：两个月各保留最后一个快照
+	plan = engine.PlanRetention(records, engine.RetentionPolicy{KeepMonthly: 12}, time.Local)
+	if len(plan.Snapshots) != 120-2 {
+		t.Fatalf("每月保留时应清理 118 个快照，实际 %d 个", len(plan.Snapshots))
+	}
+
+	// 每天按任务的时区划分：UTC 的两天在东八区是同一天
+	shanghai, err := engine.LoadTimeZone("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	night := []history.Record{
+		{Timestamp: time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC), Success: true, DestPath: "snapshot-a"},
+		{Timestamp: time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC), Success: true, DestPath: "snapshot-b"},
+	}
+	if plan := engine.PlanRetention(night, engine.RetentionPolicy{KeepDaily: 2}, time.UTC); len(plan.Snapshots) != 0 {
+		t.Fatalf("按 UTC 划分是两天，不应清理: %d 个", len(plan.Snapshots))
+	}
+	if plan := engine.PlanRetention(night, engine.RetentionPolicy{KeepDaily: 2}, shanghai); len(plan.Snapshots) != 1 || plan.Snapshots[0].DestPath != "snapshot-a" {
+		t.Fatalf("按东八区划分是同一天，应清理较早的快照: %+v", plan.Snapshots)
+	}
+
+	// 备份后按策略清理，快照目录被删除
+	e := newEnv(t)
+	e.config.Retention.KeepLast = 2
+	for i, content := range []string{"v1", "v2", "v3"} {
+		e.write("doc.txt", content, time.Duration(3-i)*time.Hour)
+		e.mustBackup()
+	}
+	count, err := e.engine.ApplyRetention()
+	if err != nil || count != 1 {
+		t.Fatalf("按保留策略清理: 删除 %d 个，错误 %v", count, err)
+	}
+	if _, err := os.Stat(e.config.History[0].DestPath); !os.IsNotExist(err) || !e.config.History[0].Pruned {
+		t.Fatal("最旧的快照应被清理")
+	}
+}
+
+// 快照骤减或骤增时记录异常，确认之前暂停按保留策略清理
+func TestSizeAnomaly(t *testing.T) {
+	e := newEnv(t)
+	e.config.Retention.KeepLast = 1
+	for i := 0; i < 3; i++ {
+		e.config.History = append(e.config.History, history.Record{
+			Timestamp: time.Now().Add(time.Duration(i-3) * time.Hour), SourcePath: e.config.SourcePath,
+			FileCount: 40, TotalSize: 40 * 1024, Success: true,
+		})
+	}
+	e.write("a.txt", "a", time.Hour)
+	e.write("b.txt", "b", time.Hour)
+	record := e.mustBackup()
+	if !strings.Contains(record.SizeAnomaly, "明显变小") {
+		t.Fatalf("文件数从 40 降到 2 应提醒，实际: %q", record.SizeAnomaly)
+	}
+	if _, err := e.engine.ApplyRetention(); !errors.Is(err, engine.ErrRetentionHeld) {
+		t.Fatalf("确认之前应暂停清理，实际: %v", err)
+	}
+	if !history.AcknowledgeAnomaly(e.config.History, record) {
+		t.Fatal("找不到要确认的记录")
+	}
+	if _, err := e.engine.ApplyRetention(); err != nil {
+		t.Fatalf("确认后应恢复清理: %v", err)
+	}
+
+	// 骤增同样提醒；正常的波动、太少的历史和很小的文件夹不提醒
+	normal := history.Record{SourcePath: "/src", FileCount: 100, TotalSize: 100 << 20, Success: true}
+	runs := []history.Record{normal, normal, normal}
+	grown := normal
+	grown.TotalSize = 400 << 20
+	if anomaly := engine.DetectSizeAnomaly(runs, grown); !strings.Contains(anomaly, "明显变大") {
+		t.Errorf("大小增加到 4 倍应提醒，实际: %q", anomaly)
+	}
+	steady := normal
+	steady.FileCount, steady.TotalSize = 120, 150<<20
+	if anomaly := engine.DetectSizeAnomaly(runs, steady); anomaly != "" {
+		t.Errorf("正常的波动不应提醒: %q", anomaly)
+	}
+	if anomaly := engine.DetectSizeAnomaly(runs[:2], grown); anomaly != "" {
+		t.Errorf("历史太少时不应提醒: %q", anomaly)
+	}
+	small := []history.Record{{SourcePath: "/src", FileCount: 3, TotalSize: 30, Success: true}}
+	small = append(small, small[0], small[0])
+	if anomaly := engine.DetectSizeAnomaly(small, history.Record{SourcePath: "/src", FileCount: 1, TotalSize: 1}); anomaly != "" {
+		t.Errorf("很小的文件夹不应提醒: %q", anomaly)
+	}
+}
+
+// 要求清理前校验时，没有较新的快照最近通过校验就推迟清理，校验通过后恢复
+func TestVerifyBeforePrune(t *testing.T) {
+	e := newEnv(t)
+	e.config.Retention = engine.RetentionPolicy{KeepLast: 1, VerifyBeforePrune: true, VerifiedWithinDays: 7}
+	for i, content := range []string{"v1", "v2", "v3"} {
+		e.write("doc.txt", content, time.Duration(3-i)*time.Hour)
+		e.mustBackup()
+	}
+	count, err := e.engine.ApplyRetention()
+	if !errors.Is(err, engine.ErrRetentionUnverified) || count != 0 {
+		t.Fatalf("没有通过校验的快照时应推迟清理: 删除 %d 个，错误 %v", count, err)
+	}
+	for _, record := range e.config.History {
+		if _, err := os.Stat(record.DestPath); err != nil {
+			t.Fatalf("推迟清理时快照应保留: %v", err)
+		}
+	}
+
+	// 校验通过的时间超过有效期时同样推迟
+	latest := e.config.History[2]
+	history.SetVerified(e.config.History, latest, true, time.Now().AddDate(0, 0, -10))
+	plan := engine.PlanRetention(e.config.History, e.config.Retention, e.config.Location())
+	if allowed, deferred := engine.GateByVerification(e.config.History, plan, e.config.Retention, time.Now()); len(allowed) != 0 || deferred != 2 {
+		t.Fatalf("校验已过期时应推迟 2 个快照，实际可清理 %d 个，推迟 %d 个", len(allowed), deferred)
+	}
+
+	// 手动校验最新的快照通过后，更早的快照照常清理
+	result, err := e.engine.VerifySnapshot(latest)
+	if err != nil || !result.OK() {
+		t.Fatalf("校验快照: %v %v", result, err)
+	}
+	history.SetVerified(e.config.History, latest, true, time.Now())
+	count, err = e.engine.ApplyRetention()
+	if err != nil || count != 2 {
+		t.Fatalf("校验通过后应清理 2 个快照: 删除 %d 个，错误 %v", count, err)
+	}
+	if !e.config.History[0].Pruned || !e.config.History[1].Pruned || e.config.History[2].Pruned {
+		t.Fatal("应只保留通过校验的最新快照")
+	}
+
+	// 备份后校验通过的快照同样可以作为依据
+	e.config.VerifyAfterBackup = true
+	e.write("doc.txt", "v4", 0)
+	if record := e.mustBackup(); !record.Verified || record.VerifiedAt.IsZero() {
+		t.Fatal("备份后校验通过应记录校验时间")
+	}
+	if count, err := e.engine.ApplyRetention(); err != nil || count != 1 {
+		t.Fatalf("清理上一个快照: 删除 %d 个，错误 %v", count, err)
+	}
+}
+
+func TestDiskFullFailsBackup(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a", time.Hour)
+	faults.Set(map[faults.Kind]float64{faults.DiskFull: 1})
+
+	record, err := e.backup()
+	if err == nil {
+		t.Fatal("磁盘已满时备份应失败")
+	}
+	if !strings.Contains(err.Error(), syscall.ENOSPC.Error()) {
+		t.Errorf("错误信息没有说明磁盘已满: %v", err)
+	}
+	if record == nil || record.Success || record.ErrorMessage == "" {
+		t.Fatalf("失败的备份应记录到历史: %+v", record)
+	}
+
+	// 故障消失后可以正常备份
+	faults.Set(nil)
+	if next := e.mustBackup(); next.FileCount != 1 {
+		t.Errorf("恢复后备份了 %d 个文件，应为 1", next.FileCount)
+	}
+}
+
+func TestPermissionDeniedFailsBackup(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a", time.Hour)
+	faults.Set(map[faults.Kind]float64{faults.PermissionDenied: 1})
+
+	record, err := e.backup()
+	if err == nil || !strings.Contains(err.Error(), os.ErrPermission.Error()) {
+		t.Fatalf("权限不足时备份应失败并说明原因: %v", err)
+	}
+	// 在创建备份目录时就失败，不会生成快照记录
+	if record != nil {
+		t.Errorf("创建备份目录失败时不应生成记录: %+v", record)
+	}
+}
+
+// 提权辅助进程解析符号链接后再检查读取范围，指向范围外的符号链接不能被读取或列出，
+// 上级目录被替换为指向范围外的符号链接后同样无法读取，令牌从文件读取，不出现在命令行参数中
+func TestElevatedHelperScope(t *testing.T) {
+	newEnv(t)
+	root := t.TempDir()
+	scope := filepath.Join(root, "source")
+	outside := filepath.Join(root, "secret")
+	for _, dir := range []string{scope, outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(scope, "inside.txt"), []byte("inside"), 0644)
+	os.WriteFile(filepath.Join(outside, "shadow"), []byte("secret"), 0600)
+	if err := os.Symlink(filepath.Join(outside, "shadow"), filepath.Join(scope, "link")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	os.Symlink(outside, filepath.Join(scope, "linkdir"))
+
+	tokenPath := filepath.Join(root, "token")
+	if err := os.WriteFile(tokenPath, []byte("t0ken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	go engine.RunElevatedHelper([]string{listener.Addr().String(), tokenPath, scope})
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	reader := bufio.NewReader(conn)
+	if line, err := reader.ReadString('\n'); err != nil || line != "t0ken\n" {
+		t.Fatalf("辅助进程应发送令牌文件中的令牌: %q %v", line, err)
+	}
+	request := func(op, path string) map[string]any {
+		t.Helper()
+		data, _ := json.Marshal(map[string]string{"Op": op, "Path": path})
+		if _, err := conn.Write(append(data, '\n')); err != nil {
+			t.Fatal(err)
+		}
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp map[string]any
+		json.Unmarshal(line, &resp)
+		return resp
+	}
+
+	for _, path := range []string{filepath.Join(scope, "link"), filepath.Join(scope, "linkdir", "shadow")} {
+		if resp := request("read", path); resp["Error"] == "" {
+			t.Fatalf("不应读取符号链接指向的范围外文件 %s", path)
+		}
+	}
+	if resp := request("list", filepath.Join(scope, "linkdir")); resp["Error"] == "" {
+		t.Fatal("不应列出符号链接指向的范围外目录")
+	}
+	resp := request("list", scope)
+	if resp["Error"] != "" || len(resp["Entries"].([]any)) != 3 {
+		t.Fatalf("列出范围内的目录失败: %v", resp)
+	}
+	if resp := request("read", filepath.Join(scope, "inside.txt")); resp["Error"] != "" {
+		t.Fatalf("读取范围内的文件失败: %v", resp["Error"])
+	}
+	header := make([]byte, 4)
+	io.ReadFull(reader, header)
+	content := make([]byte, 6)
+	io.ReadFull(reader, content)
+	if string(content) != "inside" {
+		t.Fatalf("读取的内容不正确: %q", content)
+	}
+	io.ReadFull(reader, header)
+
+	// 范围内的子目录被换成指向范围外的符号链接后不能读取
+	sub := filepath.Join(scope, "sub")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(sub, "shadow"), []byte("inside"), 0644)
+	os.Rename(sub, filepath.Join(root, "moved"))
+	os.Symlink(outside, sub)
+	if resp := request("read", filepath.Join(sub, "shadow")); resp["Error"] == "" {
+		t.Fatal("上级目录被替换为符号链接后不应读取范围外的文件")
+	}
+}
+
+func TestNotifyNetworkDrop(t *testing.T) {
+	newEnv(t)
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer server.Close()
+	config := notify.Config{Enabled: true, Service: notify.ServiceNtfy, Server: server.URL, Topic: "syncsafe"}
+
+	faults.Set(map[faults.Kind]float64{faults.NetworkDrop: 1})
+	if err := notify.Send(config, notify.LevelError, "备份失败", "测试"); err == nil {
+		t.Error("网络中断时发送通知应失败")
+	}
+	faults.Set(nil)
+	if err := notify.Send(config, notify.LevelError, "备份失败", "测试"); err != nil {
+		t.Errorf("发送通知失败: %v", err)
+	}
+	if received != 1 {
+		t.Errorf("服务器收到 %d 条通知，应为 1", received)
+	}
+}
+
+// 只支持明文会话的最小 SMTP 服务器，收到的邮件内容发送到返回的通道
+func newSMTPServer(t *testing.T) (int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+			case "DATA":
+				text.PrintfLine("354 go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				messages <- string(data)
+				text.PrintfLine("250 ok")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				return
+			default:
+				text.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, messages
+}
+
+func TestFailureEmail(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a", time.Hour)
+	faults.Set(map[faults.Kind]float64{faults.DiskFull: 1})
+	record, err := e.backup()
+	if err == nil || record == nil {
+		t.Fatalf("磁盘已满时备份应失败并生成记录: %v", err)
+	}
+
+	port, messages := newSMTPServer(t)
+	e.config.Email = notify.EmailConfig{
+		Enabled:    true,
+		Host:       "127.0.0.1",
+		Port:       port,
+		Security:   notify.SecurityNone,
+		From:       "syncsafe@example.com",
+		Recipients: []string{"admin@example.com"},
+	}
+	if err := engine.SendFailureEmail(e.config, *record); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	message := <-messages
+	decoded, _ := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(message[strings.Index(message, "\n\n")+2:]), ""))
+	body := strings.ReplaceAll(string(decoded), "\r\n", "\n")
+	if !strings.Contains(body, record.ErrorMessage) || !strings.Contains(body, e.source) {
+		t.Errorf("邮件正文应包含错误信息和源文件夹:\n%s", body)
+	}
+}
+
+func TestWebhookRetryQueue(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a", time.Hour)
+	record := e.mustBackup()
+
+	var available atomic.Bool
+	received := make(chan notify.WebhookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		if !available.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var ev notify.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("Webhook 内容无法解析: %v", err)
+		}
+		received <- ev
+	}))
+	defer server.Close()
+
+	// 服务器暂时不可用时加入重试队列，被拒绝的不重试
+	e.config.Webhooks = []notify.Webhook{
+		{URL: server.URL + "/hook"},
+		{URL: server.URL + "/gone", Format: notify.WebhookSlack},
+	}
+	err := engine.SendWebhooks(e.config, record)
+	if !errors.Is(err, notify.ErrWebhookRejected) {
+		t.Fatalf("返回 404 的 Webhook 应报告被拒绝: %v", err)
+	}
+	if pending := engine.PendingWebhooks(); pending != 1 {
+		t.Fatalf("重试队列中应有 1 条，实际 %d 条", pending)
+	}
+
+	available.Store(true)
+	if sent, pending := engine.RetryWebhooks(); sent != 1 || pending != 0 {
+		t.Fatalf("重试应发送 1 条且清空队列，实际发送 %d 条，剩余 %d 条", sent, pending)
+	}
+	ev := <-received
+	if ev.Event != notify.EventSucceeded || ev.Source != e.source || ev.Files != 1 || ev.Snapshot != filepath.Base(record.DestPath) {
+		t.Errorf("Webhook 内容与备份记录不符: %+v", ev)
+	}
+}
+
+// 在临时目录中创建作为远程仓库的裸仓库
+func newBareRemote(t *testing.T) string {
+	t.Helper()
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInitWithOptions(remote, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.Master},
+		Bare:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return remote
+}
+
+// 远程仓库 master 分支上的提交数，没有 master 分支时返回 0
+func remoteCommitCount(t *testing.T, remote string) int {
+	t.Helper()
+	repo, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.Master, true)
+	if err != nil {
+		return 0
+	}
+	commits, err := repo.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	commits.ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	})
+	return count
+}
+
+func TestGitPushRejected(t *testing.T) {
+	e := newEnv(t)
+	remote := newBareRemote(t)
+	e.config.Git.Enabled = true
+	e.config.Git.RepoURL = remote
+	e.config.Git.UserName = "SyncSafe"
+	e.config.Git.UserEmail = "syncsafe@example.com"
+	if err := e.engine.Git().Init(); err != nil {
+		t.Fatalf("初始化仓库失败: %v", err)
+	}
+
+	e.write("a.txt", "a", time.Hour)
+	faults.Set(map[faults.Kind]float64{faults.GitPushRejected: 1})
+	_, err := e.backup()
+	var gitErr *engine.GitError
+	if !errors.As(err, &gitErr) || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("推送被拒绝时应返回 Git 错误: %v", err)
+	}
+
+	// 故障消失后，之前的提交随下一次备份推送到远程
+	faults.Set(nil)
+	e.write("b.txt", "b", time.Hour)
+	e.mustBackup()
+	if count := remoteCommitCount(t, remote); count != 2 {
+		t.Fatalf("远程应有 2 次提交，实际 %d 次", count)
+	}
+}
+
+// 修复向导：未完成的合并和冲突的索引被清理后，工作区重新提交并推送
+func TestGitRepair(t *testing.T) {
+	e := newEnv(t)
+	remote := newBareRemote(t)
+	e.config.Git.Enabled = true
+	e.config.Git.RepoURL = remote
+	e.config.Git.UserName = "SyncSafe"
+	e.config.Git.UserEmail = "syncsafe@example.com"
+	repo := e.engine.Git()
+	if err := repo.Init(); err != nil {
+		t.Fatalf("初始化仓库失败: %v", err)
+	}
+	e.write("a.txt", "a", time.Hour)
+	e.mustBackup()
+
+	gitDir := filepath.Join(e.source, ".git")
+	os.WriteFile(filepath.Join(gitDir, "MERGE_HEAD"), []byte("0000000000000000000000000000000000000000\n"), 0644)
+	os.WriteFile(filepath.Join(gitDir, "index.lock"), nil, 0644)
+	os.WriteFile(filepath.Join(gitDir, "index"), []byte("corrupt"), 0644)
+	diagnosis := gitsync.Diagnose(e.source)
+	if !diagnosis.Merging || !diagnosis.IndexCorrupt || len(diagnosis.StaleLocks) != 1 || !diagnosis.HasRemote || diagnosis.Healthy() {
+		t.Fatalf("诊断结果不符: %+v", diagnosis)
+	}
+
+	e.write("b.txt", "b", 0)
+	if err := repo.RecommitWorkingTree(diagnosis, func(string) {}); err != nil {
+		t.Fatalf("重新提交失败: %v", err)
+	}
+	if diagnosis := gitsync.Diagnose(e.source); !diagnosis.Healthy() {
+		t.Fatalf("修复后仓库应正常: %v", diagnosis.Problems())
+	}
+
+	// 重置到远程版本后，下次提交在远程历史之上推送
+	if err := repo.ResetToRemote(gitsync.Diagnose(e.source), func(string) {}); err != nil {
+		t.Fatalf("重置到远程版本失败: %v", err)
+	}
+	if err := repo.Backup(); err != nil {
+		t.Fatal(err)
+	}
+	if count := remoteCommitCount(t, remote); count != 2 {
+		t.Fatalf("远程应有 2 次提交，实际 %d 次", count)
+	}
+}
+
+func TestGitCommitSchedule(t *testing.T) {
+	e := newEnv(t)
+	remote := newBareRemote(t)
+	e.config.Git.Enabled = true
+	e.config.Git.RepoURL = remote
+	e.config.Git.UserName = "SyncSafe"
+	e.config.Git.UserEmail = "syncsafe@example.com"
+	e.config.Git.Schedule = "0 0 1 1 *"
+	e.config.LastGitCommit = time.Now()
+	if err := e.engine.Git().Init(); err != nil {
+		t.Fatalf("初始化仓库失败: %v", err)
+	}
+
+	// 没到提交时间时本地快照照常进行，不提交
+	e.write("a.txt", "a", 2*time.Hour)
+	e.mustBackup()
+	e.write("b.txt", "b", time.Hour)
+	if record := e.mustBackup(); record.FileCount != 2 {
+		t.Fatalf("快照中应有 2 个文件，实际 %d 个", record.FileCount)
+	}
+	if count := remoteCommitCount(t, remote); count != 0 {
+		t.Fatal("没到提交时间时不应推送")
+	}
+
+	// 按计划提交时两次备份之间的变化合并为一次提交
+	if err := e.engine.CommitGit(); err != nil {
+		t.Fatal(err)
+	}
+	if count := remoteCommitCount(t, remote); count != 1 {
+		t.Fatalf("远程应有 1 次提交，实际 %d 次", count)
+	}
+
+	// 错过了计划的时间时，下一次备份补上提交
+	e.config.LastGitCommit = time.Now().AddDate(-2, 0, 0)
+	e.write("c.txt", "c", 0)
+	e.mustBackup()
+	if count := remoteCommitCount(t, remote); count != 2 {
+		t.Fatalf("远程应有 2 次提交，实际 %d 次", count)
+	}
+	if time.Since(e.config.LastGitCommit) > time.Minute {
+		t.Fatal("提交后应更新上次提交的时间")
+	}
+}
+
+func TestScheduleTimeZone(t *testing.T) {
+	config := &engine.Config{Schedule: "30 2 * * *", TimeZone: "America/New_York"}
+	newYork := config.Location()
+	if newYork.String() != "America/New_York" {
+		t.Fatalf("时区应为 America/New_York，实际 %s", newYork)
+	}
+
+	// 夏令时开始的那天 2:30 不存在，在跳过后立即运行；结束的那天 1:00-2:00 重复，2:30 只运行一次
+	runs := func(from time.Time, count int) []string {
+		var times []string
+		for next := from; len(times) < count; {
+			var err error
+			if next, err = config.NextScheduledBackup(next); err != nil || next.IsZero() {
+				t.Fatalf("计算下一次定时备份失败: %v", err)
+			}
+			times = append(times, next.In(newYork).Format("01-02 15:04 MST"))
+		}
+		return times
+	}
+	if got, want := runs(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), 3), "03-09 02:30 EST,03-10 03:00 EDT,03-11 02:30 EDT"; strings.Join(got, ",") != want {
+		t.Fatalf("夏令时开始时的运行时间应为 %s，实际 %s", want, strings.Join(got, ","))
+	}
+	// 参数为其他时区的时间时也按任务的时区计算
+	if got := runs(time.Date(2024, 3, 10, 6, 0, 0, 0, time.UTC), 1)[0]; got != "03-10 03:00 EDT" {
+		t.Fatalf("应按任务的时区计算，实际 %s", got)
+	}
+
+	config.Schedule = "*/30 1 * * *"
+	if got, want := runs(time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), 3), "11-03 01:00 EDT,11-03 01:30 EDT,11-04 01:00 EST"; strings.Join(got, ",") != want {
+		t.Fatalf("夏令时结束时重复的时间应只运行一次，期望 %s，实际 %s", want, strings.Join(got, ","))
+	}
+
+	// 每小时运行：夏令时开始的那天 23 次，结束的那天 24 次，没有重复
+	config.Schedule = "0 * * * *"
+	for _, c := range []struct {
+		day  time.Time
+		want int
+	}{
+		{time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), 23},
+		{time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), 24},
+	} {
+		day := c.day
+		seen := map[time.Time]bool{}
+		end := day.AddDate(0, 0, 1)
+		for next := day.Add(-time.Minute); ; {
+			next, _ = config.NextScheduledBackup(next)
+			if !next.Before(end) {
+				break
+			}
+			if seen[next] {
+				t.Fatalf("%s 运行了两次", next)
+			}
+			seen[next] = true
+		}
+		if len(seen) != c.want {
+			t.Fatalf("%s 应运行 %d 次，实际 %d 次", day.Format("2006-01-02"), c.want, len(seen))
+		}
+	}
+
+	// 历史记录的时间按任务的时区显示和搜索
+	record := history.Record{Timestamp: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC), SourcePath: "/src"}
+	if got := record.FormatTime(newYork); got != "2024-07-01 08:00:00" {
+		t.Fatalf("记录时间应按任务的时区显示，实际 %s", got)
+	}
+	if !(history.Filter{Search: "2024-07-01 08:00", Location: newYork}).Match(record) {
+		t.Fatal("应能按任务时区的时间搜索记录")
+	}
+
+	// 无效的时区按本机时区运行并在配置检查中列出
+	config.TimeZone = "Mars/Olympus"
+	if config.Location() != time.Local {
+		t.Fatal("无效的时区应使用本机时区")
+	}
+	found := false
+	for _, problem := range config.Validate() {
+		found = found || (problem.Setting == engine.SettingSchedule && strings.Contains(problem.Message, "Mars/Olympus"))
+	}
+	if !found {
+		t.Fatal("配置检查应列出无效的时区")
+	}
+}
+
+func TestPeerPush(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a1", time.Hour)
+	e.write("docs/b.txt", "b1", time.Hour)
+
+	cert, err := peer.LoadCertificate(filepath.Join(engine.DataDir, "peer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan peer.Received, 1)
+	receiveDir := filepath.Join(t.TempDir(), "received")
+	receiver, err := peer.Listen("127.0.0.1:0", peer.ReceiverOptions{
+		Dir:        receiveDir,
+		Code:       "1234-5678",
+		Cert:       cert,
+		OnReceived: func(r peer.Received) { received <- r },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+	code := peer.PairingCode("1234-5678", receiver.Fingerprint())
+	if !peer.CompleteCode(code) || peer.CompleteCode("1234-5678") {
+		t.Fatalf("配对码 %q 的格式不正确", code)
+	}
+
+	// 冒充接收端的设备证书与配对码中的指纹不符，发送端在握手后直接断开，不发送任何数据
+	impostorCert, err := peer.LoadCertificate(filepath.Join(t.TempDir(), "impostor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	impostor, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{impostorCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer impostor.Close()
+	leaked := make(chan int, 1)
+	go func() {
+		conn, err := impostor.Accept()
+		if err != nil {
+			leaked <- -1
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		leaked <- len(data)
+	}()
+	e.config.Peer = peer.Config{Enabled: true, Address: impostor.Addr().String(), Code: code}
+	e.mustBackup()
+	if n := <-leaked; n != 0 {
+		t.Fatalf("冒充的接收端收到了 %d 字节", n)
+	}
+	if e.config.Peer.Fingerprint != "" {
+		t.Fatal("证书不符时不应记录接收端指纹")
+	}
+
+	// 配对码错误时本地备份仍然成功，不记录接收端指纹
+	e.config.Peer = peer.Config{Enabled: true, Address: receiver.Addr().String(), Code: peer.PairingCode("0000-0000", receiver.Fingerprint())}
+	e.mustBackup()
+	if e.config.Peer.Fingerprint != "" {
+		t.Fatal("配对失败时不应记录接收端指纹")
+	}
+
+	// 配对码不区分大小写，分隔符可以省略
+	e.config.Peer.Code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	record := e.mustBackup()
+	if e.config.Peer.Fingerprint != receiver.Fingerprint() {
+		t.Fatalf("接收端指纹 = %q，期望 %q", e.config.Peer.Fingerprint, receiver.Fingerprint())
+	}
+	r := <-received
+	if r.Files != 2 || r.Snapshot != filepath.Base(record.DestPath) {
+		t.Fatalf("收到 %+v", r)
+	}
+	files := readTree(t, r.Path)
+	if files["a.txt"] != "a1" || files["docs/b.txt"] != "b1" {
+		t.Fatalf("接收端的快照内容不正确: %v", files)
+	}
+
+	// 接收端证书变化时拒绝推送
+	e.config.Peer.Fingerprint = strings.Repeat("0", 64)
+	e.write("a.txt", "a2", 0)
+	e.mustBackup()
+	select {
+	case r := <-received:
+		t.Fatalf("证书不匹配时不应推送: %+v", r)
+	default:
+	}
+}
+
+func TestShareIndex(t *testing.T) {
+	e := newEnv(t)
+	e.config.ShareIndex = true
+	e.write("docs/报告 1.txt", "alpha", time.Hour)
+	first := e.mustBackup()
+	e.write("b.txt", "beta", 0)
+	second := e.mustBackup()
+
+	index, err := os.ReadFile(filepath.Join(e.dest, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []history.Record{first, second} {
+		if !strings.Contains(string(index), filepath.Base(record.DestPath)) {
+			t.Errorf("索引中没有快照 %s", filepath.Base(record.DestPath))
+		}
+	}
+	page, err := os.ReadFile(filepath.Join(e.dest, "syncsafe-index", filepath.Base(second.DestPath)+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 文件链接相对于列表页，指向快照中的文件
+	link := "../" + filepath.Base(second.DestPath) + "/docs/" + url.PathEscape("报告 1.txt")
+	if !strings.Contains(string(page), `href="`+link+`"`) {
+		t.Errorf("列表页中没有文件链接 %s:\n%s", link, page)
+	}
+	unescaped, _ := url.PathUnescape(link)
+	target := filepath.Join(e.dest, "syncsafe-index", filepath.FromSlash(unescaped))
+	if data, err := os.ReadFile(target); err != nil || string(data) != "alpha" {
+		t.Errorf("链接没有指向快照中的文件: %v", err)
+	}
+}
+
+func TestInteropLayouts(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a1", 2*time.Hour)
+	e.write("docs/b.txt", "b1", 2*time.Hour)
+
+	rsyncDir := filepath.Join(t.TempDir(), "rsync")
+	e.config.InteropLayout = engine.LayoutRsync
+	e.config.InteropTarget = rsyncDir
+	first := e.mustBackup()
+	e.write("a.txt", "a2", time.Hour)
+	os.Remove(filepath.Join(e.source, "docs", "b.txt"))
+	e.write("c.txt", "c1", time.Hour)
+	second := e.mustBackup()
+
+	// rsync 布局：每个快照一个目录，未变化的文件硬链接，latest 指向最新的目录
+	latest, err := os.Readlink(filepath.Join(rsyncDir, "latest"))
+	if err != nil || latest != filepath.Base(second.DestPath) {
+		t.Fatalf("latest = %q, %v", latest, err)
+	}
+	if files := readTree(t, filepath.Join(rsyncDir, filepath.Base(first.DestPath))); files["a.txt"] != "a1" || files["docs/b.txt"] != "b1" {
+		t.Fatalf("第一个导出的快照不正确: %v", files)
+	}
+	if files := readTree(t, filepath.Join(rsyncDir, latest)); files["a.txt"] != "a2" || files["c.txt"] != "c1" || len(files) != 2 {
+		t.Fatalf("最新导出的快照不正确: %v", files)
+	}
+
+	e.config.InteropLayout = ""
+	e.write("d.txt", "d1", time.Hour)
+	third := e.mustBackup()
+	result, err := engine.ExportLayout(third.DestPath, rsyncDir, engine.LayoutRsync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Linked == 0 {
+		t.Fatalf("未变化的文件应硬链接到上一次导出: %+v", result)
+	}
+	before, _ := os.Stat(filepath.Join(rsyncDir, filepath.Base(second.DestPath), "a.txt"))
+	after, _ := os.Stat(filepath.Join(rsyncDir, filepath.Base(third.DestPath), "a.txt"))
+	if !os.SameFile(before, after) {
+		t.Fatal("a.txt 没有硬链接到上一次导出")
+	}
+
+	// Syncthing 布局：目录始终是最新快照的镜像
+	syncDir := filepath.Join(t.TempDir(), "sync")
+	if _, err := engine.ExportLayout(first.DestPath, syncDir, engine.LayoutSyncthing); err != nil {
+		t.Fatal(err)
+	}
+	result, err = engine.ExportLayout(second.DestPath, syncDir, engine.LayoutSyncthing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed == 0 {
+		t.Fatalf("快照中没有的文件应被删除: %+v", result)
+	}
+	files := readTree(t, syncDir)
+	if files["a.txt"] != "a2" || files["c.txt"] != "c1" || len(files) != 2 {
+		t.Fatalf("镜像内容不正确: %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(syncDir, ".stfolder")); err != nil {
+		t.Fatal("缺少 .stfolder 标记")
+	}
+
+	// 不是 Syncthing 文件夹的非空目录不能作为镜像目标
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(other, "keep.txt"), []byte("x"), 0644)
+	if _, err := engine.ExportLayout(second.DestPath, other, engine.LayoutSyncthing); err == nil {
+		t.Fatal("非空目录应被拒绝")
+	}
+}
+
+func TestCancelBackup(t *testing.T) {
+	e := newEnv(t)
+	for i := 0; i < 500; i++ {
+		e.write(filepath.Join("dir", strings.Repeat("x", i%5+1), time.Duration(i).String()+".txt"), "data", time.Hour)
+	}
+	e.config.CopyWorkers = 1
+
+	// 复制第一个文件后取消
+	ctx, cancel := context.WithCancel(context.Background())
+	events, unsubscribe := e.engine.Subscribe(1024)
+	go func() {
+		for ev := range events {
+			if ev.Kind == engine.EventFileCopied {
+				cancel()
+			}
+		}
+	}()
+	record, err := e.engine.Backup(ctx, 0)
+	unsubscribe()
+	if !errors.Is(err, engine.ErrCancelled) {
+		t.Fatalf("err = %v，期望 ErrCancelled", err)
+	}
+	if record == nil || !record.Cancelled || record.Success {
+		t.Fatalf("取消的备份记录不正确: %+v", record)
+	}
+	if record.FileCount >= 500 {
+		t.Fatalf("取消后仍复制了全部 %d 个文件", record.FileCount)
+	}
+	if _, err := os.Stat(record.DestPath); !os.IsNotExist(err) {
+		t.Fatalf("未完成的快照目录应被删除: %v", err)
+	}
+	if record.FailedFiles != 0 {
+		t.Fatalf("取消不应计为复制失败: %d", record.FailedFiles)
+	}
+}
+
+// 模拟 Nextcloud：文件和分块上传目录分别由 WebDAV 处理，支持 X-OC-Mtime 和合并分块的 MOVE
+func newNextcloud(t *testing.T, files string, chunks *int) *httptest.Server {
+	t.Helper()
+	const filesPrefix = "/remote.php/dav/files/alice"
+	const uploadsPrefix = "/remote.php/dav/uploads/alice"
+	uploads := t.TempDir()
+	filesHandler := &webdav.Handler{Prefix: filesPrefix, FileSystem: webdav.Dir(files), LockSystem: webdav.NewMemLS()}
+	uploadsHandler := &webdav.Handler{Prefix: uploadsPrefix, FileSystem: webdav.Dir(uploads), LockSystem: webdav.NewMemLS()}
+	setMtime := func(path string, r *http.Request) {
+		if sec, err := strconv.ParseInt(r.Header.Get("X-OC-Mtime"), 10, 64); err == nil {
+			os.Chtimes(path, time.Now(), time.Unix(sec, 0))
+		}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, uploadsPrefix) && r.Method == "MOVE":
+			dir := filepath.Join(uploads, filepath.FromSlash(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, uploadsPrefix), "/.file")))
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var data []byte
+			for _, entry := range entries {
+				chunk, _ := os.ReadFile(filepath.Join(dir, entry.Name()))
+				data = append(data, chunk...)
+			}
+			dest, _ := url.Parse(r.Header.Get("Destination"))
+			path := filepath.Join(files, filepath.FromSlash(strings.TrimPrefix(dest.Path, filesPrefix)))
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			setMtime(path, r)
+			os.RemoveAll(dir)
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(r.URL.Path, uploadsPrefix):
+			if r.Method == http.MethodPut {
+				*chunks++
+			}
+			uploadsHandler.ServeHTTP(w, r)
+		default:
+			filesHandler.ServeHTTP(w, r)
+			if r.Method == http.MethodPut {
+				setMtime(filepath.Join(files, filepath.FromSlash(strings.TrimPrefix(r.URL.Path, filesPrefix))), r)
+			}
+		}
+	}))
+}
+
+func TestWebDAVDestination(t *testing.T) {
+	e := newEnv(t)
+	files := t.TempDir()
+	chunks := 0
+	server := newNextcloud(t, files, &chunks)
+	defer server.Close()
+
+	e.write("a.txt", "a", time.Hour)
+	e.write("sub/b.txt", "b", time.Hour)
+	e.write("big.bin", strings.Repeat("x", storage.DefaultChunkSize+1), time.Hour)
+	e.config.DestinationPath = server.URL + "/remote.php/dav/files/alice/Backups"
+	e.config.WebDAV = storage.WebDAVConfig{Username: "alice", Password: "secret"}
+	e.config.QuickSync = true
+
+	e.mustBackup()
+	mirror := filepath.Join(files, "Backups", "source-mirror")
+	got := readTree(t, mirror)
+	if len(got) != 3 || got["a.txt"] != "a" || got["sub/b.txt"] != "b" || len(got["big.bin"]) != storage.DefaultChunkSize+1 {
+		t.Fatalf("WebDAV 上的文件不正确: %d 个文件", len(got))
+	}
+	if chunks != 2 {
+		t.Fatalf("大文件应分 2 块上传，实际 %d 块", chunks)
+	}
+
+	// 删除的文件从镜像中移到回收文件夹，修改时间保留的大文件不再上传
+	if err := os.Remove(filepath.Join(e.source, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	e.config.MirrorTrash = true
+	e.mustBackup()
+	if _, err := os.Stat(filepath.Join(mirror, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("已删除的文件仍在 WebDAV 镜像中: %v", err)
+	}
+	if trashed, _ := filepath.Glob(filepath.Join(files, "Backups", "source-mirror-trash", "*", "a.txt")); len(trashed) != 1 {
+		t.Fatal("已删除的文件没有移到 WebDAV 上的回收文件夹")
+	}
+	if chunks != 2 {
+		t.Fatalf("未变化的大文件被重新上传")
+	}
+
+	e.config.WebDAV.Password = "wrong"
+	if _, err := e.backup(); err == nil || !strings.Contains(err.Error(), "用户名或密码错误") {
+		t.Fatalf("密码错误时应提示，实际: %v", err)
+	}
+}
+
+// WebDAV 目标的性能测试通过存储后端上传测试文件，测完后删除，报告中显示上传速度
+func TestBenchmarkWebDAV(t *testing.T) {
+	e := newEnv(t)
+	files := t.TempDir()
+	chunks := 0
+	server := newNextcloud(t, files, &chunks)
+	defer server.Close()
+
+	e.write("a.txt", "a", time.Hour)
+	e.config.DestinationPath = server.URL + "/remote.php/dav/files/alice/Backups"
+	e.config.WebDAV = storage.WebDAVConfig{Username: "alice", Password: "secret"}
+
+	result, err := e.engine.RunBenchmark(func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Remote || result.UploadMBps <= 0 || len(result.DestWriteMBps) != 0 || chunks == 0 {
+		t.Fatalf("应通过 WebDAV 上传测试文件: %+v 分块 %d", result, chunks)
+	}
+	if left := readTree(t, filepath.Join(files, "Backups")); len(left) != 0 {
+		t.Fatalf("测试文件应被删除: %v", left)
+	}
+	if report := result.Report(); !strings.Contains(report, "云端上传") || strings.Contains(report, "缓冲区大小") {
+		t.Fatalf("报告应显示上传速度: %s", report)
+	}
+
+	e.config.WebDAV.Password = "wrong"
+	if _, err := e.engine.RunBenchmark(func(string) {}); err == nil {
+		t.Fatal("上传失败时性能测试应失败")
+	}
+}
+
+// 直接浏览 WebDAV 上的快照，只下载选中的文件还原，加密的文件和文件名在还原时解密
+func TestWebDAVRestore(t *testing.T) {
+	e := newEnv(t)
+	files := t.TempDir()
+	chunks := 0
+	server := newNextcloud(t, files, &chunks)
+	defer server.Close()
+
+	e.write("docs/a.txt", "alpha", 2*time.Hour)
+	e.write("docs/deep/b.txt", "beta", 2*time.Hour)
+	e.write("c.txt", "gamma", 2*time.Hour)
+	e.config.DestinationPath = server.URL + "/remote.php/dav/files/alice/Backups"
+	e.config.WebDAV = storage.WebDAVConfig{Username: "alice", Password: "secret"}
+	e.config.Encryption = engine.EncryptionConfig{Enabled: true, ObfuscateNames: true, Passphrase: "correct horse battery staple"}
+	record := e.mustBackup()
+	if !storage.IsWebDAV(record.DestPath) {
+		t.Fatalf("快照路径应为 WebDAV 地址: %s", record.DestPath)
+	}
+
+	tree, err := e.engine.LoadRemoteTree(record.DestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docs string
+	for _, entry := range tree.Children("") {
+		if entry.IsDir() && e.engine.SnapshotName(record, entry.Name()) == "docs" {
+			docs = entry.Name()
+		}
+	}
+	if docs == "" || len(tree.Children(docs)) != 2 {
+		t.Fatalf("远程快照的目录结构不正确: %v", tree.Children(""))
+	}
+
+	target := t.TempDir()
+	result, err := e.engine.Restore(record.DestPath, []string{docs}, target, false)
+	if err != nil || result.Files != 2 {
+		t.Fatalf("还原 %d 个文件，错误 %v", result.Files, err)
+	}
+	restored := readTree(t, target)
+	if restored["docs/a.txt"] != "alpha" || restored["docs/deep/b.txt"] != "beta" || len(restored) != 2 {
+		t.Errorf("还原结果为 %v", restored)
+	}
+	restoredInfo, err := os.Stat(filepath.Join(target, "docs", "a.txt"))
+	sourceInfo, _ := os.Stat(filepath.Join(e.source, "docs", "a.txt"))
+	if err != nil || !restoredInfo.ModTime().Equal(sourceInfo.ModTime().Truncate(time.Second)) {
+		t.Errorf("还原的修改时间为 %v，应为 %v", restoredInfo.ModTime(), sourceInfo.ModTime())
+	}
+
+	conflicts, err := e.engine.RestoreConflicts(record.DestPath, []string{"."}, target)
+	if err != nil || len(conflicts) != 2 {
+		t.Fatalf("冲突为 %v，错误 %v，应为 docs 中的 2 个文件", conflicts, err)
+	}
+}
+
+// 模拟 S3 兼容的对象存储：对象保存在内存中，支持存储类别、用户元数据、服务器端复制、批量删除和从冷存储取回。
+// minio-go 在 http 上按 aws-chunked 格式分块上传，这里只解出内容，不校验签名
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]*fakeS3Object
+}
+
+type fakeS3Object struct {
+	data     []byte
+	meta     http.Header // x-amz-meta-* 头
+	class    string
+	restore  string // x-amz-restore 头，为空表示没有请求取回
+	modified time.Time
+}
+
+// 冷存储中还没有取回的对象不能读取和复制
+func (o *fakeS3Object) frozen() bool {
+	return storage.NeedsRetrieval(o.class) && !strings.Contains(o.restore, `ongoing-request="false"`)
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	s := &fakeS3{objects: make(map[string]*fakeS3Object)}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func s3Fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "backups" {
+		s3Fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	query := r.URL.Query()
+	object := s.objects[key]
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+	case key == "" && r.Method == http.MethodGet && query.Get("list-type") == "2":
+		s.list(w, query.Get("prefix"), query.Get("metadata") == "true")
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		var request struct {
+			Objects []struct{ Key string } `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+			s3Fail(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		for _, o := range request.Objects {
+			delete(s.objects, o.Key)
+		}
+		fmt.Fprint(w, "<DeleteResult></DeleteResult>")
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		src := s.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), "backups/")]
+		switch {
+		case src == nil:
+			s3Fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		case src.frozen():
+			s3Fail(w, http.StatusForbidden, "InvalidObjectState")
+			return
+		}
+		copied := &fakeS3Object{data: src.data, meta: src.meta, class: storage.ClassStandard, modified: time.Now()}
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			copied.meta = s3Meta(r.Header)
+		}
+		if class := r.Header.Get("X-Amz-Storage-Class"); class != "" {
+			copied.class = class
+		}
+		s.objects[key] = copied
+		fmt.Fprintf(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>%s</LastModified></CopyObjectResult>`,
+			copied.modified.UTC().Format(time.RFC3339))
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err == nil && strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			data, err = decodeAWSChunked(data)
+		}
+		if err != nil {
+			s3Fail(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		class := r.Header.Get("X-Amz-Storage-Class")
+		if class == "" {
+			class = storage.ClassStandard
+		}
+		s.objects[key] = &fakeS3Object{data: data, meta: s3Meta(r.Header), class: class, modified: time.Now()}
+		w.Header().Set("ETag", `"etag"`)
+	case object == nil:
+		s3Fail(w, http.StatusNotFound, "NoSuchKey")
+	case r.Method == http.MethodPost && query.Has("restore"):
+		if !storage.NeedsRetrieval(object.class) {
+			s3Fail(w, http.StatusForbidden, "InvalidObjectState")
+			return
+		}
+		object.restore = `ongoing-request="true"`
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && object.frozen():
+		s3Fail(w, http.StatusForbidden, "InvalidObjectState")
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		for name, values := range object.meta {
+			w.Header()[name] = values
+		}
+		if object.class != storage.ClassStandard {
+			w.Header().Set("X-Amz-Storage-Class", object.class)
+		}
+		if object.restore != "" {
+			w.Header().Set("X-Amz-Restore", object.restore)
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", object.modified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+	default:
+		s3Fail(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// 按对象名顺序列出前缀下的对象，metadata 为 true 时像 MinIO 一样返回用户元数据
+func (s *fakeS3) list(w http.ResponseWriter, prefix string, metadata bool) {
+	type contents struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+		StorageClass string
+		Mtime        []string `xml:"UserMetadata>X-Amz-Meta-Mtime"`
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []contents
+	}{Name: "backups", Prefix: prefix, MaxKeys: 1000}
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		object := s.objects[key]
+		item := contents{Key: key, LastModified: object.modified.UTC().Format(time.RFC3339), ETag: `"etag"`,
+			Size: len(object.data), StorageClass: object.class}
+		if mtime := object.meta.Get("X-Amz-Meta-Mtime"); metadata && mtime != "" {
+			item.Mtime = []string{mtime}
+		}
+		result.Contents = append(result.Contents, item)
+	}
+	result.KeyCount = len(result.Contents)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// 请求中的 x-amz-meta-* 头
+func s3Meta(header http.Header) http.Header {
+	meta := make(http.Header)
+	for name, values := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-meta-") {
+			meta[name] = values
+		}
+	}
+	return meta
+}
+
+// 解出 aws-chunked 上传的内容：每块为 "十六进制长度[;chunk-signature=...]\r\n内容\r\n"，以长度 0 的块结束
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	var data []byte
+	reader := bufio.NewReader(bytes.NewReader(body))
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeText, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeText, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+// 正在进行的取回全部完成，取回的副本保留 7 天
+func (s *fakeS3) completeRestores() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry := time.Now().AddDate(0, 0, 7).UTC().Format(http.TimeFormat)
+	for _, object := range s.objects {
+		if object.restore == `ongoing-request="true"` {
+			object.restore = fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`, expiry)
+		}
+	}
+}
+
+// 前缀下各对象的存储类别，键为去掉前缀的对象名
+func (s *fakeS3) classes(prefix string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	classes := make(map[string]string)
+	for key, object := range s.objects {
+		if rel, ok := strings.CutPrefix(key, prefix); ok {
+			classes[rel] = object.class
+		}
+	}
+	return classes
+}
+
+// S3 目标中保存超过设置天数的快照在备份后转入 Glacier，较新的快照留在标准存储；
+// 冷存储中的快照要先取回，取回完成后才能还原
+func TestS3ColdStorage(t *testing.T) {
+	e := newEnv(t)
+	fake, server := newFakeS3(t)
+	e.write("a.txt", "alpha", 2*time.Hour)
+	e.write("sub/b.txt", "beta", 2*time.Hour)
+	e.config.DestinationPath = "s3://backups/laptop"
+	e.config.S3 = storage.S3Config{Endpoint: server.URL, Region: "us-east-1", AccessKey: "key", SecretKey: "secret", ColdAfterDays: 30}
+
+	old := e.mustBackup()
+	if !storage.IsS3(old.DestPath) {
+		t.Fatalf("快照路径应为 S3 地址: %s", old.DestPath)
+	}
+	oldPrefix := "laptop/" + filepath.Base(old.DestPath) + "/"
+	if classes := fake.classes(oldPrefix); len(classes) != 2 || classes["a.txt"] != storage.ClassStandard || classes["sub/b.txt"] != storage.ClassStandard {
+		t.Fatalf("新快照应在标准存储中: %v", classes)
+	}
+
+	// 第一个快照已保存 40 天，下次备份后转入冷存储
+	e.config.History[0].Timestamp = e.config.History[0].Timestamp.AddDate(0, 0, -40)
+	e.write("a.txt", "alpha 2", time.Hour)
+	recent := e.mustBackup()
+	old = e.config.History[0]
+	if !old.ColdStorage || e.config.History[1].ColdStorage {
+		t.Fatalf("只有旧快照应标记为冷存储: %v %v", old.ColdStorage, e.config.History[1].ColdStorage)
+	}
+	if classes := fake.classes(oldPrefix); len(classes) != 2 || classes["a.txt"] != storage.ClassGlacier || classes["sub/b.txt"] != storage.ClassGlacier {
+		t.Fatalf("旧快照应转入 Glacier: %v", classes)
+	}
+	if classes := fake.classes("laptop/" + filepath.Base(recent.DestPath) + "/"); classes["a.txt"] != storage.ClassStandard {
+		t.Fatalf("新快照应留在标准存储中: %v", classes)
+	}
+	files, err := e.engine.LoadRemoteTree(old.DestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceInfo, _ := os.Stat(filepath.Join(e.source, "sub", "b.txt"))
+	if children := files.Children("sub"); len(children) != 1 || !children[0].ModTime().Equal(sourceInfo.ModTime().Truncate(time.Second)) {
+		t.Fatalf("转入冷存储后修改时间应保留: %v", children)
+	}
+
+	// 取回前不能还原
+	target := t.TempDir()
+	if _, err := e.engine.Restore(old.DestPath, []string{"."}, target, false); err == nil || !strings.Contains(err.Error(), storage.ErrColdStorage.Error()) {
+		t.Fatalf("取回前还原应失败: %v", err)
+	}
+	status, err := e.engine.SnapshotRetrieval(old)
+	if err != nil || status.Class != storage.ClassGlacier || status.Files != 2 || status.Pending != 2 || status.Complete() {
+		t.Fatalf("取回状态为 %+v，错误 %v", status, err)
+	}
+
+	count, err := e.engine.RetrieveSnapshot(old, storage.RetrievalBulk, 7)
+	if err != nil || count != 2 {
+		t.Fatalf("请求取回 %d 个文件，错误 %v", count, err)
+	}
+	if count, err := e.engine.RetrieveSnapshot(old, storage.RetrievalBulk, 7); err != nil || count != 0 {
+		t.Fatalf("正在取回的文件不应重复请求: %d %v", count, err)
+	}
+	if status, err := e.engine.SnapshotRetrieval(old); err != nil || status.Restoring != 2 || status.Complete() {
+		t.Fatalf("取回状态为 %+v，错误 %v", status, err)
+	}
+
+	fake.completeRestores()
+	status, err = e.engine.SnapshotRetrieval(old)
+	if err != nil || status.Ready != 2 || !status.Complete() || !status.Expires.After(time.Now()) {
+		t.Fatalf("取回状态为 %+v，错误 %v", status, err)
+	}
+	result, err := e.engine.Restore(old.DestPath, []string{"."}, target, false)
+	if err != nil || result.Files != 2 {
+		t.Fatalf("还原 %d 个文件，错误 %v", result.Files, err)
+	}
+	if restored := readTree(t, target); restored["a.txt"] != "alpha" || restored["sub/b.txt"] != "beta" {
+		t.Errorf("还原结果为 %v", restored)
+	}
+
+	if err := storage.NewS3(e.config.DestinationPath, e.config.S3).RemoveAll(old.DestPath); err != nil {
+		t.Fatal(err)
+	}
+	if classes := fake.classes(oldPrefix); len(classes) != 0 {
+		t.Fatalf("删除后仍有对象: %v", classes)
+	}
+}
+
+// 源文件夹中的 .gitignore 和 .syncsafeignore 在开启后生效，任务的规则可以重新包含
+func TestIgnoreFiles(t *testing.T) {
+	e := newEnv(t)
+	e.write(".gitignore", "node_modules/\n/build\n*.log\n", time.Hour)
+	e.write(".syncsafeignore", "secret.txt\n", time.Hour)
+	e.write("main.go", "main", time.Hour)
+	e.write("node_modules/pkg/index.js", "js", time.Hour)
+	e.write("build/out.bin", "bin", time.Hour)
+	e.write("docs/build/page.html", "page", time.Hour)
+	e.write("debug.log", "log", time.Hour)
+	e.write("keep.log", "keep", time.Hour)
+	e.write("secret.txt", "secret", time.Hour)
+
+	record := e.mustBackup()
+	if got := readTree(t, record.DestPath); len(got) != 9 {
+		t.Fatalf("没有开启时应备份所有文件，实际 %d 个", len(got))
+	}
+
+	e.config.UseIgnoreFiles = true
+	e.config.FilterRules = []string{"!keep.log"}
+	record = e.mustBackup()
+	got := readTree(t, record.DestPath)
+	for _, name := range []string{".gitignore", ".syncsafeignore", "main.go", "docs/build/page.html", "keep.log"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s 应被备份", name)
+		}
+	}
+	if len(got) != 5 {
+		t.Errorf("应只备份 5 个文件，实际: %v", got)
+	}
+}
+
+func TestMirrorTrash(t *testing.T) {
+	e := newEnv(t)
+	e.config.QuickSync = true
+	e.config.MirrorTrash = true
+	e.write("a.txt", "a", time.Hour)
+	e.write("docs/b.txt", "b", time.Hour)
+	e.mustBackup()
+
+	// 删除的文件从镜像移到回收文件夹，保留相对路径
+	if err := os.Remove(filepath.Join(e.source, "docs", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	e.mustBackup()
+	mirror := readTree(t, filepath.Join(e.dest, "source-mirror"))
+	if _, ok := mirror["docs/b.txt"]; ok || mirror["a.txt"] != "a" {
+		t.Fatalf("镜像中的文件不正确: %v", mirror)
+	}
+	trashed, err := filepath.Glob(filepath.Join(e.dest, "source-mirror-trash", "*", "docs", "b.txt"))
+	if err != nil || len(trashed) != 1 {
+		t.Fatalf("回收文件夹中应有 docs/b.txt，实际: %v", trashed)
+	}
+	if content, _ := os.ReadFile(trashed[0]); string(content) != "b" {
+		t.Fatalf("回收文件夹中 docs/b.txt 的内容为 %q", content)
+	}
+}
+
+func TestTwoWaySync(t *testing.T) {
+	e := newEnv(t)
+	peerDir := filepath.Join(t.TempDir(), "peer")
+	writePeer := func(relPath, content string, age time.Duration) {
+		t.Helper()
+		path := filepath.Join(peerDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.config.SyncPath = peerDir
+	e.write("a.txt", "a", 3*time.Hour)
+	e.write("docs/b.txt", "b", 3*time.Hour)
+	writePeer("c.txt", "c", 3*time.Hour)
+
+	// 第一次同步合并两侧的文件，快照中包含同步后的源文件夹
+	e.mustBackup()
+	want := map[string]string{"a.txt": "a", "docs/b.txt": "b", "c.txt": "c"}
+	if got := readTree(t, peerDir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("同步文件夹中的文件不正确: %v", got)
+	}
+	if got := readTree(t, e.source); !reflect.DeepEqual(got, want) {
+		t.Fatalf("源文件夹中的文件不正确: %v", got)
+	}
+
+	// 一侧的修改和删除传到另一侧
+	e.write("a.txt", "a2", 2*time.Hour)
+	if err := os.Remove(filepath.Join(peerDir, "docs", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	e.mustBackup()
+	want = map[string]string{"a.txt": "a2", "c.txt": "c"}
+	if got := readTree(t, peerDir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("同步文件夹中的文件不正确: %v", got)
+	}
+	if got := readTree(t, e.source); !reflect.DeepEqual(got, want) {
+		t.Fatalf("源文件夹中的文件不正确: %v", got)
+	}
+
+	// 两侧都修改时默认等待手动处理，两侧保持不变
+	e.write("a.txt", "local", time.Hour)
+	writePeer("a.txt", "remote", 30*time.Minute)
+	e.mustBackup()
+	conflicts := e.engine.SyncConflicts()
+	if len(conflicts) != 1 || conflicts[0].RelPath != "a.txt" {
+		t.Fatalf("应有 a.txt 一个冲突: %v", conflicts)
+	}
+	if content, _ := os.ReadFile(filepath.Join(peerDir, "a.txt")); string(content) != "remote" {
+		t.Fatalf("处理冲突之前同步文件夹不应改动，a.txt 的内容为 %q", content)
+	}
+
+	// 两个都保留：同步文件夹中的版本成为冲突副本，两侧一致
+	if err := e.engine.ResolveSyncConflict("a.txt", engine.ResolveBoth); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts := e.engine.SyncConflicts(); len(conflicts) != 0 {
+		t.Fatalf("冲突处理后仍有冲突: %v", conflicts)
+	}
+	local, peer := readTree(t, e.source), readTree(t, peerDir)
+	if !reflect.DeepEqual(local, peer) || local["a.txt"] != "local" || len(local) != 3 {
+		t.Fatalf("两侧的文件不一致，本机: %v，同步文件夹: %v", local, peer)
+	}
+	for relPath, content := range local {
+		if strings.Contains(relPath, "冲突副本") && content != "remote" {
+			t.Fatalf("冲突副本 %s 的内容为 %q", relPath, content)
+		}
+	}
+
+	// 保留较新的版本时自动处理
+	e.config.SyncConflicts = engine.ConflictNewest
+	e.write("c.txt", "old", 20*time.Minute)
+	writePeer("c.txt", "new", 10*time.Minute)
+	e.mustBackup()
+	if content, _ := os.ReadFile(filepath.Join(e.source, "c.txt")); string(content) != "new" {
+		t.Fatalf("应保留较新的版本，c.txt 的内容为 %q", content)
+	}
+	if conflicts := e.engine.SyncConflicts(); len(conflicts) != 0 {
+		t.Fatalf("不应留下冲突: %v", conflicts)
+	}
+}
+
+func TestSharedPool(t *testing.T) {
+	e := newEnv(t)
+	e.config.SharedPool = true
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(e.source, "assets", "logo.png"), "shared asset")
+	writeFile(filepath.Join(e.source, "a.txt"), "only in a")
+
+	// 另一个任务备份到同一个目标文件夹，共享同一个素材
+	otherSource := filepath.Join(t.TempDir(), "project-b")
+	writeFile(filepath.Join(otherSource, "assets", "logo.png"), "shared asset")
+	writeFile(filepath.Join(otherSource, "b.txt"), "only in b")
+	other := engine.NewConfig()
+	other.SourcePath = otherSource
+	other.DestinationPath = e.dest
+	other.SharedPool = true
+	otherEngine := engine.New(other, engine.Hooks{})
+
+	first := e.mustBackup()
+	otherRecord, err := otherEngine.Backup(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.History = append(other.History, *otherRecord)
+	if first.PooledFiles != 0 || otherRecord.PooledFiles != 1 {
+		t.Fatalf("第二个任务应从存储池链接 1 个文件，实际 %d、%d", first.PooledFiles, otherRecord.PooledFiles)
+	}
+	a, err := os.Stat(filepath.Join(first.DestPath, "assets", "logo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.Stat(filepath.Join(otherRecord.DestPath, "assets", "logo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(a, b) || !b.ModTime().Equal(modTime) {
+		t.Fatal("两个任务的相同文件应只保存一份并保留修改时间")
+	}
+	if got := readTree(t, otherRecord.DestPath); got["assets/logo.png"] != "shared asset" || got["b.txt"] != "only in b" {
+		t.Fatalf("快照内容不正确: %v", got)
+	}
+
+	// 清理一个任务的快照时，仍被另一个任务引用的文件保留在池中
+	objectCount := func() int {
+		return len(readTree(t, filepath.Join(e.dest, "syncsafe-pool")))
+	}
+	if count := objectCount(); count != 3 {
+		t.Fatalf("存储池中应有 3 个文件，实际 %d 个", count)
+	}
+	if _, err := e.engine.PruneSnapshots([]history.Record{first}); err != nil {
+		t.Fatal(err)
+	}
+	if count := objectCount(); count != 2 {
+		t.Fatalf("清理第一个任务的快照后存储池中应有 2 个文件，实际 %d 个", count)
+	}
+	if _, err := otherEngine.PruneSnapshots([]history.Record{*otherRecord}); err != nil {
+		t.Fatal(err)
+	}
+	if count := objectCount(); count != 0 {
+		t.Fatalf("清理所有快照后存储池应为空，实际 %d 个", count)
+	}
+}
+
+func TestVerifyAfterBackup(t *testing.T) {
+	e := newEnv(t)
+	e.config.VerifyAfterBackup = true
+	e.write("a.txt", "alpha", time.Hour)
+	e.write("docs/b.txt", "beta", time.Hour)
+	if record := e.mustBackup(); !record.Verified || len(record.Mismatches) != 0 {
+		t.Fatalf("备份后校验应通过: %v", record.Mismatches)
+	}
+
+	// 写入的内容静默损坏时备份仍然成功，不一致的文件记录在历史中
+	faults.Set(map[faults.Kind]float64{faults.Corrupt: 1})
+	e.write("a.txt", "alpha2", 0)
+	record := e.mustBackup()
+	if record.Verified || len(record.Mismatches) != 2 {
+		t.Fatalf("应发现 2 个不一致的文件: %v", record.Mismatches)
+	}
+}
+
+// 复查队列：备份中的问题按路径记录，重试后重新复制，忽略后添加排除规则，不再出现的问题自动移出
+func TestReviewQueue(t *testing.T) {
+	e := newEnv(t)
+	e.config.Incremental = true
+	e.config.VerifyAfterBackup = true
+	e.write("a.txt", "lower", time.Hour)
+	e.write("A.txt", "upper", time.Hour)
+	e.write("b.txt", "beta", time.Hour)
+	find := func(kind string) (engine.ReviewItem, bool) {
+		for _, item := range e.config.Review {
+			if item.Kind == kind {
+				return item, true
+			}
+		}
+		return engine.ReviewItem{}, false
+	}
+	e.mustBackup()
+	if collision, ok := find(engine.IssueCollision); !ok || collision.Path != "a.txt" || len(e.config.Review) != 1 {
+		t.Fatalf("应记录大小写冲突: %+v", e.config.Review)
+	}
+
+	// 静默损坏的副本在之后的增量快照中被硬链接，问题一直存在，直到重试时重新复制
+	faults.Set(map[faults.Kind]float64{faults.Corrupt: 1})
+	e.write("b.txt", "beta2", 0)
+	e.mustBackup()
+	faults.Set(nil)
+	e.mustBackup()
+	mismatch, ok := find(engine.IssueMismatch)
+	if !ok || mismatch.Path != "b.txt" || mismatch.Count != 2 {
+		t.Fatalf("应记录两次校验不一致: %+v", e.config.Review)
+	}
+	e.engine.RetryReview(mismatch)
+	if record := e.mustBackup(); !record.Verified {
+		t.Fatalf("重试后校验应通过: %v", record.Mismatches)
+	}
+	if _, ok := find(engine.IssueMismatch); ok {
+		t.Fatalf("重试成功后应移出队列: %+v", e.config.Review)
+	}
+
+	collision, _ := find(engine.IssueCollision)
+	e.config.IgnoreReview(collision)
+	if !slices.Contains(e.config.FilterRules, "/a.txt") || len(e.config.Review) != 0 {
+		t.Fatalf("忽略后应添加排除规则: %v %+v", e.config.FilterRules, e.config.Review)
+	}
+	record := e.mustBackup()
+	if got := readTree(t, record.DestPath); got["a.txt"] != "" || got["A.txt"] != "upper" || len(e.config.Review) != 0 {
+		t.Fatalf("忽略的文件不应备份: %v %+v", got, e.config.Review)
+	}
+}
+
+func TestRollbackBeforeUpgrade(t *testing.T) {
+	e := newEnv(t)
+	oldVersion := version.Version
+	t.Cleanup(func() { version.Version = oldVersion })
+	loadSource := func() string {
+		t.Helper()
+		profiles, err := engine.LoadProfiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return profiles.List[0].SourcePath
+	}
+
+	e.config.SourcePath = "v1"
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	version.Version = "1.0"
+	loadSource()
+	version.Version = "2.0"
+	loadSource()
+	if source := loadSource(); source != "v1" {
+		t.Fatalf("升级不应修改配置: %s", source)
+	}
+	rollbacks, err := engine.ListRollbacks()
+	if err != nil || len(rollbacks) != 2 || rollbacks[0].AppVersion != "1.0" {
+		t.Fatalf("每次升级前应保存一个回滚包: %v %v", rollbacks, err)
+	}
+
+	// 新版本迁移后的配置可以还原到升级前的状态，还原前的配置也保存为回滚包
+	e.config.SourcePath = "v2"
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RevertRollback(rollbacks[0]); err != nil {
+		t.Fatal(err)
+	}
+	version.Version = "1.0"
+	if source := loadSource(); source != "v1" {
+		t.Fatalf("还原后的源文件夹应为 v1: %s", source)
+	}
+	if rollbacks, _ := engine.ListRollbacks(); len(rollbacks) != 3 {
+		t.Fatalf("还原前应保存当前配置: %v", rollbacks)
+	}
+}
+
+func TestHistoryStoreMigration(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a", time.Hour)
+	first := e.mustBackup()
+	e.write("a.txt", "a2", 0)
+	e.mustBackup()
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟旧版本：历史记录保存在本机配置中，没有数据库
+	machineFiles, _ := filepath.Glob(filepath.Join(engine.DataDir, "machines", "*.json"))
+	dbFiles, _ := filepath.Glob(filepath.Join(engine.DataDir, "machines", "*.history.db"))
+	if len(machineFiles) != 1 || len(dbFiles) != 1 {
+		t.Fatalf("应有一个本机配置和一个历史记录数据库: %v %v", machineFiles, dbFiles)
+	}
+	var local map[string]interface{}
+	data, _ := os.ReadFile(machineFiles[0])
+	json.Unmarshal(data, &local)
+	if _, ok := local["History"]; ok {
+		t.Fatal("本机配置中不应再保存历史记录")
+	}
+	local["History"] = e.config.History
+	data, _ = json.Marshal(local)
+	os.WriteFile(machineFiles[0], data, 0600)
+	os.Remove(dbFiles[0])
+
+	config, err := engine.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.History) != 2 || !config.History[0].Same(first) {
+		t.Fatalf("迁移后应有 2 条历史记录: %+v", config.History)
+	}
+	data, _ = os.ReadFile(machineFiles[0])
+	if strings.Contains(string(data), `"History"`) {
+		t.Error("迁移后应从本机配置中删除历史记录")
+	}
+
+	// 按源文件夹查询使用索引，删除的记录同时从数据库中删除
+	if err := config.RemoveHistory([]history.Record{first}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := config.QueryHistory(history.Filter{Source: e.source})
+	if err != nil || len(records) != 1 || records[0].Same(first) || len(config.History) != 1 {
+		t.Fatalf("查询结果应只有第二次备份: %+v %v", records, err)
+	}
+	if records, _ := config.QueryHistory(history.Filter{Source: "/other"}); len(records) != 0 {
+		t.Errorf("其他源文件夹不应有记录: %+v", records)
+	}
+
+	// 另一个进程（例如计划任务运行的命令行）写入的记录，不会被本进程保存配置时删除
+	other, err := engine.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.write("a.txt", "a3", 0)
+	third, err := engine.New(other, engine.Hooks{}).Backup(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.History = append(other.History, *third)
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+	config.IgnoreSizeSwings = true
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	records, _ = config.QueryHistory(history.Filter{})
+	if len(records) != 2 || !records[1].Same(*third) {
+		t.Fatalf("其他进程写入的记录应保留: %+v", records)
+	}
+}
+
+func TestScrubRepairsFromOtherSnapshot(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "alpha", 3*time.Hour)
+	e.write("b.txt", "beta", 3*time.Hour)
+	first := e.mustBackup()
+	second := e.mustBackup()
+
+	scrub := func() engine.ScrubResult {
+		t.Helper()
+		result, err := e.engine.Scrub(context.Background(), e.config.History, nil)
+		if err != nil {
+			t.Fatalf("数据巡检失败: %v", err)
+		}
+		return result
+	}
+	// 第一次巡检建立哈希记录
+	if result := scrub(); !result.OK() || result.Files != 4 {
+		t.Fatalf("第一次巡检: %v", result)
+	}
+
+	// 模拟静默损坏：内容变化，大小和修改时间不变
+	corrupt := func(path, content string) {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corrupt(filepath.Join(second.DestPath, "a.txt"), "alphX")
+	result := scrub()
+	if !result.OK() || len(result.Repaired) != 1 {
+		t.Fatalf("应修复 1 个文件: %v %v", result, result.Unrepairable)
+	}
+	if got := readTree(t, second.DestPath)["a.txt"]; got != "alpha" {
+		t.Fatalf("修复后的内容为 %q", got)
+	}
+
+	// 两个快照中的副本都损坏且源文件已修改，无法修复，再次巡检仍能发现
+	corrupt(filepath.Join(first.DestPath, "b.txt"), "bexa")
+	corrupt(filepath.Join(second.DestPath, "b.txt"), "betX")
+	e.write("b.txt", "beta2", time.Hour)
+	for i := 0; i < 2; i++ {
+		if result := scrub(); len(result.Unrepairable) != 2 || len(result.Repaired) != 0 {
+			t.Fatalf("第 %d 次: 应有 2 个无法修复的文件: %v %v", i+1, result, result.Unrepairable)
+		}
+	}
+}
+
+func TestEncryptedBackup(t *testing.T) {
+	e := newEnv(t)
+	e.config.Encryption = engine.EncryptionConfig{Enabled: true, ObfuscateNames: true, Passphrase: "correct horse battery staple"}
+	e.write("docs/secret.txt", "top secret plans", 2*time.Hour)
+	e.write("empty.txt", "", 2*time.Hour)
+	record := e.mustBackup()
+	if !record.Encrypted || !record.EncryptedNames {
+		t.Fatalf("记录应标记为已加密: %+v", record)
+	}
+
+	// 目标中既看不到明文内容，也看不到原始文件名
+	stored := readTree(t, record.DestPath)
+	if len(stored) != 2 {
+		t.Fatalf("快照中有 %d 个文件，应为 2", len(stored))
+	}
+	for name, content := range stored {
+		if strings.Contains(name, "secret") || strings.Contains(name, "docs") || strings.Contains(content, "top secret") {
+			t.Errorf("快照中出现明文: %s", name)
+		}
+	}
+	if params, err := os.ReadFile(filepath.Join(e.dest, ".syncsafe-encryption.json")); err != nil {
+		t.Errorf("目标中没有加密参数文件: %v", err)
+	} else if !strings.Contains(string(params), `"argon2id"`) {
+		t.Errorf("密钥应由 Argon2id 派生: %s", params)
+	}
+
+	// 还原时解密内容和文件名
+	target := t.TempDir()
+	result, err := e.engine.Restore(record.DestPath, []string{"."}, target, false)
+	if err != nil || result.Files != 2 {
+		t.Fatalf("还原 %d 个文件，错误 %v", result.Files, err)
+	}
+	restored := readTree(t, target)
+	if restored["docs/secret.txt"] != "top secret plans" || restored["empty.txt"] != "" || len(restored) != 2 {
+		t.Errorf("还原结果为 %v", restored)
+	}
+	if result, err := e.engine.VerifySnapshot(record); err != nil || !result.OK() || result.Files != 2 {
+		t.Errorf("校验结果为 %+v，错误 %v", result, err)
+	}
+
+	// 密码短语错误时拒绝还原，也拒绝继续备份到同一目标
+	wrong := *e.config
+	wrong.Encryption.Passphrase = "wrong"
+	other := engine.New(&wrong, engine.Hooks{})
+	if _, err := other.Restore(record.DestPath, []string{"."}, t.TempDir(), false); err == nil {
+		t.Error("密码短语错误时还原应失败")
+	}
+	if _, err := other.Backup(context.Background(), 0); err == nil {
+		t.Error("密码短语错误时备份应失败")
+	}
+}
+
+func TestSnapshotBrowser(t *testing.T) {
+	const passphrase = "correct horse battery staple"
+	for _, mode := range []string{"plain", "encrypted", storage.ArchiveZip} {
+		t.Run(mode, func(t *testing.T) {
+			e := newEnv(t)
+			switch mode {
+			case "encrypted":
+				e.config.Encryption = engine.EncryptionConfig{Enabled: true, ObfuscateNames: true, Passphrase: passphrase}
+			case storage.ArchiveZip:
+				e.config.ArchiveFormat = storage.ArchiveZip
+			}
+			e.write("notes.txt", strings.Repeat("0123456789", 100), 2*time.Hour)
+			e.write("docs/a.txt", "alpha", 2*time.Hour)
+			record := e.mustBackup()
+
+			browser, err := e.engine.BrowseSnapshot(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 目录排在文件之前，显示解密后的名称和原始大小
+			root, err := browser.List("")
+			if err != nil || len(root) != 2 {
+				t.Fatalf("根目录内容为 %+v，错误 %v", root, err)
+			}
+			if !root[0].IsDir || root[0].Name != "docs" || root[1].Name != "notes.txt" || root[1].Size != 1000 {
+				t.Errorf("根目录内容为 %+v", root)
+			}
+			docs, err := browser.List(root[0].Path)
+			if err != nil || len(docs) != 1 || docs[0].Name != "a.txt" || docs[0].Size != 5 {
+				t.Fatalf("docs 目录内容为 %+v，错误 %v", docs, err)
+			}
+
+			// 预览只读取开头，内容不完整时标记截断
+			data, truncated, err := browser.Preview(root[1].Path, 64)
+			if err != nil || !truncated || string(data) != strings.Repeat("0123456789", 7)[:64] {
+				t.Errorf("预览内容为 %q，截断 %v，错误 %v", data, truncated, err)
+			}
+			data, truncated, err = browser.Preview(docs[0].Path, 64)
+			if err != nil || truncated || string(data) != "alpha" {
+				t.Errorf("预览内容为 %q，截断 %v，错误 %v", data, truncated, err)
+			}
+			if _, _, err := browser.Preview("missing.txt", 64); err == nil {
+				t.Error("预览不存在的文件应失败")
+			}
+		})
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		t.Run(fmt.Sprint("encrypted=", encrypted), func(t *testing.T) {
+			e := newEnv(t)
+			if encrypted {
+				e.config.Encryption = engine.EncryptionConfig{Enabled: true, ObfuscateNames: true, Passphrase: "correct horse battery staple"}
+			}
+			e.write("docs/notes.txt", "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n", 3*time.Hour)
+			e.write("gone.txt", "bye", 3*time.Hour)
+			e.write("same.txt", "same", 3*time.Hour)
+			first := e.mustBackup()
+
+			e.write("docs/notes.txt", "one\ntwo\nthree\nfour\nFIVE\nsix\nseven\neight\nnine\n", time.Hour)
+			if err := os.Remove(filepath.Join(e.source, "gone.txt")); err != nil {
+				t.Fatal(err)
+			}
+			e.write("added.txt", "hello", time.Hour)
+			second := e.mustBackup()
+
+			// 新增、修改和删除的文件按路径顺序列出，没有变化的文件不列出
+			diff, err := e.engine.DiffSnapshots(first, second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(diff))
+			for i, entry := range diff {
+				got[i] = fmt.Sprintf("%s %s %d", entry.Op, filepath.ToSlash(entry.Path), entry.SizeDelta())
+			}
+			want := []string{"new added.txt 5", "modified docs/notes.txt 5", "deleted gone.txt -3"}
+			if !slices.Equal(got, want) {
+				t.Fatalf("差异为 %v，应为 %v", got, want)
+			}
+
+			text, err := e.engine.TextDiff(first, second, diff[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, hunks, _ := strings.Cut(text, "@@")
+			wantHunks := " -2,7 +2,8 @@\n two\n three\n four\n-five\n+FIVE\n six\n seven\n eight\n+nine\n"
+			if hunks != wantHunks {
+				t.Errorf("逐行差异为\n%s\n应为\n@@%s", text, wantHunks)
+			}
+			if text, err := e.engine.TextDiff(first, second, diff[2]); err != nil || !strings.HasSuffix(text, "@@ -1 +0,0 @@\n-bye\n\\ No newline at end of file\n") {
+				t.Errorf("删除的文件的差异为 %q，错误 %v", text, err)
+			}
+		})
+	}
+}
+
+// 在所有快照中查找文件，列出每个版本，并能还原其中较旧的版本
+func TestFindFile(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		t.Run(fmt.Sprint("encrypted=", encrypted), func(t *testing.T) {
+			e := newEnv(t)
+			if encrypted {
+				e.config.Encryption = engine.EncryptionConfig{Enabled: true, ObfuscateNames: true, Passphrase: "correct horse battery staple"}
+			}
+			e.write("docs/Report.docx", "first draft", 3*time.Hour)
+			e.write("notes.txt", "notes", 3*time.Hour)
+			e.mustBackup()
+			e.write("notes.txt", "more notes", 2*time.Hour)
+			second := e.mustBackup()
+			e.write("docs/Report.docx", "final version", time.Hour)
+			third := e.mustBackup()
+
+			// 通配符只匹配文件名，不区分大小写；同一版本只列一次，记录包含它的最新快照
+			versions, err := e.config.FindFile("report*", 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(versions) != 2 || filepath.ToSlash(versions[0].Path) != "docs/Report.docx" {
+				t.Fatalf("应找到 2 个版本，实际 %+v", versions)
+			}
+			if versions[0].Size != int64(len("final version")) || !versions[0].Record.Same(third) || versions[0].Snapshots != 1 {
+				t.Fatalf("最新版本不正确: %+v", versions[0])
+			}
+			old := versions[1]
+			if old.Size != int64(len("first draft")) || !old.Record.Same(second) || old.Snapshots != 2 {
+				t.Fatalf("旧版本应出现在前两个快照中，最新的是第二个: %+v", old)
+			}
+
+			// 没有通配符时在路径中查找
+			if versions, _ := e.config.FindFile("DOCS", 100); len(versions) != 2 {
+				t.Fatalf("按路径查找应找到 2 个版本，实际 %d 个", len(versions))
+			}
+			if _, err := e.config.FindFile("[", 100); err == nil {
+				t.Fatal("通配符格式不正确时应返回错误")
+			}
+
+			// 还原旧版本到其他位置
+			relPath, err := e.engine.SnapshotPath(old.Record, old.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target := t.TempDir()
+			if _, err := e.engine.Restore(old.Record.DestPath, []string{relPath}, target, false); err != nil {
+				t.Fatal(err)
+			}
+			if tree := readTree(t, target); tree["docs/Report.docx"] != "first draft" {
+				t.Fatalf("还原的旧版本内容不正确: %v", tree)
+			}
+		})
+	}
+}
+
+// 附加目标按各自的格式和加密设置保存快照的完整副本，一个目标失败不影响其他目标和备份结果
+func TestReplicas(t *testing.T) {
+	const passphrase = "correct horse battery staple"
+	e := newEnv(t)
+	var statuses []string
+	e.engine = engine.New(e.config, engine.Hooks{Status: func(message string) { statuses = append(statuses, message) }})
+	e.config.Encryption = engine.EncryptionConfig{Enabled: true, ObfuscateNames: true, Passphrase: passphrase}
+	e.write("docs/a.txt", "alpha", 2*time.Hour)
+	e.write("b.txt", "beta", 2*time.Hour)
+	if err := os.MkdirAll(filepath.Join(e.source, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	blocker := filepath.Join(root, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain, archived, encrypted := filepath.Join(root, "plain"), filepath.Join(root, "archived"), filepath.Join(root, "encrypted")
+	e.config.Replicas = []engine.Replica{
+		{Path: filepath.Join(blocker, "broken")},
+		{Path: plain},
+		{Path: archived, ArchiveFormat: storage.ArchiveZip, Encrypt: true},
+		{Path: encrypted, Encrypt: true},
+		{Path: filepath.Join(root, "disabled"), Disabled: true},
+	}
+	record := e.mustBackup()
+	if record.ErrorMessage != "" {
+		t.Fatalf("附加目标失败不应影响备份结果: %s", record.ErrorMessage)
+	}
+	name := filepath.Base(record.DestPath)
+
+	// 主目标加密，普通目录的副本是解密后的原始文件，空目录同样保留
+	if got := readTree(t, filepath.Join(plain, name)); got["docs/a.txt"] != "alpha" || got["b.txt"] != "beta" || len(got) != 2 {
+		t.Errorf("目录副本为 %v", got)
+	}
+	if info, err := os.Stat(filepath.Join(plain, name, "empty")); err != nil || !info.IsDir() {
+		t.Errorf("目录副本中没有空目录，错误 %v", err)
+	}
+
+	// 加密的 zip 副本可以用同一个密码短语还原
+	target := t.TempDir()
+	if _, err := e.engine.Restore(filepath.Join(archived, name+".zip"), []string{"."}, target, false); err != nil {
+		t.Fatalf("还原 zip 副本失败: %v", err)
+	}
+	if got := readTree(t, target); got["docs/a.txt"] != "alpha" || got["b.txt"] != "beta" {
+		t.Errorf("zip 副本还原结果为 %v", got)
+	}
+
+	// 加密的目录副本中看不到明文，目标中有自己的加密参数
+	stored := readTree(t, filepath.Join(encrypted, name))
+	if len(stored) != 2 {
+		t.Errorf("加密副本中有 %d 个文件，应为 2", len(stored))
+	}
+	for path, content := range stored {
+		if strings.Contains(path, "docs") || strings.Contains(content, "alpha") {
+			t.Errorf("加密副本中出现明文: %s", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(encrypted, ".syncsafe-encryption.json")); err != nil {
+		t.Errorf("加密副本的目标中没有加密参数文件: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "disabled")); !os.IsNotExist(err) {
+		t.Error("停用的附加目标不应写入")
+	}
+	if !slices.ContainsFunc(statuses, func(status string) bool { return strings.Contains(status, blocker) }) {
+		t.Errorf("状态中没有提示附加目标失败: %v", statuses)
+	}
+}
+
+func TestArchiveBackup(t *testing.T) {
+	for _, format := range []string{storage.ArchiveTarGz, storage.ArchiveZip} {
+		t.Run(format, func(t *testing.T) {
+			e := newEnv(t)
+			e.config.ArchiveFormat = format
+			e.write("docs/a.txt", "alpha", 2*time.Hour)
+			e.write("docs/deep/b.txt", "beta", 2*time.Hour)
+			e.write("c.txt", "gamma", 2*time.Hour)
+			record := e.mustBackup()
+
+			info, err := os.Stat(record.DestPath)
+			if err != nil || !info.Mode().IsRegular() || !strings.HasSuffix(record.DestPath, "."+format) {
+				t.Fatalf("快照应为 %s 文件: %s，错误 %v", format, record.DestPath, err)
+			}
+			if record.FileCount != 3 {
+				t.Errorf("记录了 %d 个文件，应为 3", record.FileCount)
+			}
+
+			tree, err := e.engine.LoadArchiveTree(record.DestPath)
+			if err != nil || !tree.IsDir("docs") || len(tree.Children("docs")) != 2 {
+				t.Fatalf("归档目录结构不正确，错误 %v", err)
+			}
+
+			// 只还原选中的目录
+			target := t.TempDir()
+			result, err := e.engine.Restore(record.DestPath, []string{"docs"}, target, false)
+			if err != nil || result.Files != 2 {
+				t.Fatalf("还原 %d 个文件，错误 %v", result.Files, err)
+			}
+			restored := readTree(t, target)
+			if restored["docs/a.txt"] != "alpha" || restored["docs/deep/b.txt"] != "beta" || len(restored) != 2 {
+				t.Errorf("还原结果为 %v", restored)
+			}
+			restoredInfo, err := os.Stat(filepath.Join(target, "docs", "a.txt"))
+			sourceInfo, _ := os.Stat(filepath.Join(e.source, "docs", "a.txt"))
+			if err != nil || !restoredInfo.ModTime().Equal(sourceInfo.ModTime().Truncate(time.Second)) {
+				t.Errorf("还原的修改时间为 %v，应为 %v", restoredInfo.ModTime(), sourceInfo.ModTime())
+			}
+		})
+	}
+}
+
+// libarchive 3.7.7 生成的 WinZip AES-256 条目（bsdtar --options zip:encryption=aes256），
+// 作为已知答案：SyncSafe 必须能解密其他工具生成的加密 zip，并发现错误的密码和被篡改的数据
+const zipAESVector = `UEsDBBQACQBjAIIYIlgAAAAAAAAAAAAAAAAJACsAaGVsbG8udHh0dXgLAAEEAAAAAAQAAAAAAZkH
+AAEAQUUDCABVVA0AByV9k2UlfZNlM2XSasw8kxVBEULVoluadNTNkBvZ8P9ZSMb+omA8N0KfjHW9
+AphoRCEDSsqvLNBkRt7ipCBYVfgv/0nWvOeKD/PmUEsHCDp8+MI+AAAAIAAAAFBLAQIUAxQACQBj
+AIIYIlg6fPjCPgAAACAAAAAJACMAAAAAAAAAAACkgQAAAABoZWxsby50eHR1eAsAAQQAAAAABAAA
+AAABmQcAAQBBRQMIAFVUBQABJX2TZVBLBQYAAAAAAQABAFoAAACgAAAAAAA=`
+
+func TestZipAESKnownAnswer(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(zipAESVector, "\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(data []byte, password string) (string, error) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return "", err
+		}
+		if len(zr.File) != 1 || zr.File[0].Name != "hello.txt" || !crypt.IsZipAES(zr.File[0]) {
+			t.Fatalf("测试向量的条目不正确: %+v", zr.File)
+		}
+		aes, err := crypt.NewZipAESReader(bytes.NewReader(data), int64(len(data)), password)
+		if err != nil {
+			return "", err
+		}
+		r, err := aes.Open(0)
+		if err != nil {
+			return "", err
+		}
+		content, err := io.ReadAll(r)
+		return string(content), err
+	}
+
+	if content, err := open(data, "correct horse battery staple"); err != nil || content != "SyncSafe WinZip AES test vector\n" {
+		t.Fatalf("解密结果为 %q，错误 %v", content, err)
+	}
+	if _, err := open(data, "wrong"); !errors.Is(err, crypt.ErrAuth) {
+		t.Fatalf("密码错误时应返回 ErrAuth: %v", err)
+	}
+	// 篡改加密数据中的一个字节，认证码核对失败
+	tampered := bytes.Clone(data)
+	tampered[100] ^= 0x01
+	if _, err := open(tampered, "correct horse battery staple"); err == nil {
+		t.Fatal("数据被篡改时解密应失败")
+	}
+}
+
+func TestEncryptedArchiveInterop(t *testing.T) {
+	const passphrase = "correct horse battery staple"
+	for _, format := range []string{storage.ArchiveTarGz, storage.ArchiveZip} {
+		t.Run(format, func(t *testing.T) {
+			e := newEnv(t)
+			e.config.ArchiveFormat = format
+			e.config.Encryption = engine.EncryptionConfig{Enabled: true, Passphrase: passphrase}
+			e.write("docs/secret.txt", "top secret plans", 2*time.Hour)
+			e.write("big.bin", strings.Repeat("0123456789", 20000), 2*time.Hour)
+			record := e.mustBackup()
+			if !record.Encrypted || record.EncryptedNames {
+				t.Fatalf("归档应标记为已加密、文件名未加密: %+v", record)
+			}
+			data, err := os.ReadFile(record.DestPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("top secret")) {
+				t.Fatal("归档中出现明文内容")
+			}
+
+			// 不经过 SyncSafe 的代码，直接用库解密：tar.gz.age 用 filippo.io/age（即 age -d），
+			// zip 用 github.com/alexmullins/zip；其他工具生成的加密 zip 由 TestZipAESKnownAnswer 覆盖
+			files := make(map[string]string)
+			if format == storage.ArchiveTarGz {
+				if !strings.HasSuffix(record.DestPath, ".tar.gz.age") || !bytes.HasPrefix(data, []byte("age-encryption.org/v1\n")) {
+					t.Fatalf("快照应为 age 文件: %s", record.DestPath)
+				}
+				identity, err := age.NewScryptIdentity(passphrase)
+				if err != nil {
+					t.Fatal(err)
+				}
+				plain, err := age.Decrypt(bytes.NewReader(data), identity)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gz, err := gzip.NewReader(plain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tr := tar.NewReader(gz)
+				for {
+					header, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					if header.Typeflag == tar.TypeDir {
+						continue
+					}
+					content, _ := io.ReadAll(tr)
+					files[header.Name] = string(content)
+				}
+			} else {
+				zr, err := aeszip.NewReader(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, file := range zr.File {
+					if file.FileInfo().IsDir() {
+						continue
+					}
+					if !file.IsEncrypted() {
+						t.Fatalf("%s 没有用 AES 加密", file.Name)
+					}
+					file.SetPassword(passphrase)
+					r, err := file.Open()
+					if err != nil {
+						t.Fatal(err)
+					}
+					content, err := io.ReadAll(r)
+					if err != nil {
+						t.Fatal(err)
+					}
+					files[file.Name] = string(content)
+				}
+			}
+			if files["docs/secret.txt"] != "top secret plans" || len(files["big.bin"]) != 200000 || len(files) != 2 {
+				t.Fatalf("解密出的文件不正确: %d 个文件", len(files))
+			}
+
+			// 还原和校验
+			target := t.TempDir()
+			if result, err := e.engine.Restore(record.DestPath, []string{"."}, target, false); err != nil || result.Files != 2 {
+				t.Fatalf("还原 %d 个文件，错误 %v", result.Files, err)
+			}
+			if result, err := e.engine.VerifySnapshot(record); err != nil || !result.OK() || result.Files != 2 {
+				t.Fatalf("校验结果为 %+v，错误 %v", result, err)
+			}
+
+			// 密码短语错误或内容被篡改时校验不通过
+			wrong := *e.config
+			wrong.Encryption.Passphrase = "wrong"
+			if result, err := engine.New(&wrong, engine.Hooks{}).VerifySnapshot(record); err == nil && result.OK() {
+				t.Error("密码短语错误时校验应失败")
+			}
+			data[len(data)/2] ^= 0xff
+			if err := os.WriteFile(record.DestPath, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if result, err := e.engine.VerifySnapshot(record); err == nil && result.OK() {
+				t.Error("归档被篡改时校验应失败")
+			}
+		})
+	}
+}
+
+func TestStructuredLogging(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		logging.SetLevel("")
+	})
+
+	dir := filepath.Join(t.TempDir(), "logs")
+	logging.SetLevel("warn")
+	if err := logging.Setup(dir); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("低于记录级别的日志")
+	slog.Warn("上传失败", "host", "example.com")
+	slog.Error("备份失败", "source", "/data/photos")
+
+	entries, err := logging.Read(dir, logging.Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Message != "上传失败" || entries[0].Attrs != "host=example.com" {
+		t.Fatalf("记录级别为警告时应只记录两条日志: %+v", entries)
+	}
+
+	entries, _ = logging.Read(dir, logging.Filter{MinLevel: slog.LevelError}, 0)
+	if len(entries) != 1 || entries[0].Level != slog.LevelError {
+		t.Fatalf("按级别筛选的结果不正确: %+v", entries)
+	}
+	entries, _ = logging.Read(dir, logging.Filter{Search: "PHOTOS"}, 0)
+	if len(entries) != 1 || entries[0].Message != "备份失败" {
+		t.Fatalf("按关键字搜索的结果不正确: %+v", entries)
+	}
+
+	// 修改记录级别后立即生效
+	logging.SetLevel("debug")
+	slog.Debug("调试信息")
+	entries, _ = logging.Read(dir, logging.Filter{MinLevel: slog.LevelDebug}, 1)
+	if len(entries) != 1 || entries[0].Message != "调试信息" {
+		t.Fatalf("应只返回最新的一条日志: %+v", entries)
+	}
+}
+
+func TestAutoPauseAfterFailures(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "a1", time.Hour)
+	e.mustBackup()
+
+	e.config.PauseAfterFailures = 3
+	failed := func(attempt int) {
+		e.config.History = append(e.config.History, history.Record{
+			Timestamp: time.Now(), SourcePath: e.source, ErrorMessage: "目标文件夹不存在", Attempt: attempt,
+		})
+	}
+	// 一次自动备份和它的两次重试只算一次失败，取消的备份不计入
+	failed(1)
+	failed(2)
+	failed(3)
+	e.config.History = append(e.config.History, history.Record{Timestamp: time.Now(), SourcePath: e.source, Cancelled: true})
+	failed(0)
+	if n := history.ConsecutiveFailures(e.config.History, e.source); n != 2 {
+		t.Fatalf("连续失败次数应为 2，实际为 %d", n)
+	}
+	if e.engine.PauseIfFailing() || e.config.AutoPaused {
+		t.Fatal("没有达到上限时不应暂停")
+	}
+
+	failed(1)
+	if !e.engine.PauseIfFailing() || !e.config.AutoPaused {
+		t.Fatal("连续失败 3 次后应自动暂停")
+	}
+	if e.engine.PauseIfFailing() {
+		t.Fatal("已暂停的任务不应再次暂停")
+	}
+
+	// 暂停状态只保存在本机
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	shared, err := os.ReadFile(filepath.Join(engine.DataDir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(shared), `"AutoPaused": true`) {
+		t.Fatal("暂停状态不应写入共享配置")
+	}
+	loaded, err := engine.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.AutoPaused {
+		t.Fatal("重新加载后应保持暂停状态")
+	}
+
+	// 成功的备份之后重新计数；设置为从不暂停时不再暂停
+	e.write("a.txt", "a2", 0)
+	e.mustBackup()
+	if n := history.ConsecutiveFailures(e.config.History, e.source); n != 0 {
+		t.Fatalf("成功备份后连续失败次数应为 0，实际为 %d", n)
+	}
+	e.config.AutoPaused = false
+	e.config.PauseAfterFailures = -1
+	for i := 0; i < engine.DefaultPauseAfterFailures+1; i++ {
+		failed(0)
+	}
+	if e.engine.PauseIfFailing() {
+		t.Fatal("设置为从不暂停时不应暂停")
+	}
+}
+
+// 切换界面语言后立即生效，包级别的错误变量在显示时翻译，仍然可以用 errors.Is 比较
+func TestLanguageSwitch(t *testing.T) {
+	t.Cleanup(func() { i18n.SetLanguage(i18n.ZhCN) })
+	if i18n.Current() != i18n.ZhCN {
+		t.Fatal("没有设置时应使用简体中文")
+	}
+	i18n.SetLanguage(i18n.EnUS)
+	if i18n.Current() != i18n.EnUS {
+		t.Fatal("应立即切换语言")
+	}
+
+	wrapped := fmt.Errorf("任务 A: %w", engine.ErrCancelled)
+	if !errors.Is(wrapped, engine.ErrCancelled) || engine.ErrCancelled.Error() != "Backup cancelled" {
+		t.Fatalf("错误变量没有翻译或无法比较: %v", wrapped)
+	}
+	if _, err := schedule.Parse("0 2 *"); err == nil || !strings.Contains(err.Error(), "needs 5 fields") {
+		t.Fatalf("格式化的错误应使用英文: %v", err)
+	}
+	if _, err := schedule.Parse("0 25 * * *"); err == nil || !strings.HasPrefix(err.Error(), "hour field out of range") {
+		t.Fatalf("字段名称应翻译: %v", err)
+	}
+	if got := i18n.T("没有翻译的消息"); got != "没有翻译的消息" {
+		t.Fatalf("没有翻译的消息应显示原文: %s", got)
+	}
+
+	i18n.SetLanguage(i18n.ZhCN)
+	if engine.ErrCancelled.Error() != "备份已取消" {
+		t.Fatal("切换回简体中文后应显示原文")
+	}
+}
+
+// 程序设置保存在默认任务的 config.json 中，新建的任务使用默认保留策略
+func TestPreferences(t *testing.T) {
+	newEnv(t)
+	t.Cleanup(func() { engine.Preferences{}.Apply() })
+
+	profiles, err := engine.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *profiles.Preferences() != (engine.Preferences{}) {
+		t.Fatalf("没有保存过的设置应为默认值: %+v", *profiles.Preferences())
+	}
+	want := engine.Preferences{
+		Language:       i18n.EnUS,
+		Theme:          engine.ThemeDark,
+		Debounce:       5,
+		CopyWorkers:    1,
+		StartMinimized: true,
+		Retention:      engine.RetentionPolicy{KeepLast: 3, KeepDaily: 7},
+		LogLevel:       "warn",
+	}
+	*profiles.Preferences() = want
+	if err := profiles.SavePreferences(); err != nil {
+		t.Fatal(err)
+	}
+	if want.WatchDebounce() != 5*time.Second {
+		t.Fatalf("监控延迟不正确: %v", want.WatchDebounce())
+	}
+
+	shared, err := os.ReadFile(filepath.Join(engine.DataDir, "config.json"))
+	if err != nil || !strings.Contains(string(shared), `"Theme": "dark"`) {
+		t.Fatalf("程序设置应保存在 config.json 中: %v\n%s", err, shared)
+	}
+	loaded, err := engine.LoadPreferences()
+	if err != nil || loaded != want {
+		t.Fatalf("创建界面前读取的设置不正确: %+v %v", loaded, err)
+	}
+	profiles, err = engine.LoadProfiles()
+	if err != nil || *profiles.Preferences() != want {
+		t.Fatalf("重新加载后的设置不正确: %+v %v", *profiles.Preferences(), err)
+	}
+
+	config, err := profiles.Add("照片")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Retention != want.Retention {
+		t.Fatalf("新建的任务应使用默认保留策略: %+v", config.Retention)
+	}
+	if config.Preferences != (engine.Preferences{}) {
+		t.Fatal("程序设置只保存在默认任务中")
+	}
+}
+
+// 归档的任务从任务列表中隐藏并停止监控，历史记录保留，恢复后重新出现
+func TestProfileArchive(t *testing.T) {
+	newEnv(t)
+	profiles, err := engine.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Archive(profiles.List[0]); err == nil {
+		t.Fatal("默认任务不应能归档")
+	}
+	config, err := profiles.Add("旧项目")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.IsWatching = true
+	config.History = append(config.History, history.Record{Timestamp: time.Now(), Success: true, DestPath: "snapshot"})
+	profiles.Active = "旧项目"
+	if err := profiles.SaveActive(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 配置保存失败时归档失败，内存中的状态不变，任务继续运行
+	dirs, _ := filepath.Glob(filepath.Join(engine.DataDir, "profiles", "*", "config.json"))
+	if len(dirs) != 1 {
+		t.Fatalf("找不到任务配置文件: %v", dirs)
+	}
+	if err := os.Remove(dirs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dirs[0], "blocked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Archive(config); err == nil {
+		t.Fatal("配置保存失败时归档应失败")
+	}
+	if config.Archived || !config.IsWatching || !slices.Contains(profiles.Names(), "旧项目") {
+		t.Fatal("归档失败后应恢复归档和监控状态")
+	}
+	if err := os.RemoveAll(dirs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := profiles.Archive(config); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(profiles.Names(), "旧项目") || len(profiles.ArchivedList()) != 1 || config.IsWatching {
+		t.Fatalf("归档后应从列表中隐藏并停止监控: %v", profiles.Names())
+	}
+	if profiles.Current() != profiles.List[0] {
+		t.Fatal("归档当前任务后应切换到默认任务")
+	}
+
+	// 重新加载后仍然是归档状态，历史记录保留，名称仍然占用
+	profiles, err = engine.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived := profiles.ArchivedList()
+	if len(archived) != 1 || len(archived[0].History) != 1 || archived[0].IsWatching {
+		t.Fatalf("重新加载后的归档任务不正确: %+v", archived)
+	}
+	if _, err := profiles.Add("旧项目"); err == nil {
+		t.Fatal("已归档任务的名称不应能重复使用")
+	}
+
+	if err := profiles.Unarchive(archived[0]); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err = engine.LoadProfiles()
+	if err != nil || !slices.Contains(profiles.Names(), "旧项目") || len(profiles.ArchivedList()) != 0 {
+		t.Fatalf("恢复后应重新出现在任务列表中: %v %v", profiles.Names(), err)
+	}
+}
+
+// 配置检查一次列出所有问题，阻止备份的问题在前；备份前同样检查，不会在备份过程中逐个失败
+func TestConfigValidation(t *testing.T) {
+	e := newEnv(t)
+	e.write("a.txt", "alpha", time.Hour)
+	if problems := e.config.Validate(); len(problems) != 0 {
+		t.Fatalf("有效的配置不应有问题: %v", problems)
+	}
+
+	e.config.Schedule = "0 25 * * *"
+	e.config.Notify.Enabled = true
+	e.config.SourceFiles = []string{"missing.txt"}
+	problems := e.config.Validate()
+	if len(problems) != 3 {
+		t.Fatalf("应发现 3 个问题: %v", problems)
+	}
+	for _, problem := range problems {
+		if problem.Blocking {
+			t.Fatalf("这些问题不应阻止备份: %v", problem)
+		}
+	}
+	if err := e.config.ValidateForBackup(); err != nil {
+		t.Fatalf("只有不阻止备份的问题时应可以备份: %v", err)
+	}
+
+	e.config.SourceFiles = nil
+	e.config.SourcePath = filepath.Join(t.TempDir(), "missing")
+	e.config.Encryption.Enabled = true
+	problems = e.config.Validate()
+	if len(problems) != 4 || !problems[0].Blocking || !problems[1].Blocking || problems[2].Blocking {
+		t.Fatalf("阻止备份的问题应排在前面: %v", problems)
+	}
+	if problems[0].Setting != engine.SettingSource || problems[1].Setting != engine.SettingEncryption {
+		t.Fatalf("问题对应的设置不正确: %v", problems)
+	}
+
+	_, err := e.engine.Backup(context.Background(), 0)
+	var problemsErr *engine.ProblemsError
+	if !errors.As(err, &problemsErr) || len(problemsErr.Problems) != 2 {
+		t.Fatalf("备份前应一次列出所有阻止备份的问题: %v", err)
+	}
+	if !strings.Contains(err.Error(), "源文件夹不存在") || !strings.Contains(err.Error(), "密码短语") {
+		t.Fatalf("错误信息应包含所有问题: %v", err)
+	}
+
+	// 命令行的 check 命令：有阻止备份的问题时为配置错误，只有其他问题时为部分成功
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cli.Run([]string{"check"}); got != cli.ExitConfig {
+		t.Errorf("退出码 %d，应为 %d", got, cli.ExitConfig)
+	}
+	e.config.SourcePath = e.source
+	e.config.Encryption.Enabled = false
+	if err := e.config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cli.Run([]string{"check"}); got != cli.ExitPartial {
+		t.Errorf("退出码 %d，应为 %d", got, cli.ExitPartial)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build linux
+
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Linux 上用 user 命名空间的扩展属性测试附加数据流，Windows 的备用数据流和 macOS 的资源分支走同样的流程
+
+func TestAlternateStreams(t *testing.T) {
+	e := newEnv(t)
+	e.write("design.psd", "layers", time.Hour)
+	e.write("plain.txt", "text", time.Hour)
+	tagged := filepath.Join(e.source, "design.psd")
+	if err := syscall.Setxattr(tagged, "user.xdg.tags", []byte("客户A,终稿"), 0); err != nil {
+		t.Skipf("文件系统不支持扩展属性: %v", err)
+	}
+
+	// 没有开启时记录丢失的附加数据流
+	record := e.mustBackup()
+	if record.StreamsLost != 1 || record.StreamFiles != 0 {
+		t.Fatalf("应记录 1 个文件的附加数据流没有备份: %+v", record)
+	}
+
+	e.config.CaptureStreams = true
+	e.write("design.psd", "layers v2", 0)
+	record = e.mustBackup()
+	if record.StreamFiles != 1 || record.StreamsLost != 0 {
+		t.Fatalf("应保存 1 个文件的附加数据流: %+v", record)
+	}
+	xattr := func(path string) string {
+		buf := make([]byte, 256)
+		n, err := syscall.Getxattr(path, "user.xdg.tags", buf)
+		if err != nil {
+			return ""
+		}
+		return string(buf[:n])
+	}
+	copied := filepath.Join(record.DestPath, "design.psd")
+	if got := xattr(copied); got != "客户A,终稿" {
+		t.Fatalf("快照中的副本应带有扩展属性，实际为 %q", got)
+	}
+	info, err := os.Stat(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, _ := os.Stat(tagged)
+	if !info.ModTime().Equal(source.ModTime()) {
+		t.Fatal("写入附加数据流后应保留修改时间")
+	}
+
+	// 还原时写回附加数据流
+	target := t.TempDir()
+	result, err := e.engine.Restore(record.DestPath, []string{"."}, target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 2 || result.Streams != 1 || result.Lost != 0 {
+		t.Fatalf("还原结果不正确: %+v", result)
+	}
+	if got := xattr(filepath.Join(target, "design.psd")); got != "客户A,终稿" {
+		t.Fatalf("还原的文件应带有扩展属性，实际为 %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 快照大小骤变的判断：与最近几次正常备份的平均值相比
+const (
+	anomalyWindow   = 5  // 计算平均值的最近备份数
+	anomalyMinRuns  = 3  // 至少有这么多次正常备份才比较
+	anomalyMinFiles = 10 // 平均文件数少于此值时不比较，小文件夹的正常波动很大
+	anomalyShrink   = 50 // 文件数或大小降到平均值的百分之多少以下视为骤减
+	anomalyGrow     = 3  // 文件数或大小超过平均值的多少倍视为骤增
+)
+
+// 最近一次备份的大小骤变还没有确认时暂停按保留策略清理，避免正常的旧快照被清理掉
+var ErrRetentionHeld = i18n.Error("最近一次备份的大小异常，确认正常之前暂停按保留策略清理")
+
+// 与历史中最近几次正常备份的平均值相比，record 的文件数或大小是否骤减（源文件夹被误清空）
+// 或骤增（日志、缓存失控），返回说明，没有异常时返回空字符串。
+// 未确认的异常备份不计入平均值，源文件夹路径模板展开后不同的备份也不比较
+func DetectSizeAnomaly(records []history.Record, record history.Record) string {
+	source := ExpandPathTemplate(record.SourcePath, record.Timestamp)
+	var files, size int64
+	n := 0
+	for i := len(records) - 1; i >= 0 && n < anomalyWindow; i-- {
+		r := records[i]
+		if !r.Success || r.DryRun || r.SizeAnomaly != "" || ExpandPathTemplate(r.SourcePath, r.Timestamp) != source {
+			continue
+		}
+		files += int64(r.FileCount)
+		size += r.TotalSize
+		n++
+	}
+	if n < anomalyMinRuns || files/int64(n) < anomalyMinFiles {
+		return ""
+	}
+	avgFiles, avgSize := files/int64(n), size/int64(n)
+	count := int64(record.FileCount)
+	mb := func(bytes int64) float64 { return float64(bytes) / (1024 * 1024) }
+	switch {
+	case count*100 < avgFiles*anomalyShrink || record.TotalSize*100 < avgSize*anomalyShrink:
+		return i18n.Sprintf("快照明显变小：%d 个文件（%.2f MB），最近 %d 次平均 %d 个文件（%.2f MB）。源文件夹是否被误删或清空？",
+			count, mb(record.TotalSize), n, avgFiles, mb(avgSize))
+	case count > avgFiles*anomalyGrow || record.TotalSize > avgSize*anomalyGrow:
+		return i18n.Sprintf("快照明显变大：%d 个文件（%.2f MB），最近 %d 次平均 %d 个文件（%.2f MB）。是否有日志或缓存文件失控增长？",
+			count, mb(record.TotalSize), n, avgFiles, mb(avgSize))
+	}
+	return ""
+}
+
+// 最近一次成功的备份有未确认的大小异常时返回该记录
+func HeldBySizeAnomaly(records []history.Record) (history.Record, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Success && !records[i].DryRun {
+			return records[i], records[i].SizeAnomaly != ""
+		}
+	}
+	return history.Record{}, false
+}
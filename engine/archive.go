@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/crypt"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 快照是否为归档模式写入的单个文件
+func IsArchiveSnapshot(snapshotPath string) bool {
+	return storage.ArchiveFormat(snapshotPath) != ""
+}
+
+// 归档中的路径转换为本地相对路径，跳出归档根目录的路径返回 false
+func archiveRelPath(name string) (string, bool) {
+	relPath := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if relPath == "" || !filepath.IsLocal(relPath) {
+		return "", false
+	}
+	return filepath.Clean(relPath), true
+}
+
+// 依次访问归档中的文件和目录，文件内容在 visit 返回前可以从 r 读取。
+// 加密的归档用 passphrase 解密：tar.gz.age 整体解密，zip 中的文件在读取时解密
+func walkArchive(path, passphrase string, visit func(relPath string, info os.FileInfo, r io.Reader) error) error {
+	format := storage.ArchiveFormat(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return i18n.Errorf("打开归档失败: %v", err)
+	}
+	defer file.Close()
+	if format == storage.ArchiveZip {
+		return walkZip(file, passphrase, visit)
+	}
+	var body io.Reader = file
+	if format == storage.ArchiveTarGzAge {
+		if passphrase == "" {
+			return errNoPassphrase
+		}
+		if body, err = crypt.NewAgeReader(file, passphrase); err != nil {
+			return i18n.Errorf("解密归档失败: %v", err)
+		}
+	}
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return i18n.Errorf("读取归档失败: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return i18n.Errorf("读取归档失败: %v", err)
+		}
+		relPath, ok := archiveRelPath(header.Name)
+		info := header.FileInfo()
+		if !ok || !(info.IsDir() || info.Mode().IsRegular()) {
+			continue
+		}
+		if err := visit(relPath, info, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// 文件的元数据由 archive/zip 读取，加密的内容交给 crypt.ZipAESReader 解密
+func walkZip(file *os.File, passphrase string, visit func(string, os.FileInfo, io.Reader) error) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return i18n.Errorf("打开归档失败: %v", err)
+	}
+	zr, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return i18n.Errorf("打开归档失败: %v", err)
+	}
+	var aes *crypt.ZipAESReader
+	if passphrase != "" {
+		if aes, err = crypt.NewZipAESReader(file, stat.Size(), passphrase); err != nil {
+			return i18n.Errorf("打开归档失败: %v", err)
+		}
+	}
+	for index, entry := range zr.File {
+		relPath, ok := archiveRelPath(entry.Name)
+		info := entry.FileInfo()
+		if !ok || !(info.IsDir() || info.Mode().IsRegular()) {
+			continue
+		}
+		if err := visitZipFile(entry, aes, index, relPath, info, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitZipFile(file *zip.File, aes *crypt.ZipAESReader, index int, relPath string, info os.FileInfo, visit func(string, os.FileInfo, io.Reader) error) error {
+	if info.IsDir() {
+		return visit(relPath, info, nil)
+	}
+	r := &zipEntryReader{file: file, aes: aes, index: index}
+	defer r.Close()
+	return visit(relPath, info, r)
+}
+
+// 第一次读取时才打开 zip 中的文件：只列出文件时不需要解压，加密的文件也不需要密码短语
+type zipEntryReader struct {
+	file  *zip.File
+	aes   *crypt.ZipAESReader // 没有密码短语时为 nil
+	index int
+	r     io.ReadCloser
+}
+
+func (z *zipEntryReader) Read(p []byte) (int, error) {
+	if z.r == nil {
+		var err error
+		switch {
+		case !crypt.IsZipAES(z.file):
+			z.r, err = z.file.Open()
+		case z.aes == nil:
+			err = errNoPassphrase
+		default:
+			z.r, err = z.aes.Open(z.index)
+		}
+		if err != nil {
+			return 0, i18n.Errorf("读取归档失败: %v\n文件: %s", err, z.file.Name)
+		}
+	}
+	return z.r.Read(p)
+}
+
+func (z *zipEntryReader) Close() error {
+	if z.r == nil {
+		return nil
+	}
+	return z.r.Close()
+}
+
+// 归档快照和远程快照的目录结构，用于在还原页中浏览
+type ArchiveTree struct {
+	children map[string][]os.FileInfo // 键为目录的相对路径，根目录为 ""
+	dirs     map[string]bool
+	seen     map[string]bool
+}
+
+func newArchiveTree() *ArchiveTree {
+	return &ArchiveTree{children: make(map[string][]os.FileInfo), dirs: map[string]bool{"": true}, seen: make(map[string]bool)}
+}
+
+// 加入文件或目录，没有单独记录的上级目录也一并加入
+func (t *ArchiveTree) add(relPath string, info os.FileInfo) {
+	if info.IsDir() {
+		t.dirs[relPath] = true
+	}
+	t.addEntry(relPath, info)
+	for dir := filepath.Dir(relPath); dir != "."; dir = filepath.Dir(dir) {
+		if !t.dirs[dir] {
+			t.dirs[dir] = true
+			t.addEntry(dir, implicitDir(filepath.Base(dir)))
+		}
+	}
+}
+
+func (t *ArchiveTree) addEntry(relPath string, info os.FileInfo) {
+	if t.seen[relPath] {
+		return
+	}
+	t.seen[relPath] = true
+	parent := filepath.Dir(relPath)
+	if parent == "." {
+		parent = ""
+	}
+	t.children[parent] = append(t.children[parent], info)
+}
+
+// 每个目录中的条目按名称排列
+func (t *ArchiveTree) sortChildren() {
+	for _, list := range t.children {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	}
+}
+
+// 读取归档快照中所有文件和目录的信息。归档中没有单独记录的上级目录也会列出。
+// tar.gz.age 归档的文件名也已加密，需要在加密设置中填写密码短语
+func (e *Engine) LoadArchiveTree(path string) (*ArchiveTree, error) {
+	t := newArchiveTree()
+	err := walkArchive(path, e.Config.Encryption.Passphrase, func(relPath string, info os.FileInfo, r io.Reader) error {
+		t.add(relPath, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.sortChildren()
+	return t, nil
+}
+
+// dir 中的文件和目录，按名称排列
+func (t *ArchiveTree) Children(dir string) []os.FileInfo {
+	if dir == "." {
+		dir = ""
+	}
+	return t.children[dir]
+}
+
+// relPath 是否为目录
+func (t *ArchiveTree) IsDir(relPath string) bool {
+	if relPath == "." {
+		relPath = ""
+	}
+	return t.dirs[relPath]
+}
+
+// 归档中没有单独记录的目录
+type implicitDir string
+
+func (d implicitDir) Name() string       { return string(d) }
+func (d implicitDir) Size() int64        { return 0 }
+func (d implicitDir) Mode() os.FileMode  { return os.ModeDir | 0755 }
+func (d implicitDir) ModTime() time.Time { return time.Time{} }
+func (d implicitDir) IsDir() bool        { return true }
+func (d implicitDir) Sys() any           { return nil }
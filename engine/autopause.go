@@ -0,0 +1,31 @@
+package engine
+
+import "syncsafe/history"
+
+// 默认连续失败多少次后自动暂停任务
+const DefaultPauseAfterFailures = 5
+
+// 连续失败多少次后自动暂停任务，0 表示从不暂停
+func (c *Config) FailureLimit() int {
+	switch {
+	case c.PauseAfterFailures < 0:
+		return 0
+	case c.PauseAfterFailures == 0:
+		return DefaultPauseAfterFailures
+	}
+	return c.PauseAfterFailures
+}
+
+// 源文件夹连续失败的次数达到上限时暂停任务，返回是否刚刚暂停。
+// 目标文件夹永久消失等无法自行恢复的错误不会无休止地重试，调用方负责保存配置
+func (e *Engine) PauseIfFailing() bool {
+	limit := e.Config.FailureLimit()
+	if e.Config.AutoPaused || limit == 0 {
+		return false
+	}
+	if history.ConsecutiveFailures(e.Config.History, e.Config.SourcePath) < limit {
+		return false
+	}
+	e.Config.AutoPaused = true
+	return true
+}
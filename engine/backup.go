@@ -0,0 +1,849 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 备份被取消
+var ErrCancelled = i18n.Error("备份已取消")
+
+// Git 备份失败
+type GitError struct {
+	Err error
+}
+
+func (e *GitError) Error() string { return e.Err.Error() }
+func (e *GitError) Unwrap() error { return e.Err }
+
+// 执行一次备份。attempt 为自动备份的第几次尝试，手动备份为 0。
+// 复制开始后无论成败都返回本次备份的记录，在此之前失败（例如 Git 备份失败）时记录为 nil。
+// 启用 Config.SkipUnchanged 且与上一个快照相比没有变化时不创建快照，记录和错误都为 nil。
+// 启用 Config.ChecksumCompare 时按 SHA-256 判断文件是否变化，哈希记录在数据目录的索引中。
+// 启用 Config.QuickSync 时写入固定的镜像目录，成功后之前写入该目录的记录标记为已清理。
+// 设置了 Config.ArchiveFormat 时快照写入单个 tar.gz 或 zip 文件（加密时为 age 加密的 tar.gz.age
+// 或 AES-256 加密的 zip），记录的 DestPath 为归档路径。
+// 启用 Config.Peer 时备份成功后把快照推送到局域网中的接收端，设置了 Config.InteropLayout 时
+// 按该布局导出给其他同步工具，设置了 Config.Replicas 时把快照复制到附加目标，
+// 推送、导出和复制失败都不影响备份结果。
+// ctx 取消时尽快停止复制并返回 ErrCancelled，记录标记为已取消，未完成的快照目录被删除。
+// 记录不会自动加入 Config.History，由调用方决定如何保存
+func (e *Engine) Backup(ctx context.Context, attempt int) (record *history.Record, err error) {
+	e.totalFiles, e.totalBytes = 0, 0
+	e.setStage(StageStarting, i18n.T("开始备份"))
+	defer func() {
+		if err != nil {
+			e.emit(Event{Kind: EventError, Message: err.Error()})
+		}
+		message := i18n.T("备份完成")
+		if err != nil {
+			message = i18n.T("备份失败")
+		}
+		if record != nil {
+			message = i18n.Sprintf("%s，共 %d 个文件", message, record.FileCount)
+		}
+		e.setStage(StageDone, message)
+	}()
+
+	// 一次列出所有阻止备份的配置问题，而不是在备份过程中逐个失败
+	if err := e.Config.ValidateForBackup(); err != nil {
+		return nil, err
+	}
+	// 路径模板在备份开始时展开一次，备份过程中跨月也使用同一个目录
+	source := e.SourcePath()
+
+	// 双向同步：先与同步文件夹交换两侧的变化，快照保存同步后的结果，同步出错时可以从快照还原
+	var synced *SyncResult
+	if e.Config.SyncPath != "" {
+		e.setStage(StageSync, i18n.T("与同步文件夹交换变化"))
+		result, err := e.TwoWaySync(ctx, source)
+		if err != nil {
+			if errors.Is(err, ErrCancelled) {
+				return nil, err
+			}
+			return nil, i18n.Errorf("双向同步失败: %v", err)
+		}
+		e.status(result.String())
+		synced = &result
+	}
+
+	// 与上一个快照相比没有任何变化时不创建空快照和历史记录
+	if e.Config.SkipUnchanged && !e.Config.DryRun {
+		if previous, ok := e.previousSnapshot(); ok && !e.sourceChanged(source, previous) {
+			e.emit(Event{Kind: EventNoChanges, Path: source,
+				Message: i18n.Sprintf("与 %s 的快照相比没有变化", previous.FormatTime(e.Config.Location()))})
+			e.status(i18n.T("没有变化，跳过本次备份"))
+			return nil, nil
+		}
+	}
+
+	e.status(i18n.T("开始备份..."))
+	defer e.closeElevatedHelper()
+
+	// 如果启用了 Git 备份，先执行 Git 操作。设置了提交计划时按计划批量提交，本地快照照常进行
+	if e.Config.Git.Enabled && e.Config.gitCommitDue(time.Now()) {
+		e.setStage(StageGit, i18n.T("提交并推送到 Git 仓库"))
+		if err := e.CommitGit(); err != nil {
+			return nil, err
+		}
+		e.status(i18n.T("Git 备份完成"))
+	}
+	if ctx.Err() != nil {
+		return nil, ErrCancelled
+	}
+
+	// 从源文件夹所在文件系统的只读快照中读取，备份期间仍在写入的文件也是同一时刻的状态。
+	// 无法创建快照时直接读取源文件夹，备份完成后提示
+	readRoot := source
+	snapshotWarning := ""
+	if e.Config.SourceSnapshot && !e.Config.DryRun {
+		e.status(i18n.T("正在创建文件系统快照..."))
+		if snap, snapErr := e.snapshotSource(source); snapErr != nil {
+			slog.Warn("创建文件系统快照失败", "err", snapErr)
+			snapshotWarning = i18n.T("创建文件系统快照失败，直接从源文件夹读取: ") + snapErr.Error()
+		} else {
+			defer snap.Release()
+			readRoot = snap.Root
+			e.status(i18n.Sprintf("已创建 %s 快照", snap.Kind))
+		}
+	}
+
+	// 记录开始时间
+	startTime := time.Now()
+	sampler := startMemorySampler()
+	dest := e.Destination()
+
+	// 创建本地备份文件夹（替换空格为下划线）
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	folderName := strings.ReplaceAll(filepath.Base(source), " ", "_") + "-" + timestamp
+	backupDir := filepath.Join(filepath.Clean(e.Config.DestinationPath), folderName)
+	// 归档模式下快照是一个压缩文件，快速同步和增量快照不适用
+	archiveFormat := e.Config.ArchiveFormat
+	quickSync := e.Config.QuickSync && archiveFormat == ""
+	if quickSync {
+		backupDir = mirrorDir(e.Config.DestinationPath, source)
+	}
+	var archive *storage.Archive
+	if archiveFormat != "" {
+		if storage.IsRemote(e.Config.DestinationPath) {
+			return nil, i18n.Errorf("归档模式只支持本地目标文件夹")
+		}
+		// 加密的归档使用标准格式，没有 SyncSafe 时也能解密：tar.gz 整体用 age 加密，
+		// zip 中的文件用 AES-256 加密。密码短语仍然与目标的加密参数核对，
+		// 避免同一目标中的快照使用不同的密码短语
+		passphrase := ""
+		if e.Config.Encryption.Enabled {
+			if !e.Config.DryRun {
+				if _, err := e.encryptionKey(e.Config.DestinationPath, e.Destination()); err != nil {
+					return nil, err
+				}
+			}
+			passphrase = e.Config.Encryption.Passphrase
+			if archiveFormat == storage.ArchiveTarGz {
+				archiveFormat = storage.ArchiveTarGzAge
+			}
+		}
+		backupDir += "." + archiveFormat
+		if !e.Config.DryRun {
+			if archive, err = storage.NewArchive(dest, backupDir, archiveFormat, passphrase); err != nil {
+				return nil, err
+			}
+			dest = archive
+		}
+	}
+	if e.Config.Encryption.Enabled && archiveFormat == "" && !e.Config.DryRun {
+		if dest, err = e.encryptedDestination(dest); err != nil {
+			return nil, err
+		}
+	}
+	e.setStage(StageCopying, i18n.T("复制文件到 ")+e.Config.DestinationPath)
+
+	// 模拟模式下只记录将要执行的写入操作
+	dryRun := e.Config.DryRun
+	if !e.Simulated("创建备份目录 %s", backupDir) {
+		// 确保父目录存在
+		parentDir := filepath.Dir(backupDir)
+		if err := dest.MkdirAll(parentDir, 0755); err != nil {
+			return nil, i18n.Errorf("创建父目录失败: %v\n目录: %s", err, parentDir)
+		}
+
+		// 创建备份目录
+		if err := dest.MkdirAll(backupDir, 0755); err != nil {
+			return nil, i18n.Errorf("创建备份目录失败: %v\n目录: %s", err, backupDir)
+		}
+	}
+
+	// 遍历源文件夹
+	var fileCount int
+	var totalSize int64
+	var newFiles int
+	var modifiedFiles int
+	var linkedFiles int
+
+	// 与上一个快照的清单按遍历顺序流式对比来跟踪变化，不在内存中保存完整文件列表
+	var previous *ManifestReader
+	lastRecord, hasLast := history.LastSnapshot(e.Config.History)
+	if hasLast {
+		previous = openSnapshotManifest(lastRecord)
+	}
+	diff := newManifestDiff(previous)
+	var changes changeLog
+	diff.onDeleted = func(entry ManifestEntry) {
+		changes.add(history.Change{Path: entry.RelPath, Op: history.ChangeDeleted, Size: entry.Size})
+	}
+
+	// 增量快照：未变化的文件硬链接到上一个快照，只复制新增和修改的文件。
+	// 上一个快照的加密设置不同时不能链接
+	linkDir := ""
+	encrypted, _ := dest.(*storage.Encrypted)
+	if e.Config.Incremental && !quickSync && archive == nil && hasLast {
+		if snapshot, ok := e.previousSnapshot(); ok && snapshot.Same(lastRecord) &&
+			snapshot.Encrypted == (encrypted != nil) && snapshot.EncryptedNames == (encrypted != nil && encrypted.ObfuscateNames) {
+			linkDir = snapshot.DestPath
+		}
+	}
+
+	// 按变化备份：监控期间只有部分路径变化时，其余文件沿用上一个快照的清单，不再读取。
+	// 只在没有变化的文件不需要复制时使用：增量快照硬链接，快速同步保留镜像中的文件。
+	// 从文件系统快照读取时完整遍历快照
+	selectionKey := e.Config.selectionKey()
+	changed, selective := e.changes.take(source, lastRecord, selectionKey, time.Now())
+	var reuse *ManifestReader
+	if selective && !dryRun && !e.Config.ElevatedRead && readRoot == source &&
+		(linkDir != "" || (quickSync && lastRecord.DestPath == backupDir)) {
+		reuse = openSnapshotManifest(lastRecord)
+	}
+	selective = reuse != nil
+	defer func() {
+		e.changes.finish(record, selectionKey, selective, time.Now())
+	}()
+
+	// 复查队列中等待重试的路径重新读取并复制；备份中发现的问题和只有大小写不同的路径记录在 issues 中
+	retry := e.takeRetryPaths()
+	var issues []ReviewItem
+	collisions := make(collisionCheck)
+
+	// 快速同步：先列出镜像中已有的文件，只上传大小或修改时间不同的文件。
+	// 按变化备份时镜像中的文件就是上一次同步的清单，不需要列出
+	var remote map[string]storage.RemoteFile
+	if quickSync {
+		if selective {
+			remote, err = manifestFiles(lastRecord)
+		} else {
+			remote, err = listMirror(dest, backupDir)
+		}
+		if err != nil {
+			reuse.Close()
+			diff.Finish()
+			return nil, err
+		}
+	}
+
+	// 记录本次快照的清单，模拟备份不生成快照也不需要清单
+	var manifest *ManifestWriter
+	if !dryRun {
+		manifest, err = createManifest(manifestPath(backupDir))
+		if err != nil {
+			reuse.Close()
+			diff.Finish()
+			return nil, err
+		}
+	}
+
+	// 附加数据流（NTFS 备用数据流、扩展属性）保存在快照清单旁。本地目标文件夹中复制的文件
+	// 同时写入数据流，硬链接和池中的文件与其他快照共用，不修改。没有开启时只检查复制的文件，记录丢失的数量
+	var streams *streamWriter
+	var streamFiles, streamsLost int
+	nativeStreams := false
+	if e.Config.CaptureStreams && !dryRun {
+		if streams, err = createStreams(streamsPath(backupDir)); err != nil {
+			reuse.Close()
+			diff.Finish()
+			manifest.Abort()
+			return nil, err
+		}
+		_, local := dest.(*storage.Local)
+		nativeStreams = local
+	}
+
+	// 按配置排除的隐藏文件、系统文件和点文件
+	filter := e.Config.fileFilter()
+
+	// 目录的权限和修改时间在所有文件复制完成后再设置：
+	// 复制文件会改变目录的修改时间，只读目录也无法继续写入
+	var dirs []ManifestEntry
+
+	// 文件由工作池并行复制，清单和统计仍按遍历顺序更新。
+	// 使用去重存储池时，池中已有的文件直接硬链接
+	storagePool := ""
+	if archive == nil && encrypted == nil {
+		storagePool = e.backupPool(dest, quickSync)
+	}
+	var pooledFiles atomic.Int64
+	// 任务没有设置并发复制数时使用程序设置
+	workers := e.Config.CopyWorkers
+	if workers <= 0 {
+		workers = int(defaultCopyWorkers.Load())
+	}
+	pool := newCopyPool(workers, func(src, dst string) error {
+		if storagePool != "" {
+			return e.copyPooled(ctx, dest, storagePool, src, dst, &pooledFiles)
+		}
+		return e.copyFile(ctx, dest, src, dst)
+	})
+	var failures copyFailures
+
+	// 按内容比较时加载上次备份的哈希，本次成功写入的文件的哈希记录在 newHashes 中
+	var hashes *hashIndex
+	newHashes := make(map[string]hashEntry)
+	if e.Config.ChecksumCompare {
+		hashes = loadHashIndex(source, e.Config.DestinationPath)
+	}
+
+	// 复制单个文件或创建目录，索引遍历和文件树遍历共用
+	newEntries := make(map[string]IndexEntry)
+	visit := func(path, relPath string, info os.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		destPath := filepath.Join(backupDir, relPath)
+		newEntries[relPath] = IndexEntry{Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}
+		if other, ok := collisions.add(relPath); ok {
+			issues = append(issues, ReviewItem{Kind: IssueCollision, Path: relPath,
+				Message: i18n.Sprintf("与 %s 只有大小写不同，在不区分大小写的目标中会互相覆盖", other)})
+		}
+
+		if info.IsDir() {
+			// 不保留目录时只在复制文件时创建其所在的目录
+			if e.Config.SkipDirectories {
+				return nil
+			}
+			entry := ManifestEntry{RelPath: relPath, ModTime: info.ModTime(), IsDir: true, Mode: info.Mode().Perm()}
+			diff.Compare(entry)
+			if dryRun {
+				return nil
+			}
+			if err := dest.MkdirAll(destPath, 0755); err != nil {
+				return i18n.Errorf("创建目录失败: %v\n目录: %s", err, destPath)
+			}
+			dirs = append(dirs, entry)
+			return pool.Submit("", "", func(error) error {
+				return addEntry(manifest, entry)
+			})
+		}
+
+		// 检查文件是否存在和是否被修改
+		entry := ManifestEntry{RelPath: relPath, Size: info.Size(), ModTime: info.ModTime()}
+		change := diff.Compare(entry)
+		// 按内容比较：只有修改时间变化的文件视为未变化，内容变化但修改时间相同的文件视为修改。
+		// 文件无法读取时沿用按修改时间判断的结果
+		var sum hashEntry
+		hashed := false
+		_, reused := info.(manifestFileInfo)
+		if hashes != nil && e.Config.hashEnabled(relPath) {
+			if reused {
+				// 按变化备份沿用的文件没有变化，不重新读取，保留上次的哈希
+				sum, hashed = hashes.get(relPath)
+			} else {
+				var hashErr error
+				if change, sum, hashErr = hashes.compare(path, entry, change); hashErr == nil {
+					hashed = true
+				}
+			}
+		}
+		remoteFile, inRemote := remote[relPath]
+		delete(remote, relPath)
+		switch change {
+		case changeNew:
+			newFiles++
+			changes.add(history.Change{Path: relPath, Op: history.ChangeNew, Size: entry.Size})
+			e.Simulated("复制新增文件 %s", relPath)
+		case changeModified:
+			modifiedFiles++
+			changes.add(history.Change{Path: relPath, Op: history.ChangeModified, Size: entry.Size})
+			e.Simulated("复制修改的文件 %s", relPath)
+		}
+
+		if dryRun {
+			fileCount++
+			totalSize += info.Size()
+			e.emit(Event{Kind: EventFileSkipped, Path: path, Size: info.Size(), Files: fileCount, Bytes: totalSize, Message: i18n.T("模拟模式")})
+			return nil
+		}
+
+		var fileStreams []Stream
+		if streams != nil {
+			var streamErr error
+			if fileStreams, streamErr = readStreams(path); streamErr != nil {
+				streamsLost++
+				slog.Warn("读取附加数据流失败", "path", path, "err", streamErr)
+			} else if len(fileStreams) > 0 {
+				if err := streams.Add(relPath, fileStreams); err != nil {
+					return err
+				}
+				streamFiles++
+			}
+		} else if change != changeUnchanged {
+			if infos, _ := listStreams(path); len(infos) > 0 {
+				streamsLost++
+			}
+		}
+
+		if inRemote && hashed {
+			// 目标中的副本内容相同时，修改时间不同也不重新上传；内容不同时修改时间相同也要上传
+			inRemote = remoteFile.Size == info.Size() && change == changeUnchanged
+			sum.ModTime = remoteFile.ModTime
+		} else {
+			inRemote = inRemote && remoteUnchanged(remoteFile, info)
+		}
+		if inRemote && !retry[relPath] {
+			return pool.Submit("", "", func(error) error {
+				if err := addEntry(manifest, entry); err != nil {
+					return err
+				}
+				if hashed {
+					newHashes[relPath] = sum
+				}
+				fileCount++
+				totalSize += info.Size()
+				e.emit(Event{Kind: EventFileSkipped, Path: path, Size: info.Size(), Files: fileCount, Bytes: totalSize, Message: i18n.T("目标中已是最新")})
+				return nil
+			})
+		}
+
+		if change == changeUnchanged && linkDir != "" && !retry[relPath] {
+			// 只有修改时间变化的文件，上一个快照中的副本仍是原来的修改时间
+			linkTime := entry.ModTime
+			if hashed {
+				if old, ok := hashes.get(relPath); ok {
+					linkTime = old.ModTime
+				}
+			}
+			if err := dest.Link(filepath.Join(linkDir, relPath), destPath, entry.Size, linkTime); err == nil {
+				sum.ModTime = linkTime
+				return pool.Submit("", "", func(error) error {
+					if err := addEntry(manifest, entry); err != nil {
+						return err
+					}
+					if hashed {
+						newHashes[relPath] = sum
+					}
+					fileCount++
+					linkedFiles++
+					totalSize += info.Size()
+					e.emit(Event{Kind: EventFileCopied, Path: path, Size: info.Size(), Files: fileCount, Bytes: totalSize, Message: i18n.T("硬链接")})
+					return nil
+				})
+			}
+			// 无法创建硬链接时改为复制
+		}
+
+		// 单个文件复制失败不中断备份，继续复制其他文件，结束后汇总所有失败的文件
+		return pool.Submit(path, destPath, func(copyErr error) error {
+			if copyErr != nil {
+				// 取消导致的复制失败不计入失败的文件
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				failures.add(copyErr)
+				message, _, _ := strings.Cut(copyErr.Error(), "\n")
+				issues = append(issues, ReviewItem{Kind: IssueCopyFailed, Path: relPath, Message: message})
+				e.emit(Event{Kind: EventFileSkipped, Path: path, Size: info.Size(), Files: fileCount, Bytes: totalSize, Message: i18n.T("复制失败")})
+				return nil
+			}
+			if err := addEntry(manifest, entry); err != nil {
+				return err
+			}
+			if hashed {
+				newHashes[relPath] = sum
+			}
+			if nativeStreams && storagePool == "" && len(fileStreams) > 0 {
+				if streamErr := applyStreams(destPath, fileStreams); streamErr != nil {
+					slog.Debug("目标文件夹不支持附加数据流", "path", destPath, "err", streamErr)
+				}
+			}
+			fileCount++
+			totalSize += info.Size()
+			e.emit(Event{Kind: EventFileCopied, Path: path, Size: info.Size(), Files: fileCount, Bytes: totalSize})
+			return nil
+		})
+	}
+
+	// 遍历文件树时访问每个文件和目录，完整遍历和按变化备份重新读取变化的目录共用
+	walkVisit := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 无权读取的目录交给提权辅助进程
+			if e.Config.ElevatedRead && writesDirectly(dest) && info != nil && info.IsDir() && os.IsPermission(err) {
+				if e.Simulated("通过提权辅助进程备份受保护的目录 %s", path) {
+					return filepath.SkipDir
+				}
+				relPath, _ := filepath.Rel(readRoot, path)
+				forgetRemoteDir(remote, relPath)
+				count, size, err := e.backupProtectedDir(path, filepath.Join(backupDir, relPath))
+				fileCount += count
+				totalSize += size
+				return err
+			}
+			return i18n.Errorf("访问文件失败: %v\n文件: %s", err, path)
+		}
+
+		// 跳过 .git 目录
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		relPath, err := filepath.Rel(readRoot, path)
+		if err != nil {
+			return i18n.Errorf("获取相对路径失败: %v", err)
+		}
+		if relPath == "." {
+			return nil // 备份目录已创建
+		}
+		if filter.Exclude(relPath, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return visit(path, relPath, info)
+	}
+
+	idx, idxErr := e.SourceIndex()
+	if selective {
+		// 没有变化的条目直接按上一个快照的清单处理，变化的路径（目录连同其中的内容）重新读取
+		e.emit(Event{Kind: EventScanStarted, Path: source, Message: i18n.Sprintf("按变化（%d 个路径）", len(changed))})
+		e.setTotals(lastRecord.FileCount, lastRecord.TotalSize)
+		err = mergeChanges(reuse, changed, func(entry ManifestEntry) error {
+			return visit(filepath.Join(source, entry.RelPath), entry.RelPath, manifestFileInfo{entry})
+		}, func(relPath string) error {
+			path := filepath.Join(source, relPath)
+			// 已删除的路径不再出现在快照中，上一个快照中的条目也不沿用
+			if _, statErr := os.Lstat(path); os.IsNotExist(statErr) || e.Config.ancestorExcluded(source, relPath) {
+				return nil
+			}
+			return filepath.Walk(path, walkVisit)
+		})
+	} else if idxErr == nil && readRoot == source && (idx.Live() || idx.CatchUp()) {
+		// 索引由监控实时维护或已通过变更日志追赶到最新，直接按索引复制，无需遍历文件树
+		e.emit(Event{Kind: EventScanStarted, Path: source, Message: i18n.T("按索引")})
+		e.setTotals(idx.Stats())
+		for _, relPath := range idx.Paths() {
+			path := filepath.Join(source, relPath)
+			info, statErr := os.Lstat(path)
+			if statErr != nil {
+				if os.IsNotExist(statErr) {
+					// 文件在索引更新前已被删除
+					e.emit(Event{Kind: EventFileSkipped, Path: path, Files: fileCount, Bytes: totalSize, Message: i18n.T("文件已被删除")})
+					continue
+				}
+				err = i18n.Errorf("访问文件失败: %v\n文件: %s", statErr, path)
+				break
+			}
+			if filter.Exclude(relPath, info) {
+				continue
+			}
+			if err = visit(path, relPath, info); err != nil {
+				break
+			}
+		}
+	} else {
+		// 遍历前记录变更日志位置，遍历期间发生的变更留到下次枚举
+		cursor, _ := journalCurrent(source)
+		e.emit(Event{Kind: EventScanStarted, Path: source, Message: i18n.T("遍历文件树")})
+		e.setTotals(prescan(ctx, readRoot, filter))
+
+		err = filepath.Walk(readRoot, walkVisit)
+
+		// 完整遍历的结果顺便用于刷新索引，排除了部分文件时结果不完整
+		if err == nil && idxErr == nil && !filter.Active() {
+			idx.Reset(newEntries)
+			idx.SetCursor(cursor)
+		}
+	}
+
+	// 等待所有复制完成，遍历出错时仍需等待已提交的复制结束
+	if closeErr := pool.Close(); err == nil {
+		err = closeErr
+	}
+	walked := err == nil
+	if err == nil {
+		err = failures.Err()
+	}
+	// 取消后遍历和复制返回的各种错误统一为 ErrCancelled，未完成的快照没有保留的意义
+	if err != nil && ctx.Err() != nil {
+		err = ErrCancelled
+		if !dryRun && !quickSync {
+			if removeErr := dest.RemoveAll(backupDir); removeErr != nil {
+				slog.Warn("删除未完成的快照失败", "err", removeErr)
+			}
+		}
+	}
+	e.setStage(StageFinishing, i18n.T("保存清单和索引"))
+
+	// 删除镜像中源文件夹已经没有的文件，需要在设置目录属性之前
+	if err == nil && len(remote) > 0 {
+		var removed int
+		trash := ""
+		if e.Config.MirrorTrash {
+			trash = filepath.Join(mirrorTrashDir(e.Config.DestinationPath, source), timestamp)
+		}
+		removed, err = e.removeStale(dest, backupDir, remote, trash)
+		if removed > 0 && trash != "" {
+			e.status(i18n.Sprintf("已把目标中 %d 个多余的文件移到回收文件夹 %s", removed, trash))
+		} else if removed > 0 {
+			e.status(i18n.Sprintf("已删除目标中 %d 个多余的文件", removed))
+		}
+	}
+
+	// 从最深的目录开始设置，子目录的属性不会再影响上级目录
+	if err == nil {
+		for i := len(dirs) - 1; i >= 0; i-- {
+			dir := dirs[i]
+			if attrErr := dest.SetAttributes(filepath.Join(backupDir, dir.RelPath), dir.Mode, dir.ModTime); attrErr != nil {
+				slog.Warn("设置目录属性失败", "dir", dir.RelPath, "err", attrErr)
+			}
+		}
+	}
+	if idxErr == nil && idx.Dirty() {
+		if saveErr := idx.Save(); saveErr != nil {
+			slog.Warn("保存索引失败", "err", saveErr)
+		}
+	}
+	// 哈希索引描述本次快照中的文件。取消时未完成的快照已删除，上次的索引仍然有效；
+	// 快速同步的镜像中没有处理到的文件保持原样，保留它们的记录
+	if hashes != nil && !dryRun {
+		cancelled := errors.Is(err, ErrCancelled)
+		if !cancelled || quickSync {
+			if saveErr := hashes.save(newHashes, cancelled); saveErr != nil {
+				slog.Warn("保存哈希索引失败", "err", saveErr)
+			}
+		}
+	}
+
+	// 完成归档。复制失败的文件不影响已写入的内容，与目录快照一样保留
+	if archive != nil && ctx.Err() == nil {
+		if closeErr := archive.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	// 计算删除的文件数
+	deletedFiles, diffErr := diff.Finish()
+	if diffErr != nil {
+		slog.Warn("读取上一个快照的清单失败", "err", diffErr)
+	}
+
+	// 只为成功的快照保留清单
+	snapshotManifest := ""
+	contentHash := ""
+	if manifest != nil {
+		if err == nil {
+			if err = manifest.Close(); err == nil {
+				snapshotManifest = manifestPath(backupDir)
+				contentHash = manifest.Sum()
+			}
+		} else {
+			manifest.Abort()
+		}
+	}
+	if streams != nil {
+		if err == nil {
+			err = streams.Close()
+		} else {
+			streams.Abort()
+		}
+	}
+
+	// 记录备份历史
+	record = &history.Record{
+		Timestamp:      time.Now(),
+		SourcePath:     e.Config.SourcePath,
+		DestPath:       backupDir, // Fix: Use the actual backup directory
+		FileCount:      fileCount,
+		TotalSize:      totalSize,
+		Success:        err == nil,
+		Duration:       time.Since(startTime), // Fix: Use startTime for duration calculation
+		NewFiles:       newFiles,
+		ModifiedFiles:  modifiedFiles,
+		LinkedFiles:    linkedFiles,
+		PooledFiles:    int(pooledFiles.Load()),
+		DeletedFiles:   deletedFiles,
+		FailedFiles:    failures.count,
+		StreamFiles:    streamFiles,
+		StreamsLost:    streamsLost,
+		ManifestPath:   snapshotManifest,
+		ContentHash:    contentHash,
+		PeakMemory:     sampler.Stop(),
+		Icon:           e.Config.Icon,
+		Color:          e.Config.Color,
+		DryRun:         dryRun,
+		Attempt:        attempt,
+		Cancelled:      errors.Is(err, ErrCancelled),
+		Changes:        changes.changes,
+		ChangesOmitted: changes.omitted,
+	}
+	if encrypted != nil {
+		record.Encrypted = true
+		record.EncryptedNames = encrypted.ObfuscateNames
+	}
+	if archiveFormat != "" && e.Config.Encryption.Enabled {
+		record.Encrypted = true
+	}
+
+	if err == nil && quickSync && !dryRun {
+		e.supersedeMirror(backupDir)
+	}
+
+	// 推送和导出失败不影响本地快照，只在状态中提示。归档模式的快照是单个文件，不推送也不导出
+	var warnings []string
+	if snapshotWarning != "" {
+		warnings = append(warnings, snapshotWarning)
+	}
+	if synced != nil && len(synced.Conflicts) > 0 {
+		warnings = append(warnings, i18n.Sprintf("双向同步有 %d 个冲突等待处理", len(synced.Conflicts)))
+	}
+	// 备份后校验发现的问题同样只提示，不一致的文件记录在历史中
+	verified := false
+	if err == nil && e.Config.VerifyAfterBackup && !dryRun {
+		if storage.IsRemote(e.Config.DestinationPath) {
+			warnings = append(warnings, i18n.T("远程目标不支持备份后校验"))
+		} else {
+			e.status(i18n.T("正在校验备份..."))
+			mismatches, verifyErr := e.verifyBackup(readRoot, *record)
+			for _, mismatch := range mismatches {
+				record.Mismatches = append(record.Mismatches, mismatch.String())
+			}
+			issues = append(issues, mismatches...)
+			verified = verifyErr == nil
+			record.Verified = verifyErr == nil && len(mismatches) == 0
+			if record.Verified {
+				record.VerifiedAt = time.Now()
+			}
+			if verifyErr != nil {
+				warnings = append(warnings, i18n.T("校验备份失败: ")+verifyErr.Error())
+			} else if len(mismatches) > 0 {
+				warnings = append(warnings, i18n.Sprintf("校验发现 %d 个文件与源文件不一致", len(mismatches)))
+			}
+		}
+	}
+	// 遍历完整时更新复查队列，本次检查过的路径没有再出现问题的移出队列
+	if walked && !dryRun {
+		checked := []string{IssueCopyFailed, IssueCollision}
+		if verified {
+			checked = append(checked, IssueMismatch)
+		}
+		e.Config.updateReview(issues, checked, time.Now())
+		if len(issues) > 0 {
+			warnings = append(warnings, i18n.Sprintf("%d 个问题已加入复查队列", len(issues)))
+		}
+	}
+	if streamsLost > 0 {
+		if e.Config.CaptureStreams {
+			warnings = append(warnings, i18n.Sprintf("%d 个文件的附加数据流无法读取，没有备份", streamsLost))
+		} else {
+			warnings = append(warnings, i18n.Sprintf("%d 个文件带有备用数据流或扩展属性（资源分支、标签等），没有开启备份附加数据流", streamsLost))
+		}
+	}
+	// 快照大小骤变可能是源文件夹被误清空，提醒并记录，保留策略在确认前不会清理旧快照
+	if err == nil && !dryRun && !e.Config.IgnoreSizeSwings {
+		if anomaly := DetectSizeAnomaly(e.Config.History, *record); anomaly != "" {
+			record.SizeAnomaly = anomaly
+			warnings = append(warnings, anomaly)
+		}
+	}
+	if err == nil && e.Config.Peer.Enabled && !dryRun && archive == nil {
+		if peerErr := e.pushToPeer(backupDir); peerErr != nil {
+			warnings = append(warnings, i18n.T("推送到局域网设备失败: ")+peerErr.Error())
+		}
+	}
+	if err == nil && e.Config.InteropLayout != "" && !dryRun && archive == nil {
+		if interopErr := e.exportInterop(record); interopErr != nil {
+			warnings = append(warnings, i18n.T("导出失败: ")+interopErr.Error())
+		}
+	}
+
+	if err == nil && len(e.Config.Replicas) > 0 && !dryRun {
+		warnings = append(warnings, e.replicate(ctx, *record)...)
+	}
+	// 较旧的快照转入冷存储，失败时下次备份再转
+	if err == nil && !dryRun {
+		if _, tierErr := e.tierSnapshots(time.Now()); tierErr != nil {
+			warnings = append(warnings, tierErr.Error())
+		}
+	}
+
+	if err == nil && e.Config.ShareIndex && !dryRun {
+		if storage.IsRemote(e.Config.DestinationPath) {
+			warnings = append(warnings, i18n.T("远程目标不支持共享索引"))
+		} else if indexErr := e.writeShareIndex(append(append([]history.Record(nil), e.Config.History...), *record), backupDir); indexErr != nil {
+			warnings = append(warnings, i18n.T("生成共享索引失败: ")+indexErr.Error())
+		}
+	}
+
+	if errors.Is(err, ErrCancelled) {
+		record.ErrorMessage = err.Error()
+		e.status(i18n.T("备份已取消"))
+	} else if err != nil {
+		record.ErrorMessage = err.Error()
+		e.status(i18n.T("备份失败: ") + err.Error())
+	} else if dryRun {
+		e.Simulated("完成：新增 %d、修改 %d、删除 %d 个文件，共 %d 个文件 %.2f MB",
+			newFiles, modifiedFiles, deletedFiles, fileCount, float64(totalSize)/(1024*1024))
+		e.status(i18n.T("模拟备份完成，详情见命令输出"))
+	} else if len(warnings) > 0 {
+		e.status(i18n.T("备份完成，但") + strings.Join(warnings, i18n.T("；")))
+	} else {
+		e.status(i18n.T("备份完成"))
+	}
+
+	return record, err
+}
+
+// 复制单个文件，无权读取时交给提权辅助进程。在复制工作协程中调用
+func (e *Engine) copyFile(ctx context.Context, dest storage.Backend, path, destPath string) error {
+	if err := dest.CopyFile(ctx, path, destPath); err != nil {
+		if !e.Config.ElevatedRead || !permissionDenied(path) {
+			return i18n.Errorf("复制文件失败: %v\n源文件: %s\n目标文件: %s", err, path, destPath)
+		}
+		// 无权读取的文件交给提权辅助进程，辅助进程直接写入目标，不能用于加密和归档的备份
+		if !writesDirectly(dest) {
+			return i18n.Errorf("复制文件失败: %v\n源文件: %s\n加密或归档的备份不能通过提权辅助进程读取", err, path)
+		}
+		helper, helperErr := e.elevatedHelper()
+		if helperErr != nil {
+			return i18n.Errorf("复制文件失败: %v\n源文件: %s", helperErr, path)
+		}
+		if err := helper.CopyFile(path, destPath); err != nil {
+			return i18n.Errorf("复制受保护文件失败: %v\n源文件: %s", err, path)
+		}
+	}
+	return nil
+}
+
+// 是否直接写入目标文件夹，提权辅助进程只能用于这种目标
+func writesDirectly(dest storage.Backend) bool {
+	switch dest.(type) {
+	case *storage.Encrypted, *storage.Archive:
+		return false
+	}
+	return true
+}
+
+// 写入一条清单记录
+func addEntry(manifest *ManifestWriter, entry ManifestEntry) error {
+	if err := manifest.Add(entry); err != nil {
+		return i18n.Errorf("写入清单失败: %v", err)
+	}
+	return nil
+}
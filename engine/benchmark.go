@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+const (
+	benchmarkReadLimit  = 256 * 1024 * 1024 // 源读取测试最多读取的数据量
+	benchmarkReadTime   = 10 * time.Second  // 源读取测试最长时间
+	benchmarkWriteSize  = 64 * 1024 * 1024  // 每种缓冲区大小写入的数据量
+	benchmarkHashAmount = 256 * 1024 * 1024 // 哈希测试的数据量
+	benchmarkUploadSize = 16 * 1024 * 1024  // 通过存储后端复制到目标的测试文件大小
+)
+
+// 测试的写入缓冲区大小
+var benchmarkBufferSizes = []int{64 * 1024, 256 * 1024, 1024 * 1024, 4 * 1024 * 1024}
+
+// 性能测试结果
+type BenchmarkResult struct {
+	SourceFiles        int
+	SourceBytes        int64
+	SourceReadMBps     float64
+	SourceFilesPerSec  float64
+	DestWriteMBps      map[int]float64 // 缓冲区大小 -> 写入速度，远程目标不测试
+	Remote             bool            // 目标是 WebDAV、S3 等远程目标
+	UploadMBps         float64         // 通过存储后端复制到目标的速度，远程目标即上传带宽
+	HashMBps           float64
+	RecommendedWorkers int
+	RecommendedBuffer  int
+}
+
+func mbps(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) / (1024 * 1024) / elapsed.Seconds()
+}
+
+// 测试源文件夹读取速度
+func benchmarkSourceRead(source string, result *BenchmarkResult) error {
+	buf := make([]byte, 256*1024)
+	start := time.Now()
+	errStop := fmt.Errorf("stop")
+
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // 测试中跳过无法访问的文件
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		n, _ := io.CopyBuffer(io.Discard, file, buf)
+		file.Close()
+
+		result.SourceFiles++
+		result.SourceBytes += n
+		if result.SourceBytes >= benchmarkReadLimit || time.Since(start) >= benchmarkReadTime {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return i18n.Errorf("读取源文件夹失败: %v", err)
+	}
+
+	elapsed := time.Since(start)
+	result.SourceReadMBps = mbps(result.SourceBytes, elapsed)
+	if elapsed > 0 {
+		result.SourceFilesPerSec = float64(result.SourceFiles) / elapsed.Seconds()
+	}
+	return nil
+}
+
+// 使用不同缓冲区大小测试目标文件夹写入速度
+func benchmarkDestWrite(dest string, result *BenchmarkResult) error {
+	result.DestWriteMBps = make(map[int]float64)
+	for _, size := range benchmarkBufferSizes {
+		buf := make([]byte, size)
+		for i := range buf {
+			buf[i] = byte(i)
+		}
+
+		tmpFile := filepath.Join(dest, fmt.Sprintf(".syncsafe-bench-%d.tmp", time.Now().UnixNano()))
+		file, err := os.Create(tmpFile)
+		if err != nil {
+			return i18n.Errorf("创建测试文件失败: %v", err)
+		}
+
+		start := time.Now()
+		var written int64
+		for written < benchmarkWriteSize {
+			n, err := file.Write(buf)
+			if err != nil {
+				file.Close()
+				os.Remove(tmpFile)
+				return i18n.Errorf("写入测试文件失败: %v", err)
+			}
+			written += int64(n)
+		}
+		err = file.Sync()
+		elapsed := time.Since(start)
+		file.Close()
+		os.Remove(tmpFile)
+		if err != nil {
+			return i18n.Errorf("同步测试文件失败: %v", err)
+		}
+
+		result.DestWriteMBps[size] = mbps(written, elapsed)
+	}
+	return nil
+}
+
+// 通过存储后端把一个测试文件复制到目标，与备份时复制文件的方式相同，远程目标即测试上传带宽
+func benchmarkUpload(dest storage.Backend, destPath string, result *BenchmarkResult) error {
+	local, err := os.CreateTemp("", "syncsafe-bench-*.tmp")
+	if err != nil {
+		return i18n.Errorf("创建测试文件失败: %v", err)
+	}
+	defer os.Remove(local.Name())
+	buf := make([]byte, 1024*1024)
+	for i := range buf {
+		buf[i] = byte(i * 13)
+	}
+	for written := 0; written < benchmarkUploadSize; written += len(buf) {
+		if _, err := local.Write(buf); err != nil {
+			local.Close()
+			return i18n.Errorf("写入测试文件失败: %v", err)
+		}
+	}
+	if err := local.Close(); err != nil {
+		return i18n.Errorf("写入测试文件失败: %v", err)
+	}
+
+	target := filepath.Join(filepath.Clean(destPath), fmt.Sprintf(".syncsafe-bench-%d.tmp", time.Now().UnixNano()))
+	start := time.Now()
+	err = dest.CopyFile(context.Background(), local.Name(), target)
+	elapsed := time.Since(start)
+	dest.RemoveAll(target)
+	if err != nil {
+		return i18n.Errorf("复制测试文件到目标失败: %v", err)
+	}
+	result.UploadMBps = mbps(benchmarkUploadSize, elapsed)
+	return nil
+}
+
+// 测试 SHA-256 哈希吞吐量
+func benchmarkHash(result *BenchmarkResult) {
+	buf := make([]byte, 1024*1024)
+	for i := range buf {
+		buf[i] = byte(i * 7)
+	}
+	hash := sha256.New()
+	start := time.Now()
+	for hashed := 0; hashed < benchmarkHashAmount; hashed += len(buf) {
+		hash.Write(buf)
+	}
+	result.HashMBps = mbps(benchmarkHashAmount, time.Since(start))
+}
+
+// 根据测试结果推荐并发数和缓冲区大小
+func (r *BenchmarkResult) recommend() {
+	// 远程目标的写入由服务器决定，不推荐缓冲区大小
+	if !r.Remote {
+		for _, size := range benchmarkBufferSizes {
+			if r.RecommendedBuffer == 0 || r.DestWriteMBps[size] > r.DestWriteMBps[r.RecommendedBuffer] {
+				r.RecommendedBuffer = size
+			}
+		}
+	}
+
+	workers := runtime.NumCPU()
+	switch {
+	case r.SourceReadMBps < 150:
+		// 读取较慢（机械硬盘或网络路径），并发过多只会增加寻道
+		workers = 2
+	case r.SourceFiles > 0 && r.SourceBytes/int64(r.SourceFiles) < 256*1024:
+		// 大量小文件时瓶颈在打开/关闭文件，可以提高并发
+		workers = runtime.NumCPU() * 2
+	}
+	if workers > 16 {
+		workers = 16
+	}
+	r.RecommendedWorkers = workers
+}
+
+// 运行完整的性能测试。本地目标测试不同缓冲区大小的写入速度，
+// 所有目标都通过存储后端测试复制速度，WebDAV 目标即上传带宽
+func (e *Engine) RunBenchmark(progress func(string)) (BenchmarkResult, error) {
+	result := BenchmarkResult{Remote: storage.IsRemote(e.Config.DestinationPath)}
+
+	progress(i18n.T("正在测试源文件夹读取速度..."))
+	if err := benchmarkSourceRead(e.SourcePath(), &result); err != nil {
+		return result, err
+	}
+
+	if !result.Remote {
+		progress(i18n.T("正在测试目标文件夹写入速度..."))
+		if err := benchmarkDestWrite(e.Config.DestinationPath, &result); err != nil {
+			return result, err
+		}
+		progress(i18n.T("正在测试复制到目标的速度..."))
+	} else {
+		progress(i18n.T("正在测试上传速度..."))
+	}
+	if err := benchmarkUpload(e.Destination(), e.Config.DestinationPath, &result); err != nil {
+		return result, err
+	}
+
+	progress(i18n.T("正在测试哈希速度..."))
+	benchmarkHash(&result)
+
+	result.recommend()
+	return result, nil
+}
+
+// 格式化测试报告
+func (r *BenchmarkResult) Report() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, i18n.T("源文件夹读取: %.1f MB/s (%d 个文件, %.1f 文件/秒)\n"),
+		r.SourceReadMBps, r.SourceFiles, r.SourceFilesPerSec)
+	if r.Remote {
+		fmt.Fprintf(&sb, i18n.T("云端上传: %.1f MB/s\n"), r.UploadMBps)
+	} else {
+		sb.WriteString(i18n.T("目标文件夹写入:\n"))
+		for _, size := range benchmarkBufferSizes {
+			fmt.Fprintf(&sb, i18n.T("  缓冲区 %4d KB: %.1f MB/s\n"), size/1024, r.DestWriteMBps[size])
+		}
+		fmt.Fprintf(&sb, i18n.T("复制到目标: %.1f MB/s\n"), r.UploadMBps)
+	}
+	fmt.Fprintf(&sb, i18n.T("SHA-256 哈希: %.1f MB/s\n"), r.HashMBps)
+	sb.WriteString(i18n.T("\n推荐设置:\n"))
+	fmt.Fprintf(&sb, i18n.T("  并发复制数: %d\n"), r.RecommendedWorkers)
+	if r.RecommendedBuffer > 0 {
+		fmt.Fprintf(&sb, i18n.T("  缓冲区大小: %d KB\n"), r.RecommendedBuffer/1024)
+	}
+	if r.HashMBps < r.SourceReadMBps {
+		sb.WriteString(i18n.T("  注意: 哈希速度低于读取速度，启用校验会成为瓶颈\n"))
+	}
+	return sb.String()
+}
@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 暂停时段：期间不执行自动备份，结束后补做一次备份。手动备份不受影响
+type BlackoutPeriod struct {
+	Name   string
+	Start  time.Time // 开始日期（含）
+	End    time.Time // 结束日期（含）
+	Yearly bool      // 每年重复，只比较月和日
+}
+
+const BlackoutDateLayout = "2006-01-02"
+
+// 当天零点
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// 月日组成的比较键，用于每年重复的时段
+func monthDayKey(t time.Time) int {
+	return int(t.Month())*100 + t.Day()
+}
+
+// 时间 t 是否处于暂停时段内
+func (p BlackoutPeriod) Contains(t time.Time) bool {
+	if p.Yearly {
+		key, start, end := monthDayKey(t), monthDayKey(p.Start), monthDayKey(p.End)
+		if start <= end {
+			return key >= start && key <= end
+		}
+		// 跨年的时段，例如 12-24 到 01-02
+		return key >= start || key <= end
+	}
+	day := dateOf(t)
+	return !day.Before(dateOf(p.Start)) && !day.After(dateOf(p.End))
+}
+
+// 包含时间 t 的这一次暂停时段的结束时刻（结束日期次日零点，按 t 所在的时区）
+func (p BlackoutPeriod) EndAfter(t time.Time) time.Time {
+	end := dateOf(p.End)
+	if p.Yearly {
+		end = time.Date(t.Year(), p.End.Month(), p.End.Day(), 0, 0, 0, 0, time.Local)
+		if end.Before(dateOf(t)) {
+			end = end.AddDate(1, 0, 0)
+		}
+	}
+	return time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+func (p BlackoutPeriod) String() string {
+	if p.Yearly {
+		return i18n.Sprintf("%s（每年 %s 至 %s）", p.Name, p.Start.Format("01-02"), p.End.Format("01-02"))
+	}
+	return i18n.Sprintf("%s（%s 至 %s）", p.Name, p.Start.Format(BlackoutDateLayout), p.End.Format(BlackoutDateLayout))
+}
+
+// 当前生效的暂停时段，日期按任务的时区计算
+func (c *Config) ActiveBlackout(t time.Time) (BlackoutPeriod, bool) {
+	t = t.In(c.Location())
+	for _, p := range c.Blackouts {
+		if p.Contains(t) {
+			return p, true
+		}
+	}
+	return BlackoutPeriod{}, false
+}
+
+// 从 iCalendar (.ics) 节假日日历中读取全部事件作为暂停时段。
+// 全天事件的 DTEND 不包含在内，带有 FREQ=YEARLY 的事件视为每年重复
+func ParseICS(r io.Reader) ([]BlackoutPeriod, error) {
+	// 展开折行：以空格或制表符开头的行是上一行的延续
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	parseDate := func(value string) (time.Time, bool, error) {
+		if len(value) < 8 {
+			return time.Time{}, false, i18n.Errorf("日期格式错误: %s", value)
+		}
+		t, err := time.ParseInLocation("20060102", value[:8], time.Local)
+		return t, len(value) == 8, err
+	}
+
+	var periods []BlackoutPeriod
+	var current *BlackoutPeriod
+	var hasEnd, allDayEnd bool
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				current = &BlackoutPeriod{}
+				hasEnd, allDayEnd = false, false
+			}
+		case "END":
+			if strings.EqualFold(value, "VEVENT") && current != nil {
+				if current.Start.IsZero() {
+					return nil, i18n.Errorf("事件 %s 缺少开始日期", current.Name)
+				}
+				if !hasEnd {
+					current.End = current.Start
+				} else if allDayEnd && current.End.After(current.Start) {
+					current.End = current.End.AddDate(0, 0, -1)
+				}
+				if current.Name == "" {
+					current.Name = i18n.T("节假日")
+				}
+				periods = append(periods, *current)
+				current = nil
+			}
+		case "SUMMARY":
+			if current != nil {
+				current.Name = strings.ReplaceAll(value, "\\,", ",")
+			}
+		case "DTSTART":
+			if current != nil {
+				t, _, err := parseDate(value)
+				if err != nil {
+					return nil, err
+				}
+				current.Start = t
+			}
+		case "DTEND":
+			if current != nil {
+				t, allDay, err := parseDate(value)
+				if err != nil {
+					return nil, err
+				}
+				current.End, hasEnd, allDayEnd = t, true, allDay
+			}
+		case "RRULE":
+			if current != nil && strings.Contains(strings.ToUpper(value), "FREQ=YEARLY") {
+				current.Yearly = true
+			}
+		}
+	}
+	return periods, nil
+}
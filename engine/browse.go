@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"syncsafe/crypt"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 快照中的一个文件或目录
+type SnapshotEntry struct {
+	Path    string // 相对快照根目录的路径，文件名加密时为加密后的名称
+	Name    string // 显示名称，加密的文件名已解密
+	Size    int64  // 原始文件的大小
+	ModTime time.Time
+	IsDir   bool
+}
+
+// 浏览一个快照：本地目录快照在展开目录时才读取，归档快照和远程快照打开时读取一次目录结构
+type SnapshotBrowser struct {
+	engine *Engine
+	record history.Record
+	key    *crypt.Key
+	tree   *ArchiveTree // 本地目录快照为 nil
+}
+
+// 打开快照用于浏览，加密的快照需要填写密码短语
+func (e *Engine) BrowseSnapshot(record history.Record) (*SnapshotBrowser, error) {
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return nil, err
+	}
+	s := &SnapshotBrowser{engine: e, record: record, key: key}
+	switch {
+	case storage.IsRemote(record.DestPath):
+		s.tree, err = e.LoadRemoteTree(record.DestPath)
+	case IsArchiveSnapshot(record.DestPath):
+		s.tree, err = e.LoadArchiveTree(record.DestPath)
+	default:
+		if _, err = os.Stat(record.DestPath); err != nil {
+			err = i18n.Errorf("快照不存在: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dir 中的文件和目录，目录排在文件之前，按显示名称排列。根目录为空字符串
+func (s *SnapshotBrowser) List(dir string) ([]SnapshotEntry, error) {
+	var infos []os.FileInfo
+	if s.tree != nil {
+		infos = s.tree.Children(dir)
+	} else {
+		entries, err := os.ReadDir(filepath.Join(s.record.DestPath, dir))
+		if err != nil {
+			return nil, i18n.Errorf("读取快照失败: %v", err)
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				infos = append(infos, info)
+			}
+		}
+	}
+	list := make([]SnapshotEntry, 0, len(infos))
+	for _, info := range infos {
+		entry := SnapshotEntry{Path: filepath.Join(dir, info.Name()), Name: info.Name(), Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}
+		if s.key != nil {
+			if s.record.EncryptedNames {
+				if name, err := s.key.DecryptName(info.Name()); err == nil {
+					entry.Name = name
+				}
+			}
+			if !entry.IsDir {
+				entry.Size, _ = crypt.PlainSize(entry.Size)
+			}
+		}
+		list = append(list, entry)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].IsDir != list[j].IsDir {
+			return list[i].IsDir
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// 预览读取到足够的内容后停止遍历快照
+var errPreviewDone = errors.New("preview done")
+
+// 读取快照中文件开头的至多 limit 字节（加密的快照解密），用于预览。
+// 返回的内容不完整时第二个返回值为 true
+func (s *SnapshotBrowser) Preview(relPath string, limit int) ([]byte, bool, error) {
+	buf := &previewBuffer{limit: limit}
+	found := false
+	err := s.engine.walkRestore(s.record.DestPath, []string{relPath}, func(path string, info os.FileInfo, content io.Reader) error {
+		if path != relPath {
+			return nil
+		}
+		found = true
+		if content == nil {
+			file, err := os.Open(filepath.Join(s.record.DestPath, path))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			content = file
+		}
+		var err error
+		if s.key != nil {
+			err = s.key.Decrypt(buf, content)
+		} else {
+			_, err = io.Copy(buf, content)
+		}
+		if err != nil && !buf.full {
+			return err
+		}
+		return errPreviewDone
+	})
+	if err != nil && !errors.Is(err, errPreviewDone) {
+		return nil, false, i18n.Errorf("读取快照中的文件失败: %v", err)
+	}
+	if !found {
+		return nil, false, i18n.Errorf("快照中没有该文件: %s", relPath)
+	}
+	return buf.data, buf.full, nil
+}
+
+// 只保留前 limit 字节，写满后返回错误使复制停止
+type previewBuffer struct {
+	data  []byte
+	limit int
+	full  bool
+}
+
+func (b *previewBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - len(b.data); len(p) > room {
+		b.data = append(b.data, p[:room]...)
+		b.full = true
+		return room, errPreviewDone
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
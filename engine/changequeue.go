@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 按变化备份：监控期间记录变化的路径，下一次备份只读取这些路径，
+// 其余文件直接沿用上一个快照的清单（增量快照硬链接，快速同步保留镜像中的文件），
+// 不再遍历和逐个读取整个源文件夹。以下情况改为完整遍历：
+// 还没有监控期间完成的快照、变化不完整（事件风暴、超过上限）、影响文件选择的设置有变化、
+// 规则文件有变化，以及距上次完整遍历已经过了 fullScanInterval 或 fullScanRuns 次
+const (
+	maxQueuedChanges = 10000          // 最多记录的变化路径，超过后下一次备份完整遍历
+	fullScanRuns     = 50             // 连续按变化备份的最多次数
+	fullScanInterval = 24 * time.Hour // 两次完整遍历的最长间隔
+)
+
+// 监控期间变化的路径
+type changeQueue struct {
+	mu       sync.Mutex
+	root     string          // 记录变化时的源文件夹，为空表示没有在记录
+	paths    map[string]bool // 相对源文件夹的路径
+	overflow bool            // 变化不完整
+	basis    history.Record  // 变化相对的快照，没有时 DestPath 为空
+	key      string          // 生成 basis 时影响文件选择的设置
+	runs     int             // 上次完整遍历之后按变化备份的次数
+	lastFull time.Time       // 上次完整遍历的时间
+}
+
+// 开始记录 root 中的变化。在此之前的变化未知，第一次备份完整遍历
+func (e *Engine) TrackChanges(root string) {
+	q := &e.changes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.root = filepath.Clean(root)
+	q.paths = nil
+	q.overflow = false
+	q.basis = history.Record{}
+}
+
+// 停止记录变化，之后的备份完整遍历
+func (e *Engine) StopTrackingChanges() {
+	q := &e.changes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.root = ""
+	q.paths = nil
+	q.basis = history.Record{}
+}
+
+// 记录一个变化的路径（绝对路径），没有在记录时忽略
+func (e *Engine) ChangeObserved(path string) {
+	q := &e.changes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.root == "" || q.overflow {
+		return
+	}
+	relPath, err := filepath.Rel(q.root, filepath.Clean(path))
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return
+	}
+	if q.paths == nil {
+		q.paths = make(map[string]bool)
+	}
+	if len(q.paths) >= maxQueuedChanges {
+		q.overflow = true
+		q.paths = nil
+		return
+	}
+	q.paths[relPath] = true
+}
+
+// 变化不完整（例如事件风暴），下一次备份完整遍历
+func (e *Engine) ChangesOverflowed() {
+	q := &e.changes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.overflow = true
+	q.paths = nil
+}
+
+// 取出记录的变化，按文件树顺序排列。只有变化相对 last 完整且不需要完整遍历时返回 true。
+// 无论是否返回 true 都清空记录，之后的变化留给下一次备份
+func (q *changeQueue) take(root string, last history.Record, key string, now time.Time) ([]string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	paths, overflow := q.paths, q.overflow
+	q.paths, q.overflow = nil, false
+	if q.root == "" || q.root != filepath.Clean(root) || overflow || q.basis.DestPath == "" || !q.basis.Same(last) || q.key != key ||
+		q.runs >= fullScanRuns || now.Sub(q.lastFull) >= fullScanInterval {
+		return nil, false
+	}
+	relPaths := make([]string, 0, len(paths))
+	for relPath := range paths {
+		// 规则文件变化后被排除的文件可能不同
+		if slices.Contains(IgnoreFiles, relPath) {
+			return nil, false
+		}
+		relPaths = append(relPaths, relPath)
+	}
+	slices.SortFunc(relPaths, comparePaths)
+	return relPaths, true
+}
+
+// 备份结束：成功写入快照时之后的变化相对该快照，失败时下一次完整遍历
+func (q *changeQueue) finish(record *history.Record, key string, selective bool, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.root == "" {
+		return
+	}
+	if record == nil || !record.HasSnapshot() {
+		q.basis = history.Record{}
+		return
+	}
+	q.basis, q.key = *record, key
+	if selective {
+		q.runs++
+	} else {
+		q.runs, q.lastFull = 0, now
+	}
+}
+
+// 按文件树顺序合并上一个快照的清单和变化的路径：没有变化的条目交给 reuse，
+// 变化的路径交给 rescan，清单中位于已重新读取的路径之下的条目不再沿用。读完后关闭 reader
+func mergeChanges(reader *ManifestReader, changed []string, reuse func(ManifestEntry) error, rescan func(relPath string) error) error {
+	defer reader.Close()
+	covered := "" // 最近重新读取的路径
+	under := func(relPath string) bool {
+		return covered != "" && (relPath == covered || strings.HasPrefix(relPath, covered+string(filepath.Separator)))
+	}
+	next := func(relPath string) error {
+		if under(relPath) {
+			return nil
+		}
+		covered = relPath
+		return rescan(relPath)
+	}
+	i := 0
+	for {
+		entry, ok, err := reader.Next()
+		if err != nil {
+			return i18n.Errorf("读取上一个快照的清单失败: %v", err)
+		}
+		if !ok {
+			break
+		}
+		for ; i < len(changed) && comparePaths(changed[i], entry.RelPath) <= 0; i++ {
+			if err := next(changed[i]); err != nil {
+				return err
+			}
+		}
+		if under(entry.RelPath) {
+			continue
+		}
+		if err := reuse(entry); err != nil {
+			return err
+		}
+	}
+	for ; i < len(changed); i++ {
+		if err := next(changed[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 快照清单中的文件，用作快速同步镜像中已有的文件
+func manifestFiles(record history.Record) (map[string]storage.RemoteFile, error) {
+	reader := openSnapshotManifest(record)
+	if reader == nil {
+		return nil, i18n.Errorf("读取上一个快照的清单失败")
+	}
+	defer reader.Close()
+	files := make(map[string]storage.RemoteFile)
+	for {
+		entry, ok, err := reader.Next()
+		if err != nil {
+			return nil, i18n.Errorf("读取上一个快照的清单失败: %v", err)
+		}
+		if !ok {
+			return files, nil
+		}
+		if !entry.IsDir {
+			files[entry.RelPath] = storage.RemoteFile{Size: entry.Size, ModTime: entry.ModTime}
+		}
+	}
+}
+
+// relPath 所在的某一级目录是否被排除或是 .git 目录，此时 relPath 本身也不备份
+func (c *Config) ancestorExcluded(source, relPath string) bool {
+	filter := c.fileFilter()
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i := 1; i < len(parts); i++ {
+		dir := filepath.Join(parts[:i]...)
+		if parts[i-1] == ".git" {
+			return true
+		}
+		info, err := os.Lstat(filepath.Join(source, dir))
+		if err != nil || filter.Exclude(dir, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// 影响备份哪些文件以及如何沿用上一个快照的设置，变化后需要完整遍历
+func (c *Config) selectionKey() string {
+	return fmt.Sprint(c.SourcePath, c.SourceFiles, c.DestinationPath, c.ExcludeHidden, c.ExcludeSystem, c.ExcludeDotfiles,
+		c.FilterRules, c.UseIgnoreFiles, c.SourceSnapshot, c.SkipDirectories, c.CaptureStreams, c.ChecksumCompare,
+		c.TrustMetadata, c.HashDirs, c.TrustDirs, c.Incremental, c.QuickSync, c.ArchiveFormat, c.Encryption.Enabled,
+		c.Encryption.ObfuscateNames)
+}
+
+// 上一个快照清单中的文件或目录
+type manifestFileInfo struct {
+	entry ManifestEntry
+}
+
+func (f manifestFileInfo) Name() string { return filepath.Base(f.entry.RelPath) }
+func (f manifestFileInfo) Size() int64  { return f.entry.Size }
+func (f manifestFileInfo) Mode() os.FileMode {
+	if f.entry.IsDir {
+		return os.ModeDir | f.entry.Mode
+	}
+	return 0644
+}
+func (f manifestFileInfo) ModTime() time.Time { return f.entry.ModTime }
+func (f manifestFileInfo) IsDir() bool        { return f.entry.IsDir }
+func (f manifestFileInfo) Sys() any           { return nil }
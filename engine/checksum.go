@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 启用 Config.ChecksumCompare 时，按 SHA-256 判断文件内容是否变化：修改时间被保留的内容变化也会备份，
+// 只有修改时间变化的文件不再重新复制。哈希索引记录上次备份时每个文件的哈希，
+// 每个源文件夹和目标文件夹的组合对应一个索引文件。快速模式和按目录设置不计算哈希的文件不记录在索引中
+type hashIndex struct {
+	path    string
+	entries map[string]hashEntry
+}
+
+// 上次备份时文件的大小、在目标中的修改时间和内容哈希
+type hashEntry struct {
+	Size    int64
+	ModTime time.Time // 目标中副本的修改时间，只有修改时间变化的文件硬链接时沿用原来的时间
+	Hash    string
+}
+
+func hashIndexPath(source, destination string) string {
+	sum := sha1.Sum([]byte(filepath.Clean(source) + "\x00" + filepath.Clean(destination)))
+	return filepath.Join(DataDir, "index", hex.EncodeToString(sum[:8])+".sums")
+}
+
+// 加载源文件夹和目标文件夹对应的哈希索引
+func loadHashIndex(source, destination string) *hashIndex {
+	return readHashIndex(hashIndexPath(source, destination))
+}
+
+// 读取哈希索引文件，不存在或损坏时返回空索引
+func readHashIndex(path string) *hashIndex {
+	h := &hashIndex{path: path, entries: make(map[string]hashEntry)}
+	file, err := os.Open(h.path)
+	if err != nil {
+		return h
+	}
+	defer file.Close()
+	var entries map[string]hashEntry
+	if err := gob.NewDecoder(file).Decode(&entries); err == nil && entries != nil {
+		h.entries = entries
+	}
+	return h
+}
+
+// 计算文件内容的 SHA-256
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// 是否为文件计算哈希：由 HashDirs 和 TrustDirs 中包含该文件的最深目录决定，
+// 都不包含时只有快速模式（TrustMetadata）不计算。不计算哈希的文件只按大小和修改时间判断
+func (c *Config) hashEnabled(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	enabled, depth := !c.TrustMetadata, -1
+	match := func(dirs []string, value bool) {
+		for _, dir := range dirs {
+			if (relPath == dir || strings.HasPrefix(relPath, dir+"/")) && len(dir) > depth {
+				enabled, depth = value, len(dir)
+			}
+		}
+	}
+	match(c.HashDirs, true)
+	match(c.TrustDirs, false)
+	return enabled
+}
+
+// 整理界面中输入的目录列表：每行一个相对源文件夹的目录，统一为 / 分隔，去掉空行和重复的目录
+func CleanHashDirs(lines []string) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		dir := filepath.ToSlash(filepath.Clean(filepath.FromSlash(line)))
+		if filepath.IsAbs(line) || strings.HasPrefix(dir, "/") || dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+			return nil, i18n.Errorf("目录必须是源文件夹中的相对路径: %s", line)
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// 按内容重新判断文件的变化。change 为按大小和修改时间判断的结果，
+// 返回按内容判断的结果和文件当前的哈希记录。文件无法读取时返回错误，调用方沿用 change
+func (h *hashIndex) compare(path string, entry ManifestEntry, change changeKind) (changeKind, hashEntry, error) {
+	hash, err := fileHash(path)
+	if err != nil {
+		return change, hashEntry{}, err
+	}
+	current := hashEntry{Size: entry.Size, ModTime: entry.ModTime, Hash: hash}
+	old, ok := h.entries[entry.RelPath]
+	if change == changeNew || !ok || old.Size != entry.Size {
+		return change, current, nil
+	}
+	if old.Hash == hash {
+		return changeUnchanged, current, nil
+	}
+	return changeModified, current, nil
+}
+
+// 上次备份时的记录
+func (h *hashIndex) get(relPath string) (hashEntry, bool) {
+	entry, ok := h.entries[relPath]
+	return entry, ok
+}
+
+// 用本次备份的记录替换索引并保存。merge 为 true 时保留本次没有处理到的文件的记录
+func (h *hashIndex) save(entries map[string]hashEntry, merge bool) error {
+	if merge {
+		for relPath, entry := range entries {
+			h.entries[relPath] = entry
+		}
+	} else {
+		h.entries = entries
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return i18n.Errorf("创建索引目录失败: %v", err)
+	}
+	tmpPath := h.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return i18n.Errorf("创建哈希索引失败: %v", err)
+	}
+	if err := gob.NewEncoder(file).Encode(h.entries); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return i18n.Errorf("写入哈希索引失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("写入哈希索引失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("保存哈希索引失败: %v", err)
+	}
+	return nil
+}
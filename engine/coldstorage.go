@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// S3 目标中保存超过 ColdAfterDays 天的快照转入冷存储类别（例如 Glacier），较新的快照留在设置的存储类别中，
+// 可以随时浏览和还原。冷存储中的快照要先取回才能读取，按选择的速度需要几分钟到两天
+
+// S3 目标的后端，目标不是 S3 时返回 nil
+func (e *Engine) s3Destination() *storage.S3 {
+	if !storage.IsS3(e.Config.DestinationPath) {
+		return nil
+	}
+	return storage.NewS3(e.Config.DestinationPath, e.Config.S3)
+}
+
+// 把保存超过 ColdAfterDays 天的快照转入冷存储，返回转入的快照数。
+// 快速同步的镜像每次备份都会更新，始终留在设置的存储类别中
+func (e *Engine) tierSnapshots(now time.Time) (int, error) {
+	s3 := e.s3Destination()
+	if s3 == nil || e.Config.S3.ColdAfterDays <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -e.Config.S3.ColdAfterDays)
+	destination := filepath.Clean(e.Config.DestinationPath)
+	count := 0
+	for i := range e.Config.History {
+		record := &e.Config.History[i]
+		if !record.HasSnapshot() || record.ColdStorage || !record.Timestamp.Before(cutoff) ||
+			filepath.Dir(record.DestPath) != destination ||
+			record.DestPath == mirrorDir(destination, ExpandPathTemplate(record.SourcePath, record.Timestamp)) {
+			continue
+		}
+		files, err := s3.Transition(record.DestPath, e.Config.S3.Cold())
+		if err != nil {
+			return count, i18n.Errorf("快照转入冷存储失败: %v", err)
+		}
+		record.ColdStorage = true
+		count++
+		slog.Info("快照已转入冷存储", "snapshot", record.DestPath, "class", e.Config.S3.Cold(), "files", files)
+	}
+	return count, nil
+}
+
+// 冷存储中的快照在当前 S3 目标中的后端
+func (e *Engine) coldSnapshot(record history.Record) (*storage.S3, error) {
+	s3 := e.s3Destination()
+	if s3 == nil || !storage.IsS3(record.DestPath) {
+		return nil, i18n.Errorf("快照不在 S3 目标中: %s", record.DestPath)
+	}
+	return s3, nil
+}
+
+// 快照中冷存储文件的取回状态，全部取回后才能浏览内容和还原
+func (e *Engine) SnapshotRetrieval(record history.Record) (storage.Retrieval, error) {
+	s3, err := e.coldSnapshot(record)
+	if err != nil {
+		return storage.Retrieval{}, err
+	}
+	status, err := s3.Retrieval(record.DestPath)
+	if err != nil {
+		return status, i18n.Errorf("查询取回状态失败: %v", err)
+	}
+	return status, nil
+}
+
+// 请求取回快照中冷存储的文件，tier 为 storage.RetrievalStandard 等，取回的副本保留 days 天。
+// 返回请求取回的文件数，已经在取回或已取回的文件跳过
+func (e *Engine) RetrieveSnapshot(record history.Record, tier string, days int) (int, error) {
+	s3, err := e.coldSnapshot(record)
+	if err != nil {
+		return 0, err
+	}
+	count, err := s3.Retrieve(record.DestPath, tier, days)
+	if err != nil {
+		return count, i18n.Errorf("请求取回快照失败: %v", err)
+	}
+	slog.Info("已请求从冷存储取回快照", "snapshot", record.DestPath, "tier", tier, "days", days, "files", count)
+	return count, nil
+}
@@ -0,0 +1,329 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"syncsafe/gitsync"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+	"syncsafe/peer"
+	"syncsafe/storage"
+)
+
+// 备份配置，即一个备份任务
+type Config struct {
+	Name               string // 任务名称，默认任务可以为空
+	Archived           bool   // 已归档：不再监控和定时备份，界面中隐藏，历史记录和快照保留
+	SourcePath         string
+	SourceFiles        []string // 只备份源文件夹中的这些文件（相对路径，以 / 分隔），为空表示备份整个文件夹
+	DestinationPath    string   // 本地文件夹、http(s) 开头的 WebDAV 地址，或 s3://存储桶/前缀
+	IsWatching         bool
+	LastBackupTime     time.Time
+	LastGitCommit      time.Time // 上次按计划批量提交 Git 的时间，只保存在本机
+	Git                gitsync.Config
+	ElevatedRead       bool   // 遇到无权读取的文件时通过提权辅助进程读取
+	SourceSnapshot     bool   // 复制前为源文件夹所在的文件系统（Btrfs、ZFS、LVM、APFS）创建只读快照，从快照中读取
+	PowerAction        string // 关机或睡眠前的操作
+	Icon               string // 配置图标
+	Color              string // 配置颜色
+	DryRun             bool   // 模拟模式：只记录写入操作，不实际执行
+	Blackouts          []BlackoutPeriod
+	Schedule           string   // 定时备份的 cron 表达式，为空表示不定时
+	TimeZone           string   // 定时计划和显示时间使用的时区（IANA 名称），为空表示本机时区
+	SchedulePaused     bool     // 暂停定时备份，保留表达式
+	AutoPaused         bool     // 连续失败后自动暂停：不再执行监控、定时和重试触发的备份，只保存在本机
+	ScrubSchedule      string   // 定期数据巡检的 cron 表达式，为空表示不巡检
+	RetryAttempts      int      // 自动备份失败后的重试次数
+	RetryDelay         int      // 重试间隔（分钟）
+	PauseAfterFailures int      // 连续失败多少次后自动暂停，0 表示 DefaultPauseAfterFailures，小于 0 表示从不暂停
+	CapacityThresholds []int    // 目标磁盘使用率提醒阈值（百分比）
+	KeepVersions       int      // 清理快照时每个文件至少保留的最近版本数，0 表示不保留
+	SkipUnchanged      bool     // 没有任何变化时不创建快照
+	ConfirmWatchBackup bool     // 监控触发备份前列出变化的文件，可以跳过本次备份
+	Incremental        bool     // 增量快照：未变化的文件硬链接到上一个快照
+	ChecksumCompare    bool     // 按内容（SHA-256）判断文件是否变化，而不是修改时间
+	VerifyAfterBackup  bool     // 备份完成后重新读出快照，与源文件的 SHA-256 比较
+	TrustMetadata      bool     // 快速模式：只按大小和修改时间判断，按内容比较和备份后校验都不计算哈希
+	HashDirs           []string // 始终计算哈希的目录（相对源文件夹，以 / 分隔），例如快速模式下的文档文件夹
+	TrustDirs          []string // 从不计算哈希的目录，例如媒体库。与 HashDirs 重叠时最深的目录优先
+	QuickSync          bool     // 快速同步：目标中只保留一个镜像，只上传有差异的文件
+	SyncPath           string   // 双向同步的另一侧文件夹，例如另一台电脑也在同步的共享文件夹，为空表示不同步，只保存在本机
+	SyncConflicts      string   // 双向同步中两侧都修改的文件的处理方式，见 ConflictManual 等
+	SharedPool         bool     // 去重存储池：同一目标文件夹下所有任务的快照共用按内容保存的文件，相同的文件只保存一份
+	MirrorTrash        bool     // 快速同步时把源文件夹中已删除的文件移到镜像旁的回收文件夹，而不是直接删除
+	ShareIndex         bool     // 每次备份后在目标文件夹生成静态 HTML 索引，可以用浏览器浏览快照
+	ArchiveFormat      string   // 归档模式：每次备份写入一个 tar.gz 或 zip 文件，为空表示复制为目录
+	CopyWorkers        int      // 并行复制文件的协程数，0 表示使用程序设置中的默认值
+	ExcludeHidden      bool     // 不备份隐藏文件
+	ExcludeSystem      bool     // 不备份系统文件（Windows）
+	ExcludeDotfiles    bool     // 不备份以 . 开头的文件和目录
+	SkipDirectories    bool     // 不保留空目录以及目录的权限和修改时间
+	CaptureStreams     bool     // 备份 NTFS 备用数据流和 macOS 扩展属性（资源分支、Finder 信息和标签），还原时写回
+	FilterRules        []string // 排除规则，语法与 .gitignore 相同，以 ! 开头的规则重新包含
+	UseIgnoreFiles     bool     // 同时遵守源文件夹根目录中 .gitignore 和 .syncsafeignore 的规则
+	Notify             notify.Config
+	Email              notify.EmailConfig   // 备份失败时发送邮件，密码只保存在本机
+	Webhooks           []notify.Webhook     // 每次备份后发送摘要，地址中通常含有密钥，只保存在本机
+	Peer               peer.Config          // 备份完成后推送到局域网中的另一个 SyncSafe
+	InteropLayout      string               // 备份完成后按该布局导出给 rsync 或 Syncthing，为空表示不导出
+	InteropTarget      string               // 导出目录
+	WebDAV             storage.WebDAVConfig // DestinationPath 为 WebDAV 地址时的登录信息
+	S3                 storage.S3Config     // DestinationPath 为 S3 地址时的服务、密钥和存储类别
+	Replicas           []Replica            // 每次备份成功后复制快照的附加目标，各自选择格式和加密，只保存在本机
+	Retention          RetentionPolicy      // 每次备份后自动清理不再保留的快照
+	IgnoreSizeSwings   bool                 // 快照大小骤变时不提醒，也不暂停按保留策略清理
+	Review             []ReviewItem         // 等待复查的备份问题，只保存在本机
+	Preferences        Preferences          // 程序设置，只在默认任务中使用
+	Encryption         EncryptionConfig     // 客户端加密，密码短语只保存在本机
+	TaskFrequency      string               // 在 Windows 任务计划程序中注册的运行频率，为空表示没有注册，只保存在本机
+	TaskTime           string               // TaskDaily 每天运行的时间（HH:MM）
+	History            []history.Record
+	dir                string         // 配置目录，默认任务为空，使用 DataDir
+	store              *history.Store // 历史记录数据库，记住本进程读到和写入的记录
+}
+
+// 默认配置
+func NewConfig() *Config {
+	return &Config{History: make([]history.Record, 0)}
+}
+
+// 每个配置文件保留的历史备份数量（config.json.1 为最新）
+const configBackupCount = 5
+
+// 配置文件损坏，Backup 为最近一个可以正常解析的备份
+type CorruptError struct {
+	Path   string
+	Backup string
+	Err    error
+}
+
+func (e *CorruptError) Error() string {
+	return i18n.Sprintf("配置文件已损坏: %s\n%v", e.Path, e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// 共享配置文件路径
+func configPath(dir string) string {
+	return filepath.Join(dir, "config.json")
+}
+
+// 配置所在的目录
+func (c *Config) configDir() string {
+	if c.dir == "" {
+		return DataDir
+	}
+	return c.dir
+}
+
+// 保存配置到文件：共享设置写入 config.json，本机设置写入 machines/<主机名>.json
+func (c *Config) Save() error {
+	dir := c.configDir()
+
+	// 创建配置目录
+	if err := os.MkdirAll(filepath.Dir(machineConfigPath(dir)), 0755); err != nil {
+		return i18n.Errorf("创建配置目录失败: %v", err)
+	}
+
+	// 新增和修改的历史记录先写入数据库，失败时不修改配置文件
+	store := c.historyStore()
+	if err := store.Put(store.Changed(c.History)...); err != nil {
+		return err
+	}
+
+	shared, local := splitConfig(c)
+
+	// 序列化配置
+	data, err := json.MarshalIndent(shared, "", "  ")
+	if err != nil {
+		return i18n.Errorf("序列化配置失败: %v", err)
+	}
+	localData, err := json.MarshalIndent(local, "", "  ")
+	if err != nil {
+		return i18n.Errorf("序列化本机配置失败: %v", err)
+	}
+
+	// 原子写入文件，并保留最近几次的备份
+	if err := saveConfigFile(configPath(dir), data, 0644); err != nil {
+		return i18n.Errorf("写入配置文件失败: %v", err)
+	}
+	if err := saveConfigFile(machineConfigPath(dir), localData, 0600); err != nil {
+		return i18n.Errorf("写入本机配置文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// 从文件加载默认任务的配置，配置文件不存在时返回默认配置，文件损坏时返回 *CorruptError
+func LoadConfig() (*Config, error) {
+	return loadConfigFrom("")
+}
+
+// 从配置目录加载配置，dir 为空表示 DataDir
+func loadConfigFrom(dir string) (*Config, error) {
+	config := Config{dir: dir}
+	path := configPath(config.configDir())
+
+	// 检查配置文件是否存在
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		config.History = make([]history.Record, 0)
+		return &config, nil
+	}
+
+	// 读取并解析配置
+	if err := readConfigFile(path, &config); err != nil {
+		var corrupt *CorruptError
+		if errors.As(err, &corrupt) {
+			return nil, err
+		}
+		return nil, i18n.Errorf("读取配置文件失败: %v", err)
+	}
+
+	// 叠加本机设置；没有本机配置时沿用 config.json 中的旧值，下次保存时自动拆分
+	var local MachineConfig
+	if err := readConfigFile(machineConfigPath(config.configDir()), &local); err == nil {
+		mergeConfig(&config, local)
+	} else if !os.IsNotExist(err) {
+		var corrupt *CorruptError
+		if errors.As(err, &corrupt) {
+			return nil, err
+		}
+		return nil, i18n.Errorf("读取本机配置文件失败: %v", err)
+	}
+
+	if err := config.loadHistory(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) historyStore() *history.Store {
+	path := historyStorePath(c.configDir())
+	if c.store == nil || c.store.Path() != path {
+		c.store = history.NewStore(path)
+	}
+	return c.store
+}
+
+// 从历史记录和数据库中删除 records，其他进程写入的记录不受影响
+func (c *Config) RemoveHistory(records []history.Record) error {
+	if err := c.historyStore().Delete(records...); err != nil {
+		return err
+	}
+	c.History = history.Without(c.History, records)
+	return nil
+}
+
+// 从历史记录数据库读取历史记录。c.History 中是旧版本保存在本机配置中的记录时，
+// 先合并到数据库再重写本机配置，旧的本机配置保留在配置备份（.1）中
+func (c *Config) loadHistory() error {
+	store := c.historyStore()
+	records, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if len(c.History) == 0 {
+		c.History = records
+		return nil
+	}
+
+	c.History = history.Merge(records, c.History)
+	if err := c.Save(); err != nil {
+		return i18n.Errorf("迁移历史记录失败: %v", err)
+	}
+	slog.Info("已把历史记录迁移到数据库", "records", len(c.History), "path", historyStorePath(c.configDir()))
+	return nil
+}
+
+// 按筛选条件从历史记录数据库查询，按时间顺序排列。指定源文件夹时使用索引，
+// 只能查到已保存的记录
+func (c *Config) QueryHistory(filter history.Filter) ([]history.Record, error) {
+	return c.historyStore().Query(filter)
+}
+
+// 第 n 个备份的路径
+func configBackupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// 是否为可以正常解析的 JSON 配置
+func validConfigFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var v map[string]interface{}
+	return json.Unmarshal(data, &v) == nil
+}
+
+// 写入新配置前轮换备份：只有当前文件可以正常解析时才保留为 .1，
+// 避免损坏的配置把可用的备份挤掉
+func rotateConfigBackups(path string) error {
+	if !validConfigFile(path) {
+		return nil
+	}
+	for n := configBackupCount - 1; n >= 1; n-- {
+		if _, err := os.Stat(configBackupPath(path, n)); err == nil {
+			if err := os.Rename(configBackupPath(path, n), configBackupPath(path, n+1)); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(configBackupPath(path, 1), data, 0600)
+}
+
+// 轮换备份后原子写入配置文件
+func saveConfigFile(path string, data []byte, perm os.FileMode) error {
+	if err := rotateConfigBackups(path); err != nil {
+		return i18n.Errorf("备份旧配置失败: %v", err)
+	}
+	return storage.WriteFileAtomic(path, data, perm)
+}
+
+// 读取并解析配置文件，解析失败时查找最近的可用备份
+func readConfigFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		corrupt := &CorruptError{Path: path, Err: err}
+		for n := 1; n <= configBackupCount; n++ {
+			if validConfigFile(configBackupPath(path, n)) {
+				corrupt.Backup = configBackupPath(path, n)
+				break
+			}
+		}
+		return corrupt
+	}
+	return nil
+}
+
+// 用备份替换损坏的配置文件，损坏的文件另存一份以便排查
+func RestoreConfigBackup(corrupt *CorruptError) error {
+	data, err := os.ReadFile(corrupt.Backup)
+	if err != nil {
+		return i18n.Errorf("读取备份失败: %v", err)
+	}
+	damaged := fmt.Sprintf("%s.corrupt-%s", corrupt.Path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(corrupt.Path, damaged); err != nil {
+		return i18n.Errorf("保留损坏的配置失败: %v", err)
+	}
+	if err := storage.WriteFileAtomic(corrupt.Path, data, 0600); err != nil {
+		return i18n.Errorf("恢复配置失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"syncsafe/i18n"
+)
+
+// 聚合错误信息中最多列出的文件数
+const maxCopyErrors = 10
+
+// 并行复制文件的工作池。复制在工作协程中并行执行，
+// 完成后的处理（写入清单、统计和进度事件）在调用方协程中按提交顺序执行，
+// 清单仍与遍历顺序一致，与上一个快照的流式对比不受影响
+type copyPool struct {
+	copy    func(src, dst string) error
+	jobs    chan *copyJob
+	pending []*copyJob // 按提交顺序等待处理的任务
+	limit   int        // 最多同时等待处理的任务数，超过时阻塞提交
+	wg      sync.WaitGroup
+}
+
+type copyJob struct {
+	src, dst string
+	then     func(copyErr error) error
+	err      error
+	done     chan struct{}
+}
+
+// 启动 workers 个工作协程，workers 不大于 0 时使用 CPU 核数
+func newCopyPool(workers int, copy func(src, dst string) error) *copyPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	p := &copyPool{
+		copy:  copy,
+		jobs:  make(chan *copyJob, workers),
+		limit: workers * 4,
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				job.err = p.copy(job.src, job.dst)
+				close(job.done)
+			}
+		}()
+	}
+	return p
+}
+
+// 提交一个任务：src 不为空时由工作协程复制到 dst，之后按提交顺序调用 then。
+// 返回此前完成的任务中 then 返回的错误
+func (p *copyPool) Submit(src, dst string, then func(copyErr error) error) error {
+	job := &copyJob{src: src, dst: dst, then: then, done: make(chan struct{})}
+	p.pending = append(p.pending, job)
+	if src == "" {
+		close(job.done)
+	} else {
+		p.jobs <- job
+	}
+	return p.drain(false)
+}
+
+// 按顺序处理已完成的任务，all 为 true 时等待所有任务完成
+func (p *copyPool) drain(all bool) error {
+	for len(p.pending) > 0 {
+		job := p.pending[0]
+		if all || len(p.pending) > p.limit {
+			<-job.done
+		} else {
+			select {
+			case <-job.done:
+			default:
+				return nil
+			}
+		}
+		p.pending = p.pending[1:]
+		if err := job.then(job.err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 等待所有任务完成并停止工作协程。出错时不再处理剩余的任务
+func (p *copyPool) Close() error {
+	close(p.jobs)
+	err := p.drain(true)
+	p.wg.Wait()
+	return err
+}
+
+// 复制失败的文件，错误信息只保留前 maxCopyErrors 条
+type copyFailures struct {
+	count    int
+	messages []string
+}
+
+func (f *copyFailures) add(err error) {
+	f.count++
+	if len(f.messages) < maxCopyErrors {
+		f.messages = append(f.messages, err.Error())
+	}
+}
+
+// 汇总的错误，没有失败的文件时为 nil
+func (f *copyFailures) Err() error {
+	if f.count == 0 {
+		return nil
+	}
+	message := strings.Join(f.messages, "\n")
+	if f.count > len(f.messages) {
+		message += i18n.Sprintf("\n……以及另外 %d 个文件", f.count-len(f.messages))
+	}
+	return i18n.Errorf("%d 个文件复制失败:\n%s", f.count, message)
+}
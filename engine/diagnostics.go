@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"syncsafe/i18n"
+	"syncsafe/version"
+)
+
+// 诊断信息：版本、构建环境和配置所在的位置，用于关于对话框、命令行和问题反馈
+type Diagnostics struct {
+	Version       version.Info `json:"version"`
+	Machine       string       `json:"machine"`
+	DataDir       string       `json:"dataDir"`
+	ConfigPath    string       `json:"configPath"`
+	MachinePath   string       `json:"machineConfigPath"`
+	HistoryPath   string       `json:"historyPath"`
+	ConfigVersion int          `json:"configVersion"`
+}
+
+// 收集 config 所在任务的诊断信息，路径均为绝对路径
+func (c *Config) Diagnostics() Diagnostics {
+	dir := c.configDir()
+	return Diagnostics{
+		Version:       version.Get(),
+		Machine:       machineID(),
+		DataDir:       absPath(DataDir),
+		ConfigPath:    absPath(configPath(dir)),
+		MachinePath:   absPath(machineConfigPath(dir)),
+		HistoryPath:   absPath(historyStorePath(dir)),
+		ConfigVersion: ConfigVersion,
+	}
+}
+
+// 无法转换时返回原路径
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// 多行文本，复制后可以直接粘贴到问题反馈中
+func (d Diagnostics) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SyncSafe %s\n", d.Version.Version)
+	fmt.Fprintf(&sb, i18n.T("提交: %s\n"), valueOr(d.Version.Commit, i18n.T("未知")))
+	if d.Version.Modified {
+		sb.WriteString(i18n.T("构建时有未提交的修改\n"))
+	}
+	fmt.Fprintf(&sb, i18n.T("构建时间: %s\n"), valueOr(d.Version.Date, i18n.T("未知")))
+	fmt.Fprintf(&sb, i18n.T("Go 版本: %s\n"), d.Version.GoVersion)
+	fmt.Fprintf(&sb, i18n.T("平台: %s\n"), d.Version.Platform)
+	fmt.Fprintf(&sb, i18n.T("计算机: %s\n"), d.Machine)
+	fmt.Fprintf(&sb, i18n.T("配置格式: %d\n"), d.ConfigVersion)
+	fmt.Fprintf(&sb, i18n.T("数据目录: %s\n"), d.DataDir)
+	fmt.Fprintf(&sb, i18n.T("配置文件: %s\n"), d.ConfigPath)
+	fmt.Fprintf(&sb, i18n.T("本机配置: %s\n"), d.MachinePath)
+	fmt.Fprintf(&sb, i18n.T("历史数据库: %s"), d.HistoryPath)
+	return sb.String()
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
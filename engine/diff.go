@@ -0,0 +1,294 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 两个快照之间变化的一个文件
+type DiffEntry struct {
+	Path       string // 相对源文件夹的路径
+	Op         string // history.ChangeNew、ChangeModified 或 ChangeDeleted
+	OldSize    int64  // 在较早的快照中的大小，新增的文件为 0
+	NewSize    int64  // 在较新的快照中的大小，删除的文件为 0
+	OldModTime time.Time
+	NewModTime time.Time
+}
+
+// 大小的变化
+func (d DiffEntry) SizeDelta() int64 {
+	return d.NewSize - d.OldSize
+}
+
+// 比较两个快照的清单，列出新增、删除和修改（大小或修改时间不同）的文件，按路径顺序排列。
+// older 和 newer 的先后只决定新增和删除的方向
+func (e *Engine) DiffSnapshots(older, newer history.Record) ([]DiffEntry, error) {
+	oldReader := openSnapshotManifest(older)
+	if oldReader == nil {
+		return nil, i18n.Errorf("快照没有清单，无法比较: %s", older.DestPath)
+	}
+	defer oldReader.Close()
+	newReader := openSnapshotManifest(newer)
+	if newReader == nil {
+		return nil, i18n.Errorf("快照没有清单，无法比较: %s", newer.DestPath)
+	}
+	defer newReader.Close()
+
+	// 只比较文件，目录的变化体现在其中的文件上
+	next := func(reader *ManifestReader) (*ManifestEntry, error) {
+		for {
+			entry, ok, err := reader.Next()
+			if err != nil {
+				return nil, i18n.Errorf("读取快照清单失败: %v", err)
+			}
+			if !ok {
+				return nil, nil
+			}
+			if !entry.IsDir {
+				return &entry, nil
+			}
+		}
+	}
+	oldEntry, err := next(oldReader)
+	if err != nil {
+		return nil, err
+	}
+	newEntry, err := next(newReader)
+	if err != nil {
+		return nil, err
+	}
+	var diff []DiffEntry
+	for oldEntry != nil || newEntry != nil {
+		order := 0
+		switch {
+		case oldEntry == nil:
+			order = 1
+		case newEntry == nil:
+			order = -1
+		default:
+			order = comparePaths(oldEntry.RelPath, newEntry.RelPath)
+		}
+		switch {
+		case order < 0:
+			diff = append(diff, DiffEntry{Path: oldEntry.RelPath, Op: history.ChangeDeleted, OldSize: oldEntry.Size, OldModTime: oldEntry.ModTime})
+			oldEntry, err = next(oldReader)
+		case order > 0:
+			diff = append(diff, DiffEntry{Path: newEntry.RelPath, Op: history.ChangeNew, NewSize: newEntry.Size, NewModTime: newEntry.ModTime})
+			newEntry, err = next(newReader)
+		default:
+			if oldEntry.Size != newEntry.Size || !oldEntry.ModTime.Equal(newEntry.ModTime) {
+				diff = append(diff, DiffEntry{Path: newEntry.RelPath, Op: history.ChangeModified,
+					OldSize: oldEntry.Size, NewSize: newEntry.Size, OldModTime: oldEntry.ModTime, NewModTime: newEntry.ModTime})
+			}
+			if oldEntry, err = next(oldReader); err == nil {
+				newEntry, err = next(newReader)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+// 逐行比较的文本文件不超过该大小
+const maxTextDiffSize = 1024 * 1024
+
+// 逐行比较时对比表的最大单元数（两个版本不同部分的行数之积）
+const maxDiffCells = 4 * 1024 * 1024
+
+// 统一格式差异中每处修改前后保留的上下文行数
+const diffContext = 3
+
+// DiffSnapshots 列出的一个文本文件的逐行差异，统一格式（与 diff -u 相同），内容相同时返回空字符串。
+// 新增的文件与空文件比较，删除的文件与空文件比较
+func (e *Engine) TextDiff(older, newer history.Record, entry DiffEntry) (string, error) {
+	var oldText, newText string
+	var err error
+	if entry.Op != history.ChangeNew {
+		if oldText, err = e.snapshotText(older, entry.Path); err != nil {
+			return "", err
+		}
+	}
+	if entry.Op != history.ChangeDeleted {
+		if newText, err = e.snapshotText(newer, entry.Path); err != nil {
+			return "", err
+		}
+	}
+	loc := e.Config.Location()
+	path := filepath.ToSlash(entry.Path)
+	header := fmt.Sprintf("--- a/%s\t%s\n+++ b/%s\t%s\n", path, older.FormatTime(loc), path, newer.FormatTime(loc))
+	return unifiedDiff(header, splitLines(oldText), splitLines(newText))
+}
+
+// 读取快照中的文本文件，relPath 为相对源文件夹的路径
+func (e *Engine) snapshotText(record history.Record, relPath string) (string, error) {
+	browser, err := e.BrowseSnapshot(record)
+	if err != nil {
+		return "", err
+	}
+	snapshotPath, err := snapshotRelPath(browser.key, record, relPath)
+	if err != nil {
+		return "", err
+	}
+	data, truncated, err := browser.Preview(snapshotPath, maxTextDiffSize)
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		return "", i18n.Errorf("文件超过 %d MB，不逐行比较", maxTextDiffSize/(1024*1024))
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return "", i18n.Errorf("不是文本文件，不逐行比较")
+	}
+	return string(data), nil
+}
+
+// 按行拆分，每行保留结尾的换行符，最后一行没有换行符时原样保留
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// 用最长公共子序列计算逐行差异，生成统一格式的修改块
+func unifiedDiff(header string, a, b []string) (string, error) {
+	// 相同的开头和结尾不参与对比
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return "", nil
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+	if n*m > maxDiffCells {
+		return "", i18n.Errorf("修改的行数过多，不逐行比较")
+	}
+
+	// lcs[i][j] 为 midA[i:] 和 midB[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 逐行的操作：' ' 相同，'-' 删除，'+' 新增
+	type line struct {
+		op   byte
+		text string
+	}
+	lines := make([]line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, line{' ', text})
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && midA[i] == midB[j]:
+			lines = append(lines, line{' ', midA[i]})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', midA[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', midB[j]})
+			j++
+		}
+	}
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, line{' ', text})
+	}
+
+	// 修改之间相隔不超过两倍上下文的合并为一个修改块
+	var sb strings.Builder
+	sb.WriteString(header)
+	oldLine, newLine := 1, 1 // lines[k] 之前的行号
+	for k := 0; k < len(lines); {
+		if lines[k].op == ' ' {
+			oldLine++
+			newLine++
+			k++
+			continue
+		}
+		start := max(k-diffContext, 0)
+		end := k
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			same := end
+			for same < len(lines) && lines[same].op == ' ' {
+				same++
+			}
+			if same == len(lines) || same-end > 2*diffContext {
+				end = min(end+diffContext, len(lines))
+				break
+			}
+			end = same
+		}
+		oldStart, newStart := oldLine-(k-start), newLine-(k-start)
+		oldCount, newCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, l := range lines[start:end] {
+			sb.WriteByte(l.op)
+			sb.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		for _, l := range lines[k:end] {
+			if l.op != '+' {
+				oldLine++
+			}
+			if l.op != '-' {
+				newLine++
+			}
+		}
+		k = end
+	}
+	return sb.String(), nil
+}
+
+// 修改块的行范围，与 diff -u 相同：空范围的起始行为前一行
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,483 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 提权辅助进程的命令行参数
+const ElevatedHelperFlag = "--elevated-helper"
+
+// 等待用户确认提权（UAC / polkit）的最长时间
+const elevatedHelperTimeout = 2 * time.Minute
+
+// 辅助进程的请求
+type helperRequest struct {
+	Op   string // "list" 或 "read"
+	Path string
+}
+
+// 辅助进程返回的目录条目
+type helperEntry struct {
+	RelPath string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+}
+
+// 辅助进程的响应头，read 请求之后紧跟分块的文件内容
+type helperResponse struct {
+	Error   string
+	Entries []helperEntry
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+// 以管理员权限运行的只读辅助进程，仅用于读取源文件夹内无权访问的文件
+type ElevatedHelper struct {
+	mu       sync.Mutex
+	scope    string
+	listener net.Listener
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+// 启动提权辅助进程，scope 限定辅助进程只能读取该目录内的文件
+func startElevatedHelper(scope string) (*ElevatedHelper, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, i18n.Errorf("获取程序路径失败: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, i18n.Errorf("创建辅助进程连接失败: %v", err)
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		listener.Close()
+		return nil, i18n.Errorf("生成辅助进程令牌失败: %v", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	// 令牌写入只有当前用户可以读取的临时文件，不出现在其他用户可见的命令行参数中。
+	// 函数返回时辅助进程已经读取并校验过令牌，随即删除
+	tokenDir, err := os.MkdirTemp("", "syncsafe-helper-")
+	if err != nil {
+		listener.Close()
+		return nil, i18n.Errorf("保存辅助进程令牌失败: %v", err)
+	}
+	defer os.RemoveAll(tokenDir)
+	tokenPath := filepath.Join(tokenDir, "token")
+	if err := os.WriteFile(tokenPath, []byte(token), 0600); err != nil {
+		listener.Close()
+		return nil, i18n.Errorf("保存辅助进程令牌失败: %v", err)
+	}
+
+	args := []string{ElevatedHelperFlag, listener.Addr().String(), tokenPath, filepath.Clean(scope)}
+	slog.Info("启动提权辅助进程", "scope", scope)
+	if err := runElevated(exe, args); err != nil {
+		listener.Close()
+		return nil, i18n.Errorf("启动提权辅助进程失败: %v", err)
+	}
+
+	// 等待辅助进程连接并校验令牌
+	type accepted struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan accepted, 1)
+	go func() {
+		conn, err := listener.Accept()
+		result <- accepted{conn, err}
+	}()
+
+	var conn net.Conn
+	select {
+	case a := <-result:
+		if a.err != nil {
+			listener.Close()
+			return nil, i18n.Errorf("等待辅助进程连接失败: %v", a.err)
+		}
+		conn = a.conn
+	case <-time.After(elevatedHelperTimeout):
+		listener.Close()
+		return nil, i18n.Errorf("等待提权确认超时")
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := reader.ReadString('\n')
+	conn.SetReadDeadline(time.Time{})
+	if err != nil || line != token+"\n" {
+		conn.Close()
+		listener.Close()
+		return nil, i18n.Errorf("辅助进程身份校验失败")
+	}
+
+	return &ElevatedHelper{
+		scope:    filepath.Clean(scope),
+		listener: listener,
+		conn:     conn,
+		reader:   reader,
+	}, nil
+}
+
+// 关闭辅助进程，连接断开后辅助进程自动退出
+func (h *ElevatedHelper) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conn.Close()
+	h.listener.Close()
+	slog.Info("提权辅助进程已关闭")
+}
+
+func (h *ElevatedHelper) request(req helperRequest) (helperResponse, error) {
+	var resp helperResponse
+	if !pathWithin(h.scope, req.Path) {
+		return resp, i18n.Errorf("路径超出提权读取范围: %s", req.Path)
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	if _, err := h.conn.Write(append(data, '\n')); err != nil {
+		return resp, i18n.Errorf("发送请求失败: %v", err)
+	}
+	line, err := h.reader.ReadBytes('\n')
+	if err != nil {
+		return resp, i18n.Errorf("读取响应失败: %v", err)
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return resp, i18n.Errorf("解析响应失败: %v", err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("%s", resp.Error)
+	}
+	return resp, nil
+}
+
+// 列出目录下的所有条目（递归）
+func (h *ElevatedHelper) List(dir string) ([]helperEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	resp, err := h.request(helperRequest{Op: "list", Path: dir})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// 通过辅助进程把受保护的文件复制到 dst
+func (h *ElevatedHelper) CopyFile(src, dst string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	resp, err := h.request(helperRequest{Op: "read", Path: src})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		discardChunks(h.reader)
+		return i18n.Errorf("创建目标目录失败: %v", err)
+	}
+	tmpFile := fmt.Sprintf("%s.tmp_%d", dst, time.Now().UnixNano())
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		discardChunks(h.reader)
+		return i18n.Errorf("创建临时文件失败: %v", err)
+	}
+
+	if err := readChunks(h.reader, out); err != nil {
+		out.Close()
+		os.Remove(tmpFile)
+		return i18n.Errorf("读取受保护文件失败: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpFile)
+		return i18n.Errorf("关闭目标文件失败: %v", err)
+	}
+
+	// 备份副本由当前用户拥有，只保留权限位
+	os.Chmod(tmpFile, resp.Mode.Perm()|0200)
+	os.Chtimes(tmpFile, time.Now(), resp.ModTime)
+	if err := os.Rename(tmpFile, dst); err != nil {
+		os.Remove(tmpFile)
+		return i18n.Errorf("重命名文件失败: %v", err)
+	}
+	return nil
+}
+
+// 分块传输：每块以 4 字节长度开头，长度为 0 表示结束，0xFFFFFFFF 表示读取出错
+func writeChunks(w io.Writer, r io.Reader) error {
+	buf := make([]byte, 256*1024)
+	header := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(header, uint32(n))
+			if _, werr := w.Write(header); werr != nil {
+				return werr
+			}
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			binary.BigEndian.PutUint32(header, 0)
+			_, werr := w.Write(header)
+			return werr
+		}
+		if err != nil {
+			binary.BigEndian.PutUint32(header, 0xFFFFFFFF)
+			w.Write(header)
+			return err
+		}
+	}
+}
+
+func readChunks(r io.Reader, w io.Writer) error {
+	header := make([]byte, 4)
+	var writeErr error
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		n := binary.BigEndian.Uint32(header)
+		switch n {
+		case 0:
+			return writeErr
+		case 0xFFFFFFFF:
+			return i18n.Errorf("辅助进程读取文件出错")
+		}
+		if writeErr != nil {
+			w = io.Discard
+		}
+		if _, err := io.CopyN(w, r, int64(n)); err != nil {
+			if writeErr == nil {
+				writeErr = err
+			}
+		}
+	}
+}
+
+func discardChunks(r io.Reader) {
+	readChunks(r, io.Discard)
+}
+
+// 解析路径中的所有符号链接，解析后的路径仍在 scope 内才允许访问，
+// 防止通过指向范围外的符号链接读取其他文件。scope 应为已解析的路径
+func resolveHelperPath(scope, path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if !pathWithin(scope, resolved) {
+		return "", i18n.Errorf("路径超出提权读取范围")
+	}
+	return resolved, nil
+}
+
+// 辅助进程入口：只响应 scope 内的只读请求，每次访问都记录日志。
+// 日志写入只有管理员可以修改的系统日志目录，不使用由普通用户控制的数据目录
+func RunElevatedHelper(args []string) {
+	if len(args) != 3 {
+		os.Exit(2)
+	}
+	addr, tokenPath, scope := args[0], args[1], filepath.Clean(args[2])
+
+	if logFile, err := openHelperLog(helperLogPath()); err == nil {
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	} else {
+		log.Printf("无法打开辅助进程日志，改为输出到标准错误: %v", err)
+	}
+	// 请求的路径来自主程序，以带引号的形式记录，换行等控制字符不能伪造日志行
+	log.Printf("提权辅助进程启动 (pid %d)，读取范围: %q", os.Getpid(), scope)
+
+	// 读取范围本身可能是符号链接，请求的路径解析后与解析后的范围比较
+	if resolved, err := filepath.EvalSymlinks(scope); err == nil {
+		scope = resolved
+	}
+	token, err := os.ReadFile(tokenPath)
+	if err != nil {
+		log.Printf("读取令牌失败: %v", err)
+		os.Exit(1)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		log.Printf("连接主程序失败: %v", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(append(token, '\n')); err != nil {
+		os.Exit(1)
+	}
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			log.Printf("主程序已断开，辅助进程退出")
+			return
+		}
+
+		var req helperRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			log.Printf("无效请求: %v", err)
+			return
+		}
+
+		path, err := resolveHelperPath(scope, filepath.Clean(req.Path))
+		if err != nil {
+			log.Printf("拒绝请求: %q %q: %q", req.Op, req.Path, err)
+			writeHelperResponse(writer, helperResponse{Error: err.Error()})
+			continue
+		}
+
+		switch req.Op {
+		case "list":
+			log.Printf("列出目录: %q", path)
+			var entries []helperEntry
+			err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(path, p)
+				if err != nil || rel == "." {
+					return err
+				}
+				entries = append(entries, helperEntry{
+					RelPath: rel,
+					Size:    info.Size(),
+					Mode:    info.Mode(),
+					ModTime: info.ModTime(),
+					IsDir:   info.IsDir(),
+				})
+				return nil
+			})
+			resp := helperResponse{Entries: entries}
+			if err != nil {
+				resp.Error = err.Error()
+			}
+			writeHelperResponse(writer, resp)
+
+		case "read":
+			log.Printf("读取文件: %q", path)
+			file, err := openWithinScope(scope, path)
+			if err != nil {
+				log.Printf("拒绝读取 %q: %q", path, err)
+				writeHelperResponse(writer, helperResponse{Error: err.Error()})
+				continue
+			}
+			info, err := file.Stat()
+			if err != nil || !info.Mode().IsRegular() {
+				file.Close()
+				writeHelperResponse(writer, helperResponse{Error: i18n.T("不是普通文件")})
+				continue
+			}
+			writeHelperResponse(writer, helperResponse{Mode: info.Mode(), ModTime: info.ModTime()})
+			if err := writeChunks(writer, file); err != nil {
+				log.Printf("读取文件失败 %q: %q", path, err)
+			}
+			file.Close()
+			writer.Flush()
+
+		default:
+			writeHelperResponse(writer, helperResponse{Error: i18n.T("不支持的操作: ") + req.Op})
+		}
+	}
+}
+
+func writeHelperResponse(w *bufio.Writer, resp helperResponse) {
+	data, _ := json.Marshal(resp)
+	w.Write(append(data, '\n'))
+	w.Flush()
+}
+
+// 获取本次备份使用的提权辅助进程，首次需要时才启动
+func (e *Engine) elevatedHelper() (*ElevatedHelper, error) {
+	e.helperMutex.Lock()
+	defer e.helperMutex.Unlock()
+	if e.helper != nil {
+		return e.helper, nil
+	}
+	helper, err := startElevatedHelper(e.SourcePath())
+	if err != nil {
+		return nil, err
+	}
+	e.helper = helper
+	return helper, nil
+}
+
+// 关闭本次备份使用的提权辅助进程
+func (e *Engine) closeElevatedHelper() {
+	e.helperMutex.Lock()
+	defer e.helperMutex.Unlock()
+	if e.helper != nil {
+		e.helper.Close()
+		e.helper = nil
+	}
+}
+
+// 通过提权辅助进程备份无权读取的目录
+func (e *Engine) backupProtectedDir(dir, destDir string) (fileCount int, totalSize int64, err error) {
+	helper, err := e.elevatedHelper()
+	if err != nil {
+		return 0, 0, err
+	}
+	entries, err := helper.List(dir)
+	if err != nil {
+		return 0, 0, i18n.Errorf("列出受保护目录失败: %v\n目录: %s", err, dir)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return 0, 0, i18n.Errorf("创建目录失败: %v\n目录: %s", err, destDir)
+	}
+	for _, entry := range entries {
+		target := filepath.Join(destDir, entry.RelPath)
+		if entry.IsDir {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fileCount, totalSize, i18n.Errorf("创建目录失败: %v\n目录: %s", err, target)
+			}
+			continue
+		}
+		if !entry.Mode.IsRegular() {
+			continue
+		}
+		if err := helper.CopyFile(filepath.Join(dir, entry.RelPath), target); err != nil {
+			return fileCount, totalSize, i18n.Errorf("复制受保护文件失败: %v\n文件: %s", err, entry.RelPath)
+		}
+		fileCount++
+		totalSize += entry.Size
+	}
+	return fileCount, totalSize, nil
+}
+
+// 文件是否因权限不足而无法读取
+func permissionDenied(path string) bool {
+	file, err := os.Open(path)
+	if err == nil {
+		file.Close()
+		return false
+	}
+	return os.IsPermission(err)
+}
@@ -0,0 +1,23 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// 在读取范围内打开文件：macOS 没有 openat2，打开后按 F_GETPATH 返回的实际路径检查范围
+func openWithinScope(scope, path string) (*os.File, error) {
+	return openAndVerify(scope, path)
+}
+
+// 已打开的文件的实际路径
+func fdPath(file *os.File) (string, error) {
+	buf := make([]byte, unix.PathMax)
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_GETPATH, int(uintptr(unsafe.Pointer(&buf[0])))); err != nil {
+		return "", err
+	}
+	return string(buf[:bytes.IndexByte(buf, 0)]), nil
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+
+	"syncsafe/i18n"
+)
+
+// 在读取范围内打开文件：openat2 以范围目录为根解析路径，不允许跳出范围，也不跟随任何符号链接，
+// 解析和打开在同一次系统调用中完成。内核不支持 openat2（5.6 以前）时打开后再检查实际路径
+func openWithinScope(scope, path string) (*os.File, error) {
+	rel, err := filepath.Rel(scope, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return nil, i18n.Errorf("路径超出提权读取范围")
+	}
+	dir, err := unix.Open(scope, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: scope, Err: err}
+	}
+	defer unix.Close(dir)
+
+	fd, err := unix.Openat2(dir, rel, &unix.OpenHow{
+		Flags:   unix.O_RDONLY | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_SYMLINKS,
+	})
+	if errors.Is(err, unix.ENOSYS) {
+		return openAndVerify(scope, path)
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "openat2", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// 已打开的文件的实际路径
+func fdPath(file *os.File) (string, error) {
+	return os.Readlink("/proc/self/fd/" + strconv.Itoa(int(file.Fd())))
+}
@@ -0,0 +1,100 @@
+//go:build !windows
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"syncsafe/i18n"
+)
+
+// 辅助进程的日志位置，只有 root 可以在其中创建文件
+func helperLogPath() string {
+	if runtime.GOOS == "darwin" {
+		return "/Library/Logs/SyncSafe/elevated-helper.log"
+	}
+	return "/var/log/syncsafe/elevated-helper.log"
+}
+
+// 以 root 身份打开辅助进程日志。目录必须属于 root 且其他用户不能写入，
+// 日志文件不跟随符号链接、不能是硬链接，防止借助日志向任意文件追加内容
+func openHelperLog(path string) (*os.File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || !info.IsDir() || stat.Uid != 0 || info.Mode().Perm()&0022 != 0 {
+		return nil, i18n.Errorf("日志目录不属于 root 或允许其他用户写入: %s", dir)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err = file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || !info.Mode().IsRegular() || stat.Uid != 0 || stat.Nlink != 1 {
+		file.Close()
+		return nil, i18n.Errorf("日志文件不是 root 拥有的普通文件: %s", path)
+	}
+	return file, nil
+}
+
+// 不跟随符号链接打开文件，再按实际打开的文件的路径检查读取范围。
+// 检查之后才替换的上级目录会使实际路径落在范围外，打开的文件随即被拒绝
+func openAndVerify(scope, path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := fdPath(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !pathWithin(scope, opened) {
+		file.Close()
+		return nil, i18n.Errorf("路径超出提权读取范围")
+	}
+	return file, nil
+}
+
+// 通过 polkit（Linux）或系统授权对话框（macOS）以 root 身份启动辅助进程
+func runElevated(exe string, args []string) error {
+	if runtime.GOOS == "darwin" {
+		quoted := make([]string, 0, len(args)+1)
+		for _, arg := range append([]string{exe}, args...) {
+			quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+		}
+		script := fmt.Sprintf(`do shell script "%s > /dev/null 2>&1 &" with administrator privileges`,
+			strings.ReplaceAll(strings.Join(quoted, " "), `"`, `\"`))
+		if output, err := exec.Command("osascript", "-e", script).CombinedOutput(); err != nil {
+			return i18n.Errorf("%v\n输出: %s", err, output)
+		}
+		return nil
+	}
+
+	pkexec, err := exec.LookPath("pkexec")
+	if err != nil {
+		return i18n.Errorf("未找到 pkexec，无法请求管理员权限")
+	}
+	cmd := exec.Command(pkexec, append([]string{exe}, args...)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
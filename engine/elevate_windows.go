@@ -0,0 +1,118 @@
+//go:build windows
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"syncsafe/i18n"
+)
+
+var (
+	modshell32        = syscall.NewLazyDLL("shell32.dll")
+	procShellExecuteW = modshell32.NewProc("ShellExecuteW")
+)
+
+// 辅助进程的日志位置：Windows\Logs 下只有管理员可以创建文件。
+// 系统目录通过 API 获取，不读取可以被用户修改的环境变量
+func helperLogPath() string {
+	dir, err := windows.GetSystemWindowsDirectory()
+	if err != nil {
+		dir = `C:\Windows`
+	}
+	return filepath.Join(dir, "Logs", "SyncSafe", "elevated-helper.log")
+}
+
+// 以管理员身份打开辅助进程日志，目录和文件都不能是符号链接或目录联接
+func openHelperLog(path string) (*os.File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for _, p := range []string{dir, path} {
+		if info, err := os.Lstat(p); err == nil && info.Mode()&(os.ModeSymlink|os.ModeIrregular) != 0 {
+			return nil, i18n.Errorf("日志路径是符号链接或目录联接: %s", p)
+		}
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+}
+
+// 在读取范围内打开文件：按打开后的句柄取得实际路径（解析所有符号链接和目录联接）再检查范围，
+// 检查和读取的是同一个文件，打开前替换上级目录同样无法读取范围外的文件
+func openWithinScope(scope, path string) (*os.File, error) {
+	scopeFile, err := os.Open(scope)
+	if err != nil {
+		return nil, err
+	}
+	scopePath, err := handlePath(windows.Handle(scopeFile.Fd()))
+	scopeFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := handlePath(windows.Handle(file.Fd()))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !pathWithin(scopePath, opened) {
+		file.Close()
+		return nil, i18n.Errorf("路径超出提权读取范围")
+	}
+	return file, nil
+}
+
+// 句柄对应的实际路径，去掉 \\?\ 前缀
+func handlePath(handle windows.Handle) (string, error) {
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	n, err := windows.GetFinalPathNameByHandle(handle, &buf[0], uint32(len(buf)), 0)
+	if err != nil {
+		return "", err
+	}
+	path := windows.UTF16ToString(buf[:n])
+	if strings.HasPrefix(path, `\\?\UNC\`) {
+		return `\\` + path[len(`\\?\UNC\`):], nil
+	}
+	return strings.TrimPrefix(path, `\\?\`), nil
+}
+
+// 通过 UAC 提示以管理员身份启动辅助进程
+func runElevated(exe string, args []string) error {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = syscall.EscapeArg(arg)
+	}
+
+	verb, _ := syscall.UTF16PtrFromString("runas")
+	file, err := syscall.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+	params, err := syscall.UTF16PtrFromString(strings.Join(quoted, " "))
+	if err != nil {
+		return err
+	}
+
+	// SW_HIDE：辅助进程不显示窗口
+	r, _, _ := procShellExecuteW.Call(0,
+		uintptr(unsafe.Pointer(verb)),
+		uintptr(unsafe.Pointer(file)),
+		uintptr(unsafe.Pointer(params)),
+		0,
+		0,
+	)
+	if r <= 32 {
+		return i18n.Errorf("用户取消了提权或启动失败 (代码 %d)", r)
+	}
+	return nil
+}
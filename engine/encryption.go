@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"syncsafe/crypt"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 客户端加密设置
+type EncryptionConfig struct {
+	Enabled        bool
+	ObfuscateNames bool   // 同时加密快照中的文件名和目录名
+	Passphrase     string // 只保存在本机，遗失后无法还原加密的快照
+}
+
+// 目标根目录中的加密参数文件，换一台电脑时用同一个密码短语即可还原
+const encryptionParamsName = ".syncsafe-encryption.json"
+
+// 本机缓存的加密参数，远程目标无法直接读取参数文件
+func encryptionParamsCache(destination string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(destination)))
+	return filepath.Join(DataDir, "keys", hex.EncodeToString(sum[:8])+".json")
+}
+
+// 读取目标的加密参数，目标中没有时读取本机缓存
+func loadEncryptionParams(destination string) (crypt.Params, bool) {
+	var params crypt.Params
+	paths := []string{encryptionParamsCache(destination)}
+	if !storage.IsRemote(destination) {
+		paths = append([]string{filepath.Join(destination, encryptionParamsName)}, paths...)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err == nil && json.Unmarshal(data, &params) == nil {
+			return params, true
+		}
+	}
+	return params, false
+}
+
+// 派生目标的加密密钥，结果在引擎中缓存。dest 不为 nil 且目标还没有加密参数时生成新的参数，
+// 写入目标根目录并在本机缓存一份
+func (e *Engine) encryptionKey(destination string, dest storage.Backend) (*crypt.Key, error) {
+	destination = filepath.Clean(destination)
+	passphrase := e.Config.Encryption.Passphrase
+	id := destination + "\x00" + passphrase
+
+	e.keyMutex.Lock()
+	defer e.keyMutex.Unlock()
+	if e.key != nil && e.keyID == id {
+		return e.key, nil
+	}
+
+	params, found := loadEncryptionParams(destination)
+	if !found {
+		if dest == nil {
+			return nil, i18n.Errorf("找不到加密参数文件 %s", encryptionParamsName)
+		}
+		params = crypt.NewParams()
+	}
+	key, err := crypt.DeriveKey(passphrase, &params)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if err := saveEncryptionParams(dest, destination, params); err != nil {
+			return nil, err
+		}
+	}
+	e.key, e.keyID = key, id
+	return key, nil
+}
+
+// 把加密参数写入目标根目录和本机缓存
+func saveEncryptionParams(dest storage.Backend, destination string, params crypt.Params) error {
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	cache := encryptionParamsCache(destination)
+	if err := os.MkdirAll(filepath.Dir(cache), 0700); err != nil {
+		return i18n.Errorf("保存加密参数失败: %v", err)
+	}
+	if err := storage.WriteFileAtomic(cache, data, 0600); err != nil {
+		return i18n.Errorf("保存加密参数失败: %v", err)
+	}
+	if err := dest.MkdirAll(destination, 0755); err != nil {
+		return i18n.Errorf("创建目标文件夹失败: %v", err)
+	}
+	if err := dest.CopyFile(context.Background(), cache, filepath.Join(destination, encryptionParamsName)); err != nil {
+		return i18n.Errorf("写入加密参数失败: %v", err)
+	}
+	return nil
+}
+
+// 按设置包装为加密后端，加密参数写入目标文件夹而不是 dest 中（dest 可能是归档）
+func (e *Engine) encryptedDestination(dest storage.Backend) (storage.Backend, error) {
+	key, err := e.encryptionKey(e.Config.DestinationPath, e.Destination())
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewEncrypted(dest, filepath.Clean(e.Config.DestinationPath), key, e.Config.Encryption.ObfuscateNames), nil
+}
+
+// 快照对应的历史记录，找不到时按未加密处理
+func (e *Engine) snapshotRecord(snapshotDir string) history.Record {
+	for _, record := range e.Config.History {
+		if filepath.Clean(record.DestPath) == filepath.Clean(snapshotDir) {
+			return record
+		}
+	}
+	return history.Record{DestPath: snapshotDir}
+}
+
+// 快照已加密但没有填写密码短语
+var errNoPassphrase = i18n.Error("快照已加密，请先在加密设置中填写密码短语")
+
+// 目录快照的解密密钥，快照没有加密时返回 nil。
+// 加密的归档使用标准格式，由密码短语直接解密，也返回 nil
+func (e *Engine) snapshotKey(record history.Record) (*crypt.Key, error) {
+	if !record.Encrypted || IsArchiveSnapshot(record.DestPath) {
+		return nil, nil
+	}
+	if e.Config.Encryption.Passphrase == "" {
+		return nil, errNoPassphrase
+	}
+	key, err := e.encryptionKey(filepath.Dir(record.DestPath), nil)
+	if err != nil {
+		return nil, i18n.Errorf("无法解密快照: %v", err)
+	}
+	return key, nil
+}
+
+// 把快照中的相对路径转换为原始路径，文件名未加密时原样返回
+func plainRelPath(key *crypt.Key, record history.Record, relPath string) (string, error) {
+	if key == nil || !record.EncryptedNames {
+		return relPath, nil
+	}
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i, part := range parts {
+		name, err := key.DecryptName(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = name
+	}
+	return filepath.Join(parts...), nil
+}
+
+// 把原始路径转换为快照中的相对路径，plainRelPath 的逆操作
+func snapshotRelPath(key *crypt.Key, record history.Record, relPath string) (string, error) {
+	if key == nil || !record.EncryptedNames {
+		return relPath, nil
+	}
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i, part := range parts {
+		name, err := key.EncryptName(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = name
+	}
+	return filepath.Join(parts...), nil
+}
+
+// 原始路径在快照中的相对路径，文件名加密的快照中逐段加密，用于按原始路径还原
+func (e *Engine) SnapshotPath(record history.Record, relPath string) (string, error) {
+	if !record.EncryptedNames {
+		return relPath, nil
+	}
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return "", err
+	}
+	return snapshotRelPath(key, record, relPath)
+}
+
+// 快照中文件名的显示名称：加密的文件名解密后显示，无法解密时原样显示
+func (e *Engine) SnapshotName(record history.Record, name string) string {
+	if !record.EncryptedNames {
+		return name
+	}
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return name
+	}
+	if plain, err := key.DecryptName(name); err == nil {
+		return plain
+	}
+	return name
+}
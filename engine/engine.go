@@ -0,0 +1,209 @@
+// Package engine 是 SyncSafe 的备份引擎，不依赖图形界面，可以嵌入其他 Go 程序使用。
+//
+// 基本用法：
+//
+//	config, err := engine.LoadConfig()
+//	e := engine.New(config, engine.Hooks{Status: log.Println})
+//	record, err := e.Backup(context.Background(), 0)
+//
+// Backup 返回的记录由调用方决定是否追加到 Config.History 并保存。
+// 备份进度通过 Hooks.Progress 回调或 Engine.Subscribe 返回的通道以 Event 的形式发出。
+// 监控源文件夹见 syncsafe/watcher，Git 备份见 syncsafe/gitsync。
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/crypt"
+	"syncsafe/faults"
+	"syncsafe/gitsync"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 配置、索引和清单的保存目录
+var DataDir = filepath.Join(".", "syncsafe")
+
+// 引擎回调，均可以为 nil
+type Hooks struct {
+	// 备份进度和结果的状态提示
+	Status func(message string)
+	// 外部命令的输出和模拟模式下记录的操作，逐行调用
+	Output func(line string)
+	// 结构化的备份进度事件，在备份协程中同步调用，应尽快返回
+	Progress func(event Event)
+}
+
+// 备份引擎，按 Config 执行备份
+type Engine struct {
+	Config *Config
+
+	hooks       Hooks
+	helper      *ElevatedHelper
+	helperMutex sync.Mutex // 复制工作协程可能同时启动提权辅助进程
+	index       *SourceIndex
+	indexMutex  sync.Mutex
+	changes     changeQueue     // 监控期间变化的路径，用于按变化备份
+	retryPaths  map[string]bool // 复查队列中等待重试的路径
+	retryMu     sync.Mutex
+	stage       Stage
+	totalFiles  int   // 本次备份预扫描得到的文件总数
+	totalBytes  int64 // 本次备份预扫描得到的总大小
+	subs        subscribers
+	key         *crypt.Key // 最近派生的加密密钥，派生很慢，按目标和密码短语缓存
+	keyID       string
+	keyMutex    sync.Mutex
+}
+
+// 创建备份引擎
+func New(config *Config, hooks Hooks) *Engine {
+	return &Engine{Config: config, hooks: hooks}
+}
+
+func (e *Engine) status(message string) {
+	if e.hooks.Status != nil {
+		e.hooks.Status(message)
+	}
+}
+
+// 当前实际使用的源文件夹，路径模板在调用时展开
+func (e *Engine) SourcePath() string {
+	return ExpandPathTemplate(e.Config.SourcePath, time.Now())
+}
+
+// 备份目标的存储后端：本地文件夹、WebDAV 或 S3，启用故障注入时包装为会注入写入故障的后端
+func (e *Engine) Destination() storage.Backend {
+	switch {
+	case storage.IsWebDAV(e.Config.DestinationPath):
+		return faults.WrapBackend(storage.NewWebDAV(e.Config.DestinationPath, e.Config.WebDAV))
+	case storage.IsS3(e.Config.DestinationPath):
+		return faults.WrapBackend(storage.NewS3(e.Config.DestinationPath, e.Config.S3))
+	}
+	return faults.WrapBackend(storage.NewLocal(e.Config.DestinationPath))
+}
+
+// 源文件夹对应的 Git 仓库，推送进度写入 Hooks.Output，模拟模式下只记录不执行
+func (e *Engine) Git() *gitsync.Repo {
+	return &gitsync.Repo{
+		Dir:       e.SourcePath(),
+		Config:    e.Config.Git,
+		Output:    e.hooks.Output,
+		Simulated: e.Simulated,
+		Status:    e.status,
+	}
+}
+
+// 提交并推送到 Git 仓库。设置了提交计划时把上次提交以来的所有变化合并为一次提交
+func (e *Engine) CommitGit() error {
+	repo := e.Git()
+	var err error
+	if e.Config.Git.Schedule == "" {
+		err = repo.Backup()
+	} else {
+		err = repo.Commit(i18n.Sprintf("定时提交 - %s", time.Now().Format("2006-01-02 15:04:05")))
+	}
+	if err != nil {
+		return &GitError{Err: err}
+	}
+	if !e.Config.DryRun {
+		e.Config.LastGitCommit = time.Now()
+	}
+	return nil
+}
+
+// 模拟模式：所有会修改文件、仓库或远程的操作只记录到 Hooks.Output，不实际执行。
+// 用于在正式使用前评估配置变更，或用于演示和培训
+
+// 模拟模式下记录操作并返回 true，调用方应跳过实际执行
+func (e *Engine) Simulated(format string, args ...interface{}) bool {
+	if !e.Config.DryRun {
+		return false
+	}
+	line := i18n.Sprintf("[%s] [模拟] %s", time.Now().Format("15:04:05"), i18n.Sprintf(format, args...))
+	if e.hooks.Output != nil {
+		e.hooks.Output(line)
+	} else {
+		slog.Info("模拟运行", "line", line)
+	}
+	return true
+}
+
+// 执行外部命令，输出逐行实时写入 Hooks.Output，同时返回合并后的输出供错误信息使用
+func (e *Engine) Exec(dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = env
+	}
+
+	var combined bytes.Buffer
+	var combinedMu sync.Mutex
+	logLine := func(line string) {
+		if e.hooks.Output != nil {
+			e.hooks.Output(line)
+		}
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+
+	logLine(fmt.Sprintf("[%s] $ %s %s", time.Now().Format("15:04:05"), name, redactArgs(args)))
+	if err := cmd.Start(); err != nil {
+		logLine(i18n.T("启动失败: ") + err.Error())
+		return "", err
+	}
+
+	var wg sync.WaitGroup
+	stream := func(r io.Reader) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := scanner.Text()
+			combinedMu.Lock()
+			combined.WriteString(line + "\n")
+			combinedMu.Unlock()
+			logLine(line)
+		}
+	}
+	wg.Add(2)
+	go stream(stdout)
+	go stream(stderr)
+	wg.Wait()
+
+	err = cmd.Wait()
+	if err != nil {
+		logLine(i18n.T("退出: ") + err.Error())
+	}
+	return combined.String(), err
+}
+
+// 隐藏参数中的访问令牌，避免出现在输出中
+func redactArgs(args []string) string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.Contains(arg, "@") && strings.Contains(arg, "://") {
+			if scheme, rest, ok := strings.Cut(arg, "://"); ok {
+				if _, host, ok := strings.Cut(rest, "@"); ok {
+					arg = scheme + "://***@" + host
+				}
+			}
+		}
+		redacted[i] = arg
+	}
+	return strings.Join(redacted, " ")
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 进度事件类型
+type EventKind string
+
+const (
+	EventStage       EventKind = "stage"     // 备份进入新的阶段
+	EventScanStarted EventKind = "scan"      // 开始枚举源文件夹
+	EventScanned     EventKind = "scanned"   // 预扫描完成，TotalFiles 和 TotalBytes 为需要备份的总数
+	EventFileCopied  EventKind = "copied"    // 文件已复制到快照
+	EventFileSkipped EventKind = "skipped"   // 文件未复制，原因见 Message
+	EventError       EventKind = "error"     // 备份失败
+	EventNoChanges   EventKind = "unchanged" // 与上一个快照相比没有变化，跳过本次备份
+)
+
+// 备份阶段
+type Stage string
+
+const (
+	StageStarting  Stage = "starting"  // 检查源文件夹和目标文件夹
+	StageGit       Stage = "git"       // Git 提交和推送
+	StageSync      Stage = "sync"      // 与同步文件夹双向同步
+	StageCopying   Stage = "copying"   // 枚举并复制文件
+	StageFinishing Stage = "finishing" // 保存清单和索引
+	StagePeer      Stage = "peer"      // 推送到局域网中的其他设备
+	StageReplica   Stage = "replica"   // 复制到附加目标
+	StageDone      Stage = "done"      // 备份结束（无论成败）
+)
+
+// 阶段的显示名称
+var StageLabels = map[Stage]string{
+	StageStarting:  "准备",
+	StageGit:       "Git 备份",
+	StageSync:      "双向同步",
+	StageCopying:   "复制文件",
+	StageFinishing: "保存清单",
+	StagePeer:      "局域网推送",
+	StageReplica:   "附加目标",
+	StageDone:      "完成",
+}
+
+// 备份进度事件
+type Event struct {
+	Kind  EventKind
+	Time  time.Time
+	Stage Stage  // 事件发生时所处的阶段
+	Path  string // 文件事件对应的源文件路径；扫描事件为源文件夹
+	Size  int64  // 文件大小
+	Files int    // 到目前为止已处理的文件数
+	Bytes int64  // 到目前为止已处理的字节数
+	// 预扫描得到的文件总数和总大小，预扫描完成前为 0
+	TotalFiles int
+	TotalBytes int64
+	Message    string // 阶段说明、跳过原因或错误信息
+}
+
+func (ev Event) String() string {
+	switch ev.Kind {
+	case EventStage:
+		return fmt.Sprintf("[%s] %s", i18n.T(StageLabels[ev.Stage]), ev.Message)
+	case EventScanStarted:
+		return i18n.Sprintf("开始扫描 %s（%s）", ev.Path, ev.Message)
+	case EventScanned:
+		return i18n.T("预扫描完成: ") + ev.Message
+	case EventFileCopied:
+		return i18n.Sprintf("已复制 %s（%d 字节）", ev.Path, ev.Size)
+	case EventFileSkipped:
+		return i18n.Sprintf("跳过 %s: %s", ev.Path, ev.Message)
+	case EventError:
+		return i18n.T("备份失败: ") + ev.Message
+	case EventNoChanges:
+		return i18n.T("跳过备份: ") + ev.Message
+	}
+	return string(ev.Kind)
+}
+
+// 进度事件的订阅者
+type subscribers struct {
+	mu    sync.Mutex
+	chans map[chan Event]struct{}
+}
+
+// 订阅进度事件，返回事件通道和取消订阅的函数。
+// 通道已满时丢弃新的事件，订阅者处理过慢不会拖慢备份
+func (e *Engine) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	e.subs.mu.Lock()
+	if e.subs.chans == nil {
+		e.subs.chans = make(map[chan Event]struct{})
+	}
+	e.subs.chans[ch] = struct{}{}
+	e.subs.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			e.subs.mu.Lock()
+			delete(e.subs.chans, ch)
+			e.subs.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// 发送进度事件给 Hooks.Progress 和所有订阅者
+func (e *Engine) emit(ev Event) {
+	ev.Time = time.Now()
+	if ev.Stage == "" {
+		ev.Stage = e.stage
+	}
+	if ev.TotalFiles == 0 && ev.TotalBytes == 0 {
+		ev.TotalFiles, ev.TotalBytes = e.totalFiles, e.totalBytes
+	}
+	if e.hooks.Progress != nil {
+		e.hooks.Progress(ev)
+	}
+	e.subs.mu.Lock()
+	for ch := range e.subs.chans {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	e.subs.mu.Unlock()
+}
+
+// 进入新的阶段
+func (e *Engine) setStage(stage Stage, message string) {
+	e.stage = stage
+	e.emit(Event{Kind: EventStage, Stage: stage, Message: message})
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+
+	"syncsafe/i18n"
+)
+
+// 统计快照中文件的总大小，用于显示导出进度
+func SnapshotSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// 复制文件内容并报告进度
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	progress func(int64)
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	p.done += int64(n)
+	if p.progress != nil {
+		p.progress(p.done)
+	}
+	return n, err
+}
+
+// 把快照目录打包为一个独立的归档。快照中的硬链接按普通文件写入，
+// 归档不依赖其他快照即可完整还原。progress 报告已写入的字节数，可以为 nil
+func ExportSnapshot(dir string, w io.Writer, format string, progress func(int64)) error {
+	root := filepath.Base(dir)
+	counter := &progressWriter{progress: progress}
+
+	var addFile func(relPath string, info os.FileInfo, path string) error
+	var finish func() error
+
+	if format == "tar.gz" {
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		counter.w = tw
+		addFile = func(relPath string, info os.FileInfo, path string) error {
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				var err error
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relPath)
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(counter, file)
+			return err
+		}
+		finish = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return gz.Close()
+		}
+	} else {
+		zw := zip.NewWriter(w)
+		addFile = func(relPath string, info os.FileInfo, path string) error {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relPath)
+			if info.IsDir() {
+				header.Name += "/"
+			} else {
+				header.Method = zip.Deflate
+			}
+			entry, err := zw.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				// zip 中的符号链接以目标路径作为内容
+				link, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(entry, link)
+				return err
+			case info.Mode().IsRegular():
+				file, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				counter.w = entry
+				_, err = io.Copy(counter, file)
+				return err
+			}
+			return nil
+		}
+		finish = zw.Close
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		// 归档内以快照目录名作为顶层目录
+		return addFile(filepath.Join(root, relPath), info, path)
+	})
+	if err != nil {
+		return i18n.Errorf("打包快照失败: %v", err)
+	}
+	if err := finish(); err != nil {
+		return i18n.Errorf("写入归档失败: %v", err)
+	}
+	return nil
+}
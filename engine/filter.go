@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/ignore"
+)
+
+// 按配置排除隐藏文件、系统文件、点文件和符合排除规则的文件
+type fileFilter struct {
+	hidden   bool // 排除隐藏文件（Windows 隐藏属性，macOS 隐藏标志，其他系统为点文件）
+	system   bool // 排除系统文件（仅 Windows 有系统属性）
+	dotfiles bool // 排除以 . 开头的文件和目录
+	rules    *ignore.Matcher
+	files    *fileSet // 只备份这些单独的文件，为 nil 表示备份整个源文件夹
+	skipped  string
+}
+
+func (c *Config) fileFilter() *fileFilter {
+	return &fileFilter{hidden: c.ExcludeHidden, system: c.ExcludeSystem, dotfiles: c.ExcludeDotfiles, rules: c.ignoreRules(), files: c.fileSet()}
+}
+
+// 单独备份的文件及其所在的各级目录，路径相对源文件夹
+type fileSet struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// Config.SourceFiles 组成的集合，没有单独的文件时返回 nil
+func (c *Config) fileSet() *fileSet {
+	if len(c.SourceFiles) == 0 {
+		return nil
+	}
+	s := &fileSet{files: make(map[string]bool), dirs: make(map[string]bool)}
+	for _, file := range c.SourceFiles {
+		relPath := filepath.Clean(filepath.FromSlash(file))
+		s.files[relPath] = true
+		for dir := filepath.Dir(relPath); dir != "."; dir = filepath.Dir(dir) {
+			s.dirs[dir] = true
+		}
+	}
+	return s
+}
+
+// 添加一个单独备份的文件。还没有源文件夹时使用文件所在的目录，否则文件必须在源文件夹中
+func (c *Config) AddSourceFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return i18n.Errorf("无法访问文件: %v", err)
+	}
+	if info.IsDir() {
+		return i18n.Errorf("%s 是文件夹，请选择文件", path)
+	}
+	source := c.SourcePath
+	if source == "" {
+		source = filepath.Dir(path)
+	}
+	relPath, err := filepath.Rel(ExpandPathTemplate(source, time.Now()), path)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return i18n.Errorf("只能添加源文件夹中的文件，其他文件夹中的文件请新建一个任务: %s", path)
+	}
+	c.SourcePath = source
+	if relPath = filepath.ToSlash(relPath); !slices.Contains(c.SourceFiles, relPath) {
+		c.SourceFiles = append(c.SourceFiles, relPath)
+	}
+	return nil
+}
+
+// 路径是否不在集合中：目录只保留单独文件的上级目录
+func (s *fileSet) excluded(relPath string, isDir bool) bool {
+	if isDir {
+		return !s.dirs[relPath]
+	}
+	return !s.files[relPath]
+}
+
+// 源文件夹根目录中的规则文件，按顺序读取
+var IgnoreFiles = []string{".gitignore", ".syncsafeignore"}
+
+// 读取源文件夹中规则文件的所有行，没有规则文件时返回 nil
+func ReadIgnoreFiles(source string) []string {
+	var lines []string
+	for _, name := range IgnoreFiles {
+		data, err := os.ReadFile(filepath.Join(source, name))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				slog.Warn("读取规则文件失败", "file", name, "err", err)
+			}
+			continue
+		}
+		lines = append(lines, strings.Split(strings.TrimPrefix(string(data), "\ufeff"), "\n")...)
+	}
+	return lines
+}
+
+// 编译排除规则，无效的规则被跳过。规则文件中的规则在前，任务的规则可以用 ! 重新包含
+func (c *Config) ignoreRules() *ignore.Matcher {
+	var lines []string
+	if c.UseIgnoreFiles && c.SourcePath != "" {
+		lines = ReadIgnoreFiles(ExpandPathTemplate(c.SourcePath, time.Now()))
+	}
+	lines = append(lines, c.FilterRules...)
+	// 位于源文件夹中的文件系统快照（Btrfs）不备份，放在最后，不能被 ! 规则重新包含
+	if c.SourceSnapshot {
+		lines = append(lines, "/"+fsSnapshotPrefix+"*/")
+	}
+	rules, err := ignore.Compile(lines)
+	if err != nil {
+		slog.Warn("排除规则无效", "err", err)
+	}
+	return rules
+}
+
+// 监控源文件夹 root 时判断完整路径 path 是否被排除规则排除（包括上级目录）。
+// 只备份单独的文件时只监控这些文件所在的目录，只保留这些文件的事件。
+// 没有排除规则也没有单独的文件时返回 nil
+func (c *Config) WatchIgnore(root string) func(path string, isDir bool) bool {
+	rules := c.ignoreRules()
+	files := c.fileSet()
+	if rules.Empty() && files == nil {
+		return nil
+	}
+	return func(path string, isDir bool) bool {
+		relPath, err := filepath.Rel(root, path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			return false
+		}
+		if files != nil && files.excluded(relPath, isDir) {
+			return true
+		}
+		return rules.Excluded(relPath, isDir)
+	}
+}
+
+// 是否需要排除任何文件
+func (f *fileFilter) Active() bool {
+	return f.hidden || f.system || f.dotfiles || !f.rules.Empty() || f.files != nil
+}
+
+// 文件或目录是否被排除。被排除的目录中的内容也一并排除，
+// 调用顺序需要与文件树遍历顺序一致（目录排在其内容之前）
+func (f *fileFilter) Exclude(relPath string, info os.FileInfo) bool {
+	if !f.Active() {
+		return false
+	}
+	if f.skipped != "" {
+		if strings.HasPrefix(relPath, f.skipped+string(filepath.Separator)) {
+			return true
+		}
+		f.skipped = ""
+	}
+
+	name := info.Name()
+	dotfile := strings.HasPrefix(name, ".")
+	hidden, system := fileAttributes(info)
+	if (f.files != nil && f.files.excluded(relPath, info.IsDir())) ||
+		(f.dotfiles && dotfile) || (f.hidden && hidden) || (f.system && system) || f.rules.Match(relPath, info.IsDir()) {
+		if info.IsDir() {
+			f.skipped = relPath
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+//go:build darwin
+
+package engine
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// chflags hidden 设置的标志
+const ufHidden = 0x8000
+
+// 点文件和设置了隐藏标志的文件都是隐藏文件，macOS 没有系统文件属性
+func fileAttributes(info os.FileInfo) (hidden, system bool) {
+	hidden = strings.HasPrefix(info.Name(), ".")
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Flags&ufHidden != 0 {
+		hidden = true
+	}
+	return hidden, false
+}
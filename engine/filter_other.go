@@ -0,0 +1,13 @@
+//go:build !windows && !darwin
+
+package engine
+
+import (
+	"os"
+	"strings"
+)
+
+// 隐藏文件即点文件，没有系统文件属性
+func fileAttributes(info os.FileInfo) (hidden, system bool) {
+	return strings.HasPrefix(info.Name(), "."), false
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// 文件的隐藏和系统属性，Windows 上隐藏与否由属性决定，与文件名无关
+func fileAttributes(info os.FileInfo) (hidden, system bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return false, false
+	}
+	return data.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0,
+		data.FileAttributes&syscall.FILE_ATTRIBUTE_SYSTEM != 0
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 文件系统快照的名称前缀。Btrfs 快照可能位于源文件夹中，备份和监控时排除
+const fsSnapshotPrefix = ".syncsafe-snapshot-"
+
+// 源文件夹所在文件系统的只读快照，备份期间从中读取文件
+type fsSnapshot struct {
+	Kind    string // 文件系统类型，例如 Btrfs
+	Root    string // 快照中与源文件夹对应的目录
+	release func() error
+}
+
+// 为源文件夹所在的文件系统创建只读快照，文件系统不支持或创建失败时返回错误
+func (e *Engine) snapshotSource(source string) (*fsSnapshot, error) {
+	source, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return nil, i18n.Errorf("无法访问源文件夹: %v", err)
+	}
+	name := fsSnapshotPrefix + time.Now().Format("20060102-150405")
+	snap, err := createFSSnapshot(source, name)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("已创建文件系统快照", "kind", snap.Kind, "root", snap.Root)
+	return snap, nil
+}
+
+// 删除快照，失败时只记录日志
+func (s *fsSnapshot) Release() {
+	if s == nil {
+		return
+	}
+	if err := s.release(); err != nil {
+		slog.Warn("删除文件系统快照失败", "kind", s.Kind, "root", s.Root, "err", err)
+	}
+}
+
+// 执行创建或删除快照的命令，返回标准输出，失败时错误中带有命令的错误输出
+func runSnapshotCommand(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s: %v: %s", name, err, message)
+		}
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"syncsafe/i18n"
+)
+
+// 数据卷通过固件链接出现在根目录下，例如 /Users 实际位于该卷中
+const dataVolume = "/System/Volumes/Data"
+
+// APFS：通过 tmutil 为本机的 APFS 卷创建本地快照，只读挂载源文件夹所在卷的快照
+func createFSSnapshot(source, name string) (*fsSnapshot, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(source, &fs); err != nil {
+		return nil, i18n.Errorf("无法读取源文件夹所在的文件系统: %v", err)
+	}
+	fsType, volume := cString(fs.Fstypename[:]), cString(fs.Mntonname[:])
+	if fsType != "apfs" {
+		return nil, i18n.Errorf("源文件夹所在的文件系统（%s）不支持快照，需要 APFS", fsType)
+	}
+	relPath, err := filepath.Rel(volume, source)
+	if err != nil || !filepath.IsLocal(relPath) {
+		if volume != dataVolume {
+			return nil, i18n.Errorf("源文件夹不在卷 %s 中", volume)
+		}
+		relPath = strings.TrimPrefix(source, "/")
+	}
+
+	// 输出的最后一个词是快照的日期，例如 Created local snapshot with date: 2024-03-09-123456
+	output, err := runSnapshotCommand("tmutil", "localsnapshot")
+	if err != nil {
+		return nil, i18n.Errorf("创建 APFS 快照失败: %v", err)
+	}
+	words := strings.Fields(output)
+	if len(words) == 0 {
+		return nil, i18n.Errorf("创建 APFS 快照失败: %s", output)
+	}
+	date := words[len(words)-1]
+	deleteSnapshot := func() error {
+		_, err := runSnapshotCommand("tmutil", "deletelocalsnapshots", date)
+		return err
+	}
+	dir, err := os.MkdirTemp("", strings.TrimPrefix(name, "."))
+	if err != nil {
+		deleteSnapshot()
+		return nil, i18n.Errorf("创建挂载目录失败: %v", err)
+	}
+	snapshot := "com.apple.TimeMachine." + date + ".local"
+	if _, err := runSnapshotCommand("mount_apfs", "-o", "ro", "-s", snapshot, volume, dir); err != nil {
+		os.Remove(dir)
+		deleteSnapshot()
+		return nil, i18n.Errorf("挂载 APFS 快照失败: %v", err)
+	}
+	return &fsSnapshot{Kind: "APFS", Root: filepath.Join(dir, relPath), release: func() error {
+		if _, err := runSnapshotCommand("umount", dir); err != nil {
+			return err
+		}
+		os.Remove(dir)
+		return deleteSnapshot()
+	}}, nil
+}
+
+// 以 0 结尾的字符数组
+func cString(b []int8) string {
+	s := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == 0 {
+			break
+		}
+		s = append(s, byte(c))
+	}
+	return string(s)
+}
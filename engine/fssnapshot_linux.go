@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"syncsafe/i18n"
+)
+
+// statfs 返回的文件系统类型
+const (
+	btrfsMagic = 0x9123683e
+	zfsMagic   = 0x2fc12fc1
+)
+
+// Btrfs 和 ZFS 按文件系统类型创建快照，其他文件系统位于 LVM 逻辑卷上时创建 LVM 快照
+func createFSSnapshot(source, name string) (*fsSnapshot, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(source, &fs); err != nil {
+		return nil, i18n.Errorf("无法读取源文件夹所在的文件系统: %v", err)
+	}
+	switch uint32(fs.Type) {
+	case btrfsMagic:
+		return btrfsSnapshot(source, name)
+	case zfsMagic:
+		return zfsSnapshot(source, name)
+	}
+	return lvmSnapshot(source, name)
+}
+
+// Btrfs：在源文件夹所在子卷的根目录创建只读快照。快照不包含嵌套的子卷，其中的内容不会备份
+func btrfsSnapshot(source, name string) (*fsSnapshot, error) {
+	subvolume, err := btrfsSubvolume(source)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(subvolume, name)
+	if _, err := runSnapshotCommand("btrfs", "subvolume", "snapshot", "-r", subvolume, path); err != nil {
+		return nil, i18n.Errorf("创建 Btrfs 快照失败: %v", err)
+	}
+	relPath, _ := filepath.Rel(subvolume, source)
+	return &fsSnapshot{Kind: "Btrfs", Root: filepath.Join(path, relPath), release: func() error {
+		_, err := runSnapshotCommand("btrfs", "subvolume", "delete", path)
+		return err
+	}}, nil
+}
+
+// 路径所在子卷的根目录：同一设备上 inode 为 256 的最近一级目录
+func btrfsSubvolume(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+	dev := st.Dev
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if err := syscall.Stat(dir, &st); err != nil {
+			return "", err
+		}
+		if st.Dev != dev {
+			break
+		}
+		if st.Ino == 256 {
+			return dir, nil
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return "", i18n.Errorf("找不到 %s 所在的 Btrfs 子卷", path)
+}
+
+// ZFS：为源文件夹所在的数据集创建快照，从挂载点下的 .zfs/snapshot 读取
+func zfsSnapshot(source, name string) (*fsSnapshot, error) {
+	output, err := runSnapshotCommand("zfs", "list", "-H", "-o", "name,mountpoint", source)
+	if err != nil {
+		return nil, i18n.Errorf("找不到源文件夹所在的 ZFS 数据集: %v", err)
+	}
+	fields := strings.Split(strings.TrimSpace(output), "\t")
+	if len(fields) != 2 {
+		return nil, i18n.Errorf("找不到源文件夹所在的 ZFS 数据集: %s", output)
+	}
+	dataset, mountpoint := fields[0], fields[1]
+	relPath, err := filepath.Rel(mountpoint, source)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return nil, i18n.Errorf("源文件夹不在 ZFS 数据集 %s 的挂载点中", dataset)
+	}
+	name = strings.TrimPrefix(name, ".")
+	snapshot := dataset + "@" + name
+	if _, err := runSnapshotCommand("zfs", "snapshot", snapshot); err != nil {
+		return nil, i18n.Errorf("创建 ZFS 快照失败: %v", err)
+	}
+	return &fsSnapshot{Kind: "ZFS", Root: filepath.Join(mountpoint, ".zfs", "snapshot", name, relPath), release: func() error {
+		_, err := runSnapshotCommand("zfs", "destroy", snapshot)
+		return err
+	}}, nil
+}
+
+// LVM：为源文件夹所在的逻辑卷创建快照，只读挂载到临时目录
+func lvmSnapshot(source, name string) (*fsSnapshot, error) {
+	mount, err := findMount(source)
+	if err != nil {
+		return nil, err
+	}
+	output, err := runSnapshotCommand("lvs", "--noheadings", "-o", "vg_name,lv_name", mount.device)
+	if err != nil {
+		return nil, i18n.Errorf("源文件夹所在的文件系统（%s）不支持快照，需要 Btrfs、ZFS 或 LVM 逻辑卷", mount.fsType)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return nil, i18n.Errorf("无法识别 %s 所在的 LVM 逻辑卷", mount.device)
+	}
+	group := fields[0]
+	name = strings.TrimPrefix(name, ".")
+	// 快照大小为原逻辑卷的 10%，备份期间写入的数据超过该大小时快照失效，读取出错
+	if _, err := runSnapshotCommand("lvcreate", "--snapshot", "--extents", "10%ORIGIN", "--name", name, group+"/"+fields[1]); err != nil {
+		return nil, i18n.Errorf("创建 LVM 快照失败: %v", err)
+	}
+	volume := group + "/" + name
+	removeVolume := func() error {
+		_, err := runSnapshotCommand("lvremove", "-f", volume)
+		return err
+	}
+	dir, err := os.MkdirTemp("", name)
+	if err != nil {
+		removeVolume()
+		return nil, i18n.Errorf("创建挂载目录失败: %v", err)
+	}
+	// XFS 拒绝挂载与已挂载的文件系统 UUID 相同的快照
+	options := "ro"
+	if mount.fsType == "xfs" {
+		options += ",nouuid"
+	}
+	if _, err := runSnapshotCommand("mount", "-o", options, "/dev/"+volume, dir); err != nil {
+		os.Remove(dir)
+		removeVolume()
+		return nil, i18n.Errorf("挂载 LVM 快照失败: %v", err)
+	}
+	relPath, _ := filepath.Rel(mount.point, source)
+	return &fsSnapshot{Kind: "LVM", Root: filepath.Join(dir, mount.root, relPath), release: func() error {
+		if _, err := runSnapshotCommand("umount", dir); err != nil {
+			return err
+		}
+		os.Remove(dir)
+		return removeVolume()
+	}}, nil
+}
+
+// /proc/self/mountinfo 中的一个挂载点
+type mountEntry struct {
+	point  string // 挂载点
+	root   string // 挂载的是文件系统中的哪个目录
+	fsType string
+	device string
+}
+
+// 路径所在的挂载点：挂载点是路径上级目录中最深的一个
+func findMount(path string) (mountEntry, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return mountEntry{}, i18n.Errorf("读取挂载信息失败: %v", err)
+	}
+	defer file.Close()
+	var found mountEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 格式：ID 上级ID 主:次 根目录 挂载点 选项 [可选字段...] - 类型 设备 超级块选项
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+2 >= len(fields) {
+			continue
+		}
+		point := unescapeMount(fields[4])
+		if relPath, err := filepath.Rel(point, path); err != nil || !filepath.IsLocal(relPath) {
+			continue
+		}
+		if len(point) >= len(found.point) {
+			found = mountEntry{point: point, root: unescapeMount(fields[3]), fsType: fields[sep+1], device: unescapeMount(fields[sep+2])}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return mountEntry{}, i18n.Errorf("读取挂载信息失败: %v", err)
+	}
+	if found.point == "" {
+		return mountEntry{}, i18n.Errorf("找不到源文件夹所在的挂载点")
+	}
+	return found, nil
+}
+
+// 还原挂载信息中转义为 \ooo 的空格、制表符等字符
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package engine
+
+import "syncsafe/i18n"
+
+func createFSSnapshot(source, name string) (*fsSnapshot, error) {
+	return nil, i18n.Errorf("当前系统不支持文件系统快照")
+}
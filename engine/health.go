@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"syncsafe/i18n"
+)
+
+// 源文件夹中无法读取的路径
+type HealthProblem struct {
+	Path string
+	Err  string
+}
+
+// 遍历源文件夹，尝试读取每个目录并打开每个文件，返回所有无法读取的路径。
+// 源文件夹本身无法访问时返回错误
+func ScanSourceHealth(root string) ([]HealthProblem, error) {
+	if _, err := os.ReadDir(root); err != nil {
+		return nil, i18n.Errorf("源文件夹无法访问: %v", err)
+	}
+
+	var problems []HealthProblem
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 目录无法读取（权限不足或挂载已断开），跳过其内容
+			problems = append(problems, HealthProblem{Path: path, Err: err.Error()})
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			problems = append(problems, HealthProblem{Path: path, Err: err.Error()})
+			return nil
+		}
+		file.Close()
+		return nil
+	})
+	return problems, err
+}
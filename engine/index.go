@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 索引中的单个条目
+type IndexEntry struct {
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// 源文件夹索引：持久化保存源文件夹中每个文件的大小和修改时间，
+// 由监控事件增量更新，统计文件数量/大小时无需完整遍历文件树
+type SourceIndex struct {
+	mu        sync.RWMutex
+	Root      string
+	Entries   map[string]IndexEntry // key 为相对源文件夹的路径
+	BuiltAt   time.Time
+	UpdatedAt time.Time
+	Cursor    JournalCursor // 索引对应的变更日志位置
+	live      bool          // 是否由监控事件实时维护
+	dirty     bool
+}
+
+// 索引文件路径，每个源文件夹对应一个索引文件
+func indexPath(root string) string {
+	sum := sha1.Sum([]byte(filepath.Clean(root)))
+	return filepath.Join(DataDir, "index", hex.EncodeToString(sum[:8])+".idx")
+}
+
+func newSourceIndex(root string) *SourceIndex {
+	return &SourceIndex{
+		Root:    filepath.Clean(root),
+		Entries: make(map[string]IndexEntry),
+	}
+}
+
+// 加载源文件夹的索引，不存在或损坏时返回空索引
+func loadSourceIndex(root string) *SourceIndex {
+	idx := newSourceIndex(root)
+
+	file, err := os.Open(indexPath(root))
+	if err != nil {
+		return idx
+	}
+	defer file.Close()
+
+	var stored SourceIndex
+	if err := gob.NewDecoder(file).Decode(&stored); err != nil || stored.Root != idx.Root {
+		return idx
+	}
+	if stored.Entries != nil {
+		idx.Entries = stored.Entries
+	}
+	idx.BuiltAt = stored.BuiltAt
+	idx.UpdatedAt = stored.UpdatedAt
+	idx.Cursor = stored.Cursor
+	return idx
+}
+
+// 保存索引到文件
+func (idx *SourceIndex) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	path := indexPath(idx.Root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return i18n.Errorf("创建索引目录失败: %v", err)
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return i18n.Errorf("创建索引文件失败: %v", err)
+	}
+	if err := gob.NewEncoder(file).Encode(idx); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return i18n.Errorf("写入索引失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("写入索引失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return i18n.Errorf("保存索引失败: %v", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
+
+// 完整遍历源文件夹重建索引
+func (idx *SourceIndex) Rebuild() error {
+	entries := make(map[string]IndexEntry)
+	err := filepath.Walk(idx.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		relPath, err := filepath.Rel(idx.Root, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		entries[relPath] = IndexEntry{Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}
+		return nil
+	})
+	if err != nil {
+		return i18n.Errorf("建立索引失败: %v", err)
+	}
+
+	idx.mu.Lock()
+	idx.Entries = entries
+	idx.BuiltAt = time.Now()
+	idx.UpdatedAt = idx.BuiltAt
+	idx.dirty = true
+	idx.mu.Unlock()
+	return nil
+}
+
+// 索引是否为空（从未建立）
+func (idx *SourceIndex) Empty() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.BuiltAt.IsZero()
+}
+
+// 标记索引是否由监控事件实时维护
+func (idx *SourceIndex) SetLive(live bool) {
+	idx.mu.Lock()
+	idx.live = live
+	idx.mu.Unlock()
+}
+
+// 索引是否实时有效，可以代替遍历文件树
+func (idx *SourceIndex) Live() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.live && !idx.BuiltAt.IsZero()
+}
+
+// 根据监控事件更新单个路径：路径存在则刷新条目，不存在则删除该条目及其子条目
+func (idx *SourceIndex) Update(path string) {
+	relPath, err := filepath.Rel(idx.Root, path)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return
+	}
+	for _, part := range strings.Split(relPath, string(filepath.Separator)) {
+		if part == ".git" {
+			return
+		}
+	}
+
+	info, err := os.Lstat(path)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.UpdatedAt = time.Now()
+	idx.dirty = true
+
+	if err != nil {
+		idx.removeLocked(relPath)
+		return
+	}
+
+	idx.Entries[relPath] = IndexEntry{Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}
+	if !info.IsDir() {
+		return
+	}
+
+	// 新建（或移入）的目录需要把其中已有的内容一并加入索引
+	filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if fi.IsDir() && fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if rel, err := filepath.Rel(idx.Root, p); err == nil && rel != relPath {
+			idx.Entries[rel] = IndexEntry{Size: fi.Size(), ModTime: fi.ModTime(), IsDir: fi.IsDir()}
+		}
+		return nil
+	})
+}
+
+// 删除条目及其所有子条目，调用方需持有写锁
+func (idx *SourceIndex) removeLocked(relPath string) {
+	entry, ok := idx.Entries[relPath]
+	delete(idx.Entries, relPath)
+	if ok && !entry.IsDir {
+		return
+	}
+	prefix := relPath + string(filepath.Separator)
+	for p := range idx.Entries {
+		if strings.HasPrefix(p, prefix) {
+			delete(idx.Entries, p)
+		}
+	}
+}
+
+// 返回文件数量和总大小
+func (idx *SourceIndex) Stats() (fileCount int, totalSize int64) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	for _, entry := range idx.Entries {
+		if entry.IsDir {
+			continue
+		}
+		fileCount++
+		totalSize += entry.Size
+	}
+	return fileCount, totalSize
+}
+
+// 按文件树遍历顺序返回所有条目的相对路径，目录排在其内容之前
+func (idx *SourceIndex) Paths() []string {
+	idx.mu.RLock()
+	paths := make([]string, 0, len(idx.Entries))
+	for p := range idx.Entries {
+		paths = append(paths, p)
+	}
+	idx.mu.RUnlock()
+	sort.Slice(paths, func(i, j int) bool {
+		return comparePaths(paths[i], paths[j]) < 0
+	})
+	return paths
+}
+
+// 获取单个条目
+func (idx *SourceIndex) Get(relPath string) (IndexEntry, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	entry, ok := idx.Entries[relPath]
+	return entry, ok
+}
+
+// 索引是否有尚未保存的修改
+func (idx *SourceIndex) Dirty() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.dirty
+}
+
+// 用新的条目集合替换索引内容
+func (idx *SourceIndex) Reset(entries map[string]IndexEntry) {
+	idx.mu.Lock()
+	idx.Entries = entries
+	idx.BuiltAt = time.Now()
+	idx.UpdatedAt = idx.BuiltAt
+	idx.dirty = true
+	idx.mu.Unlock()
+}
+
+// 索引最后更新的时间
+func (idx *SourceIndex) Updated() time.Time {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.UpdatedAt
+}
+
+// 获取当前源文件夹的索引，必要时从文件加载
+func (e *Engine) SourceIndex() (*SourceIndex, error) {
+	e.indexMutex.Lock()
+	defer e.indexMutex.Unlock()
+
+	if e.Config.SourcePath == "" {
+		return nil, i18n.Errorf("请先选择源文件夹")
+	}
+	if e.index != nil && e.index.Root == filepath.Clean(e.SourcePath()) {
+		return e.index, nil
+	}
+
+	e.index = loadSourceIndex(e.SourcePath())
+	return e.index, nil
+}
+
+// 已经加载的索引，尚未加载时为 nil
+func (e *Engine) LoadedIndex() *SourceIndex {
+	e.indexMutex.Lock()
+	defer e.indexMutex.Unlock()
+	return e.index
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 导出给其他同步工具使用的目录布局
+const (
+	// 每个快照一个按时间命名的目录，未变化的文件硬链接到上一次导出，latest 链接指向最新的目录，
+	// 可以直接作为 rsync --link-dest 的基准，也可以被 rsnapshot 一类的工具接着轮转
+	LayoutRsync = "rsync"
+	// 目标文件夹始终是最新快照的完整镜像，作为 Syncthing 共享文件夹发布，由 Syncthing 负责分发和版本
+	LayoutSyncthing = "syncthing"
+)
+
+// 布局的显示名称
+var LayoutLabels = map[string]string{
+	LayoutRsync:     "rsync（按时间的快照目录 + latest）",
+	LayoutSyncthing: "Syncthing（最新快照的镜像）",
+}
+
+// rsync 布局中指向最新快照目录的链接
+const latestLink = "latest"
+
+// Syncthing 识别共享文件夹的标记目录，以及不属于快照、镜像时不删除的文件
+const stFolderMarker = ".stfolder"
+
+var syncthingReserved = map[string]bool{
+	stFolderMarker: true,
+	".stignore":    true,
+	".stversions":  true,
+}
+
+// 导出结果
+type InteropResult struct {
+	Path    string // 导出的目录
+	Copied  int
+	Linked  int // 硬链接到上一次导出的文件数
+	Removed int // 镜像中删除的多余文件数
+}
+
+// 按 layout 把快照目录 dir 导出到 target
+func ExportLayout(dir, target, layout string) (InteropResult, error) {
+	if target == "" {
+		return InteropResult{}, i18n.Errorf("请先选择导出目录")
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return InteropResult{}, i18n.Errorf("快照不存在: %v", err)
+	}
+	switch layout {
+	case LayoutRsync:
+		return exportRsync(dir, target)
+	case LayoutSyncthing:
+		return exportSyncthing(dir, target)
+	}
+	return InteropResult{}, i18n.Errorf("未知的导出布局: %s", layout)
+}
+
+// 导出为 rsync --link-dest 布局
+func exportRsync(dir, target string) (InteropResult, error) {
+	result := InteropResult{Path: filepath.Join(target, filepath.Base(dir))}
+	if err := os.MkdirAll(result.Path, 0755); err != nil {
+		return result, i18n.Errorf("创建导出目录失败: %v", err)
+	}
+	previous := ""
+	if name, err := os.Readlink(filepath.Join(target, latestLink)); err == nil && name != filepath.Base(dir) {
+		previous = filepath.Join(target, name)
+	}
+
+	err := walkSnapshot(dir, func(relPath string, info os.FileInfo) error {
+		dst := filepath.Join(result.Path, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(dst, info.Mode().Perm()|0700)
+		}
+		if previous != "" {
+			if old, err := os.Stat(filepath.Join(previous, relPath)); err == nil && sameFile(old, info) {
+				os.Remove(dst)
+				if os.Link(filepath.Join(previous, relPath), dst) == nil {
+					result.Linked++
+					return nil
+				}
+			}
+		}
+		if err := storage.CopyFile(filepath.Join(dir, relPath), dst); err != nil {
+			return err
+		}
+		result.Copied++
+		return nil
+	})
+	if err != nil {
+		return result, i18n.Errorf("导出快照失败: %v", err)
+	}
+
+	// 先创建临时链接再重命名，latest 始终指向一个完整的目录
+	tmpLink := filepath.Join(target, latestLink+".tmp")
+	os.Remove(tmpLink)
+	if err := os.Symlink(filepath.Base(dir), tmpLink); err != nil {
+		// Windows 上创建符号链接需要权限，没有 latest 时可以直接指定目录名作为 --link-dest
+		slog.Warn("创建 latest 链接失败", "err", err)
+		return result, nil
+	}
+	if err := os.Rename(tmpLink, filepath.Join(target, latestLink)); err != nil {
+		os.Remove(tmpLink)
+		slog.Warn("更新 latest 链接失败", "err", err)
+	}
+	return result, nil
+}
+
+// 导出为 Syncthing 共享文件夹：只复制有差异的文件并删除快照中没有的文件
+func exportSyncthing(dir, target string) (InteropResult, error) {
+	result := InteropResult{Path: target}
+
+	// 镜像会删除多余的文件，只写入空目录或已有的 Syncthing 文件夹
+	entries, err := os.ReadDir(target)
+	if err != nil && !os.IsNotExist(err) {
+		return result, i18n.Errorf("读取导出目录失败: %v", err)
+	}
+	if len(entries) > 0 {
+		if _, err := os.Stat(filepath.Join(target, stFolderMarker)); err != nil {
+			return result, i18n.Errorf("导出目录不是空的，也不是 Syncthing 文件夹（缺少 %s）: %s", stFolderMarker, target)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(target, stFolderMarker), 0755); err != nil {
+		return result, i18n.Errorf("创建导出目录失败: %v", err)
+	}
+
+	keep := make(map[string]bool)
+	err = walkSnapshot(dir, func(relPath string, info os.FileInfo) error {
+		keep[relPath] = true
+		dst := filepath.Join(target, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(dst, info.Mode().Perm()|0700)
+		}
+		if old, err := os.Stat(dst); err == nil && sameFile(old, info) {
+			return nil
+		}
+		if err := storage.CopyFile(filepath.Join(dir, relPath), dst); err != nil {
+			return err
+		}
+		result.Copied++
+		return nil
+	})
+	if err != nil {
+		return result, i18n.Errorf("导出快照失败: %v", err)
+	}
+
+	// 删除快照中已经没有的文件和目录
+	err = filepath.Walk(target, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(target, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if syncthingReserved[relPath] {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if keep[relPath] {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		result.Removed++
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return result, i18n.Errorf("删除多余的文件失败: %v", err)
+	}
+	return result, nil
+}
+
+// 遍历快照目录中除根目录以外的所有文件和目录
+func walkSnapshot(dir string, visit func(relPath string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		return visit(relPath, info)
+	})
+}
+
+// 大小和修改时间（精确到秒）都相同的文件视为相同
+func sameFile(a, b os.FileInfo) bool {
+	return a.Size() == b.Size() && a.ModTime().Unix() == b.ModTime().Unix()
+}
+
+// 按配置把刚完成的快照导出给其他同步工具，失败不影响备份结果
+func (e *Engine) exportInterop(record *history.Record) error {
+	result, err := ExportLayout(record.DestPath, e.Config.InteropTarget, e.Config.InteropLayout)
+	if err != nil {
+		slog.Warn("导出到其他同步工具失败", "target", e.Config.InteropTarget, "err", err)
+		return err
+	}
+	slog.Info("已导出到其他同步工具", "layout", e.Config.InteropLayout, "path", result.Path,
+		"copied", result.Copied, "linked", result.Linked, "removed", result.Removed)
+	return nil
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"syncsafe/i18n"
+)
+
+// 变更日志游标，记录上次同步索引时文件系统变更日志的位置
+type JournalCursor struct {
+	Kind      string // "usn"（NTFS）或 "fsevents"（macOS），为空表示没有可用游标
+	Volume    string
+	JournalID uint64
+	Position  int64
+}
+
+// 平台或文件系统不支持变更日志，或游标已失效，调用方应回退到遍历文件树
+var errJournalUnavailable = i18n.Error("变更日志不可用")
+
+// 判断 path 是否位于 root 之内（Windows 下不区分大小写）
+func pathWithin(root, path string) bool {
+	root = filepath.Clean(root)
+	path = filepath.Clean(path)
+	if filepath.Separator == '\\' {
+		root = strings.ToLower(root)
+		path = strings.ToLower(path)
+	}
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// 记录当前的变更日志位置，之后可以从这里开始枚举变更
+func (idx *SourceIndex) MarkJournal() {
+	cursor, _ := journalCurrent(idx.Root)
+	idx.SetCursor(cursor)
+}
+
+// 设置变更日志游标，游标之前的变更必须已经反映在索引中
+func (idx *SourceIndex) SetCursor(cursor JournalCursor) {
+	idx.mu.Lock()
+	idx.Cursor = cursor
+	idx.dirty = true
+	idx.mu.Unlock()
+}
+
+// 通过变更日志把索引追赶到最新状态，成功时索引可以代替遍历文件树
+func (idx *SourceIndex) CatchUp() bool {
+	idx.mu.RLock()
+	cursor := idx.Cursor
+	built := !idx.BuiltAt.IsZero()
+	idx.mu.RUnlock()
+
+	if !built || cursor.Kind == "" {
+		return false
+	}
+
+	changes, next, err := journalChanges(idx.Root, cursor)
+	if err != nil {
+		if !errors.Is(err, errJournalUnavailable) {
+			slog.Warn("读取变更日志失败", "err", err)
+		}
+		return false
+	}
+
+	for _, path := range changes {
+		idx.Update(path)
+	}
+
+	idx.SetCursor(next)
+	return true
+}
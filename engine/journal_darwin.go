@@ -0,0 +1,149 @@
+//go:build darwin && cgo
+
+package engine
+
+/*
+#cgo LDFLAGS: -framework CoreServices -framework CoreFoundation
+#include <CoreServices/CoreServices.h>
+#include <dispatch/dispatch.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+extern void syncsafeFSEvent(uintptr_t handle, char *path, FSEventStreamEventFlags flags);
+
+static void syncsafeFSEventCallback(ConstFSEventStreamRef stream, void *info, size_t n, void *paths,
+	const FSEventStreamEventFlags flags[], const FSEventStreamEventId ids[]) {
+	char **p = (char **)paths;
+	for (size_t i = 0; i < n; i++) {
+		syncsafeFSEvent((uintptr_t)info, p[i], flags[i]);
+	}
+}
+
+static FSEventStreamRef syncsafeCreateStream(uintptr_t handle, const char *root, FSEventStreamEventId since) {
+	CFStringRef path = CFStringCreateWithCString(NULL, root, kCFStringEncodingUTF8);
+	CFArrayRef paths = CFArrayCreate(NULL, (const void **)&path, 1, &kCFTypeArrayCallBacks);
+	FSEventStreamContext ctx = {0, (void *)handle, NULL, NULL, NULL};
+	FSEventStreamRef stream = FSEventStreamCreate(NULL, syncsafeFSEventCallback, &ctx, paths, since, 0,
+		kFSEventStreamCreateFlagFileEvents | kFSEventStreamCreateFlagNoDefer);
+	CFRelease(paths);
+	CFRelease(path);
+	return stream;
+}
+
+static dispatch_queue_t syncsafeStartStream(FSEventStreamRef stream) {
+	dispatch_queue_t queue = dispatch_queue_create("syncsafe.fsevents", NULL);
+	FSEventStreamSetDispatchQueue(stream, queue);
+	if (!FSEventStreamStart(stream)) {
+		FSEventStreamInvalidate(stream);
+		FSEventStreamRelease(stream);
+		dispatch_release(queue);
+		return NULL;
+	}
+	return queue;
+}
+
+static void syncsafeStopStream(FSEventStreamRef stream, dispatch_queue_t queue) {
+	FSEventStreamStop(stream);
+	FSEventStreamInvalidate(stream);
+	FSEventStreamRelease(stream);
+	dispatch_release(queue);
+}
+*/
+import "C"
+
+import (
+	"path/filepath"
+	"runtime/cgo"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// 回放历史事件时收集变更路径
+type fseventsCollector struct {
+	mu     sync.Mutex
+	paths  []string
+	rescan bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+//export syncsafeFSEvent
+func syncsafeFSEvent(handle C.uintptr_t, path *C.char, flags C.FSEventStreamEventFlags) {
+	c := cgo.Handle(handle).Value().(*fseventsCollector)
+
+	if flags&C.kFSEventStreamEventFlagHistoryDone != 0 {
+		c.once.Do(func() { close(c.done) })
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 历史记录不完整时 FSEvents 要求重新扫描，此时只能回退到遍历
+	if flags&(C.kFSEventStreamEventFlagMustScanSubDirs|
+		C.kFSEventStreamEventFlagUserDropped|
+		C.kFSEventStreamEventFlagKernelDropped|
+		C.kFSEventStreamEventFlagRootChanged|
+		C.kFSEventStreamEventFlagEventIdsWrapped) != 0 {
+		c.rescan = true
+	}
+	c.paths = append(c.paths, C.GoString(path))
+}
+
+func journalCurrent(root string) (JournalCursor, error) {
+	return JournalCursor{
+		Kind:     "fsevents",
+		Volume:   filepath.Clean(root),
+		Position: int64(C.FSEventsGetCurrentEventId()),
+	}, nil
+}
+
+func journalChanges(root string, cursor JournalCursor) ([]string, JournalCursor, error) {
+	if cursor.Kind != "fsevents" || cursor.Volume != filepath.Clean(root) {
+		return nil, cursor, errJournalUnavailable
+	}
+
+	// 先记下当前事件 ID，回放期间新产生的事件留到下次处理
+	current := int64(C.FSEventsGetCurrentEventId())
+
+	collector := &fseventsCollector{done: make(chan struct{})}
+	handle := cgo.NewHandle(collector)
+	defer handle.Delete()
+
+	cRoot := C.CString(filepath.Clean(root))
+	defer C.free(unsafe.Pointer(cRoot))
+
+	stream := C.syncsafeCreateStream(C.uintptr_t(handle), cRoot, C.FSEventStreamEventId(cursor.Position))
+	if stream == nil {
+		return nil, cursor, errJournalUnavailable
+	}
+	queue := C.syncsafeStartStream(stream)
+	if queue == nil {
+		return nil, cursor, errJournalUnavailable
+	}
+
+	select {
+	case <-collector.done:
+	case <-time.After(30 * time.Second):
+		C.syncsafeStopStream(stream, queue)
+		return nil, cursor, errJournalUnavailable
+	}
+	C.syncsafeStopStream(stream, queue)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if collector.rescan {
+		return nil, cursor, errJournalUnavailable
+	}
+
+	var changes []string
+	for _, path := range collector.paths {
+		if pathWithin(root, path) {
+			changes = append(changes, path)
+		}
+	}
+
+	next := cursor
+	next.Position = current
+	return changes, next, nil
+}
@@ -0,0 +1,12 @@
+//go:build !windows && !(darwin && cgo)
+
+package engine
+
+// 当前平台不支持变更日志，始终回退到遍历文件树
+func journalCurrent(root string) (JournalCursor, error) {
+	return JournalCursor{}, errJournalUnavailable
+}
+
+func journalChanges(root string, cursor JournalCursor) ([]string, JournalCursor, error) {
+	return nil, cursor, errJournalUnavailable
+}
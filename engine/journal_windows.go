@@ -0,0 +1,253 @@
+//go:build windows
+
+package engine
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"syncsafe/i18n"
+)
+
+const (
+	fsctlQueryUsnJournal    = 0x000900f4
+	fsctlReadUsnJournal     = 0x000900bb
+	fileFlagBackupSemantics = 0x02000000
+)
+
+var (
+	modkernel32                   = syscall.NewLazyDLL("kernel32.dll")
+	procOpenFileById              = modkernel32.NewProc("OpenFileById")
+	procGetFinalPathNameByHandleW = modkernel32.NewProc("GetFinalPathNameByHandleW")
+)
+
+// USN_JOURNAL_DATA_V0
+type usnJournalData struct {
+	UsnJournalID    uint64
+	FirstUsn        int64
+	NextUsn         int64
+	LowestValidUsn  int64
+	MaxUsn          int64
+	MaximumSize     uint64
+	AllocationDelta uint64
+}
+
+// READ_USN_JOURNAL_DATA_V0
+type readUsnJournalData struct {
+	StartUsn          int64
+	ReasonMask        uint32
+	ReturnOnlyOnClose uint32
+	Timeout           uint64
+	BytesToWaitFor    uint64
+	UsnJournalID      uint64
+}
+
+// FILE_ID_DESCRIPTOR
+type fileIDDescriptor struct {
+	Size   uint32
+	Type   uint32
+	FileID [16]byte
+}
+
+// 打开源文件夹所在的卷，只支持带盘符的本地卷
+func openVolume(root string) (syscall.Handle, string, error) {
+	volume := filepath.VolumeName(filepath.Clean(root))
+	if len(volume) != 2 || volume[1] != ':' {
+		return syscall.InvalidHandle, "", errJournalUnavailable
+	}
+
+	path, err := syscall.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return syscall.InvalidHandle, "", err
+	}
+	handle, err := syscall.CreateFile(path,
+		syscall.GENERIC_READ,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE,
+		nil,
+		syscall.OPEN_EXISTING,
+		0,
+		0,
+	)
+	if err != nil {
+		// 打开卷需要管理员权限，普通用户直接回退到遍历
+		return syscall.InvalidHandle, "", errJournalUnavailable
+	}
+	return handle, strings.ToUpper(volume), nil
+}
+
+func queryUsnJournal(volume syscall.Handle) (usnJournalData, error) {
+	var data usnJournalData
+	var returned uint32
+	err := syscall.DeviceIoControl(volume, fsctlQueryUsnJournal,
+		nil, 0,
+		(*byte)(unsafe.Pointer(&data)), uint32(unsafe.Sizeof(data)),
+		&returned, nil,
+	)
+	if err != nil {
+		// 非 NTFS/ReFS 卷或未启用变更日志
+		return data, errJournalUnavailable
+	}
+	return data, nil
+}
+
+func journalCurrent(root string) (JournalCursor, error) {
+	volume, name, err := openVolume(root)
+	if err != nil {
+		return JournalCursor{}, err
+	}
+	defer syscall.CloseHandle(volume)
+
+	journal, err := queryUsnJournal(volume)
+	if err != nil {
+		return JournalCursor{}, err
+	}
+	return JournalCursor{
+		Kind:      "usn",
+		Volume:    name,
+		JournalID: journal.UsnJournalID,
+		Position:  journal.NextUsn,
+	}, nil
+}
+
+func journalChanges(root string, cursor JournalCursor) ([]string, JournalCursor, error) {
+	if cursor.Kind != "usn" {
+		return nil, cursor, errJournalUnavailable
+	}
+
+	volume, name, err := openVolume(root)
+	if err != nil {
+		return nil, cursor, err
+	}
+	defer syscall.CloseHandle(volume)
+
+	journal, err := queryUsnJournal(volume)
+	if err != nil {
+		return nil, cursor, err
+	}
+	// 日志被重建或已经覆盖了游标之后的记录，无法得知完整变更
+	if name != cursor.Volume || journal.UsnJournalID != cursor.JournalID || cursor.Position < journal.LowestValidUsn {
+		return nil, cursor, errJournalUnavailable
+	}
+
+	// 按 (父目录, 文件名) 去重收集变更记录
+	type entry struct {
+		parent uint64
+		name   string
+	}
+	seen := make(map[entry]bool)
+	var entries []entry
+
+	buf := make([]byte, 64*1024)
+	req := readUsnJournalData{
+		StartUsn:     cursor.Position,
+		ReasonMask:   0xFFFFFFFF,
+		UsnJournalID: journal.UsnJournalID,
+	}
+	for req.StartUsn < journal.NextUsn {
+		var returned uint32
+		err := syscall.DeviceIoControl(volume, fsctlReadUsnJournal,
+			(*byte)(unsafe.Pointer(&req)), uint32(unsafe.Sizeof(req)),
+			&buf[0], uint32(len(buf)),
+			&returned, nil,
+		)
+		if err != nil {
+			return nil, cursor, i18n.Errorf("读取 USN 日志失败: %v", err)
+		}
+		if returned <= 8 {
+			break
+		}
+
+		// 输出以下一个 USN 开头，后面是连续的 USN_RECORD_V2
+		next := int64(binary.LittleEndian.Uint64(buf[:8]))
+		for offset := uint32(8); offset+60 <= returned; {
+			recordLength := binary.LittleEndian.Uint32(buf[offset:])
+			if recordLength == 0 {
+				break
+			}
+			if binary.LittleEndian.Uint16(buf[offset+4:]) == 2 {
+				parent := binary.LittleEndian.Uint64(buf[offset+16:])
+				nameLength := uint32(binary.LittleEndian.Uint16(buf[offset+56:]))
+				nameOffset := uint32(binary.LittleEndian.Uint16(buf[offset+58:]))
+				raw := buf[offset+nameOffset : offset+nameOffset+nameLength]
+				chars := make([]uint16, len(raw)/2)
+				for i := range chars {
+					chars[i] = binary.LittleEndian.Uint16(raw[i*2:])
+				}
+				e := entry{parent: parent, name: syscall.UTF16ToString(chars)}
+				if !seen[e] {
+					seen[e] = true
+					entries = append(entries, e)
+				}
+			}
+			offset += recordLength
+		}
+
+		if next <= req.StartUsn {
+			break
+		}
+		req.StartUsn = next
+	}
+
+	// 通过父目录的文件 ID 还原完整路径，只保留源文件夹内的变更
+	dirs := make(map[uint64]string)
+	var changes []string
+	for _, e := range entries {
+		dir, ok := dirs[e.parent]
+		if !ok {
+			// 父目录已被删除时无法还原，其删除记录会通过上一级目录体现
+			dir, _ = pathByFileID(volume, e.parent)
+			dirs[e.parent] = dir
+		}
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, e.name)
+		if pathWithin(root, path) {
+			changes = append(changes, path)
+		}
+	}
+
+	next := cursor
+	next.Position = journal.NextUsn
+	return changes, next, nil
+}
+
+// 根据 NTFS 文件引用号获取路径
+func pathByFileID(volume syscall.Handle, id uint64) (string, error) {
+	desc := fileIDDescriptor{Size: uint32(unsafe.Sizeof(fileIDDescriptor{}))}
+	binary.LittleEndian.PutUint64(desc.FileID[:8], id)
+
+	r, _, e := procOpenFileById.Call(
+		uintptr(volume),
+		uintptr(unsafe.Pointer(&desc)),
+		0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		0,
+		fileFlagBackupSemantics,
+	)
+	handle := syscall.Handle(r)
+	if handle == syscall.InvalidHandle {
+		return "", e
+	}
+	defer syscall.CloseHandle(handle)
+
+	buf := make([]uint16, syscall.MAX_PATH)
+	for {
+		n, _, e := procGetFinalPathNameByHandleW.Call(
+			uintptr(handle),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+			0,
+		)
+		if n == 0 {
+			return "", e
+		}
+		if int(n) < len(buf) {
+			return strings.TrimPrefix(syscall.UTF16ToString(buf[:n]), `\\?\`), nil
+		}
+		buf = make([]uint16, n)
+	}
+}
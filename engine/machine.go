@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/notify"
+)
+
+// 本机设置：路径、监控状态、凭据和备份历史只属于当前机器，
+// 不写入共享的 config.json，避免通过网盘同步配置时互相覆盖
+type MachineConfig struct {
+	Machine         string
+	SourcePath      string
+	DestinationPath string
+	IsWatching      bool
+	AutoPaused      bool
+	LastBackupTime  time.Time
+	LastGitCommit   time.Time
+	AccessToken     string
+	NotifyToken     string
+	EmailPassword   string
+	Webhooks        []notify.Webhook
+	PeerCode        string
+	InteropTarget   string
+	SyncPath        string
+	WebDAVPassword  string
+	S3SecretKey     string
+	Passphrase      string
+	TaskFrequency   string
+	TaskTime        string
+	Review          []ReviewItem
+	Replicas        []Replica
+	History         []history.Record `json:",omitempty"` // 旧版本保存在这里的历史记录，加载时迁移到历史记录数据库
+}
+
+// 当前机器的标识，用作本机配置文件名
+func machineID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "default"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, host)
+}
+
+// 本机配置文件路径
+func machineConfigPath(configDir string) string {
+	return filepath.Join(configDir, "machines", machineID()+".json")
+}
+
+// 本机历史记录数据库路径，与本机配置一样按机器区分
+func historyStorePath(configDir string) string {
+	return filepath.Join(configDir, "machines", machineID()+".history.db")
+}
+
+// 把配置拆分为共享部分和本机部分
+func splitConfig(config *Config) (Config, MachineConfig) {
+	shared := *config
+	local := MachineConfig{
+		Machine:         machineID(),
+		SourcePath:      config.SourcePath,
+		DestinationPath: config.DestinationPath,
+		IsWatching:      config.IsWatching,
+		AutoPaused:      config.AutoPaused,
+		LastBackupTime:  config.LastBackupTime,
+		LastGitCommit:   config.LastGitCommit,
+		AccessToken:     config.Git.AccessToken,
+		NotifyToken:     config.Notify.Token,
+		EmailPassword:   config.Email.Password,
+		Webhooks:        config.Webhooks,
+		PeerCode:        config.Peer.Code,
+		InteropTarget:   config.InteropTarget,
+		SyncPath:        config.SyncPath,
+		WebDAVPassword:  config.WebDAV.Password,
+		S3SecretKey:     config.S3.SecretKey,
+		Passphrase:      config.Encryption.Passphrase,
+		TaskFrequency:   config.TaskFrequency,
+		TaskTime:        config.TaskTime,
+		Review:          config.Review,
+		Replicas:        config.Replicas,
+	}
+
+	shared.SourcePath = ""
+	shared.DestinationPath = ""
+	shared.IsWatching = false
+	shared.AutoPaused = false
+	shared.LastBackupTime = time.Time{}
+	shared.LastGitCommit = time.Time{}
+	shared.Git.AccessToken = ""
+	shared.Notify.Token = ""
+	shared.Email.Password = ""
+	shared.Webhooks = nil
+	shared.Peer.Code = ""
+	shared.InteropTarget = ""
+	shared.SyncPath = ""
+	shared.WebDAV.Password = ""
+	shared.S3.SecretKey = ""
+	shared.Encryption.Passphrase = ""
+	shared.TaskFrequency = ""
+	shared.TaskTime = ""
+	shared.Review = nil
+	shared.Replicas = nil
+	shared.History = nil
+
+	return shared, local
+}
+
+// 用本机设置覆盖共享配置中的对应字段
+func mergeConfig(config *Config, local MachineConfig) {
+	config.SourcePath = local.SourcePath
+	config.DestinationPath = local.DestinationPath
+	config.IsWatching = local.IsWatching
+	config.AutoPaused = local.AutoPaused
+	config.LastBackupTime = local.LastBackupTime
+	config.LastGitCommit = local.LastGitCommit
+	config.Git.AccessToken = local.AccessToken
+	config.Notify.Token = local.NotifyToken
+	config.Email.Password = local.EmailPassword
+	config.Webhooks = local.Webhooks
+	config.Peer.Code = local.PeerCode
+	config.InteropTarget = local.InteropTarget
+	config.SyncPath = local.SyncPath
+	config.WebDAV.Password = local.WebDAVPassword
+	config.S3.SecretKey = local.S3SecretKey
+	config.Encryption.Passphrase = local.Passphrase
+	config.TaskFrequency = local.TaskFrequency
+	config.TaskTime = local.TaskTime
+	config.Review = local.Review
+	config.Replicas = local.Replicas
+	config.History = local.History
+	if config.History == nil {
+		config.History = make([]history.Record, 0)
+	}
+}
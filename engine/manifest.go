@@ -0,0 +1,400 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 快照清单中的一个文件或目录
+type ManifestEntry struct {
+	RelPath string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	Mode    os.FileMode // 目录的权限，文件不记录
+}
+
+// 快照清单路径，清单保存在配置目录中，不写入快照本身
+func manifestPath(snapshotDir string) string {
+	sum := sha1.Sum([]byte(filepath.Clean(snapshotDir)))
+	return filepath.Join(DataDir, "manifests", hex.EncodeToString(sum[:8])+".manifest")
+}
+
+// 按文件树遍历顺序比较两个相对路径：逐级比较路径分量，父目录排在其内容之前。
+// 与 filepath.Walk 的访问顺序一致，清单可以和遍历过程流式对比
+func comparePaths(a, b string) int {
+	for {
+		if a == b {
+			return 0
+		}
+		if a == "" {
+			return -1
+		}
+		if b == "" {
+			return 1
+		}
+		aHead, aTail, _ := strings.Cut(a, string(filepath.Separator))
+		bHead, bTail, _ := strings.Cut(b, string(filepath.Separator))
+		if aHead != bHead {
+			if aHead < bHead {
+				return -1
+			}
+			return 1
+		}
+		a, b = aTail, bTail
+	}
+}
+
+// 顺序写入快照清单，先写临时文件，完成后再重命名。
+// 写入的同时计算快照的内容哈希：条目按 comparePaths 的固定顺序逐行计入，
+// 相同的文件集合（路径、大小和修改时间）总是得到相同的哈希
+type ManifestWriter struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	hash   hash.Hash
+	lines  io.Writer
+}
+
+func createManifest(path string) (*ManifestWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, i18n.Errorf("创建清单目录失败: %v", err)
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, i18n.Errorf("创建清单文件失败: %v", err)
+	}
+	m := &ManifestWriter{path: path, file: file, writer: bufio.NewWriter(file), hash: sha256.New()}
+	m.lines = io.MultiWriter(m.writer, m.hash)
+	return m, nil
+}
+
+// 每行一个条目：大小、修改时间（纳秒）和带引号的相对路径，以制表符分隔。
+// 目录的第一列为 d 加八进制权限
+func (m *ManifestWriter) Add(entry ManifestEntry) error {
+	first := strconv.FormatInt(entry.Size, 10)
+	if entry.IsDir {
+		first = "d" + strconv.FormatUint(uint64(entry.Mode.Perm()), 8)
+	}
+	_, err := fmt.Fprintf(m.lines, "%s\t%d\t%s\n", first, entry.ModTime.UnixNano(), strconv.Quote(entry.RelPath))
+	return err
+}
+
+func (m *ManifestWriter) Close() error {
+	if err := m.writer.Flush(); err != nil {
+		m.Abort()
+		return i18n.Errorf("写入清单失败: %v", err)
+	}
+	if err := m.file.Close(); err != nil {
+		os.Remove(m.file.Name())
+		return i18n.Errorf("写入清单失败: %v", err)
+	}
+	if err := os.Rename(m.file.Name(), m.path); err != nil {
+		os.Remove(m.file.Name())
+		return i18n.Errorf("保存清单失败: %v", err)
+	}
+	return nil
+}
+
+// 已写入条目的内容哈希（十六进制）
+func (m *ManifestWriter) Sum() string {
+	return hex.EncodeToString(m.hash.Sum(nil))
+}
+
+// 放弃写入并删除临时文件
+func (m *ManifestWriter) Abort() {
+	m.file.Close()
+	os.Remove(m.file.Name())
+}
+
+// 顺序读取快照清单
+type ManifestReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+}
+
+func openManifest(path string) (*ManifestReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &ManifestReader{file: file, scanner: scanner}, nil
+}
+
+// 读取下一个条目，读完时返回 false
+func (r *ManifestReader) Next() (ManifestEntry, bool, error) {
+	if !r.scanner.Scan() {
+		return ManifestEntry{}, false, r.scanner.Err()
+	}
+	fields := strings.SplitN(r.scanner.Text(), "\t", 3)
+	if len(fields) != 3 {
+		return ManifestEntry{}, false, i18n.Errorf("清单格式错误")
+	}
+	var entry ManifestEntry
+	if mode, ok := strings.CutPrefix(fields[0], "d"); ok {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return ManifestEntry{}, false, i18n.Errorf("清单格式错误: %v", err)
+		}
+		entry.IsDir = true
+		entry.Mode = os.FileMode(perm).Perm()
+	} else {
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return ManifestEntry{}, false, i18n.Errorf("清单格式错误: %v", err)
+		}
+		entry.Size = size
+	}
+	nanos, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return ManifestEntry{}, false, i18n.Errorf("清单格式错误: %v", err)
+	}
+	relPath, err := strconv.Unquote(fields[2])
+	if err != nil {
+		return ManifestEntry{}, false, i18n.Errorf("清单格式错误: %v", err)
+	}
+	entry.RelPath = relPath
+	entry.ModTime = time.Unix(0, nanos)
+	return entry, true, nil
+}
+
+// 关闭清单，r 为 nil 时什么都不做
+func (r *ManifestReader) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// 为没有清单的旧快照遍历生成清单
+func buildManifest(snapshotDir, path string) error {
+	writer, err := createManifest(path)
+	if err != nil {
+		return err
+	}
+	err = filepath.Walk(snapshotDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		relPath, err := filepath.Rel(snapshotDir, p)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if info.IsDir() {
+			return writer.Add(ManifestEntry{RelPath: relPath, ModTime: info.ModTime(), IsDir: true, Mode: info.Mode()})
+		}
+		return writer.Add(ManifestEntry{RelPath: relPath, Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if err != nil {
+		writer.Abort()
+		return i18n.Errorf("生成清单失败: %v", err)
+	}
+	return writer.Close()
+}
+
+// 打开快照的清单，旧版本创建的快照没有清单时遍历快照目录补建
+func openSnapshotManifest(record history.Record) *ManifestReader {
+	path := record.ManifestPath
+	if path == "" {
+		path = manifestPath(record.DestPath)
+	}
+	if reader, err := openManifest(path); err == nil {
+		return reader
+	}
+	// 归档快照没有目录树可以补建清单
+	if IsArchiveSnapshot(record.DestPath) {
+		return nil
+	}
+	if _, err := os.Stat(record.DestPath); err != nil {
+		return nil
+	}
+	if err := buildManifest(record.DestPath, path); err != nil {
+		return nil
+	}
+	reader, err := openManifest(path)
+	if err != nil {
+		return nil
+	}
+	return reader
+}
+
+// 文件相对上一个快照的变化
+type changeKind int
+
+const (
+	changeNew changeKind = iota
+	changeModified
+	changeUnchanged
+)
+
+// 流式对比：按遍历顺序依次传入当前文件，与上一个快照的清单同步前进，
+// 内存占用与文件数量无关
+type ManifestDiff struct {
+	reader      *ManifestReader
+	pending     *ManifestEntry
+	deleted     int
+	deletedDirs int
+	err         error
+	onDeleted   func(ManifestEntry) // 确定一个文件被删除时调用，可以为 nil
+}
+
+// 打开上一个快照的清单用于对比，reader 为 nil 时所有文件都视为新增
+func newManifestDiff(reader *ManifestReader) *ManifestDiff {
+	d := &ManifestDiff{reader: reader}
+	d.advance()
+	return d
+}
+
+func (d *ManifestDiff) advance() {
+	d.pending = nil
+	if d.reader == nil || d.err != nil {
+		return
+	}
+	entry, ok, err := d.reader.Next()
+	if err != nil {
+		d.err = err
+		return
+	}
+	if ok {
+		d.pending = &entry
+	}
+}
+
+// 对比一个当前文件，调用顺序必须与清单顺序一致
+func (d *ManifestDiff) Compare(entry ManifestEntry) changeKind {
+	// 清单中排在前面的条目在当前文件树中已不存在
+	for d.pending != nil && comparePaths(d.pending.RelPath, entry.RelPath) < 0 {
+		d.skip()
+	}
+	if d.pending == nil || d.pending.RelPath != entry.RelPath {
+		return changeNew
+	}
+
+	old := *d.pending
+	if old.IsDir != entry.IsDir {
+		// 文件被替换为同名目录或相反
+		d.skip()
+		return changeNew
+	}
+	d.advance()
+	if entry.IsDir {
+		// 目录的修改时间随内容变化，只比较权限
+		if old.Mode.Perm() != entry.Mode.Perm() {
+			return changeModified
+		}
+		return changeUnchanged
+	}
+	if !old.ModTime.Equal(entry.ModTime) || old.Size != entry.Size {
+		return changeModified
+	}
+	return changeUnchanged
+}
+
+// 当前条目在文件树中已不存在
+func (d *ManifestDiff) skip() {
+	if d.pending.IsDir {
+		d.deletedDirs++
+	} else {
+		d.deleted++
+		if d.onDeleted != nil {
+			d.onDeleted(*d.pending)
+		}
+	}
+	d.advance()
+}
+
+// 已经确定被删除的目录数
+func (d *ManifestDiff) DeletedDirs() int {
+	return d.deletedDirs
+}
+
+// 结束对比，返回被删除的文件数
+func (d *ManifestDiff) Finish() (int, error) {
+	for d.pending != nil {
+		d.skip()
+	}
+	if d.reader != nil {
+		d.reader.Close()
+	}
+	return d.deleted, d.err
+}
+
+// 一次备份中变化的文件，按对比的顺序（即路径顺序）记录，超出 history.MaxChanges 的只计数
+type changeLog struct {
+	changes []history.Change
+	omitted int
+}
+
+func (l *changeLog) add(change history.Change) {
+	if len(l.changes) >= history.MaxChanges {
+		l.omitted++
+		return
+	}
+	change.Path = filepath.ToSlash(change.Path)
+	l.changes = append(l.changes, change)
+}
+
+// 备份期间定期采样内存占用，记录峰值
+type memorySampler struct {
+	mu   sync.Mutex
+	peak uint64
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startMemorySampler() *memorySampler {
+	m := &memorySampler{stop: make(chan struct{}), done: make(chan struct{})}
+	m.sample()
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sample()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+	return m
+}
+
+func (m *memorySampler) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	inUse := stats.HeapInuse + stats.StackInuse
+	m.mu.Lock()
+	if inUse > m.peak {
+		m.peak = inUse
+	}
+	m.mu.Unlock()
+}
+
+// 停止采样并返回峰值（字节）
+func (m *memorySampler) Stop() uint64 {
+	close(m.stop)
+	<-m.done
+	m.sample()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 按推送设置在后台发送通知，低于设置的严重程度时忽略，发送失败只记录日志
+func (e *Engine) Notify(level notify.Level, title, message string) {
+	config := e.Config.Notify
+	if !config.Accepts(level) {
+		return
+	}
+	go func() {
+		if err := notify.Send(config, level, title, message); err != nil {
+			slog.Warn("推送通知失败", "err", err)
+		}
+	}()
+}
+
+// 备份失败邮件的标题和正文：任务、时间、路径、错误信息和已复制的部分
+func FailureEmail(config *Config, record history.Record) (string, string) {
+	subject := i18n.Sprintf("[SyncSafe] 备份失败: %s", config.ProfileName())
+	var sb strings.Builder
+	fmt.Fprintf(&sb, i18n.T("任务 %s 的备份失败。\n\n"), config.ProfileName())
+	fmt.Fprintf(&sb, i18n.T("计算机: %s\n"), machineID())
+	fmt.Fprintf(&sb, i18n.T("时间: %s\n"), record.FormatTime(config.Location()))
+	fmt.Fprintf(&sb, i18n.T("源文件夹: %s\n"), record.SourcePath)
+	fmt.Fprintf(&sb, i18n.T("目标文件夹: %s\n"), config.DestinationPath)
+	if record.Attempt > 0 {
+		fmt.Fprintf(&sb, i18n.T("自动备份尝试次数: %d\n"), record.Attempt)
+	}
+	if record.Duration >= time.Second {
+		fmt.Fprintf(&sb, i18n.T("耗时: %v\n"), record.Duration.Round(time.Second))
+	}
+	if record.FileCount > 0 || record.FailedFiles > 0 {
+		fmt.Fprintf(&sb, i18n.T("文件: 共 %d 个，新增 %d、修改 %d、删除 %d，复制失败 %d\n"),
+			record.FileCount, record.NewFiles, record.ModifiedFiles, record.DeletedFiles, record.FailedFiles)
+	}
+	fmt.Fprintf(&sb, i18n.T("\n错误信息:\n%s\n"), record.ErrorMessage)
+	return subject, sb.String()
+}
+
+// 按邮件设置发送备份失败的邮件，没有启用时不发送
+func SendFailureEmail(config *Config, record history.Record) error {
+	if !config.Email.Enabled {
+		return nil
+	}
+	subject, body := FailureEmail(config, record)
+	return notify.SendEmail(config.Email, subject, body)
+}
+
+// 在后台发送备份失败的邮件，发送失败只记录日志
+func (e *Engine) EmailFailure(record history.Record) {
+	if !e.Config.Email.Enabled {
+		return
+	}
+	config := *e.Config
+	go func() {
+		if err := SendFailureEmail(&config, record); err != nil {
+			slog.Warn("发送失败通知邮件失败", "err", err)
+		}
+	}()
+}
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"syncsafe/i18n"
+	"syncsafe/peer"
+)
+
+// 局域网接收端的证书和设置所在的目录
+func PeerDir() string {
+	return filepath.Join(DataDir, "peer")
+}
+
+// 程序日志和日志设置所在的目录
+func LogDir() string {
+	return filepath.Join(DataDir, "logs")
+}
+
+// 读取本机的接收端设置
+func LoadReceiverSettings() (peer.ReceiverSettings, error) {
+	return peer.LoadSettings(PeerDir())
+}
+
+// 按设置开始接收其他设备推送的快照
+func StartReceiver(settings peer.ReceiverSettings, onReceived func(peer.Received), onError func(remote string, err error)) (*peer.Receiver, error) {
+	cert, err := peer.LoadCertificate(PeerDir())
+	if err != nil {
+		return nil, err
+	}
+	return peer.Listen(fmt.Sprintf(":%d", settings.Port), peer.ReceiverOptions{
+		Dir:        settings.Dir,
+		Code:       settings.Code,
+		Cert:       cert,
+		OnReceived: onReceived,
+		OnError:    onError,
+	})
+}
+
+// 把快照推送到局域网中的接收端，首次成功时记住接收端的证书指纹
+func (e *Engine) pushToPeer(backupDir string) error {
+	e.setStage(StagePeer, i18n.T("推送快照到 ")+e.Config.Peer.Address)
+	fp, err := peer.Push(e.Config.Peer, machineID(), backupDir, func(files int, bytes int64) {
+		e.status(i18n.Sprintf("正在推送到局域网设备: 已发送 %d 个文件，%.2f MB", files, float64(bytes)/(1024*1024)))
+	})
+	if err != nil {
+		slog.Warn("推送到局域网设备失败", "err", err)
+		return err
+	}
+	if e.Config.Peer.Fingerprint == "" {
+		e.Config.Peer.Fingerprint = fp
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 去重存储池：开启后快照中的文件都硬链接到目标文件夹下按内容命名的对象，
+// 同一目标文件夹下的所有任务共用一个池，内容和修改时间都相同的文件只保存一份
+const poolDirName = "syncsafe-pool"
+
+func poolDir(destination string) string {
+	return filepath.Join(filepath.Clean(destination), poolDirName)
+}
+
+// 池中对象的路径：按 SHA-256 和修改时间命名，哈希的前两位作为子目录。
+// 快照中的硬链接与对象共用修改时间，修改时间不同的相同内容分别保存
+func poolObject(pool, hash string, modTime time.Time) string {
+	return filepath.Join(pool, hash[:2], fmt.Sprintf("%s-%d", hash, modTime.UnixNano()))
+}
+
+// 本次备份使用的存储池，不能使用时返回空字符串。只支持本地目标，
+// 加密、归档和快速同步的快照不放入池中
+func (e *Engine) backupPool(dest storage.Backend, quickSync bool) string {
+	if !e.Config.SharedPool || quickSync || e.Config.DryRun {
+		return ""
+	}
+	if _, ok := dest.(*storage.Local); !ok {
+		return ""
+	}
+	return poolDir(e.Config.DestinationPath)
+}
+
+// 通过存储池复制文件：池中已有相同的对象时直接硬链接，pooled 加一；
+// 否则复制文件，再把快照中的副本加入池中供之后的快照和其他任务使用
+func (e *Engine) copyPooled(ctx context.Context, dest storage.Backend, pool, src, dst string, pooled *atomic.Int64) error {
+	if info, err := os.Stat(src); err == nil {
+		if hash, err := fileHash(src); err == nil {
+			object := poolObject(pool, hash, info.ModTime())
+			if objectInfo, err := os.Stat(object); err == nil && objectInfo.Size() == info.Size() {
+				if err := dest.Link(object, dst, info.Size(), objectInfo.ModTime()); err == nil {
+					pooled.Add(1)
+					return nil
+				}
+			}
+		}
+	}
+	if err := e.copyFile(ctx, dest, src, dst); err != nil {
+		return err
+	}
+	// 按写入快照的内容加入池中，复制期间源文件被修改也不会让对象与名称不符。
+	// 加入失败不影响快照
+	info, err := os.Stat(dst)
+	if err != nil {
+		return nil
+	}
+	hash, err := fileHash(dst)
+	if err != nil {
+		return nil
+	}
+	object := poolObject(pool, hash, info.ModTime())
+	if os.MkdirAll(filepath.Dir(object), 0755) == nil {
+		os.Link(dst, object)
+	}
+	return nil
+}
+
+// 删除池中已没有快照引用的对象（只剩池中一个链接），返回删除的数量和释放的空间
+func CleanPool(destination string) (int, int64, error) {
+	pool := poolDir(destination)
+	var count int
+	var freed int64
+	err := filepath.Walk(pool, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == pool && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		links, err := linkCount(path, info)
+		if err != nil || links > 1 {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		count++
+		freed += info.Size()
+		return nil
+	})
+	if err != nil {
+		return count, freed, i18n.Errorf("清理去重存储池失败: %v", err)
+	}
+	return count, freed, nil
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package engine
+
+import (
+	"os"
+	"syscall"
+
+	"syncsafe/i18n"
+)
+
+// 文件的硬链接数
+func linkCount(path string, info os.FileInfo) (uint64, error) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, i18n.Errorf("无法读取硬链接数: %s", path)
+	}
+	return uint64(stat.Nlink), nil
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// 文件的硬链接数
+func linkCount(path string, info os.FileInfo) (uint64, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	handle, err := syscall.CreateFile(name, 0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.CloseHandle(handle)
+	var data syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(handle, &data); err != nil {
+		return 0, err
+	}
+	return uint64(data.NumberOfLinks), nil
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// 界面主题
+const (
+	ThemeSystem = ""      // 跟随系统
+	ThemeLight  = "light" // 浅色
+	ThemeDark   = "dark"  // 深色
+)
+
+// 程序设置，对所有任务生效，保存在默认任务的 config.json 中
+type Preferences struct {
+	Language       string          // 界面语言代码，为空表示简体中文
+	Theme          string          // 界面主题，见 ThemeSystem 等
+	Debounce       int             // 监控到变化后等待多少秒没有新的变化再备份，0 表示默认值
+	CopyWorkers    int             // 任务没有设置并发复制数时使用的协程数，0 表示使用 CPU 核数
+	StartMinimized bool            // 开机自动启动时只显示托盘图标，不显示主窗口
+	StartOnLogin   bool            // 登录系统时自动启动
+	Retention      RetentionPolicy // 新建任务的默认保留策略
+	LogLevel       string          // 日志记录级别：debug、info、warn 或 error，为空表示 info
+}
+
+// 监控的等待时间，0 表示使用监控的默认值
+func (p Preferences) WatchDebounce() time.Duration {
+	return time.Duration(p.Debounce) * time.Second
+}
+
+// 任务没有设置并发复制数时使用的协程数
+var defaultCopyWorkers atomic.Int64
+
+// 应用对备份引擎生效的设置
+func (p Preferences) Apply() {
+	defaultCopyWorkers.Store(int64(max(p.CopyWorkers, 0)))
+}
+
+// 在创建界面前只读取程序设置，配置文件不存在或无法读取时返回默认设置
+func LoadPreferences() (Preferences, error) {
+	var config struct{ Preferences Preferences }
+	if err := readConfigFile(configPath(DataDir), &config); err != nil && !os.IsNotExist(err) {
+		return Preferences{}, err
+	}
+	return config.Preferences, nil
+}
+
+// 程序设置，保存在默认任务中
+func (p *Profiles) Preferences() *Preferences {
+	return &p.List[0].Preferences
+}
+
+// 保存程序设置并应用对备份引擎生效的部分
+func (p *Profiles) SavePreferences() error {
+	p.Preferences().Apply()
+	return p.List[0].Save()
+}
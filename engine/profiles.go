@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 默认任务的显示名称，即升级前唯一的一份配置
+const DefaultProfileName = "默认任务"
+
+// 所有备份任务。默认任务的配置保存在 DataDir，其他任务各自保存在 DataDir/profiles/<ID>，
+// 每个任务有独立的源文件夹、目标文件夹、Git 设置、监控状态和历史记录
+type Profiles struct {
+	List   []*Config
+	Active string // 界面中当前显示的任务名称
+}
+
+// 记录当前任务的文件
+type profilesState struct {
+	Active string
+}
+
+// 其他任务的配置目录
+func profilesDir() string {
+	return filepath.Join(DataDir, "profiles")
+}
+
+func profilesStatePath() string {
+	return filepath.Join(profilesDir(), "active.json")
+}
+
+// 任务名称，默认任务没有设置名称时为 DefaultProfileName
+func (c *Config) ProfileName() string {
+	if c.Name == "" {
+		return DefaultProfileName
+	}
+	return c.Name
+}
+
+// 是否为默认任务，默认任务不能删除
+func (c *Config) IsDefaultProfile() bool {
+	return c.dir == ""
+}
+
+// 加载所有任务。默认任务的配置损坏时使用默认配置并返回 *CorruptError，
+// 其他任务的配置无法读取时跳过并记录日志，不会被覆盖
+func LoadProfiles() (*Profiles, error) {
+	// 升级后第一次读取配置前保存回滚包
+	if rollback, err := PrepareUpgrade(); err != nil {
+		slog.Warn("保存升级前的回滚包失败", "err", err)
+	} else if rollback != nil {
+		slog.Info("已保存升级前的回滚包", "path", rollback.Path)
+	}
+
+	profiles := &Profiles{}
+	defaultConfig, loadErr := LoadConfig()
+	if loadErr != nil {
+		defaultConfig = NewConfig()
+	}
+	profiles.List = append(profiles.List, defaultConfig)
+
+	// 目录名按创建时间递增，按名称排序即为创建顺序
+	entries, err := os.ReadDir(profilesDir())
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("读取任务目录失败", "err", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(profilesDir(), entry.Name())
+		if _, err := os.Stat(configPath(dir)); err != nil {
+			continue
+		}
+		config, err := loadConfigFrom(dir)
+		if err != nil {
+			slog.Warn("读取任务配置失败", "dir", dir, "err", err)
+			continue
+		}
+		profiles.List = append(profiles.List, config)
+	}
+
+	var state profilesState
+	if data, err := os.ReadFile(profilesStatePath()); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	profiles.Active = state.Active
+	if config := profiles.Get(profiles.Active); config == nil || config.Archived {
+		profiles.Active = defaultConfig.ProfileName()
+	}
+	profiles.Preferences().Apply()
+	return profiles, loadErr
+}
+
+// 按名称查找任务，不存在时返回 nil
+func (p *Profiles) Get(name string) *Config {
+	for _, config := range p.List {
+		if config.ProfileName() == name {
+			return config
+		}
+	}
+	return nil
+}
+
+// 当前任务
+func (p *Profiles) Current() *Config {
+	if config := p.Get(p.Active); config != nil && !config.Archived {
+		return config
+	}
+	return p.List[0]
+}
+
+// 未归档的任务名称列表，按创建顺序
+func (p *Profiles) Names() []string {
+	var names []string
+	for _, config := range p.List {
+		if !config.Archived {
+			names = append(names, config.ProfileName())
+		}
+	}
+	return names
+}
+
+// 已归档的任务，按创建顺序
+func (p *Profiles) ArchivedList() []*Config {
+	var archived []*Config
+	for _, config := range p.List {
+		if config.Archived {
+			archived = append(archived, config)
+		}
+	}
+	return archived
+}
+
+// 检查任务名称是否可用
+func (p *Profiles) validName(name string) error {
+	if name == "" {
+		return i18n.Errorf("任务名称不能为空")
+	}
+	if p.Get(name) != nil {
+		return i18n.Errorf("任务 %s 已存在", name)
+	}
+	return nil
+}
+
+// 新建一个空任务并保存
+func (p *Profiles) Add(name string) (*Config, error) {
+	name = strings.TrimSpace(name)
+	if err := p.validName(name); err != nil {
+		return nil, err
+	}
+	config := NewConfig()
+	config.Name = name
+	config.Retention = p.Preferences().Retention
+	config.dir = filepath.Join(profilesDir(), strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := config.Save(); err != nil {
+		return nil, err
+	}
+	p.List = append(p.List, config)
+	return config, nil
+}
+
+// 重命名任务
+func (p *Profiles) Rename(config *Config, name string) error {
+	name = strings.TrimSpace(name)
+	if name == config.ProfileName() {
+		return nil
+	}
+	if err := p.validName(name); err != nil {
+		return err
+	}
+	wasActive := p.Active == config.ProfileName()
+	config.Name = name
+	if err := config.Save(); err != nil {
+		return err
+	}
+	if wasActive {
+		p.Active = name
+		return p.SaveActive()
+	}
+	return nil
+}
+
+// 归档任务：停止监控，不再出现在任务列表中，配置、历史记录和快照都保留，可以随时恢复。
+// 归档的是当前任务时切换到默认任务
+func (p *Profiles) Archive(config *Config) error {
+	if config.IsDefaultProfile() {
+		return i18n.Errorf("默认任务不能归档")
+	}
+	watching := config.IsWatching
+	config.Archived = true
+	config.IsWatching = false
+	if err := config.Save(); err != nil {
+		// 保存失败时任务仍然在运行，恢复内存中的状态
+		config.Archived = false
+		config.IsWatching = watching
+		return err
+	}
+	// 任务计划程序中的计划任务先取消注册，恢复时按原来的设置重新注册
+	if TaskSchedulerSupported && config.TaskFrequency != "" {
+		if err := UnregisterTask(config); err != nil {
+			slog.Warn("取消注册计划任务失败", "profile", config.ProfileName(), "err", err)
+		}
+	}
+	if p.Active == config.ProfileName() {
+		p.Active = p.List[0].ProfileName()
+		return p.SaveActive()
+	}
+	return nil
+}
+
+// 恢复已归档的任务，监控需要重新开启
+func (p *Profiles) Unarchive(config *Config) error {
+	config.Archived = false
+	if err := config.Save(); err != nil {
+		config.Archived = true
+		return err
+	}
+	if TaskSchedulerSupported && config.TaskFrequency != "" {
+		if err := RegisterTask(config, config.TaskFrequency, config.TaskTime); err != nil {
+			slog.Warn("重新注册计划任务失败", "profile", config.ProfileName(), "err", err)
+		}
+	}
+	return nil
+}
+
+// 删除任务及其配置和历史记录，快照本身不会被删除
+func (p *Profiles) Remove(config *Config) error {
+	if config.IsDefaultProfile() {
+		return i18n.Errorf("默认任务不能删除")
+	}
+	if err := os.RemoveAll(config.dir); err != nil {
+		return i18n.Errorf("删除任务配置失败: %v", err)
+	}
+	for i, c := range p.List {
+		if c == config {
+			p.List = append(p.List[:i], p.List[i+1:]...)
+			break
+		}
+	}
+	if config := p.Get(p.Active); config == nil || config.Archived {
+		p.Active = p.List[0].ProfileName()
+		return p.SaveActive()
+	}
+	return nil
+}
+
+// 保存当前任务的选择
+func (p *Profiles) SaveActive() error {
+	if err := os.MkdirAll(profilesDir(), 0755); err != nil {
+		return i18n.Errorf("创建任务目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(profilesState{Active: p.Active}, "", "  ")
+	if err != nil {
+		return i18n.Errorf("序列化任务设置失败: %v", err)
+	}
+	return storage.WriteFileAtomic(profilesStatePath(), data, 0644)
+}
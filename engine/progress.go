@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 预扫描源文件夹，统计需要备份的文件数和总大小，排除规则与备份相同。
+// 无法访问的目录直接跳过，只影响进度的准确性
+func prescan(ctx context.Context, source string, filter *fileFilter) (files int, bytes int64) {
+	filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		relPath, err := filepath.Rel(source, path)
+		if err != nil || relPath == "." {
+			return nil
+		}
+		if filter.Exclude(relPath, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files++
+			bytes += info.Size()
+		}
+		return nil
+	})
+	return files, bytes
+}
+
+// 记录预扫描的结果，之后的事件都带上总数
+func (e *Engine) setTotals(files int, bytes int64) {
+	e.totalFiles, e.totalBytes = files, bytes
+	e.emit(Event{Kind: EventScanned, Message: i18n.Sprintf("共 %d 个文件，%.2f MB", files, float64(bytes)/(1024*1024))})
+}
+
+// 根据进度事件计算百分比、当前文件、速度和剩余时间，图形界面和命令行共用
+type Progress struct {
+	TotalFiles int
+	TotalBytes int64
+	Files      int
+	Bytes      int64
+	Current    string // 最近处理的文件
+	Stage      Stage
+	started    time.Time // 开始复制的时间
+	updated    time.Time
+}
+
+// 用一个进度事件更新进度，EventStage 进入复制阶段时重新开始计时
+func (p *Progress) Update(ev Event) {
+	p.Stage = ev.Stage
+	if ev.TotalFiles > 0 || ev.TotalBytes > 0 {
+		p.TotalFiles, p.TotalBytes = ev.TotalFiles, ev.TotalBytes
+	}
+	switch ev.Kind {
+	case EventStage:
+		if ev.Stage == StageStarting {
+			*p = Progress{Stage: ev.Stage}
+		}
+	case EventScanned:
+		p.started = ev.Time
+	case EventFileCopied, EventFileSkipped:
+		if p.started.IsZero() {
+			p.started = ev.Time
+		}
+		p.Files, p.Bytes = ev.Files, ev.Bytes
+		p.Current = ev.Path
+		p.updated = ev.Time
+	}
+}
+
+// 完成的比例（0 到 1），按字节计算，全是空文件时按文件数计算，总数未知时为 0
+func (p *Progress) Fraction() float64 {
+	var fraction float64
+	switch {
+	case p.TotalBytes > 0:
+		fraction = float64(p.Bytes) / float64(p.TotalBytes)
+	case p.TotalFiles > 0:
+		fraction = float64(p.Files) / float64(p.TotalFiles)
+	}
+	// 预扫描之后新增的文件可能使已处理的数量超过总数
+	return min(fraction, 1)
+}
+
+// 平均速度（字节/秒）
+func (p *Progress) Speed() float64 {
+	elapsed := p.updated.Sub(p.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / elapsed
+}
+
+// 按平均速度估计的剩余时间，无法估计时返回 false
+func (p *Progress) ETA() (time.Duration, bool) {
+	speed := p.Speed()
+	if speed <= 0 || p.TotalBytes <= 0 {
+		return 0, false
+	}
+	remaining := p.TotalBytes - p.Bytes
+	if remaining <= 0 {
+		return 0, true
+	}
+	return time.Duration(float64(remaining) / speed * float64(time.Second)), true
+}
+
+// 一行进度说明，例如 "45.2%  1200/2650 个文件  12.5 MB/s  剩余 1m20s"
+func (p *Progress) String() string {
+	text := i18n.Sprintf("%d 个文件", p.Files)
+	if p.TotalFiles > 0 {
+		text = i18n.Sprintf("%.1f%%  %d/%d 个文件", p.Fraction()*100, p.Files, p.TotalFiles)
+	}
+	if speed := p.Speed(); speed > 0 {
+		text += fmt.Sprintf("  %.1f MB/s", speed/(1024*1024))
+	}
+	if eta, ok := p.ETA(); ok {
+		text += i18n.T("  剩余 ") + eta.Round(time.Second).String()
+	}
+	return text
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"sort"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 默认的容量提醒阈值（使用率百分比）
+var DefaultCapacityThresholds = []int{80, 90, 95}
+
+// 清理建议：按时间从旧到新列出建议删除的快照
+type PrunePlan struct {
+	Snapshots []history.Record
+	Freed     int64
+}
+
+// 从小到大排列的容量提醒阈值
+func (c *Config) Thresholds() []int {
+	thresholds := c.CapacityThresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultCapacityThresholds
+	}
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+// 生成清理建议：从最旧的快照开始删除，直到使用率降到 target 以下，始终保留最近一次快照
+func PlanPrune(records []history.Record, total, free uint64, target int) PrunePlan {
+	var plan PrunePlan
+	need := int64(total-free) - int64(total)*int64(target)/100
+	if need <= 0 {
+		return plan
+	}
+
+	latest, _ := history.LastSnapshot(records)
+	for _, record := range records {
+		if plan.Freed >= need {
+			break
+		}
+		if !record.HasSnapshot() || record.DestPath == latest.DestPath {
+			continue
+		}
+		if _, err := os.Stat(record.DestPath); err != nil {
+			continue
+		}
+		plan.Snapshots = append(plan.Snapshots, record)
+		plan.Freed += record.TotalSize
+	}
+	return plan
+}
+
+// 删除快照目录和清单，历史记录保留并标记为已清理。设置了 Config.KeepVersions 时
+// 先把每个文件最近的几个版本保留到版本库，失败时不删除任何快照。
+// 删除失败时停止，已删除的快照仍会被标记，返回已删除的数量
+func (e *Engine) PruneSnapshots(snapshots []history.Record) (int, error) {
+	if e.Config.KeepVersions > 0 {
+		if err := e.keepVersions(snapshots); err != nil {
+			return 0, err
+		}
+	}
+	dest := e.Destination()
+	pruned := make(map[string]bool)
+	var err error
+	for _, record := range snapshots {
+		if e.Simulated("删除快照 %s", record.DestPath) {
+			continue
+		}
+		if removeErr := dest.RemoveAll(record.DestPath); removeErr != nil {
+			err = i18n.Errorf("删除快照失败: %v\n目录: %s", removeErr, record.DestPath)
+			break
+		}
+		if record.ManifestPath != "" {
+			os.Remove(record.ManifestPath)
+		}
+		os.Remove(streamsPath(record.DestPath))
+		pruned[record.DestPath] = true
+	}
+
+	for i := range e.Config.History {
+		if pruned[e.Config.History[i].DestPath] {
+			e.Config.History[i].Pruned = true
+		}
+	}
+
+	// 删除去重存储池中已没有快照引用的文件，池中的文件可能属于其他任务，只删除没有任何引用的
+	if len(pruned) > 0 && !storage.IsRemote(e.Config.DestinationPath) {
+		if count, freed, poolErr := CleanPool(e.Config.DestinationPath); poolErr != nil {
+			slog.Warn("清理去重存储池失败", "err", poolErr)
+		} else if count > 0 {
+			slog.Info("已从去重存储池删除不再使用的文件", "files", count, "freed", freed)
+		}
+	}
+	return len(pruned), err
+}
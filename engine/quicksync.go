@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 快速同步的镜像目录，每次同步都写入同一个目录
+func mirrorDir(destination, source string) string {
+	name := strings.ReplaceAll(filepath.Base(source), " ", "_") + "-mirror"
+	return filepath.Join(filepath.Clean(destination), name)
+}
+
+// 目标上的文件是否与源文件相同：大小相同且修改时间在同一秒内（远程后端通常只保存到秒）
+func remoteUnchanged(remote storage.RemoteFile, info os.FileInfo) bool {
+	return remote.Size == info.Size() &&
+		remote.ModTime.Truncate(time.Second).Equal(info.ModTime().Truncate(time.Second))
+}
+
+// 镜像的回收文件夹，每次同步移入的文件放在以时间命名的子目录中
+func mirrorTrashDir(destination, source string) string {
+	return mirrorDir(destination, source) + "-trash"
+}
+
+// 列出镜像中已有的文件，后端不支持列出时返回 nil，所有文件都重新上传
+func listMirror(dest storage.Backend, dir string) (map[string]storage.RemoteFile, error) {
+	lister, ok := dest.(storage.Lister)
+	if !ok {
+		return nil, nil
+	}
+	files, err := lister.List(dir)
+	if err != nil {
+		return nil, i18n.Errorf("列出目标中已有的文件失败: %v\n目录: %s", err, dir)
+	}
+	return files, nil
+}
+
+// 不再跟踪 relPath 目录下的文件，这些文件由提权辅助进程单独复制
+func forgetRemoteDir(remote map[string]storage.RemoteFile, relPath string) {
+	prefix := relPath + string(filepath.Separator)
+	for path := range remote {
+		if strings.HasPrefix(path, prefix) {
+			delete(remote, path)
+		}
+	}
+}
+
+// 删除镜像中源文件夹已经没有的文件，返回删除的数量。
+// trash 不为空时把文件按原来的相对路径移到该目录，而不是直接删除
+func (e *Engine) removeStale(dest storage.Backend, dir string, remote map[string]storage.RemoteFile, trash string) (int, error) {
+	removed := 0
+	for relPath := range remote {
+		if trash != "" {
+			if e.Simulated("把目标中多余的文件 %s 移到回收文件夹", relPath) {
+				continue
+			}
+			path := filepath.Join(dir, relPath)
+			mover, ok := dest.(storage.Mover)
+			if !ok {
+				return removed, i18n.Errorf("目标不支持移到回收文件夹")
+			}
+			if err := mover.Move(path, filepath.Join(trash, relPath)); err != nil {
+				return removed, i18n.Errorf("把目标中多余的文件移到回收文件夹失败: %v\n文件: %s", err, path)
+			}
+			removed++
+			continue
+		}
+		if e.Simulated("删除目标中多余的文件 %s", relPath) {
+			continue
+		}
+		path := filepath.Join(dir, relPath)
+		if err := dest.RemoveAll(path); err != nil {
+			return removed, i18n.Errorf("删除目标中多余的文件失败: %v\n文件: %s", err, path)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// 镜像被覆盖后，之前写入同一目录的快照已不存在，标记为已清理
+func (e *Engine) supersedeMirror(dir string) {
+	for i := range e.Config.History {
+		if e.Config.History[i].DestPath == dir {
+			e.Config.History[i].Pruned = true
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 可以直接浏览和读取其中快照的远程目标
+type remoteStore interface {
+	storage.Lister
+	storage.Opener
+}
+
+// 快照所在的远程目标，本地快照返回 nil。快照必须位于当前设置的目标中
+func (e *Engine) remoteStore(snapshotDir string) remoteStore {
+	switch {
+	case storage.IsWebDAV(snapshotDir):
+		return storage.NewWebDAV(e.Config.DestinationPath, e.Config.WebDAV)
+	case storage.IsS3(snapshotDir):
+		return storage.NewS3(e.Config.DestinationPath, e.Config.S3)
+	}
+	return nil
+}
+
+// 列出远程快照中的所有文件，只读取文件的元数据
+func listRemote(remote remoteStore, snapshotDir string) (map[string]storage.RemoteFile, error) {
+	files, err := remote.List(snapshotDir)
+	if err != nil {
+		return nil, i18n.Errorf("读取远程快照失败: %v", err)
+	}
+	return files, nil
+}
+
+// 读取远程快照中所有文件和目录的信息，不下载文件内容
+func (e *Engine) LoadRemoteTree(snapshotDir string) (*ArchiveTree, error) {
+	remote := e.remoteStore(snapshotDir)
+	if remote == nil {
+		return nil, i18n.Errorf("不是远程快照: %s", snapshotDir)
+	}
+	files, err := listRemote(remote, snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	t := newArchiveTree()
+	for relPath, file := range files {
+		t.add(relPath, remoteFileInfo{name: filepath.Base(relPath), file: file})
+	}
+	t.sortChildren()
+	return t, nil
+}
+
+// 依次访问远程快照中选中的文件，按文件树顺序。
+// 文件内容在第一次读取时才下载，只检查冲突时不产生下载
+func walkRemote(remote remoteStore, snapshotDir string, roots []string, visit func(relPath string, info os.FileInfo, content io.Reader) error) error {
+	files, err := listRemote(remote, snapshotDir)
+	if err != nil {
+		return err
+	}
+	var relPaths []string
+	for relPath := range files {
+		if inRestoreRoots(relPath, roots) {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Slice(relPaths, func(i, j int) bool { return comparePaths(relPaths[i], relPaths[j]) < 0 })
+	for _, relPath := range relPaths {
+		content := &remoteReader{remote: remote, path: filepath.Join(snapshotDir, relPath)}
+		err := visit(relPath, remoteFileInfo{name: filepath.Base(relPath), file: files[relPath]}, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 第一次读取时才请求远程文件的内容
+type remoteReader struct {
+	remote storage.Opener
+	path   string
+	r      io.ReadCloser
+}
+
+func (r *remoteReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		body, err := r.remote.Open(r.path)
+		if err != nil {
+			return 0, i18n.Errorf("读取远程文件失败: %v", err)
+		}
+		r.r = body
+	}
+	return r.r.Read(p)
+}
+
+func (r *remoteReader) Close() error {
+	if r.r == nil {
+		return nil
+	}
+	return r.r.Close()
+}
+
+// 远程快照中的文件
+type remoteFileInfo struct {
+	name string
+	file storage.RemoteFile
+}
+
+func (f remoteFileInfo) Name() string       { return f.name }
+func (f remoteFileInfo) Size() int64        { return f.file.Size }
+func (f remoteFileInfo) Mode() os.FileMode  { return 0644 }
+func (f remoteFileInfo) ModTime() time.Time { return f.file.ModTime }
+func (f remoteFileInfo) IsDir() bool        { return false }
+func (f remoteFileInfo) Sys() any           { return nil }
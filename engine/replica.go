@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"syncsafe/faults"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 附加目标：每次备份成功后把快照再复制一份到其他目标，每个目标单独选择快照格式和是否加密，
+// 例如外接硬盘上保存普通目录便于直接打开，云端保存加密的归档。附加目标中的快照都是完整副本，
+// 不加入历史记录，也不按保留策略清理
+type Replica struct {
+	Path          string               // 本地文件夹，或 http(s) 开头的 WebDAV 地址
+	WebDAV        storage.WebDAVConfig // Path 为 WebDAV 地址时的登录信息
+	ArchiveFormat string               // 写入单个 tar.gz 或 zip 文件，为空表示复制为目录
+	Encrypt       bool                 // 用任务的密码短语加密，与主目标是否加密无关
+	Disabled      bool                 // 暂时不复制到该目标，保留设置
+}
+
+// 附加目标的存储后端
+func (r Replica) backend() storage.Backend {
+	if storage.IsWebDAV(r.Path) {
+		return faults.WrapBackend(storage.NewWebDAV(r.Path, r.WebDAV))
+	}
+	return faults.WrapBackend(storage.NewLocal(r.Path))
+}
+
+// 把快照复制到所有启用的附加目标，返回每个失败的目标的说明。一个目标失败不影响其他目标
+func (e *Engine) replicate(ctx context.Context, record history.Record) []string {
+	var failures []string
+	for _, replica := range e.Config.Replicas {
+		if replica.Disabled {
+			continue
+		}
+		e.setStage(StageReplica, i18n.T("复制快照到 ")+replica.Path)
+		path, err := e.replicateTo(ctx, record, replica)
+		if err != nil {
+			slog.Warn("复制到附加目标失败", "target", replica.Path, "err", err)
+			failures = append(failures, i18n.Sprintf("复制到附加目标 %s 失败: %v", replica.Path, err))
+			continue
+		}
+		e.status(i18n.Sprintf("快照已复制到 %s", path))
+	}
+	return failures
+}
+
+// 按附加目标的格式和加密设置写入快照的完整副本，返回副本的路径。
+// 从刚写入的快照中读出（并解密）每个文件，而不是重新读取源文件夹，副本与快照的内容一致
+func (e *Engine) replicateTo(ctx context.Context, record history.Record, replica Replica) (path string, err error) {
+	if replica.Encrypt && e.Config.Encryption.Passphrase == "" {
+		return "", i18n.Errorf("加密附加目标需要先在加密设置中填写密码短语")
+	}
+	if storage.IsS3(replica.Path) {
+		return "", i18n.Errorf("附加目标不支持 S3，S3 只能作为目标文件夹")
+	}
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return "", err
+	}
+	base := replica.backend()
+	dest := base
+	name := filepath.Base(record.DestPath)
+	if format := storage.ArchiveFormat(name); format != "" {
+		name = strings.TrimSuffix(name, "."+format)
+	}
+	path = filepath.Join(filepath.Clean(replica.Path), name)
+
+	var archive *storage.Archive
+	switch {
+	case replica.ArchiveFormat != "":
+		if storage.IsWebDAV(replica.Path) {
+			return "", i18n.Errorf("归档模式只支持本地目标文件夹")
+		}
+		format, passphrase := replica.ArchiveFormat, ""
+		if replica.Encrypt {
+			passphrase = e.Config.Encryption.Passphrase
+			if format == storage.ArchiveTarGz {
+				format = storage.ArchiveTarGzAge
+			}
+		}
+		path += "." + format
+		if archive, err = storage.NewArchive(base, path, format, passphrase); err != nil {
+			return "", err
+		}
+		dest = archive
+	case replica.Encrypt:
+		replicaKey, err := e.encryptionKey(replica.Path, base)
+		if err != nil {
+			return "", err
+		}
+		dest = storage.NewEncrypted(base, filepath.Clean(replica.Path), replicaKey, e.Config.Encryption.ObfuscateNames)
+	}
+	defer func() {
+		if err != nil {
+			if removeErr := dest.RemoveAll(path); removeErr != nil {
+				slog.Warn("删除未完成的副本失败", "err", removeErr)
+			}
+		}
+	}()
+	if err := dest.MkdirAll(path, 0755); err != nil {
+		return path, i18n.Errorf("创建备份目录失败: %v\n目录: %s", err, path)
+	}
+
+	// 加密、归档和远程快照中的文件先解密到临时文件，再交给目标后端写入
+	tmpDir, err := os.MkdirTemp("", "syncsafe-replica-")
+	if err != nil {
+		return path, err
+	}
+	defer os.RemoveAll(tmpDir)
+	files := 0
+	err = e.walkRestore(record.DestPath, []string{"."}, func(relPath string, info os.FileInfo, content io.Reader) error {
+		if ctx.Err() != nil {
+			return ErrCancelled
+		}
+		plain, err := plainRelPath(key, record, relPath)
+		if err != nil {
+			return i18n.Errorf("无法解密文件名: %v\n文件: %s", err, relPath)
+		}
+		src := filepath.Join(record.DestPath, relPath)
+		if content != nil || key != nil {
+			src = filepath.Join(tmpDir, "file")
+			if err := restoreFile(key, record.DestPath, relPath, content, src, info); err != nil {
+				return i18n.Errorf("读取快照中的文件失败: %v\n文件: %s", err, plain)
+			}
+		}
+		if err := dest.CopyFile(ctx, src, filepath.Join(path, plain)); err != nil {
+			return i18n.Errorf("复制文件失败: %v\n源文件: %s", err, plain)
+		}
+		files++
+		e.status(i18n.Sprintf("正在复制到附加目标: 已复制 %d 个文件", files))
+		return nil
+	})
+	if err != nil {
+		return path, err
+	}
+
+	// 空目录以及目录的权限和修改时间来自快照清单
+	if reader := openSnapshotManifest(record); reader != nil {
+		var dirs []ManifestEntry
+		for {
+			entry, ok, readErr := reader.Next()
+			if readErr != nil || !ok {
+				break
+			}
+			if entry.IsDir {
+				dirs = append(dirs, entry)
+			}
+		}
+		reader.Close()
+		for i := len(dirs) - 1; i >= 0; i-- {
+			dir := filepath.Join(path, dirs[i].RelPath)
+			if err := dest.MkdirAll(dir, 0755); err != nil {
+				return path, i18n.Errorf("创建目录失败: %v\n目录: %s", err, dir)
+			}
+			if attrErr := dest.SetAttributes(dir, dirs[i].Mode, dirs[i].ModTime); attrErr != nil {
+				slog.Warn("设置目录属性失败", "dir", dir, "err", attrErr)
+			}
+		}
+	}
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"syncsafe/crypt"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 还原结果
+type RestoreResult struct {
+	Files   int   // 复制的文件数
+	Bytes   int64 // 复制的字节数
+	Skipped int   // 因目标已存在而跳过的文件数
+	Streams int   // 写回了附加数据流的文件数
+	Lost    int   // 附加数据流无法写回（目标文件系统不支持）的文件数
+}
+
+// 去掉重复的路径和已被选中目录包含的路径，按文件树顺序排列
+func restoreRoots(relPaths []string) []string {
+	sorted := append([]string(nil), relPaths...)
+	sort.Slice(sorted, func(i, j int) bool { return comparePaths(sorted[i], sorted[j]) < 0 })
+	var roots []string
+	for _, relPath := range sorted {
+		relPath = filepath.Clean(relPath)
+		if n := len(roots); n > 0 {
+			last := roots[n-1]
+			if relPath == last || last == "." || strings.HasPrefix(relPath, last+string(filepath.Separator)) {
+				continue
+			}
+		}
+		roots = append(roots, relPath)
+	}
+	return roots
+}
+
+// relPath 是否在选中的某个文件或目录中
+func inRestoreRoots(relPath string, roots []string) bool {
+	for _, root := range roots {
+		if root == "." || relPath == root || strings.HasPrefix(relPath, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 依次访问快照中选中的文件，目录展开为其中的所有文件。
+// 归档快照（加密的归档用密码短语解密）和远程快照中的文件内容由 content 提供，
+// 本地目录快照中 content 为 nil，直接读取快照中的文件
+func (e *Engine) walkRestore(snapshotDir string, relPaths []string, visit func(relPath string, info os.FileInfo, content io.Reader) error) error {
+	roots := restoreRoots(relPaths)
+	if remote := e.remoteStore(snapshotDir); remote != nil {
+		return walkRemote(remote, snapshotDir, roots, visit)
+	}
+	if IsArchiveSnapshot(snapshotDir) {
+		return walkArchive(snapshotDir, e.Config.Encryption.Passphrase, func(relPath string, info os.FileInfo, r io.Reader) error {
+			if info.IsDir() || !inRestoreRoots(relPath, roots) {
+				return nil
+			}
+			return visit(relPath, info, r)
+		})
+	}
+	for _, root := range roots {
+		err := filepath.Walk(filepath.Join(snapshotDir, root), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return i18n.Errorf("读取快照失败: %v", err)
+			}
+			if info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(snapshotDir, path)
+			if err != nil {
+				return err
+			}
+			return visit(relPath, info, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 快照中选中的文件或目录（相对快照根目录）还原到 target 时会覆盖的已有文件，返回还原后的相对路径
+func (e *Engine) RestoreConflicts(snapshotDir string, relPaths []string, target string) ([]string, error) {
+	record := e.snapshotRecord(snapshotDir)
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	err = e.walkRestore(snapshotDir, relPaths, func(relPath string, info os.FileInfo, content io.Reader) error {
+		plain, err := plainRelPath(key, record, relPath)
+		if err != nil {
+			return i18n.Errorf("无法解密文件名: %v\n文件: %s", err, relPath)
+		}
+		if _, err := os.Lstat(filepath.Join(target, plain)); err == nil {
+			conflicts = append(conflicts, plain)
+		}
+		return nil
+	})
+	return conflicts, err
+}
+
+// 把快照中选中的文件或目录复制到 target 下相同的相对路径，保留修改时间。
+// 加密的快照在还原时解密，归档快照从归档中解压，远程快照逐个文件下载。overwrite 为 false 时跳过 target 中已存在的文件
+func (e *Engine) Restore(snapshotDir string, relPaths []string, target string, overwrite bool) (RestoreResult, error) {
+	var result RestoreResult
+	record := e.snapshotRecord(snapshotDir)
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return result, err
+	}
+	// 备份时保存的附加数据流在文件还原后写回
+	streams, err := loadStreams(snapshotDir, restoreRoots(relPaths))
+	if err != nil {
+		return result, err
+	}
+	err = e.walkRestore(snapshotDir, relPaths, func(relPath string, info os.FileInfo, content io.Reader) error {
+		plain, err := plainRelPath(key, record, relPath)
+		if err != nil {
+			return i18n.Errorf("无法解密文件名: %v\n文件: %s", err, relPath)
+		}
+		dst := filepath.Join(target, plain)
+		if _, err := os.Lstat(dst); err == nil && !overwrite {
+			result.Skipped++
+			return nil
+		}
+		if e.Simulated("还原 %s 到 %s", plain, dst) {
+			return nil
+		}
+		size := info.Size()
+		if key != nil {
+			size, _ = crypt.PlainSize(size)
+		}
+		if content == nil && key == nil {
+			err = storage.CopyFile(filepath.Join(snapshotDir, relPath), dst)
+		} else {
+			err = restoreFile(key, snapshotDir, relPath, content, dst, info)
+		}
+		if err != nil {
+			return i18n.Errorf("还原文件失败: %v\n文件: %s", err, plain)
+		}
+		if fileStreams := streams[plain]; len(fileStreams) > 0 {
+			if streamErr := applyStreams(dst, fileStreams); streamErr != nil {
+				slog.Warn("写回附加数据流失败", "path", dst, "err", streamErr)
+				result.Lost++
+			} else {
+				result.Streams++
+			}
+		}
+		result.Files++
+		result.Bytes += size
+		e.status(i18n.Sprintf("已还原 %d 个文件", result.Files))
+		return nil
+	})
+	return result, err
+}
+
+// 把快照中的文件写入 dst：content 不为 nil 时从中读取，key 不为 nil 时解密，保留权限和修改时间
+func restoreFile(key *crypt.Key, snapshotDir, relPath string, content io.Reader, dst string, info os.FileInfo) (err error) {
+	if content == nil {
+		source, err := os.Open(filepath.Join(snapshotDir, relPath))
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		content = source
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".syncsafe-restore-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if key != nil {
+		err = key.Decrypt(tmp, content)
+	} else {
+		_, err = io.Copy(tmp, content)
+	}
+	if err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 较新的快照没有在要求的时间内通过校验时推迟清理，避免删除仅有的完好副本
+var ErrRetentionUnverified = i18n.Error("没有最近通过校验的较新快照，推迟按保留策略清理")
+
+// 保留策略：每次备份后只保留满足任一条件的快照，其余的自动清理。全部为 0 表示不清理
+type RetentionPolicy struct {
+	KeepLast    int // 最近的几个快照
+	KeepDaily   int // 最近几天中每天最后一个快照
+	KeepWeekly  int // 最近几周中每周最后一个快照
+	KeepMonthly int // 最近几个月中每月最后一个快照
+
+	VerifyBeforePrune  bool // 只清理早于某个最近通过校验的快照的旧快照
+	VerifiedWithinDays int  // 校验通过的时间不早于最近几天，0 表示不限
+}
+
+// 是否设置了保留策略
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0
+}
+
+// 按保留策略生成清理计划：按时间从旧到新列出不再保留的快照。
+// 每天、每周、每月按 loc 时区划分，与定时备份使用同一个时区。
+// 没有设置策略时计划为空，最近一次快照始终保留
+func PlanRetention(records []history.Record, policy RetentionPolicy, loc *time.Location) PrunePlan {
+	var plan PrunePlan
+	if !policy.Enabled() {
+		return plan
+	}
+
+	// 从新到旧检查，每个时间段只保留最新的快照
+	keep := make(map[int]bool)
+	periods := []struct {
+		count int
+		key   func(history.Record) string
+		seen  map[string]bool
+	}{
+		{policy.KeepDaily, func(r history.Record) string { return r.Timestamp.In(loc).Format("2006-01-02") }, nil},
+		{policy.KeepWeekly, func(r history.Record) string {
+			year, week := r.Timestamp.In(loc).ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}, nil},
+		{policy.KeepMonthly, func(r history.Record) string { return r.Timestamp.In(loc).Format("2006-01") }, nil},
+	}
+	for i := range periods {
+		periods[i].seen = make(map[string]bool)
+	}
+	snapshots := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if !record.HasSnapshot() {
+			continue
+		}
+		snapshots++
+		if snapshots == 1 || snapshots <= policy.KeepLast {
+			keep[i] = true
+		}
+		for k := range periods {
+			period := &periods[k]
+			key := period.key(record)
+			if len(period.seen) < period.count && !period.seen[key] {
+				period.seen[key] = true
+				keep[i] = true
+			}
+		}
+	}
+
+	for i, record := range records {
+		if record.HasSnapshot() && !keep[i] {
+			plan.Snapshots = append(plan.Snapshots, record)
+			plan.Freed += record.TotalSize
+		}
+	}
+	return plan
+}
+
+// 按策略的校验要求筛选清理计划：只有更晚的、保留下来的快照在 now 之前的 VerifiedWithinDays 天内
+// 通过了校验，才清理计划中的快照，其余的推迟到下次清理。返回可以清理的快照和推迟的数量
+func GateByVerification(records []history.Record, plan PrunePlan, policy RetentionPolicy, now time.Time) ([]history.Record, int) {
+	if !policy.VerifyBeforePrune || len(plan.Snapshots) == 0 {
+		return plan.Snapshots, 0
+	}
+	planned := func(record history.Record) bool {
+		for _, r := range plan.Snapshots {
+			if r.Same(record) {
+				return true
+			}
+		}
+		return false
+	}
+	// 最新的、保留下来并且最近通过校验的快照
+	var anchor time.Time
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		verifiedAt := record.VerifiedTime()
+		if !record.HasSnapshot() || verifiedAt.IsZero() || planned(record) {
+			continue
+		}
+		if policy.VerifiedWithinDays > 0 && now.Sub(verifiedAt) > time.Duration(policy.VerifiedWithinDays)*24*time.Hour {
+			continue
+		}
+		anchor = record.Timestamp
+		break
+	}
+	var allowed []history.Record
+	for _, record := range plan.Snapshots {
+		if record.Timestamp.Before(anchor) {
+			allowed = append(allowed, record)
+		}
+	}
+	return allowed, len(plan.Snapshots) - len(allowed)
+}
+
+// 按配置的保留策略清理快照，返回清理的数量。最近一次备份的大小异常还没有确认时返回 ErrRetentionHeld；
+// 要求清理前校验而有快照因此推迟清理时，清理其余的快照并返回包装了 ErrRetentionUnverified 的错误
+func (e *Engine) ApplyRetention() (int, error) {
+	if _, held := HeldBySizeAnomaly(e.Config.History); held && !e.Config.IgnoreSizeSwings {
+		return 0, ErrRetentionHeld
+	}
+	plan := PlanRetention(e.Config.History, e.Config.Retention, e.Config.Location())
+	snapshots, deferred := GateByVerification(e.Config.History, plan, e.Config.Retention, time.Now())
+	count := 0
+	if len(snapshots) > 0 {
+		var err error
+		if count, err = e.PruneSnapshots(snapshots); err != nil {
+			return count, err
+		}
+	}
+	if deferred > 0 {
+		return count, i18n.Errorf("%w: %d 个快照等待较新的快照通过校验", ErrRetentionUnverified, deferred)
+	}
+	return count, nil
+}
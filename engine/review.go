@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 复查队列：备份中没有中断备份的问题按路径记录在本机配置中，可以逐个重试、
+// 永久忽略（添加排除规则）或上报，而不是只出现在日志中。
+// 之后的备份再次检查同一路径而没有发现问题时自动移出队列
+const (
+	IssueCopyFailed = "copy"      // 文件复制失败，例如被其他程序锁定
+	IssueMismatch   = "mismatch"  // 备份后校验发现与源文件不一致、无法读取或缺失
+	IssueCollision  = "collision" // 与另一个路径只有大小写不同，在不区分大小写的目标中互相覆盖
+)
+
+// 队列中最多保存的问题数，超过后不再加入新的问题
+const maxReviewItems = 1000
+
+// 复查队列中的一个问题
+type ReviewItem struct {
+	Kind      string // 问题类型，见 IssueCopyFailed 等
+	Path      string // 相对源文件夹的路径
+	Message   string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int  // 出现的次数
+	Escalated bool // 已通过手机推送或邮件上报
+}
+
+func (r ReviewItem) String() string {
+	return r.Path + ": " + r.Message
+}
+
+// 是否是同一个问题
+func (r ReviewItem) same(other ReviewItem) bool {
+	return r.Kind == other.Kind && r.Path == other.Path
+}
+
+// 合并一次备份发现的问题：checked 中的类型在本次备份中检查过，
+// 队列中这些类型没有再次出现的问题已经解决，移出队列；再次出现的问题更新信息和次数
+func (c *Config) updateReview(found []ReviewItem, checked []string, now time.Time) {
+	var queue []ReviewItem
+	for _, item := range c.Review {
+		i := slices.IndexFunc(found, item.same)
+		if i < 0 {
+			if !slices.Contains(checked, item.Kind) {
+				queue = append(queue, item)
+			}
+			continue
+		}
+		item.Message, item.LastSeen = found[i].Message, now
+		item.Count++
+		queue = append(queue, item)
+	}
+	for _, item := range found {
+		if len(queue) >= maxReviewItems {
+			break
+		}
+		if !slices.ContainsFunc(queue, item.same) {
+			item.FirstSeen, item.LastSeen, item.Count = now, now, 1
+			queue = append(queue, item)
+		}
+	}
+	c.Review = queue
+}
+
+// 把问题移出队列
+func (c *Config) ResolveReview(items ...ReviewItem) {
+	c.Review = slices.DeleteFunc(c.Review, func(item ReviewItem) bool {
+		return slices.ContainsFunc(items, item.same)
+	})
+}
+
+// 永久忽略：按路径添加只匹配该文件的排除规则，该路径的所有问题移出队列
+func (c *Config) IgnoreReview(item ReviewItem) {
+	rule := "/" + escapeRule(filepath.ToSlash(item.Path))
+	if !slices.Contains(c.FilterRules, rule) {
+		c.FilterRules = append(c.FilterRules, rule)
+	}
+	c.Review = slices.DeleteFunc(c.Review, func(other ReviewItem) bool {
+		return other.Path == item.Path
+	})
+}
+
+// 转义路径中排除规则的通配符，规则只匹配该路径
+func escapeRule(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`\*?[`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// 重试：移出队列，下一次备份重新读取并复制这些路径，不沿用上一个快照中的副本
+func (e *Engine) RetryReview(items ...ReviewItem) {
+	e.Config.ResolveReview(items...)
+	e.retryMu.Lock()
+	defer e.retryMu.Unlock()
+	if e.retryPaths == nil {
+		e.retryPaths = make(map[string]bool)
+	}
+	for _, item := range items {
+		e.retryPaths[item.Path] = true
+	}
+}
+
+// 取出等待重试的路径
+func (e *Engine) takeRetryPaths() map[string]bool {
+	e.retryMu.Lock()
+	defer e.retryMu.Unlock()
+	paths := e.retryPaths
+	e.retryPaths = nil
+	return paths
+}
+
+// 上报：通过手机推送和邮件发送问题，没有设置任何一种时返回错误。
+// 成功后由调用方用 MarkEscalated 标记
+func (e *Engine) EscalateReview(item ReviewItem) error {
+	if !e.Config.Notify.Enabled && !e.Config.Email.Enabled {
+		return i18n.Errorf("没有启用手机推送或邮件通知，无法上报")
+	}
+	title := i18n.Sprintf("[SyncSafe] 备份问题: %s", e.Config.ProfileName())
+	var sb strings.Builder
+	fmt.Fprintf(&sb, i18n.T("任务 %s 的备份中有一个问题需要处理。\n\n"), e.Config.ProfileName())
+	fmt.Fprintf(&sb, i18n.T("计算机: %s\n"), machineID())
+	fmt.Fprintf(&sb, i18n.T("源文件夹: %s\n"), e.Config.SourcePath)
+	fmt.Fprintf(&sb, i18n.T("文件: %s\n"), item.Path)
+	fmt.Fprintf(&sb, i18n.T("问题: %s\n"), item.Message)
+	fmt.Fprintf(&sb, i18n.T("首次出现: %s，共 %d 次\n"), item.FirstSeen.In(e.Config.Location()).Format("2006-01-02 15:04"), item.Count)
+	if e.Config.Notify.Enabled {
+		if err := notify.Send(e.Config.Notify, notify.LevelError, title, sb.String()); err != nil {
+			return i18n.Errorf("推送通知失败: %v", err)
+		}
+	}
+	if e.Config.Email.Enabled {
+		if err := notify.SendEmail(e.Config.Email, title, sb.String()); err != nil {
+			return i18n.Errorf("发送邮件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 标记问题已上报
+func (c *Config) MarkEscalated(item ReviewItem) {
+	for i := range c.Review {
+		if c.Review[i].same(item) {
+			c.Review[i].Escalated = true
+		}
+	}
+}
+
+// 按路径检查只有大小写不同的文件和目录
+type collisionCheck map[string]string
+
+// 记录一个路径，与之前的路径只有大小写不同时返回之前的路径
+func (c collisionCheck) add(relPath string) (string, bool) {
+	key := strings.ToLower(relPath)
+	if other, ok := c[key]; ok && other != relPath {
+		return other, true
+	}
+	c[key] = relPath
+	return "", false
+}
@@ -0,0 +1,348 @@
+package engine
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+	"syncsafe/version"
+)
+
+// 配置格式的版本，格式变化需要迁移时递增
+const ConfigVersion = 1
+
+// 保留的回滚包数量，超出时删除最旧的
+const rollbackKeep = 10
+
+// 回滚包中记录来源版本和原因的条目
+const rollbackInfoName = "rollback.json"
+
+// 回滚包包含的文件和目录：默认任务的配置和历史记录、本机设置、其他任务、加密参数缓存和局域网同步证书
+var rollbackEntries = []string{"config.json", "machines", "profiles", "keys", "peer"}
+
+// 数据目录对应的程序版本和配置格式版本
+type versionState struct {
+	ConfigVersion int
+	AppVersion    string
+}
+
+// 一个回滚包：升级或迁移配置前保存的配置、历史记录和密钥
+type Rollback struct {
+	Path          string `json:"-"`
+	Created       time.Time
+	ConfigVersion int
+	AppVersion    string
+	Reason        string
+}
+
+// 回滚包的说明，用于界面和命令行显示
+func (r Rollback) String() string {
+	return i18n.Sprintf("%s  版本 %s（配置格式 %d）  %s", r.Created.Format("2006-01-02 15:04:05"), r.AppVersion, r.ConfigVersion, r.Reason)
+}
+
+func rollbackDir() string {
+	return filepath.Join(DataDir, "rollback")
+}
+
+func versionPath() string {
+	return filepath.Join(DataDir, "version.json")
+}
+
+// 读取数据目录的版本，没有版本文件时为 0（第一个带版本的程序之前的格式）
+func loadVersionState() versionState {
+	var state versionState
+	if data, err := os.ReadFile(versionPath()); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func saveVersionState(state versionState) error {
+	if err := os.MkdirAll(DataDir, 0755); err != nil {
+		return i18n.Errorf("创建数据目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return i18n.Errorf("序列化版本信息失败: %v", err)
+	}
+	return storage.WriteFileAtomic(versionPath(), data, 0644)
+}
+
+// 数据目录中是否已有配置
+func hasConfigData() bool {
+	for _, name := range rollbackEntries {
+		if _, err := os.Stat(filepath.Join(DataDir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// 程序版本或配置格式与数据目录记录的不同时（升级、降级或需要迁移配置），
+// 在读取和迁移配置之前保存一个回滚包，然后记录当前版本。返回新建的回滚包，不需要时为 nil。
+// 回滚包保存失败时不记录版本，下次启动再试
+func PrepareUpgrade() (*Rollback, error) {
+	old := loadVersionState()
+	current := versionState{ConfigVersion: ConfigVersion, AppVersion: version.Get().Version}
+	if old == current {
+		return nil, nil
+	}
+	var rollback *Rollback
+	if hasConfigData() {
+		reason := i18n.Sprintf("升级到版本 %s（配置格式 %d）前", current.AppVersion, ConfigVersion)
+		if old.ConfigVersion > ConfigVersion {
+			reason = i18n.Sprintf("降级到版本 %s（配置格式 %d）前", current.AppVersion, ConfigVersion)
+		}
+		r, err := createRollback(old, reason)
+		if err != nil {
+			return nil, err
+		}
+		rollback = &r
+	}
+	if err := saveVersionState(current); err != nil {
+		return rollback, err
+	}
+	return rollback, nil
+}
+
+// 为当前的配置保存一个回滚包
+func CreateRollback(reason string) (Rollback, error) {
+	state := loadVersionState()
+	if state == (versionState{}) && !hasConfigData() {
+		return Rollback{}, i18n.Errorf("还没有可以保存的配置")
+	}
+	return createRollback(state, reason)
+}
+
+// 把 rollbackEntries 打包为 DataDir/rollback/<时间>.zip，并删除超出数量的旧回滚包
+func createRollback(state versionState, reason string) (Rollback, error) {
+	r := Rollback{
+		Created:       time.Now(),
+		ConfigVersion: state.ConfigVersion,
+		AppVersion:    state.AppVersion,
+		Reason:        reason,
+	}
+	if r.AppVersion == "" {
+		r.AppVersion = i18n.T("未知")
+	}
+	if err := os.MkdirAll(rollbackDir(), 0700); err != nil {
+		return r, i18n.Errorf("创建回滚目录失败: %v", err)
+	}
+	r.Path = filepath.Join(rollbackDir(), r.Created.Format("20060102-150405.000")+".zip")
+	tmpPath := r.Path + ".tmp"
+	if err := writeRollback(tmpPath, r); err != nil {
+		os.Remove(tmpPath)
+		return r, i18n.Errorf("保存回滚包失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, r.Path); err != nil {
+		os.Remove(tmpPath)
+		return r, i18n.Errorf("保存回滚包失败: %v", err)
+	}
+	pruneRollbacks()
+	return r, nil
+}
+
+func writeRollback(path string, r Rollback) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(file)
+	info, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w, err := zw.Create(rollbackInfoName)
+	if err == nil {
+		_, err = w.Write(info)
+	}
+	for _, name := range rollbackEntries {
+		if err != nil {
+			break
+		}
+		err = addRollbackEntry(zw, name)
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 把 DataDir 中的文件或目录写入回滚包，不存在时跳过。目录中的临时文件不打包
+func addRollbackEntry(zw *zip.Writer, name string) error {
+	root := filepath.Join(DataDir, name)
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() || strings.Contains(info.Name(), ".tmp") {
+			return nil
+		}
+		relPath, err := filepath.Rel(DataDir, path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		header.Method = zip.Deflate
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(w, file)
+		return err
+	})
+}
+
+// 只保留最新的 rollbackKeep 个回滚包
+func pruneRollbacks() {
+	rollbacks, err := ListRollbacks()
+	if err != nil {
+		return
+	}
+	for i := rollbackKeep; i < len(rollbacks); i++ {
+		if err := os.Remove(rollbacks[i].Path); err != nil {
+			slog.Warn("删除旧回滚包失败", "err", err)
+		}
+	}
+}
+
+// 所有回滚包，最新的在前。无法读取的回滚包跳过
+func ListRollbacks() ([]Rollback, error) {
+	paths, err := filepath.Glob(filepath.Join(rollbackDir(), "*.zip"))
+	if err != nil {
+		return nil, err
+	}
+	var rollbacks []Rollback
+	for _, path := range paths {
+		r, err := readRollbackInfo(path)
+		if err != nil {
+			slog.Warn("读取回滚包失败", "path", path, "err", err)
+			continue
+		}
+		rollbacks = append(rollbacks, r)
+	}
+	sort.Slice(rollbacks, func(i, k int) bool {
+		return rollbacks[i].Created.After(rollbacks[k].Created)
+	})
+	return rollbacks, nil
+}
+
+func readRollbackInfo(path string) (Rollback, error) {
+	r := Rollback{Path: path}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return r, err
+	}
+	defer zr.Close()
+	entry, err := zr.Open(rollbackInfoName)
+	if err != nil {
+		return r, err
+	}
+	defer entry.Close()
+	if err := json.NewDecoder(entry).Decode(&r); err != nil {
+		return r, err
+	}
+	r.Path = path
+	return r, nil
+}
+
+// 用回滚包替换当前的配置、历史记录和密钥，并恢复回滚包记录的版本。
+// 替换前先为当前的配置保存一个回滚包，还原本身也可以撤销。调用方需要重新加载配置
+func RevertRollback(r Rollback) error {
+	// 先解压到临时目录，全部成功后再替换，解压失败时当前配置保持不变。
+	// 解压在保存新的回滚包之前，要还原的回滚包不会被清理掉
+	tmpDir, err := os.MkdirTemp(rollbackDir(), "restore-")
+	if err != nil {
+		return i18n.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := extractRollback(r.Path, tmpDir); err != nil {
+		return i18n.Errorf("解压回滚包失败: %v", err)
+	}
+	if hasConfigData() {
+		if _, err := CreateRollback(i18n.Sprintf("还原到 %s 的回滚包前", r.Created.Format("2006-01-02 15:04:05"))); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range rollbackEntries {
+		current := filepath.Join(DataDir, name)
+		if err := os.RemoveAll(current); err != nil {
+			return i18n.Errorf("删除当前配置失败: %v", err)
+		}
+		restored := filepath.Join(tmpDir, name)
+		if _, err := os.Stat(restored); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(restored, current); err != nil {
+			return i18n.Errorf("还原 %s 失败: %v", name, err)
+		}
+	}
+	return saveVersionState(versionState{ConfigVersion: r.ConfigVersion, AppVersion: r.AppVersion})
+}
+
+// 把回滚包中的配置文件解压到 dir，拒绝指向 dir 之外的条目
+func extractRollback(path, dir string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == rollbackInfoName || f.FileInfo().IsDir() {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			return i18n.Errorf("回滚包中的路径无效: %s", f.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := extractRollbackFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractRollbackFile(f *zip.File, target string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
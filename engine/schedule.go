@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"time"
+
+	"syncsafe/schedule"
+)
+
+// 下一次定时备份的时间，按任务的时区计算。没有设置定时或已暂停时返回零值，表达式无效时返回错误
+func (c *Config) NextScheduledBackup(after time.Time) (time.Time, error) {
+	if c.Schedule == "" || c.SchedulePaused {
+		return time.Time{}, nil
+	}
+	s, err := schedule.Parse(c.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Next(after.In(c.Location())), nil
+}
+
+// 下一次批量提交 Git 的时间。没有启用 Git 或没有设置提交计划时返回零值，表达式无效时返回错误
+func (c *Config) NextGitCommit(after time.Time) (time.Time, error) {
+	if !c.Git.Enabled || c.Git.Schedule == "" {
+		return time.Time{}, nil
+	}
+	s, err := schedule.Parse(c.Git.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Next(after.In(c.Location())), nil
+}
+
+// 备份时是否提交 Git：没有提交计划时每次都提交；有计划时只在错过了计划的时间
+// （例如程序没有运行）后的第一次备份时补上提交。表达式无效时每次都提交
+func (c *Config) gitCommitDue(now time.Time) bool {
+	next, err := c.NextGitCommit(c.LastGitCommit)
+	if err != nil || c.Git.Schedule == "" {
+		return true
+	}
+	return !next.IsZero() && !next.After(now)
+}
+
+// 下一次数据巡检的时间。没有设置时返回零值，表达式无效时返回错误
+func (c *Config) NextScheduledScrub(after time.Time) (time.Time, error) {
+	if c.ScrubSchedule == "" {
+		return time.Time{}, nil
+	}
+	s, err := schedule.Parse(c.ScrubSchedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Next(after.In(c.Location())), nil
+}
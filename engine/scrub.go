@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 读取出错时的重试次数，坏扇区有时重读几次能够读出
+const scrubReadAttempts = 3
+
+// 数据巡检结果
+type ScrubResult struct {
+	Files        int      // 完整读出且内容未变的文件数
+	Bytes        int64    // 读出的字节数
+	Repaired     []string // 已用其他副本修复的文件
+	Unrepairable []string // 损坏且没有可用副本的文件及原因
+}
+
+// 是否没有无法修复的文件
+func (r ScrubResult) OK() bool {
+	return len(r.Unrepairable) == 0
+}
+
+// 结果的说明，用于界面和命令行显示
+func (r ScrubResult) String() string {
+	if r.OK() {
+		message := i18n.Sprintf("数据巡检完成：%d 个文件、%.2f MB 均可读出", r.Files, float64(r.Bytes)/(1024*1024))
+		if len(r.Repaired) > 0 {
+			message += i18n.Sprintf("，已修复 %d 个损坏的文件", len(r.Repaired))
+		}
+		return message
+	}
+	return i18n.Sprintf("数据巡检发现 %d 个无法修复的文件（已修复 %d 个，%d 个完好）", len(r.Unrepairable), len(r.Repaired), r.Files)
+}
+
+// 巡检时记录的目标文件哈希，每个目标文件夹对应一个索引文件
+func scrubIndexPath(destination string) string {
+	sum := sha1.Sum([]byte(filepath.Clean(destination)))
+	return filepath.Join(DataDir, "index", hex.EncodeToString(sum[:8])+".scrub")
+}
+
+// 快照中读取失败或内容变化的文件
+type damagedFile struct {
+	record  history.Record
+	relPath string
+	path    string
+	info    os.FileInfo
+	hash    string // 上次巡检时的哈希，没有记录时为空
+	reason  string
+}
+
+// 已读过的文件，硬链接到多个快照的同一个文件只读一次
+type scrubbedFile struct {
+	info os.FileInfo
+	hash string
+	err  error
+}
+
+// 数据巡检：读出 records（本任务的历史记录）中所有快照的每个文件，发现读取错误（坏扇区）和静默损坏（bit rot）。
+// 每个文件的哈希记录在数据目录中，大小和修改时间不变而内容变化即为静默损坏，第一次巡检只建立记录。
+// 损坏的文件尝试用其他快照或 copies（其他目标中同一源文件夹的快照）中相同的副本修复，
+// 未加密的快照还可以用未修改的源文件修复，无法修复的文件在结果中列出。
+// 单个文件出错不中断巡检，ctx 取消时返回 ErrCancelled 和已检查的部分。
+// 只支持本地目标文件夹，records 由调用方复制，巡检期间可以继续备份
+func (e *Engine) Scrub(ctx context.Context, records, copies []history.Record) (ScrubResult, error) {
+	var result ScrubResult
+	if storage.IsRemote(e.Config.DestinationPath) {
+		return result, i18n.Errorf("数据巡检只支持本地目标文件夹")
+	}
+
+	// 快速同步的多条记录指向同一个镜像目录，只检查一次
+	var snapshots []history.Record
+	seenDirs := make(map[string]bool)
+	for _, record := range records {
+		if record.HasSnapshot() && !seenDirs[record.DestPath] {
+			seenDirs[record.DestPath] = true
+			snapshots = append(snapshots, record)
+		}
+	}
+
+	sums := readHashIndex(scrubIndexPath(e.Config.DestinationPath))
+	checked := make(map[string]hashEntry)
+	read := make(map[int64][]scrubbedFile)
+	var damaged []damagedFile
+	var err error
+	for _, record := range snapshots {
+		err = filepath.Walk(record.DestPath, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ErrCancelled
+			}
+			if err != nil {
+				// 巡检期间被清理的快照不算损坏
+				if os.IsNotExist(err) {
+					return nil
+				}
+				result.Unrepairable = append(result.Unrepairable, i18n.Sprintf("%s: 目录无法读取: %v", path, err))
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			relPath, err := filepath.Rel(record.DestPath, path)
+			if err != nil {
+				return err
+			}
+
+			var hash string
+			var readErr error
+			found := false
+			for _, other := range read[info.Size()] {
+				if os.SameFile(other.info, info) {
+					hash, readErr, found = other.hash, other.err, true
+					break
+				}
+			}
+			if !found {
+				hash, readErr = scrubHash(path)
+				if os.IsNotExist(readErr) {
+					return nil
+				}
+				read[info.Size()] = append(read[info.Size()], scrubbedFile{info: info, hash: hash, err: readErr})
+			}
+
+			old, known := sums.get(path)
+			known = known && old.Size == info.Size() && old.ModTime.Equal(info.ModTime())
+			expected := ""
+			if known {
+				expected = old.Hash
+			}
+			switch {
+			case readErr != nil:
+				damaged = append(damaged, damagedFile{record, relPath, path, info, expected, i18n.Sprintf("读取失败: %v", readErr)})
+			case known && old.Hash != hash:
+				damaged = append(damaged, damagedFile{record, relPath, path, info, expected, i18n.T("内容与上次巡检时不同，可能已静默损坏")})
+			default:
+				checked[path] = hashEntry{Size: info.Size(), ModTime: info.ModTime(), Hash: hash}
+				result.Files++
+				result.Bytes += info.Size()
+				if result.Files%100 == 0 {
+					e.status(i18n.Sprintf("数据巡检：已检查 %d 个文件", result.Files))
+				}
+			}
+			// 损坏的文件保留原来的记录，修复前再次巡检仍能发现
+			if _, ok := checked[path]; !ok && known {
+				checked[path] = old
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		for _, bad := range damaged {
+			if ctx.Err() != nil {
+				err = ErrCancelled
+				break
+			}
+			source, sum := findRepairSource(bad, snapshots, copies, sums)
+			if source == "" {
+				result.Unrepairable = append(result.Unrepairable, i18n.Sprintf("%s: %s，没有可用的副本", bad.path, bad.reason))
+				continue
+			}
+			if repairErr := repairFile(ctx, source, bad.path, sum); repairErr != nil {
+				result.Unrepairable = append(result.Unrepairable, i18n.Sprintf("%s: %s，修复失败: %v", bad.path, bad.reason, repairErr))
+				continue
+			}
+			e.status(i18n.Sprintf("已用 %s 修复 %s", source, bad.path))
+			result.Repaired = append(result.Repaired, bad.path)
+			checked[bad.path] = hashEntry{Size: bad.info.Size(), ModTime: bad.info.ModTime(), Hash: sum}
+		}
+	}
+
+	// 取消时没有检查到的文件保留原来的记录
+	if saveErr := sums.save(checked, err != nil); saveErr != nil {
+		e.status(i18n.Sprintf("保存巡检记录失败: %v", saveErr))
+	}
+	if err != nil && !errors.Is(err, ErrCancelled) {
+		return result, i18n.Errorf("数据巡检失败: %v", err)
+	}
+	return result, err
+}
+
+// 计算文件的哈希，读取出错时重试几次
+func scrubHash(path string) (string, error) {
+	var hash string
+	var err error
+	for attempt := 0; attempt < scrubReadAttempts; attempt++ {
+		if hash, err = fileHash(path); err == nil || os.IsNotExist(err) {
+			return hash, err
+		}
+		time.Sleep(time.Second)
+	}
+	return "", err
+}
+
+// 查找可以用来修复 bad 的完好副本，返回副本路径和哈希，没有时返回空字符串。
+// 副本必须大小和修改时间相同、不是同一个文件（硬链接）且能完整读出；
+// 有上次巡检的哈希时内容必须与之相同，否则副本自己不能有静默损坏的迹象
+func findRepairSource(bad damagedFile, snapshots, copies []history.Record, sums *hashIndex) (string, string) {
+	// 归档快照是单个文件，各快照的内容不同
+	if bad.relPath == "." {
+		return "", ""
+	}
+	var candidates []string
+	for _, record := range append(append([]history.Record(nil), snapshots...), copies...) {
+		if record.DestPath == bad.record.DestPath || !record.HasSnapshot() || storage.IsRemote(record.DestPath) ||
+			record.SourcePath != bad.record.SourcePath ||
+			record.Encrypted != bad.record.Encrypted || record.EncryptedNames != bad.record.EncryptedNames {
+			continue
+		}
+		candidates = append(candidates, filepath.Join(record.DestPath, bad.relPath))
+	}
+	// 源文件在备份后没有修改时与未加密快照中的副本相同
+	if !bad.record.Encrypted {
+		candidates = append(candidates, filepath.Join(bad.record.SourcePath, bad.relPath))
+	}
+
+	for _, path := range candidates {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() != bad.info.Size() ||
+			!info.ModTime().Equal(bad.info.ModTime()) || os.SameFile(info, bad.info) {
+			continue
+		}
+		hash, err := scrubHash(path)
+		if err != nil {
+			continue
+		}
+		if bad.hash != "" {
+			if hash == bad.hash {
+				return path, hash
+			}
+			continue
+		}
+		if old, ok := sums.get(path); ok && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) && old.Hash != hash {
+			continue
+		}
+		return path, hash
+	}
+	return "", ""
+}
+
+// 用副本替换损坏的文件：先复制到同一目录的临时文件并核对哈希，再重命名覆盖，
+// 修复失败时损坏的文件保持原样
+func repairFile(ctx context.Context, source, path, hash string) error {
+	tmpPath := path + ".repair"
+	os.Remove(tmpPath)
+	if err := storage.CopyFileContext(ctx, source, tmpPath); err != nil {
+		return err
+	}
+	if written, err := fileHash(tmpPath); err != nil || written != hash {
+		os.Remove(tmpPath)
+		if err == nil {
+			err = i18n.Errorf("写入的内容与副本不同")
+		}
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
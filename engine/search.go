@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 搜索结果：匹配的历史记录，或者快照中路径匹配的文件
+type SearchResult struct {
+	Record history.Record
+	File   string // 快照中匹配的文件（相对路径），为空表示匹配的是历史记录本身
+}
+
+// 在历史记录（备注、错误信息、路径和时间）和快照清单的文件路径中搜索关键字，不区分大小写。
+// 同一个文件只返回包含它的最新快照，最多返回 limit 个结果，从新到旧排列
+func (c *Config) Search(query string, limit int) []SearchResult {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	filter := history.Filter{Search: query, Location: c.Location()}
+
+	var results []SearchResult
+	seen := make(map[string]bool)
+	for i := len(c.History) - 1; i >= 0 && len(results) < limit; i-- {
+		record := c.History[i]
+		if filter.Match(record) {
+			results = append(results, SearchResult{Record: record})
+		}
+		if !record.HasSnapshot() {
+			continue
+		}
+		reader := openSnapshotManifest(record)
+		if reader == nil {
+			continue
+		}
+		for len(results) < limit {
+			entry, ok, err := reader.Next()
+			if err != nil || !ok {
+				break
+			}
+			key := record.SourcePath + "\x00" + entry.RelPath
+			if entry.IsDir || seen[key] || !strings.Contains(strings.ToLower(entry.RelPath), query) {
+				continue
+			}
+			seen[key] = true
+			results = append(results, SearchResult{Record: record, File: entry.RelPath})
+		}
+		reader.Close()
+	}
+	return results
+}
+
+// 一个文件的一个版本，大小和修改时间（精确到秒）都相同的视为同一版本
+type FileVersion struct {
+	Path      string // 相对源文件夹的路径
+	Size      int64
+	ModTime   time.Time
+	Record    history.Record // 包含该版本的最新快照，还原时从这里读取
+	Snapshots int            // 包含该版本的快照数
+}
+
+// 在所有快照清单中查找文件，列出每个文件的所有版本。pattern 含有 *、? 或 [ 时按通配符匹配文件名，
+// 否则在路径中查找，都不区分大小写。结果按路径排列，同一文件的版本从新到旧，最多 limit 个版本
+func (c *Config) FindFile(pattern string, limit int) ([]FileVersion, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return nil, nil
+	}
+	glob := strings.ContainsAny(pattern, "*?[")
+	if _, err := filepath.Match(pattern, ""); glob && err != nil {
+		return nil, i18n.Errorf("通配符格式不正确: %s", pattern)
+	}
+	match := func(relPath string) bool {
+		if !glob {
+			return strings.Contains(strings.ToLower(relPath), pattern)
+		}
+		ok, _ := filepath.Match(pattern, strings.ToLower(filepath.Base(relPath)))
+		return ok
+	}
+
+	type versionKey struct {
+		source, path string
+		size, mtime  int64
+	}
+	var versions []*FileVersion
+	found := make(map[versionKey]*FileVersion)
+	// 从新到旧读取清单，每个版本第一次出现的快照就是包含它的最新快照
+	for i := len(c.History) - 1; i >= 0; i-- {
+		record := c.History[i]
+		if !record.HasSnapshot() {
+			continue
+		}
+		reader := openSnapshotManifest(record)
+		if reader == nil {
+			continue
+		}
+		for {
+			entry, ok, err := reader.Next()
+			if err != nil || !ok {
+				break
+			}
+			if entry.IsDir || !match(entry.RelPath) {
+				continue
+			}
+			key := versionKey{record.SourcePath, entry.RelPath, entry.Size, entry.ModTime.Unix()}
+			if version := found[key]; version != nil {
+				version.Snapshots++
+				continue
+			}
+			if len(versions) >= limit {
+				continue
+			}
+			version := &FileVersion{Path: entry.RelPath, Size: entry.Size, ModTime: entry.ModTime, Record: record, Snapshots: 1}
+			found[key] = version
+			versions = append(versions, version)
+		}
+		reader.Close()
+	}
+
+	results := make([]FileVersion, len(versions))
+	for i, version := range versions {
+		results[i] = *version
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].ModTime.After(results[j].ModTime)
+	})
+	return results, nil
+}
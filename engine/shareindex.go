@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 共享索引：目标根目录的 index.html 列出所有快照，每个快照的文件列表在 shareIndexDir 中，
+// 目标文件夹通过 NAS 或网页服务器共享时，其他电脑用浏览器就能浏览和下载快照中的文件
+const shareIndexDir = "syncsafe-index"
+
+// 索引页中的一个快照
+type indexSnapshot struct {
+	Name      string
+	Time      string
+	Files     int
+	Size      string
+	Link      string // 文件列表页或归档文件的相对地址，加密的快照为空
+	Archive   bool
+	Encrypted bool
+	Note      string
+}
+
+// 快照文件列表中的一个文件
+type indexFile struct {
+	Path string
+	Link string
+	Size string
+	Time string
+}
+
+var shareIndexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"t":    i18n.T,
+	"lang": i18n.Current,
+}).Parse(`<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #37474f; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #eceff1; }
+td.num { text-align: right; white-space: nowrap; }
+a { color: #1e88a8; text-decoration: none; }
+.muted { color: #90a4ae; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="muted">{{printf (t "由 SyncSafe 生成于 %s，只读浏览，不需要安装 SyncSafe。") .Generated}}</p>
+{{if .Snapshots}}<table>
+<tr><th>{{t "快照"}}</th><th>{{t "时间"}}</th><th>{{t "文件数"}}</th><th>{{t "大小"}}</th><th>{{t "备注"}}</th></tr>
+{{range .Snapshots}}<tr>
+<td>{{if .Link}}<a href="{{.Link}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{if .Archive}} <span class="muted">{{t "（归档）"}}</span>{{end}}{{if .Encrypted}} <span class="muted">{{if .Archive}}{{t "（已加密，可用 age 或 7-Zip 解密）"}}{{else}}{{t "（已加密，需要用 SyncSafe 还原）"}}{{end}}</span>{{end}}</td>
+<td>{{.Time}}</td><td class="num">{{.Files}}</td><td class="num">{{.Size}}</td><td>{{.Note}}</td>
+</tr>
+{{end}}</table>{{end}}
+{{if .Page}}<p><a href="../index.html">{{t "返回快照列表"}}</a></p>
+<table>
+<tr><th>{{t "文件"}}</th><th>{{t "大小"}}</th><th>{{t "修改时间"}}</th></tr>
+{{range .Files}}<tr><td><a href="{{.Link}}">{{.Path}}</a></td><td class="num">{{.Size}}</td><td>{{.Time}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// 按记录重新生成目标文件夹的共享索引。已有的快照文件列表不再重新生成，
+// current（本次备份的快照）和快速同步的镜像每次都重新生成，已不存在的快照的列表页被删除。
+// 只支持本地目标文件夹
+func (e *Engine) writeShareIndex(records []history.Record, current string) error {
+	destination := filepath.Clean(e.Config.DestinationPath)
+	pagesDir := filepath.Join(destination, shareIndexDir)
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		return i18n.Errorf("创建索引目录失败: %v", err)
+	}
+
+	var snapshots []indexSnapshot
+	pages := make(map[string]bool)
+	seen := make(map[string]bool)
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if !record.HasSnapshot() || filepath.Dir(filepath.Clean(record.DestPath)) != destination || seen[record.DestPath] {
+			continue
+		}
+		seen[record.DestPath] = true
+		info, err := os.Stat(record.DestPath)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(record.DestPath)
+		snapshot := indexSnapshot{
+			Name:      name,
+			Time:      record.FormatTime(e.Config.Location()),
+			Files:     record.FileCount,
+			Size:      formatIndexSize(record.TotalSize),
+			Archive:   !info.IsDir(),
+			Encrypted: record.Encrypted,
+			Note:      record.Note,
+		}
+		switch {
+		case snapshot.Archive:
+			// 加密的归档可以用 age 或 7-Zip 解密，同样提供下载
+			snapshot.Link = url.PathEscape(name)
+		case !record.Encrypted:
+			page := name + ".html"
+			pages[page] = true
+			snapshot.Link = shareIndexDir + "/" + url.PathEscape(page)
+			pagePath := filepath.Join(pagesDir, page)
+			_, statErr := os.Stat(pagePath)
+			if record.DestPath == current || statErr != nil || record.DestPath == mirrorDir(destination, record.SourcePath) {
+				if err := writeSnapshotPage(record.DestPath, pagePath, e.Config.Location()); err != nil {
+					return err
+				}
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	// 删除已被清理的快照的列表页
+	if entries, err := os.ReadDir(pagesDir); err == nil {
+		for _, entry := range entries {
+			if !pages[entry.Name()] && strings.HasSuffix(entry.Name(), ".html") {
+				os.Remove(filepath.Join(pagesDir, entry.Name()))
+			}
+		}
+	}
+
+	return writeIndexPage(filepath.Join(destination, "index.html"), map[string]interface{}{
+		"Title":     i18n.T("SyncSafe 快照: ") + e.Config.ProfileName(),
+		"Generated": time.Now().In(e.Config.Location()).Format("2006-01-02 15:04:05"),
+		"Snapshots": snapshots,
+	})
+}
+
+// 生成一个快照的文件列表页，链接指向快照目录中的文件
+func writeSnapshotPage(dir, pagePath string, loc *time.Location) error {
+	name := filepath.Base(dir)
+	var files []indexFile
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(relPath)
+		segments := strings.Split(slashPath, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		files = append(files, indexFile{
+			Path: slashPath,
+			Link: path.Join("..", url.PathEscape(name), strings.Join(segments, "/")),
+			Size: formatIndexSize(info.Size()),
+			Time: info.ModTime().In(loc).Format("2006-01-02 15:04:05"),
+		})
+		return nil
+	})
+	if err != nil {
+		return i18n.Errorf("读取快照 %s 失败: %v", name, err)
+	}
+	sort.Slice(files, func(i, k int) bool { return files[i].Path < files[k].Path })
+	return writeIndexPage(pagePath, map[string]interface{}{
+		"Title":     i18n.T("快照 ") + name,
+		"Generated": time.Now().In(loc).Format("2006-01-02 15:04:05"),
+		"Page":      true,
+		"Files":     files,
+	})
+}
+
+func writeIndexPage(pagePath string, data map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := shareIndexTemplate.Execute(&buf, data); err != nil {
+		return i18n.Errorf("生成索引页失败: %v", err)
+	}
+	if err := storage.WriteFileAtomic(pagePath, buf.Bytes(), 0644); err != nil {
+		return i18n.Errorf("写入索引页失败: %v", err)
+	}
+	return nil
+}
+
+// 文件大小的显示文本
+func formatIndexSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.2f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
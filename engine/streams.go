@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"syncsafe/i18n"
+)
+
+// 文件的附加数据流：Windows 上为 NTFS 备用数据流，macOS 上为扩展属性（资源分支、Finder 信息和标签等），
+// Linux 上为 user 命名空间的扩展属性。普通的复制不会带上这些数据
+type Stream struct {
+	Name string
+	Data []byte
+}
+
+// 列出数据流时得到的名称和大小
+type streamInfo struct {
+	Name string
+	Size int64
+}
+
+// 单个数据流的大小上限，更大的数据流不备份，记录为丢失
+const maxStreamSize = 64 * 1024 * 1024
+
+// 当前平台不支持附加数据流
+var errStreamsUnsupported = i18n.Error("当前平台不支持附加数据流")
+
+// 快照附加数据流的保存位置：与快照清单一样保存在本机的配置目录中，不写入快照本身，
+// 加密、归档和 WebDAV 目标同样适用
+func streamsPath(snapshotDir string) string {
+	return strings.TrimSuffix(manifestPath(snapshotDir), ".manifest") + ".streams"
+}
+
+// 读取文件的所有附加数据流，文件没有附加数据流时返回 nil
+func readStreams(path string) ([]Stream, error) {
+	infos, err := listStreams(path)
+	if err != nil || len(infos) == 0 {
+		return nil, err
+	}
+	streams := make([]Stream, 0, len(infos))
+	for _, info := range infos {
+		if info.Size > maxStreamSize {
+			return nil, i18n.Errorf("数据流 %s 超过 %d MB", info.Name, maxStreamSize/(1024*1024))
+		}
+		data, err := readStream(path, info.Name)
+		if err != nil {
+			return nil, i18n.Errorf("读取数据流 %s 失败: %v", info.Name, err)
+		}
+		streams = append(streams, Stream{Name: info.Name, Data: data})
+	}
+	return streams, nil
+}
+
+// 把附加数据流写入文件，保留文件的修改时间（Windows 上写入数据流会更新修改时间）
+func applyStreams(path string, streams []Stream) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		if err := writeStream(path, stream.Name, stream.Data); err != nil {
+			return i18n.Errorf("写入数据流 %s 失败: %v", stream.Name, err)
+		}
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// 快照附加数据流文件中的一行：一个文件的所有数据流
+type streamRecord struct {
+	Path    string
+	Streams []Stream
+}
+
+// 写入快照的附加数据流，每行一个 JSON 记录，数据以 base64 保存
+type streamWriter struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	count  int
+}
+
+func createStreams(path string) (*streamWriter, error) {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, i18n.Errorf("创建数据流文件失败: %v", err)
+	}
+	return &streamWriter{path: path, file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+func (w *streamWriter) Add(relPath string, streams []Stream) error {
+	data, err := json.Marshal(streamRecord{Path: relPath, Streams: streams})
+	if err != nil {
+		return err
+	}
+	w.writer.Write(data)
+	if err := w.writer.WriteByte('\n'); err != nil {
+		return i18n.Errorf("写入数据流文件失败: %v", err)
+	}
+	w.count++
+	return nil
+}
+
+// 保存数据流文件，没有任何文件带有数据流时不保留
+func (w *streamWriter) Close() error {
+	if w.count == 0 {
+		w.Abort()
+		os.Remove(w.path)
+		return nil
+	}
+	if err := w.writer.Flush(); err != nil {
+		w.Abort()
+		return i18n.Errorf("写入数据流文件失败: %v", err)
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return i18n.Errorf("写入数据流文件失败: %v", err)
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		os.Remove(w.file.Name())
+		return i18n.Errorf("保存数据流文件失败: %v", err)
+	}
+	return nil
+}
+
+func (w *streamWriter) Abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// 读取快照中选中的文件或目录（见 restoreRoots）中文件的附加数据流，按相对路径索引。
+// 快照没有保存附加数据流时返回空表
+func loadStreams(snapshotDir string, roots []string) (map[string][]Stream, error) {
+	streams := make(map[string][]Stream)
+	file, err := os.Open(streamsPath(snapshotDir))
+	if os.IsNotExist(err) {
+		return streams, nil
+	}
+	if err != nil {
+		return nil, i18n.Errorf("读取数据流文件失败: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 2*maxStreamSize)
+	for scanner.Scan() {
+		var record streamRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, i18n.Errorf("数据流文件已损坏: %v", err)
+		}
+		if inRestoreRoots(record.Path, roots) {
+			streams[record.Path] = record.Streams
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, i18n.Errorf("读取数据流文件失败: %v", err)
+	}
+	return streams, nil
+}
@@ -0,0 +1,81 @@
+//go:build darwin && cgo
+
+package engine
+
+/*
+#include <stdlib.h>
+#include <sys/xattr.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"unsafe"
+)
+
+// macOS 上备份所有扩展属性，包括资源分支（com.apple.ResourceFork）、Finder 信息和标签
+func listStreams(path string) ([]streamInfo, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	size, err := C.listxattr(cpath, nil, 0, C.XATTR_NOFOLLOW)
+	if size < 0 {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	size, err = C.listxattr(cpath, (*C.char)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)), C.XATTR_NOFOLLOW)
+	if size < 0 {
+		return nil, err
+	}
+	var infos []streamInfo
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		cname := C.CString(string(name))
+		n, err := C.getxattr(cpath, cname, nil, 0, 0, C.XATTR_NOFOLLOW)
+		C.free(unsafe.Pointer(cname))
+		if n < 0 {
+			return nil, err
+		}
+		infos = append(infos, streamInfo{Name: string(name), Size: int64(n)})
+	}
+	return infos, nil
+}
+
+func readStream(path, name string) ([]byte, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	size, err := C.getxattr(cpath, cname, nil, 0, 0, C.XATTR_NOFOLLOW)
+	if size < 0 {
+		return nil, err
+	}
+	if size == 0 {
+		return []byte{}, nil
+	}
+	buf := make([]byte, size)
+	size, err = C.getxattr(cpath, cname, unsafe.Pointer(&buf[0]), C.size_t(len(buf)), 0, C.XATTR_NOFOLLOW)
+	if size < 0 {
+		return nil, err
+	}
+	return buf[:size], nil
+}
+
+func writeStream(path, name string, data []byte) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	var value unsafe.Pointer
+	if len(data) > 0 {
+		value = unsafe.Pointer(&data[0])
+	}
+	if r, err := C.setxattr(cpath, cname, value, C.size_t(len(data)), 0, C.XATTR_NOFOLLOW); r < 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+)
+
+// Linux 上只备份 user 命名空间的扩展属性，security 和 system 命名空间需要特权才能写回
+const xattrPrefix = "user."
+
+func listStreams(path string) ([]streamInfo, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		if err == syscall.ENOTSUP {
+			return nil, nil
+		}
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = syscall.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+	var infos []streamInfo
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if !strings.HasPrefix(string(name), xattrPrefix) {
+			continue
+		}
+		n, err := syscall.Getxattr(path, string(name), nil)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, streamInfo{Name: string(name), Size: int64(n)})
+	}
+	return infos, nil
+}
+
+func readStream(path, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Getxattr(path, name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
+
+func writeStream(path, name string, data []byte) error {
+	return syscall.Setxattr(path, name, data, 0)
+}
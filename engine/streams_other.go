@@ -0,0 +1,15 @@
+//go:build !windows && !linux && !(darwin && cgo)
+
+package engine
+
+func listStreams(path string) ([]streamInfo, error) {
+	return nil, nil
+}
+
+func readStream(path, name string) ([]byte, error) {
+	return nil, errStreamsUnsupported
+}
+
+func writeStream(path, name string, data []byte) error {
+	return errStreamsUnsupported
+}
@@ -0,0 +1,69 @@
+//go:build windows
+
+package engine
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procFindFirstStreamW = modkernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modkernel32.NewProc("FindNextStreamW")
+)
+
+// FindFirstStreamW 返回的数据流信息
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+const (
+	errorHandleEOF        syscall.Errno = 38 // 没有更多数据流
+	errorNotSupported     syscall.Errno = 50
+	errorInvalidParameter syscall.Errno = 87
+)
+
+// 列出文件的 NTFS 备用数据流，不包括文件内容本身（未命名的 ::$DATA）
+func listStreams(path string) ([]streamInfo, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var data win32FindStreamData
+	handle, _, callErr := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		// 没有数据流，或者文件系统（FAT、exFAT 等）不支持数据流
+		if callErr == errorHandleEOF || callErr == errorNotSupported || callErr == errorInvalidParameter {
+			return nil, nil
+		}
+		return nil, callErr
+	}
+	defer syscall.FindClose(syscall.Handle(handle))
+
+	var infos []streamInfo
+	for {
+		// 名称的格式为 :名称:$DATA
+		name := strings.TrimSuffix(strings.TrimPrefix(syscall.UTF16ToString(data.StreamName[:]), ":"), ":$DATA")
+		if name != "" {
+			infos = append(infos, streamInfo{Name: name, Size: data.StreamSize})
+		}
+		r, _, callErr := procFindNextStreamW.Call(handle, uintptr(unsafe.Pointer(&data)))
+		if r == 0 {
+			if callErr == errorHandleEOF {
+				return infos, nil
+			}
+			return nil, callErr
+		}
+	}
+}
+
+func readStream(path, name string) ([]byte, error) {
+	return os.ReadFile(path + ":" + name)
+}
+
+func writeStream(path, name string, data []byte) error {
+	return os.WriteFile(path+":"+name, data, 0644)
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 在 Windows 任务计划程序中注册的运行频率
+const (
+	TaskDaily  = "daily"  // 每天在指定时间
+	TaskHourly = "hourly" // 每小时
+	TaskLogon  = "logon"  // 用户登录时
+)
+
+// 计划任务的状态
+type TaskStatus struct {
+	Registered bool
+	State      string    // 就绪、正在运行、已禁用
+	LastRun    time.Time // 从未运行时为零值
+	LastResult int       // 上次运行的退出码，0 表示成功
+	NextRun    time.Time
+}
+
+// 任务在任务计划程序中的文件夹和名称
+const taskFolder = `\SyncSafe\`
+
+func taskName(config *Config) string {
+	// 任务名称不能包含 \ / : * ? " < > |
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, config.ProfileName())
+	return taskFolder + name
+}
+
+// 在任务计划程序中注册任务，按 frequency 运行命令行模式的 backup，图形界面没有打开时也会备份。
+// at 为每天运行的时间（HH:MM），只用于 TaskDaily。已有同名任务时替换
+func RegisterTask(config *Config, frequency, at string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return i18n.Errorf("获取程序路径失败: %v", err)
+	}
+	command := strings.Join([]string{
+		escapeArg(exe), "backup",
+		"--config", escapeArg(DataDir),
+		"--profile", escapeArg(config.ProfileName()),
+	}, " ")
+	args := []string{"/Create", "/F", "/TN", taskName(config), "/TR", command}
+	switch frequency {
+	case TaskDaily:
+		if _, err := time.Parse("15:04", at); err != nil {
+			return i18n.Errorf("运行时间格式应为 HH:MM: %s", at)
+		}
+		args = append(args, "/SC", "DAILY", "/ST", at)
+	case TaskHourly:
+		args = append(args, "/SC", "HOURLY")
+	case TaskLogon:
+		args = append(args, "/SC", "ONLOGON")
+	default:
+		return i18n.Errorf("不支持的运行频率: %s", frequency)
+	}
+	if _, err := runSchtasks(args...); err != nil {
+		return i18n.Errorf("注册计划任务失败: %v", err)
+	}
+	return nil
+}
+
+// 删除任务的计划任务，没有注册时不报错
+func UnregisterTask(config *Config) error {
+	status, err := QueryTask(config)
+	if err != nil || !status.Registered {
+		return err
+	}
+	if _, err := runSchtasks("/Delete", "/F", "/TN", taskName(config)); err != nil {
+		return i18n.Errorf("删除计划任务失败: %v", err)
+	}
+	return nil
+}
+
+// 查询任务的计划任务状态。schtasks 的输出随系统语言变化，这里通过 PowerShell 读取
+func QueryTask(config *Config) (TaskStatus, error) {
+	name := strings.TrimPrefix(taskName(config), taskFolder)
+	script := fmt.Sprintf(`[Console]::OutputEncoding = [Text.Encoding]::UTF8
+$task = Get-ScheduledTask -TaskPath '%s' -TaskName '%s' -ErrorAction SilentlyContinue
+if (-not $task) { '{}'; exit }
+$info = $task | Get-ScheduledTaskInfo
+[pscustomobject]@{
+  State = [string]$task.State
+  LastRun = if ($info.LastRunTime) { $info.LastRunTime.ToString('o') } else { '' }
+  LastResult = $info.LastTaskResult
+  NextRun = if ($info.NextRunTime) { $info.NextRunTime.ToString('o') } else { '' }
+} | ConvertTo-Json`, taskFolder, strings.ReplaceAll(name, "'", "''"))
+	output, err := runPowerShell(script)
+	if err != nil {
+		return TaskStatus{}, i18n.Errorf("查询计划任务失败: %v", err)
+	}
+	var raw struct {
+		State      string
+		LastRun    string
+		LastResult int
+		NextRun    string
+	}
+	output = strings.TrimSpace(strings.TrimPrefix(output, "\ufeff"))
+	if err := json.Unmarshal([]byte(output), &raw); err != nil {
+		return TaskStatus{}, i18n.Errorf("查询计划任务失败: %v", err)
+	}
+	if raw.State == "" {
+		return TaskStatus{}, nil
+	}
+	status := TaskStatus{Registered: true, State: taskStates[raw.State], LastResult: raw.LastResult}
+	if status.State == "" {
+		status.State = raw.State
+	}
+	// 从未运行时 LastRunTime 为 1999-11-30 之类的占位时间
+	if t, err := time.Parse(time.RFC3339Nano, raw.LastRun); err == nil && t.Year() > 2000 {
+		status.LastRun = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, raw.NextRun); err == nil && t.Year() > 2000 {
+		status.NextRun = t
+	}
+	return status, nil
+}
+
+var taskStates = map[string]string{
+	"Ready":    "就绪",
+	"Running":  "正在运行",
+	"Disabled": "已禁用",
+	"Queued":   "排队中",
+}
+
+// 状态的说明，用于界面显示
+func (s TaskStatus) String() string {
+	if !s.Registered {
+		return i18n.T("未注册")
+	}
+	parts := []string{i18n.T(s.State)}
+	if s.LastRun.IsZero() {
+		parts = append(parts, i18n.T("尚未运行"))
+	} else if s.LastResult == 0 {
+		parts = append(parts, i18n.Sprintf("上次运行 %s 成功", s.LastRun.Format("2006-01-02 15:04")))
+	} else if s.LastResult == 1 {
+		// 命令行模式的退出码 1 表示快照已写入，但有文件复制失败或校验不一致
+		parts = append(parts, i18n.Sprintf("上次运行 %s 部分成功", s.LastRun.Format("2006-01-02 15:04")))
+	} else {
+		parts = append(parts, i18n.Sprintf("上次运行 %s 失败（退出码 %d）", s.LastRun.Format("2006-01-02 15:04"), s.LastResult))
+	}
+	if !s.NextRun.IsZero() {
+		parts = append(parts, i18n.T("下次运行 ")+s.NextRun.Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, i18n.T("，"))
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package engine
+
+import "syncsafe/i18n"
+
+// 是否支持注册到系统的计划任务
+const TaskSchedulerSupported = false
+
+func escapeArg(arg string) string {
+	return arg
+}
+
+func runSchtasks(args ...string) (string, error) {
+	return "", i18n.Errorf("计划任务只支持 Windows")
+}
+
+func runPowerShell(script string) (string, error) {
+	return "", i18n.Errorf("计划任务只支持 Windows")
+}
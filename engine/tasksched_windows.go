@@ -0,0 +1,43 @@
+//go:build windows
+
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// 是否支持注册到系统的计划任务
+const TaskSchedulerSupported = true
+
+const createNoWindow = 0x08000000
+
+func escapeArg(arg string) string {
+	return syscall.EscapeArg(arg)
+}
+
+// 不显示控制台窗口地运行命令，返回标准输出
+func runHidden(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%v: %s", err, message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func runSchtasks(args ...string) (string, error) {
+	return runHidden("schtasks.exe", args...)
+}
+
+func runPowerShell(script string) (string, error) {
+	return runHidden("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+}
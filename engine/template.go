@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 路径模板中的日期占位符，例如 /var/log/app/{yyyy-MM}
+var pathTemplatePattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// 占位符中的日期格式与 Go 时间格式的对应关系，长的写在前面
+var pathTemplateLayout = strings.NewReplacer(
+	"yyyy", "2006",
+	"yy", "06",
+	"MM", "01",
+	"dd", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// 路径中是否包含日期占位符
+func HasPathTemplate(path string) bool {
+	return pathTemplatePattern.MatchString(path)
+}
+
+// 按时间 t 展开路径中的日期占位符
+func ExpandPathTemplate(path string, t time.Time) string {
+	return pathTemplatePattern.ReplaceAllStringFunc(path, func(match string) string {
+		layout := pathTemplateLayout.Replace(match[1 : len(match)-1])
+		return t.Format(layout)
+	})
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"time"
+	// 内置时区数据库，Windows 和精简的系统上也能按名称加载时区
+	_ "time/tzdata"
+
+	"syncsafe/i18n"
+)
+
+// 定时和显示时间时使用的时区，没有设置或名称无效时为本机时区
+func (c *Config) Location() *time.Location {
+	if c.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := LoadTimeZone(c.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// 按 IANA 名称（例如 Asia/Shanghai）加载时区，为空表示本机时区
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, i18n.Errorf("未知的时区: %s", name)
+	}
+	return loc, nil
+}
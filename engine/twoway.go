@@ -0,0 +1,372 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 双向同步：源文件夹与另一台电脑也在同步的文件夹（例如 NAS 上的共享文件夹）互相同步。
+// 状态数据库记录上次同步后两侧每个文件的大小和修改时间，据此判断每一侧是否有变化：
+// 只有一侧变化时把变化（包括删除）传到另一侧，两侧都修改时为冲突，按 Config.SyncConflicts 处理
+
+// 冲突的处理方式
+const (
+	ConflictManual = ""       // 两侧的文件保持不动，等待在界面中逐个选择
+	ConflictNewest = "newest" // 保留修改时间较新的一侧
+	ConflictBoth   = "both"   // 两个版本都保留，同步文件夹中的版本改名为冲突副本
+)
+
+// 手动处理冲突时的选择
+const (
+	ResolveLocal  = "local"  // 保留本机的版本
+	ResolveRemote = "remote" // 保留同步文件夹中的版本
+	ResolveBoth   = "both"   // 两个版本都保留
+)
+
+// 文件的大小和修改时间
+type FileStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+func stampOf(info os.FileInfo) FileStamp {
+	return FileStamp{Size: info.Size(), ModTime: info.ModTime()}
+}
+
+func (s FileStamp) same(other FileStamp) bool {
+	return s.Size == other.Size && s.ModTime.Equal(other.ModTime)
+}
+
+// 两侧都修改过的文件
+type SyncConflict struct {
+	RelPath string
+	Local   FileStamp // 本机的版本
+	Remote  FileStamp // 同步文件夹中的版本
+}
+
+// 一次双向同步的结果
+type SyncResult struct {
+	ToPeer    int            // 复制到同步文件夹的文件数
+	FromPeer  int            // 从同步文件夹复制回来的文件数
+	Deleted   int            // 因另一侧删除而删除的文件数
+	Conflicts []SyncConflict // 等待手动处理的冲突
+}
+
+func (r SyncResult) String() string {
+	summary := i18n.Sprintf("双向同步：发送 %d 个、接收 %d 个、删除 %d 个文件", r.ToPeer, r.FromPeer, r.Deleted)
+	if len(r.Conflicts) > 0 {
+		summary += i18n.Sprintf("，%d 个冲突等待处理", len(r.Conflicts))
+	}
+	return summary
+}
+
+// 上次同步后两侧文件的状态
+type syncBase struct {
+	Local  FileStamp
+	Remote FileStamp
+}
+
+// 双向同步的状态数据库，每个源文件夹和同步文件夹的组合对应一个文件
+type syncState struct {
+	path      string
+	Files     map[string]syncBase
+	Conflicts []SyncConflict
+}
+
+func syncStatePath(source, peer string) string {
+	sum := sha1.Sum([]byte(filepath.Clean(source) + "\x00" + filepath.Clean(peer)))
+	return filepath.Join(DataDir, "sync", hex.EncodeToString(sum[:8])+".state")
+}
+
+// 读取状态数据库，不存在或损坏时返回空状态（两侧已有的不同文件都视为冲突，不会丢失）
+func loadSyncState(source, peer string) *syncState {
+	s := &syncState{path: syncStatePath(source, peer)}
+	if file, err := os.Open(s.path); err == nil {
+		gob.NewDecoder(file).Decode(s)
+		file.Close()
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]syncBase)
+	}
+	return s
+}
+
+func (s *syncState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return i18n.Errorf("创建同步状态目录失败: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(s.path, buf.Bytes(), 0644)
+}
+
+// 列出文件夹中的文件，跳过 .git 目录和按配置排除的文件
+func (e *Engine) scanSyncTree(ctx context.Context, root string) (map[string]FileStamp, error) {
+	files := make(map[string]FileStamp)
+	filter := e.Config.fileFilter()
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if filter.Exclude(relPath, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files[relPath] = stampOf(info)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// 与同步文件夹交换两侧的变化。同步文件夹不可用、或一侧的文件全部消失（通常是磁盘没有挂载）时
+// 不做任何修改并返回错误
+func (e *Engine) TwoWaySync(ctx context.Context, source string) (SyncResult, error) {
+	var result SyncResult
+	peer := e.Config.SyncPath
+	if info, err := os.Stat(peer); err != nil || !info.IsDir() {
+		return result, i18n.Errorf("同步文件夹不可用: %s", peer)
+	}
+	state := loadSyncState(source, peer)
+	local, err := e.scanSyncTree(ctx, source)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ErrCancelled
+		}
+		return result, i18n.Errorf("列出源文件夹失败: %v", err)
+	}
+	remote, err := e.scanSyncTree(ctx, peer)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ErrCancelled
+		}
+		return result, i18n.Errorf("列出同步文件夹失败: %v", err)
+	}
+	if len(state.Files) > 0 && (len(local) == 0 || len(remote) == 0) {
+		return result, i18n.Errorf("一侧的文件已全部消失，可能是磁盘没有挂载，已停止同步")
+	}
+
+	paths := make(map[string]bool, len(local)+len(remote))
+	for relPath := range local {
+		paths[relPath] = true
+	}
+	for relPath := range remote {
+		paths[relPath] = true
+	}
+	for relPath := range state.Files {
+		paths[relPath] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for relPath := range paths {
+		sorted = append(sorted, relPath)
+	}
+	sort.Strings(sorted)
+
+	for _, relPath := range sorted {
+		if err := ctx.Err(); err != nil {
+			return result, ErrCancelled
+		}
+		l, inLocal := local[relPath]
+		r, inRemote := remote[relPath]
+		base, known := state.Files[relPath]
+		localChanged := inLocal != known || (inLocal && !l.same(base.Local))
+		remoteChanged := inRemote != known || (inRemote && !r.same(base.Remote))
+
+		var err error
+		switch {
+		case !localChanged && !remoteChanged:
+			continue
+		case !inLocal && !inRemote:
+			// 两侧都已删除
+			delete(state.Files, relPath)
+		case inLocal && inRemote && l.same(r):
+			// 两侧做了相同的修改
+			state.Files[relPath] = syncBase{Local: l, Remote: r}
+		case !remoteChanged || (localChanged && !inRemote):
+			// 只有本机变化，或本机修改而另一侧删除：修改优先，不丢失内容
+			err = e.syncSide(state, &result, relPath, source, peer, inLocal, true)
+		case !localChanged || !inLocal:
+			err = e.syncSide(state, &result, relPath, source, peer, inRemote, false)
+		default:
+			err = e.syncConflict(state, &result, SyncConflict{RelPath: relPath, Local: l, Remote: r}, source, peer)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	state.Conflicts = result.Conflicts
+	if e.Config.DryRun {
+		return result, nil
+	}
+	if err := state.save(); err != nil {
+		return result, i18n.Errorf("保存同步状态失败: %v", err)
+	}
+	return result, nil
+}
+
+// 把一侧的变化传到另一侧：toPeer 为 true 时从源文件夹到同步文件夹，exists 为变化的一侧文件是否还存在
+func (e *Engine) syncSide(state *syncState, result *SyncResult, relPath, source, peer string, exists, toPeer bool) error {
+	from, to := filepath.Join(source, relPath), filepath.Join(peer, relPath)
+	if !toPeer {
+		from, to = to, from
+	}
+	if !exists {
+		if e.Simulated("双向同步：删除 %s", to) {
+			return nil
+		}
+		if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+			return i18n.Errorf("删除文件失败: %v", err)
+		}
+		delete(state.Files, relPath)
+		result.Deleted++
+		return nil
+	}
+	if e.Simulated("双向同步：复制 %s 到 %s", from, to) {
+		return nil
+	}
+	if err := syncCopy(from, to); err != nil {
+		return err
+	}
+	if toPeer {
+		result.ToPeer++
+	} else {
+		result.FromPeer++
+	}
+	return state.record(relPath, source, peer)
+}
+
+// 按配置处理冲突，手动处理时记录在结果中，两侧保持不动
+func (e *Engine) syncConflict(state *syncState, result *SyncResult, conflict SyncConflict, source, peer string) error {
+	switch e.Config.SyncConflicts {
+	case ConflictNewest:
+		toPeer := conflict.Local.ModTime.After(conflict.Remote.ModTime)
+		return e.syncSide(state, result, conflict.RelPath, source, peer, true, toPeer)
+	case ConflictBoth:
+		if e.Simulated("双向同步：%s 两侧都有修改，保留两个版本", conflict.RelPath) {
+			return nil
+		}
+		return state.keepBoth(conflict.RelPath, source, peer)
+	}
+	result.Conflicts = append(result.Conflicts, conflict)
+	return nil
+}
+
+// 复制文件并保留修改时间。CopyFile 会跳过修改时间相同的已有文件，这时先删除旧文件
+func syncCopy(from, to string) error {
+	fromInfo, err := os.Stat(from)
+	if err != nil {
+		return i18n.Errorf("读取文件失败: %v", err)
+	}
+	if toInfo, err := os.Stat(to); err == nil && toInfo.ModTime().Equal(fromInfo.ModTime()) {
+		if err := os.Remove(to); err != nil {
+			return i18n.Errorf("替换文件失败: %v", err)
+		}
+	}
+	return storage.CopyFile(from, to)
+}
+
+// 记录文件在两侧的当前状态
+func (s *syncState) record(relPath, source, peer string) error {
+	l, err := os.Stat(filepath.Join(source, relPath))
+	if err != nil {
+		return i18n.Errorf("读取文件失败: %v", err)
+	}
+	r, err := os.Stat(filepath.Join(peer, relPath))
+	if err != nil {
+		return i18n.Errorf("读取文件失败: %v", err)
+	}
+	s.Files[relPath] = syncBase{Local: stampOf(l), Remote: stampOf(r)}
+	return nil
+}
+
+// 冲突副本的文件名，例如 report (冲突副本 2024-05-01 103000).docx，使用界面语言
+func conflictCopyName(relPath string, t time.Time) string {
+	ext := filepath.Ext(relPath)
+	return i18n.Sprintf("%s (冲突副本 %s)%s", strings.TrimSuffix(relPath, ext), t.Format("2006-01-02 150405"), ext)
+}
+
+// 两个版本都保留：同步文件夹中的版本改名为冲突副本并复制到本机，本机的版本复制到同步文件夹
+func (s *syncState) keepBoth(relPath, source, peer string) error {
+	copyPath := conflictCopyName(relPath, time.Now())
+	if err := os.Rename(filepath.Join(peer, relPath), filepath.Join(peer, copyPath)); err != nil {
+		return i18n.Errorf("保存冲突副本失败: %v", err)
+	}
+	if err := syncCopy(filepath.Join(peer, copyPath), filepath.Join(source, copyPath)); err != nil {
+		return err
+	}
+	if err := syncCopy(filepath.Join(source, relPath), filepath.Join(peer, relPath)); err != nil {
+		return err
+	}
+	if err := s.record(copyPath, source, peer); err != nil {
+		return err
+	}
+	return s.record(relPath, source, peer)
+}
+
+// 上次同步留下的、等待手动处理的冲突
+func (e *Engine) SyncConflicts() []SyncConflict {
+	if e.Config.SyncPath == "" {
+		return nil
+	}
+	return loadSyncState(e.SourcePath(), e.Config.SyncPath).Conflicts
+}
+
+// 按 choice（ResolveLocal、ResolveRemote 或 ResolveBoth）处理一个冲突
+func (e *Engine) ResolveSyncConflict(relPath, choice string) error {
+	source, peer := e.SourcePath(), e.Config.SyncPath
+	state := loadSyncState(source, peer)
+	var err error
+	switch choice {
+	case ResolveLocal:
+		err = syncCopy(filepath.Join(source, relPath), filepath.Join(peer, relPath))
+	case ResolveRemote:
+		err = syncCopy(filepath.Join(peer, relPath), filepath.Join(source, relPath))
+	case ResolveBoth:
+		err = state.keepBoth(relPath, source, peer)
+	default:
+		err = i18n.Errorf("未知的处理方式: %s", choice)
+	}
+	if err == nil && choice != ResolveBoth {
+		err = state.record(relPath, source, peer)
+	}
+	if err != nil {
+		return err
+	}
+	for i, conflict := range state.Conflicts {
+		if conflict.RelPath == relPath {
+			state.Conflicts = append(state.Conflicts[:i], state.Conflicts[i+1:]...)
+			break
+		}
+	}
+	if err := state.save(); err != nil {
+		return i18n.Errorf("保存同步状态失败: %v", err)
+	}
+	return nil
+}
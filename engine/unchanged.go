@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 预扫描发现第一处变化后提前结束遍历
+var errSourceChanged = i18n.Error("源文件夹有变化")
+
+// 可以用来判断是否有变化的上一个快照：同一源文件夹、同一目标文件夹且快照仍然存在
+func (e *Engine) previousSnapshot() (history.Record, bool) {
+	last, ok := history.LastSnapshot(e.Config.History)
+	if !ok || !last.HasSnapshot() || last.SourcePath != e.Config.SourcePath {
+		return history.Record{}, false
+	}
+	if filepath.Dir(last.DestPath) != filepath.Clean(e.Config.DestinationPath) {
+		return history.Record{}, false
+	}
+	return last, true
+}
+
+// 预扫描源文件夹，与上一个快照的清单对比是否有新增、修改或删除的文件。
+// 无法确定时（清单缺失、访问出错）视为有变化
+func (e *Engine) sourceChanged(source string, previous history.Record) bool {
+	reader := openSnapshotManifest(previous)
+	if reader == nil {
+		return true
+	}
+	diff := newManifestDiff(reader)
+	filter := e.Config.fileFilter()
+	var hashes *hashIndex
+	if e.Config.ChecksumCompare {
+		hashes = loadHashIndex(source, e.Config.DestinationPath)
+	}
+	compare := func(entry ManifestEntry) error {
+		change := diff.Compare(entry)
+		if hashes != nil && !entry.IsDir && e.Config.hashEnabled(entry.RelPath) {
+			change, _, _ = hashes.compare(filepath.Join(source, entry.RelPath), entry, change)
+		}
+		if change != changeUnchanged {
+			return errSourceChanged
+		}
+		return nil
+	}
+
+	var err error
+	if idx, idxErr := e.SourceIndex(); idxErr == nil && (idx.Live() || idx.CatchUp()) {
+		for _, relPath := range idx.Paths() {
+			entry, ok := idx.Get(relPath)
+			if !ok {
+				continue
+			}
+			if filter.Active() || entry.IsDir {
+				// 隐藏和系统属性、目录权限不在索引中，需要读取文件信息
+				info, statErr := os.Lstat(filepath.Join(source, relPath))
+				if statErr != nil || filter.Exclude(relPath, info) {
+					continue
+				}
+				if entry.IsDir {
+					if e.Config.SkipDirectories {
+						continue
+					}
+					if err = compare(ManifestEntry{RelPath: relPath, ModTime: info.ModTime(), IsDir: true, Mode: info.Mode().Perm()}); err != nil {
+						break
+					}
+					continue
+				}
+			}
+			if err = compare(ManifestEntry{RelPath: relPath, Size: entry.Size, ModTime: entry.ModTime}); err != nil {
+				break
+			}
+		}
+	} else {
+		err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			relPath, err := filepath.Rel(source, path)
+			if err != nil || relPath == "." {
+				return err
+			}
+			if filter.Exclude(relPath, info) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if e.Config.SkipDirectories {
+					return nil
+				}
+				return compare(ManifestEntry{RelPath: relPath, ModTime: info.ModTime(), IsDir: true, Mode: info.Mode().Perm()})
+			}
+			return compare(ManifestEntry{RelPath: relPath, Size: info.Size(), ModTime: info.ModTime()})
+		})
+	}
+
+	deleted, diffErr := diff.Finish()
+	if !e.Config.SkipDirectories && diff.DeletedDirs() > 0 {
+		return true
+	}
+	return err != nil || diffErr != nil || deleted > 0
+}
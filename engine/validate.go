@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/ignore"
+	"syncsafe/notify"
+	"syncsafe/peer"
+	"syncsafe/schedule"
+	"syncsafe/storage"
+)
+
+// 配置中出问题的设置，界面据此打开对应的设置对话框
+type Setting string
+
+const (
+	SettingSource      Setting = "source"
+	SettingDestination Setting = "destination"
+	SettingWebDAV      Setting = "webdav"
+	SettingS3          Setting = "s3"
+	SettingEncryption  Setting = "encryption"
+	SettingArchive     Setting = "archive"
+	SettingSync        Setting = "sync"
+	SettingFilter      Setting = "filter"
+	SettingSchedule    Setting = "schedule"
+	SettingGit         Setting = "git"
+	SettingNotify      Setting = "notify"
+	SettingEmail       Setting = "email"
+	SettingWebhooks    Setting = "webhooks"
+	SettingPeer        Setting = "peer"
+	SettingInterop     Setting = "interop"
+	SettingReplicas    Setting = "replicas"
+)
+
+// 设置的显示名称，在显示时翻译
+var SettingLabels = map[Setting]string{
+	SettingSource:      "源文件夹",
+	SettingDestination: "目标文件夹",
+	SettingWebDAV:      "WebDAV",
+	SettingS3:          "S3",
+	SettingEncryption:  "加密",
+	SettingArchive:     "快照格式",
+	SettingSync:        "双向同步",
+	SettingFilter:      "排除规则",
+	SettingSchedule:    "定时备份",
+	SettingGit:         "Git",
+	SettingNotify:      "推送通知",
+	SettingEmail:       "邮件通知",
+	SettingWebhooks:    "Webhook",
+	SettingPeer:        "局域网同步",
+	SettingInterop:     "导出到同步工具",
+	SettingReplicas:    "附加目标",
+}
+
+// 配置中的一个问题
+type Problem struct {
+	Setting  Setting
+	Message  string // 问题和解决方法
+	Blocking bool   // 备份无法进行；否则备份照常进行，只是该功能不会生效
+}
+
+func (p Problem) String() string {
+	return i18n.T(SettingLabels[p.Setting]) + ": " + p.Message
+}
+
+// 阻止备份的配置问题，一次列出所有问题
+type ProblemsError struct {
+	Problems []Problem
+}
+
+func (e *ProblemsError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 检查整个配置：路径是否存在、规则和计划能否解析、启用的功能是否填写了凭据。
+// 返回所有问题，阻止备份的问题在前
+func (c *Config) Validate() []Problem {
+	var blocking, warnings []Problem
+	add := func(setting Setting, isBlocking bool, message string) {
+		problem := Problem{Setting: setting, Message: message, Blocking: isBlocking}
+		if isBlocking {
+			blocking = append(blocking, problem)
+		} else {
+			warnings = append(warnings, problem)
+		}
+	}
+
+	// 源文件夹，路径模板按当前时间展开
+	if c.SourcePath == "" {
+		add(SettingSource, true, i18n.T("没有选择源文件夹"))
+	} else {
+		source := ExpandPathTemplate(c.SourcePath, time.Now())
+		if info, err := os.Stat(source); err != nil {
+			add(SettingSource, true, i18n.Sprintf("源文件夹不存在或无法访问: %v", err))
+		} else if !info.IsDir() {
+			add(SettingSource, true, i18n.Sprintf("%s 不是文件夹", source))
+		} else {
+			for _, file := range c.SourceFiles {
+				if _, err := os.Stat(filepath.Join(source, filepath.FromSlash(file))); err != nil {
+					add(SettingSource, false, i18n.Sprintf("要备份的文件不存在: %s", file))
+				}
+			}
+		}
+	}
+
+	// 目标文件夹：WebDAV 和 S3 检查地址和密码，本地文件夹不存在时备份时会创建，只检查所在的驱动器
+	switch {
+	case c.DestinationPath == "":
+		add(SettingDestination, true, i18n.T("没有选择目标文件夹"))
+	case storage.IsWebDAV(c.DestinationPath):
+		if _, err := url.Parse(c.DestinationPath); err != nil {
+			add(SettingWebDAV, true, i18n.Sprintf("WebDAV 地址无效: %v", err))
+		}
+		if c.WebDAV.Username != "" && c.WebDAV.Password == "" {
+			add(SettingWebDAV, false, i18n.T("没有填写 WebDAV 密码，密码只保存在本机，换电脑后需要重新填写"))
+		}
+	case storage.IsS3(c.DestinationPath):
+		if err := storage.NewS3(c.DestinationPath, c.S3).Err(); err != nil {
+			add(SettingS3, true, err.Error())
+		}
+		if c.S3.AccessKey != "" && c.S3.SecretKey == "" {
+			add(SettingS3, false, i18n.T("没有填写 S3 私有访问密钥，密钥只保存在本机，换电脑后需要重新填写"))
+		}
+	default:
+		info, err := os.Stat(c.DestinationPath)
+		switch {
+		case os.IsNotExist(err):
+			// 外接硬盘或网络驱动器没有连接时驱动器本身不存在
+			if volume := filepath.VolumeName(c.DestinationPath); volume != "" {
+				if _, err := os.Stat(volume + string(filepath.Separator)); err != nil {
+					add(SettingDestination, true, i18n.Sprintf("目标文件夹所在的驱动器 %s 不存在，外接硬盘或网络驱动器是否已连接？", volume))
+				}
+			}
+		case err != nil:
+			add(SettingDestination, true, i18n.Sprintf("目标文件夹无法访问: %v", err))
+		case !info.IsDir():
+			add(SettingDestination, true, i18n.Sprintf("%s 不是文件夹", c.DestinationPath))
+		}
+	}
+
+	if c.Encryption.Enabled && c.Encryption.Passphrase == "" {
+		add(SettingEncryption, true, i18n.T("已启用加密，但没有填写密码短语。密码短语只保存在本机，换电脑后需要重新填写"))
+	}
+	switch c.ArchiveFormat {
+	case "", storage.ArchiveTarGz, storage.ArchiveTarGzAge, storage.ArchiveZip:
+	default:
+		add(SettingArchive, true, i18n.Sprintf("不支持的归档格式: %s", c.ArchiveFormat))
+	}
+	if c.SyncPath != "" {
+		if _, err := os.Stat(c.SyncPath); err != nil {
+			add(SettingSync, true, i18n.Sprintf("同步文件夹不存在或无法访问: %v", err))
+		}
+	}
+
+	// 以下问题不影响备份本身
+	if _, err := ignore.Compile(c.FilterRules); err != nil {
+		add(SettingFilter, false, i18n.Sprintf("%v，该规则会被跳过", err))
+	}
+	if c.Schedule != "" {
+		if _, err := schedule.Parse(c.Schedule); err != nil {
+			add(SettingSchedule, false, i18n.Sprintf("定时备份表达式无效，不会定时备份: %v", err))
+		}
+	}
+	if _, err := LoadTimeZone(c.TimeZone); err != nil {
+		add(SettingSchedule, false, i18n.Sprintf("%v，定时计划按本机时区运行", err))
+	}
+	if c.ScrubSchedule != "" {
+		if _, err := schedule.Parse(c.ScrubSchedule); err != nil {
+			add(SettingSchedule, false, i18n.Sprintf("数据巡检表达式无效，不会定期巡检: %v", err))
+		}
+	}
+
+	if c.Git.Enabled {
+		if c.Git.RepoURL == "" {
+			add(SettingGit, false, i18n.T("没有填写仓库地址，只提交到本地仓库"))
+		} else if strings.HasPrefix(c.Git.RepoURL, "https://") && c.Git.AccessToken == "" {
+			add(SettingGit, false, i18n.T("没有填写访问令牌，推送到 HTTPS 仓库可能失败。令牌只保存在本机"))
+		}
+		if c.Git.Schedule != "" {
+			if _, err := schedule.Parse(c.Git.Schedule); err != nil {
+				add(SettingGit, false, i18n.Sprintf("批量提交计划无效，每次备份都会提交: %v", err))
+			}
+		}
+	}
+
+	if c.Notify.Enabled {
+		switch c.Notify.Service {
+		case notify.ServiceGotify:
+			if c.Notify.Server == "" || c.Notify.Token == "" {
+				add(SettingNotify, false, i18n.T("Gotify 需要填写服务器地址和应用令牌"))
+			}
+		default:
+			if c.Notify.Topic == "" {
+				add(SettingNotify, false, i18n.T("没有填写 ntfy 主题"))
+			}
+		}
+	}
+	if c.Email.Enabled {
+		if err := c.Email.Validate(); err != nil {
+			add(SettingEmail, false, err.Error())
+		} else if c.Email.Username != "" && c.Email.Password == "" {
+			add(SettingEmail, false, i18n.T("没有填写邮箱密码，密码只保存在本机"))
+		}
+	}
+	for _, webhook := range c.Webhooks {
+		if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(SettingWebhooks, false, i18n.Sprintf("Webhook 地址无效: %s", webhook.URL))
+		}
+	}
+	if c.Peer.Enabled {
+		if c.Peer.Address == "" {
+			add(SettingPeer, false, i18n.T("没有填写接收端地址"))
+		}
+		if c.Peer.Code == "" {
+			add(SettingPeer, false, i18n.T("没有填写配对码，配对码只保存在本机"))
+		} else if !peer.CompleteCode(c.Peer.Code) {
+			add(SettingPeer, false, i18n.T("配对码不完整，请输入接收端显示的完整配对码"))
+		}
+	}
+	if c.InteropLayout != "" {
+		if _, ok := LayoutLabels[c.InteropLayout]; !ok {
+			add(SettingInterop, false, i18n.Sprintf("不支持的导出布局: %s", c.InteropLayout))
+		} else if c.InteropTarget == "" {
+			add(SettingInterop, false, i18n.T("没有选择导出目录"))
+		}
+	}
+	for _, replica := range c.Replicas {
+		switch {
+		case replica.Disabled:
+		case replica.Path == "":
+			add(SettingReplicas, false, i18n.T("有附加目标没有填写路径"))
+		case filepath.Clean(replica.Path) == filepath.Clean(c.DestinationPath):
+			add(SettingReplicas, false, i18n.Sprintf("附加目标与目标文件夹相同: %s", replica.Path))
+		case storage.IsS3(replica.Path):
+			add(SettingReplicas, false, i18n.Sprintf("附加目标不支持 S3: %s", replica.Path))
+		case replica.ArchiveFormat != "" && storage.IsRemote(replica.Path):
+			add(SettingReplicas, false, i18n.Sprintf("归档模式只支持本地目标文件夹: %s", replica.Path))
+		case replica.Encrypt && c.Encryption.Passphrase == "":
+			add(SettingReplicas, false, i18n.Sprintf("加密附加目标 %s 需要先在加密设置中填写密码短语", replica.Path))
+		}
+	}
+	return append(blocking, warnings...)
+}
+
+// 只检查阻止备份的问题，有问题时返回 *ProblemsError
+func (c *Config) ValidateForBackup() error {
+	var blocking []Problem
+	for _, problem := range c.Validate() {
+		if problem.Blocking {
+			blocking = append(blocking, problem)
+		}
+	}
+	if len(blocking) == 0 {
+		return nil
+	}
+	return &ProblemsError{Problems: blocking}
+}
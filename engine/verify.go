@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"syncsafe/crypt"
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 快照校验结果
+type VerifyResult struct {
+	Files   int      // 完整读出的文件数
+	Bytes   int64    // 读出的明文字节数
+	Damaged []string // 无法读取、解密失败或大小与清单不符的文件及原因
+	Missing []string // 清单中有但快照中找不到的文件
+}
+
+// 快照是否完好
+func (r VerifyResult) OK() bool {
+	return len(r.Damaged) == 0 && len(r.Missing) == 0
+}
+
+// 结果的说明，用于界面和命令行显示
+func (r VerifyResult) String() string {
+	if r.OK() {
+		return i18n.Sprintf("快照完好：%d 个文件、%.2f MB 均可读出", r.Files, float64(r.Bytes)/(1024*1024))
+	}
+	return i18n.Sprintf("快照有问题：%d 个文件完好，%d 个文件损坏，%d 个文件缺失", r.Files, len(r.Damaged), len(r.Missing))
+}
+
+// 像还原一样逐个读出快照中的文件，但不写入任何地方：加密的快照完整解密一遍，
+// 认证失败说明密码短语错误或内容被篡改；再与快照清单核对文件列表和大小。
+// 快照整体无法读取（例如归档损坏）时返回错误
+func (e *Engine) VerifySnapshot(record history.Record) (VerifyResult, error) {
+	var result VerifyResult
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return result, err
+	}
+
+	// 清单中的文件大小，读出的文件从中删除，剩下的就是缺失的文件
+	expected := make(map[string]int64)
+	if manifest := openSnapshotManifest(record); manifest != nil {
+		for {
+			entry, ok, err := manifest.Next()
+			if err != nil || !ok {
+				break
+			}
+			if !entry.IsDir {
+				expected[entry.RelPath] = entry.Size
+			}
+		}
+		manifest.Close()
+	}
+
+	err = e.walkRestore(record.DestPath, []string{"."}, func(relPath string, info os.FileInfo, content io.Reader) error {
+		plain, err := plainRelPath(key, record, relPath)
+		if err != nil {
+			result.Damaged = append(result.Damaged, i18n.Sprintf("%s: 无法解密文件名", relPath))
+			return nil
+		}
+		size, _, err := readSnapshotFile(key, filepath.Join(record.DestPath, relPath), content)
+		if err != nil {
+			result.Damaged = append(result.Damaged, fmt.Sprintf("%s: %v", plain, err))
+			return nil
+		}
+		if want, ok := expected[plain]; ok && want != size {
+			result.Damaged = append(result.Damaged, i18n.Sprintf("%s: 大小为 %d 字节，清单中为 %d 字节", plain, size, want))
+			delete(expected, plain)
+			return nil
+		}
+		delete(expected, plain)
+		result.Files++
+		result.Bytes += size
+		if result.Files%100 == 0 {
+			e.status(i18n.Sprintf("已校验 %d 个文件", result.Files))
+		}
+		return nil
+	})
+	if err != nil {
+		return result, i18n.Errorf("校验快照失败: %v", err)
+	}
+	for relPath := range expected {
+		result.Missing = append(result.Missing, relPath)
+	}
+	sort.Strings(result.Missing)
+	return result, nil
+}
+
+// 读完快照中的一个文件并丢弃，返回明文大小和 SHA-256。content 为 nil 时读取 path
+func readSnapshotFile(key *crypt.Key, path string, content io.Reader) (int64, string, error) {
+	if content == nil {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer file.Close()
+		content = file
+	}
+	counter := &byteCounter{}
+	hash := sha256.New()
+	w := io.MultiWriter(counter, hash)
+	var err error
+	if key != nil {
+		err = key.Decrypt(w, content)
+	} else {
+		_, err = io.Copy(w, content)
+	}
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), err
+}
+
+// 备份完成后的校验：重新读出快照中的每个文件（加密的快照解密），与源文件的 SHA-256 比较，
+// 返回内容不一致、无法读取或缺失的文件。备份后又被修改或无法读取的源文件不参与比较，
+// 不计算哈希的目录（快速模式）中的文件只检查是否存在
+func (e *Engine) verifyBackup(source string, record history.Record) ([]ReviewItem, error) {
+	key, err := e.snapshotKey(record)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[string]ManifestEntry)
+	manifest := openSnapshotManifest(record)
+	if manifest == nil {
+		return nil, i18n.Errorf("找不到快照清单")
+	}
+	for {
+		entry, ok, err := manifest.Next()
+		if err != nil {
+			manifest.Close()
+			return nil, i18n.Errorf("读取快照清单失败: %v", err)
+		}
+		if !ok {
+			break
+		}
+		if !entry.IsDir {
+			expected[entry.RelPath] = entry
+		}
+	}
+	manifest.Close()
+
+	var mismatches []ReviewItem
+	mismatch := func(relPath, message string) {
+		mismatches = append(mismatches, ReviewItem{Kind: IssueMismatch, Path: relPath, Message: message})
+	}
+	checked := 0
+	err = e.walkRestore(record.DestPath, []string{"."}, func(relPath string, info os.FileInfo, content io.Reader) error {
+		plain, err := plainRelPath(key, record, relPath)
+		if err != nil {
+			mismatch(relPath, i18n.T("无法解密文件名"))
+			return nil
+		}
+		entry, ok := expected[plain]
+		if !ok {
+			return nil
+		}
+		delete(expected, plain)
+		if !e.Config.hashEnabled(plain) {
+			return nil
+		}
+		_, hash, err := readSnapshotFile(key, filepath.Join(record.DestPath, relPath), content)
+		if err != nil {
+			mismatch(plain, i18n.Sprintf("无法读取: %v", err))
+			return nil
+		}
+		sourcePath := filepath.Join(source, plain)
+		if sourceInfo, err := os.Stat(sourcePath); err != nil || sourceInfo.Size() != entry.Size || !sourceInfo.ModTime().Equal(entry.ModTime) {
+			return nil
+		}
+		sourceHash, err := fileHash(sourcePath)
+		if err != nil {
+			return nil
+		}
+		if hash != sourceHash {
+			mismatch(plain, i18n.T("内容与源文件不一致"))
+		}
+		if checked++; checked%100 == 0 {
+			e.status(i18n.Sprintf("校验备份：已比较 %d 个文件", checked))
+		}
+		return nil
+	})
+	if err != nil {
+		return mismatches, err
+	}
+	for relPath := range expected {
+		mismatch(relPath, i18n.T("快照中缺失"))
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Path < mismatches[j].Path })
+	return mismatches, nil
+}
+
+// 只统计写入字节数的 io.Writer
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
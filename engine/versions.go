@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 版本库中文件名的时间后缀格式：<相对路径>~<修改时间>
+const versionStampFormat = "20060102-150405"
+
+// 一个文件的某个版本，修改时间精确到秒
+type fileVersion struct {
+	size    int64
+	modTime int64
+}
+
+// 一个版本保存在哪些地方
+type versionCopies struct {
+	kept   bool   // 在保留的快照中
+	pruned string // 在即将删除的快照中（快照目录）
+	stored string // 在版本库中（完整路径）
+}
+
+// 源文件夹的版本库：清理快照时，每个文件最近的 KeepVersions 个版本如果只存在于
+// 被删除的快照中，会先复制到这里
+func versionsDir(record history.Record) string {
+	name := strings.ReplaceAll(filepath.Base(record.SourcePath), " ", "_") + "-versions"
+	return filepath.Join(filepath.Dir(record.DestPath), name)
+}
+
+// 在删除快照之前按 Config.KeepVersions 保留每个文件最近的几个版本，
+// 并删除版本库中已经超出数量的旧版本
+func (e *Engine) keepVersions(snapshots []history.Record) error {
+	pruning := make(map[string]bool)
+	var sources []history.Record
+	seen := make(map[string]bool)
+	for _, record := range snapshots {
+		pruning[record.DestPath] = true
+		if !seen[record.SourcePath] {
+			seen[record.SourcePath] = true
+			sources = append(sources, record)
+		}
+	}
+	for _, record := range sources {
+		if err := e.keepSourceVersions(record.SourcePath, versionsDir(record), pruning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Engine) keepSourceVersions(source, store string, pruning map[string]bool) error {
+	versions := make(map[string]map[fileVersion]*versionCopies)
+	copiesOf := func(relPath string, version fileVersion) *versionCopies {
+		if versions[relPath] == nil {
+			versions[relPath] = make(map[fileVersion]*versionCopies)
+		}
+		copies := versions[relPath][version]
+		if copies == nil {
+			copies = &versionCopies{}
+			versions[relPath][version] = copies
+		}
+		return copies
+	}
+
+	// 所有快照清单中出现过的版本
+	for _, record := range e.Config.History {
+		if record.SourcePath != source || !record.HasSnapshot() {
+			continue
+		}
+		reader := openSnapshotManifest(record)
+		if reader == nil {
+			continue
+		}
+		for {
+			entry, ok, err := reader.Next()
+			if err != nil {
+				reader.Close()
+				return i18n.Errorf("读取快照清单失败: %v\n快照: %s", err, record.DestPath)
+			}
+			if !ok {
+				break
+			}
+			if entry.IsDir {
+				continue
+			}
+			copies := copiesOf(entry.RelPath, fileVersion{entry.Size, entry.ModTime.Unix()})
+			if !pruning[record.DestPath] {
+				copies.kept = true
+			} else if copies.pruned == "" && !IsArchiveSnapshot(record.DestPath) && !record.Encrypted {
+				// 归档和加密的快照中不能直接复制出原始文件
+				copies.pruned = record.DestPath
+			}
+		}
+		reader.Close()
+	}
+
+	// 版本库中已有的版本
+	dest := e.Destination()
+	if lister, ok := dest.(storage.Lister); ok {
+		stored, err := lister.List(store)
+		if err != nil {
+			return i18n.Errorf("读取版本库失败: %v\n目录: %s", err, store)
+		}
+		for name, file := range stored {
+			i := strings.LastIndex(name, "~")
+			if i < 0 {
+				continue
+			}
+			copiesOf(name[:i], fileVersion{file.Size, file.ModTime.Unix()}).stored = filepath.Join(store, name)
+		}
+	}
+
+	// 按修改时间从新到旧，前 KeepVersions 个版本必须保留
+	for relPath, byVersion := range versions {
+		list := make([]fileVersion, 0, len(byVersion))
+		for version := range byVersion {
+			list = append(list, version)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].modTime > list[j].modTime })
+
+		for i, version := range list {
+			copies := byVersion[version]
+			if i >= e.Config.KeepVersions {
+				if copies.stored != "" && !e.Simulated("从版本库删除旧版本 %s", copies.stored) {
+					if err := dest.RemoveAll(copies.stored); err != nil {
+						return i18n.Errorf("删除旧版本失败: %v\n文件: %s", err, copies.stored)
+					}
+				}
+				continue
+			}
+			if copies.kept || copies.stored != "" || copies.pruned == "" {
+				continue
+			}
+			src := filepath.Join(copies.pruned, relPath)
+			dst := filepath.Join(store, relPath+"~"+time.Unix(version.modTime, 0).Format(versionStampFormat))
+			if e.Simulated("保留文件版本 %s", dst) {
+				continue
+			}
+			if err := dest.CopyFile(context.Background(), src, dst); err != nil {
+				return i18n.Errorf("保留文件版本失败: %v\n文件: %s", err, src)
+			}
+		}
+	}
+	return nil
+}
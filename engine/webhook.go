@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+	"syncsafe/storage"
+)
+
+// 发送失败的 Webhook 最多尝试的次数，超过后放弃
+const webhookMaxAttempts = 10
+
+// 重试间隔从 webhookRetryBase 开始每次加倍，最长 webhookRetryMax
+const (
+	webhookRetryBase = time.Minute
+	webhookRetryMax  = time.Hour
+)
+
+// 后台检查重试队列的间隔
+const webhookRetryInterval = time.Minute
+
+// 重试队列中的一次发送。队列保存在数据目录中，程序退出后下次启动继续重试
+type webhookDelivery struct {
+	URL       string
+	Payload   json.RawMessage
+	Created   time.Time
+	Attempts  int
+	NextTry   time.Time
+	LastError string
+}
+
+// 保护重试队列文件，发送和重试不会同时修改队列
+var webhookMutex sync.Mutex
+
+var webhookRetriesOnce sync.Once
+
+func webhookQueuePath() string {
+	return filepath.Join(DataDir, "webhook-queue.json")
+}
+
+func loadWebhookQueue() []webhookDelivery {
+	var queue []webhookDelivery
+	if data, err := os.ReadFile(webhookQueuePath()); err == nil {
+		if err := json.Unmarshal(data, &queue); err != nil {
+			slog.Warn("读取 Webhook 重试队列失败", "err", err)
+		}
+	}
+	return queue
+}
+
+// 队列中的地址通常含有密钥，只有当前用户可以读取
+func saveWebhookQueue(queue []webhookDelivery) error {
+	if len(queue) == 0 {
+		if err := os.Remove(webhookQueuePath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(DataDir, 0755); err != nil {
+		return i18n.Errorf("创建数据目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return i18n.Errorf("序列化 Webhook 重试队列失败: %v", err)
+	}
+	return storage.WriteFileAtomic(webhookQueuePath(), data, 0600)
+}
+
+// 第 attempts 次失败后等待的时间
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+// 日志中只显示 Webhook 的主机名，地址的路径和参数通常是密钥
+func webhookHost(webhookURL string) string {
+	if u, err := url.Parse(webhookURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return i18n.T("无效地址")
+}
+
+// 备份记录对应的 Webhook 事件
+func BackupWebhookEvent(config *Config, record history.Record) notify.WebhookEvent {
+	ev := notify.WebhookEvent{
+		Event:         notify.EventSucceeded,
+		Profile:       config.ProfileName(),
+		Machine:       machineID(),
+		Source:        record.SourcePath,
+		Destination:   config.DestinationPath,
+		Time:          record.Timestamp.In(config.Location()),
+		Success:       record.Success,
+		DryRun:        record.DryRun,
+		Files:         record.FileCount,
+		NewFiles:      record.NewFiles,
+		ModifiedFiles: record.ModifiedFiles,
+		DeletedFiles:  record.DeletedFiles,
+		FailedFiles:   record.FailedFiles,
+		Size:          record.TotalSize,
+		Duration:      record.Duration.Seconds(),
+		Attempt:       record.Attempt,
+		Note:          record.Note,
+		Error:         record.ErrorMessage,
+	}
+	switch {
+	case record.Cancelled:
+		ev.Event = notify.EventCancelled
+	case !record.Success:
+		ev.Event = notify.EventFailed
+	}
+	if record.HasSnapshot() {
+		ev.Snapshot = filepath.Base(record.DestPath)
+	}
+	return ev
+}
+
+// 把备份记录发送到所有 Webhook。网络中断或服务器暂时不可用的发送加入重试队列，
+// 稍后由 RetryWebhooks 或后台重试发送；被服务器拒绝的发送返回错误
+func SendWebhooks(config *Config, record history.Record) error {
+	ev := BackupWebhookEvent(config, record)
+	var errs []error
+	for _, hook := range config.Webhooks {
+		if !hook.Accepts(ev) {
+			continue
+		}
+		payload, err := notify.WebhookPayload(hook, ev)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		err = notify.PostWebhook(hook.URL, payload)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, notify.ErrWebhookRejected) {
+			errs = append(errs, fmt.Errorf("%s: %w", webhookHost(hook.URL), err))
+			continue
+		}
+		slog.Warn("发送 Webhook 失败，稍后重试", "host", webhookHost(hook.URL), "err", err)
+		if err := queueWebhook(hook.URL, payload, err); err != nil {
+			errs = append(errs, i18n.Errorf("保存 Webhook 重试队列失败: %v", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func queueWebhook(webhookURL string, payload []byte, sendErr error) error {
+	webhookMutex.Lock()
+	defer webhookMutex.Unlock()
+	now := time.Now()
+	queue := append(loadWebhookQueue(), webhookDelivery{
+		URL:       webhookURL,
+		Payload:   payload,
+		Created:   now,
+		Attempts:  1,
+		NextTry:   now.Add(webhookBackoff(1)),
+		LastError: sendErr.Error(),
+	})
+	return saveWebhookQueue(queue)
+}
+
+// 在后台定期重试队列中到了重试时间的 Webhook，多次调用只启动一次
+func StartWebhookRetries() {
+	webhookRetriesOnce.Do(func() {
+		go func() {
+			retryWebhooks(false)
+			for range time.Tick(webhookRetryInterval) {
+				retryWebhooks(false)
+			}
+		}()
+	})
+}
+
+// 在后台发送备份记录的 Webhook，失败只记录日志
+func (e *Engine) SendWebhooks(record history.Record) {
+	if len(e.Config.Webhooks) == 0 {
+		return
+	}
+	StartWebhookRetries()
+	config := *e.Config
+	go func() {
+		if err := SendWebhooks(&config, record); err != nil {
+			slog.Warn("发送 Webhook 失败", "err", err)
+		}
+	}()
+}
+
+// 立即重试队列中所有等待发送的 Webhook，返回发送成功和仍在等待的数量
+func RetryWebhooks() (sent, pending int) {
+	return retryWebhooks(true)
+}
+
+// 重试队列中的 Webhook，all 为 false 时只重试到了重试时间的。
+// 被拒绝或超过最大尝试次数的发送从队列中删除
+func retryWebhooks(all bool) (sent, pending int) {
+	webhookMutex.Lock()
+	defer webhookMutex.Unlock()
+	queue := loadWebhookQueue()
+	if len(queue) == 0 {
+		return 0, 0
+	}
+	now := time.Now()
+	var remaining []webhookDelivery
+	changed := false
+	for _, d := range queue {
+		if !all && now.Before(d.NextTry) {
+			remaining = append(remaining, d)
+			continue
+		}
+		changed = true
+		err := notify.PostWebhook(d.URL, d.Payload)
+		if err == nil {
+			sent++
+			continue
+		}
+		d.Attempts++
+		d.LastError = err.Error()
+		if errors.Is(err, notify.ErrWebhookRejected) || d.Attempts >= webhookMaxAttempts {
+			slog.Error("放弃发送 Webhook", "created", d.Created.Format("2006-01-02 15:04:05"),
+				"host", webhookHost(d.URL), "attempts", d.Attempts, "err", err)
+			continue
+		}
+		d.NextTry = now.Add(webhookBackoff(d.Attempts))
+		remaining = append(remaining, d)
+	}
+	if changed {
+		if err := saveWebhookQueue(remaining); err != nil {
+			slog.Warn("保存 Webhook 重试队列失败", "err", err)
+		}
+	}
+	return sent, len(remaining)
+}
+
+// 重试队列中等待发送的数量
+func PendingWebhooks() int {
+	webhookMutex.Lock()
+	defer webhookMutex.Unlock()
+	return len(loadWebhookQueue())
+}
@@ -0,0 +1,81 @@
+package faults
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"syncsafe/storage"
+)
+
+// 在写入操作中注入磁盘已满和权限不足的存储后端
+type backend struct {
+	storage.Backend
+}
+
+// 同时支持列出文件的后端，保留快速同步的能力
+type listingBackend struct {
+	backend
+	storage.Lister
+}
+
+// 启用故障注入时包装存储后端，否则原样返回
+func WrapBackend(b storage.Backend) storage.Backend {
+	if !Enabled() {
+		return b
+	}
+	if lister, ok := b.(storage.Lister); ok {
+		return listingBackend{backend{b}, lister}
+	}
+	return backend{b}
+}
+
+func (b backend) MkdirAll(path string, perm os.FileMode) error {
+	if err := Inject(PermissionDenied, path); err != nil {
+		return err
+	}
+	return b.Backend.MkdirAll(path, perm)
+}
+
+func (b backend) CopyFile(ctx context.Context, src, dst string) error {
+	if err := Inject(DiskFull, dst); err != nil {
+		return err
+	}
+	if err := Inject(PermissionDenied, dst); err != nil {
+		return err
+	}
+	if err := b.Backend.CopyFile(ctx, src, dst); err != nil {
+		return err
+	}
+	// 静默损坏不返回错误，只有校验才能发现
+	if Inject(Corrupt, dst) != nil {
+		corruptFile(dst)
+	}
+	return nil
+}
+
+// 翻转文件的第一个字节，保留修改时间
+func corruptFile(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	b := make([]byte, 1)
+	if _, err := file.ReadAt(b, 0); err == nil {
+		b[0] ^= 0xff
+		file.WriteAt(b, 0)
+	}
+	file.Close()
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+func (b backend) Link(existing, dst string, size int64, modTime time.Time) error {
+	if err := Inject(DiskFull, dst); err != nil {
+		return err
+	}
+	return b.Backend.Link(existing, dst, size, modTime)
+}
@@ -0,0 +1,143 @@
+// Package faults 是面向开发者的故障注入：按设置的概率模拟磁盘已满、权限不足、
+// 网络中断、Git 推送被拒绝和写入的内容静默损坏，用来验证备份、导出、校验和清理流程在真实故障下的表现。
+//
+// 通过环境变量启用，概率为 0 到 1 之间的小数：
+//
+//	SYNCSAFE_FAULTS="disk-full=0.01,permission=0.01,network=0.5,git-push=1"
+package faults
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// 启用故障注入的环境变量
+const EnvVar = "SYNCSAFE_FAULTS"
+
+// 故障类型
+type Kind string
+
+const (
+	DiskFull         Kind = "disk-full"  // 写入目标时磁盘已满
+	PermissionDenied Kind = "permission" // 创建目录或写入文件时权限不足
+	NetworkDrop      Kind = "network"    // 推送通知或 Git 推送时网络中断
+	GitPushRejected  Kind = "git-push"   // Git 推送被远程拒绝
+	Corrupt          Kind = "corrupt"    // 写入目标的文件内容静默损坏
+)
+
+// 所有故障类型
+var Kinds = []Kind{DiskFull, PermissionDenied, NetworkDrop, GitPushRejected, Corrupt}
+
+var (
+	mu    sync.Mutex
+	rates map[Kind]float64
+)
+
+func init() {
+	spec := os.Getenv(EnvVar)
+	if spec == "" {
+		return
+	}
+	if err := Configure(spec); err != nil {
+		slog.Warn("故障注入设置无效", "err", err)
+		return
+	}
+	slog.Warn("故障注入已启用", "spec", spec)
+}
+
+// 按 "类型=概率,..." 的格式设置故障概率，替换之前的设置，空字符串表示关闭
+func Configure(spec string) error {
+	parsed := make(map[Kind]float64)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("缺少概率: %q", item)
+		}
+		kind := Kind(strings.TrimSpace(name))
+		if !known(kind) {
+			return fmt.Errorf("未知的故障类型 %q，可选: %v", kind, Kinds)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return fmt.Errorf("概率必须在 0 到 1 之间: %q", item)
+		}
+		parsed[kind] = rate
+	}
+	Set(parsed)
+	return nil
+}
+
+// 设置故障概率，nil 表示关闭
+func Set(r map[Kind]float64) {
+	mu.Lock()
+	defer mu.Unlock()
+	rates = r
+}
+
+// 是否启用了任何故障
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, rate := range rates {
+		if rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 当前设置，格式与 Configure 相同
+func String() string {
+	mu.Lock()
+	defer mu.Unlock()
+	parts := make([]string, 0, len(rates))
+	for kind, rate := range rates {
+		parts = append(parts, fmt.Sprintf("%s=%g", kind, rate))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func known(kind Kind) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// 按概率决定是否注入故障，注入时返回与真实故障相同类型的错误，否则返回 nil。
+// target 为出错的文件路径或地址
+func Inject(kind Kind, target string) error {
+	mu.Lock()
+	rate := rates[kind]
+	mu.Unlock()
+	if rate <= 0 || rand.Float64() >= rate {
+		return nil
+	}
+	switch kind {
+	case DiskFull:
+		return &os.PathError{Op: "write", Path: target, Err: syscall.ENOSPC}
+	case PermissionDenied:
+		return &os.PathError{Op: "open", Path: target, Err: os.ErrPermission}
+	case NetworkDrop:
+		return fmt.Errorf("故障注入: 连接 %s 时网络中断", target)
+	case GitPushRejected:
+		return fmt.Errorf("故障注入: ! [rejected] master -> master (fetch first)\n推送到 %s 被拒绝", target)
+	case Corrupt:
+		return fmt.Errorf("故障注入: %s 的内容已损坏", target)
+	}
+	return nil
+}
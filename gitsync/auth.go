@@ -0,0 +1,55 @@
+package gitsync
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// 用访问令牌认证 HTTPS 请求。令牌只在请求头中发送，
+// 不会出现在仓库配置和远程地址中，没有令牌或不是 HTTPS 地址时返回 nil
+func (c Config) auth() transport.AuthMethod {
+	if c.AccessToken == "" {
+		return nil
+	}
+	if endpoint, err := transport.NewEndpoint(c.RepoURL); err != nil || (endpoint.Protocol != "https" && endpoint.Protocol != "http") {
+		return nil
+	}
+	// GitHub 接受任意用户名加令牌，Gitee 需要账号用户名
+	user := "x-access-token"
+	if strings.EqualFold(c.Platform, "gitee") && c.UserName != "" {
+		user = c.UserName
+	}
+	return &githttp.BasicAuth{Username: user, Password: c.AccessToken}
+}
+
+// 把远程返回的进度信息逐行写入 Output，没有设置 Output 时返回 nil
+func (r *Repo) progress() io.Writer {
+	if r.Output == nil {
+		return nil
+	}
+	return &lineWriter{output: r.Output}
+}
+
+// 按行切分写入的内容，进度信息以 \r 或 \n 结尾
+type lineWriter struct {
+	output  func(line string)
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexAny(w.pending, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		if line := strings.TrimSpace(string(w.pending[:i])); line != "" {
+			w.output(line)
+		}
+		w.pending = w.pending[i+1:]
+	}
+}
@@ -0,0 +1,194 @@
+// Package gitsync 把源文件夹提交并推送到 Git 仓库，并提供仓库诊断和修复操作。
+// 仓库操作通过 go-git 在进程内完成，不需要安装 git 命令
+package gitsync
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+
+	"syncsafe/faults"
+	"syncsafe/i18n"
+)
+
+// 本地路径的远程仓库（如 NAS 上的裸仓库）同样在进程内推送，
+// go-git 默认的 file 传输会调用 git 命令
+func init() {
+	client.InstallProtocol("file", server.DefaultServer)
+}
+
+// 备份使用的分支
+const branch = plumbing.ReferenceName("refs/heads/master")
+
+// Git 备份设置
+type Config struct {
+	Platform    string // "gitee" 或 "github"
+	RepoURL     string
+	AccessToken string
+	UserName    string
+	UserEmail   string
+	Enabled     bool
+	Schedule    string // 批量提交的 cron 表达式，为空表示每次备份都提交并推送
+}
+
+// 源文件夹对应的 Git 仓库
+type Repo struct {
+	Dir    string
+	Config Config
+	// 推送时远程返回的进度信息，可以为 nil
+	Output func(line string)
+	// 模拟模式下记录操作并返回 true，调用方应跳过实际执行；可以为 nil
+	Simulated func(format string, args ...interface{}) bool
+	// 进度提示，可以为 nil
+	Status func(message string)
+}
+
+func (r *Repo) simulated(format string, args ...interface{}) bool {
+	return r.Simulated != nil && r.Simulated(format, args...)
+}
+
+func (r *Repo) status(message string) {
+	if r.Status != nil {
+		r.Status(message)
+	}
+}
+
+// 故障注入：推送时网络中断或被远程拒绝
+func injectPushFault(remote string) error {
+	if err := faults.Inject(faults.NetworkDrop, remote); err != nil {
+		return err
+	}
+	return faults.Inject(faults.GitPushRejected, remote)
+}
+
+// 初始化 Git 仓库，已经是仓库时不做任何操作
+func (r *Repo) Init() error {
+	if r.Config.RepoURL == "" {
+		return i18n.Errorf("Git 仓库地址不能为空")
+	}
+
+	if r.Config.UserName == "" || r.Config.UserEmail == "" {
+		return i18n.Errorf("请先设置 Git 用户名和邮箱")
+	}
+
+	// 检查是否已经是 Git 仓库
+	if _, err := git.PlainOpen(r.Dir); err == nil {
+		return nil
+	}
+	if r.simulated("git init %s", r.Dir) {
+		return nil
+	}
+
+	repo, err := git.PlainInitWithOptions(r.Dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: branch},
+	})
+	if err != nil {
+		return i18n.Errorf("初始化 Git 仓库失败: %v", err)
+	}
+
+	// 配置 Git 用户信息和远程仓库
+	cfg, err := repo.Config()
+	if err != nil {
+		return i18n.Errorf("Git 配置失败: %v", err)
+	}
+	cfg.User.Name = r.Config.UserName
+	cfg.User.Email = r.Config.UserEmail
+	cfg.Init.DefaultBranch = branch.Short()
+	cfg.Remotes["origin"] = &gitconfig.RemoteConfig{
+		Name:  "origin",
+		URLs:  []string{r.Config.RepoURL},
+		Fetch: []gitconfig.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+	}
+	cfg.Branches[branch.Short()] = &gitconfig.Branch{Name: branch.Short(), Remote: "origin", Merge: branch}
+	if err := repo.SetConfig(cfg); err != nil {
+		return i18n.Errorf("Git 配置失败: %v", err)
+	}
+	return nil
+}
+
+// 提交工作区的所有变更，有远程仓库时推送
+func (r *Repo) Backup() error {
+	return r.Commit(i18n.Sprintf("自动备份 - %s", time.Now().Format("2006-01-02 15:04:05")))
+}
+
+// 以 message 提交工作区的所有变更，有远程仓库时推送
+func (r *Repo) Commit(message string) error {
+	repo, err := git.PlainOpen(r.Dir)
+	if err != nil {
+		return i18n.Errorf("打开 Git 仓库失败: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return i18n.Errorf("打开 Git 仓库失败: %v", err)
+	}
+
+	// 检查是否有变更，没有变更时直接返回
+	status, err := worktree.Status()
+	if err != nil {
+		return i18n.Errorf("检查 Git 状态失败: %v", err)
+	}
+	if status.IsClean() {
+		r.status(i18n.T("没有需要提交的更改"))
+		return nil
+	}
+
+	if !r.simulated("git add --all") {
+		if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+			return i18n.Errorf("%s 失败: %v", "add", err)
+		}
+	}
+	r.status(i18n.Sprintf("Git %s 成功", "add"))
+
+	if !r.simulated("git commit -m %q", message) {
+		if err := r.commit(worktree, message); err != nil {
+			return i18n.Errorf("%s 失败: %v", "commit", err)
+		}
+	}
+	r.status(i18n.Sprintf("Git %s 成功", "commit"))
+
+	// 有远程仓库时推送
+	if _, err := repo.Remote("origin"); err != nil {
+		return nil
+	}
+	if err := r.push(repo); err != nil {
+		return err
+	}
+	r.status(i18n.Sprintf("Git %s 成功", "push"))
+	return nil
+}
+
+// 以配置的用户提交已暂存的变更
+func (r *Repo) commit(worktree *git.Worktree, message string) error {
+	options := &git.CommitOptions{}
+	if r.Config.UserName != "" && r.Config.UserEmail != "" {
+		options.Author = &object.Signature{Name: r.Config.UserName, Email: r.Config.UserEmail, When: time.Now()}
+	}
+	_, err := worktree.Commit(message, options)
+	return err
+}
+
+// 把 master 分支推送到 origin，使用访问令牌认证
+func (r *Repo) push(repo *git.Repository) error {
+	if r.simulated("git push origin master") {
+		return nil
+	}
+	if err := injectPushFault(r.Config.RepoURL); err != nil {
+		return i18n.Errorf("push 失败: %v", err)
+	}
+	err := repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(branch + ":" + branch)},
+		Auth:       r.Config.auth(),
+		Progress:   r.progress(),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return i18n.Errorf("push 失败: %v", err)
+	}
+	return nil
+}
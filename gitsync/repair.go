@@ -0,0 +1,302 @@
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"syncsafe/i18n"
+)
+
+// Git 仓库状态诊断结果
+type Diagnosis struct {
+	NotRepo      bool
+	Detached     bool
+	Merging      bool
+	Rebasing     bool
+	Conflicts    []string
+	IndexCorrupt bool
+	StaleLocks   []string
+	HasRemote    bool
+}
+
+// 是否存在需要修复的问题
+func (d *Diagnosis) Healthy() bool {
+	return !d.NotRepo && !d.Detached && !d.Merging && !d.Rebasing &&
+		len(d.Conflicts) == 0 && !d.IndexCorrupt && len(d.StaleLocks) == 0
+}
+
+// 问题描述列表
+func (d *Diagnosis) Problems() []string {
+	var problems []string
+	if d.NotRepo {
+		problems = append(problems, i18n.T("源文件夹不是 Git 仓库或 .git 目录已损坏"))
+	}
+	if d.Detached {
+		problems = append(problems, i18n.T("HEAD 处于分离状态，不在任何分支上"))
+	}
+	if d.Merging {
+		problems = append(problems, i18n.T("存在未完成的合并"))
+	}
+	if d.Rebasing {
+		problems = append(problems, i18n.T("存在未完成的变基"))
+	}
+	if len(d.Conflicts) > 0 {
+		problems = append(problems, i18n.Sprintf("%d 个文件存在冲突: %s", len(d.Conflicts), strings.Join(d.Conflicts, ", ")))
+	}
+	if d.IndexCorrupt {
+		problems = append(problems, i18n.T("索引文件 (.git/index) 已损坏"))
+	}
+	if len(d.StaleLocks) > 0 {
+		problems = append(problems, i18n.T("存在残留的锁定文件: ")+strings.Join(d.StaleLocks, ", "))
+	}
+	if !d.NotRepo && !d.HasRemote {
+		problems = append(problems, i18n.T("未配置远程仓库 origin"))
+	}
+	return problems
+}
+
+// 诊断仓库状态
+func Diagnose(dir string) *Diagnosis {
+	d := &Diagnosis{}
+	gitDir := filepath.Join(dir, ".git")
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		d.NotRepo = true
+		return d
+	}
+
+	for _, lock := range []string{"index.lock", "HEAD.lock", filepath.Join("refs", "heads", "master.lock")} {
+		if _, err := os.Stat(filepath.Join(gitDir, lock)); err == nil {
+			d.StaleLocks = append(d.StaleLocks, lock)
+		}
+	}
+
+	if head, err := repo.Reference(plumbing.HEAD, false); err != nil || head.Type() != plumbing.SymbolicReference {
+		d.Detached = true
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "MERGE_HEAD")); err == nil {
+		d.Merging = true
+	}
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			d.Rebasing = true
+		}
+	}
+
+	// 冲突的文件在索引中有非零的暂存编号
+	if index, err := repo.Storer.Index(); err != nil {
+		d.IndexCorrupt = true
+	} else {
+		seen := make(map[string]bool)
+		for _, entry := range index.Entries {
+			if entry.Stage != 0 && !seen[entry.Name] {
+				seen[entry.Name] = true
+				d.Conflicts = append(d.Conflicts, entry.Name)
+			}
+		}
+	}
+
+	if _, err := repo.Remote("origin"); err == nil {
+		d.HasRemote = true
+	}
+	return d
+}
+
+// 打开仓库和工作区
+func (r *Repo) open() (*git.Repository, *git.Worktree, error) {
+	repo, err := git.PlainOpen(r.Dir)
+	if err != nil {
+		return nil, nil, i18n.Errorf("打开 Git 仓库失败: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, i18n.Errorf("打开 Git 仓库失败: %v", err)
+	}
+	return repo, worktree, nil
+}
+
+// 清理中间状态：删除残留锁、放弃未完成的合并/变基、重建损坏的索引。
+// 这些操作都不会修改工作区中的文件
+func (r *Repo) ClearState(d *Diagnosis, log func(string)) error {
+	gitDir := filepath.Join(r.Dir, ".git")
+
+	for _, lock := range d.StaleLocks {
+		log(i18n.T("删除锁定文件 ") + lock)
+		if r.simulated("删除 %s", filepath.Join(gitDir, lock)) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(gitDir, lock)); err != nil && !os.IsNotExist(err) {
+			return i18n.Errorf("删除锁定文件失败: %v", err)
+		}
+	}
+
+	// 与 git rebase --quit 相同，只删除变基状态，不移动 HEAD
+	if d.Rebasing {
+		log(i18n.T("放弃未完成的变基"))
+		for _, name := range []string{"rebase-merge", "rebase-apply"} {
+			if !r.simulated("删除 %s", filepath.Join(gitDir, name)) {
+				os.RemoveAll(filepath.Join(gitDir, name))
+			}
+		}
+	}
+	if d.Merging {
+		log(i18n.T("放弃未完成的合并"))
+		for _, name := range []string{"MERGE_HEAD", "MERGE_MSG", "MERGE_MODE"} {
+			if !r.simulated("删除 %s", filepath.Join(gitDir, name)) {
+				os.Remove(filepath.Join(gitDir, name))
+			}
+		}
+	}
+
+	if d.IndexCorrupt || len(d.Conflicts) > 0 || d.Merging {
+		log(i18n.T("重建索引"))
+		if r.simulated("删除 %s 并按 HEAD 重建", filepath.Join(gitDir, "index")) {
+			return nil
+		}
+		if err := os.Remove(filepath.Join(gitDir, "index")); err != nil && !os.IsNotExist(err) {
+			return i18n.Errorf("删除损坏的索引失败: %v", err)
+		}
+		repo, worktree, err := r.open()
+		if err != nil {
+			return err
+		}
+		// 还没有任何提交时空索引就是正确的索引
+		head, err := repo.Head()
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil
+		}
+		if err != nil {
+			return i18n.Errorf("读取 HEAD 失败: %v", err)
+		}
+		if err := worktree.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.MixedReset}); err != nil {
+			return i18n.Errorf("重建索引失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 重新提交工作区：回到 master 分支并把工作区的当前内容提交为新版本
+func (r *Repo) RecommitWorkingTree(d *Diagnosis, log func(string)) error {
+	if err := r.ClearState(d, log); err != nil {
+		return err
+	}
+	if r.simulated("重新提交 %s 的工作区", r.Dir) {
+		return nil
+	}
+	repo, worktree, err := r.open()
+	if err != nil {
+		return err
+	}
+	if d.Detached {
+		log(i18n.T("把 master 分支移动到当前提交"))
+		head, err := repo.Head()
+		if err != nil {
+			return i18n.Errorf("读取 HEAD 失败: %v", err)
+		}
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, head.Hash())); err != nil {
+			return i18n.Errorf("更新 master 分支失败: %v", err)
+		}
+		if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+			return i18n.Errorf("更新 master 分支失败: %v", err)
+		}
+	}
+	log(i18n.T("提交工作区"))
+	if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return i18n.Errorf("%s 失败: %v", "add", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return i18n.Errorf("检查 Git 状态失败: %v", err)
+	}
+	if status.IsClean() {
+		return nil
+	}
+	if err := r.commit(worktree, i18n.Sprintf("修复后重新提交 - %s", time.Now().Format("2006-01-02 15:04:05"))); err != nil {
+		return i18n.Errorf("%s 失败: %v", "commit", err)
+	}
+	return nil
+}
+
+// 重置到远程版本：分支和索引指向远程 master，工作区文件保持不变，
+// 下次备份会在远程版本之上提交本地内容
+func (r *Repo) ResetToRemote(d *Diagnosis, log func(string)) error {
+	if !d.HasRemote {
+		return i18n.Errorf("未配置远程仓库 origin")
+	}
+	if err := r.ClearState(d, log); err != nil {
+		return err
+	}
+	return r.resetToOrigin(log)
+}
+
+// 获取远程 master，把 master 分支和索引重置到远程版本，不修改工作区
+func (r *Repo) resetToOrigin(log func(string)) error {
+	log(i18n.T("获取远程版本"))
+	if r.simulated("git fetch origin master") {
+		return nil
+	}
+	repo, worktree, err := r.open()
+	if err != nil {
+		return err
+	}
+	remoteBranch := plumbing.NewRemoteReferenceName("origin", branch.Short())
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec("+" + branch + ":" + remoteBranch)},
+		Auth:       r.Config.auth(),
+		Progress:   r.progress(),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return i18n.Errorf("获取远程版本失败: %v", err)
+	}
+	remote, err := repo.Reference(remoteBranch, true)
+	if err != nil {
+		return i18n.Errorf("获取远程版本失败: %v", err)
+	}
+
+	log(i18n.T("切换到 master 分支"))
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return i18n.Errorf("更新 master 分支失败: %v", err)
+	}
+	log(i18n.T("把分支和索引重置到 origin/master（保留工作区文件）"))
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, remote.Hash())); err != nil {
+		return i18n.Errorf("更新 master 分支失败: %v", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: remote.Hash(), Mode: git.MixedReset}); err != nil {
+		return i18n.Errorf("重建索引失败: %v", err)
+	}
+	return nil
+}
+
+// 重新克隆：保留损坏的 .git 目录，重新初始化并从远程获取历史，工作区文件保持不变
+func (r *Repo) Reclone(log func(string)) error {
+	if r.Config.RepoURL == "" {
+		return i18n.Errorf("Git 仓库地址不能为空")
+	}
+
+	gitDir := filepath.Join(r.Dir, ".git")
+	if _, err := os.Stat(gitDir); err == nil {
+		broken := filepath.Join(r.Dir, fmt.Sprintf(".git.broken-%s", time.Now().Format("20060102-150405")))
+		log(i18n.T("保留损坏的仓库为 ") + filepath.Base(broken))
+		if !r.simulated("重命名 %s 为 %s", gitDir, broken) {
+			if err := os.Rename(gitDir, broken); err != nil {
+				return i18n.Errorf("移动损坏的仓库失败: %v", err)
+			}
+		}
+	}
+
+	log(i18n.T("重新初始化仓库"))
+	if err := r.Init(); err != nil {
+		return err
+	}
+	return r.resetToOrigin(log)
+}
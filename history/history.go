@@ -0,0 +1,369 @@
+// Package history 定义备份历史记录及其筛选、统计和导出。
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 一次备份的记录
+type Record struct {
+	Timestamp      time.Time
+	SourcePath     string
+	DestPath       string
+	FileCount      int
+	TotalSize      int64
+	Success        bool
+	ErrorMessage   string
+	Duration       time.Duration
+	ModifiedFiles  int
+	NewFiles       int
+	DeletedFiles   int
+	LinkedFiles    int    // 增量快照中硬链接到上一个快照的文件数
+	PooledFiles    int    // 去重存储池中已有、直接硬链接的文件数
+	FailedFiles    int    // 复制失败的文件数
+	StreamFiles    int    // 保存了附加数据流（NTFS 备用数据流、扩展属性）的文件数
+	StreamsLost    int    // 带有附加数据流但没有备份的文件数
+	ManifestPath   string // 快照清单文件
+	PeakMemory     uint64 // 备份期间的内存峰值（字节）
+	Icon           string // 备份时配置的图标和颜色
+	Color          string
+	DryRun         bool      // 模拟备份，没有实际写入快照
+	Attempt        int       // 自动备份的第几次尝试，手动备份为 0
+	Pruned         bool      // 快照已因空间不足被清理
+	Cancelled      bool      // 备份被用户取消
+	Note           string    // 用户添加的备注
+	ContentHash    string    // 快照清单的内容哈希，旧版本的记录和模拟备份为空
+	Encrypted      bool      // 快照内容已加密
+	EncryptedNames bool      // 快照中的文件名已加密
+	Verified       bool      // 快照通过校验：备份后校验与源文件内容一致，或手动校验时每个文件都完好
+	VerifiedAt     time.Time `json:",omitempty"` // 最近一次校验通过的时间，旧版本的记录为空
+	Mismatches     []string  // 备份后校验发现的不一致、无法读取或缺失的文件
+	Changes        []Change  `json:",omitempty"` // 相对上一个快照变化的文件，按路径排序，最多 MaxChanges 个
+	ChangesOmitted int       `json:",omitempty"` // 超出 MaxChanges 没有记录的变化数
+	SizeAnomaly    string    `json:",omitempty"` // 文件数或大小与最近几次备份相比骤变的说明，确认正常后清空
+	ColdStorage    bool      `json:",omitempty"` // 快照已转入冷存储（例如 S3 Glacier），还原前需要先取回
+}
+
+// 每条记录最多保存的文件变化，首次备份等大量变化时只保存前面的部分，完整的文件列表见快照清单
+const MaxChanges = 5000
+
+// 文件变化的类型
+const (
+	ChangeNew      = "new"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+)
+
+// 一个文件相对上一个快照的变化
+type Change struct {
+	Path string // 相对源文件夹的路径
+	Op   string // ChangeNew、ChangeModified 或 ChangeDeleted
+	Size int64  // 备份时的大小，删除的文件为上一个快照中的大小
+}
+
+// 变化类型的显示名称
+func (c Change) OpName() string {
+	switch c.Op {
+	case ChangeNew:
+		return i18n.T("新增")
+	case ChangeModified:
+		return i18n.T("修改")
+	case ChangeDeleted:
+		return i18n.T("删除")
+	}
+	return c.Op
+}
+
+// 是否为同一次备份
+func (r Record) Same(other Record) bool {
+	return r.Timestamp.Equal(other.Timestamp) && r.SourcePath == other.SourcePath && r.DestPath == other.DestPath
+}
+
+// 快照是否实际写入且仍然存在
+func (r Record) HasSnapshot() bool {
+	return r.Success && !r.DryRun && !r.Pruned && r.DestPath != ""
+}
+
+// 在 loc 时区显示的备份时间，loc 为 nil 时按记录保存时的时区
+func (r Record) FormatTime(loc *time.Location) string {
+	t := r.Timestamp
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format(TimeLayout)
+}
+
+// 两个快照的内容是否完全相同（文件路径、大小和修改时间），
+// 任意一方没有内容哈希时无法判断，返回 false
+func (r Record) SameContent(other Record) bool {
+	return r.ContentHash != "" && r.ContentHash == other.ContentHash
+}
+
+// 同一源文件夹在 record 之前的最近一个快照
+func Previous(records []Record, record Record) (Record, bool) {
+	found := false
+	for i := len(records) - 1; i >= 0; i-- {
+		if !found {
+			found = records[i].Same(record)
+			continue
+		}
+		if records[i].SourcePath == record.SourcePath && records[i].HasSnapshot() {
+			return records[i], true
+		}
+	}
+	return Record{}, false
+}
+
+// 显示备份时间的格式
+const TimeLayout = "2006-01-02 15:04:05"
+
+// 历史记录筛选条件
+type Filter struct {
+	Source   string         // 只显示该源文件夹的记录，为空表示全部
+	Search   string         // 搜索关键字（小写），匹配备注、错误信息、路径和时间
+	Location *time.Location // 按该时区显示的时间匹配，为空时按记录保存时的时区
+}
+
+// 是否没有任何筛选条件
+func (f Filter) Empty() bool {
+	return f.Source == "" && f.Search == ""
+}
+
+// 记录是否符合筛选条件
+func (f Filter) Match(record Record) bool {
+	if f.Source != "" && record.SourcePath != f.Source {
+		return false
+	}
+	if f.Search == "" {
+		return true
+	}
+	for _, field := range []string{
+		record.Note,
+		record.ErrorMessage,
+		record.SourcePath,
+		record.DestPath,
+		record.FormatTime(f.Location),
+	} {
+		if strings.Contains(strings.ToLower(field), f.Search) {
+			return true
+		}
+	}
+	return false
+}
+
+// 符合筛选条件的记录，保持原有顺序
+func (f Filter) Apply(records []Record) []Record {
+	if f.Empty() {
+		return records
+	}
+	visible := make([]Record, 0, len(records))
+	for _, record := range records {
+		if f.Match(record) {
+			visible = append(visible, record)
+		}
+	}
+	return visible
+}
+
+// 去掉与 removed 中为同一次备份的记录，返回剩余的记录
+func Without(records, removed []Record) []Record {
+	kept := make([]Record, 0, len(records))
+	for _, record := range records {
+		if !slices.ContainsFunc(removed, record.Same) {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// 成功和失败的次数
+func Counts(records []Record) (success, failed int) {
+	for _, record := range records {
+		if record.Success {
+			success++
+		}
+	}
+	return success, len(records) - success
+}
+
+// 成功率显示文本，没有记录时为 "-"
+func SuccessRate(records []Record) string {
+	if len(records) == 0 {
+		return "-"
+	}
+	success, _ := Counts(records)
+	return fmt.Sprintf("%.1f%%", float64(success)*100/float64(len(records)))
+}
+
+// 历史记录中出现过的源文件夹，按首次出现的顺序
+func Sources(records []Record) []string {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if record.SourcePath != "" && !seen[record.SourcePath] {
+			seen[record.SourcePath] = true
+			sources = append(sources, record.SourcePath)
+		}
+	}
+	return sources
+}
+
+// 源文件夹最近一次备份记录
+func Latest(records []Record, source string) (Record, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].SourcePath == source {
+			return records[i], true
+		}
+	}
+	return Record{}, false
+}
+
+// 源文件夹最近连续失败的备份次数：从最新的记录往前数到上一次成功的备份。
+// 自动备份的重试属于同一次备份，只算一次；取消的备份不计入
+func ConsecutiveFailures(records []Record, source string) int {
+	count := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.SourcePath != source || record.Cancelled {
+			continue
+		}
+		if record.Success {
+			break
+		}
+		if record.Attempt <= 1 {
+			count++
+		}
+	}
+	return count
+}
+
+// 最近一个实际执行的备份记录，模拟备份没有快照，不参与变化对比
+func LastSnapshot(records []Record) (Record, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if !records[i].DryRun {
+			return records[i], true
+		}
+	}
+	return Record{}, false
+}
+
+// 修改记录的备注，找不到记录时返回 false
+func SetNote(records []Record, target Record, note string) bool {
+	for i := range records {
+		if records[i].Same(target) {
+			records[i].Note = note
+			return true
+		}
+	}
+	return false
+}
+
+// 记录手动校验快照的结果，找不到记录时返回 false
+func SetVerified(records []Record, target Record, ok bool, at time.Time) bool {
+	for i := range records {
+		if records[i].Same(target) {
+			records[i].Verified = ok
+			records[i].VerifiedAt = at
+			return true
+		}
+	}
+	return false
+}
+
+// 最近一次校验通过的时间，旧版本的记录没有保存时以备份时间为准。没有通过校验时返回零值
+func (r Record) VerifiedTime() time.Time {
+	switch {
+	case !r.Verified:
+		return time.Time{}
+	case r.VerifiedAt.IsZero():
+		return r.Timestamp
+	}
+	return r.VerifiedAt
+}
+
+// 确认记录的大小骤变正常，找不到记录时返回 false
+func AcknowledgeAnomaly(records []Record, target Record) bool {
+	for i := range records {
+		if records[i].Same(target) {
+			records[i].SizeAnomaly = ""
+			return true
+		}
+	}
+	return false
+}
+
+// 以 CSV 格式导出记录，时间按 loc 时区显示
+func WriteCSV(w io.Writer, records []Record, loc *time.Location) error {
+	csvWriter := csv.NewWriter(w)
+
+	// 写入表头
+	headers := []string{
+		i18n.T("时间"), i18n.T("源路径"), i18n.T("目标路径"), i18n.T("总文件数"), i18n.T("总大小(MB)"),
+		i18n.T("新增文件数"), i18n.T("修改文件数"), i18n.T("删除文件数"),
+		i18n.T("耗时(ms)"), i18n.T("峰值内存(MB)"), i18n.T("状态"), i18n.T("错误信息"), i18n.T("备注"), i18n.T("内容哈希"),
+	}
+	csvWriter.Write(headers)
+
+	// 写入数据
+	for _, record := range records {
+		status := i18n.T("成功")
+		if !record.Success {
+			status = i18n.T("失败")
+		}
+
+		row := []string{
+			record.FormatTime(loc),
+			record.SourcePath,
+			record.DestPath,
+			fmt.Sprintf("%d", record.FileCount),
+			fmt.Sprintf("%.2f", float64(record.TotalSize)/(1024*1024)),
+			fmt.Sprintf("%d", record.NewFiles),
+			fmt.Sprintf("%d", record.ModifiedFiles),
+			fmt.Sprintf("%d", record.DeletedFiles),
+			fmt.Sprintf("%d", record.Duration.Milliseconds()),
+			fmt.Sprintf("%.1f", float64(record.PeakMemory)/(1024*1024)),
+			status,
+			record.ErrorMessage,
+			record.Note,
+			record.ContentHash,
+		}
+		csvWriter.Write(row)
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// 以 CSV 格式导出记录中变化的文件，每个文件一行，时间按 loc 时区显示
+func WriteChangesCSV(w io.Writer, records []Record, loc *time.Location) error {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{i18n.T("时间"), i18n.T("源路径"), i18n.T("操作"), i18n.T("文件"), i18n.T("大小(字节)")})
+	for _, record := range records {
+		for _, change := range record.Changes {
+			csvWriter.Write([]string{
+				record.FormatTime(loc),
+				record.SourcePath,
+				change.OpName(),
+				change.Path,
+				fmt.Sprintf("%d", change.Size),
+			})
+		}
+		if record.ChangesOmitted > 0 {
+			csvWriter.Write([]string{
+				record.FormatTime(loc),
+				record.SourcePath,
+				"",
+				i18n.Sprintf("另有 %d 个变化没有记录", record.ChangesOmitted),
+				"",
+			})
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 使用情况统计的时间范围
+const (
+	InsightsWindow    = 30 * 24 * time.Hour // 备份频率按最近 30 天统计
+	GrowthWindow      = 90 * 24 * time.Hour // 存储增长速度按最近 90 天估算
+	ProjectionMonths  = 6                   // 存储增长预测的月数
+	topFailureCauses  = 5
+	failureCauseWidth = 60
+)
+
+// 一种失败原因及其次数
+type FailureCause struct {
+	Cause string
+	Count int
+}
+
+// 根据本地历史记录统计的使用情况，不上传任何数据
+type Insights struct {
+	AutoBackups     int           // 最近 30 天自动备份的次数（包括重试）
+	ManualBackups   int           // 最近 30 天手动备份的次数
+	AutoPerDay      float64       // 最近 30 天平均每天自动备份的次数
+	AverageDuration time.Duration // 成功备份的平均耗时
+	Failures        int           // 失败的次数
+	FailureCauses   []FailureCause
+	StoredBytes     int64   // 仍然存在的快照的总大小
+	BytesPerDay     float64 // 最近 90 天平均每天新增的快照大小
+	Projection      []int64 // 之后每个月末预计的快照总大小
+}
+
+// 统计使用情况，now 为统计的截止时间
+func ComputeInsights(records []Record, now time.Time) Insights {
+	var in Insights
+	var durationSum time.Duration
+	var durationCount int
+	var recentBytes int64
+	var first time.Time
+	causes := make(map[string]int)
+
+	for _, record := range records {
+		// 模拟备份和取消的备份不反映实际的使用情况
+		if record.DryRun || record.Cancelled {
+			continue
+		}
+		if now.Sub(record.Timestamp) <= InsightsWindow {
+			if record.Attempt > 0 {
+				in.AutoBackups++
+			} else {
+				in.ManualBackups++
+			}
+		}
+		if !record.Success {
+			in.Failures++
+			causes[failureCause(record.ErrorMessage)]++
+			continue
+		}
+		durationSum += record.Duration
+		durationCount++
+		if record.HasSnapshot() {
+			in.StoredBytes += record.TotalSize
+		}
+		if now.Sub(record.Timestamp) <= GrowthWindow {
+			recentBytes += record.TotalSize
+			if first.IsZero() || record.Timestamp.Before(first) {
+				first = record.Timestamp
+			}
+		}
+	}
+
+	in.AutoPerDay = float64(in.AutoBackups) / (InsightsWindow.Hours() / 24)
+	if durationCount > 0 {
+		in.AverageDuration = durationSum / time.Duration(durationCount)
+	}
+
+	for cause, count := range causes {
+		in.FailureCauses = append(in.FailureCauses, FailureCause{Cause: cause, Count: count})
+	}
+	sort.Slice(in.FailureCauses, func(i, j int) bool {
+		a, b := in.FailureCauses[i], in.FailureCauses[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Cause < b.Cause)
+	})
+	if len(in.FailureCauses) > topFailureCauses {
+		in.FailureCauses = in.FailureCauses[:topFailureCauses]
+	}
+
+	// 按最近的增长速度线性外推，记录不足一天时按一天计算
+	if !first.IsZero() {
+		days := now.Sub(first).Hours() / 24
+		if days < 1 {
+			days = 1
+		}
+		in.BytesPerDay = float64(recentBytes) / days
+	}
+	for month := 1; month <= ProjectionMonths; month++ {
+		days := now.AddDate(0, month, 0).Sub(now).Hours() / 24
+		in.Projection = append(in.Projection, in.StoredBytes+int64(in.BytesPerDay*days))
+	}
+	return in
+}
+
+// 错误信息的概括：第一行中第一个冒号之前的部分，例如 "复制文件失败"
+func failureCause(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	if cause, _, ok := strings.Cut(line, ": "); ok {
+		line = cause
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return i18n.T("未知原因")
+	}
+	if runes := []rune(line); len(runes) > failureCauseWidth {
+		line = string(runes[:failureCauseWidth]) + "..."
+	}
+	return line
+}
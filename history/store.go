@@ -0,0 +1,276 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"syncsafe/i18n"
+)
+
+// 数据库中的桶：records 按时间顺序保存每条记录，sources 为每个源文件夹保存记录键的索引
+var (
+	recordsBucket = []byte("records")
+	sourcesBucket = []byte("sources")
+)
+
+// 等待其他进程（例如命令行模式）释放数据库的时间
+const storeLockTimeout = 5 * time.Second
+
+// 历史记录数据库。每条记录单独保存，一次写入失败不会影响其他记录；
+// 每次操作时打开数据库，操作结束后关闭，图形界面和命令行模式可以交替使用
+type Store struct {
+	path  string
+	mu    sync.Mutex
+	saved map[string][]byte // 本进程读到或写入的记录，键为 recordKey
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// 数据库文件的路径
+func (s *Store) Path() string {
+	return s.path
+}
+
+// 数据库文件是否存在
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+func (s *Store) open(readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: storeLockTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, i18n.Errorf("打开历史记录数据库失败: %v", err)
+	}
+	return db, nil
+}
+
+// 记录的键：8 字节的时间戳（纳秒，大端序）、源文件夹、0 和目标文件夹，按键排序即为时间顺序
+func recordKey(record Record) []byte {
+	key := make([]byte, 8, 8+len(record.SourcePath)+1+len(record.DestPath))
+	binary.BigEndian.PutUint64(key, uint64(record.Timestamp.UnixNano()))
+	key = append(key, record.SourcePath...)
+	key = append(key, 0)
+	return append(key, record.DestPath...)
+}
+
+// 合并两组记录，同一次备份（Same）只保留 a 中的一条，结果按时间顺序排列
+func Merge(a, b []Record) []Record {
+	seen := make(map[string]bool, len(a))
+	merged := make([]Record, 0, len(a)+len(b))
+	for _, record := range a {
+		seen[string(recordKey(record))] = true
+		merged = append(merged, record)
+	}
+	for _, record := range b {
+		if !seen[string(recordKey(record))] {
+			seen[string(recordKey(record))] = true
+			merged = append(merged, record)
+		}
+	}
+	sort.SliceStable(merged, func(i, k int) bool {
+		return merged[i].Timestamp.Before(merged[k].Timestamp)
+	})
+	return merged
+}
+
+// 按时间顺序读取所有记录，数据库不存在时返回空列表
+func (s *Store) Load() ([]Record, error) {
+	records, err := s.Query(Filter{})
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(records))
+	for i, record := range records {
+		values[i], _ = json.Marshal(record)
+	}
+	s.remember(records, values)
+	return records, nil
+}
+
+// 按筛选条件查询记录，按时间顺序排列。指定源文件夹时只读取索引中该文件夹的记录
+func (s *Store) Query(filter Filter) ([]Record, error) {
+	records := make([]Record, 0)
+	if !s.Exists() {
+		return records, nil
+	}
+	db, err := s.open(true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		recordsB := tx.Bucket(recordsBucket)
+		if recordsB == nil {
+			return nil
+		}
+		add := func(key, value []byte) error {
+			var record Record
+			if err := json.Unmarshal(value, &record); err != nil {
+				return i18n.Errorf("历史记录 %x 已损坏: %v", key, err)
+			}
+			if filter.Match(record) {
+				records = append(records, record)
+			}
+			return nil
+		}
+		if filter.Source == "" {
+			return recordsB.ForEach(add)
+		}
+		index := tx.Bucket(sourcesBucket)
+		if index == nil {
+			return nil
+		}
+		keys := index.Bucket([]byte(filter.Source))
+		if keys == nil {
+			return nil
+		}
+		return keys.ForEach(func(key, _ []byte) error {
+			if value := recordsB.Get(key); value != nil {
+				return add(key, value)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, i18n.Errorf("读取历史记录失败: %v", err)
+	}
+	return records, nil
+}
+
+// 写入新增和修改的记录，数据库中的其他记录不受影响。事务失败时数据库保持原样
+func (s *Store) Put(records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	values := make([][]byte, len(records))
+	for i, record := range records {
+		value, err := json.Marshal(record)
+		if err != nil {
+			return i18n.Errorf("写入历史记录失败: %v", err)
+		}
+		values[i] = value
+	}
+	db, err := s.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		recordsB, err := tx.CreateBucketIfNotExists(recordsBucket)
+		if err != nil {
+			return err
+		}
+		index, err := tx.CreateBucketIfNotExists(sourcesBucket)
+		if err != nil {
+			return err
+		}
+		for i, record := range records {
+			key := recordKey(record)
+			if bytes.Equal(recordsB.Get(key), values[i]) {
+				continue
+			}
+			if err := recordsB.Put(key, values[i]); err != nil {
+				return err
+			}
+			if record.SourcePath == "" {
+				continue
+			}
+			keys, err := index.CreateBucketIfNotExists([]byte(record.SourcePath))
+			if err != nil {
+				return err
+			}
+			if err := keys.Put(key, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return i18n.Errorf("写入历史记录失败: %v", err)
+	}
+	s.remember(records, values)
+	return nil
+}
+
+// 删除记录及其索引，不存在的记录忽略
+func (s *Store) Delete(records ...Record) error {
+	if len(records) == 0 || !s.Exists() {
+		return nil
+	}
+	db, err := s.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		recordsB := tx.Bucket(recordsBucket)
+		if recordsB == nil {
+			return nil
+		}
+		index := tx.Bucket(sourcesBucket)
+		for _, record := range records {
+			key := recordKey(record)
+			if err := recordsB.Delete(key); err != nil {
+				return err
+			}
+			if index == nil {
+				continue
+			}
+			if keys := index.Bucket([]byte(record.SourcePath)); keys != nil {
+				if err := keys.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return i18n.Errorf("删除历史记录失败: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		delete(s.saved, string(recordKey(record)))
+	}
+	return nil
+}
+
+// 记录本进程读到或写入的版本
+func (s *Store) remember(records []Record, values [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved == nil {
+		s.saved = make(map[string][]byte, len(records))
+	}
+	for i, record := range records {
+		s.saved[string(recordKey(record))] = values[i]
+	}
+}
+
+// records 中自本进程上次读取或写入以来新增或修改的记录。
+// 只写入这些记录，其他进程（例如计划任务运行的命令行）之后写入或修改的记录不会被覆盖
+func (s *Store) Changed(records []Record) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed []Record
+	for _, record := range records {
+		value, err := json.Marshal(record)
+		if err != nil || !bytes.Equal(s.saved[string(recordKey(record))], value) {
+			changed = append(changed, record)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,1565 @@
+package i18n
+
+// 英文消息目录，键为中文原文。按消息所在的包分组
+var enUS = map[string]string{
+	// crypt
+	"密码短语不能为空":               "The passphrase must not be empty",
+	"解密失败：密码短语错误或文件已损坏":      "Decryption failed: wrong passphrase or corrupted file",
+	"请先设置加密密码短语":             "Please set an encryption passphrase first",
+	"不支持的密钥派生算法: %s":         "Unsupported key derivation algorithm: %s",
+	"不是有效的 age 加密文件: %v":     "Not a valid age-encrypted file: %v",
+	"密钥派生参数无效":               "Invalid key derivation parameters",
+	"密码短语与目标中已有的加密设置不一致":     "The passphrase does not match the existing encryption settings at the destination",
+	"不是 SyncSafe 加密的文件":      "Not a SyncSafe-encrypted file",
+	"文件名过长，加密后超过 %d 个字符: %s": "File name too long, exceeds %d characters after encryption: %s",
+	"不是加密的文件名: %s":           "Not an encrypted file name: %s",
+	"zip 条目缺少 AES 加密信息":      "zip entry is missing AES encryption info",
+
+	// engine
+	"打开归档失败: %v":         "Failed to open archive: %v",
+	"解密归档失败: %v":         "Failed to decrypt archive: %v",
+	"读取归档失败: %v":         "Failed to read archive: %v",
+	"读取远程快照失败: %v":       "Failed to list remote snapshot: %v",
+	"不是远程快照: %s":         "Not a remote snapshot: %s",
+	"读取远程文件失败: %v":       "Failed to read remote file: %v",
+	"读取归档失败: %v\n文件: %s": "Failed to read archive: %v\nFile: %s",
+	"备份已取消":              "Backup cancelled",
+	"最近一次备份的大小异常，确认正常之前暂停按保留策略清理": "The latest backup changed size abruptly; pruning by retention policy is paused until it is confirmed as expected",
+	"没有最近通过校验的较新快照，推迟按保留策略清理":     "No newer snapshot has passed verification recently; pruning by retention policy is deferred",
+	"%w: %d 个快照等待较新的快照通过校验":       "%w: %d snapshots are waiting for a newer snapshot to pass verification",
+	"推迟清理旧快照": "Pruning of old snapshots deferred",
+	"在历史记录中校验最新的快照，或开启备份后校验":                                           "Verify the latest snapshot in History, or enable verification after backup",
+	"快照明显变小：%d 个文件（%.2f MB），最近 %d 次平均 %d 个文件（%.2f MB）。源文件夹是否被误删或清空？":   "Snapshot is much smaller: %d files (%.2f MB), the last %d averaged %d files (%.2f MB). Was the source folder accidentally deleted or emptied?",
+	"快照明显变大：%d 个文件（%.2f MB），最近 %d 次平均 %d 个文件（%.2f MB）。是否有日志或缓存文件失控增长？": "Snapshot is much larger: %d files (%.2f MB), the last %d averaged %d files (%.2f MB). Are log or cache files growing out of control?",
+	"开始备份":        "Backup started",
+	"备份完成":        "Backup completed",
+	"备份失败":        "Backup failed",
+	"%s，共 %d 个文件": "%s, %d files in total",
+	"请先选择源文件夹和备份文件夹":   "Please choose a source folder and a backup folder first",
+	"源文件夹不存在或无法访问: %v": "Source folder does not exist or is not accessible: %v",
+	"源文件夹":          "Source folder",
+	"目标文件夹":         "Destination folder",
+	"快照格式":          "Snapshot format",
+	"推送通知":          "Push notifications",
+	"没有选择源文件夹":      "No source folder selected",
+	"%s 不是文件夹":      "%s is not a folder",
+	"要备份的文件不存在: %s": "File to back up does not exist: %s",
+	"没有选择目标文件夹":     "No destination folder selected",
+	"没有填写 WebDAV 密码，密码只保存在本机，换电脑后需要重新填写":  "No WebDAV password entered; passwords are stored only on this computer and must be entered again on a new computer",
+	"目标文件夹所在的驱动器 %s 不存在，外接硬盘或网络驱动器是否已连接？": "The drive %s of the destination folder does not exist. Is the external or network drive connected?",
+	"目标文件夹无法访问: %v": "Destination folder is not accessible: %v",
+	"已启用加密，但没有填写密码短语。密码短语只保存在本机，换电脑后需要重新填写": "Encryption is enabled but no passphrase is entered. The passphrase is stored only on this computer and must be entered again on a new computer",
+	"不支持的归档格式: %s":                       "Unsupported archive format: %s",
+	"同步文件夹不存在或无法访问: %v":                  "Sync folder does not exist or is not accessible: %v",
+	"%v，该规则会被跳过":                         "%v; the rule will be skipped",
+	"定时备份表达式无效，不会定时备份: %v":               "Invalid schedule expression, no scheduled backups will run: %v",
+	"%v，定时计划按本机时区运行":                     "%v; schedules run in the local time zone",
+	"未知的时区: %s":                          "Unknown time zone: %s",
+	"数据巡检表达式无效，不会定期巡检: %v":               "Invalid scrub expression, no scheduled scrubs will run: %v",
+	"没有填写仓库地址，只提交到本地仓库":                  "No repository URL entered; commits stay in the local repository",
+	"没有填写访问令牌，推送到 HTTPS 仓库可能失败。令牌只保存在本机": "No access token entered; pushing to an HTTPS repository may fail. The token is stored only on this computer",
+	"批量提交计划无效，每次备份都会提交: %v":              "Invalid commit schedule, every backup will commit: %v",
+	"Gotify 需要填写服务器地址和应用令牌":              "Gotify needs a server address and an application token",
+	"没有填写 ntfy 主题":                       "No ntfy topic entered",
+	"没有填写邮箱密码，密码只保存在本机":                  "No email password entered; it is stored only on this computer",
+	"没有填写接收端地址":                          "No receiver address entered",
+	"没有填写配对码，配对码只保存在本机":                  "No pairing code entered; it is stored only on this computer",
+	"不支持的导出布局: %s":                       "Unsupported export layout: %s",
+	"没有选择导出目录":                           "No export directory selected",
+	"有附加目标没有填写路径":                        "An additional destination has no path",
+	"附加目标与目标文件夹相同: %s":                   "The additional destination is the same as the destination folder: %s",
+	"归档模式只支持本地目标文件夹: %s":                 "Archive mode only supports local destination folders: %s",
+	"加密附加目标 %s 需要先在加密设置中填写密码短语":          "The encrypted additional destination %s needs the passphrase in the encryption settings",
+	"与同步文件夹交换变化":                         "Exchanging changes with the sync folder",
+	"双向同步失败: %v":                         "Two-way sync failed: %v",
+	"与 %s 的快照相比没有变化":                     "No changes since the snapshot of %s",
+	"没有变化，跳过本次备份":                        "No changes, skipping this backup",
+	"开始备份...":                            "Starting backup...",
+	"提交并推送到 Git 仓库":                      "Committing and pushing to the Git repository",
+	"Git 备份完成":                           "Git backup completed",
+	"归档模式只支持本地目标文件夹":                     "Archive mode only supports local destination folders",
+	"复制文件到 ":                             "Copying files to ",
+	"创建备份目录 %s":                          "Create backup directory %s",
+	"创建父目录失败: %v\n目录: %s":                "Failed to create parent directory: %v\nDirectory: %s",
+	"创建备份目录失败: %v\n目录: %s":               "Failed to create backup directory: %v\nDirectory: %s",
+	"创建目录失败: %v\n目录: %s":                 "Failed to create directory: %v\nDirectory: %s",
+	"复制新增文件 %s":                          "Copy new file %s",
+	"复制修改的文件 %s":                         "Copy modified file %s",
+	"模拟模式":                               "Dry run",
+	"目标中已是最新":                            "Already up to date at destination",
+	"硬链接":                                "Hard link",
+	"复制失败":                               "Copy failed",
+	"按索引":                                "By index",
+	"文件已被删除":                             "File has been deleted",
+	"访问文件失败: %v\n文件: %s":                 "Failed to access file: %v\nFile: %s",
+	"遍历文件树":                              "Walking the file tree",
+	"按变化（%d 个路径）":                        "By changes (%d paths)",
+	"读取上一个快照的清单失败: %v":                   "Failed to read the previous snapshot's manifest: %v",
+	"读取上一个快照的清单失败":                       "Failed to read the previous snapshot's manifest",
+	"通过提权辅助进程备份受保护的目录 %s":                "Back up protected directory %s through the elevated helper",
+	"获取相对路径失败: %v":                       "Failed to get relative path: %v",
+	"保存清单和索引":                            "Saving manifest and index",
+	"已把目标中 %d 个多余的文件移到回收文件夹 %s":          "Moved %d extra files at the destination to the trash folder %s",
+	"已删除目标中 %d 个多余的文件":                   "Deleted %d extra files at the destination",
+	"双向同步有 %d 个冲突等待处理":                   "Two-way sync has %d conflicts to resolve",
+	"正在创建文件系统快照...":                      "Creating file system snapshot...",
+	"创建文件系统快照失败，直接从源文件夹读取: ":             "Could not create a file system snapshot, read the source folder directly: ",
+	"已创建 %s 快照":                          "Created %s snapshot",
+	"无法访问源文件夹: %v":                       "Cannot access the source folder: %v",
+	"无法读取源文件夹所在的文件系统: %v":                "Cannot read the file system of the source folder: %v",
+	"当前系统不支持文件系统快照":                      "File system snapshots are not supported on this system",
+	"源文件夹所在的文件系统（%s）不支持快照，需要 APFS":       "The file system of the source folder (%s) does not support snapshots, APFS is required",
+	"源文件夹所在的文件系统（%s）不支持快照，需要 Btrfs、ZFS 或 LVM 逻辑卷": "The file system of the source folder (%s) does not support snapshots, Btrfs, ZFS or an LVM logical volume is required",
+	"源文件夹不在卷 %s 中":            "The source folder is not on volume %s",
+	"创建 APFS 快照失败: %v":        "Failed to create APFS snapshot: %v",
+	"创建 APFS 快照失败: %s":        "Failed to create APFS snapshot: %s",
+	"挂载 APFS 快照失败: %v":        "Failed to mount APFS snapshot: %v",
+	"创建挂载目录失败: %v":            "Failed to create mount folder: %v",
+	"创建 Btrfs 快照失败: %v":       "Failed to create Btrfs snapshot: %v",
+	"找不到 %s 所在的 Btrfs 子卷":     "Cannot find the Btrfs subvolume of %s",
+	"找不到源文件夹所在的 ZFS 数据集: %v":  "Cannot find the ZFS dataset of the source folder: %v",
+	"找不到源文件夹所在的 ZFS 数据集: %s":  "Cannot find the ZFS dataset of the source folder: %s",
+	"源文件夹不在 ZFS 数据集 %s 的挂载点中": "The source folder is not under the mount point of ZFS dataset %s",
+	"创建 ZFS 快照失败: %v":         "Failed to create ZFS snapshot: %v",
+	"无法识别 %s 所在的 LVM 逻辑卷":     "Cannot identify the LVM logical volume of %s",
+	"创建 LVM 快照失败: %v":         "Failed to create LVM snapshot: %v",
+	"挂载 LVM 快照失败: %v":         "Failed to mount LVM snapshot: %v",
+	"读取挂载信息失败: %v":            "Failed to read mount information: %v",
+	"找不到源文件夹所在的挂载点":           "Cannot find the mount point of the source folder",
+	"远程目标不支持备份后校验":            "Remote destinations do not support post-backup verification",
+	"正在校验备份...":               "Verifying backup...",
+	"校验备份失败: ":                "Backup verification failed: ",
+	"与 %s 只有大小写不同，在不区分大小写的目标中会互相覆盖": "Differs from %s only in letter case, one overwrites the other on a case-insensitive destination",
+	"%d 个问题已加入复查队列":                 "%d issues were added to the review queue",
+	"没有启用手机推送或邮件通知，无法上报":            "Neither push notifications nor email notifications are enabled, cannot escalate",
+	"[SyncSafe] 备份问题: %s":           "[SyncSafe] Backup issue: %s",
+	"任务 %s 的备份中有一个问题需要处理。\n\n":      "A backup issue of task %s needs attention.\n\n",
+	"文件: %s\n":              "File: %s\n",
+	"问题: %s\n":              "Issue: %s\n",
+	"首次出现: %s，共 %d 次\n":     "First seen: %s, %d times in total\n",
+	"推送通知失败: %v":            "Failed to send push notification: %v",
+	"校验发现 %d 个文件与源文件不一致":    "Verification found %d files that differ from the source",
+	"%d 个文件的附加数据流无法读取，没有备份": "Alternate data streams of %d files could not be read and were not backed up",
+	"%d 个文件带有备用数据流或扩展属性（资源分支、标签等），没有开启备份附加数据流": "%d files have alternate data streams or extended attributes (resource forks, tags, etc.) but stream backup is off",
+	"推送到局域网设备失败: ":           "Failed to push to LAN device: ",
+	"导出失败: ":                 "Export failed: ",
+	"复制快照到 ":                 "Copying snapshot to ",
+	"复制到附加目标 %s 失败: %v":      "Failed to copy to additional destination %s: %v",
+	"快照已复制到 %s":              "Snapshot copied to %s",
+	"正在复制到附加目标: 已复制 %d 个文件":  "Copying to additional destination: %d files copied",
+	"加密附加目标需要先在加密设置中填写密码短语":  "Encrypted additional destinations need the passphrase in the encryption settings",
+	"读取快照中的文件失败: %v\n文件: %s": "Failed to read the file in the snapshot: %v\nFile: %s",
+	"远程目标不支持共享索引":            "Remote destinations do not support the share index",
+	"生成共享索引失败: ":             "Failed to generate share index: ",
+	"备份失败: ":                 "Backup failed: ",
+	"完成：新增 %d、修改 %d、删除 %d 个文件，共 %d 个文件 %.2f MB": "Done: %d new, %d modified, %d deleted, %d files in total, %.2f MB",
+	"模拟备份完成，详情见命令输出":                            "Dry-run backup completed, see the command output for details",
+	"备份完成，但": "Backup completed, but ",
+	"；":      "; ",
+	"复制文件失败: %v\n源文件: %s\n目标文件: %s":             "Failed to copy file: %v\nSource file: %s\nDestination file: %s",
+	"复制文件失败: %v\n源文件: %s\n加密或归档的备份不能通过提权辅助进程读取": "Failed to copy file: %v\nSource file: %s\nEncrypted or archived backups cannot read through the elevated helper",
+	"复制文件失败: %v\n源文件: %s":                       "Failed to copy file: %v\nSource file: %s",
+	"复制受保护文件失败: %v\n源文件: %s":                    "Failed to copy protected file: %v\nSource file: %s",
+	"写入清单失败: %v":                                "Failed to write manifest: %v",
+	"读取源文件夹失败: %v":                              "Failed to read source folder: %v",
+	"创建测试文件失败: %v":                              "Failed to create test file: %v",
+	"写入测试文件失败: %v":                              "Failed to write test file: %v",
+	"同步测试文件失败: %v":                              "Failed to sync test file: %v",
+	"正在测试源文件夹读取速度...":                           "Testing source folder read speed...",
+	"正在测试目标文件夹写入速度...":                          "Testing destination folder write speed...",
+	"正在测试哈希速度...":                               "Testing hashing speed...",
+	"源文件夹读取: %.1f MB/s (%d 个文件, %.1f 文件/秒)\n":   "Source folder read: %.1f MB/s (%d files, %.1f files/s)\n",
+	"目标文件夹写入:\n":                                "Destination folder write:\n",
+	"  缓冲区 %4d KB: %.1f MB/s\n":                 "  Buffer %4d KB: %.1f MB/s\n",
+	"SHA-256 哈希: %.1f MB/s\n":                   "SHA-256 hashing: %.1f MB/s\n",
+	"云端上传: %.1f MB/s\n":                         "Cloud upload: %.1f MB/s\n",
+	"复制到目标: %.1f MB/s\n":                        "Copy to destination: %.1f MB/s\n",
+	"正在测试复制到目标的速度...":                           "Testing copy speed to the destination...",
+	"正在测试上传速度...":                               "Testing upload speed...",
+	"复制测试文件到目标失败: %v":                           "Failed to copy the test file to the destination: %v",
+	"\n推荐设置:\n":                                 "\nRecommended settings:\n",
+	"  并发复制数: %d\n":                             "  Concurrent copies: %d\n",
+	"  缓冲区大小: %d KB\n":                          "  Buffer size: %d KB\n",
+	"  注意: 哈希速度低于读取速度，启用校验会成为瓶颈\n":              "  Note: hashing is slower than reading, enabling checksums will be the bottleneck\n",
+	"%s（每年 %s 至 %s）":                            "%s (every year %s to %s)",
+	"%s（%s 至 %s）":                               "%s (%s to %s)",
+	"日期格式错误: %s":                                "Invalid date format: %s",
+	"事件 %s 缺少开始日期":                              "Event %s is missing a start date",
+	"节假日":                                       "Holiday",
+	"目录必须是源文件夹中的相对路径: %s":                       "Directory must be a relative path inside the source folder: %s",
+	"创建索引目录失败: %v":                              "Failed to create index directory: %v",
+	"创建哈希索引失败: %v":                              "Failed to create hash index: %v",
+	"写入哈希索引失败: %v":                              "Failed to write hash index: %v",
+	"保存哈希索引失败: %v":                              "Failed to save hash index: %v",
+	"配置文件已损坏: %s\n%v":                           "Configuration file is corrupted: %s\n%v",
+	"创建配置目录失败: %v":                              "Failed to create configuration directory: %v",
+	"序列化配置失败: %v":                               "Failed to serialize configuration: %v",
+	"序列化本机配置失败: %v":                             "Failed to serialize machine configuration: %v",
+	"写入配置文件失败: %v":                              "Failed to write configuration file: %v",
+	"写入本机配置文件失败: %v":                            "Failed to write machine configuration file: %v",
+	"读取配置文件失败: %v":                              "Failed to read configuration file: %v",
+	"读取本机配置文件失败: %v":                            "Failed to read machine configuration file: %v",
+	"迁移历史记录失败: %v":                              "Failed to migrate history: %v",
+	"备份旧配置失败: %v":                               "Failed to back up old configuration: %v",
+	"读取备份失败: %v":                                "Failed to read backup: %v",
+	"保留损坏的配置失败: %v":                             "Failed to keep the corrupted configuration: %v",
+	"恢复配置失败: %v":                                "Failed to restore configuration: %v",
+	"\n……以及另外 %d 个文件":                           "\n...and %d more files",
+	"%d 个文件复制失败:\n%s":                           "%d files failed to copy:\n%s",
+	"提交: %s\n":                                  "Commit: %s\n",
+	"未知":                                        "Unknown",
+	"构建时有未提交的修改\n":                              "Built with uncommitted changes\n",
+	"构建时间: %s\n":                                "Build time: %s\n",
+	"Go 版本: %s\n":                               "Go version: %s\n",
+	"平台: %s\n":                                  "Platform: %s\n",
+	"计算机: %s\n":                                 "Computer: %s\n",
+	"配置格式: %d\n":                                "Configuration format: %d\n",
+	"数据目录: %s\n":                                "Data directory: %s\n",
+	"配置文件: %s\n":                                "Configuration file: %s\n",
+	"本机配置: %s\n":                                "Machine configuration: %s\n",
+	"历史数据库: %s":                                 "History database: %s",
+	"获取程序路径失败: %v":                              "Failed to get program path: %v",
+	"日志目录不属于 root 或允许其他用户写入: %s":                "Log directory is not owned by root or is writable by other users: %s",
+	"日志文件不是 root 拥有的普通文件: %s":                   "Log file is not a regular file owned by root: %s",
+	"日志路径是符号链接或目录联接: %s":                        "Log path is a symbolic link or junction: %s",
+	"创建辅助进程连接失败: %v":                            "Failed to create helper connection: %v",
+	"生成辅助进程令牌失败: %v":                            "Failed to generate helper token: %v",
+	"启动提权辅助进程失败: %v":                            "Failed to start elevated helper: %v",
+	"等待辅助进程连接失败: %v":                            "Failed waiting for helper connection: %v",
+	"等待提权确认超时":                                  "Timed out waiting for elevation confirmation",
+	"辅助进程身份校验失败":                                "Helper authentication failed",
+	"路径超出提权读取范围: %s":                            "Path is outside the elevated read scope: %s",
+	"发送请求失败: %v":                                "Failed to send request: %v",
+	"读取响应失败: %v":                                "Failed to read response: %v",
+	"解析响应失败: %v":                                "Failed to parse response: %v",
+	"创建目标目录失败: %v":                              "Failed to create destination directory: %v",
+	"创建临时文件失败: %v":                              "Failed to create temporary file: %v",
+	"读取受保护文件失败: %v":                             "Failed to read protected file: %v",
+	"关闭目标文件失败: %v":                              "Failed to close destination file: %v",
+	"重命名文件失败: %v":                               "Failed to rename file: %v",
+	"辅助进程读取文件出错":                                "Helper failed to read file",
+	"路径超出提权读取范围":                                "Path is outside the elevated read scope",
+	"保存辅助进程令牌失败: %v":                            "Failed to save the helper token: %v",
+	"不是普通文件":                                    "Not a regular file",
+	"不支持的操作: ":                                  "Unsupported operation: ",
+	"列出受保护目录失败: %v\n目录: %s":                     "Failed to list protected directory: %v\nDirectory: %s",
+	"复制受保护文件失败: %v\n文件: %s":                     "Failed to copy protected file: %v\nFile: %s",
+	"%v\n输出: %s":                                "%v\nOutput: %s",
+	"未找到 pkexec，无法请求管理员权限":                      "pkexec not found, cannot request administrator privileges",
+	"用户取消了提权或启动失败 (代码 %d)":                      "The user cancelled elevation or it failed to start (code %d)",
+	"找不到加密参数文件 %s":                              "Encryption parameter file %s not found",
+	"保存加密参数失败: %v":                              "Failed to save encryption parameters: %v",
+	"创建目标文件夹失败: %v":                             "Failed to create destination folder: %v",
+	"写入加密参数失败: %v":                              "Failed to write encryption parameters: %v",
+	"快照已加密，请先在加密设置中填写密码短语":                      "The snapshot is encrypted, please enter the passphrase in the encryption settings first",
+	"无法解密快照: %v":                                "Cannot decrypt snapshot: %v",
+	"定时提交 - %s":                                 "Scheduled commit - %s",
+	"[%s] [模拟] %s":                              "[%s] [dry run] %s",
+	"启动失败: ":                                    "Failed to start: ",
+	"退出: ":                                      "Exited: ",
+	"准备":                                        "Preparing",
+	"Git 备份":                                    "Git backup",
+	"双向同步":                                      "Two-way sync",
+	"复制文件":                                      "Copying files",
+	"保存清单":                                      "Saving manifest",
+	"局域网推送":                                     "LAN push",
+	"附加目标":                                      "Additional destinations",
+	"完成":                                        "Done",
+	"开始扫描 %s（%s）":                               "Scanning %s (%s)",
+	"预扫描完成: ":                                   "Pre-scan complete: ",
+	"已复制 %s（%d 字节）":                             "Copied %s (%d bytes)",
+	"跳过 %s: %s":                                 "Skipped %s: %s",
+	"跳过备份: ":                                    "Backup skipped: ",
+	"打包快照失败: %v":                                "Failed to pack snapshot: %v",
+	"写入归档失败: %v":                                "Failed to write archive: %v",
+	"无法访问文件: %v":                                "Cannot access file: %v",
+	"%s 是文件夹，请选择文件":                             "%s is a folder, please choose a file",
+	"只能添加源文件夹中的文件，其他文件夹中的文件请新建一个任务: %s": "Only files inside the source folder can be added; create a new profile for files in other folders: %s",
+	"源文件夹无法访问: %v":             "Source folder is not accessible: %v",
+	"创建索引文件失败: %v":             "Failed to create index file: %v",
+	"写入索引失败: %v":               "Failed to write index: %v",
+	"保存索引失败: %v":               "Failed to save index: %v",
+	"建立索引失败: %v":               "Failed to build index: %v",
+	"请先选择源文件夹":                 "Please choose a source folder first",
+	"rsync（按时间的快照目录 + latest）": "rsync (timestamped snapshot directories + latest)",
+	"Syncthing（最新快照的镜像）":       "Syncthing (mirror of the latest snapshot)",
+	"请先选择导出目录":                 "Please choose an export directory first",
+	"快照不存在: %v":                "Snapshot does not exist: %v",
+	"未知的导出布局: %s":              "Unknown export layout: %s",
+	"创建导出目录失败: %v":             "Failed to create export directory: %v",
+	"导出快照失败: %v":               "Failed to export snapshot: %v",
+	"读取导出目录失败: %v":             "Failed to read export directory: %v",
+	"导出目录不是空的，也不是 Syncthing 文件夹（缺少 %s）: %s": "Export directory is neither empty nor a Syncthing folder (missing %s): %s",
+	"删除多余的文件失败: %v":                         "Failed to delete extra files: %v",
+	"变更日志不可用":                               "Change journal unavailable",
+	"读取 USN 日志失败: %v":                       "Failed to read USN journal: %v",
+	"创建清单目录失败: %v":                          "Failed to create manifest directory: %v",
+	"创建清单文件失败: %v":                          "Failed to create manifest file: %v",
+	"保存清单失败: %v":                            "Failed to save manifest: %v",
+	"清单格式错误":                                "Invalid manifest format",
+	"清单格式错误: %v":                            "Invalid manifest format: %v",
+	"生成清单失败: %v":                            "Failed to generate manifest: %v",
+	"[SyncSafe] 备份失败: %s":                   "[SyncSafe] Backup failed: %s",
+	"任务 %s 的备份失败。\n\n":                      "The backup of profile %s failed.\n\n",
+	"时间: %s\n":                              "Time: %s\n",
+	"源文件夹: %s\n":                            "Source folder: %s\n",
+	"目标文件夹: %s\n":                           "Destination folder: %s\n",
+	"自动备份尝试次数: %d\n":                        "Automatic backup attempts: %d\n",
+	"耗时: %v\n":                              "Duration: %v\n",
+	"文件: 共 %d 个，新增 %d、修改 %d、删除 %d，复制失败 %d\n": "Files: %d in total, %d new, %d modified, %d deleted, %d failed to copy\n",
+	"\n错误信息:\n%s\n": "\nError:\n%s\n",
+	"推送快照到 ":        "Pushing snapshot to ",
+	"正在推送到局域网设备: 已发送 %d 个文件，%.2f MB": "Pushing to LAN device: %d files sent, %.2f MB",
+	"清理去重存储池失败: %v":                  "Failed to clean up the deduplicated storage pool: %v",
+	"无法读取硬链接数: %s":                   "Cannot read hard link count: %s",
+	"任务名称不能为空":                       "Profile name must not be empty",
+	"任务 %s 已存在":                      "Profile %s already exists",
+	"默认任务不能删除":                       "The default profile cannot be deleted",
+	"默认任务不能归档":                       "The default profile cannot be archived",
+	"归档":                             "Archive",
+	"已归档（%d）":                        "Archived (%d)",
+	"归档任务":                           "Archive profile",
+	"是否归档任务 %s？\n归档后停止监控和定时备份，任务不再显示在列表中；设置、历史记录和快照都会保留，可以在「已归档」中随时恢复。": "Archive profile %s?\nWatching and scheduled backups stop and the profile is hidden from the list; its settings, history and snapshots are kept and it can be restored from \"Archived\" at any time.",
+	"任务 %s 已归档":                "Profile %s archived",
+	"已归档的任务":                   "Archived profiles",
+	"没有已归档的任务":                 "No archived profiles",
+	"%s\n%s → %s，%d 条历史记录":     "%s\n%s → %s, %d history records",
+	"删除任务配置失败: %v":             "Failed to delete profile configuration: %v",
+	"创建任务目录失败: %v":             "Failed to create profile directory: %v",
+	"序列化任务设置失败: %v":            "Failed to serialize profile settings: %v",
+	"共 %d 个文件，%.2f MB":         "%d files, %.2f MB in total",
+	"%d 个文件":                   "%d files",
+	"%.1f%%  %d/%d 个文件":        "%.1f%%  %d/%d files",
+	"  剩余 ":                    "  remaining ",
+	"删除快照 %s":                  "Delete snapshot %s",
+	"删除快照失败: %v\n目录: %s":       "Failed to delete snapshot: %v\nDirectory: %s",
+	"列出目标中已有的文件失败: %v\n目录: %s": "Failed to list existing files at the destination: %v\nDirectory: %s",
+	"把目标中多余的文件 %s 移到回收文件夹":     "Move extra file %s at the destination to the trash folder",
+	"目标不支持移到回收文件夹":             "The destination does not support moving to the trash folder",
+	"把目标中多余的文件移到回收文件夹失败: %v\n文件: %s": "Failed to move extra file at the destination to the trash folder: %v\nFile: %s",
+	"删除目标中多余的文件 %s":                  "Delete extra file %s at the destination",
+	"删除目标中多余的文件失败: %v\n文件: %s":       "Failed to delete extra file at the destination: %v\nFile: %s",
+	"读取快照失败: %v":                     "Failed to read snapshot: %v",
+	"读取快照中的文件失败: %v":                 "Failed to read the file in the snapshot: %v",
+	"快照没有清单，无法比较: %s":                "The snapshot has no manifest and cannot be compared: %s",
+	"文件超过 %d MB，不逐行比较":               "The file is larger than %d MB; no line-by-line comparison",
+	"不是文本文件，不逐行比较":                   "Not a text file; no line-by-line comparison",
+	"修改的行数过多，不逐行比较":                  "Too many changed lines; no line-by-line comparison",
+	"快照中没有该文件: %s":                   "The snapshot does not contain this file: %s",
+	"无法解密文件名: %v\n文件: %s":            "Cannot decrypt file name: %v\nFile: %s",
+	"还原 %s 到 %s":                     "Restore %s to %s",
+	"还原文件失败: %v\n文件: %s":             "Failed to restore file: %v\nFile: %s",
+	"已还原 %d 个文件":                     "Restored %d files",
+	"%s  版本 %s（配置格式 %d）  %s":         "%s  version %s (configuration format %d)  %s",
+	"创建数据目录失败: %v":                   "Failed to create data directory: %v",
+	"序列化版本信息失败: %v":                  "Failed to serialize version info: %v",
+	"升级到版本 %s（配置格式 %d）前":             "Before upgrading to version %s (configuration format %d)",
+	"降级到版本 %s（配置格式 %d）前":             "Before downgrading to version %s (configuration format %d)",
+	"还没有可以保存的配置":                     "There is no configuration to save yet",
+	"创建回滚目录失败: %v":                   "Failed to create rollback directory: %v",
+	"保存回滚包失败: %v":                    "Failed to save rollback package: %v",
+	"创建临时目录失败: %v":                   "Failed to create temporary directory: %v",
+	"解压回滚包失败: %v":                    "Failed to extract rollback package: %v",
+	"还原到 %s 的回滚包前":                   "Before restoring the rollback package of %s",
+	"删除当前配置失败: %v":                   "Failed to delete current configuration: %v",
+	"还原 %s 失败: %v":                   "Failed to restore %s: %v",
+	"回滚包中的路径无效: %s":                  "Invalid path in rollback package: %s",
+	"数据巡检完成：%d 个文件、%.2f MB 均可读出":     "Scrub complete: %d files, %.2f MB, all readable",
+	"，已修复 %d 个损坏的文件":                 ", repaired %d corrupted files",
+	"数据巡检发现 %d 个无法修复的文件（已修复 %d 个，%d 个完好）": "Scrub found %d files that could not be repaired (%d repaired, %d intact)",
+	"数据巡检只支持本地目标文件夹":                      "Scrubbing only supports local destination folders",
+	"%s: 目录无法读取: %v":                      "%s: directory cannot be read: %v",
+	"读取失败: %v":                            "Read failed: %v",
+	"内容与上次巡检时不同，可能已静默损坏":                  "Content differs from the last scrub, it may be silently corrupted",
+	"数据巡检：已检查 %d 个文件":                     "Scrub: %d files checked",
+	"%s: %s，没有可用的副本":                      "%s: %s, no usable copy",
+	"%s: %s，修复失败: %v":                     "%s: %s, repair failed: %v",
+	"已用 %s 修复 %s":                         "Repaired %s using %s",
+	"保存巡检记录失败: %v":                        "Failed to save scrub records: %v",
+	"数据巡检失败: %v":                          "Scrub failed: %v",
+	"写入的内容与副本不同":                          "Written content differs from the copy",
+	"修改时间":                                "Modified",
+	"（归档）":                                " (archive)",
+	"备注":                                  "Note",
+	"文件数":                                 "Files",
+	"返回快照列表":                              "Back to snapshot list",
+	"（已加密，可用 age 或 7-Zip 解密）":             " (encrypted, decrypt with age or 7-Zip)",
+	"时间": "Time",
+	"（已加密，需要用 SyncSafe 还原）": " (encrypted, restore with SyncSafe)",
+	"大小": "Size",
+	"由 SyncSafe 生成于 %s，只读浏览，不需要安装 SyncSafe。": "Generated by SyncSafe at %s. Read-only browsing, no need to install SyncSafe.",
+	"文件":                 "File",
+	"快照":                 "Snapshot",
+	"SyncSafe 快照: ":      "SyncSafe snapshots: ",
+	"读取快照 %s 失败: %v":     "Failed to read snapshot %s: %v",
+	"快照 ":                "Snapshot ",
+	"生成索引页失败: %v":        "Failed to generate index page: %v",
+	"写入索引页失败: %v":        "Failed to write index page: %v",
+	"当前平台不支持附加数据流":       "Alternate data streams are not supported on this platform",
+	"数据流 %s 超过 %d MB":    "Stream %s exceeds %d MB",
+	"读取数据流 %s 失败: %v":    "Failed to read stream %s: %v",
+	"写入数据流 %s 失败: %v":    "Failed to write stream %s: %v",
+	"创建数据流文件失败: %v":      "Failed to create stream file: %v",
+	"写入数据流文件失败: %v":      "Failed to write stream file: %v",
+	"保存数据流文件失败: %v":      "Failed to save stream file: %v",
+	"读取数据流文件失败: %v":      "Failed to read stream file: %v",
+	"数据流文件已损坏: %v":       "Stream file is corrupted: %v",
+	"运行时间格式应为 HH:MM: %s": "Run time must be in HH:MM format: %s",
+	"不支持的运行频率: %s":       "Unsupported run frequency: %s",
+	"注册计划任务失败: %v":       "Failed to register scheduled task: %v",
+	"删除计划任务失败: %v":       "Failed to delete scheduled task: %v",
+	"查询计划任务失败: %v":       "Failed to query scheduled task: %v",
+	"就绪":                 "Ready",
+	"正在运行":               "Running",
+	"已禁用":                "Disabled",
+	"排队中":                "Queued",
+	"未注册":                "Not registered",
+	"尚未运行":               "Not run yet",
+	"上次运行 %s 成功":         "Last run %s succeeded",
+	"上次运行 %s 部分成功":       "Last run %s partially succeeded",
+	"上次运行 %s 失败（退出码 %d）": "Last run %s failed (exit code %d)",
+	"下次运行 ":              "Next run ",
+	"，":                  ", ",
+	"计划任务只支持 Windows":    "Scheduled tasks are only supported on Windows",
+	"双向同步：发送 %d 个、接收 %d 个、删除 %d 个文件":   "Two-way sync: %d files sent, %d received, %d deleted",
+	"，%d 个冲突等待处理":                      ", %d conflicts to resolve",
+	"创建同步状态目录失败: %v":                   "Failed to create sync state directory: %v",
+	"同步文件夹不可用: %s":                     "Sync folder unavailable: %s",
+	"列出源文件夹失败: %v":                     "Failed to list source folder: %v",
+	"列出同步文件夹失败: %v":                    "Failed to list sync folder: %v",
+	"一侧的文件已全部消失，可能是磁盘没有挂载，已停止同步":       "All files on one side have disappeared, the disk may not be mounted; sync stopped",
+	"保存同步状态失败: %v":                     "Failed to save sync state: %v",
+	"双向同步：删除 %s":                       "Two-way sync: delete %s",
+	"删除文件失败: %v":                       "Failed to delete file: %v",
+	"双向同步：复制 %s 到 %s":                  "Two-way sync: copy %s to %s",
+	"双向同步：%s 两侧都有修改，保留两个版本":            "Two-way sync: %s was modified on both sides, keeping both versions",
+	"读取文件失败: %v":                       "Failed to read file: %v",
+	"替换文件失败: %v":                       "Failed to replace file: %v",
+	"保存冲突副本失败: %v":                     "Failed to save conflict copy: %v",
+	"未知的处理方式: %s":                      "Unknown resolution: %s",
+	"源文件夹有变化":                          "Source folder has changed",
+	"快照完好：%d 个文件、%.2f MB 均可读出":         "Snapshot is intact: %d files, %.2f MB, all readable",
+	"快照有问题：%d 个文件完好，%d 个文件损坏，%d 个文件缺失": "Snapshot has problems: %d files intact, %d corrupted, %d missing",
+	"%s: 无法解密文件名":                      "%s: cannot decrypt file name",
+	"%s: 大小为 %d 字节，清单中为 %d 字节":         "%s: size is %d bytes, manifest says %d bytes",
+	"已校验 %d 个文件":                       "%d files verified",
+	"校验快照失败: %v":                       "Failed to verify snapshot: %v",
+	"找不到快照清单":                          "Snapshot manifest not found",
+	"读取快照清单失败: %v":                     "Failed to read snapshot manifest: %v",
+	"无法读取: %v":                         "cannot read: %v",
+	"内容与源文件不一致":                        "content differs from the source file",
+	"校验备份：已比较 %d 个文件":                  "Verifying backup: %d files compared",
+	"快照中缺失":                            "missing from snapshot",
+	"无法解密文件名":                          "cannot decrypt file name",
+	"读取快照清单失败: %v\n快照: %s":             "Failed to read snapshot manifest: %v\nSnapshot: %s",
+	"读取版本库失败: %v\n目录: %s":              "Failed to read version store: %v\nDirectory: %s",
+	"从版本库删除旧版本 %s":                     "Delete old version %s from the version store",
+	"删除旧版本失败: %v\n文件: %s":              "Failed to delete old version: %v\nFile: %s",
+	"保留文件版本 %s":                        "Keep file version %s",
+	"保留文件版本失败: %v\n文件: %s":             "Failed to keep file version: %v\nFile: %s",
+	"序列化 Webhook 重试队列失败: %v":           "Failed to serialize webhook retry queue: %v",
+	"无效地址":                             "Invalid address",
+	"保存 Webhook 重试队列失败: %v":            "Failed to save webhook retry queue: %v",
+	"快照不在 S3 目标中: %s":                  "Snapshot is not in the S3 destination: %s",
+	"快照转入冷存储失败: %v":                    "Failed to move snapshots to cold storage: %v",
+	"查询取回状态失败: %v":                     "Failed to query retrieval status: %v",
+	"请求取回快照失败: %v":                     "Failed to request snapshot retrieval: %v",
+	"附加目标不支持 S3，S3 只能作为目标文件夹":          "Additional destinations do not support S3; S3 can only be used as the destination folder",
+	"没有填写 S3 私有访问密钥，密钥只保存在本机，换电脑后需要重新填写": "No S3 secret access key entered; keys are stored only on this computer and must be entered again on a new computer",
+	"附加目标不支持 S3: %s": "Additional destinations do not support S3: %s",
+
+	// gitsync
+	"Git 仓库地址不能为空":              "Git repository URL must not be empty",
+	"请先设置 Git 用户名和邮箱":           "Please set a Git user name and email first",
+	"初始化 Git 仓库失败: %v":          "Failed to initialize Git repository: %v",
+	"Git 配置失败: %v":              "Git configuration failed: %v",
+	"自动备份 - %s":                 "Automatic backup - %s",
+	"打开 Git 仓库失败: %v":           "Failed to open Git repository: %v",
+	"检查 Git 状态失败: %v":           "Failed to check Git status: %v",
+	"没有需要提交的更改":                 "No changes to commit",
+	"push 失败: %v":               "push failed: %v",
+	"%s 失败: %v":                 "%s failed: %v",
+	"Git %s 成功":                 "Git %s succeeded",
+	"源文件夹不是 Git 仓库或 .git 目录已损坏": "The source folder is not a Git repository or the .git directory is corrupted",
+	"HEAD 处于分离状态，不在任何分支上":       "HEAD is detached and not on any branch",
+	"存在未完成的合并":                  "There is an unfinished merge",
+	"存在未完成的变基":                  "There is an unfinished rebase",
+	"%d 个文件存在冲突: %s":            "%d files have conflicts: %s",
+	"索引文件 (.git/index) 已损坏":     "The index file (.git/index) is corrupted",
+	"存在残留的锁定文件: ":               "Stale lock files exist: ",
+	"未配置远程仓库 origin":            "Remote origin is not configured",
+	"读取 HEAD 失败: %v":            "Failed to read HEAD: %v",
+	"更新 master 分支失败: %v":        "Failed to update the master branch: %v",
+	"重建索引失败: %v":                "Failed to rebuild the index: %v",
+	"删除 %s 并按 HEAD 重建":          "Delete %s and rebuild it from HEAD",
+	"重新提交 %s 的工作区":              "Recommit the working tree of %s",
+	"获取远程版本失败: %v":              "Failed to fetch the remote version: %v",
+	"删除锁定文件 ":                   "Delete lock file ",
+	"删除 %s":                     "Delete %s",
+	"删除锁定文件失败: %v":              "Failed to delete lock file: %v",
+	"放弃未完成的变基":                  "Abort the unfinished rebase",
+	"放弃未完成的合并":                  "Abort the unfinished merge",
+	"重建索引":                      "Rebuild the index",
+	"删除损坏的索引失败: %v":             "Failed to delete the corrupted index: %v",
+	"把 master 分支移动到当前提交":        "Move the master branch to the current commit",
+	"提交工作区":                     "Commit the working tree",
+	"修复后重新提交 - %s":              "Recommit after repair - %s",
+	"获取远程版本":                    "Fetch the remote",
+	"切换到 master 分支":             "Switch to the master branch",
+	"把分支和索引重置到 origin/master（保留工作区文件）": "Reset branch and index to origin/master (working tree files are kept)",
+	"保留损坏的仓库为 ":     "Keeping the corrupted repository as ",
+	"重命名 %s 为 %s":   "Rename %s to %s",
+	"移动损坏的仓库失败: %v": "Failed to move the corrupted repository: %v",
+	"重新初始化仓库":       "Reinitialize the repository",
+
+	// history
+	"新增":              "New",
+	"修改":              "Modified",
+	"删除":              "Deleted",
+	"源路径":             "Source path",
+	"目标路径":            "Destination path",
+	"总文件数":            "Total files",
+	"总大小(MB)":         "Total size (MB)",
+	"新增文件数":           "New files",
+	"修改文件数":           "Modified files",
+	"删除文件数":           "Deleted files",
+	"耗时(ms)":          "Duration (ms)",
+	"内容哈希":            "Content hash",
+	"峰值内存(MB)":        "Peak memory (MB)",
+	"状态":              "Status",
+	"错误信息":            "Error message",
+	"成功":              "Success",
+	"失败":              "Failed",
+	"大小(字节)":          "Size (bytes)",
+	"操作":              "Operation",
+	"另有 %d 个变化没有记录":   "%d more changes not recorded",
+	"未知原因":            "Unknown cause",
+	"打开历史记录数据库失败: %v": "Failed to open history database: %v",
+	"历史记录 %x 已损坏: %v": "History record %x is corrupted: %v",
+	"读取历史记录失败: %v":    "Failed to read history: %v",
+	"写入历史记录失败: %v":    "Failed to write history: %v",
+	"删除历史记录失败: %v":    "Failed to delete history: %v",
+
+	// logging
+	"创建日志目录失败: %v": "Failed to create log directory: %v",
+	"错误":           "Error",
+	"警告":           "Warning",
+	"信息":           "Info",
+	"调试":           "Debug",
+	"打开日志文件失败: %v": "Failed to open log file: %v",
+	"读取日志失败: %v":   "Failed to read log: %v",
+
+	// notify
+	"请填写 SMTP 服务器地址":               "Please enter the SMTP server address",
+	"不支持的加密方式: %s":                 "Unsupported encryption method: %s",
+	"端口无效: %d":                     "Invalid port: %d",
+	"发件人地址无效: %s":                  "Invalid sender address: %s",
+	"请填写收件人":                       "Please enter recipients",
+	"收件人地址无效: %s":                  "Invalid recipient address: %s",
+	"发送邮件失败: %v":                   "Failed to send email: %v",
+	"连接 SMTP 服务器失败: %v":            "Failed to connect to SMTP server: %v",
+	"SMTP 登录失败: %v":                "SMTP login failed: %v",
+	"收件人 %s 被拒绝: %v":               "Recipient %s was rejected: %v",
+	"服务器不支持 STARTTLS":              "The server does not support STARTTLS",
+	"全部":                           "All",
+	"警告和错误":                        "Warnings and errors",
+	"仅错误":                          "Errors only",
+	"不支持的推送服务: %s":                 "Unsupported push service: %s",
+	"发送通知失败: %v":                   "Failed to send notification: %v",
+	"推送服务返回错误: %s":                 "Push service returned an error: %s",
+	"请填写 ntfy 主题":                  "Please enter an ntfy topic",
+	"服务器地址无效: %v":                  "Invalid server address: %v",
+	"请填写 Gotify 服务器地址和应用令牌":        "Please enter the Gotify server address and application token",
+	"Webhook 地址无效: %s":             "Invalid webhook URL: %s",
+	"不支持的 Webhook 格式: %s":          "Unsupported webhook format: %s",
+	"备份已取消: ":                      "Backup cancelled: ",
+	"备份完成: ":                       "Backup completed: ",
+	"计算机 %s，%s":                    "Computer %s, %s",
+	"\n共 %d 个文件，新增 %d、修改 %d、删除 %d": "\n%d files in total, %d new, %d modified, %d deleted",
+	"，复制失败 %d":                     ", %d failed to copy",
+	"\n（模拟备份）":                     "\n(dry run)",
+	"\n错误: ":                       "\nError: ",
+	"Webhook 被服务器拒绝":               "Webhook rejected by the server",
+	"%w: 地址无效: %v":                 "%w: invalid URL: %v",
+	"发送 Webhook 失败: %v":            "Failed to send webhook: %v",
+	"Webhook 服务器返回错误: %s":          "Webhook server returned an error: %s",
+
+	// peer
+	"消息过长: %d 字节": "Message too long: %d bytes",
+	"消息格式错误: %v":  "Malformed message: %v",
+	"对方拒绝: %s":    "Rejected by peer: %s",
+	"意外的消息: %s":   "Unexpected message: %s",
+	"请先填写接收端地址":   "Please enter the receiver address first",
+	"连接接收端失败: %v": "Failed to connect to receiver: %v",
+	"接收端没有提供证书":   "The receiver did not provide a certificate",
+	"接收端证书已变化，可能是其他设备冒充接收端；确认无误后请重新配对": "The receiver certificate has changed, another device may be impersonating the receiver; pair again once you have confirmed it",
+	"接收端证书与配对码不符，可能是其他设备冒充接收端":         "The receiver certificate does not match the pairing code, another device may be impersonating the receiver",
+	"配对码不完整，请输入接收端显示的完整配对码":            "The pairing code is incomplete; enter the full code shown on the receiver",
+	"发送失败: %v": "Send failed: %v",
+	"配对失败: %v": "Pairing failed: %v",
+	"配对失败: 接收端无法证明持有配对码": "Pairing failed: the receiver could not prove it holds the pairing code",
+	"推送快照失败: %v":         "Failed to push snapshot: %v",
+	"接收端保存快照失败: %v":      "The receiver failed to save the snapshot: %v",
+	"读取文件失败: %v\n文件: %s": "Failed to read file: %v\nFile: %s",
+	"读取接收端设置失败: %v":      "Failed to read receiver settings: %v",
+	"解析接收端设置失败: %v":      "Failed to parse receiver settings: %v",
+	"创建目录失败: %v":         "Failed to create directory: %v",
+	"序列化接收端设置失败: %v":     "Failed to serialize receiver settings: %v",
+	"保存接收端设置失败: %v":      "Failed to save receiver settings: %v",
+	"生成密钥失败: %v":         "Failed to generate key: %v",
+	"生成证书失败: %v":         "Failed to generate certificate: %v",
+	"编码密钥失败: %v":         "Failed to encode key: %v",
+	"保存密钥失败: %v":         "Failed to save key: %v",
+	"保存证书失败: %v":         "Failed to save certificate: %v",
+	"请先选择接收目录":           "Please choose a receive directory first",
+	"配对码不能为空":            "Pairing code must not be empty",
+	"监听端口失败: %v":         "Failed to listen on port: %v",
+	"创建接收目录失败: %v":       "Failed to create receive directory: %v",
+	"接收失败: %v":           "Receive failed: %v",
+	"删除旧快照失败: %v":        "Failed to delete old snapshot: %v",
+	"保存快照失败: %v":         "Failed to save snapshot: %v",
+	"创建文件失败: %v":         "Failed to create file: %v",
+	"接收文件失败: %v\n文件: %s": "Failed to receive file: %v\nFile: %s",
+	"写入文件失败: %v\n文件: %s": "Failed to write file: %v\nFile: %s",
+	"非法路径: %s":           "Illegal path: %s",
+	"配对码不正确":             "Incorrect pairing code",
+
+	// schedule
+	"分钟": "minute",
+	"小时": "hour",
+	"日":  "day-of-month",
+	"月":  "month",
+	"星期": "day-of-week",
+	"定时表达式需要 5 个字段（分钟 小时 日 月 星期）: %q": "A schedule expression needs 5 fields (minute hour day month weekday): %q",
+	"%s字段的步长无效: %q":      "Invalid step in %s field: %q",
+	"%s字段无效: %q":         "Invalid %s field: %q",
+	"%s字段超出范围 %d-%d: %q": "%s field out of range %d-%d: %q",
+
+	// storage
+	"创建归档失败: %v":                     "Failed to create archive: %v",
+	"获取源文件信息失败: %v":                  "Failed to get source file info: %v",
+	"打开源文件失败: %v":                    "Failed to open source file: %v",
+	"归档已关闭":                          "Archive is closed",
+	"文件在复制期间被修改":                     "The file was modified while being copied",
+	"写入归档失败: %v\n文件: %s":             "Failed to write archive: %v\nFile: %s",
+	"归档不支持硬链接":                       "Archives do not support hard links",
+	"不能删除归档中的文件: %s":                 "Cannot delete files in an archive: %s",
+	"复制文件内容失败: %v":                   "Failed to copy file contents: %v",
+	"同步文件内容失败: %v":                   "Failed to sync file contents: %v",
+	"设置文件权限失败: %v":                   "Failed to set file permissions: %v",
+	"设置文件时间失败: %v":                   "Failed to set file times: %v",
+	"删除已存在的目标文件失败: %v":               "Failed to delete the existing destination file: %v",
+	"重命名文件失败: %v\n源文件: %s\n目标文件: %s": "Failed to rename file: %v\nSource file: %s\nDestination file: %s",
+	"加密文件失败: %v":                     "Failed to encrypt file: %v",
+	"目标不支持移动文件":                      "The destination does not support moving files",
+	"上一个快照中的文件已被修改: %s":              "A file in the previous snapshot has been modified: %s",
+	"WebDAV 地址无效: %v":                "Invalid WebDAV URL: %v",
+	"路径不在 WebDAV 目标中: %s":            "Path is not inside the WebDAV destination: %s",
+	"WebDAV 用户名或密码错误":                "Wrong WebDAV user name or password",
+	"WebDAV 存储空间不足":                  "Not enough WebDAV storage space",
+	"%s %s: 服务器返回 %s":                "%s %s: server returned %s",
+	"上传文件失败: %v\n文件: %s":             "Failed to upload file: %v\nFile: %s",
+	"创建分块上传目录失败: %v":                 "Failed to create chunked upload directory: %v",
+	"上传第 %d 块失败: %v\n文件: %s":         "Failed to upload chunk %d: %v\nFile: %s",
+	"合并分块失败: %v\n文件: %s":             "Failed to merge chunks: %v\nFile: %s",
+	"WebDAV 不支持硬链接":                  "WebDAV does not support hard links",
+	"删除失败: %v":                       "Delete failed: %v",
+	"移动失败: %v":                       "Move failed: %v",
+	"解析 WebDAV 响应失败: %v":             "Failed to parse WebDAV response: %v",
+	"WebDAV 服务器没有提供配额信息":             "The WebDAV server did not provide quota information",
+	"S3 地址缺少存储桶名称: %s":               "S3 URL is missing the bucket name: %s",
+	"S3 服务地址无效: %s":                  "Invalid S3 endpoint: %s",
+	"路径不在 S3 目标中: %s":                "Path is not inside the S3 destination: %s",
+	"S3 访问密钥错误或没有权限: %v":             "Wrong S3 access key or insufficient permissions: %v",
+	"S3 存储桶不存在: %s":                  "S3 bucket does not exist: %s",
+	"S3 不支持硬链接":                      "S3 does not support hard links",
+	"S3 目标没有容量信息":                    "The S3 destination has no capacity information",
+	"修改存储类别失败: %v\n文件: %s":           "Failed to change the storage class: %v\nFile: %s",
+	"请求取回失败: %v\n文件: %s":             "Failed to request retrieval: %v\nFile: %s",
+	"文件在冷存储中，需要先取回才能读取":              "The file is in cold storage and must be retrieved before it can be read",
+
+	// ui
+	"复制诊断信息":                "Copy diagnostics",
+	"诊断信息已复制到剪贴板":           "Diagnostics copied to the clipboard",
+	"关于":                    "About",
+	"关闭":                    "Close",
+	"输入 Git 用户名":            "Enter Git user name",
+	"输入 Git 邮箱":             "Enter Git email",
+	"输入仓库 HTTPS 地址":         "Enter repository HTTPS URL",
+	"输入访问令牌 (Access Token)": "Enter access token",
+	"启用 Git 备份":             "Enable Git backup",
+	"例如 @hourly 或 0 18 * * *，为空表示每次备份都提交": "For example @hourly or 0 18 * * *, empty commits on every backup",
+	"Git 平台":         "Git platform",
+	"选择 Git 托管平台":    "Choose the Git hosting platform",
+	"用户名":            "User name",
+	"您的 Git 用户名":     "Your Git user name",
+	"邮箱":             "Email",
+	"您的 Git 邮箱地址":    "Your Git email address",
+	"仓库地址":           "Repository URL",
+	"仓库的 HTTPS 克隆地址": "HTTPS clone URL of the repository",
+	"访问令牌":           "Access token",
+	"用于身份验证的访问令牌":    "Access token used for authentication",
+	"提交计划":           "Commit schedule",
+	"本地备份照常在每次变化后进行，Git 按该 cron 表达式把期间的所有变化合并为一次提交并推送": "Local backups still run after every change; Git combines all changes in between into one commit and pushes it on this cron schedule",
+	"\n### Git 配置说明\n\n#### 1. 平台选择\n- 支持 Gitee 和 GitHub\n- 请选择您已注册的平台\n\n#### 2. 基本信息\n- **用户名**: Git 提交时显示的作者名\n- **邮箱**: Git 提交关联的邮箱地址\n\n#### 3. 仓库配置\n- **仓库地址**: 使用 HTTPS 格式\n  - Gitee 格式: https://gitee.com/用户名/仓库名.git\n  - GitHub 格式: https://github.com/用户名/仓库名.git\n\n#### 4. 访问令牌\n- **Gitee**: 在 设置 -> 私人令牌 中生成\n- **GitHub**: 在 Settings -> Developer settings -> Personal access tokens 中生成\n- 确保令牌具有仓库的读写权限\n": "\n### Git configuration\n\n#### 1. Platform\n- Gitee and GitHub are supported\n- Choose the platform you have an account on\n\n#### 2. Basic information\n- **User name**: author name shown on Git commits\n- **Email**: email address associated with Git commits\n\n#### 3. Repository\n- **Repository URL**: use the HTTPS form\n  - Gitee: https://gitee.com/username/repository.git\n  - GitHub: https://github.com/username/repository.git\n\n#### 4. Access token\n- **Gitee**: generate one under Settings -> Private tokens\n- **GitHub**: generate one under Settings -> Developer settings -> Personal access tokens\n- Make sure the token has read and write access to the repository\n",
+	"Git 备份配置":        "Git backup configuration",
+	"Git 配置":          "Git configuration",
+	"确定":              "OK",
+	"取消":              "Cancel",
+	"提交计划无效: %v":      "Invalid commit schedule: %v",
+	"请选择 Git 平台":      "Please choose a Git platform",
+	"请输入 Git 用户名":     "Please enter a Git user name",
+	"请输入 Git 邮箱":      "Please enter a Git email",
+	"请输入仓库地址":         "Please enter a repository URL",
+	"请输入访问令牌":         "Please enter an access token",
+	"保存配置失败: %v":      "Failed to save configuration: %v",
+	"Git 仓库初始化失败: %v": "Failed to initialize Git repository: %v",
+	"Git 配置已更新":       "Git configuration updated",
+	"准备就绪":            "Ready",
+	"未选择源文件夹":         "No source folder selected",
+	"未选择目标文件夹":        "No destination folder selected",
+	"文件备份工具":          "File Backup Tool",
+	"选择源文件夹":          "Choose source folder",
+	"选择备份文件夹":         "Choose backup folder",
+	"已选择备份文件夹: ":      "Backup folder selected: ",
+	"开始监控":            "Start watching",
+	"立即备份":            "Back up now",
+	"性能测试":            "Benchmark",
+	"提权读取受保护文件":       "Read protected files with elevation",
+	"从文件系统快照读取":       "Read from a file system snapshot",
+	"模拟模式：写入操作只记录到命令输出，不会实际执行": "Dry run: write operations are only logged to the command output and not performed",
+	"已退出模拟模式":     "Dry run turned off",
+	"无变化时跳过":      "Skip when unchanged",
+	"增量快照（硬链接）":   "Incremental snapshots (hard links)",
+	"按内容比较":       "Compare by content",
+	"备份后校验":       "Verify after backup",
+	"生成共享索引":      "Generate share index",
+	"备份前提示变化":     "Prompt before backing up changes",
+	"快速同步（镜像）":    "Quick sync (mirror)",
+	"镜像删除移到回收文件夹": "Move mirror deletions to trash folder",
+	"去重存储池":       "Deduplicated storage pool",
+	"备份附加数据流":     "Back up alternate data streams",
+	"目录":          "Directory",
+	"自动":          "Auto",
+	"失败重试":        "Retry on failure",
+	"容量提醒":        "Capacity alerts",
+	"保留策略":        "Retention policy",
+	"备份范围":        "Backup scope",
+	"哈希设置":        "Hash settings",
+	"手机推送":        "Push notifications",
+	"邮件通知":        "Email notifications",
+	"定时备份":        "Scheduled backups",
+	"局域网同步":       "LAN sync",
+	"同步工具导出":      "Sync tool export",
+	"加密":          "Encryption",
+	"任务计划程序":      "Task Scheduler",
+	"暂停时段":        "Blackout periods",
+	"配置回滚":        "Configuration rollback",
+	"外观":          "Appearance",
+	"设置":          "Settings",
+	"修复 Git 仓库":   "Repair Git repository",
+	"源文件夹:":       "Source folder:",
+	"单独的文件":       "Individual files",
+	"路径模板":        "Path template",
+	"目标文件夹:":      "Destination folder:",
+	"关机或睡眠前:":     "Before shutdown or sleep:",
+	"快照格式:":       "Snapshot format:",
+	"并发复制:":       "Concurrent copies:",
+	"文件夹信息":       "Folder information",
+	"状态信息":        "Status",
+	"使用统计":        "Usage statistics",
+	"日志":          "Logs",
+	"备份":          "Backup",
+	"历史记录":        "History",
+	"还原":          "Restore",
+	"已选择源文件夹: ":   "Source folder selected: ",
+	"停止监控":        "Stop watching",
+	"请先选择目标文件夹":   "Please choose a destination folder first",
+	"检测到大量文件变化，暂停逐个处理，变化平静后重新扫描": "Large number of file changes detected, pausing per-file handling until changes settle, then rescanning",
+	"文件变化已平静，重新扫描完成":             "File changes have settled, rescan complete",
+	"切换监控目录失败: ":                 "Failed to switch the watched folder: ",
+	"开始监控文件变化":                   "Started watching for file changes",
+	"请选择文件夹:":                    "Choose a folder:",
+	"点击\"选择\"按钮浏览文件夹":            "Click \"Choose\" to browse for a folder",
+	"选择":                         "Choose",
+	"正在取消备份，完成后退出...":            "Cancelling backup, will exit when done...",
+	"SyncSafe 文件备份工具":            "SyncSafe File Backup Tool",
+	"恢复监控失败: ":                   "Failed to resume watching: ",
+	"视频":                         "Video",
+	"邮件":                         "Mail",
+	"账户":                         "Account",
+	"存储":                         "Storage",
+	"文件夹":                        "Folder",
+	"文档":                         "Document",
+	"电脑":                         "Computer",
+	"主页":                         "Home",
+	"图片":                         "Pictures",
+	"音乐":                         "Music",
+	"蓝色":                         "Blue",
+	"绿色":                         "Green",
+	"橙色":                         "Orange",
+	"红色":                         "Red",
+	"紫色":                         "Purple",
+	"青色":                         "Cyan",
+	"灰色":                         "Gray",
+	"为当前备份配置设置图标和颜色，显示在标题和历史记录中，便于区分不同的备份。": "Set an icon and color for the current backup profile. They are shown in the title and history to tell backups apart.",
+	"图标":              "Icon",
+	"颜色":              "Color",
+	"预览":              "Preview",
+	"保存":              "Save",
+	"外观已更新":           "Appearance updated",
+	"（模拟模式）":          " (dry run)",
+	"（故障注入: ":         " (fault injection: ",
+	"）":               ")",
+	"任务因连续失败已自动暂停":    "The profile was paused automatically after repeated failures",
+	"连续失败 %d 次，已自动暂停": "Paused automatically after %d consecutive failures",
+	"连续失败 %d 次，任务已自动暂停":       "Profile paused automatically after %d consecutive failures",
+	"任务已恢复，监控、定时和重试触发的备份恢复执行": "Profile resumed; backups triggered by watching, schedules and retries will run again",
+	"恢复": "Resume",
+	"连续失败 %d 次，已暂停监控、定时和重试触发的备份": "Paused backups triggered by watching, schedules and retries after %d consecutive failures",
+	"查看错误":        "View error",
+	"重试":          "Retry",
+	"尚未备份":        "Not backed up yet",
+	"上次备份已取消 %s":  "Last backup cancelled %s",
+	"上次备份成功 %s":   "Last backup succeeded %s",
+	"上次备份失败 %s":   "Last backup failed %s",
+	"准备测试...":     "Preparing benchmark...",
+	"性能测试失败: %v":  "Benchmark failed: %v",
+	"性能测试结果":      "Benchmark results",
+	"应用推荐的并发复制数":  "Apply recommended concurrent copies",
+	"并发复制数已设为 %d": "Concurrent copies set to %d",
+	"性能测试完成":      "Benchmark completed",
+	"处于暂停时段 %s，自动备份推迟到 %s":   "In blackout period %s, automatic backup postponed until %s",
+	"例如：视频渲染":                "For example: video rendering",
+	"每年重复":                   "Repeat every year",
+	"添加":                     "Add",
+	"开始日期格式错误，应为 YYYY-MM-DD": "Invalid start date, expected YYYY-MM-DD",
+	"结束日期格式错误，应为 YYYY-MM-DD": "Invalid end date, expected YYYY-MM-DD",
+	"结束日期不能早于开始日期":           "End date must not be earlier than start date",
+	"暂停备份":                   "Pause backups",
+	"导入节假日日历 (.ics)":         "Import holiday calendar (.ics)",
+	"读取日历失败: %v":             "Failed to read calendar: %v",
+	"解析日历失败: %v":             "Failed to parse calendar: %v",
+	"已导入 %d 个暂停时段":           "Imported %d blackout periods",
+	"名称":                     "Name",
+	"开始日期":                   "Start date",
+	"结束日期":                   "End date",
+	"暂停时段内不执行自动备份，时段结束后补做一次备份。手动备份不受影响。": "Automatic backups do not run during blackout periods; one backup runs after the period ends. Manual backups are not affected.",
+	"备份磁盘已使用 %d%%，剩余 %.1f GB":            "Backup disk is %d%% full, %.1f GB free",
+	"。删除最旧的 %d 个快照可释放约 %.1f GB":          ". Deleting the oldest %d snapshots would free about %.1f GB",
+	"备份磁盘空间不足":                           "Backup disk is running out of space",
+	"\n没有可以清理的旧快照。":                      "\nThere are no old snapshots to clean up.",
+	"\n\n建议删除以下快照（最近一次快照始终保留）:":          "\n\nSuggested snapshots to delete (the latest snapshot is always kept):",
+	"稍后":                              "Later",
+	"删除这些快照":                          "Delete these snapshots",
+	"已清理 %d 个旧快照":                     "Cleaned up %d old snapshots",
+	"无法读取目标磁盘容量":                      "Cannot read destination disk capacity",
+	"已使用 %d%%，剩余 %.1f GB / 共 %.1f GB": "%d%% used, %.1f GB free of %.1f GB",
+	"目标磁盘":                            "Destination disk",
+	"提醒阈值（%）":                         "Alert thresholds (%)",
+	"用逗号分隔，使用率每达到一个阈值提醒一次":                      "Comma-separated; alerts once each time usage reaches a threshold",
+	"每个文件保留版本数":                                 "Versions kept per file",
+	"清理快照前把每个文件最近的几个版本复制到 -versions 目录，0 表示不保留": "Before deleting snapshots, copy the latest few versions of each file to the -versions directory; 0 keeps none",
+	"阈值必须是 1 到 100 之间的整数: %s":                   "Threshold must be an integer between 1 and 100: %s",
+	"保留版本数必须是非负整数: %s":                          "Versions kept must be a non-negative integer: %s",
+	"容量提醒设置已保存":                                 "Capacity alert settings saved",
+	"配置文件 %s 已损坏，无法读取。\n\n是否从 %s 保存的备份恢复？\n损坏的文件将被保留以便排查。": "The configuration file %s is corrupted and cannot be read.\n\nRestore from the backup saved at %s?\nThe corrupted file will be kept for troubleshooting.",
+	"配置文件已损坏":        "Configuration file corrupted",
+	"配置文件已损坏，使用默认配置": "Configuration file corrupted, using default configuration",
+	"已从备份恢复配置":       "Configuration restored from backup",
+	"读取回滚包失败: %v":    "Failed to read rollback packages: %v",
+	"还原所选":           "Restore selected",
+	"保存当前配置":         "Save current configuration",
+	"手动保存":           "Saved manually",
+	"已保存当前配置的回滚包":    "Saved a rollback package of the current configuration",
+	"当前版本 %s（配置格式 %d）。升级或迁移配置前会自动保存回滚包，\n包含所有任务的配置、历史记录、本机设置和加密密钥。": "Current version %s (configuration format %d). A rollback package is saved automatically before upgrading or migrating the configuration,\nincluding all profiles' configuration, history, machine settings and encryption keys.",
+	"是否把配置还原到 %s 保存的状态？\n当前的配置会先保存为新的回滚包，之后也可以撤销。\n还原后所有任务的监控停止。":   "Restore the configuration to the state saved at %s?\nThe current configuration is saved as a new rollback package first, so this can be undone.\nWatching stops for all profiles after restoring.",
+	"还原配置": "Restore configuration",
+	"已还原到版本 %s 的配置，使用旧版本程序时请重新安装该版本": "Restored the configuration of version %s; reinstall that version to use the older program",
+	"加密备份内容":  "Encrypt backup contents",
+	"同时加密文件名": "Also encrypt file names",
+	"密码短语遗失后无法还原加密的快照，请另行妥善保存。\n同一目标中的快照必须使用同一个密码短语。": "Encrypted snapshots cannot be restored if the passphrase is lost, so keep it somewhere safe.\nSnapshots in the same destination must use the same passphrase.",
+	"快照文件夹名称不加密，其中的文件名和目录名加密；zip 归档中的文件名不加密":          "Snapshot folder names are not encrypted, but the file and directory names inside are; file names inside zip archives are not encrypted",
+	"密码短语":         "Passphrase",
+	"确认密码短语":       "Confirm passphrase",
+	"启用加密前请填写密码短语": "Please enter a passphrase before enabling encryption",
+	"两次输入的密码短语不一致": "The passphrases do not match",
+	"已启用备份加密":      "Backup encryption enabled",
+	"已关闭备份加密":      "Backup encryption disabled",
+	"浏览":           "Browse",
+	"浏览快照 %s":      "Browse snapshot %s",
+	"右键单击文件或目录可以还原或打开所在的文件夹": "Right-click a file or directory to restore it or open its containing folder",
+	"选择文件查看内容":         "Select a file to view its content",
+	"路径: %s\n修改时间: %s": "Path: %s\nModified: %s",
+	"\n大小: %s":         "\nSize: %s",
+	"右键单击可以还原整个目录":     "Right-click to restore the whole directory",
+	"该文件无法预览":          "This file cannot be previewed",
+	"……只显示前 %s":        "... only the first %s is shown",
+	"还原到源文件夹":          "Restore to the source folder",
+	"还原到其他位置...":       "Restore to another location...",
+	"打开所在的文件夹":         "Open containing folder",
+	"打开文件夹失败: %v":      "Failed to open the folder: %v",
+	"导出快照":             "Export snapshot",
+	"比较快照":             "Compare snapshots",
+	"查找文件":             "Find file",
+	"文件名、路径片段或通配符，例如 report*.docx，按回车查找": "File name, part of a path, or a wildcard such as report*.docx; press Enter to search",
+	"查找中...":    "Searching...",
+	"没有找到匹配的文件": "No matching files found",
+	"只显示前 %d 个版本，请输入更具体的文件名":            "Showing only the first %d versions; enter a more specific file name",
+	"修改于 %s，%s\n快照 %s，共 %d 个快照包含此版本":    "Modified %s, %s\nSnapshot %s; %d snapshots contain this version",
+	"通配符格式不正确: %s":                      "Invalid wildcard pattern: %s",
+	"至少需要两个快照才能比较":                      "At least two snapshots are needed for a comparison",
+	"较早的快照":                             "Older snapshot",
+	"较新的快照":                             "Newer snapshot",
+	"比较":                                "Compare",
+	"选择两个快照后点击「比较」":                     "Choose two snapshots and click \"Compare\"",
+	"请选择两个不同的快照":                        "Please choose two different snapshots",
+	"正在比较快照...":                         "Comparing snapshots...",
+	"%s → %s：新增 %d，删除 %d，修改 %d，大小变化 %s": "%s → %s: %d added, %d removed, %d changed, size change %s",
+	"正在比较文件内容...":                       "Comparing file contents...",
+	"内容相同，只有修改时间不同":                     "The content is identical; only the modification time differs",
+	"该快照已经是归档文件，可以直接复制:\n":              "This snapshot is already an archive file and can be copied directly:\n",
+	"共 %.2f MB":                         "%.2f MB in total",
+	"已打包 %.2f / %.2f MB":                "Packed %.2f / %.2f MB",
+	"导出完成":                              "Export completed",
+	"快照已导出到 %s\n归档大小: %s":               "Snapshot exported to %s\nArchive size: %s",
+	"快照已导出":                             "Snapshot exported",
+	"以 . 开头的文件和目录":                      "Files and directories starting with .",
+	"设置了隐藏属性的文件和目录":                     "Files and directories with the hidden attribute",
+	"以 . 开头或在访达中隐藏的文件和目录":               "Files and directories starting with . or hidden in Finder",
+	"排除隐藏文件":                            "Exclude hidden files",
+	"排除系统文件":                            "Exclude system files",
+	"设置了系统属性的文件和目录（仅 Windows）":          "Files and directories with the system attribute (Windows only)",
+	"排除点文件":                             "Exclude dotfiles",
+	"以 . 开头的文件和目录，例如 .env、.cache":       "Files and directories starting with ., such as .env and .cache",
+	"保留目录":                              "Keep directories",
+	"备份空目录，并保留目录的权限和修改时间":               "Back up empty directories and keep directory permissions and modification times",
+	"排除规则":                              "Exclude rules",
+	"每行一条，语法与 .gitignore 相同，以 ! 开头重新包含": "One per line, same syntax as .gitignore; lines starting with ! include again",
+	"匹配预览":                              "Match preview",
+	"遵守 .gitignore 和 .syncsafeignore":   "Honor .gitignore and .syncsafeignore",
+	"规则文件":                              "Ignore files",
+	"同时使用源文件夹根目录中规则文件的排除规则，跳过构建产物和 node_modules 等依赖目录，上面的规则可以用 ! 重新包含": "Also apply the exclude rules from ignore files in the root of the source folder, skipping build artifacts and dependency folders such as node_modules; the rules above can include files again with !",
+	"将跳过的目录": "Skipped folders",
+	"源文件夹中没有 .gitignore 或 .syncsafeignore": "The source folder has no .gitignore or .syncsafeignore",
+	"不会跳过任何顶层目录":                           "No top-level folders will be skipped",
+	"将跳过 %d 个顶层目录:":                        "%d top-level folders will be skipped:",
+	"重新开始监控失败: ":                           "Failed to restart watching: ",
+	"备份范围设置已保存，下次备份生效":                     "Backup scope saved, takes effect on the next backup",
+	"没有排除规则":                               "No exclude rules",
+	"将排除 %d 个文件或目录":                        "%d files or directories will be excluded",
+	"（只扫描了前 %d 项）":                         " (only the first %d entries were scanned)",
+	"未发现问题，仓库状态正常。":                        "No problems found, the repository is healthy.",
+	"失败: ":      "Failed: ",
+	"Git 仓库已修复": "Git repository repaired",
+	"重新提交工作区":   "Recommit working tree",
+	"放弃未完成的合并/变基，回到 master 分支，并把源文件夹当前内容提交为新版本。\n工作区文件不会被修改。": "Abort any unfinished merge or rebase, return to the master branch, and commit the current contents of the source folder as a new version.\nWorking tree files are not modified.",
+	"重置到远程版本": "Reset to remote",
+	"把本地分支重置到远程 origin/master，本地未推送的提交将被丢弃。\n工作区文件不会被修改，下次备份会重新提交。": "Reset the local branch to the remote origin/master; local commits that were not pushed are discarded.\nWorking tree files are not modified and will be committed again on the next backup.",
+	"重新克隆": "Clone again",
+	"把现有 .git 目录改名保留，重新初始化仓库并从远程获取历史。\n工作区文件不会被修改。": "Keep the existing .git directory under a new name, reinitialize the repository and fetch history from the remote.\nWorking tree files are not modified.",
+	"诊断结果":                "Diagnosis",
+	"修复操作":                "Repair actions",
+	"输出:":                 "Output:",
+	"Git 备份失败":            "Git backup failed",
+	"%s: %v\n\n是否打开修复向导？": "%s: %v\n\nOpen the repair wizard?",
+	"Git 提交计划无效: ":        "Invalid Git commit schedule: ",
+	"开始定时提交到 Git 仓库":      "Starting scheduled commit to the Git repository",
+	"定时提交 Git 失败":         "Scheduled Git commit failed",
+	"定时提交 Git 失败: ":       "Scheduled Git commit failed: ",
+	"保存配置失败: ":            "Failed to save configuration: ",
+	"定时提交 Git 完成":         "Scheduled Git commit completed",
+	"快速模式":                "Fast mode",
+	"不计算哈希，只按大小和修改时间判断；按内容比较和备份后校验只对下面的目录生效": "No hashes are computed, changes are detected by size and modification time; content comparison and post-backup verification only apply to the directories below",
+	"计算哈希的目录": "Directories to hash",
+	"每行一个相对源文件夹的目录，快速模式下仍按内容比较和校验": "One directory relative to the source folder per line; still compared and verified by content in fast mode",
+	"不计算哈希的目录": "Directories not to hash",
+	"每行一个目录，始终只按大小和修改时间判断；目录重叠时更深的一条优先": "One directory per line, always compared by size and modification time only; the deeper entry wins when directories overlap",
+	"哈希设置已保存，下次备份生效":                    "Hash settings saved, takes effect on the next backup",
+	"源文件夹中有 %d 个新出现的无法读取的路径":            "%d new unreadable paths appeared in the source folder",
+	"，备份时将尝试通过提权读取":                     ", the backup will try reading them with elevation",
+	"源文件夹健康检查":                          "Source folder health check",
+	"... 以及另外 %d 个":                     "... and %d more",
+	"备份历史记录":                            "Backup history",
+	"总备份次数":                             "Total backups",
+	"成功次数":                              "Successful",
+	"失败次数":                              "Failed",
+	"成功率":                               "Success rate",
+	"搜索备注、错误信息或路径":                      "Search notes, error messages or paths",
+	"源路径:":                              "Source path:",
+	"目标路径:":                             "Destination path:",
+	"文件统计":                              "File statistics",
+	"文件变更":                              "File changes",
+	"备份信息":                              "Backup information",
+	"备注:":                               "Note:",
+	"校验":                                "Verify",
+	"编辑备注":                              "Edit note",
+	"详情":                                "Details",
+	"已取消":                               "Cancelled",
+	"模拟（未写入）":                           "Dry run (nothing written)",
+	"失败\n%s":                            "Failed\n%s",
+	"（快照已清理）":                           " (snapshot pruned)",
+	"（内容与上一个快照相同）":                      " (same content as the previous snapshot)",
+	"第 %d 次尝试 %s":                       "Attempt %d %s",
+	"已校验":                               "Verified",
+	"校验发现 %d 个问题":                       "Verification found %d problems",
+	"总文件: %d\n大小: %.2f MB":              "Total files: %d\nSize: %.2f MB",
+	"\n硬链接: %d":                         "\nHard links: %d",
+	"\n存储池中已有: %d":                      "\nAlready in pool: %d",
+	"\n复制失败: %d":                        "\nFailed to copy: %d",
+	"\n附加数据流: %d":                       "\nAlternate data streams: %d",
+	"\n附加数据流未备份: %d":                    "\nAlternate data streams not backed up: %d",
+	"新增: %d":                            "New: %d",
+	"修改: %d":                            "Modified: %d",
+	"删除: %d":                            "Deleted: %d",
+	"耗时: %v\n峰值内存: %.1f MB\n状态: %s":     "Duration: %v\nPeak memory: %.1f MB\nStatus: %s",
+	"正在加载历史记录...":                       "Loading history...",
+	"清除历史记录":                            "Clear history",
+	"是否要清除所有历史记录？":                      "Clear all history?",
+	"是否要清除符合搜索条件的 %d 条历史记录？": "Clear the %d history records matching the search?",
+	"是否要清除 %s 的历史记录？":        "Clear the history of %s?",
+	"确认":                     "Confirm",
+	"导出历史记录":                 "Export history",
+	"搜索:":                    "Search:",
+	"例如：重装系统前的备份":            "For example: backup before reinstalling the system",
+	"备份时间":                   "Backup time",
+	"导出历史记录失败: %v":           "Failed to export history: %v",
+	"第 %d/%d 条：%s":           "Record %d/%d: %s",
+	"展开详情":                   "Expand details",
+	"导出变更清单":                 "Export change list",
+	"复制摘要":                   "Copy summary",
+	"已复制备份记录摘要":              "Backup record summary copied",
+	"导出变更清单失败: %v":           "Failed to export change list: %v",
+	"变更清单已导出":                "Change list exported",
+	"概要":                     "Summary",
+	"变更的文件（%d）":              "Changed files (%d)",
+	"备份详情":                   "Backup details",
+	"另有 %d 个变化没有记录，完整的文件列表见快照清单":          "%d more changes were not recorded, see the snapshot manifest for the full file list",
+	"%s %s，%s，共 %d 个文件，新增 %d、修改 %d、删除 %d": "%s %s, %s, %d files in total, %d new, %d modified, %d deleted",
+	"，备注：":                    ", note: ",
+	"状态: %s\n":                "Status: %s\n",
+	"自动备份尝试: 第 %d 次\n":        "Automatic backup attempt: %d\n",
+	"源路径: %s\n":               "Source path: %s\n",
+	"目标路径: %s\n":              "Destination path: %s\n",
+	"快照已清理\n":                 "Snapshot pruned\n",
+	"文件: 共 %d 个，%.2f MB\n":    "Files: %d in total, %.2f MB\n",
+	"变更: 新增 %d、修改 %d、删除 %d\n": "Changes: %d new, %d modified, %d deleted\n",
+	"硬链接: %d\n":               "Hard links: %d\n",
+	"存储池中已有: %d\n":            "Already in pool: %d\n",
+	"复制失败: %d\n":              "Failed to copy: %d\n",
+	"附加数据流: %d\n":             "Alternate data streams: %d\n",
+	"附加数据流未备份: %d\n":          "Alternate data streams not backed up: %d\n",
+	"内容哈希: %s\n":              "Content hash: %s\n",
+	"已加密\n":                   "Encrypted\n",
+	"备份后校验通过\n":               "Passed post-backup verification\n",
+	"校验通过: %s\n":              "Verified: %s\n",
+	"备注: %s\n":                "Note: %s\n",
+	"\n校验发现 %d 个问题:\n%s\n":    "\nVerification found %d problems:\n%s\n",
+	"\n大小异常: %s\n":            "\nSize anomaly: %s\n",
+	"创建目录 %s":                 "Create directory %s",
+	"创建收件箱失败: %v":             "Failed to create inbox: %v",
+	"剪贴板中没有文本内容":              "The clipboard has no text",
+	"剪贴板":                     "Clipboard",
+	"保存剪贴板到 %s":               "Save clipboard to %s",
+	"保存剪贴板失败: %v":             "Failed to save clipboard: %v",
+	"剪贴板已保存到收件箱: ":            "Clipboard saved to inbox: ",
+	"截图":                      "Screenshot",
+	"截图保存到 %s":                "Save screenshot to %s",
+	"截图失败: %v":                "Screenshot failed: %v",
+	"截图已保存到收件箱: ":             "Screenshot saved to inbox: ",
+	"未找到可用的截图工具（grim、gnome-screenshot、spectacle、scrot 或 import）": "No screenshot tool found (grim, gnome-screenshot, spectacle, scrot or import)",
+	"收件箱":                         "Inbox",
+	"待复查（%d）":                     "To review (%d)",
+	"校验不一致":                       "Verification mismatch",
+	"大小写冲突":                       "Case collision",
+	"复查队列":                        "Review queue",
+	"复查队列（%d）":                    "Review queue (%d)",
+	"没有等待复查的问题":                   "There are no issues to review",
+	"%d 个问题将在本次备份中重试":             "%d issues will be retried in this backup",
+	"[%s] %s\n%s\n共 %d 次，最近一次 %s": "[%s] %s\n%s\n%d times, last at %s",
+	"，已上报":                        ", escalated",
+	"忽略":                          "Ignore",
+	"永久忽略":                        "Ignore permanently",
+	"以后不再备份 %s？\n将在排除规则中添加该路径，可以在「备份范围」中删除。": "Stop backing up %s?\nThe path is added to the exclusion rules and can be removed under \"Backup scope\".",
+	"已在排除规则中添加 %s": "Added %s to the exclusion rules",
+	"上报":           "Escalate",
+	"已上报 %s":       "Escalated %s",
+	"这些问题没有中断备份。重试会在下一次备份中重新读取并复制该文件；之后的备份没有再发现问题时自动移出队列。": "These issues did not stop the backup. Retry reads and copies the file again in the next backup; issues that later backups no longer find leave the queue automatically.",
+	"全部重试":                 "Retry all",
+	"保存剪贴板 (Ctrl+Shift+V)": "Save clipboard (Ctrl+Shift+V)",
+	"保存截图 (Ctrl+Shift+S)":  "Save screenshot (Ctrl+Shift+S)",
+	"自动备份 %d 次（平均每天 %.1f 次），手动备份 %d 次": "%d automatic backups (%.1f per day on average), %d manual backups",
+	"暂无成功的备份":              "No successful backups yet",
+	"没有失败的备份":              "No failed backups",
+	"共失败 %d 次，最常见的原因:":     "%d failures in total, most common causes:",
+	"%d 次  %s":             "%d times  %s",
+	"现有快照共 %s，最近平均每天新增 %s": "Existing snapshots total %s, recently growing by %s per day on average",
+	"%s  约 %s":             "%s  about %s",
+	"根据本机的历史记录统计，不收集或上传任何数据": "Computed from this computer's history; no data is collected or uploaded",
+	"刷新":         "Refresh",
+	"最近 30 天":    "Last 30 days",
+	"平均耗时":       "Average duration",
+	"失败原因":       "Failure causes",
+	"存储增长预测":     "Storage growth forecast",
+	"不导出":        "Do not export",
+	"立即导出最新快照":   "Export latest snapshot now",
+	"请先选择导出布局":   "Please choose an export layout first",
+	"还没有可以导出的快照": "There are no snapshots to export yet",
+	"每次备份后":      "After each backup",
+	"rsync: 可作为 rsync --link-dest 的基准；Syncthing: 把目录添加为 Syncthing 共享文件夹": "rsync: usable as the rsync --link-dest base; Syncthing: add the directory as a Syncthing shared folder",
+	"导出目录": "Export directory",
+	"Syncthing 布局会删除快照中没有的文件，只能使用空目录或已有的 Syncthing 文件夹": "The Syncthing layout deletes files that are not in the snapshot, so only use an empty directory or an existing Syncthing folder",
+	"导出到同步工具":   "Export to sync tools",
+	"导出设置已保存":   "Export settings saved",
+	"正在导出快照...": "Exporting snapshot...",
+	"导出失败":      "Export failed",
+	"快照已导出到 %s\n复制 %d 个文件，硬链接 %d 个，删除 %d 个": "Snapshot exported to %s\n%d files copied, %d hard-linked, %d deleted",
+	"任务已自动暂停，跳过本次自动备份":                      "Profile is paused automatically, skipping this automatic backup",
+	"已有备份正在等待中...":                          "A backup is already waiting...",
+	"已有备份正在进行中，完成后开始下一次备份":                  "A backup is already in progress, the next one starts when it finishes",
+	"%s\n共 %d 个文件，新增 %d、修改 %d、删除 %d":        "%s\n%d files in total, %d new, %d modified, %d deleted",
+	"创建 applications 目录失败: %v":              "Failed to create applications directory: %v",
+	"文件备份":                                  "File backup",
+	"当前系统不支持程序坞快捷操作":                        "Dock quick actions are not supported on this system",
+	"COM 调用失败: 0x%08x":                      "COM call failed: 0x%08x",
+	"创建 COM 对象失败: 0x%08x":                   "Failed to create COM object: 0x%08x",
+	"初始化 COM 失败: 0x%08x":                    "Failed to initialize COM: 0x%08x",
+	"没有符合条件的日志":                             "No matching log entries",
+	"共 %d 条日志（最多显示最新的 %d 条）":                "%d log entries (showing at most the latest %d)",
+	"搜索消息或字段":                               "Search messages or fields",
+	"日志记录级别已设置为":                            "Log level set to ",
+	"显示":                                    "Show",
+	"记录级别":                                  "Log level",
+	"发送测试通知":                                "Send test notification",
+	"正在发送测试通知...":                           "Sending test notification...",
+	"SyncSafe 测试通知":                         "SyncSafe test notification",
+	"推送设置正常":                                "Push settings are working",
+	"测试通知已发送":                               "Test notification sent",
+	"启用推送":                                  "Enable push",
+	"推送服务":                                  "Push service",
+	"服务器地址":                                 "Server address",
+	"ntfy 留空时使用 ":                           "Defaults for ntfy when empty: ",
+	"ntfy 主题":                               "ntfy topic",
+	"Gotify 不需要填写":                          "Not needed for Gotify",
+	"ntfy 访问令牌（可选）或 Gotify 应用令牌，只保存在本机": "ntfy access token (optional) or Gotify application token, stored only on this computer",
+	"推送级别":    "Push level",
+	"推送设置已保存": "Push settings saved",
+	"不加密":     "None",
+	"与用户名相同":  "Same as user name",
+	"端口必须是 1-65535 之间的整数: %s": "Port must be an integer between 1 and 65535: %s",
+	"发送测试邮件":                  "Send test email",
+	"正在发送测试邮件...":             "Sending test email...",
+	"[SyncSafe] 测试邮件":         "[SyncSafe] Test email",
+	"邮件通知设置正常，备份失败时会发送到这个地址。": "Email notifications are working; failed backups will be reported to this address.",
+	"测试邮件已发送":   "Test email sent",
+	"备份失败时发送邮件": "Email when a backup fails",
+	"SMTP 服务器":  "SMTP server",
+	"加密方式":      "Encryption",
+	"端口":        "Port",
+	"留空时使用加密方式的默认端口":   "Leave empty to use the default port of the encryption method",
+	"服务器不需要登录时留空":      "Leave empty if the server does not require login",
+	"密码":               "Password",
+	"通常为邮箱的授权码，只保存在本机": "Usually the mailbox app password, stored only on this computer",
+	"发件人":                         "Sender",
+	"收件人":                         "Recipients",
+	"多个地址用逗号分隔":                   "Separate multiple addresses with commas",
+	"邮件通知设置已保存":                   "Email notification settings saved",
+	"（仅失败）":                       " (failures only)",
+	"只发送失败的备份":                    "Only send failed backups",
+	"发送测试":                        "Send test",
+	"SyncSafe 测试消息":               "SyncSafe test message",
+	"正在发送测试 Webhook...":           "Sending test webhook...",
+	"测试 Webhook 已发送":              "Test webhook sent",
+	"立即重试":                        "Retry now",
+	"有 %d 条发送失败的 Webhook 等待重试":    "%d failed webhooks are waiting to be retried",
+	"已重新发送 %d 条 Webhook，%d 条仍在等待": "Resent %d webhooks, %d still waiting",
+	"地址": "URL",
+	"格式": "Format",
+	"每次备份后把备份摘要发送到这些地址。地址中通常含有密钥，只保存在本机。": "A backup summary is sent to these URLs after every backup. URLs usually contain secrets and are stored only on this computer.",
+	"复制":      "Copy",
+	"已复制命令输出": "Command output copied",
+	"清空":      "Clear",
+	"命令输出":    "Command output",
+	"已收到 %s 的快照，共 %d 个文件": "Received a snapshot from %s, %d files in total",
+	"接收 %s 的快照失败: %v":     "Failed to receive snapshot from %s: %v",
+	"启动局域网接收失败: ":         "Failed to start LAN receiver: ",
+	"重新配对":                "Pair again",
+	"备份后推送":               "Push after backup",
+	"接收端地址":               "Receiver address",
+	"接收端设置中显示的地址":         "The address shown in the receiver settings",
+	"配对码":                 "Pairing code",
+	"接收端显示的完整配对码，只保存在本机":  "The full pairing code shown on the receiver, stored only on this computer",
+	"接收端指纹":               "Receiver fingerprint",
+	"首次推送时记录，接收端更换证书后需要重新配对": "Recorded on the first push; pair again after the receiver changes its certificate",
+	"重新生成":     "Regenerate",
+	"未找到局域网地址": "No LAN address found",
+	"接收快照":     "Receive snapshots",
+	"保存到":      "Save to",
+	"按发送端的机器名称分子目录保存":        "Saved in subdirectories named after the sender's computer",
+	"在发送端完整输入，重新生成后原来的配对码失效": "Enter the full code on the sender; regenerating invalidates the previous pairing code",
+	"本机地址":                  "Local addresses",
+	"可与发送端记录的指纹核对":          "Can be compared with the fingerprint recorded by the sender",
+	"证书指纹":                  "Certificate fingerprint",
+	"推送到其他设备":               "Push to another device",
+	"作为接收端":                 "Act as receiver",
+	"端口必须是 1 到 65535 之间的数字": "Port must be a number between 1 and 65535",
+	"局域网同步设置已保存":            "LAN sync settings saved",
+	"尚未配对":                  "Not paired yet",
+	"不处理":                   "Do nothing",
+	"备份待处理的更改":              "Back up pending changes",
+	"执行快速备份":                "Run a quick backup",
+	"关机":                    "shutdown",
+	"睡眠":                    "sleep",
+	"即将%s，正在备份...":          "About to %s, backing up...",
+	"关机或睡眠前完成备份":            "Finish backup before shutdown or sleep",
+	"未找到 gdbus: %v":         "gdbus not found: %v",
+	"启动 gdbus 失败: %v":       "Failed to start gdbus: %v",
+	"当前平台不支持电源事件":           "Power events are not supported on this platform",
+	"SyncSafe 正在完成备份":       "SyncSafe is finishing a backup",
+	"注册窗口类失败: %v":           "Failed to register window class: %v",
+	"创建窗口失败: %v":            "Failed to create window: %v",
+	"已切换到任务: ":              "Switched to profile: ",
+	"新建任务":                  "New profile",
+	"重命名":                   "Rename",
+	"重命名任务":                 "Rename profile",
+	"全局搜索":                  "Global search",
+	"备份任务:":                 "Backup profile:",
+	"例如：工作文档":               "For example: Work documents",
+	"任务名称":                  "Profile name",
+	"是否删除任务 %s？\n任务的设置和历史记录将被删除，已有的快照不会删除。": "Delete profile %s?\nIts settings and history will be deleted; existing snapshots are kept.",
+	"删除任务":         "Delete profile",
+	"取消备份":         "Cancel backup",
+	"正在取消备份...":    "Cancelling backup...",
+	"暂停/恢复监控":      "Pause/resume watching",
+	"打开历史记录":       "Open history",
+	"快捷操作被拒绝: %s":  "Quick action rejected: %s",
+	"已开始备份所有任务":    "Started backing up all profiles",
+	"原位置（源文件夹）":    "Original location (source folder)",
+	"其他位置":         "Another location",
+	"选择要还原的快照":     "Choose the snapshot to restore",
+	"选择还原位置":       "Choose the restore location",
+	"还原选中的文件":      "Restore selected files",
+	"请先选择快照":       "Please choose a snapshot first",
+	"请勾选要还原的文件或目录": "Please check the files or directories to restore",
+	"请选择还原位置":      "Please choose a restore location",
+	"快照:":          "Snapshot:",
+	"勾选要还原的文件或目录，勾选目录会还原其中的所有内容:": "Check the files or directories to restore; checking a directory restores everything in it:",
+	"还原到:":                    "Restore to:",
+	"%s 中已有 %d 个同名文件:":        "%s already contains %d files with the same names:",
+	"文件已存在":                   "Files already exist",
+	"跳过已有文件":                  "Skip existing files",
+	"全部覆盖":                    "Overwrite all",
+	"正在还原 %s 的快照...":          "Restoring the snapshot of %s...",
+	"还原失败: ":                  "Restore failed: ",
+	"已还原 %d 个文件（%.2f MB）到 %s": "Restored %d files (%.2f MB) to %s",
+	"，跳过 %d 个已有文件":            ", skipped %d existing files",
+	"，写回 %d 个文件的附加数据流":        ", wrote back alternate data streams of %d files",
+	"。%d 个文件的附加数据流（备用数据流、资源分支或标签）无法写回，目标文件系统可能不支持": ". Alternate data streams (ADS, resource forks or tags) of %d files could not be written back; the destination file system may not support them",
+	"还原完成":               "Restore completed",
+	"按保留策略清理快照失败: ":      "Failed to prune snapshots by retention policy: ",
+	"已按保留策略清理 %d 个旧快照":   "Pruned %d old snapshots by retention policy",
+	"现有的快照都会保留":          "All existing snapshots will be kept",
+	"将清理 %d 个快照，释放约 %s:": "%d snapshots will be pruned, freeing about %s:",
+	"保留最近":               "Keep last",
+	"个快照":                "snapshots",
+	"每天保留":               "Keep daily",
+	"天，每天最后一个快照":         "days, the last snapshot of each day",
+	"每周保留":               "Keep weekly",
+	"周，每周最后一个快照":         "weeks, the last snapshot of each week",
+	"每月保留":               "Keep monthly",
+	"个月，每月最后一个快照":        "months, the last snapshot of each month",
+	"%s必须是非负整数: %s":      "%s must be a non-negative integer: %s",
+	"没有设置保留策略，不会自动清理快照":  "No retention policy set, snapshots are never pruned automatically",
+	"保留策略已保存":            "Retention policy saved",
+	"快照大小骤变时提醒并暂停清理":     "Alert and pause pruning when the snapshot size changes abruptly",
+	"清理前要求较新的快照已通过校验":    "Require a newer verified snapshot before pruning",
+	"校验保护":               "Verification gate",
+	"备份后校验或手动校验通过的快照才能作为依据，避免删除仅有的完好副本": "Only snapshots that passed post-backup or manual verification count, so the only good copy is never deleted",
+	"校验有效期": "Verification valid for",
+	"天内通过的校验才算数，0 表示不限":         "days; 0 means no limit",
+	"其中 %d 个快照要等较新的快照通过校验后才会清理": "%d of them will only be pruned once a newer snapshot passes verification",
+	"异常检测": "Anomaly detection",
+	"文件数或大小不到最近几次备份平均值的一半或超过其三倍时提醒，确认正常之前不清理旧快照": "Alert when the file count or size drops below half or exceeds three times the average of the last few backups; old snapshots are not pruned until confirmed",
+	"快照大小异常": "Snapshot size anomaly",
+	"确认正常之前不会按保留策略清理旧快照。如果文件确实被误删，可以在「还原」页从之前的快照找回。": "Old snapshots will not be pruned by the retention policy until this is confirmed. If files were really deleted by mistake, recover them from an earlier snapshot on the Restore tab.",
+	"确认正常":      "Confirm as expected",
+	"稍后处理":      "Later",
+	"已确认快照大小正常": "Snapshot size confirmed as expected",
+	"确认清理快照":    "Confirm snapshot pruning",
+	"新的保留策略会立即清理 %d 个快照，释放约 %s，删除后无法恢复。是否继续？": "The new retention policy will immediately prune %d snapshots, freeing about %s. They cannot be recovered after deletion. Continue?",
+	"自动备份重试 %d 次后仍然失败":          "Automatic backup still failed after %d retries",
+	"自动备份失败（共尝试 %d 次）":          "Automatic backup failed (%d attempts in total)",
+	"自动备份失败（共尝试 %d 次）: %v":      "Automatic backup failed (%d attempts in total): %v",
+	"备份失败，%s 后进行第 %d 次重试":       "Backup failed, retry %[2]d in %[1]s",
+	"备份失败，%s 后重试":               "Backup failed, retrying in %s",
+	"重试次数":                      "Retries",
+	"监控触发的备份失败后自动重试的次数，0 表示不重试": "How many times a failed watch-triggered backup is retried automatically; 0 disables retries",
+	"重试间隔（分钟）":                  "Retry interval (minutes)",
+	"每次重试前等待的时间":                "Time to wait before each retry",
+	"连续失败后暂停":                   "Pause after consecutive failures",
+	"连续失败这么多次（重试只算一次）后自动暂停任务，0 表示从不暂停": "Pause the profile automatically after this many consecutive failures (retries count once); 0 never pauses",
+	"重试次数必须是非负整数":                   "Retries must be a non-negative integer",
+	"重试间隔必须是正整数":                    "Retry interval must be a positive integer",
+	"连续失败次数必须是非负整数":                 "Consecutive failures must be a non-negative integer",
+	"失败重试设置已保存":                     "Retry settings saved",
+	"定时表达式无效: ":                     "Invalid schedule expression: ",
+	"处于暂停时段 %s，跳过本次定时备份":            "In blackout period %s, skipping this scheduled backup",
+	"开始定时备份":                        "Starting scheduled backup",
+	"定时备份已暂停":                       "Scheduled backups paused",
+	"下次定时备份: ":                      "Next scheduled backup: ",
+	"例如 0 2 * * * 表示每天 2:00":        "For example 0 2 * * * means every day at 2:00",
+	"暂停":                            "Pause",
+	"例如 0 3 * * 0 表示每周日 3:00，为空不巡检": "For example 0 3 * * 0 means every Sunday at 3:00; empty disables scrubbing",
+	"立即巡检":                          "Scrub now",
+	"不定时备份":                         "No scheduled backups",
+	"该表达式不会运行":                      "This expression never runs",
+	"定时表达式":                         "Schedule expression",
+	"分钟 小时 日 月 星期，也可以使用 @hourly、@daily、@weekly": "minute hour day month weekday; @hourly, @daily and @weekly also work",
+	"接下来运行": "Next runs",
+	"时区":    "Time zone",
+	"定时计划、暂停时段和历史记录中的时间都按该时区，为空表示本机时区": "Schedules, blackout periods and history times use this time zone; leave empty for the local time zone",
+	"本机时区（%s）": "Local time zone (%s)",
+	"数据巡检":     "Data scrubbing",
+	"定期读出目标文件夹中的所有快照，发现坏扇区和静默损坏并尽量从其他副本修复": "Periodically reads back every snapshot in the destination folder to find bad sectors and silent corruption, repairing from other copies where possible",
+	"定时备份设置已保存":   "Scheduled backup settings saved",
+	"数据巡检表达式无效: ": "Invalid scrub expression: ",
+	"开始数据巡检":      "Starting data scrub",
+	": 已修复":       ": repaired",
+	"搜索所有任务的备注、错误信息、路径和快照中的文件名，按回车搜索": "Search notes, error messages, paths and file names in snapshots across all profiles; press Enter to search",
+	"搜索中...":     "Searching...",
+	"%s（%d）":     "%s (%d)",
+	"%s（已归档，%d）": "%s (archived, %d)",
+	"没有找到匹配的结果":  "No matches found",
+	"%s\n快照 %s":  "%s\nSnapshot %s",
+	"\n备注: ":     "\nNote: ",
+	"\n失败: ":     "\nFailed: ",
+	"跳转":         "Go to",
+	"界面语言":       "Interface language",
+	"界面语言已切换":    "Interface language changed",
+	"跟随系统":       "Follow system",
+	"浅色":         "Light",
+	"深色":         "Dark",
+	"主题":         "Theme",
+	"监控延迟（秒）":    "Watch delay (seconds)",
+	"最后一次文件变化后等待多久开始备份": "How long to wait after the last file change before backing up",
+	"并发复制数": "Concurrent copies",
+	"没有单独设置并发复制数的任务使用该值": "Used by profiles without their own concurrent copy setting",
+	"启动": "Startup",
+	"开机自动启动时只显示托盘图标": "Start minimized to the system tray when launched at login",
+	"登录系统时自动启动":      "Start automatically on login",
+	"日志记录级别":         "Log level",
+	"新建任务的默认保留策略":    "Default retention policy for new profiles",
+	"监控延迟必须是正整数":     "Watch delay must be a positive integer",
+	"设置已保存":          "Settings saved",
+	"配置检查":           "Check configuration",
+	"没有发现配置问题":       "No configuration problems found",
+	"红色标记的问题会阻止备份，其他问题只会使对应的功能不生效。": "Problems marked in red prevent backups; the others only stop the related feature from working.",
+	"配置问题（%d）":                          "Configuration problems (%d)",
+	"创建 autostart 目录失败: %v":             "Failed to create autostart directory: %v",
+	"取消开机自动启动失败: %v":                    "Failed to disable start on login: %v",
+	"创建 LaunchAgents 目录失败: %v":          "Failed to create LaunchAgents directory: %v",
+	"打开注册表失败: %v":                       "Failed to open the registry: %v",
+	"设置开机自动启动失败: %v":                    "Failed to enable start on login: %v",
+	"当前系统不支持开机自动启动":                     "Start on login is not supported on this system",
+	"源文件夹不可用，监控已停止: ":                   "Source folder unavailable, watching stopped: ",
+	"路径恢复后自动继续监控":                       "Watching resumes automatically when the path comes back",
+	"监控的源文件夹已被删除、重命名或卸载":                "The watched source folder was deleted, renamed or unmounted",
+	"监控已停止，在处理之前不会再进行自动备份。":             "Watching has stopped; no automatic backups will run until this is resolved.",
+	"源文件夹不可用":                           "Source folder unavailable",
+	"重新选择源文件夹":                          "Choose source folder again",
+	"等待源文件夹恢复: %s":                      "Waiting for source folder to come back: %s",
+	"自动恢复监控失败: ":                        "Failed to resume watching automatically: ",
+	"源文件夹已恢复，继续监控":                      "Source folder is back, watching resumed",
+	"正在建立索引...":                         "Building index...",
+	"文件数: %d  总大小: %.2f MB  (索引更新于 %s)": "Files: %d  Total size: %.2f MB  (index updated %s)",
+	"添加文件":                              "Add files",
+	"只备份源文件夹中的这些文件，列表为空时备份整个文件夹。\n其他文件夹中的文件请新建一个任务。": "Only these files in the source folder are backed up; an empty list backs up the whole folder.\nCreate a new profile for files in other folders.",
+	"每天":          "Daily",
+	"每小时":         "Hourly",
+	"登录时":         "At logon",
+	"正在查询...":     "Querying...",
+	"取消注册":        "Unregister",
+	"已从任务计划程序中删除": "Removed from Task Scheduler",
+	"运行频率":        "Frequency",
+	"运行时间":        "Run time",
+	"每天运行的时间，格式为 HH:MM": "Daily run time in HH:MM format",
+	"注册":            "Register",
+	"已注册到任务计划程序：%s": "Registered with Task Scheduler: %s",
+	"任务计划程序中的备份任务已被删除": "The backup task in Task Scheduler has been deleted",
+	"计划任务":           "Scheduled task",
+	"%s\n当前: %s":     "%s\nCurrent: %s",
+	"\n只备份其中 %d 个文件": "\nOnly %d files in it are backed up",
+	"当前展开为: ":        "Currently expands to: ",
+	"可以在路径中使用日期占位符，每次备份时按当前时间展开：\n":                "Date placeholders in the path are expanded with the current time on every backup:\n",
+	"{yyyy} 年  {yy} 两位年  {MM} 月  {dd} 日  {HH} 时\n": "{yyyy} year  {yy} two-digit year  {MM} month  {dd} day  {HH} hour\n",
+	"也可以组合使用，例如 {yyyy-MM} 或 {yyyy}/{MM}":           "They can be combined, for example {yyyy-MM} or {yyyy}/{MM}",
+	"源路径模板":   "Source path template",
+	"源路径不能为空": "Source path must not be empty",
+	"程序仍在后台运行，可以从系统托盘打开或退出": "The program is still running in the background and can be opened or quit from the system tray",
+	"暂停监控":     "Pause watching",
+	"恢复监控":     "Resume watching",
+	"退出":       "Quit",
+	"显示窗口":     "Show window",
+	"正在备份...":  "Backing up...",
+	"最近的备份失败":  "The latest backup failed",
+	"空闲":       "Idle",
+	"上次备份: %s": "Last backup: %s",
+	"已暂停监控":    "Watching paused",
+	"保留较新的版本":  "Keep the newer version",
+	"两个版本都保留":  "Keep both versions",
+	"手动选择":     "Choose manually",
+	"另一台电脑也在同步的文件夹，例如 NAS 上的共享文件夹": "A folder another computer also syncs, such as a shared folder on a NAS",
+	"处理冲突（%d）": "Resolve conflicts (%d)",
+	"同步文件夹":    "Sync folder",
+	"每次备份前与源文件夹互相同步，两侧的新增、修改和删除都会传到另一侧；为空表示不同步": "Synced both ways with the source folder before every backup; additions, changes and deletions on either side reach the other; empty disables sync",
+	"两侧都修改时": "When both sides changed",
+	"两个都保留时，同步文件夹中的版本改名为「冲突副本」":   "When keeping both, the version in the sync folder is renamed as a \"conflict copy\"",
+	"同步文件夹不能是源文件夹本身":              "The sync folder cannot be the source folder itself",
+	"双向同步设置已保存，下次备份生效":            "Two-way sync settings saved, takes effect on the next backup",
+	"没有等待处理的冲突":                   "No conflicts to resolve",
+	"处理冲突失败: %v":                  "Failed to resolve conflict: %v",
+	"已处理冲突 %s，还有 %d 个":            "Resolved conflict %s, %d remaining",
+	"保留本机":                        "Keep this computer's",
+	"保留同步文件夹":                     "Keep sync folder's",
+	"都保留":                         "Keep both",
+	"%s\n本机: %s，%s\n同步文件夹: %s，%s": "%s\nThis computer: %s, %s\nSync folder: %s, %s",
+	"全部保留较新的版本":                   "Keep the newer version for all",
+	"以下文件在本机和同步文件夹中都被修改过，处理之前两侧保持不变：": "These files were modified both on this computer and in the sync folder; both sides stay unchanged until resolved:",
+	"处理同步冲突":     "Resolve sync conflicts",
+	"校验快照":       "Verify snapshot",
+	": 缺失":       ": missing",
+	"……等 %d 个文件": "...and %d more files",
+	"检测到大量文件变化，列表不完整": "Large number of file changes detected, the list is incomplete",
+	"跳过本次":           "Skip this time",
+	"已跳过本次监控触发的备份":   "Skipped this watch-triggered backup",
+	"[%s] %d 个文件有变化": "[%s] %d files changed",
+	"%d 秒后备份":        "Backing up in %d seconds",
+	"https://cloud.example.com/remote.php/dav/files/用户名/Backups": "https://cloud.example.com/remote.php/dav/files/username/Backups",
+	"请填写以 http:// 或 https:// 开头的 WebDAV 地址":                      "Please enter a WebDAV URL starting with http:// or https://",
+	"测试连接":             "Test connection",
+	"正在连接 WebDAV...":   "Connecting to WebDAV...",
+	"连接 WebDAV 失败: %v": "Failed to connect to WebDAV: %v",
+	"连接 WebDAV 失败":     "WebDAV connection failed",
+	"连接成功，备份目录已就绪":     "Connected, backup directory is ready",
+	"WebDAV 连接成功":      "WebDAV connection succeeded",
+	"备份目录的 WebDAV 地址，Nextcloud 可在“文件设置”中找到": "WebDAV URL of the backup directory; Nextcloud shows it under \"Files settings\"",
+	"建议使用应用专用密码，只保存在本机":                     "An app-specific password is recommended; it is stored only on this computer",
+	"备份到 WebDAV":     "Back up to WebDAV",
+	"目标":             "Destination",
+	"启用":             "Enabled",
+	"否":              "No",
+	"是":              "Yes",
+	"主目标":            "Primary",
+	"文件夹或 WebDAV 地址": "Folder or WebDAV URL",
+	"添加目标":           "Add destination",
+	"已保存 %d 个附加目标":   "Saved %d additional destinations",
+	"WebDAV 登录信息":    "WebDAV login",
+	"每次备份成功后，快照按各目标的格式和加密设置再复制一份，例如外接硬盘上保存普通目录，云端保存加密的归档。\n加密使用加密设置中的密码短语；附加目标中的快照不会自动清理。WebDAV 目标用行末的登录按钮填写用户名和密码，只支持目录格式。": "After each successful backup, the snapshot is copied again with each destination's format and encryption, e.g. a plain directory on an external drive and an encrypted archive in the cloud.\nEncryption uses the passphrase from the encryption settings; snapshots in additional destinations are not pruned automatically. Enter WebDAV credentials with the login button at the end of the row; WebDAV only supports the directory format.",
+	"已选择 WebDAV 备份目标: ": "WebDAV backup destination selected: ",
+	"已请求取回 %d 个文件":      "Requested retrieval of %d files",
+	"已请求取回":             "Retrieval requested",
+	"已请求取回 %d 个文件，通常 %s 内完成。完成后再次还原即可，取回的副本保留 %d 天": "Requested retrieval of %d files, usually done within %s. Restore again once it finishes; the retrieved copies are kept for %d days",
+	"加急":             "Expedited",
+	"标准":             "Standard",
+	"批量":             "Bulk",
+	"%d 分钟":          "%d minutes",
+	"%d 小时":          "%d hours",
+	"正在查询冷存储中的快照...": "Checking the snapshot in cold storage...",
+	"快照正在从冷存储取回":     "Snapshot is being retrieved from cold storage",
+	"正在取回":           "Retrieving",
+	"快照中的 %d 个文件正在从冷存储取回，已完成 %d 个。取回完成后再还原": "%d files in the snapshot are being retrieved from cold storage, %d done. Restore once the retrieval finishes",
+	"%s（通常 %s 内完成）": "%s (usually done within %s)",
+	"该快照已转入冷存储（%s），其中 %d 个文件要先取回才能还原。取回在云端进行，期间可以关闭程序，完成后再次还原即可。越快的取回费用越高。": "This snapshot has moved to cold storage (%s); %d of its files must be retrieved before they can be restored. Retrieval happens in the cloud and the app can be closed meanwhile; restore again once it finishes. Faster retrieval costs more.",
+	"取回速度": "Retrieval speed",
+	"保留天数": "Days to keep",
+	"取回的副本保留多少天，过期后需要重新取回": "How many days to keep the retrieved copies; they must be retrieved again after they expire",
+	"从冷存储取回":         "Retrieve from cold storage",
+	"取回":             "Retrieve",
+	"保留天数必须是正整数: %s": "Days to keep must be a positive integer: %s",
+	"正在请求取回快照...":    "Requesting snapshot retrieval...",
+	"[冷存储]":          "[cold storage]",
+	"已选择 S3 备份目标: ":  "S3 backup destination selected: ",
+	"请填写以 s3:// 开头的地址，例如 s3://存储桶/Backups": "Please enter a URL starting with s3://, e.g. s3://bucket/Backups",
+	"转入冷存储的天数必须是非负整数: %s":                  "Days before cold storage must be a non-negative integer: %s",
+	"正在连接 S3...":    "Connecting to S3...",
+	"连接 S3 失败: %v":  "Failed to connect to S3: %v",
+	"连接 S3 失败":      "S3 connection failed",
+	"连接成功，存储桶已就绪":   "Connected; the bucket is ready",
+	"S3 连接成功":       "S3 connection succeeded",
+	"存储桶和其中保存快照的前缀": "Bucket and the prefix to store snapshots under",
+	"服务地址":          "Endpoint",
+	"MinIO 等自建服务填写服务地址，Amazon S3 留空": "Enter the endpoint for self-hosted services such as MinIO; leave empty for Amazon S3",
+	"区域": "Region",
+	"留空时自动查询存储桶所在的区域": "Leave empty to look up the bucket's region automatically",
+	"访问密钥 ID":  "Access key ID",
+	"私有访问密钥":   "Secret access key",
+	"只保存在本机":   "Stored only on this computer",
+	"存储类别":     "Storage class",
+	"新快照的存储类别": "Storage class of new snapshots",
+	"转入冷存储":    "Move to cold storage",
+	"快照保存多少天后转入冷存储，0 表示不转入。冷存储费用更低，但还原前要先取回，需要几分钟到两天": "Days after which snapshots move to cold storage; 0 means never. Cold storage costs less, but snapshots must be retrieved before restoring, which takes minutes to two days",
+	"冷存储类别":  "Cold storage class",
+	"备份到 S3": "Back up to S3",
+
+	// watcher
+	"创建监控失败: %v":      "Failed to create watcher: %v",
+	"添加监控目录失败 %s: %v": "Failed to add watched directory %s: %v",
+	"设置监控失败: %v":      "Failed to set up watching: %v",
+
+	// cli
+	"执行一次备份（包括 Git 提交和推送）":            "Run a backup once (including Git commit and push)",
+	"监控源文件夹，变化平静后自动备份，直到按 Ctrl+C":     "Watch the source folder and back up automatically once changes settle, until Ctrl+C",
+	"列出所有备份任务，* 为当前任务，- 为已归档的任务":      "List all backup profiles, * marks the current profile, - marks archived profiles",
+	"作为局域网接收端，接收其他设备推送的快照，直到按 Ctrl+C": "Act as a LAN receiver and accept snapshots pushed by other devices, until Ctrl+C",
+	"校验最近一个快照能否完整读出，加密的快照完整解密一遍":      "Verify that the latest snapshot can be read completely, decrypting encrypted snapshots in full",
+	"数据巡检：读出目标文件夹中的所有快照，发现并尽量修复损坏的文件": "Data scrub: read every snapshot in the destination folder, find damaged files and repair them where possible",
+	"显示版本、构建信息和配置文件位置，用于问题反馈":         "Show version, build information and config file location for bug reports",
+	"检查配置：路径、排除规则、计划表达式和凭据，一次列出所有问题":  "Check the configuration: paths, exclusion rules, schedule expressions and credentials, listing all problems at once",
+	"参数不正确": "Invalid arguments",
+	"配置错误":  "Configuration error",
+	"配置文件 config.json 或其所在目录，默认为 %s": "Config file config.json or its directory, defaults to %s",
+	"备份任务名称，默认为界面中当前选择的任务":           "Backup profile name, defaults to the profile currently selected in the UI",
+	"receive 命令保存快照的目录，默认使用界面中设置的目录": "Directory where the receive command saves snapshots, defaults to the one set in the UI",
+	"在标准输出写入 JSON 格式的运行结果，日志写入标准错误":  "Write the run result as JSON to standard output and logs to standard error",
+	"多余的参数: %v": "Unexpected arguments: %v",
+	"错误:":       "Error:",
+	"用法: syncsafe <命令> [--config 配置文件] [--profile 任务名称] [--json]": "Usage: syncsafe <command> [--config config-file] [--profile profile-name] [--json]",
+	"不带命令时启动图形界面。命令:":                                             "Without a command the graphical interface starts. Commands:",
+	"退出码: 0 成功，1 部分成功，2 失败，3 参数或配置错误":                             "Exit codes: 0 success, 1 partial success, 2 failure, 3 invalid arguments or configuration",
+	"%w: 配置文件必须名为 config.json: %s":                                "%w: the config file must be named config.json: %s",
+	"%w: 配置文件不存在或无法访问: %v":                                        "%w: the config file does not exist or cannot be accessed: %v",
+	"读取程序设置失败: %v":                                                "Failed to read app settings: %v",
+	"无法写入日志文件: %v":                                                "Cannot write the log file: %v",
+	"%w: 加载配置失败: %v":                                              "%w: failed to load configuration: %v",
+	"%w: 没有名为 %q 的备份任务，可选: %v":                                    "%w: no backup profile named %q, available: %v",
+	"%w: 任务 %q 已归档，恢复后才能备份":                                       "%w: profile %q is archived, restore it before backing up",
+	"按保留策略清理快照失败: %v":                                             "Failed to prune snapshots by the retention policy: %v",
+	"保存历史记录失败: %v":                                                "Failed to save history: %v",
+	"发送失败通知邮件失败: %v":                                              "Failed to send the failure notification email: %v",
+	"共 %d 个文件，新增 %d、修改 %d、删除 %d":                                  "%d files in total, %d added, %d modified, %d deleted",
+	"自动备份失败: %v":                                                  "Automatic backup failed: %v",
+	"开始监控 %s，按 Ctrl+C 停止":                                         "Watching %s, press Ctrl+C to stop",
+	"定时提交 Git 失败: %v":                                             "Scheduled Git commit failed: %v",
+	"已停止监控":                                                       "Stopped watching",
+	"源文件夹已被删除、重命名或卸载: %s":                                         "The source folder was deleted, renamed or unmounted: %s",
+	"%w: 请用 --dir 指定保存快照的目录":                                      "%w: use --dir to specify where to save snapshots",
+	"已收到 %s 的快照 %s，共 %d 个文件，保存在 %s":                               "Received snapshot %[2]s from %[1]s, %[3]d files, saved in %[4]s",
+	"正在接收快照，保存到 %s，按 Ctrl+C 停止":                                   "Receiving snapshots into %s, press Ctrl+C to stop",
+	"本机地址: %s":                                                    "Local address: %s",
+	"配对码: %s":                                                     "Pairing code: %s",
+	"证书指纹: %s":                                                    "Certificate fingerprint: %s",
+	"已停止接收":                                                       "Stopped receiving",
+	"任务 %s 还没有可以校验的快照":                                            "Profile %s has no snapshot to verify yet",
+	"[%s] 校验快照 %s":                                                "[%s] Verifying snapshot %s",
+	"损坏:":                                                         "Damaged:",
+	"缺失:":                                                         "Missing:",
+	"快照校验未通过":                                                     "Snapshot verification failed",
+	"[%s] 数据巡检 %s":                                                "[%s] Scrubbing %s",
+	"已修复:":                                                        "Repaired:",
+	"无法修复:":                                                       "Cannot repair:",
+	"有无法修复的文件":                                                    "Some files cannot be repaired",
+	"%w: 有 %d 个问题会阻止备份":                                           "%w: %d problems would prevent backups",
+	"有 %d 个问题会使部分功能不生效":                                           "%d problems would keep some features from working",
+	"[%s] 配置没有发现问题":                                               "[%s] No problems found in the configuration",
+	"%s (冲突副本 %s)%s":                                              "%s (conflict copy %s)%s",
+}
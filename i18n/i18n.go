@@ -0,0 +1,85 @@
+// Package i18n 翻译界面文字。消息以中文原文为键，目录中没有的消息显示原文；
+// 切换语言后，之后创建的界面和生成的消息使用新的语言。
+//
+// 格式化的消息用 Sprintf 和 Errorf，键为格式字符串本身。
+package i18n
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+const (
+	ZhCN = "zh-CN" // 简体中文，即消息原文
+	EnUS = "en-US"
+)
+
+// 可选的界面语言
+type Language struct {
+	Code string
+	Name string // 以该语言本身书写的名称，不翻译
+}
+
+// 可选的界面语言，按显示顺序排列
+var Languages = []Language{
+	{ZhCN, "简体中文"},
+	{EnUS, "English"},
+}
+
+// 各语言的消息目录，简体中文没有目录
+var catalogs = map[string]map[string]string{
+	EnUS: enUS,
+}
+
+// 当前语言的消息目录，为 nil 表示简体中文
+var current atomic.Pointer[map[string]string]
+
+// 当前语言，为空表示简体中文
+var currentCode atomic.Value
+
+// 切换界面语言，无法识别的语言使用简体中文
+func SetLanguage(code string) {
+	catalog, ok := catalogs[code]
+	if !ok {
+		current.Store(nil)
+		currentCode.Store(ZhCN)
+		return
+	}
+	current.Store(&catalog)
+	currentCode.Store(code)
+}
+
+// 当前的界面语言
+func Current() string {
+	if code, ok := currentCode.Load().(string); ok {
+		return code
+	}
+	return ZhCN
+}
+
+// 翻译消息，当前语言没有翻译时返回原文
+func T(msg string) string {
+	catalog := current.Load()
+	if catalog == nil {
+		return msg
+	}
+	if translated, ok := (*catalog)[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// 按翻译后的格式生成消息
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// 按翻译后的格式生成错误，支持 %w
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}
+
+// 在显示时才翻译的错误，用于包级别的错误变量，可以用 errors.Is 比较
+type Error string
+
+func (e Error) Error() string { return T(string(e)) }
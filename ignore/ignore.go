@@ -0,0 +1,159 @@
+// Package ignore 按 .gitignore 语法匹配需要排除的文件。
+//
+// 每行一条规则，空行和以 # 开头的行被忽略：
+//
+//	*.log          任意目录中的 .log 文件
+//	node_modules/  任意位置名为 node_modules 的目录（以 / 结尾只匹配目录）
+//	/build         只匹配源文件夹根目录下的 build（包含 / 的规则相对根目录）
+//	.cache/**      .cache 目录中的所有内容
+//	!keep.log      以 ! 开头重新包含之前被排除的文件
+//
+// 后面的规则优先；目录被排除后其中的内容无法再被重新包含，与 Git 相同。
+package ignore
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 一条规则
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// 编译后的规则列表，nil 表示不排除任何文件
+type Matcher struct {
+	rules []rule
+}
+
+// 编译规则。无效的规则被跳过并在错误中列出，其余规则仍然有效
+func Compile(lines []string) (*Matcher, error) {
+	m := &Matcher{}
+	var invalid []string
+	for _, line := range lines {
+		r, ok, err := parseRule(line)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%q: %v", line, err))
+			continue
+		}
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	if len(invalid) > 0 {
+		return m, fmt.Errorf("无效的排除规则: %s", strings.Join(invalid, "; "))
+	}
+	return m, nil
+}
+
+func parseRule(line string) (rule, bool, error) {
+	pattern := strings.TrimRight(line, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule{}, false, nil
+	}
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return rule{}, false, nil
+	}
+
+	// 包含 / 的规则相对根目录，否则匹配任意层级的名称
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern) && i > 0 && pattern[i-1] == '/':
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return rule{}, false, fmt.Errorf("缺少 ]")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return rule{}, false, err
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// 是否没有任何规则
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.rules) == 0
+}
+
+// 路径本身是否被排除，不检查上级目录。relPath 为相对根目录的路径，
+// 按文件树顺序遍历并跳过被排除的目录时使用
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	if m.Empty() {
+		return false
+	}
+	path := filepath.ToSlash(relPath)
+	excluded := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(path) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+// 路径或其任一上级目录是否被排除
+func (m *Matcher) Excluded(relPath string, isDir bool) bool {
+	if m.Empty() {
+		return false
+	}
+	path := filepath.ToSlash(relPath)
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && m.Match(path[:i], true) {
+			return true
+		}
+	}
+	return m.Match(path, isDir)
+}
@@ -0,0 +1,205 @@
+// Package logging 把程序日志以 JSON 行写入按大小轮换的日志文件，记录级别可以在设置中调整，
+// 并提供按级别和关键字读取日志的功能。
+//
+// Setup 之后 log 包的输出同样写入日志文件，级别为信息。
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+const (
+	fileName    = "syncsafe.log"
+	maxFileSize = 5 * 1024 * 1024 // 超过该大小时轮换
+	maxBackups  = 5               // 保留的旧日志文件数（syncsafe.log.1 为最新）
+)
+
+// 当前的记录级别，可以在运行时修改
+var level = new(slog.LevelVar)
+
+// 设置记录级别，立即生效。name 为 debug、info、warn 或 error，无法识别时为信息
+func SetLevel(name string) {
+	level.Set(ParseLevel(name))
+}
+
+// 级别名称对应的级别，无法识别时为信息
+func ParseLevel(name string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// 级别的显示名称
+func LevelName(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return i18n.T("错误")
+	case l >= slog.LevelWarn:
+		return i18n.T("警告")
+	case l >= slog.LevelInfo:
+		return i18n.T("信息")
+	}
+	return i18n.T("调试")
+}
+
+// 开始把日志写入 dir 中的轮换日志文件，并作为 slog 和 log 包的默认输出
+func Setup(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return i18n.Errorf("创建日志目录失败: %v", err)
+	}
+	w := &rotatingFile{path: filepath.Join(dir, fileName)}
+	if err := w.open(); err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})))
+	return nil
+}
+
+// 按大小轮换的日志文件
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+func (w *rotatingFile) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return i18n.Errorf("打开日志文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return i18n.Errorf("打开日志文件失败: %v", err)
+	}
+	w.file, w.size = file, info.Size()
+	return nil
+}
+
+// 写入一条日志，写入后超过 maxFileSize 时先轮换
+func (w *rotatingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size > 0 && w.size+int64(len(p)) > maxFileSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// 关闭当前文件，依次把 syncsafe.log.N 改名为 N+1，最旧的删除
+func (w *rotatingFile) rotate() error {
+	w.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", w.path, maxBackups))
+	for i := maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	os.Rename(w.path, w.path+".1")
+	return w.open()
+}
+
+// 一条日志
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   string // 其他字段，按名称排序，格式为 key=value
+}
+
+// 日志筛选条件
+type Filter struct {
+	MinLevel slog.Level // 只保留不低于该级别的日志
+	Search   string     // 关键字，匹配消息和其他字段，不区分大小写
+}
+
+// 按时间顺序读取 dir 中所有日志文件里符合筛选条件的日志，最多返回最新的 limit 条
+func Read(dir string, filter Filter, limit int) ([]Entry, error) {
+	search := strings.ToLower(filter.Search)
+	var entries []Entry
+	// 从最旧的轮换文件读到当前文件
+	var paths []string
+	for i := maxBackups; i >= 1; i-- {
+		paths = append(paths, filepath.Join(dir, fmt.Sprintf("%s.%d", fileName, i)))
+	}
+	paths = append(paths, filepath.Join(dir, fileName))
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, i18n.Errorf("读取日志失败: %v", err)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			entry, ok := parseEntry(scanner.Bytes())
+			if !ok || entry.Level < filter.MinLevel {
+				continue
+			}
+			if search != "" && !strings.Contains(strings.ToLower(entry.Message+" "+entry.Attrs), search) {
+				continue
+			}
+			entries = append(entries, entry)
+			if limit > 0 && len(entries) > 2*limit {
+				entries = append(entries[:0:0], entries[len(entries)-limit:]...)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, i18n.Errorf("读取日志失败: %v", err)
+		}
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// 解析一行 JSON 日志，无法解析的行返回 false
+func parseEntry(line []byte) (Entry, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if t, ok := fields[slog.TimeKey].(string); ok {
+		entry.Time, _ = time.Parse(time.RFC3339Nano, t)
+	}
+	if l, ok := fields[slog.LevelKey].(string); ok {
+		entry.Level = ParseLevel(l)
+	}
+	entry.Message, _ = fields[slog.MessageKey].(string)
+	delete(fields, slog.TimeKey)
+	delete(fields, slog.LevelKey)
+	delete(fields, slog.MessageKey)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]string, len(keys))
+	for i, key := range keys {
+		attrs[i] = fmt.Sprintf("%s=%v", key, fields[key])
+	}
+	entry.Attrs = strings.Join(attrs, " ")
+	return entry, true
+}
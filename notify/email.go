@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"syncsafe/faults"
+	"syncsafe/i18n"
+)
+
+// SMTP 连接的加密方式
+const (
+	SecurityNone     = "none"     // 不加密，只适合本机或内网的中继
+	SecurityStartTLS = "starttls" // 明文连接后升级为 TLS，常用端口 587
+	SecurityTLS      = "tls"      // 直接建立 TLS 连接，常用端口 465
+)
+
+// 各加密方式的默认端口
+var DefaultPorts = map[string]int{
+	SecurityNone:     25,
+	SecurityStartTLS: 587,
+	SecurityTLS:      465,
+}
+
+// 邮件通知设置，备份失败时发送
+type EmailConfig struct {
+	Enabled    bool
+	Host       string
+	Port       int    // 为 0 时使用加密方式的默认端口
+	Security   string // SecurityNone、SecurityStartTLS 或 SecurityTLS，为空时使用 STARTTLS
+	Username   string // 为空时不登录
+	Password   string // 只保存在本机
+	From       string // 为空时使用 Username
+	Recipients []string
+}
+
+// 检查设置是否完整，收件人和发件人必须是有效的邮件地址
+func (c EmailConfig) Validate() error {
+	if c.Host == "" {
+		return i18n.Errorf("请填写 SMTP 服务器地址")
+	}
+	switch c.Security {
+	case "", SecurityNone, SecurityStartTLS, SecurityTLS:
+	default:
+		return i18n.Errorf("不支持的加密方式: %s", c.Security)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return i18n.Errorf("端口无效: %d", c.Port)
+	}
+	if _, err := mail.ParseAddress(c.sender()); err != nil {
+		return i18n.Errorf("发件人地址无效: %s", c.sender())
+	}
+	if len(c.Recipients) == 0 {
+		return i18n.Errorf("请填写收件人")
+	}
+	for _, to := range c.Recipients {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return i18n.Errorf("收件人地址无效: %s", to)
+		}
+	}
+	return nil
+}
+
+func (c EmailConfig) sender() string {
+	if c.From != "" {
+		return c.From
+	}
+	return c.Username
+}
+
+func (c EmailConfig) security() string {
+	if c.Security == "" {
+		return SecurityStartTLS
+	}
+	return c.Security
+}
+
+func (c EmailConfig) address() string {
+	port := c.Port
+	if port == 0 {
+		port = DefaultPorts[c.security()]
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// 按设置发送一封纯文本邮件
+func SendEmail(c EmailConfig, subject, body string) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := faults.Inject(faults.NetworkDrop, c.Host); err != nil {
+		return i18n.Errorf("发送邮件失败: %v", err)
+	}
+	client, err := dialSMTP(c)
+	if err != nil {
+		return i18n.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return i18n.Errorf("SMTP 登录失败: %v", err)
+		}
+	}
+	from, _ := mail.ParseAddress(c.sender())
+	if err := client.Mail(from.Address); err != nil {
+		return i18n.Errorf("发送邮件失败: %v", err)
+	}
+	for _, to := range c.Recipients {
+		addr, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(addr.Address); err != nil {
+			return i18n.Errorf("收件人 %s 被拒绝: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return i18n.Errorf("发送邮件失败: %v", err)
+	}
+	if _, err := w.Write(buildMessage(from.String(), c.Recipients, subject, body)); err != nil {
+		w.Close()
+		return i18n.Errorf("发送邮件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return i18n.Errorf("发送邮件失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// 连接 SMTP 服务器，按加密方式建立 TLS 连接或升级为 TLS
+func dialSMTP(c EmailConfig) (*smtp.Client, error) {
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	tlsConfig := &tls.Config{ServerName: c.Host}
+	var conn net.Conn
+	var err error
+	if c.security() == SecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.address(), tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", c.address())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 整个会话的超时，服务器没有响应时不会一直等待
+	conn.SetDeadline(time.Now().Add(4 * sendTimeout))
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if c.security() == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, i18n.Errorf("服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// 生成 UTF-8 纯文本邮件，正文用 base64 编码，中文内容不受 7 位传输限制
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var sb strings.Builder
+	header := func(name, value string) {
+		sb.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.BEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "base64")
+	sb.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded + "\r\n")
+	return []byte(sb.String())
+}
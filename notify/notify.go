@@ -0,0 +1,144 @@
+// Package notify 通过 ntfy 或 Gotify 把备份结果推送到手机，备份失败时还可以通过 SMTP 发送邮件，
+// 或者通过 Webhook 把备份摘要发送到 Slack、Discord 或自己的服务。
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"syncsafe/faults"
+	"syncsafe/i18n"
+)
+
+// 通知的严重程度
+type Level int
+
+const (
+	LevelInfo    Level = iota // 备份成功
+	LevelWarning              // 需要注意，例如磁盘空间不足、失败后等待重试
+	LevelError                // 备份失败
+)
+
+// 严重程度的显示名称
+var LevelLabels = map[Level]string{
+	LevelInfo:    "全部",
+	LevelWarning: "警告和错误",
+	LevelError:   "仅错误",
+}
+
+// 支持的推送服务
+const (
+	ServiceNtfy   = "ntfy"
+	ServiceGotify = "gotify"
+)
+
+// ntfy 的公共服务器
+const DefaultNtfyServer = "https://ntfy.sh"
+
+// 发送请求的超时时间
+const sendTimeout = 15 * time.Second
+
+// 推送设置
+type Config struct {
+	Enabled  bool
+	Service  string // ServiceNtfy 或 ServiceGotify
+	Server   string // 服务器地址，ntfy 为空时使用公共服务器
+	Topic    string // ntfy 主题
+	Token    string // ntfy 访问令牌或 Gotify 应用令牌
+	MinLevel Level  // 低于该严重程度的通知不发送
+}
+
+// 是否需要发送该严重程度的通知
+func (c Config) Accepts(level Level) bool {
+	return c.Enabled && level >= c.MinLevel
+}
+
+// 发送一条通知
+func Send(c Config, level Level, title, message string) error {
+	var req *http.Request
+	var err error
+	switch c.Service {
+	case ServiceGotify:
+		req, err = gotifyRequest(c, level, title, message)
+	case ServiceNtfy, "":
+		req, err = ntfyRequest(c, level, title, message)
+	default:
+		return i18n.Errorf("不支持的推送服务: %s", c.Service)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := faults.Inject(faults.NetworkDrop, req.URL.Host); err != nil {
+		return i18n.Errorf("发送通知失败: %v", err)
+	}
+	client := &http.Client{Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return i18n.Errorf("发送通知失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return i18n.Errorf("推送服务返回错误: %s", resp.Status)
+	}
+	return nil
+}
+
+// ntfy 以 JSON 发布到服务器根路径，标题和内容不受 HTTP 头编码限制
+func ntfyRequest(c Config, level Level, title, message string) (*http.Request, error) {
+	if c.Topic == "" {
+		return nil, i18n.Errorf("请填写 ntfy 主题")
+	}
+	server := c.Server
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+	priority := map[Level]int{LevelInfo: 3, LevelWarning: 4, LevelError: 5}[level]
+	tags := map[Level]string{LevelInfo: "white_check_mark", LevelWarning: "warning", LevelError: "rotating_light"}[level]
+	body, err := json.Marshal(map[string]interface{}{
+		"topic":    c.Topic,
+		"title":    title,
+		"message":  message,
+		"priority": priority,
+		"tags":     []string{tags},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(server, "/"), bytes.NewReader(body))
+	if err != nil {
+		return nil, i18n.Errorf("服务器地址无效: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// Gotify 使用应用令牌发送消息
+func gotifyRequest(c Config, level Level, title, message string) (*http.Request, error) {
+	if c.Server == "" || c.Token == "" {
+		return nil, i18n.Errorf("请填写 Gotify 服务器地址和应用令牌")
+	}
+	priority := map[Level]int{LevelInfo: 2, LevelWarning: 5, LevelError: 8}[level]
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    title,
+		"message":  message,
+		"priority": priority,
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimRight(c.Server, "/") + "/message?token=" + url.QueryEscape(c.Token)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, i18n.Errorf("服务器地址无效: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"syncsafe/faults"
+	"syncsafe/i18n"
+	"syncsafe/version"
+)
+
+// Webhook 的请求格式
+const (
+	WebhookJSON    = "json"    // 备份记录的 JSON 摘要，适合自己的服务或自动化平台
+	WebhookSlack   = "slack"   // Slack Incoming Webhook
+	WebhookDiscord = "discord" // Discord 频道 Webhook
+)
+
+// 一个 Webhook：每次备份后把结果 POST 到 URL
+type Webhook struct {
+	URL          string
+	Format       string // WebhookJSON、WebhookSlack 或 WebhookDiscord，为空时使用 JSON
+	FailuresOnly bool   // 只发送失败的备份
+}
+
+// 检查 URL 和格式
+func (h Webhook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return i18n.Errorf("Webhook 地址无效: %s", h.URL)
+	}
+	switch h.Format {
+	case "", WebhookJSON, WebhookSlack, WebhookDiscord:
+	default:
+		return i18n.Errorf("不支持的 Webhook 格式: %s", h.Format)
+	}
+	return nil
+}
+
+// 一次备份的摘要，JSON 格式直接发送该结构
+type WebhookEvent struct {
+	Event         string    `json:"event"` // backup.succeeded、backup.failed 或 backup.cancelled
+	Profile       string    `json:"profile"`
+	Machine       string    `json:"machine"`
+	Source        string    `json:"source"`
+	Destination   string    `json:"destination"`
+	Snapshot      string    `json:"snapshot,omitempty"`
+	Time          time.Time `json:"time"`
+	Success       bool      `json:"success"`
+	DryRun        bool      `json:"dryRun,omitempty"`
+	Files         int       `json:"files"`
+	NewFiles      int       `json:"newFiles"`
+	ModifiedFiles int       `json:"modifiedFiles"`
+	DeletedFiles  int       `json:"deletedFiles"`
+	FailedFiles   int       `json:"failedFiles"`
+	Size          int64     `json:"size"`
+	Duration      float64   `json:"durationSeconds"`
+	Attempt       int       `json:"attempt,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// 备份事件
+const (
+	EventSucceeded = "backup.succeeded"
+	EventFailed    = "backup.failed"
+	EventCancelled = "backup.cancelled"
+)
+
+// 是否需要向该 Webhook 发送事件
+func (h Webhook) Accepts(ev WebhookEvent) bool {
+	return !h.FailuresOnly || ev.Event == EventFailed
+}
+
+// 标题和正文，用于 Slack 和 Discord 消息
+func (ev WebhookEvent) text() (string, string) {
+	var title string
+	switch ev.Event {
+	case EventFailed:
+		title = i18n.T("备份失败: ") + ev.Profile
+	case EventCancelled:
+		title = i18n.T("备份已取消: ") + ev.Profile
+	default:
+		title = i18n.T("备份完成: ") + ev.Profile
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s → %s\n", ev.Source, ev.Destination)
+	fmt.Fprintf(&sb, i18n.T("计算机 %s，%s"), ev.Machine, ev.Time.Format("2006-01-02 15:04:05"))
+	if ev.Files > 0 || ev.FailedFiles > 0 {
+		fmt.Fprintf(&sb, i18n.T("\n共 %d 个文件，新增 %d、修改 %d、删除 %d"), ev.Files, ev.NewFiles, ev.ModifiedFiles, ev.DeletedFiles)
+		if ev.FailedFiles > 0 {
+			fmt.Fprintf(&sb, i18n.T("，复制失败 %d"), ev.FailedFiles)
+		}
+	}
+	if ev.DryRun {
+		sb.WriteString(i18n.T("\n（模拟备份）"))
+	}
+	if ev.Error != "" {
+		sb.WriteString(i18n.T("\n错误: ") + ev.Error)
+	}
+	return title, sb.String()
+}
+
+// Discord 嵌入消息的颜色
+var discordColors = map[string]int{
+	EventSucceeded: 0x43a047,
+	EventFailed:    0xe53935,
+	EventCancelled: 0x90a4ae,
+}
+
+// 按 Webhook 的格式生成请求内容
+func WebhookPayload(h Webhook, ev WebhookEvent) ([]byte, error) {
+	switch h.Format {
+	case WebhookSlack:
+		title, body := ev.text()
+		return json.Marshal(map[string]interface{}{
+			"text": "*SyncSafe " + title + "*\n" + body,
+		})
+	case WebhookDiscord:
+		title, body := ev.text()
+		return json.Marshal(map[string]interface{}{
+			"username": "SyncSafe",
+			"embeds": []map[string]interface{}{{
+				"title":       title,
+				"description": body,
+				"color":       discordColors[ev.Event],
+				"timestamp":   ev.Time.Format(time.RFC3339),
+			}},
+		})
+	case WebhookJSON, "":
+		return json.Marshal(ev)
+	}
+	return nil, i18n.Errorf("不支持的 Webhook 格式: %s", h.Format)
+}
+
+// 服务器拒绝了请求（地址或内容错误），重试也不会成功
+var ErrWebhookRejected = i18n.Error("Webhook 被服务器拒绝")
+
+// 把内容 POST 到 Webhook 地址。4xx 响应（408 和 429 除外）返回 ErrWebhookRejected，
+// 其他错误是网络中断或服务器暂时不可用，可以稍后重试
+func PostWebhook(webhookURL string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return i18n.Errorf("%w: 地址无效: %v", ErrWebhookRejected, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SyncSafe/"+version.Get().Version)
+
+	if err := faults.Inject(faults.NetworkDrop, req.URL.Host); err != nil {
+		return i18n.Errorf("发送 Webhook 失败: %v", err)
+	}
+	client := &http.Client{Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return i18n.Errorf("发送 Webhook 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", ErrWebhookRejected, resp.Status)
+	}
+	return i18n.Errorf("Webhook 服务器返回错误: %s", resp.Status)
+}
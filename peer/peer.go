@@ -0,0 +1,234 @@
+// Package peer 在局域网内的两个 SyncSafe 之间直接传输快照，不需要 NAS：
+// 一端作为接收端监听 TLS 连接，另一端在备份完成后把快照推送过去。
+//
+// 接收端显示的配对码由 8 位随机数字和接收端证书指纹的前 80 位组成。
+// 发送端完成 TLS 握手后先用配对码中的指纹核对接收端证书，不一致时立即断开，
+// 不会把配对证明发给冒充接收端的设备，对方也就拿不到可以离线猜测数字部分的数据。
+// 证书核对通过后，双方再各自用数字部分对另一方的随机数和证书指纹计算 HMAC 互相验证。
+// 发送端首次推送成功后记住完整的证书指纹，之后证书变化时拒绝推送。
+package peer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 接收端默认端口
+const DefaultPort = 47100
+
+const (
+	handshakeTimeout = 15 * time.Second // 握手和验证的超时时间
+	ioTimeout        = 2 * time.Minute  // 传输过程中单次读写的超时时间
+	maxHeaderSize    = 1 << 20          // 单个消息头的最大长度
+	nonceSize        = 32
+	secretDigits     = 8  // 配对码中随机数字的位数
+	codeFingerprint  = 10 // 配对码中包含的证书指纹字节数，编码后为 16 个字符
+)
+
+// 配对码中证书指纹部分的编码，只含大写字母和 2-7，不会与数字部分混淆
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// 发送端设置
+type Config struct {
+	Enabled     bool
+	Address     string // 接收端地址，host 或 host:port
+	Code        string // 接收端显示的完整配对码，包含证书指纹，只保存在本机
+	Fingerprint string // 首次推送成功时记录的接收端证书指纹
+}
+
+// 补全默认端口后的接收端地址
+func (c Config) addr() string {
+	if _, _, err := net.SplitHostPort(c.Address); err == nil {
+		return c.Address
+	}
+	return net.JoinHostPort(c.Address, strconv.Itoa(DefaultPort))
+}
+
+// 生成新的配对码数字部分，格式为 1234-5678，由接收端保存
+func NewPairingCode() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(100000000))
+	if err != nil {
+		panic(err)
+	}
+	code := fmt.Sprintf("%08d", n.Int64())
+	return code[:4] + "-" + code[4:]
+}
+
+// 接收端显示的完整配对码：数字部分后接证书指纹的前 80 位，每 4 个字符一组，
+// 例如 1234-5678-ABCD-EFGH-IJKL-MNOP
+func PairingCode(secret, fingerprint string) string {
+	code := normalizeCode(secret)
+	if len(code) > secretDigits {
+		code = code[:secretDigits]
+	}
+	code += fingerprintCode(fingerprint)
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// 配对码是否包含数字部分和证书指纹部分
+func CompleteCode(code string) bool {
+	secret, fp := splitCode(code)
+	return len(secret) == secretDigits && len(fp) == codeEncoding.EncodedLen(codeFingerprint)
+}
+
+// 证书指纹在配对码中的形式，指纹无效时为空
+func fingerprintCode(fingerprint string) string {
+	sum, err := hex.DecodeString(fingerprint)
+	if err != nil || len(sum) < codeFingerprint {
+		return ""
+	}
+	return codeEncoding.EncodeToString(sum[:codeFingerprint])
+}
+
+// 把配对码拆成数字部分和证书指纹部分，接收端只保存数字部分时指纹部分为空
+func splitCode(code string) (secret, fingerprint string) {
+	code = strings.ToUpper(normalizeCode(code))
+	if len(code) <= secretDigits {
+		return code, ""
+	}
+	return code[:secretDigits], code[secretDigits:]
+}
+
+// 去掉配对码中的分隔符和空白
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// 一方持有配对码数字部分的证明：role 区分接收端和发送端，避免把对方的证明原样送回
+func proof(secret, role string, nonce []byte, fingerprint string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(role))
+	mac.Write(nonce)
+	mac.Write([]byte(fingerprint))
+	return mac.Sum(nil)
+}
+
+func newNonce() []byte {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return nonce
+}
+
+// 证书指纹：DER 编码的 SHA-256
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// 消息类型
+const (
+	msgHello     = "hello"     // 发送端：本机名称和快照名称
+	msgChallenge = "challenge" // 接收端的随机数
+	msgAuth      = "auth"      // 发送端的证明和随机数，接收端以带证明的 ok 回复
+	msgDir       = "dir"
+	msgFile      = "file" // 消息头之后紧跟 Size 字节的文件内容
+	msgDone      = "done" // 快照传输完毕
+	msgOK        = "ok"
+	msgError     = "error"
+)
+
+// 消息头，以 4 字节长度加 JSON 编码传输
+type header struct {
+	Kind    string
+	Machine string      `json:",omitempty"`
+	Path    string      `json:",omitempty"` // hello 为快照名称，文件和目录为以 / 分隔的相对路径
+	Size    int64       `json:",omitempty"`
+	Mode    os.FileMode `json:",omitempty"`
+	ModTime time.Time   `json:",omitempty"`
+	Nonce   []byte      `json:",omitempty"`
+	Proof   []byte      `json:",omitempty"`
+	Message string      `json:",omitempty"`
+}
+
+func writeHeader(w io.Writer, h header) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readHeader(r io.Reader) (header, error) {
+	var h header
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return h, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxHeaderSize {
+		return h, i18n.Errorf("消息过长: %d 字节", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, i18n.Errorf("消息格式错误: %v", err)
+	}
+	return h, nil
+}
+
+// 读取指定类型的消息，对方报告错误时返回该错误
+func expect(r io.Reader, kind string) (header, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return h, err
+	}
+	if h.Kind == msgError {
+		return h, i18n.Errorf("对方拒绝: %s", h.Message)
+	}
+	if h.Kind != kind {
+		return h, i18n.Errorf("意外的消息: %s", h.Kind)
+	}
+	return h, nil
+}
+
+// 本机的局域网地址，用于在接收端显示
+func LocalAddresses(port int) []string {
+	var addrs []string
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range ifaceAddrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(ipNet.IP.String(), strconv.Itoa(port)))
+	}
+	return addrs
+}
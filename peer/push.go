@@ -0,0 +1,134 @@
+package peer
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/tls"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 把 snapshotDir 推送到接收端，machine 为本机名称。
+// 返回接收端证书的指纹，Config.Fingerprint 为空时由调用方保存
+func Push(c Config, machine, snapshotDir string, progress func(files int, bytes int64)) (string, error) {
+	if c.Address == "" {
+		return "", i18n.Errorf("请先填写接收端地址")
+	}
+	if !CompleteCode(c.Code) {
+		return "", i18n.Errorf("配对码不完整，请输入接收端显示的完整配对码")
+	}
+	secret, codeFP := splitCode(c.Code)
+	dialer := &net.Dialer{Timeout: handshakeTimeout}
+	// 接收端使用自签名证书，通过配对码中的指纹验证身份
+	conn, err := tls.DialWithDialer(dialer, "tcp", c.addr(), &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+	})
+	if err != nil {
+		return "", i18n.Errorf("连接接收端失败: %v", err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", i18n.Errorf("接收端没有提供证书")
+	}
+	fp := fingerprint(certs[0].Raw)
+	// 在发送任何数据之前核对证书，冒充接收端的设备拿不到配对证明
+	if fingerprintCode(fp) != codeFP {
+		return fp, i18n.Errorf("接收端证书与配对码不符，可能是其他设备冒充接收端")
+	}
+	if c.Fingerprint != "" && c.Fingerprint != fp {
+		return fp, i18n.Errorf("接收端证书已变化，可能是其他设备冒充接收端；确认无误后请重新配对")
+	}
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	reader := bufio.NewReader(conn)
+	if err := writeHeader(conn, header{Kind: msgHello, Machine: machine, Path: filepath.Base(snapshotDir)}); err != nil {
+		return fp, i18n.Errorf("发送失败: %v", err)
+	}
+	challenge, err := expect(reader, msgChallenge)
+	if err != nil {
+		return fp, i18n.Errorf("配对失败: %v", err)
+	}
+	nonce := newNonce()
+	if err := writeHeader(conn, header{Kind: msgAuth, Nonce: nonce, Proof: proof(secret, "sender", challenge.Nonce, fp)}); err != nil {
+		return fp, i18n.Errorf("发送失败: %v", err)
+	}
+	ok, err := expect(reader, msgOK)
+	if err != nil {
+		return fp, i18n.Errorf("配对失败: %v", err)
+	}
+	if !hmac.Equal(ok.Proof, proof(secret, "receiver", nonce, fp)) {
+		return fp, i18n.Errorf("配对失败: 接收端无法证明持有配对码")
+	}
+
+	var files int
+	var bytes int64
+	writer := bufio.NewWriterSize(conn, 256*1024)
+	err = filepath.Walk(snapshotDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(snapshotDir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		h := header{Path: filepath.ToSlash(relPath), Mode: info.Mode().Perm(), ModTime: info.ModTime()}
+		switch {
+		case info.IsDir():
+			h.Kind = msgDir
+			conn.SetDeadline(time.Now().Add(ioTimeout))
+			return writeHeader(writer, h)
+		case info.Mode().IsRegular():
+			h.Kind = msgFile
+			h.Size = info.Size()
+			conn.SetDeadline(time.Now().Add(ioTimeout + time.Duration(h.Size/(64*1024))*time.Second))
+			if err := sendFile(writer, path, h); err != nil {
+				return err
+			}
+			files++
+			bytes += h.Size
+			if progress != nil {
+				progress(files, bytes)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fp, i18n.Errorf("推送快照失败: %v", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+	if err := writeHeader(writer, header{Kind: msgDone}); err != nil {
+		return fp, i18n.Errorf("推送快照失败: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fp, i18n.Errorf("推送快照失败: %v", err)
+	}
+	if _, err := expect(reader, msgOK); err != nil {
+		return fp, i18n.Errorf("接收端保存快照失败: %v", err)
+	}
+	return fp, nil
+}
+
+// 发送文件的消息头和内容，文件在发送过程中变化时按消息头中的大小截断或报错
+func sendFile(w io.Writer, path string, h header) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := writeHeader(w, h); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(w, file, h.Size); err != nil {
+		return i18n.Errorf("读取文件失败: %v\n文件: %s", err, path)
+	}
+	return nil
+}
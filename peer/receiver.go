@@ -0,0 +1,389 @@
+package peer
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 接收端设置，保存在本机，不随配置同步
+type ReceiverSettings struct {
+	Enabled bool
+	Dir     string // 保存收到的快照的目录，按发送端的机器名称分子目录
+	Port    int
+	Code    string // 配对码的数字部分，显示时用 PairingCode 加上证书指纹
+}
+
+// 接收端设置文件
+func settingsPath(dir string) string {
+	return filepath.Join(dir, "receiver.json")
+}
+
+// 读取 dir 中的接收端设置，文件不存在时返回默认设置
+func LoadSettings(dir string) (ReceiverSettings, error) {
+	settings := ReceiverSettings{Port: DefaultPort}
+	data, err := os.ReadFile(settingsPath(dir))
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, i18n.Errorf("读取接收端设置失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, i18n.Errorf("解析接收端设置失败: %v", err)
+	}
+	if settings.Port <= 0 {
+		settings.Port = DefaultPort
+	}
+	return settings, nil
+}
+
+// 保存接收端设置到 dir
+func (s ReceiverSettings) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return i18n.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return i18n.Errorf("序列化接收端设置失败: %v", err)
+	}
+	if err := storage.WriteFileAtomic(settingsPath(dir), data, 0600); err != nil {
+		return i18n.Errorf("保存接收端设置失败: %v", err)
+	}
+	return nil
+}
+
+// 读取 dir 中的接收端证书，不存在时生成自签名证书并保存，
+// 证书保持不变，发送端记住的指纹才能一直有效
+func LoadCertificate(dir string) (tls.Certificate, error) {
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		return cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, i18n.Errorf("生成密钥失败: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, i18n.Errorf("生成证书失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "SyncSafe"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(20, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, i18n.Errorf("生成证书失败: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, i18n.Errorf("编码密钥失败: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return tls.Certificate{}, i18n.Errorf("创建目录失败: %v", err)
+	}
+	if err := storage.WriteFileAtomic(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, i18n.Errorf("保存密钥失败: %v", err)
+	}
+	if err := storage.WriteFileAtomic(certPath, certPEM, 0644); err != nil {
+		return tls.Certificate{}, i18n.Errorf("保存证书失败: %v", err)
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// 证书的指纹，发送端可以与之核对
+func CertificateFingerprint(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	return fingerprint(cert.Certificate[0])
+}
+
+// 收到的快照
+type Received struct {
+	Machine  string // 发送端的机器名称
+	Snapshot string // 快照目录名称
+	Path     string // 保存的位置
+	Files    int
+	Bytes    int64
+}
+
+// 接收端选项，回调在连接协程中调用，可以为 nil
+type ReceiverOptions struct {
+	Dir        string
+	Code       string // 配对码的数字部分，也可以是完整配对码
+	Cert       tls.Certificate
+	OnReceived func(Received)
+	OnError    func(remote string, err error)
+}
+
+// 接收端
+type Receiver struct {
+	opts        ReceiverOptions
+	secret      string // 配对码的数字部分
+	listener    net.Listener
+	fingerprint string
+	mu          sync.Mutex // 同一时间只接收一个快照，避免写入同一目录
+	authMu      sync.Mutex
+	wg          sync.WaitGroup
+}
+
+// 在 addr 上开始接收快照
+func Listen(addr string, opts ReceiverOptions) (*Receiver, error) {
+	if opts.Dir == "" {
+		return nil, i18n.Errorf("请先选择接收目录")
+	}
+	if secret, _ := splitCode(opts.Code); secret == "" {
+		return nil, i18n.Errorf("配对码不能为空")
+	}
+	listener, err := tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{opts.Cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return nil, i18n.Errorf("监听端口失败: %v", err)
+	}
+	secret, _ := splitCode(opts.Code)
+	r := &Receiver{opts: opts, secret: secret, listener: listener, fingerprint: CertificateFingerprint(opts.Cert)}
+	r.wg.Add(1)
+	go r.serve()
+	return r, nil
+}
+
+// 实际监听的地址
+func (r *Receiver) Addr() net.Addr {
+	return r.listener.Addr()
+}
+
+// 接收端证书指纹
+func (r *Receiver) Fingerprint() string {
+	return r.fingerprint
+}
+
+// 停止接收，等待正在进行的传输结束
+func (r *Receiver) Close() error {
+	err := r.listener.Close()
+	r.wg.Wait()
+	return err
+}
+
+func (r *Receiver) serve() {
+	defer r.wg.Done()
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer conn.Close()
+			received, err := r.handle(conn)
+			if err != nil {
+				writeHeader(conn, header{Kind: msgError, Message: err.Error()})
+				if r.opts.OnError != nil {
+					r.opts.OnError(conn.RemoteAddr().String(), err)
+				}
+				return
+			}
+			if r.opts.OnReceived != nil {
+				r.opts.OnReceived(received)
+			}
+		}()
+	}
+}
+
+// 验证发送端并接收一个快照
+func (r *Receiver) handle(conn net.Conn) (Received, error) {
+	var received Received
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	reader := bufio.NewReader(conn)
+
+	hello, err := expect(reader, msgHello)
+	if err != nil {
+		return received, err
+	}
+	nonce := newNonce()
+	if err := writeHeader(conn, header{Kind: msgChallenge, Nonce: nonce}); err != nil {
+		return received, err
+	}
+	// 发送端先证明，接收端不会向不知道配对码的一方泄露可以离线猜测配对码的证明
+	auth, err := expect(reader, msgAuth)
+	if err != nil {
+		return received, err
+	}
+	if !hmac.Equal(auth.Proof, proof(r.secret, "sender", nonce, r.fingerprint)) {
+		// 验证失败逐个延迟，防止在线猜测配对码
+		r.authMu.Lock()
+		time.Sleep(authFailureDelay)
+		r.authMu.Unlock()
+		return received, errAuth
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	received.Machine = safeName(hello.Machine)
+	received.Snapshot = safeName(hello.Path)
+	received.Path = filepath.Join(r.opts.Dir, received.Machine, received.Snapshot)
+	if err := writeHeader(conn, header{Kind: msgOK, Proof: proof(r.secret, "receiver", auth.Nonce, r.fingerprint)}); err != nil {
+		return received, err
+	}
+	if err := r.receive(reader, conn, &received); err != nil {
+		return received, err
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := writeHeader(conn, header{Kind: msgOK, Message: strconv.Itoa(received.Files)}); err != nil {
+		return received, err
+	}
+	return received, nil
+}
+
+// 接收快照的文件，写入 partial 目录，完成后替换同名的旧快照
+func (r *Receiver) receive(reader io.Reader, conn net.Conn, received *Received) error {
+	partial := received.Path + ".partial"
+	os.RemoveAll(partial)
+	if err := os.MkdirAll(partial, 0755); err != nil {
+		return i18n.Errorf("创建接收目录失败: %v", err)
+	}
+	var dirs []header
+	for {
+		conn.SetDeadline(time.Now().Add(ioTimeout))
+		h, err := readHeader(reader)
+		if err != nil {
+			os.RemoveAll(partial)
+			return i18n.Errorf("接收失败: %v", err)
+		}
+		if h.Kind == msgDone {
+			break
+		}
+		target, err := localPath(partial, h.Path)
+		if err != nil {
+			os.RemoveAll(partial)
+			return err
+		}
+		switch h.Kind {
+		case msgDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				os.RemoveAll(partial)
+				return i18n.Errorf("创建目录失败: %v", err)
+			}
+			dirs = append(dirs, h)
+		case msgFile:
+			// 按最低 64 KB/s 的速度为大文件延长超时时间
+			conn.SetDeadline(time.Now().Add(ioTimeout + time.Duration(h.Size/(64*1024))*time.Second))
+			if err := receiveFile(reader, target, h); err != nil {
+				os.RemoveAll(partial)
+				return err
+			}
+			received.Files++
+			received.Bytes += h.Size
+		default:
+			os.RemoveAll(partial)
+			return i18n.Errorf("意外的消息: %s", h.Kind)
+		}
+	}
+
+	// 从最深的目录开始设置属性
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i].Path) > len(dirs[j].Path) })
+	for _, dir := range dirs {
+		target, _ := localPath(partial, dir.Path)
+		os.Chmod(target, dir.Mode.Perm())
+		os.Chtimes(target, time.Now(), dir.ModTime)
+	}
+
+	if err := os.RemoveAll(received.Path); err != nil {
+		os.RemoveAll(partial)
+		return i18n.Errorf("删除旧快照失败: %v", err)
+	}
+	if err := os.Rename(partial, received.Path); err != nil {
+		os.RemoveAll(partial)
+		return i18n.Errorf("保存快照失败: %v", err)
+	}
+	return nil
+}
+
+// 接收单个文件的内容
+func receiveFile(reader io.Reader, target string, h header) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return i18n.Errorf("创建目录失败: %v", err)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return i18n.Errorf("创建文件失败: %v", err)
+	}
+	if _, err := io.CopyN(file, reader, h.Size); err != nil {
+		file.Close()
+		return i18n.Errorf("接收文件失败: %v\n文件: %s", err, h.Path)
+	}
+	if err := file.Close(); err != nil {
+		return i18n.Errorf("写入文件失败: %v\n文件: %s", err, h.Path)
+	}
+	if h.Mode != 0 {
+		os.Chmod(target, h.Mode.Perm())
+	}
+	os.Chtimes(target, time.Now(), h.ModTime)
+	return nil
+}
+
+// 把发送端给出的相对路径转换为 dir 中的路径，拒绝绝对路径和 ..
+func localPath(dir, relPath string) (string, error) {
+	native := filepath.FromSlash(relPath)
+	if !filepath.IsLocal(native) {
+		return "", i18n.Errorf("非法路径: %s", relPath)
+	}
+	return filepath.Join(dir, native), nil
+}
+
+// 目录名称中只保留安全的字符
+func safeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// 验证失败
+var errAuth = i18n.Error("配对码不正确")
+
+// 验证失败后的延迟
+const authFailureDelay = 2 * time.Second
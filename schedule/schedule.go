@@ -0,0 +1,199 @@
+// Package schedule 解析 cron 风格的定时表达式并计算下一次运行时间。
+//
+// 表达式为 5 个以空格分隔的字段：分钟 小时 日 月 星期（0 或 7 为星期日），
+// 每个字段支持 *、数字、范围 a-b、列表 a,b 和步长 */n、a-b/n，
+// 另外支持 @hourly、@daily、@weekly 和 @monthly。
+// 日和星期都有限制时满足其中之一即可，与标准 cron 相同。
+//
+// 表达式按墙上时间匹配，夏令时切换时每个匹配的墙上时间只运行一次：
+// 时钟拨快跳过的时间在跳过之后立即运行，时钟拨回重复的时间不会运行两次。
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 常用表达式的简写
+var shortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// 查找下一次运行时间的最大范围，超过时视为永远不会运行（例如 2 月 30 日）
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// 解析后的定时表达式，每个字段为允许取值的位集合
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// 字段的取值范围
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"分钟", 0, 59},
+	{"小时", 0, 23},
+	{"日", 1, 31},
+	{"月", 1, 12},
+	{"星期", 0, 7},
+}
+
+// 解析定时表达式
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if full, ok := shortcuts[strings.ToLower(expr)]; ok {
+		expr = full
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, i18n.Errorf("定时表达式需要 5 个字段（分钟 小时 日 月 星期）: %q", expr)
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	// 星期日可以写作 0 或 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(text string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, i18n.Errorf("%s字段的步长无效: %q", i18n.T(f.name), item)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		if rangeText != "*" {
+			lowText, highText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if low, err = strconv.Atoi(lowText); err != nil {
+				return 0, i18n.Errorf("%s字段无效: %q", i18n.T(f.name), item)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highText); err != nil {
+					return 0, i18n.Errorf("%s字段无效: %q", i18n.T(f.name), item)
+				}
+			} else if hasStep {
+				high = f.max
+			}
+		}
+		if low < f.min || high > f.max || low > high {
+			return 0, i18n.Errorf("%s字段超出范围 %d-%d: %q", i18n.T(f.name), f.min, f.max, item)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// 日期是否满足日和星期字段
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// after 之后（不含）的下一次运行时间，按 after 所在的时区计算，永远不会运行时返回零值
+func (s *Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	// 在没有夏令时的 UTC 中逐个查找匹配的墙上时间，再换算为 after 所在时区的时刻
+	wall := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := wall.Add(searchLimit)
+	for wall.Before(limit) {
+		if s.month&(1<<uint(wall.Month())) == 0 {
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(wall) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(wall.Hour())) == 0 {
+			wall = wall.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(wall.Minute())) == 0 {
+			wall = wall.Add(time.Minute)
+			continue
+		}
+		if t := instant(wall, loc); t.After(after) {
+			return t
+		}
+		wall = wall.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// 墙上时间在 loc 中对应的时刻。时钟拨回重复的时间取第一次出现；
+// 时钟拨快跳过的时间取跳过之后的第一个时刻
+func instant(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+	if sameWall(t, wall) {
+		// 往前最多 3 小时内有相同的墙上时间说明处于重复的时段，且 t 是第二次出现
+		for _, d := range []time.Duration{30 * time.Minute, time.Hour, 2 * time.Hour, 3 * time.Hour} {
+			if earlier := t.Add(-d); sameWall(earlier, wall) {
+				return earlier
+			}
+		}
+		return t
+	}
+	// 跳过的时间：从跳过前的最后一分钟往后找到墙上时间不早于 wall 的第一个时刻
+	for u := t.Add(-3 * time.Hour).Truncate(time.Minute); ; u = u.Add(time.Minute) {
+		if !wallOf(u).Before(wall) {
+			return u
+		}
+	}
+}
+
+// t 在所在时区的墙上时间，表示为 UTC 中的同一数值
+func wallOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func sameWall(t, wall time.Time) bool {
+	return wallOf(t).Equal(wall)
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/crypt"
+	"syncsafe/i18n"
+)
+
+// 归档格式。加密时 tar.gz 整体用 age 加密，zip 中的每个文件用 WinZip AES-256 加密，
+// 没有 SyncSafe 时也能用 age、7-Zip 等常见工具解密
+const (
+	ArchiveTarGz    = "tar.gz"
+	ArchiveTarGzAge = "tar.gz.age"
+	ArchiveZip      = "zip"
+)
+
+// 路径对应的归档格式，不是归档时返回空字符串
+func ArchiveFormat(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, "."+ArchiveTarGzAge):
+		return ArchiveTarGzAge
+	case strings.HasSuffix(lower, "."+ArchiveTarGz):
+		return ArchiveTarGz
+	case strings.HasSuffix(lower, "."+ArchiveZip):
+		return ArchiveZip
+	}
+	return ""
+}
+
+// 把快照写入单个归档文件的后端。Root 为归档文件的路径，Root 下的路径写入归档，
+// 其他路径交给下层后端。文件按写入顺序流式压缩，不在目标上生成目录树；
+// 目录的权限和修改时间在 Close 时写入，归档先写到 .partial 文件，完成后再改名
+type Archive struct {
+	Backend
+	Root       string
+	format     string
+	passphrase string // 不为空时加密
+	mu         sync.Mutex
+	file       *os.File
+	age        io.WriteCloser
+	gz         *gzip.Writer
+	tw         *tar.Writer
+	zw         zipWriter
+	dirs       map[string]*archiveDir
+	closed     bool
+}
+
+// zip 写入器，加密时为 crypt.ZipAESWriter
+type zipWriter interface {
+	CreateHeader(header *zip.FileHeader) (io.Writer, error)
+	Close() error
+}
+
+type archiveDir struct {
+	perm    os.FileMode
+	modTime time.Time
+}
+
+// 在 path 创建归档，format 为 ArchiveTarGz、ArchiveTarGzAge 或 ArchiveZip。
+// ArchiveTarGzAge 和设置了 passphrase 的 ArchiveZip 用 passphrase 加密
+func NewArchive(backend Backend, path, format, passphrase string) (*Archive, error) {
+	path = filepath.Clean(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, i18n.Errorf("创建目标文件夹失败: %v", err)
+	}
+	file, err := os.Create(path + ".partial")
+	if err != nil {
+		return nil, i18n.Errorf("创建归档失败: %v", err)
+	}
+	a := &Archive{Backend: backend, Root: path, format: format, passphrase: passphrase, file: file, dirs: make(map[string]*archiveDir)}
+	switch format {
+	case ArchiveZip:
+		if passphrase == "" {
+			a.zw = zip.NewWriter(file)
+		} else if a.zw, err = crypt.NewZipAESWriter(file, passphrase); err != nil {
+			a.abort()
+			return nil, i18n.Errorf("创建归档失败: %v", err)
+		}
+	case ArchiveTarGzAge:
+		if a.age, err = crypt.NewAgeWriter(file, passphrase); err != nil {
+			a.abort()
+			return nil, i18n.Errorf("创建归档失败: %v", err)
+		}
+		a.gz = gzip.NewWriter(a.age)
+		a.tw = tar.NewWriter(a.gz)
+	default:
+		a.gz = gzip.NewWriter(file)
+		a.tw = tar.NewWriter(a.gz)
+	}
+	return a, nil
+}
+
+// 归档内的路径，不在归档中时返回 false
+func (a *Archive) entryName(p string) (string, bool) {
+	rel, err := filepath.Rel(a.Root, filepath.Clean(p))
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// 记录 name 及其上级目录，在 Close 时写入
+func (a *Archive) addDirs(name string) {
+	for ; name != "." && name != "/"; name = filepath.ToSlash(filepath.Dir(name)) {
+		if a.dirs[name] == nil {
+			a.dirs[name] = &archiveDir{perm: 0755, modTime: time.Now()}
+		}
+	}
+}
+
+func (a *Archive) MkdirAll(p string, perm os.FileMode) error {
+	name, ok := a.entryName(p)
+	if !ok {
+		return a.Backend.MkdirAll(p, perm)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addDirs(name)
+	return nil
+}
+
+// 把文件写入归档。归档只能顺序写入，多个复制协程在这里依次写入
+func (a *Archive) CopyFile(ctx context.Context, src, dst string) error {
+	name, ok := a.entryName(dst)
+	if !ok {
+		return a.Backend.CopyFile(ctx, src, dst)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return i18n.Errorf("获取源文件信息失败: %v", err)
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return i18n.Errorf("打开源文件失败: %v", err)
+	}
+	defer file.Close()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return i18n.Errorf("归档已关闭")
+	}
+	a.addDirs(filepath.ToSlash(filepath.Dir(name)))
+	body := contextReader{ctx, io.LimitReader(file, info.Size())}
+	var w io.Writer
+	if a.zw != nil {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		header.Method = zip.Deflate
+		if w, err = a.zw.CreateHeader(header); err != nil {
+			return i18n.Errorf("写入归档失败: %v", err)
+		}
+	} else {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		// tar 的修改时间精确到秒，写入时会四舍五入，这里与 zip 一样舍去
+		header.ModTime = header.ModTime.Truncate(time.Second)
+		if err := a.tw.WriteHeader(header); err != nil {
+			return i18n.Errorf("写入归档失败: %v", err)
+		}
+		w = a.tw
+	}
+	// 源文件在复制期间变短时，tar 缺少的内容无法补齐，归档只能作废
+	if n, err := io.Copy(w, body); err != nil || n != info.Size() {
+		a.abort()
+		if err == nil {
+			err = i18n.Errorf("文件在复制期间被修改")
+		}
+		return i18n.Errorf("写入归档失败: %v\n文件: %s", err, src)
+	}
+	return nil
+}
+
+// 归档中的文件不能硬链接到其他快照，调用方改为复制
+func (a *Archive) Link(existing, dst string, size int64, modTime time.Time) error {
+	if _, ok := a.entryName(dst); !ok {
+		return a.Backend.Link(existing, dst, size, modTime)
+	}
+	return i18n.Errorf("归档不支持硬链接")
+}
+
+// 文件的属性在写入时已经记录，目录的属性在 Close 时写入
+func (a *Archive) SetAttributes(p string, perm os.FileMode, modTime time.Time) error {
+	name, ok := a.entryName(p)
+	if !ok {
+		return a.Backend.SetAttributes(p, perm, modTime)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if dir := a.dirs[name]; dir != nil {
+		dir.perm, dir.modTime = perm, modTime
+	}
+	return nil
+}
+
+// 删除整个归档时放弃写入，归档中的单个文件无法删除
+func (a *Archive) RemoveAll(p string) error {
+	name, ok := a.entryName(p)
+	if !ok {
+		return a.Backend.RemoveAll(p)
+	}
+	if name != "." {
+		return i18n.Errorf("不能删除归档中的文件: %s", name)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.abort()
+	return os.RemoveAll(a.Root)
+}
+
+// 写入目录项并完成归档
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return i18n.Errorf("归档已关闭")
+	}
+	if err := a.finish(); err != nil {
+		a.abort()
+		return i18n.Errorf("写入归档失败: %v", err)
+	}
+	a.closed = true
+	return os.Rename(a.file.Name(), a.Root)
+}
+
+func (a *Archive) finish() error {
+	// 上级目录排在前面，还原时先创建目录
+	names := make([]string, 0, len(a.dirs))
+	for name := range a.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dir := a.dirs[name]
+		if a.zw != nil {
+			header := &zip.FileHeader{Name: name + "/", Modified: dir.modTime}
+			header.SetMode(os.ModeDir | dir.perm)
+			if _, err := a.zw.CreateHeader(header); err != nil {
+				return err
+			}
+			continue
+		}
+		header := &tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: int64(dir.perm), ModTime: dir.modTime.Truncate(time.Second)}
+		if err := a.tw.WriteHeader(header); err != nil {
+			return err
+		}
+	}
+	if a.zw != nil {
+		if err := a.zw.Close(); err != nil {
+			return err
+		}
+	} else {
+		if err := a.tw.Close(); err != nil {
+			return err
+		}
+		if err := a.gz.Close(); err != nil {
+			return err
+		}
+		if a.age != nil {
+			if err := a.age.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return a.file.Close()
+}
+
+// 放弃写入并删除未完成的归档，可以重复调用
+func (a *Archive) abort() {
+	if a.closed {
+		return
+	}
+	a.closed = true
+	a.file.Close()
+	os.Remove(a.file.Name())
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 复制单个文件：先写入临时文件，再重命名为目标文件，保留权限和修改时间。
+// 目标文件已存在且修改时间相同时跳过
+func CopyFile(src, dst string) error {
+	return CopyFileContext(context.Background(), src, dst)
+}
+
+// 可以取消的 CopyFile：ctx 取消后复制中止并删除临时文件，目标文件保持不变
+func CopyFileContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 获取源文件信息
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return i18n.Errorf("获取源文件信息失败: %v", err)
+	}
+
+	// 如果目标文件已存在，检查是否需要更新
+	if dstInfo, err := os.Stat(dst); err == nil {
+		if dstInfo.ModTime().Equal(srcInfo.ModTime()) {
+			return nil // 文件未修改，无需复制
+		}
+	}
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return i18n.Errorf("创建目标目录失败: %v", err)
+	}
+
+	// 尝试打开源文件
+	var source *os.File
+	for retries := 0; retries < 3; retries++ {
+		source, err = os.Open(src)
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Second) // 等待一秒后重试
+	}
+	if err != nil {
+		return i18n.Errorf("打开源文件失败: %v", err)
+	}
+	defer source.Close()
+
+	// 生成临时文件名（不包含空格）
+	tmpFile := filepath.Join(
+		filepath.Dir(dst),
+		fmt.Sprintf("%s.tmp_%d",
+			strings.ReplaceAll(filepath.Base(dst), " ", "_"),
+			time.Now().UnixNano(),
+		),
+	)
+
+	// 创建临时文件
+	var destination *os.File
+	for retries := 0; retries < 3; retries++ {
+		destination, err = os.Create(tmpFile)
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Second) // 等待一秒后重试
+	}
+	if err != nil {
+		return i18n.Errorf("创建临时文件失败: %v", err)
+	}
+
+	// 使用defer和匿名函数来确保在出错时删除临时文件
+	defer func() {
+		destination.Close()
+		if err != nil {
+			os.Remove(tmpFile)
+		}
+	}()
+
+	// 复制文件内容
+	if _, err = io.Copy(destination, contextReader{ctx, source}); err != nil {
+		return i18n.Errorf("复制文件内容失败: %v", err)
+	}
+
+	// 确保文件内容已写入磁盘
+	if err = destination.Sync(); err != nil {
+		return i18n.Errorf("同步文件内容失败: %v", err)
+	}
+
+	// 关闭目标文件
+	if err = destination.Close(); err != nil {
+		return i18n.Errorf("关闭目标文件失败: %v", err)
+	}
+
+	// 设置文件权限和时间戳
+	if err = os.Chmod(tmpFile, srcInfo.Mode()); err != nil {
+		return i18n.Errorf("设置文件权限失败: %v", err)
+	}
+
+	// 设置修改时间
+	if err = os.Chtimes(tmpFile, time.Now(), srcInfo.ModTime()); err != nil {
+		return i18n.Errorf("设置文件时间失败: %v", err)
+	}
+
+	// 如果目标文件存在，先尝试删除
+	if _, err := os.Stat(dst); err == nil {
+		for retries := 0; retries < 3; retries++ {
+			err = os.Remove(dst)
+			if err == nil {
+				break
+			}
+			time.Sleep(time.Second) // 等待一秒后重试
+		}
+		if err != nil {
+			os.Remove(tmpFile) // 清理临时文件
+			return i18n.Errorf("删除已存在的目标文件失败: %v", err)
+		}
+	}
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		os.Remove(tmpFile) // 清理临时文件
+		return i18n.Errorf("创建目标目录失败: %v", err)
+	}
+
+	// 重命名临时文件为最终文件
+	for retries := 0; retries < 3; retries++ {
+		err = os.Rename(tmpFile, dst)
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Second) // 等待一秒后重试
+	}
+	if err != nil {
+		os.Remove(tmpFile) // 清理临时文件
+		return i18n.Errorf("重命名文件失败: %v\n源文件: %s\n目标文件: %s", err, tmpFile, dst)
+	}
+
+	return nil
+}
+
+// 每次读取前检查 ctx，大文件复制过程中也能及时取消
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
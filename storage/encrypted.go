@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/crypt"
+	"syncsafe/i18n"
+)
+
+// 加密的存储后端：文件先加密到本机的临时文件，再交给下层后端写入目标，可选加密文件名。
+// Root 下的第一级目录（快照目录）名称不加密，历史记录、清理和快速同步照常使用原来的路径
+type Encrypted struct {
+	Backend
+	Root           string
+	Key            *crypt.Key
+	ObfuscateNames bool
+}
+
+// 创建加密后端，root 为目标根目录
+func NewEncrypted(backend Backend, root string, key *crypt.Key, obfuscateNames bool) *Encrypted {
+	return &Encrypted{Backend: backend, Root: filepath.Clean(root), Key: key, ObfuscateNames: obfuscateNames}
+}
+
+// 目标上的实际路径：快照目录以下的每一级名称分别加密
+func (e *Encrypted) Path(p string) (string, error) {
+	if !e.ObfuscateNames {
+		return p, nil
+	}
+	rel, err := filepath.Rel(e.Root, filepath.Clean(p))
+	if err != nil || !filepath.IsLocal(rel) {
+		return p, nil
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i := 1; i < len(parts); i++ {
+		if parts[i], err = e.Key.EncryptName(parts[i]); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(append([]string{e.Root}, parts...)...), nil
+}
+
+func (e *Encrypted) MkdirAll(p string, perm os.FileMode) error {
+	target, err := e.Path(p)
+	if err != nil {
+		return err
+	}
+	return e.Backend.MkdirAll(target, perm)
+}
+
+func (e *Encrypted) CopyFile(ctx context.Context, src, dst string) error {
+	target, err := e.Path(dst)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return i18n.Errorf("获取源文件信息失败: %v", err)
+	}
+	tmp, err := e.encryptToTemp(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	// 临时文件带上源文件的权限和修改时间，由下层后端照常保留
+	if err := os.Chmod(tmp, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp, time.Now(), info.ModTime()); err != nil {
+		return err
+	}
+	return e.Backend.CopyFile(ctx, tmp, target)
+}
+
+// 把 src 加密到临时文件，返回临时文件路径
+func (e *Encrypted) encryptToTemp(ctx context.Context, src string) (string, error) {
+	source, err := os.Open(src)
+	if err != nil {
+		return "", i18n.Errorf("打开源文件失败: %v", err)
+	}
+	defer source.Close()
+	tmp, err := os.CreateTemp("", "syncsafe-encrypt-*")
+	if err != nil {
+		return "", i18n.Errorf("创建临时文件失败: %v", err)
+	}
+	err = e.Key.Encrypt(tmp, contextReader{ctx, source})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", i18n.Errorf("加密文件失败: %v", err)
+	}
+	return tmp.Name(), nil
+}
+
+func (e *Encrypted) Link(existing, dst string, size int64, modTime time.Time) error {
+	existingPath, err := e.Path(existing)
+	if err != nil {
+		return err
+	}
+	target, err := e.Path(dst)
+	if err != nil {
+		return err
+	}
+	return e.Backend.Link(existingPath, target, crypt.EncryptedSize(size), modTime)
+}
+
+func (e *Encrypted) SetAttributes(p string, perm os.FileMode, modTime time.Time) error {
+	target, err := e.Path(p)
+	if err != nil {
+		return err
+	}
+	return e.Backend.SetAttributes(target, perm, modTime)
+}
+
+func (e *Encrypted) RemoveAll(p string) error {
+	target, err := e.Path(p)
+	if err != nil {
+		return err
+	}
+	return e.Backend.RemoveAll(target)
+}
+
+func (e *Encrypted) Move(src, dst string) error {
+	mover, ok := e.Backend.(Mover)
+	if !ok {
+		return i18n.Errorf("目标不支持移动文件")
+	}
+	srcPath, err := e.Path(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := e.Path(dst)
+	if err != nil {
+		return err
+	}
+	return mover.Move(srcPath, dstPath)
+}
+
+// 列出 dir 下的文件，返回原始文件名和明文大小。无法解密的文件不在结果中，保留在目标里。
+// 下层后端不支持列出时返回空列表，所有文件重新上传
+func (e *Encrypted) List(dir string) (map[string]RemoteFile, error) {
+	lister, ok := e.Backend.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	target, err := e.Path(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := lister.List(target)
+	if err != nil {
+		return nil, err
+	}
+	plain := make(map[string]RemoteFile, len(files))
+	for rel, file := range files {
+		size, ok := crypt.PlainSize(file.Size)
+		if !ok {
+			continue
+		}
+		if e.ObfuscateNames {
+			if rel, err = e.plainRel(rel); err != nil {
+				continue
+			}
+		}
+		plain[rel] = RemoteFile{Size: size, ModTime: file.ModTime}
+	}
+	return plain, nil
+}
+
+// 解密相对路径中的每一级名称
+func (e *Encrypted) plainRel(rel string) (string, error) {
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		name, err := e.Key.DecryptName(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = name
+	}
+	return filepath.Join(parts...), nil
+}
@@ -0,0 +1,479 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"syncsafe/i18n"
+)
+
+// S3 兼容对象存储（Amazon S3、MinIO 等）的登录信息和存储类别
+type S3Config struct {
+	Endpoint      string // 服务地址，例如 http://nas:9000，为空表示 Amazon S3
+	Region        string // 存储桶所在的区域，为空时自动查询
+	AccessKey     string
+	SecretKey     string // 只保存在本机
+	StorageClass  string // 新快照的存储类别，为空表示 STANDARD
+	ColdClass     string // 较旧的快照转入的存储类别，为空表示 GLACIER
+	ColdAfterDays int    // 快照保存多少天后转入冷存储，0 表示不转入
+}
+
+// S3 存储类别
+const (
+	ClassStandard    = "STANDARD"
+	ClassStandardIA  = "STANDARD_IA"
+	ClassGlacierIR   = "GLACIER_IR" // 即时取回，不需要先取回
+	ClassGlacier     = "GLACIER"
+	ClassDeepArchive = "DEEP_ARCHIVE"
+)
+
+// 冷存储的取回速度，越快费用越高
+const (
+	RetrievalExpedited = "Expedited"
+	RetrievalStandard  = "Standard"
+	RetrievalBulk      = "Bulk"
+)
+
+// 较旧的快照转入的存储类别
+func (c S3Config) Cold() string {
+	if c.ColdClass == "" {
+		return ClassGlacier
+	}
+	return c.ColdClass
+}
+
+// 该存储类别的文件是否要先取回才能读取
+func NeedsRetrieval(class string) bool {
+	return class == ClassGlacier || class == ClassDeepArchive
+}
+
+// 按 AWS 公布的时间，取回通常在多长时间内完成。该存储类别不支持这种速度时返回 false
+func RetrievalTime(class, tier string) (time.Duration, bool) {
+	switch {
+	case class == ClassGlacier && tier == RetrievalExpedited:
+		return 5 * time.Minute, true
+	case class == ClassGlacier && tier == RetrievalStandard:
+		return 5 * time.Hour, true
+	case class == ClassGlacier && tier == RetrievalBulk:
+		return 12 * time.Hour, true
+	case class == ClassDeepArchive && tier == RetrievalStandard:
+		return 12 * time.Hour, true
+	case class == ClassDeepArchive && tier == RetrievalBulk:
+		return 48 * time.Hour, true
+	}
+	return 0, false
+}
+
+// 文件在冷存储中且还没有取回
+var ErrColdStorage = i18n.Error("文件在冷存储中，需要先取回才能读取")
+
+// 上传时保存源文件修改时间的用户元数据
+const mtimeMeta = "Mtime"
+
+// S3 目标，地址为 s3://存储桶/前缀。引擎把地址当作本地路径拼接出目标路径，
+// 这里按相对 Root 的部分映射为对象名。对象存储没有目录，创建目录不需要任何操作
+type S3 struct {
+	Root   string
+	bucket string
+	prefix string
+	config S3Config
+	client *minio.Client
+	err    error
+}
+
+// 路径是否为 S3 地址
+func IsS3(path string) bool {
+	// 快照路径经过 filepath.Clean，"//" 变成了单个分隔符
+	return strings.HasPrefix(strings.ToLower(filepath.ToSlash(path)), "s3:/")
+}
+
+// 路径是否为远程目标（WebDAV 或 S3），远程目标中的快照不能当作本地目录读取
+func IsRemote(path string) bool {
+	return IsWebDAV(path) || IsS3(path)
+}
+
+// 创建 S3 后端，rawURL 为 s3://存储桶/前缀。地址或服务地址无效时在第一次操作时返回错误
+func NewS3(rawURL string, config S3Config) *S3 {
+	s := &S3{Root: filepath.Clean(rawURL), config: config}
+	location := strings.TrimLeft(filepath.ToSlash(s.Root)[len("s3:"):], "/")
+	s.bucket, s.prefix, _ = strings.Cut(location, "/")
+	if s.bucket == "" {
+		s.err = i18n.Errorf("S3 地址缺少存储桶名称: %s", rawURL)
+		return s
+	}
+	endpoint, secure := "s3.amazonaws.com", true
+	if config.Endpoint != "" {
+		u, err := url.Parse(config.Endpoint)
+		if err != nil || u.Host == "" {
+			s.err = i18n.Errorf("S3 服务地址无效: %s", config.Endpoint)
+			return s
+		}
+		endpoint, secure = u.Host, u.Scheme != "http"
+	}
+	s.client, s.err = minio.New(endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure:    secure,
+		Region:    config.Region,
+		Transport: remoteTransport(),
+	})
+	return s
+}
+
+// 地址或服务地址无效时的错误，不连接服务器
+func (s *S3) Err() error {
+	return s.err
+}
+
+// 目标路径对应的对象名，Root 本身对应前缀
+func (s *S3) key(p string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	rel, err := filepath.Rel(s.Root, filepath.Clean(p))
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", i18n.Errorf("路径不在 S3 目标中: %s", p)
+	}
+	if rel == "." {
+		return s.prefix, nil
+	}
+	return path.Join(s.prefix, filepath.ToSlash(rel)), nil
+}
+
+// 目录 p 中所有对象的共同前缀
+func (s *S3) dirPrefix(p string) (string, error) {
+	key, err := s.key(p)
+	if err != nil || key == "" {
+		return key, err
+	}
+	return key + "/", nil
+}
+
+// 统一 S3 返回的错误：找不到对象为 fs.ErrNotExist，未取回的冷存储对象为 ErrColdStorage
+func s3Error(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		return fmt.Errorf("%v: %w", err, fs.ErrNotExist)
+	case "InvalidObjectState":
+		return ErrColdStorage
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return i18n.Errorf("S3 访问密钥错误或没有权限: %v", err)
+	}
+	return err
+}
+
+// 检查能否登录以及存储桶是否存在
+func (s *S3) Check() error {
+	if s.err != nil {
+		return s.err
+	}
+	exists, err := s.client.BucketExists(context.Background(), s.bucket)
+	if err != nil {
+		return s3Error(err)
+	}
+	if !exists {
+		return i18n.Errorf("S3 存储桶不存在: %s", s.bucket)
+	}
+	return nil
+}
+
+// 对象存储没有目录，上传对象时自动出现在前缀下
+func (s *S3) MkdirAll(p string, perm os.FileMode) error {
+	_, err := s.key(p)
+	return err
+}
+
+// 按设置的存储类别上传，源文件的修改时间保存在用户元数据中
+func (s *S3) CopyFile(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return i18n.Errorf("获取源文件信息失败: %v", err)
+	}
+	key, err := s.key(dst)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return i18n.Errorf("打开源文件失败: %v", err)
+	}
+	defer file.Close()
+
+	_, err = s.client.PutObject(ctx, s.bucket, key, file, info.Size(), minio.PutObjectOptions{
+		StorageClass: s.config.StorageClass,
+		UserMetadata: map[string]string{mtimeMeta: strconv.FormatInt(info.ModTime().Unix(), 10)},
+	})
+	if err != nil {
+		return i18n.Errorf("上传文件失败: %v\n文件: %s", s3Error(err), src)
+	}
+	return nil
+}
+
+// S3 不支持硬链接，调用方改为上传
+func (s *S3) Link(existing, dst string, size int64, modTime time.Time) error {
+	return i18n.Errorf("S3 不支持硬链接")
+}
+
+// S3 不能设置权限，文件的修改时间在上传时保存
+func (s *S3) SetAttributes(p string, perm os.FileMode, modTime time.Time) error {
+	return nil
+}
+
+// 删除对象 p 以及前缀 p/ 下的所有对象
+func (s *S3) RemoveAll(p string) error {
+	key, err := s.key(p)
+	if err != nil {
+		return err
+	}
+	prefix, _ := s.dirPrefix(p)
+	ctx := context.Background()
+	objects := make(chan minio.ObjectInfo)
+	listErr := make(chan error, 1)
+	go func() {
+		defer close(objects)
+		if key != "" {
+			objects <- minio.ObjectInfo{Key: key}
+		}
+		for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				listErr <- object.Err
+				return
+			}
+			objects <- object
+		}
+		listErr <- nil
+	}()
+	var errs []error
+	for result := range s.client.RemoveObjects(ctx, s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		errs = append(errs, fmt.Errorf("%s: %v", result.ObjectName, s3Error(result.Err)))
+	}
+	if err := <-listErr; err != nil {
+		errs = append(errs, s3Error(err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return i18n.Errorf("删除失败: %v", err)
+	}
+	return nil
+}
+
+// 在服务器上复制到 dst 后删除 src，冷存储中没有取回的文件不能复制
+func (s *S3) Move(src, dst string) error {
+	source, err := s.key(src)
+	if err != nil {
+		return err
+	}
+	target, err := s.key(dst)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	_, err = s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucket, Object: target},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: source})
+	if err == nil {
+		err = s.client.RemoveObject(ctx, s.bucket, source, minio.RemoveObjectOptions{})
+	}
+	if err != nil {
+		return i18n.Errorf("移动失败: %v", s3Error(err))
+	}
+	return nil
+}
+
+// 读取文件内容，冷存储中没有取回的文件返回 ErrColdStorage
+func (s *S3) Open(p string) (io.ReadCloser, error) {
+	key, err := s.key(p)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	// 查询对象时存储类别只在响应头中
+	if NeedsRetrieval(info.Metadata.Get("X-Amz-Storage-Class")) && (info.Restore == nil || info.Restore.OngoingRestore) {
+		return nil, fmt.Errorf("%w: %s", ErrColdStorage, p)
+	}
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return object, nil
+}
+
+// 文件的修改时间：上传时保存的元数据，列出时服务器没有返回元数据则为上传时间
+func objectModTime(object minio.ObjectInfo) time.Time {
+	for key, value := range object.UserMetadata {
+		if strings.EqualFold(strings.TrimPrefix(strings.ToLower(key), "x-amz-meta-"), mtimeMeta) {
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.Unix(sec, 0)
+			}
+		}
+	}
+	return object.LastModified
+}
+
+// 列出 dir 下的所有对象，不存在的目录为空列表。列出时返回用户元数据是 MinIO 的扩展，
+// 只对自建的服务请求；Amazon S3 上的修改时间为上传时间，快速同步会重新上传修改时间不同的文件
+func (s *S3) List(dir string) (map[string]RemoteFile, error) {
+	files := make(map[string]RemoteFile)
+	err := s.walk(dir, s.config.Endpoint != "", func(rel string, object minio.ObjectInfo) error {
+		files[filepath.FromSlash(rel)] = RemoteFile{Size: object.Size, ModTime: objectModTime(object)}
+		return nil
+	})
+	return files, err
+}
+
+// 依次访问 dir 下的对象，rel 为相对 dir 的对象名
+func (s *S3) walk(dir string, metadata bool, visit func(rel string, object minio.ObjectInfo) error) error {
+	prefix, err := s.dirPrefix(dir)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	options := minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithMetadata: metadata}
+	for object := range s.client.ListObjects(ctx, s.bucket, options) {
+		if object.Err != nil {
+			return s3Error(object.Err)
+		}
+		rel := strings.TrimPrefix(object.Key, prefix)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+		if err := visit(rel, object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 对象存储没有容量限制
+func (s *S3) Usage() (total, free uint64, err error) {
+	return 0, 0, i18n.Errorf("S3 目标没有容量信息")
+}
+
+// 把 dir 下的文件转入 class 存储类别，返回转入的文件数。S3 通过把对象复制到原位置来修改存储类别，
+// 复制时保留上传时保存的修改时间。已经是该类别的文件跳过
+func (s *S3) Transition(dir, class string) (int, error) {
+	ctx := context.Background()
+	count := 0
+	err := s.walk(dir, false, func(rel string, object minio.ObjectInfo) error {
+		if object.StorageClass == class {
+			return nil
+		}
+		info, err := s.client.StatObject(ctx, s.bucket, object.Key, minio.StatObjectOptions{})
+		if err != nil {
+			return s3Error(err)
+		}
+		metadata := map[string]string{"X-Amz-Storage-Class": class}
+		if mtime, ok := info.UserMetadata[mtimeMeta]; ok {
+			metadata[mtimeMeta] = mtime
+		}
+		dst := minio.CopyDestOptions{Bucket: s.bucket, Object: object.Key, ReplaceMetadata: true, UserMetadata: metadata}
+		src := minio.CopySrcOptions{Bucket: s.bucket, Object: object.Key}
+		// 超过 5 GiB 的对象只能分段复制
+		if info.Size > 5<<30 {
+			_, err = s.client.ComposeObject(ctx, dst, src)
+		} else {
+			_, err = s.client.CopyObject(ctx, dst, src)
+		}
+		if err != nil {
+			return i18n.Errorf("修改存储类别失败: %v\n文件: %s", s3Error(err), object.Key)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// 冷存储中文件的取回状态
+type Retrieval struct {
+	Class     string    // 冷存储类别，没有冷存储中的文件时为空
+	Files     int       // 冷存储中的文件数
+	Pending   int       // 还没有请求取回的文件数
+	Restoring int       // 正在取回的文件数
+	Ready     int       // 已经取回、可以读取的文件数
+	Expires   time.Time // 取回的副本中最早的过期时间，过期后需要重新取回
+}
+
+// 冷存储中的文件是否都已取回
+func (r Retrieval) Complete() bool {
+	return r.Pending == 0 && r.Restoring == 0
+}
+
+// 查询 dir 下冷存储中的文件的取回状态
+func (s *S3) Retrieval(dir string) (Retrieval, error) {
+	var status Retrieval
+	ctx := context.Background()
+	err := s.walk(dir, false, func(rel string, object minio.ObjectInfo) error {
+		if !NeedsRetrieval(object.StorageClass) {
+			return nil
+		}
+		info, err := s.client.StatObject(ctx, s.bucket, object.Key, minio.StatObjectOptions{})
+		if err != nil {
+			return s3Error(err)
+		}
+		status.Class = object.StorageClass
+		status.Files++
+		switch {
+		case info.Restore == nil:
+			status.Pending++
+		case info.Restore.OngoingRestore:
+			status.Restoring++
+		default:
+			status.Ready++
+			if status.Expires.IsZero() || info.Restore.ExpiryTime.Before(status.Expires) {
+				status.Expires = info.Restore.ExpiryTime
+			}
+		}
+		return nil
+	})
+	return status, err
+}
+
+// 为 dir 下冷存储中还没有取回的文件请求取回，tier 为取回速度，取回的副本保留 days 天。
+// 返回请求取回的文件数，取回在服务器上进行，完成前文件仍不能读取
+func (s *S3) Retrieve(dir, tier string, days int) (int, error) {
+	ctx := context.Background()
+	count := 0
+	err := s.walk(dir, false, func(rel string, object minio.ObjectInfo) error {
+		if !NeedsRetrieval(object.StorageClass) {
+			return nil
+		}
+		info, err := s.client.StatObject(ctx, s.bucket, object.Key, minio.StatObjectOptions{})
+		if err != nil {
+			return s3Error(err)
+		}
+		if info.Restore != nil {
+			return nil
+		}
+		var request minio.RestoreRequest
+		request.SetDays(days)
+		request.SetGlacierJobParameters(minio.GlacierJobParameters{Tier: minio.TierType(tier)})
+		// 服务器对新的取回请求返回 202，minio-go 把它当作错误
+		err = s.client.RestoreObject(ctx, s.bucket, object.Key, "", request)
+		if err != nil && minio.ToErrorResponse(err).StatusCode != http.StatusAccepted {
+			return i18n.Errorf("请求取回失败: %v\n文件: %s", s3Error(err), object.Key)
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
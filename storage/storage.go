@@ -0,0 +1,141 @@
+// Package storage 提供备份目标的存储后端和文件写入工具。
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// 备份目标的存储后端，路径均为目标上的完整路径
+type Backend interface {
+	// 创建目录及其所有上级目录
+	MkdirAll(path string, perm os.FileMode) error
+	// 把本地文件复制到目标，修改时间相同的已有文件会被跳过，ctx 取消时中止复制
+	CopyFile(ctx context.Context, src, dst string) error
+	// 为目标上已有的文件创建硬链接。existing 的大小或修改时间与预期不同、
+	// 或者文件系统不支持硬链接时返回错误，调用方应改为复制
+	Link(existing, dst string, size int64, modTime time.Time) error
+	// 设置文件或目录的权限和修改时间
+	SetAttributes(path string, perm os.FileMode, modTime time.Time) error
+	// 删除目录或文件
+	RemoveAll(path string) error
+	// 目标磁盘的总容量和可用空间（字节）
+	Usage() (total, free uint64, err error)
+}
+
+// 目标上已有文件的元数据
+type RemoteFile struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// 可以列出目标上已有文件的后端。快速同步先列出目标，只上传大小或修改时间不同的文件，
+// 对远程后端可以省去大量逐个文件的查询和上传
+type Lister interface {
+	// 递归列出 dir 下的所有文件，键为相对 dir 的路径，dir 不存在时返回空列表
+	List(dir string) (map[string]RemoteFile, error)
+}
+
+// 可以在目标中移动文件的后端。快速同步把镜像中多余的文件移到回收文件夹，而不是直接删除
+type Mover interface {
+	// 把 src 移到 dst，自动创建 dst 的上级目录
+	Move(src, dst string) error
+}
+
+// 可以读取目标上文件内容的后端。远程目标中的快照可以直接浏览和按文件还原，
+// 不需要先把整个快照下载到本地
+type Opener interface {
+	// 读取 path 的内容，调用方负责关闭
+	Open(path string) (io.ReadCloser, error)
+}
+
+// 本地文件夹（包括挂载的网络驱动器）
+type Local struct {
+	Root string
+}
+
+// 创建本地文件夹后端
+func NewLocal(root string) *Local {
+	return &Local{Root: filepath.Clean(root)}
+}
+
+func (l *Local) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (l *Local) CopyFile(ctx context.Context, src, dst string) error {
+	return CopyFileContext(ctx, src, dst)
+}
+
+func (l *Local) Link(existing, dst string, size int64, modTime time.Time) error {
+	info, err := os.Lstat(existing)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || info.Size() != size || !info.ModTime().Equal(modTime) {
+		return i18n.Errorf("上一个快照中的文件已被修改: %s", existing)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Link(existing, dst)
+}
+
+func (l *Local) SetAttributes(path string, perm os.FileMode, modTime time.Time) error {
+	if err := os.Chmod(path, perm); err != nil {
+		return err
+	}
+	return os.Chtimes(path, time.Now(), modTime)
+}
+
+func (l *Local) RemoveAll(path string) error {
+	if err := os.RemoveAll(path); err == nil {
+		return nil
+	}
+	// 快照保留了源目录的只读权限，先恢复写权限再删除其中的内容
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			os.Chmod(p, info.Mode().Perm()|0700)
+		}
+		return nil
+	})
+	return os.RemoveAll(path)
+}
+
+func (l *Local) Move(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+func (l *Local) List(dir string) (map[string]RemoteFile, error) {
+	files := make(map[string]RemoteFile)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = RemoteFile{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+func (l *Local) Usage() (total, free uint64, err error) {
+	return DiskUsage(l.Root)
+}
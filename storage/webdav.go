@@ -0,0 +1,468 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"syncsafe/i18n"
+)
+
+// WebDAV 服务器（Nextcloud、ownCloud 等）的登录信息
+type WebDAVConfig struct {
+	Username string
+	Password string // 建议使用应用专用密码，只保存在本机
+}
+
+// 默认的分块上传大小，超过该大小的文件在 Nextcloud 和 ownCloud 上分块上传
+const DefaultChunkSize = 10 << 20
+
+// 远程目标连接、TLS 握手和等待响应头的超时。上传和下载大文件的时间取决于网速，不设总超时，
+// 只避免服务器无响应时备份一直挂起
+const (
+	remoteDialTimeout      = 30 * time.Second
+	remoteHandshakeTimeout = 30 * time.Second
+	remoteResponseTimeout  = 5 * time.Minute
+)
+
+// WebDAV 目标。引擎把地址当作本地路径拼接出目标路径，这里按相对 Root 的部分映射回 URL。
+// 服务器是 Nextcloud 或 ownCloud 时，大文件分块上传，并通过 X-OC-Mtime 保留修改时间，
+// 快速同步可以按修改时间跳过未变化的文件。
+// CopyFile 不逐个查询目标上的文件，总是上传；未变化的文件由快速同步通过 List 跳过
+type WebDAV struct {
+	Root      string
+	ChunkSize int64
+	base      *url.URL
+	uploads   *url.URL // 分块上传目录，服务器不支持时为 nil
+	config    WebDAVConfig
+	client    *http.Client
+	err       error
+	created   sync.Map // 已确认存在的目录
+}
+
+// 路径是否为 WebDAV 地址
+func IsWebDAV(path string) bool {
+	// 快照路径经过 filepath.Clean，"//" 变成了单个分隔符
+	lower := strings.ToLower(filepath.ToSlash(path))
+	return strings.HasPrefix(lower, "http:/") || strings.HasPrefix(lower, "https:/")
+}
+
+// 创建 WebDAV 后端，rawURL 为备份目录的地址。地址无效时在第一次操作时返回错误
+func NewWebDAV(rawURL string, config WebDAVConfig) *WebDAV {
+	w := &WebDAV{
+		Root:      filepath.Clean(rawURL),
+		ChunkSize: DefaultChunkSize,
+		config:    config,
+		client:    &http.Client{Transport: remoteTransport()},
+	}
+	base, err := url.Parse(strings.TrimRight(rawURL, "/"))
+	if err != nil {
+		w.err = i18n.Errorf("WebDAV 地址无效: %v", err)
+		return w
+	}
+	w.base = base
+	w.uploads = chunkedUploadsURL(base)
+	return w
+}
+
+// 在默认传输的基础上（保留代理设置）加上超时，WebDAV 和 S3 共用
+func remoteTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: remoteDialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = remoteHandshakeTimeout
+	transport.ResponseHeaderTimeout = remoteResponseTimeout
+	return transport
+}
+
+// Nextcloud 和 ownCloud 的文件地址为 .../remote.php/dav/files/<用户>/...，
+// 对应的分块上传目录为 .../remote.php/dav/uploads/<用户>
+func chunkedUploadsURL(base *url.URL) *url.URL {
+	const marker = "/remote.php/dav/files/"
+	i := strings.Index(base.Path, marker)
+	if i < 0 {
+		return nil
+	}
+	user, _, _ := strings.Cut(base.Path[i+len(marker):], "/")
+	if user == "" {
+		return nil
+	}
+	u := *base
+	u.Path = base.Path[:i] + "/remote.php/dav/uploads/" + user
+	u.RawPath = ""
+	return &u
+}
+
+// 目标路径对应的 URL
+func (w *WebDAV) url(p string) (*url.URL, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	rel, err := filepath.Rel(w.Root, filepath.Clean(p))
+	if err != nil || !filepath.IsLocal(rel) {
+		return nil, i18n.Errorf("路径不在 WebDAV 目标中: %s", p)
+	}
+	if rel == "." {
+		u := *w.base
+		return &u, nil
+	}
+	return w.base.JoinPath(strings.Split(filepath.ToSlash(rel), "/")...), nil
+}
+
+// 发送请求，size 为请求体的长度
+func (w *WebDAV) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, header map[string]string) (*http.Response, error) {
+	if body == nil || size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if w.config.Username != "" || w.config.Password != "" {
+		req.SetBasicAuth(w.config.Username, w.config.Password)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return w.client.Do(req)
+}
+
+// 读取并关闭响应，状态码不在 ok 中时返回错误
+func checkResponse(resp *http.Response, err error, ok ...int) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if slices.Contains(ok, resp.StatusCode) {
+		return nil
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return i18n.Errorf("WebDAV 用户名或密码错误")
+	case http.StatusInsufficientStorage:
+		return i18n.Errorf("WebDAV 存储空间不足")
+	case http.StatusNotFound:
+		return fmt.Errorf("%s %s: %w", resp.Request.Method, resp.Request.URL.Path, fs.ErrNotExist)
+	}
+	return i18n.Errorf("%s %s: 服务器返回 %s", resp.Request.Method, resp.Request.URL.Path, resp.Status)
+}
+
+// 检查能否登录并创建备份目录
+func (w *WebDAV) Check() error {
+	return w.MkdirAll(w.Root, 0755)
+}
+
+// 从备份目录开始逐级创建，perm 在 WebDAV 上没有作用
+func (w *WebDAV) MkdirAll(p string, perm os.FileMode) error {
+	target, err := w.url(p)
+	if err != nil {
+		return err
+	}
+	dirs := []*url.URL{w.base}
+	if rel, _ := filepath.Rel(w.Root, filepath.Clean(p)); rel != "." {
+		current := w.base
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			current = current.JoinPath(part)
+			dirs = append(dirs, current)
+		}
+	}
+	for _, dir := range dirs {
+		key := dir.String()
+		if _, ok := w.created.Load(key); ok {
+			continue
+		}
+		// 201 为新建，405 为目录已存在
+		resp, err := w.do(context.Background(), "MKCOL", dir, nil, 0, nil)
+		if err := checkResponse(resp, err, http.StatusCreated, http.StatusMethodNotAllowed); err != nil {
+			return i18n.Errorf("创建目录失败: %v\n目录: %s", err, target.Path)
+		}
+		w.created.Store(key, true)
+	}
+	return nil
+}
+
+// 上传文件的修改时间，Nextcloud 和 ownCloud 会把它作为文件的修改时间
+func mtimeHeader(info os.FileInfo) map[string]string {
+	return map[string]string{"X-OC-Mtime": strconv.FormatInt(info.ModTime().Unix(), 10)}
+}
+
+func (w *WebDAV) CopyFile(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return i18n.Errorf("获取源文件信息失败: %v", err)
+	}
+	target, err := w.url(dst)
+	if err != nil {
+		return err
+	}
+	if err := w.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return i18n.Errorf("打开源文件失败: %v", err)
+	}
+	defer file.Close()
+
+	if w.uploads != nil && info.Size() > w.ChunkSize {
+		return w.uploadChunked(ctx, file, info, target)
+	}
+	body := contextReader{ctx, io.LimitReader(file, info.Size())}
+	resp, err := w.do(ctx, http.MethodPut, target, body, info.Size(), mtimeHeader(info))
+	if err := checkResponse(resp, err, http.StatusOK, http.StatusCreated, http.StatusNoContent); err != nil {
+		return i18n.Errorf("上传文件失败: %v\n文件: %s", err, src)
+	}
+	return nil
+}
+
+// Nextcloud 和 ownCloud 的分块上传：在上传目录中逐块 PUT，最后把 .file MOVE 到目标位置合并。
+// 失败时删除上传目录，服务器上不会留下不完整的文件
+func (w *WebDAV) uploadChunked(ctx context.Context, file *os.File, info os.FileInfo, target *url.URL) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	dir := w.uploads.JoinPath("syncsafe-" + hex.EncodeToString(id))
+	header := map[string]string{
+		"Destination":     target.String(),
+		"OC-Total-Length": strconv.FormatInt(info.Size(), 10),
+	}
+	resp, err := w.do(ctx, "MKCOL", dir, nil, 0, header)
+	if err := checkResponse(resp, err, http.StatusCreated); err != nil {
+		return i18n.Errorf("创建分块上传目录失败: %v", err)
+	}
+	merged := false
+	defer func() {
+		if !merged {
+			resp, err := w.do(context.Background(), http.MethodDelete, dir, nil, 0, nil)
+			checkResponse(resp, err)
+		}
+	}()
+
+	for n, offset := 1, int64(0); offset < info.Size(); n, offset = n+1, offset+w.ChunkSize {
+		size := min(w.ChunkSize, info.Size()-offset)
+		chunk := contextReader{ctx, io.NewSectionReader(file, offset, size)}
+		resp, err := w.do(ctx, http.MethodPut, dir.JoinPath(fmt.Sprintf("%05d", n)), chunk, size, header)
+		if err := checkResponse(resp, err, http.StatusCreated, http.StatusNoContent); err != nil {
+			return i18n.Errorf("上传第 %d 块失败: %v\n文件: %s", n, err, file.Name())
+		}
+	}
+
+	header["Overwrite"] = "T"
+	for key, value := range mtimeHeader(info) {
+		header[key] = value
+	}
+	resp, err = w.do(ctx, "MOVE", dir.JoinPath(".file"), nil, 0, header)
+	if err := checkResponse(resp, err, http.StatusCreated, http.StatusNoContent); err != nil {
+		return i18n.Errorf("合并分块失败: %v\n文件: %s", err, file.Name())
+	}
+	merged = true
+	return nil
+}
+
+// WebDAV 不支持硬链接，调用方改为上传
+func (w *WebDAV) Link(existing, dst string, size int64, modTime time.Time) error {
+	return i18n.Errorf("WebDAV 不支持硬链接")
+}
+
+// WebDAV 不能设置权限，文件的修改时间在上传时设置
+func (w *WebDAV) SetAttributes(p string, perm os.FileMode, modTime time.Time) error {
+	return nil
+}
+
+func (w *WebDAV) RemoveAll(p string) error {
+	target, err := w.url(p)
+	if err != nil {
+		return err
+	}
+	resp, err := w.do(context.Background(), http.MethodDelete, target, nil, 0, nil)
+	if err := checkResponse(resp, err, http.StatusOK, http.StatusNoContent, http.StatusNotFound); err != nil {
+		return i18n.Errorf("删除失败: %v", err)
+	}
+	prefix := target.String()
+	w.created.Range(func(key, _ any) bool {
+		if s := key.(string); s == prefix || strings.HasPrefix(s, prefix+"/") {
+			w.created.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
+
+// 用 MOVE 请求在服务器上移动，先逐级创建目标的上级目录
+func (w *WebDAV) Move(src, dst string) error {
+	source, err := w.url(src)
+	if err != nil {
+		return err
+	}
+	target, err := w.url(dst)
+	if err != nil {
+		return err
+	}
+	if err := w.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	resp, err := w.do(context.Background(), "MOVE", source, nil, 0, map[string]string{"Destination": target.String(), "Overwrite": "T"})
+	if err := checkResponse(resp, err, http.StatusCreated, http.StatusNoContent); err != nil {
+		return i18n.Errorf("移动失败: %v", err)
+	}
+	return nil
+}
+
+// 用 GET 请求读取文件内容，不需要先下载到本地
+func (w *WebDAV) Open(p string) (io.ReadCloser, error) {
+	u, err := w.url(p)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.do(context.Background(), http.MethodGet, u, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, checkResponse(resp, nil)
+	}
+	return resp.Body, nil
+}
+
+// PROPFIND 的响应
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop   davProp `xml:"prop"`
+			Status string  `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// 状态为 200 的属性，其余是服务器不支持的属性
+func (ms multistatus) found(i int) davProp {
+	for _, ps := range ms.Responses[i].Propstat {
+		if strings.Contains(ps.Status, " 200 ") {
+			return ps.Prop
+		}
+	}
+	return davProp{}
+}
+
+type davProp struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"collection"`
+	} `xml:"resourcetype"`
+	Length    int64  `xml:"getcontentlength"`
+	Modified  string `xml:"getlastmodified"`
+	Available string `xml:"quota-available-bytes"`
+	Used      string `xml:"quota-used-bytes"`
+}
+
+const (
+	listProps  = `<?xml version="1.0"?><d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/></d:prop></d:propfind>`
+	quotaProps = `<?xml version="1.0"?><d:propfind xmlns:d="DAV:"><d:prop><d:quota-available-bytes/><d:quota-used-bytes/></d:prop></d:propfind>`
+)
+
+func (w *WebDAV) propfind(u *url.URL, depth, body string) (multistatus, error) {
+	var ms multistatus
+	resp, err := w.do(context.Background(), "PROPFIND", u, strings.NewReader(body), int64(len(body)),
+		map[string]string{"Depth": depth, "Content-Type": "application/xml"})
+	if err != nil {
+		return ms, err
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return ms, checkResponse(resp, nil, http.StatusMultiStatus)
+	}
+	defer resp.Body.Close()
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return ms, i18n.Errorf("解析 WebDAV 响应失败: %v", err)
+	}
+	return ms, nil
+}
+
+// 递归列出 dir 下的文件，Nextcloud 默认不允许 Depth: infinity，逐个目录查询
+func (w *WebDAV) List(dir string) (map[string]RemoteFile, error) {
+	files := make(map[string]RemoteFile)
+	root, err := w.url(dir)
+	if err != nil {
+		return nil, err
+	}
+	pending := []string{""}
+	for len(pending) > 0 {
+		rel := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		u := root
+		if rel != "" {
+			u = root.JoinPath(strings.Split(rel, "/")...)
+		}
+		ms, err := w.propfind(u, "1", listProps)
+		if rel == "" && errors.Is(err, fs.ErrNotExist) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		self := strings.TrimSuffix(u.Path, "/")
+		for i, r := range ms.Responses {
+			href, err := url.Parse(r.Href)
+			if err != nil {
+				continue
+			}
+			p := strings.TrimSuffix(href.Path, "/")
+			if p == self {
+				continue // 第一项是目录本身
+			}
+			child := path.Base(p)
+			if rel != "" {
+				child = rel + "/" + child
+			}
+			prop := ms.found(i)
+			if prop.ResourceType.Collection != nil {
+				pending = append(pending, child)
+				continue
+			}
+			modTime, _ := http.ParseTime(prop.Modified)
+			files[filepath.FromSlash(child)] = RemoteFile{Size: prop.Length, ModTime: modTime}
+		}
+	}
+	return files, nil
+}
+
+// 账户的配额，服务器没有设置配额时返回错误
+func (w *WebDAV) Usage() (total, free uint64, err error) {
+	if w.err != nil {
+		return 0, 0, w.err
+	}
+	ms, err := w.propfind(w.base, "0", quotaProps)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range ms.Responses {
+		prop := ms.found(i)
+		available, availErr := strconv.ParseInt(prop.Available, 10, 64)
+		used, usedErr := strconv.ParseInt(prop.Used, 10, 64)
+		// Nextcloud 用负数表示不限空间
+		if availErr == nil && usedErr == nil && available >= 0 && used >= 0 {
+			return uint64(used + available), uint64(available), nil
+		}
+	}
+	return 0, 0, i18n.Errorf("WebDAV 服务器没有提供配额信息")
+}
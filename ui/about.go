@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 关于对话框：版本、构建信息和当前任务的配置位置，诊断信息可以复制到问题反馈中
+func (b *BackupApp) showAboutDialog() {
+	diagnostics := b.config.Diagnostics()
+
+	title := widget.NewLabelWithStyle("SyncSafe "+diagnostics.Version.Version, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	details := widget.NewLabel(diagnostics.String())
+	details.Wrapping = fyne.TextWrapBreak
+
+	copyBtn := widget.NewButtonWithIcon(i18n.T("复制诊断信息"), theme.ContentCopyIcon(), func() {
+		b.window.Clipboard().SetContent(diagnostics.String())
+		b.updateStatus(i18n.T("诊断信息已复制到剪贴板"))
+	})
+
+	content := container.NewBorder(title, container.NewHBox(copyBtn), nil, nil, container.NewVScroll(details))
+	aboutDialog := dialog.NewCustom(i18n.T("关于"), i18n.T("关闭"), content, b.window)
+	aboutDialog.Resize(fyne.NewSize(620, 420))
+	aboutDialog.Show()
+}
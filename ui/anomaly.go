@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 通过系统通知、推送和对话框提醒快照大小骤变，在 loop 中调用
+func (j *job) alertSizeAnomaly(record history.Record) {
+	slog.Warn("快照大小异常", "source", record.SourcePath, "message", record.SizeAnomaly)
+	title := i18n.T("快照大小异常")
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(title, record.SizeAnomaly))
+	j.engine.Notify(notify.LevelWarning, title, fmt.Sprintf("%s\n%s", record.SourcePath, record.SizeAnomaly))
+
+	message := widget.NewLabel(record.SizeAnomaly + "\n\n" +
+		i18n.T("确认正常之前不会按保留策略清理旧快照。如果文件确实被误删，可以在「还原」页从之前的快照找回。"))
+	message.Wrapping = fyne.TextWrapWord
+	alert := dialog.NewCustomConfirm(i18n.Sprintf("%s: %s", j.config.ProfileName(), title), i18n.T("确认正常"), i18n.T("稍后处理"),
+		container.NewPadded(message), func(ok bool) {
+			if ok {
+				j.acknowledgeAnomaly(record)
+			}
+		}, j.app.window)
+	alert.Resize(fyne.NewSize(520, 0))
+	alert.Show()
+}
+
+// 确认快照大小的变化正常：之后的备份以它为准比较，并恢复按保留策略清理
+func (j *job) acknowledgeAnomaly(record history.Record) {
+	j.do(func() {
+		if !history.AcknowledgeAnomaly(j.config.History, record) {
+			return
+		}
+		if err := j.config.Save(); err != nil {
+			dialog.ShowError(err, j.app.window)
+			return
+		}
+		if j.current() {
+			j.app.refreshHistoryView()
+		}
+		j.status(i18n.T("已确认快照大小正常"))
+		j.applyRetention()
+	})
+}
@@ -0,0 +1,1163 @@
+package ui
+
+import (
+	"image/color"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/logging"
+	"syncsafe/peer"
+	"syncsafe/schedule"
+	"syncsafe/storage"
+	"syncsafe/watcher"
+)
+
+var customFolderIcon fyne.Resource
+
+// 加载自定义文件夹图标，在创建界面时调用，命令行模式不需要
+func loadFolderIcon() {
+	iconBytes, err := os.ReadFile("assets/folder.svg")
+	if err != nil {
+		slog.Warn("加载自定义文件夹图标失败", "err", err)
+		customFolderIcon = theme.FolderIcon()
+		return
+	}
+	customFolderIcon = &fyne.StaticResource{
+		StaticName:    "folder",
+		StaticContent: iconBytes,
+	}
+}
+
+type BackupApp struct {
+	*job                     // 当前显示的任务
+	jobs              []*job // 所有未归档的任务，顺序与 profiles.List 一致
+	profiles          *engine.Profiles
+	window            fyne.Window
+	statusBar         *widget.Label
+	sourceLabel       *widget.Label
+	destLabel         *widget.Label
+	theme             *CustomTheme
+	sourceFolder      *widget.Label
+	destFolder        *widget.Label
+	watchBtn          *widget.Button
+	gitEnabled        *widget.Check
+	historyList       *historyList
+	totalBackupText   *canvas.Text
+	successBackupText *canvas.Text
+	failedBackupText  *canvas.Text
+	successRateText   *canvas.Text
+	historySelect     *widget.Select
+	historyFilter     string           // 历史记录按源文件夹筛选，为空表示全部
+	historySearch     string           // 历史记录搜索关键字（小写）
+	historyRows       []history.Record // 历史列表中显示的记录，最新的在前，由后台分批填充
+	historyLoad       int              // 历史列表的加载次数，新的加载开始后之前的加载停止
+	historyMutex      sync.Mutex       // 保护 historyRows 和 historyLoad
+	historyLoading    *fyne.Container  // 历史列表加载中的提示
+	tabs              *container.AppTabs
+	scheduleLabel     *widget.Label // 状态栏中的下一次定时备份时间
+	output            *OutputPanel
+	sourceStats       *widget.Label
+	titleBadge        *fyne.Container
+	badge             *resultBadge
+	pauseBanner       *fyne.Container // 任务自动暂停时显示的提示条
+	pauseText         *canvas.Text
+	progressShown     time.Time // 上次刷新复制进度的时间
+	progressBar       *widget.ProgressBar
+	progressLabel     *widget.Label
+	progressBox       *fyne.Container
+	receiver          *peer.Receiver // 局域网接收端，未启用时为 nil
+	tray              *trayState     // 系统托盘，平台不支持时为 nil
+	pausedJobs        []*job         // 通过托盘或快捷操作暂停监控的任务，恢复时重新开始监控
+	pauseMutex        sync.Mutex
+}
+
+// 自定义主题
+type CustomTheme struct {
+	fyne.Theme
+	Mode string // 明暗模式，见 engine.ThemeLight 等，为空时跟随系统
+}
+
+func (t *CustomTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	switch t.Mode {
+	case engine.ThemeLight:
+		variant = theme.VariantLight
+	case engine.ThemeDark:
+		variant = theme.VariantDark
+	}
+	if name == theme.ColorNamePrimary {
+		return color.NRGBA{R: 44, G: 193, B: 219, A: 255} // #2CC1DB
+	}
+	if name == theme.ColorNameHover {
+		return color.NRGBA{R: 255, G: 107, B: 139, A: 255} // #FF6B8B
+	}
+	return t.Theme.Color(name, variant)
+}
+
+// 初始化 Git 仓库
+func (b *BackupApp) initGitRepo() error {
+	return b.engine.Git().Init()
+}
+
+// 显示 Git 配置对话框
+func (b *BackupApp) showGitConfigDialog() {
+	// 创建平台选择下拉框
+	platformSelect := widget.NewSelect([]string{"Gitee", "GitHub"}, func(platform string) {
+		b.config.Git.Platform = platform
+	})
+	platformSelect.SetSelected(b.config.Git.Platform)
+
+	// 创建用户名输入框
+	userNameEntry := widget.NewEntry()
+	userNameEntry.SetPlaceHolder(i18n.T("输入 Git 用户名"))
+	userNameEntry.SetText(b.config.Git.UserName)
+	userNameEntry.OnChanged = func(name string) {
+		b.config.Git.UserName = name
+	}
+
+	// 创建邮箱输入框
+	userEmailEntry := widget.NewEntry()
+	userEmailEntry.SetPlaceHolder(i18n.T("输入 Git 邮箱"))
+	userEmailEntry.SetText(b.config.Git.UserEmail)
+	userEmailEntry.OnChanged = func(email string) {
+		b.config.Git.UserEmail = email
+	}
+
+	// 创建仓库地址输入框
+	repoEntry := widget.NewEntry()
+	repoEntry.SetPlaceHolder(i18n.T("输入仓库 HTTPS 地址"))
+	repoEntry.SetText(b.config.Git.RepoURL)
+	repoEntry.OnChanged = func(url string) {
+		b.config.Git.RepoURL = url
+	}
+
+	// 创建访问令牌输入框
+	tokenEntry := widget.NewPasswordEntry()
+	tokenEntry.SetPlaceHolder(i18n.T("输入访问令牌 (Access Token)"))
+	tokenEntry.SetText(b.config.Git.AccessToken)
+	tokenEntry.OnChanged = func(token string) {
+		b.config.Git.AccessToken = token
+	}
+
+	// 创建启用 Git 备份复选框
+	gitEnabled := widget.NewCheck(i18n.T("启用 Git 备份"), func(enabled bool) {
+		b.config.Git.Enabled = enabled
+	})
+	gitEnabled.Checked = b.config.Git.Enabled
+
+	// 创建提交计划输入框
+	gitScheduleEntry := widget.NewEntry()
+	gitScheduleEntry.SetPlaceHolder(i18n.T("例如 @hourly 或 0 18 * * *，为空表示每次备份都提交"))
+	gitScheduleEntry.SetText(b.config.Git.Schedule)
+
+	// 创建表单布局
+	form := &widget.Form{
+		Items: []*widget.FormItem{
+			{
+				Text:     i18n.T("Git 平台"),
+				Widget:   platformSelect,
+				HintText: i18n.T("选择 Git 托管平台"),
+			},
+			{
+				Text:     i18n.T("用户名"),
+				Widget:   userNameEntry,
+				HintText: i18n.T("您的 Git 用户名"),
+			},
+			{
+				Text:     i18n.T("邮箱"),
+				Widget:   userEmailEntry,
+				HintText: i18n.T("您的 Git 邮箱地址"),
+			},
+			{
+				Text:     i18n.T("仓库地址"),
+				Widget:   repoEntry,
+				HintText: i18n.T("仓库的 HTTPS 克隆地址"),
+			},
+			{
+				Text:     i18n.T("访问令牌"),
+				Widget:   tokenEntry,
+				HintText: i18n.T("用于身份验证的访问令牌"),
+			},
+			{
+				Text:     i18n.T("提交计划"),
+				Widget:   gitScheduleEntry,
+				HintText: i18n.T("本地备份照常在每次变化后进行，Git 按该 cron 表达式把期间的所有变化合并为一次提交并推送"),
+			},
+		},
+	}
+
+	// 创建帮助信息
+	helpText := widget.NewRichTextFromMarkdown(i18n.T(`
+### Git 配置说明
+
+#### 1. 平台选择
+- 支持 Gitee 和 GitHub
+- 请选择您已注册的平台
+
+#### 2. 基本信息
+- **用户名**: Git 提交时显示的作者名
+- **邮箱**: Git 提交关联的邮箱地址
+
+#### 3. 仓库配置
+- **仓库地址**: 使用 HTTPS 格式
+  - Gitee 格式: https://gitee.com/用户名/仓库名.git
+  - GitHub 格式: https://github.com/用户名/仓库名.git
+
+#### 4. 访问令牌
+- **Gitee**: 在 设置 -> 私人令牌 中生成
+- **GitHub**: 在 Settings -> Developer settings -> Personal access tokens 中生成
+- 确保令牌具有仓库的读写权限
+`))
+
+	// 创建标题
+	title := container.NewHBox(
+		widget.NewIcon(theme.SettingsIcon()),
+		widget.NewLabelWithStyle(i18n.T("Git 备份配置"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+	)
+
+	// 创建主内容
+	content := container.NewVBox(
+		title,
+		widget.NewSeparator(),
+		container.NewPadded(form),
+		container.NewPadded(gitEnabled),
+		widget.NewSeparator(),
+		container.NewPadded(helpText),
+	)
+
+	// 包装在滚动容器中
+	scrollContent := container.NewVScroll(content)
+	scrollContent.SetMinSize(fyne.NewSize(500, 400))
+
+	// 创建自定义对话框
+	dialog.ShowCustomConfirm(i18n.T("Git 配置"), i18n.T("确定"), i18n.T("取消"), scrollContent,
+		func(submit bool) {
+			if !submit {
+				return
+			}
+
+			gitSchedule := strings.TrimSpace(gitScheduleEntry.Text)
+			if gitSchedule != "" {
+				if _, err := schedule.Parse(gitSchedule); err != nil {
+					dialog.ShowError(i18n.Errorf("提交计划无效: %v", err), b.window)
+					return
+				}
+			}
+			b.config.Git.Schedule = gitSchedule
+
+			// 验证必填字段
+			if b.config.Git.Enabled {
+				if b.config.Git.Platform == "" {
+					dialog.ShowError(i18n.Errorf("请选择 Git 平台"), b.window)
+					return
+				}
+				if b.config.Git.UserName == "" {
+					dialog.ShowError(i18n.Errorf("请输入 Git 用户名"), b.window)
+					return
+				}
+				if b.config.Git.UserEmail == "" {
+					dialog.ShowError(i18n.Errorf("请输入 Git 邮箱"), b.window)
+					return
+				}
+				if b.config.Git.RepoURL == "" {
+					dialog.ShowError(i18n.Errorf("请输入仓库地址"), b.window)
+					return
+				}
+				if b.config.Git.AccessToken == "" {
+					dialog.ShowError(i18n.Errorf("请输入访问令牌"), b.window)
+					return
+				}
+
+				// 保存配置
+				if err := b.saveConfig(); err != nil {
+					dialog.ShowError(i18n.Errorf("保存配置失败: %v", err), b.window)
+					return
+				}
+
+				// 初始化 Git 仓库
+				if err := b.initGitRepo(); err != nil {
+					dialog.ShowError(i18n.Errorf("Git 仓库初始化失败: %v", err), b.window)
+					return
+				}
+
+				b.updateStatus(i18n.T("Git 配置已更新"))
+			}
+			b.startGitSchedule()
+		}, b.window)
+}
+
+// 保存当前任务的配置到文件
+func (b *BackupApp) saveConfig() error {
+	return b.config.Save()
+}
+
+// 从文件加载所有任务，显示上次选择的任务。
+// 默认任务的配置损坏时使用默认配置并返回错误
+func (b *BackupApp) loadConfig() error {
+	// 重新加载前停止所有任务的监控、定时备份和重试
+	for _, j := range b.jobs {
+		if j.watcher != nil {
+			j.stopWatching()
+		}
+		j.stopSchedule()
+		j.stopScrubSchedule()
+		j.stopGitSchedule()
+		j.close()
+		b.trayRemoveJob(j)
+	}
+
+	profiles, err := engine.LoadProfiles()
+	b.profiles = profiles
+	b.jobs = nil
+	for _, config := range profiles.List {
+		// 已归档的任务不监控也不定时备份，恢复时再创建
+		if config.Archived {
+			continue
+		}
+		j := b.newJob(config)
+		b.jobs = append(b.jobs, j)
+		if config == profiles.Current() {
+			b.job = j
+		}
+	}
+	for _, j := range b.jobs {
+		j.startSchedule()
+		j.startScrubSchedule()
+		j.startGitSchedule()
+		go j.checkScheduledTask()
+	}
+	return err
+}
+
+func newBackupApp() *BackupApp {
+	app := &BackupApp{
+		statusBar:   widget.NewLabelWithStyle(i18n.T("准备就绪"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		sourceLabel: widget.NewLabel(i18n.T("未选择源文件夹")),
+		destLabel:   widget.NewLabel(i18n.T("未选择目标文件夹")),
+		theme:       &CustomTheme{Theme: theme.DefaultTheme()},
+	}
+	config := engine.NewConfig()
+	app.profiles = &engine.Profiles{List: []*engine.Config{config}, Active: config.ProfileName()}
+	app.job = app.newJob(config)
+	app.jobs = []*job{app.job}
+	return app
+}
+
+func (b *BackupApp) createUI() {
+	// 设置窗口标题和图标
+	b.window.SetTitle(b.windowTitle())
+	b.window.Resize(fyne.NewSize(500, 400))
+
+	// 创建标题容器
+	b.titleBadge = newProfileBadge(b.config.Icon, b.config.Color)
+	titleContainer := container.NewVBox(
+		container.NewHBox(
+			layout.NewSpacer(),
+			b.titleBadge,
+			widget.NewLabelWithStyle("SyncSafe", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			layout.NewSpacer(),
+		),
+		container.NewHBox(
+			layout.NewSpacer(),
+			widget.NewLabelWithStyle(i18n.T("文件备份工具"), fyne.TextAlignCenter, fyne.TextStyle{}),
+			layout.NewSpacer(),
+		),
+	)
+
+	// 初始化标签
+	b.sourceFolder = widget.NewLabel(i18n.T("未选择源文件夹"))
+	b.destFolder = widget.NewLabel(i18n.T("未选择目标文件夹"))
+	b.sourceStats = widget.NewLabel("")
+	if b.config.SourcePath != "" {
+		b.sourceFolder.SetText(b.sourceDisplay())
+		go b.refreshSourceStats()
+	}
+	if b.config.DestinationPath != "" {
+		b.destFolder.SetText(b.config.DestinationPath)
+	}
+
+	// 创建源文件夹选择按钮和显示
+	sourceBtn := widget.NewButtonWithIcon(i18n.T("选择源文件夹"), customFolderIcon, b.chooseSourceFolder)
+	sourceBtn.Importance = widget.HighImportance
+
+	// 创建目标文件夹选择按钮和显示
+	destBtn := widget.NewButtonWithIcon(i18n.T("选择备份文件夹"), customFolderIcon, b.chooseDestinationFolder)
+	destBtn.Importance = widget.HighImportance
+
+	// 创建监控按钮
+	b.watchBtn = widget.NewButton(i18n.T("开始监控"), func() {
+		if !b.config.IsWatching {
+			if err := b.startWatching(); err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			b.setWatchButton(true)
+		} else {
+			b.stopWatching()
+			b.setWatchButton(false)
+		}
+	})
+	b.watchBtn.Icon = theme.MediaPlayIcon()
+
+	// 创建备份按钮
+	backupBtn := widget.NewButtonWithIcon(i18n.T("立即备份"), theme.MailSendIcon(), func() {
+		go b.performBackup()
+	})
+	backupBtn.Importance = widget.HighImportance
+
+	// 创建性能测试按钮
+	benchmarkBtn := widget.NewButtonWithIcon(i18n.T("性能测试"), theme.ComputerIcon(), func() {
+		b.showBenchmark()
+	})
+
+	// 添加 Git 备份选项
+	b.gitEnabled = widget.NewCheck(i18n.T("启用 Git 备份"), func(value bool) {
+		b.config.Git.Enabled = value
+	})
+	b.gitEnabled.Checked = b.config.Git.Enabled
+
+	// 添加提权读取选项
+	elevatedCheck := widget.NewCheck(i18n.T("提权读取受保护文件"), func(value bool) {
+		b.config.ElevatedRead = value
+	})
+	elevatedCheck.Checked = b.config.ElevatedRead
+
+	// 从源文件夹所在文件系统的快照中读取
+	snapshotCheck := widget.NewCheck(i18n.T("从文件系统快照读取"), func(value bool) {
+		b.config.SourceSnapshot = value
+	})
+	snapshotCheck.Checked = b.config.SourceSnapshot
+
+	// 关机或睡眠前的操作
+	powerOptions := make([]string, len(powerActionOrder))
+	for i, action := range powerActionOrder {
+		powerOptions[i] = i18n.T(powerActionLabels[action])
+	}
+	powerSelect := widget.NewSelect(powerOptions, func(selected string) {
+		for action, label := range powerActionLabels {
+			if i18n.T(label) == selected {
+				b.config.PowerAction = action
+			}
+		}
+	})
+	powerSelect.SetSelected(i18n.T(powerActionLabels[b.config.PowerAction]))
+
+	// 添加模拟模式选项
+	dryRunCheck := widget.NewCheck(i18n.T("模拟模式"), func(value bool) {
+		b.config.DryRun = value
+		b.window.SetTitle(b.windowTitle())
+		if value {
+			b.updateStatus(i18n.T("模拟模式：写入操作只记录到命令输出，不会实际执行"))
+		} else {
+			b.updateStatus(i18n.T("已退出模拟模式"))
+		}
+	})
+	dryRunCheck.Checked = b.config.DryRun
+
+	// 没有变化时跳过备份
+	skipUnchangedCheck := widget.NewCheck(i18n.T("无变化时跳过"), func(value bool) {
+		b.config.SkipUnchanged = value
+	})
+	skipUnchangedCheck.Checked = b.config.SkipUnchanged
+
+	// 增量快照选项
+	incrementalCheck := widget.NewCheck(i18n.T("增量快照（硬链接）"), func(value bool) {
+		b.config.Incremental = value
+	})
+	incrementalCheck.Checked = b.config.Incremental
+
+	// 按内容判断文件是否变化
+	checksumCheck := widget.NewCheck(i18n.T("按内容比较"), func(value bool) {
+		b.config.ChecksumCompare = value
+	})
+	checksumCheck.Checked = b.config.ChecksumCompare
+
+	// 备份完成后校验
+	verifyCheck := widget.NewCheck(i18n.T("备份后校验"), func(value bool) {
+		b.config.VerifyAfterBackup = value
+	})
+	verifyCheck.Checked = b.config.VerifyAfterBackup
+
+	// 备份后在目标文件夹生成可以用浏览器打开的快照索引
+	shareIndexCheck := widget.NewCheck(i18n.T("生成共享索引"), func(value bool) {
+		b.config.ShareIndex = value
+	})
+	shareIndexCheck.Checked = b.config.ShareIndex
+
+	// 监控触发备份前提示变化的文件
+	confirmWatchCheck := widget.NewCheck(i18n.T("备份前提示变化"), func(value bool) {
+		b.config.ConfirmWatchBackup = value
+	})
+	confirmWatchCheck.Checked = b.config.ConfirmWatchBackup
+
+	// 快速同步选项
+	quickSyncCheck := widget.NewCheck(i18n.T("快速同步（镜像）"), func(value bool) {
+		b.config.QuickSync = value
+	})
+	quickSyncCheck.Checked = b.config.QuickSync
+
+	// 镜像中删除的文件移到回收文件夹
+	mirrorTrashCheck := widget.NewCheck(i18n.T("镜像删除移到回收文件夹"), func(value bool) {
+		b.config.MirrorTrash = value
+	})
+	mirrorTrashCheck.Checked = b.config.MirrorTrash
+
+	// 同一目标文件夹下的任务共用去重存储池
+	sharedPoolCheck := widget.NewCheck(i18n.T("去重存储池"), func(value bool) {
+		b.config.SharedPool = value
+	})
+	sharedPoolCheck.Checked = b.config.SharedPool
+
+	// 备份 NTFS 备用数据流和 macOS 扩展属性
+	streamsCheck := widget.NewCheck(i18n.T("备份附加数据流"), func(value bool) {
+		b.config.CaptureStreams = value
+	})
+	streamsCheck.Checked = b.config.CaptureStreams
+
+	// 快照格式：目录或单个归档文件
+	archiveOptions := []string{i18n.T("目录"), storage.ArchiveTarGz, storage.ArchiveZip}
+	archiveSelect := widget.NewSelect(archiveOptions, func(selected string) {
+		if selected == archiveOptions[0] {
+			selected = ""
+		}
+		b.config.ArchiveFormat = selected
+	})
+	archiveSelect.Selected = archiveOptions[0]
+	if b.config.ArchiveFormat != "" {
+		archiveSelect.Selected = b.config.ArchiveFormat
+	}
+
+	// 并发复制数，自动表示使用程序设置中的默认值
+	workerOptions := []string{i18n.T("自动"), "1", "2", "4", "8", "16"}
+	workerSelect := widget.NewSelect(workerOptions, func(selected string) {
+		b.config.CopyWorkers, _ = strconv.Atoi(selected)
+	})
+	workerSelect.PlaceHolder = i18n.T("自动")
+	if b.config.CopyWorkers > 0 {
+		workerSelect.Selected = strconv.Itoa(b.config.CopyWorkers)
+	} else {
+		workerSelect.Selected = i18n.T("自动")
+	}
+
+	// 创建失败重试设置按钮
+	retryBtn := widget.NewButtonWithIcon(i18n.T("失败重试"), theme.ViewRefreshIcon(), func() {
+		b.showRetryDialog()
+	})
+
+	// 创建容量提醒设置按钮
+	capacityBtn := widget.NewButtonWithIcon(i18n.T("容量提醒"), theme.StorageIcon(), func() {
+		b.showCapacityDialog()
+	})
+
+	// 创建保留策略按钮
+	retentionBtn := widget.NewButtonWithIcon(i18n.T("保留策略"), theme.DeleteIcon(), func() {
+		b.showRetentionDialog()
+	})
+
+	// 创建备份范围设置按钮
+	filterBtn := widget.NewButtonWithIcon(i18n.T("备份范围"), theme.VisibilityOffIcon(), func() {
+		b.showFileFilterDialog()
+	})
+
+	// 创建哈希设置按钮
+	hashBtn := widget.NewButtonWithIcon(i18n.T("哈希设置"), theme.SearchIcon(), func() {
+		b.showHashDialog()
+	})
+
+	// 创建手机推送设置按钮
+	notifyBtn := widget.NewButtonWithIcon(i18n.T("手机推送"), theme.MailSendIcon(), func() {
+		b.showNotifyDialog()
+	})
+
+	// 创建邮件通知设置按钮
+	emailBtn := widget.NewButtonWithIcon(i18n.T("邮件通知"), theme.MailComposeIcon(), func() {
+		b.showEmailDialog()
+	})
+
+	// 创建 Webhook 设置按钮
+	webhookBtn := widget.NewButtonWithIcon("Webhook", theme.UploadIcon(), func() {
+		b.showWebhookDialog()
+	})
+
+	// 创建定时备份按钮
+	scheduleBtn := widget.NewButtonWithIcon(i18n.T("定时备份"), theme.HistoryIcon(), func() {
+		b.showScheduleDialog()
+	})
+
+	// 创建局域网同步按钮
+	peerBtn := widget.NewButtonWithIcon(i18n.T("局域网同步"), theme.ComputerIcon(), func() {
+		b.showPeerDialog()
+	})
+
+	// 创建同步工具导出按钮
+	interopBtn := widget.NewButtonWithIcon(i18n.T("同步工具导出"), theme.UploadIcon(), func() {
+		b.showInteropDialog()
+	})
+
+	// 创建双向同步按钮
+	twoWayBtn := widget.NewButtonWithIcon(i18n.T("双向同步"), theme.ViewRefreshIcon(), func() {
+		b.showTwoWayDialog()
+	})
+
+	// 创建加密设置按钮
+	encryptionBtn := widget.NewButtonWithIcon(i18n.T("加密"), theme.VisibilityOffIcon(), func() {
+		b.showEncryptionDialog()
+	})
+
+	// 创建任务计划程序按钮，只在 Windows 上显示
+	taskBtn := widget.NewButtonWithIcon(i18n.T("任务计划程序"), theme.HistoryIcon(), func() {
+		b.showTaskDialog()
+	})
+	if !engine.TaskSchedulerSupported {
+		taskBtn.Hide()
+	}
+
+	// 创建暂停时段按钮
+	blackoutBtn := widget.NewButtonWithIcon(i18n.T("暂停时段"), theme.MediaPauseIcon(), func() {
+		b.showBlackoutDialog()
+	})
+
+	// 创建配置回滚按钮
+	rollbackBtn := widget.NewButtonWithIcon(i18n.T("配置回滚"), theme.MediaReplayIcon(), func() {
+		b.showRollbackDialog()
+	})
+
+	// 创建外观设置按钮
+	appearanceBtn := widget.NewButtonWithIcon(i18n.T("外观"), theme.ColorPaletteIcon(), func() {
+		b.showAppearanceDialog()
+	})
+
+	// 创建程序设置按钮
+	settingsBtn := widget.NewButtonWithIcon(i18n.T("设置"), theme.SettingsIcon(), func() {
+		b.showSettingsDialog()
+	})
+
+	// 创建配置检查按钮
+	checkBtn := widget.NewButtonWithIcon(i18n.T("配置检查"), theme.WarningIcon(), func() {
+		b.showConfigProblems(b.jobs, true)
+	})
+
+	// 创建关于按钮
+	aboutBtn := widget.NewButtonWithIcon(i18n.T("关于"), theme.InfoIcon(), func() {
+		b.showAboutDialog()
+	})
+
+	// 创建 Git 配置按钮
+	gitConfigBtn := widget.NewButton(i18n.T("Git 配置"), func() {
+		b.showGitConfigDialog()
+	})
+	gitConfigBtn.Icon = theme.SettingsIcon()
+
+	// 创建 Git 修复按钮
+	gitRepairBtn := widget.NewButtonWithIcon(i18n.T("修复 Git 仓库"), theme.WarningIcon(), func() {
+		b.showGitRepairDialog()
+	})
+
+	// 创建文件夹信息区域
+	folderInfo := container.NewVBox(
+		container.NewHBox(
+			widget.NewIcon(customFolderIcon),
+			widget.NewLabel(i18n.T("源文件夹:")),
+			layout.NewSpacer(),
+			widget.NewButtonWithIcon(i18n.T("单独的文件"), theme.FileIcon(), func() {
+				b.showSourceFilesDialog()
+			}),
+			widget.NewButtonWithIcon(i18n.T("路径模板"), theme.DocumentCreateIcon(), func() {
+				b.showSourceTemplateDialog()
+			}),
+		),
+		container.NewPadded(
+			container.NewVBox(b.sourceFolder, b.sourceStats),
+		),
+		layout.NewSpacer(),
+		container.NewHBox(
+			widget.NewIcon(customFolderIcon),
+			widget.NewLabel(i18n.T("目标文件夹:")),
+			layout.NewSpacer(),
+			widget.NewButtonWithIcon("WebDAV", theme.StorageIcon(), func() {
+				b.showWebDAVDialog()
+			}),
+			widget.NewButtonWithIcon("S3", theme.StorageIcon(), func() {
+				b.showS3Dialog()
+			}),
+			widget.NewButtonWithIcon(i18n.T("附加目标"), theme.ContentAddIcon(), func() {
+				b.showReplicaDialog()
+			}),
+		),
+		container.NewPadded(
+			b.destFolder,
+		),
+	)
+
+	// 创建按钮组
+	buttonGroup := container.NewVBox(
+		container.NewGridWithColumns(2,
+			container.NewPadded(sourceBtn),
+			container.NewPadded(destBtn),
+		),
+		container.NewHBox(
+			container.NewHBox(b.gitEnabled, gitConfigBtn, gitRepairBtn, elevatedCheck, snapshotCheck),
+			layout.NewSpacer(),
+			benchmarkBtn,
+			b.watchBtn,
+			backupBtn,
+		),
+		container.NewHBox(
+			widget.NewIcon(theme.LogoutIcon()),
+			widget.NewLabel(i18n.T("关机或睡眠前:")),
+			powerSelect,
+			dryRunCheck,
+			skipUnchangedCheck,
+			incrementalCheck,
+			checksumCheck,
+			verifyCheck,
+			shareIndexCheck,
+			quickSyncCheck,
+			mirrorTrashCheck,
+			sharedPoolCheck,
+			streamsCheck,
+			confirmWatchCheck,
+			widget.NewLabel(i18n.T("快照格式:")),
+			archiveSelect,
+			widget.NewLabel(i18n.T("并发复制:")),
+			workerSelect,
+			layout.NewSpacer(),
+			b.createInboxButton(),
+			retryBtn,
+			filterBtn,
+			hashBtn,
+			notifyBtn,
+			emailBtn,
+			webhookBtn,
+			peerBtn,
+			interopBtn,
+			twoWayBtn,
+			encryptionBtn,
+			capacityBtn,
+			retentionBtn,
+			scheduleBtn,
+			taskBtn,
+			blackoutBtn,
+			rollbackBtn,
+			appearanceBtn,
+			settingsBtn,
+			checkBtn,
+			aboutBtn,
+		),
+	)
+
+	// 创建状态栏
+	b.scheduleLabel = widget.NewLabel("")
+	b.refreshScheduleLabel()
+	statusBar := container.NewHBox(
+		widget.NewIcon(theme.InfoIcon()),
+		b.statusBar,
+		layout.NewSpacer(),
+		b.scheduleLabel,
+	)
+
+	// 创建主要标签页
+	mainContainer := container.NewVBox(
+		container.NewPadded(titleContainer),
+		container.NewPadded(b.createProfileBar()),
+		b.createPauseBanner(),
+		widget.NewSeparator(),
+		buttonGroup,
+		widget.NewSeparator(),
+		container.NewPadded(
+			container.NewVBox(
+				container.NewHBox(
+					widget.NewIcon(theme.FolderIcon()),
+					widget.NewLabelWithStyle(i18n.T("文件夹信息"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				),
+				folderInfo,
+			),
+		),
+		widget.NewSeparator(),
+		container.NewPadded(b.createResultBadge()),
+		widget.NewSeparator(),
+		container.NewPadded(
+			container.NewVBox(
+				container.NewHBox(
+					widget.NewIcon(theme.InfoIcon()),
+					widget.NewLabelWithStyle(i18n.T("状态信息"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				),
+				statusBar,
+				b.createProgressPanel(),
+			),
+		),
+		b.createOutputPanel(),
+	)
+
+	// 创建历史记录标签页
+	historyContainer := b.createHistoryTab()
+
+	// 使用统计在切换到该页时刷新
+	insightsContainer, refreshInsights := b.createInsightsTab()
+	insightsTab := container.NewTabItem(i18n.T("使用统计"), insightsContainer)
+
+	// 日志在切换到该页时重新读取
+	logsContainer, refreshLogs := b.createLogsTab()
+	logsTab := container.NewTabItem(i18n.T("日志"), logsContainer)
+
+	// 创建标签页容器
+	b.tabs = container.NewAppTabs(
+		container.NewTabItem(i18n.T("备份"), mainContainer),
+		container.NewTabItem(i18n.T("历史记录"), historyContainer),
+		container.NewTabItem(i18n.T("还原"), b.createRestoreTab()),
+		insightsTab,
+		logsTab,
+	)
+	b.tabs.OnSelected = func(tab *container.TabItem) {
+		switch tab {
+		case insightsTab:
+			refreshInsights()
+		case logsTab:
+			refreshLogs()
+		}
+	}
+
+	// 设置主窗口内容
+	b.window.SetContent(b.tabs)
+}
+
+func (b *BackupApp) updateStatus(message string) {
+	b.statusBar.SetText(message)
+}
+
+// 设置源文件夹并刷新界面显示
+func (j *job) setSourcePath(path string) {
+	// 单独的文件是相对原来的源文件夹的路径
+	if path != j.config.SourcePath {
+		j.config.SourceFiles = nil
+	}
+	j.config.SourcePath = path
+	j.status(i18n.T("已选择源文件夹: ") + path)
+	if j.current() {
+		j.app.sourceLabel.SetText(path)
+		j.app.sourceFolder.SetText(j.app.sourceDisplay())
+		j.app.refreshResultBadge()
+		go j.app.refreshSourceStats()
+	}
+	j.healthKnown = nil
+	go j.runHealthCheck()
+}
+
+// 任务在界面中显示时更新监控按钮
+func (j *job) setWatchButton(watching bool) {
+	if j.current() {
+		j.app.setWatchButton(watching)
+	}
+	j.app.refreshTray()
+}
+
+// 根据监控状态更新监控按钮
+func (b *BackupApp) setWatchButton(watching bool) {
+	if b.watchBtn == nil {
+		return
+	}
+	if watching {
+		b.watchBtn.SetText(i18n.T("停止监控"))
+		b.watchBtn.SetIcon(theme.MediaStopIcon())
+	} else {
+		b.watchBtn.SetText(i18n.T("开始监控"))
+		b.watchBtn.SetIcon(theme.MediaPlayIcon())
+	}
+}
+
+func (j *job) startWatching() error {
+	if j.config.SourcePath == "" {
+		return i18n.Errorf("请先选择源文件夹")
+	}
+	source := j.engine.SourcePath()
+
+	if j.config.DestinationPath == "" {
+		return i18n.Errorf("请先选择目标文件夹")
+	}
+
+	// 监控事件实时更新索引
+	idx, idxErr := j.engine.SourceIndex()
+	if idxErr != nil {
+		idx = nil
+	}
+
+	root := filepath.Clean(source)
+	// 记录变化的路径，之后的备份只处理变化的文件
+	j.engine.TrackChanges(root)
+	var w *watcher.Watcher
+	w, err := watcher.New(root, watcher.Options{
+		Debounce:      j.app.watchDebounce(),
+		CheckInterval: sourceCheckInterval,
+		Ignore:        j.config.WatchIgnore(root),
+		OnChange: func(path string) {
+			if idx != nil {
+				idx.Update(path)
+			}
+			j.engine.ChangeObserved(path)
+			j.changes.add(path)
+		},
+		OnSettled: func() {
+			// 暂停时段内推迟到时段结束
+			if j.deferForBlackout() {
+				return
+			}
+			j.settled()
+		},
+		OnStorm: func() {
+			j.changes.markStorm()
+			j.engine.ChangesOverflowed()
+			// 风暴期间索引不再逐个更新，手动备份改为遍历文件树
+			if idx != nil {
+				idx.SetLive(false)
+			}
+			j.status(i18n.T("检测到大量文件变化，暂停逐个处理，变化平静后重新扫描"))
+		},
+		OnRescan: func() {
+			if idx != nil {
+				if err := idx.Rebuild(); err != nil {
+					slog.Warn("重建索引失败", "err", err)
+					return
+				}
+				idx.SetLive(true)
+			}
+			j.status(i18n.T("文件变化已平静，重新扫描完成"))
+		},
+		OnLost: func() {
+			j.handleSourceLost(w, root)
+		},
+		OnCheck: func() bool {
+			// 路径模板展开为新的目录（例如进入新的月份）时切换监控目录
+			if filepath.Clean(j.engine.SourcePath()) == root {
+				return true
+			}
+			j.stopWatching()
+			if err := j.startWatching(); err != nil {
+				j.status(i18n.T("切换监控目录失败: ") + err.Error())
+				j.setWatchButton(false)
+				return false
+			}
+			if j.current() {
+				j.app.sourceFolder.SetText(j.app.sourceDisplay())
+			}
+			return false
+		},
+	})
+	if err != nil {
+		j.engine.StopTrackingChanges()
+		return err
+	}
+
+	j.watcher = w
+	j.config.IsWatching = true
+
+	// 监控开始时重建索引，之后由监控事件实时维护
+	if idx != nil {
+		if err := idx.Rebuild(); err != nil {
+			slog.Warn("重建索引失败", "err", err)
+		} else {
+			idx.SetLive(true)
+		}
+	}
+
+	j.status(i18n.T("开始监控文件变化"))
+	return nil
+}
+
+func (j *job) stopWatching() {
+	if j.watcher != nil {
+		j.watcher.Close()
+		j.watcher = nil
+	}
+	j.dismissPrompt()
+	j.changes.take()
+	j.engine.StopTrackingChanges()
+	if idx := j.engine.LoadedIndex(); idx != nil {
+		idx.SetLive(false)
+		idx.MarkJournal()
+		if err := idx.Save(); err != nil {
+			slog.Warn("保存索引失败", "err", err)
+		}
+	}
+	j.config.IsWatching = false
+	j.status(i18n.T("停止监控"))
+}
+
+// 选择当前任务的源文件夹
+func (b *BackupApp) chooseSourceFolder() {
+	b.showFolderDialog(i18n.T("选择源文件夹"), func(path string) {
+		if path == "" {
+			return
+		}
+		b.setSourcePath(path)
+	})
+}
+
+// 选择当前任务的备份文件夹
+func (b *BackupApp) chooseDestinationFolder() {
+	b.showFolderDialog(i18n.T("选择备份文件夹"), func(path string) {
+		if path == "" {
+			return
+		}
+		b.config.DestinationPath = path
+		b.destLabel.SetText(path)
+		b.updateStatus(i18n.T("已选择备份文件夹: ") + path)
+		b.destFolder.SetText(path)
+	})
+}
+
+func (b *BackupApp) showFolderDialog(title string, callback func(string)) {
+	// 创建一个新窗口作为对话框
+	customDialog := dialog.NewCustom(title, i18n.T("取消"),
+		container.NewVBox(
+			widget.NewLabel(i18n.T("请选择文件夹:")),
+			container.NewHBox(
+				widget.NewIcon(customFolderIcon),
+				widget.NewLabel(i18n.T("点击\"选择\"按钮浏览文件夹")),
+			),
+		),
+		b.window,
+	)
+
+	// 添加确认按钮
+	confirmBtn := widget.NewButton(i18n.T("选择"), nil)
+	customDialog.SetButtons([]fyne.CanvasObject{confirmBtn})
+
+	// 设置确认按钮动作
+	confirmBtn.OnTapped = func() {
+		// 使用标准的文件夹选择对话框
+		dialog.ShowFolderOpen(func(lu fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			if lu == nil {
+				return
+			}
+			callback(lu.Path())
+			customDialog.Hide()
+		}, b.window)
+	}
+
+	// 显示对话框
+	customDialog.Show()
+}
+
+// 关闭窗口：先取消所有进行中的备份并等待其结束，避免留下未完成的快照
+func (b *BackupApp) quit() {
+	cancelled := false
+	for _, j := range b.jobs {
+		if j.cancelBackups() {
+			cancelled = true
+		}
+	}
+	if !cancelled {
+		b.window.Close()
+		return
+	}
+	b.updateStatus(i18n.T("正在取消备份，完成后退出..."))
+	go func() {
+		done := make(chan struct{})
+		go func() {
+			for _, j := range b.jobs {
+				j.wait()
+			}
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(quitTimeout):
+			slog.Warn("等待备份取消超时，强制退出")
+		}
+		b.window.Close()
+	}()
+}
+
+// 退出时等待备份取消的最长时间
+const quitTimeout = 15 * time.Second
+
+// 创建主窗口并运行，直到窗口关闭
+func Run() {
+	run("", false)
+}
+
+// 登录系统时自动启动，设置了最小化启动时只显示托盘图标
+func RunAtLogin() {
+	run("", true)
+}
+
+// 启动程序，action 不为空时在界面创建后执行该快捷操作，atLogin 表示由开机自动启动
+func run(action string, atLogin bool) {
+	loadFolderIcon()
+	myApp := app.New()
+	myApp.SetIcon(theme.StorageIcon())
+	preferences := loadPreferences()
+
+	window := myApp.NewWindow(i18n.T("SyncSafe 文件备份工具"))
+	window.Resize(fyne.NewSize(500, 400))
+	window.SetIcon(theme.StorageIcon())
+
+	backupApp := newBackupApp()
+	backupApp.window = window
+
+	if err := logging.Setup(engine.LogDir()); err != nil {
+		slog.Warn("无法写入日志文件", "err", err)
+	}
+
+	// 先加载配置再创建界面，界面显示的是已保存的设置
+	loadErr := backupApp.loadConfig()
+	backupApp.createUI()
+	backupApp.applyAppearance()
+	backupApp.registerInboxShortcuts()
+	backupApp.registerSearchShortcut()
+	if loadErr != nil {
+		backupApp.handleConfigLoadError(loadErr)
+	} else {
+		backupApp.checkConfigs()
+	}
+
+	backupApp.startPowerMonitor()
+	backupApp.startHealthChecks()
+	backupApp.startReceiver()
+	backupApp.setupTray()
+	backupApp.startQuickActions()
+	engine.StartWebhookRetries()
+
+	// 上次退出时正在监控的任务恢复监控
+	for _, j := range backupApp.jobs {
+		if !j.config.IsWatching {
+			continue
+		}
+		j.config.IsWatching = false
+		if err := j.startWatching(); err != nil {
+			j.status(i18n.T("恢复监控失败: ") + err.Error())
+		} else {
+			j.setWatchButton(true)
+		}
+	}
+	if action != "" {
+		backupApp.runQuickAction(action)
+	}
+
+	// 没有托盘时无法打开隐藏的窗口，仍然显示。手动打开程序时总是显示窗口
+	if atLogin && preferences.StartMinimized && backupApp.tray != nil {
+		myApp.Run()
+		return
+	}
+	window.ShowAndRun()
+}
@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/faults"
+	"syncsafe/i18n"
+)
+
+// 可选的配置图标，按显示顺序排列
+var profileIconNames = []string{"存储", "文件夹", "文档", "电脑", "主页", "图片", "音乐", "视频", "邮件", "账户"}
+
+// 可选的配置颜色，按显示顺序排列
+var profileColorNames = []string{"蓝色", "绿色", "橙色", "红色", "紫色", "青色", "灰色"}
+
+var profileColors = map[string]color.NRGBA{
+	"蓝色": {R: 33, G: 150, B: 243, A: 255},
+	"绿色": {R: 67, G: 160, B: 71, A: 255},
+	"橙色": {R: 245, G: 124, B: 0, A: 255},
+	"红色": {R: 229, G: 57, B: 53, A: 255},
+	"紫色": {R: 142, G: 36, B: 170, A: 255},
+	"青色": {R: 0, G: 172, B: 193, A: 255},
+	"灰色": {R: 117, G: 117, B: 117, A: 255},
+}
+
+// 图标名称对应的资源，未设置时使用存储图标
+func profileIcon(name string) fyne.Resource {
+	switch name {
+	case "文件夹":
+		return theme.FolderIcon()
+	case "文档":
+		return theme.DocumentIcon()
+	case "电脑":
+		return theme.ComputerIcon()
+	case "主页":
+		return theme.HomeIcon()
+	case "图片":
+		return theme.FileImageIcon()
+	case "音乐":
+		return theme.MediaMusicIcon()
+	case "视频":
+		return theme.MediaVideoIcon()
+	case "邮件":
+		return theme.MailComposeIcon()
+	case "账户":
+		return theme.AccountIcon()
+	}
+	return theme.StorageIcon()
+}
+
+// 颜色名称对应的颜色，未设置时使用蓝色
+func profileColor(name string) color.NRGBA {
+	if c, ok := profileColors[name]; ok {
+		return c
+	}
+	return profileColors["蓝色"]
+}
+
+// 显示翻译后名称的下拉框，onChanged 收到选中项的原始名称
+func newTranslatedSelect(names []string, onChanged func(string)) *widget.Select {
+	labels := make([]string, len(names))
+	for i, name := range names {
+		labels[i] = i18n.T(name)
+	}
+	return widget.NewSelect(labels, func(selected string) {
+		for i, label := range labels {
+			if label == selected {
+				onChanged(names[i])
+			}
+		}
+	})
+}
+
+// 带背景色的配置图标，用于标题和历史记录
+func newProfileBadge(icon, colorName string) *fyne.Container {
+	background := canvas.NewRectangle(profileColor(colorName))
+	background.CornerRadius = 6
+	background.SetMinSize(fyne.NewSize(28, 28))
+	return container.NewStack(background, container.NewPadded(widget.NewIcon(profileIcon(icon))))
+}
+
+// 更新已创建的配置图标
+func updateProfileBadge(badge *fyne.Container, icon, colorName string) {
+	background := badge.Objects[0].(*canvas.Rectangle)
+	background.FillColor = profileColor(colorName)
+	background.Refresh()
+	badge.Objects[1].(*fyne.Container).Objects[0].(*widget.Icon).SetResource(profileIcon(icon))
+}
+
+// 应用当前配置的图标和颜色到标题和窗口图标
+func (b *BackupApp) applyAppearance() {
+	if b.titleBadge != nil {
+		updateProfileBadge(b.titleBadge, b.config.Icon, b.config.Color)
+	}
+	b.window.SetIcon(profileIcon(b.config.Icon))
+}
+
+// 显示外观设置对话框
+func (b *BackupApp) showAppearanceDialog() {
+	icon, colorName := b.config.Icon, b.config.Color
+	preview := newProfileBadge(icon, colorName)
+
+	// 名称保存在配置中，下拉框显示翻译后的名称
+	iconSelect := newTranslatedSelect(profileIconNames, func(selected string) {
+		icon = selected
+		updateProfileBadge(preview, icon, colorName)
+	})
+	iconSelect.SetSelected(i18n.T(profileIconNames[0]))
+	if icon != "" {
+		iconSelect.SetSelected(i18n.T(icon))
+	}
+
+	colorSelect := newTranslatedSelect(profileColorNames, func(selected string) {
+		colorName = selected
+		updateProfileBadge(preview, icon, colorName)
+	})
+	colorSelect.SetSelected(i18n.T(profileColorNames[0]))
+	if colorName != "" {
+		colorSelect.SetSelected(i18n.T(colorName))
+	}
+
+	form := container.NewVBox(
+		widget.NewLabel(i18n.T("为当前备份配置设置图标和颜色，显示在标题和历史记录中，便于区分不同的备份。")),
+		widget.NewForm(
+			widget.NewFormItem(i18n.T("图标"), iconSelect),
+			widget.NewFormItem(i18n.T("颜色"), colorSelect),
+			widget.NewFormItem(i18n.T("预览"), container.NewHBox(preview)),
+		),
+	)
+
+	dialog.ShowCustomConfirm(i18n.T("外观"), i18n.T("保存"), i18n.T("取消"), form, func(ok bool) {
+		if !ok {
+			return
+		}
+		b.config.Icon = icon
+		b.config.Color = colorName
+		b.applyAppearance()
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("外观已更新"))
+	}, b.window)
+}
+
+// 窗口标题，模拟模式下附加提示
+func (b *BackupApp) windowTitle() string {
+	title := i18n.T("SyncSafe 文件备份工具")
+	if len(b.jobs) > 1 {
+		title += " - " + b.config.ProfileName()
+	}
+	if b.config.DryRun {
+		title += i18n.T("（模拟模式）")
+	}
+	if faults.Enabled() {
+		title += i18n.T("（故障注入: ") + faults.String() + i18n.T("）")
+	}
+	return title
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"image/color"
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 任务自动暂停期间跳过的自动备份返回的错误
+var errAutoPaused = i18n.Error("任务因连续失败已自动暂停")
+
+// 连续失败的次数达到上限时暂停任务，不再重试，在 loop 中执行。返回是否刚刚暂停
+func (j *job) pauseIfFailing(err error) bool {
+	if !j.engine.PauseIfFailing() {
+		return false
+	}
+	if saveErr := j.config.Save(); saveErr != nil {
+		slog.Warn("保存配置失败", "err", saveErr)
+	}
+	if j.retryTimer != nil {
+		j.retryTimer.Stop()
+		j.retryTimer = nil
+	}
+	failures := history.ConsecutiveFailures(j.config.History, j.config.SourcePath)
+	slog.Error("任务连续失败，已自动暂停", "profile", j.config.ProfileName(), "source", j.config.SourcePath,
+		"failures", failures, "err", err)
+	j.status(i18n.Sprintf("连续失败 %d 次，已自动暂停", failures))
+	j.notifyFailure(notify.LevelError, i18n.Sprintf("连续失败 %d 次，任务已自动暂停", failures), err)
+	j.emailFailure(err, 0)
+	if j.current() {
+		j.app.refreshPauseBanner()
+	}
+	return true
+}
+
+// 恢复自动暂停的任务，在 loop 中执行
+func (j *job) resumeAutoPaused() {
+	if !j.config.AutoPaused {
+		return
+	}
+	j.config.AutoPaused = false
+	if err := j.config.Save(); err != nil {
+		slog.Warn("保存配置失败", "err", err)
+	}
+	slog.Info("任务已恢复", "profile", j.config.ProfileName())
+	j.status(i18n.T("任务已恢复，监控、定时和重试触发的备份恢复执行"))
+	if j.current() {
+		j.app.refreshPauseBanner()
+	}
+}
+
+// 任务自动暂停时醒目显示的提示条，可以立即备份或直接恢复
+func (b *BackupApp) createPauseBanner() fyne.CanvasObject {
+	j := b.job
+	background := canvas.NewRectangle(color.NRGBA{R: 198, G: 40, B: 40, A: 255})
+	text := canvas.NewText("", color.White)
+	text.TextStyle = fyne.TextStyle{Bold: true}
+	backupBtn := widget.NewButtonWithIcon(i18n.T("立即备份"), theme.UploadIcon(), func() {
+		go b.performBackup()
+	})
+	resumeBtn := widget.NewButtonWithIcon(i18n.T("恢复"), theme.MediaPlayIcon(), func() {
+		go j.do(j.resumeAutoPaused)
+	})
+	b.pauseBanner = container.NewStack(background, container.NewPadded(container.NewBorder(nil, nil,
+		container.NewHBox(widget.NewIcon(theme.ErrorIcon()), text),
+		container.NewHBox(backupBtn, resumeBtn),
+	)))
+	b.pauseText = text
+	b.refreshPauseBanner()
+	return b.pauseBanner
+}
+
+// 按当前任务的暂停状态显示或隐藏提示条
+func (b *BackupApp) refreshPauseBanner() {
+	if b.pauseBanner == nil {
+		return
+	}
+	if !b.config.AutoPaused {
+		b.pauseBanner.Hide()
+		return
+	}
+	b.pauseText.Text = i18n.Sprintf("连续失败 %d 次，已暂停监控、定时和重试触发的备份",
+		history.ConsecutiveFailures(b.config.History, b.config.SourcePath))
+	b.pauseText.Refresh()
+	b.pauseBanner.Show()
+}
@@ -0,0 +1,53 @@
+//go:build darwin
+
+package ui
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 在 ~/Library/LaunchAgents 写入或删除启动代理，登录时由 launchd 启动
+func setAutostart(enabled bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(home, "Library", "LaunchAgents", "com.syncsafe.app.plist")
+	if !enabled {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return i18n.Errorf("取消开机自动启动失败: %v", err)
+		}
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return i18n.Errorf("创建 LaunchAgents 目录失败: %v", err)
+	}
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.syncsafe.app</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`, html.EscapeString(exe), AutostartFlag)
+	return storage.WriteFileAtomic(path, []byte(plist), 0644)
+}
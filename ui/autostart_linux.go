@@ -0,0 +1,42 @@
+//go:build linux
+
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 在用户的 autostart 目录写入或删除 syncsafe.desktop，桌面环境登录时启动其中的程序
+func setAutostart(enabled bool) error {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	path := filepath.Join(configHome, "autostart", "syncsafe.desktop")
+	if !enabled {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return i18n.Errorf("取消开机自动启动失败: %v", err)
+		}
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return i18n.Errorf("创建 autostart 目录失败: %v", err)
+	}
+	entry := fmt.Sprintf("[Desktop Entry]\nType=Application\nName=SyncSafe\nComment=%s\nExec=%s %s\nTerminal=false\nX-GNOME-Autostart-enabled=true\n",
+		i18n.T("文件备份"), desktopExec(exe), AutostartFlag)
+	return storage.WriteFileAtomic(path, []byte(entry), 0644)
+}
@@ -0,0 +1,9 @@
+//go:build !windows && !linux && !darwin
+
+package ui
+
+import "syncsafe/i18n"
+
+func setAutostart(enabled bool) error {
+	return i18n.Errorf("当前系统不支持开机自动启动")
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package ui
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/windows/registry"
+
+	"syncsafe/i18n"
+)
+
+// 当前用户登录时启动的程序列表
+const runKey = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+// 在注册表的 Run 键中添加或删除 SyncSafe
+func setAutostart(enabled bool) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, runKey, registry.SET_VALUE)
+	if err != nil {
+		return i18n.Errorf("打开注册表失败: %v", err)
+	}
+	defer key.Close()
+	if !enabled {
+		if err := key.DeleteValue("SyncSafe"); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return i18n.Errorf("取消开机自动启动失败: %v", err)
+		}
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := key.SetStringValue("SyncSafe", syscall.EscapeArg(exe)+" "+AutostartFlag); err != nil {
+		return i18n.Errorf("设置开机自动启动失败: %v", err)
+	}
+	return nil
+}
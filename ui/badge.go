@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 主界面上显示的最近一次备份结果
+type resultBadge struct {
+	icon      *widget.Icon
+	text      *canvas.Text
+	errorText *widget.Label
+	detailBtn *widget.Button
+	retryBtn  *widget.Button
+	reviewBtn *widget.Button // 复查队列中有问题时显示
+	record    history.Record
+}
+
+// 创建备份结果徽章：成功显示绿色对勾，失败显示红叉、错误摘要和重试按钮
+func (b *BackupApp) createResultBadge() fyne.CanvasObject {
+	badge := &resultBadge{
+		icon:      widget.NewIcon(theme.QuestionIcon()),
+		text:      canvas.NewText("", color.Black),
+		errorText: widget.NewLabel(""),
+	}
+	badge.text.TextStyle = fyne.TextStyle{Bold: true}
+	badge.errorText.Truncation = fyne.TextTruncateEllipsis
+	badge.detailBtn = widget.NewButtonWithIcon(i18n.T("查看错误"), theme.InfoIcon(), func() {
+		dialog.ShowInformation(i18n.T("备份失败"), badge.record.ErrorMessage, b.window)
+	})
+	badge.retryBtn = widget.NewButtonWithIcon(i18n.T("重试"), theme.ViewRefreshIcon(), func() {
+		go b.performBackup()
+	})
+	badge.retryBtn.Importance = widget.DangerImportance
+	badge.reviewBtn = widget.NewButtonWithIcon("", theme.WarningIcon(), b.showReviewDialog)
+	b.badge = badge
+	b.refreshResultBadge()
+
+	return container.NewBorder(nil, nil,
+		container.NewHBox(badge.icon, badge.text),
+		container.NewHBox(badge.reviewBtn, badge.detailBtn, badge.retryBtn),
+		badge.errorText,
+	)
+}
+
+// 根据最近一次备份记录刷新徽章
+func (b *BackupApp) refreshResultBadge() {
+	badge := b.badge
+	if badge == nil {
+		return
+	}
+
+	record, ok := history.Latest(b.config.History, b.config.SourcePath)
+	badge.record = record
+	switch {
+	case !ok:
+		badge.icon.SetResource(theme.QuestionIcon())
+		badge.text.Text = i18n.T("尚未备份")
+		badge.text.Color = color.NRGBA{R: 117, G: 117, B: 117, A: 255}
+	case record.Cancelled:
+		badge.icon.SetResource(theme.CancelIcon())
+		badge.text.Text = i18n.Sprintf("上次备份已取消 %s", record.FormatTime(b.config.Location()))
+		badge.text.Color = color.NRGBA{R: 117, G: 117, B: 117, A: 255}
+	case record.Success:
+		badge.icon.SetResource(theme.ConfirmIcon())
+		badge.text.Text = i18n.Sprintf("上次备份成功 %s", record.FormatTime(b.config.Location()))
+		badge.text.Color = color.NRGBA{R: 0, G: 180, B: 0, A: 255}
+	default:
+		badge.icon.SetResource(theme.CancelIcon())
+		badge.text.Text = i18n.Sprintf("上次备份失败 %s", record.FormatTime(b.config.Location()))
+		badge.text.Color = color.NRGBA{R: 180, G: 0, B: 0, A: 255}
+	}
+	badge.text.Refresh()
+
+	failed := ok && !record.Success && !record.Cancelled
+	if failed {
+		// 只显示错误的第一行，完整内容通过"查看错误"查看
+		firstLine, _, _ := strings.Cut(record.ErrorMessage, "\n")
+		badge.errorText.SetText(firstLine)
+		badge.errorText.Show()
+		badge.detailBtn.Show()
+		badge.retryBtn.Show()
+	} else {
+		badge.errorText.Hide()
+		badge.detailBtn.Hide()
+		badge.retryBtn.Hide()
+	}
+
+	if count := len(b.config.Review); count > 0 {
+		badge.reviewBtn.SetText(i18n.Sprintf("待复查（%d）", count))
+		badge.reviewBtn.Show()
+	} else {
+		badge.reviewBtn.Hide()
+	}
+}
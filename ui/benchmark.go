@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 在后台运行性能测试并显示结果
+func (b *BackupApp) showBenchmark() {
+	if b.config.SourcePath == "" || b.config.DestinationPath == "" {
+		dialog.ShowError(i18n.Errorf("请先选择源文件夹和备份文件夹"), b.window)
+		return
+	}
+
+	progressLabel := widget.NewLabel(i18n.T("准备测试..."))
+	progressDialog := dialog.NewCustomWithoutButtons(i18n.T("性能测试"), container.NewVBox(
+		widget.NewProgressBarInfinite(),
+		progressLabel,
+	), b.window)
+	progressDialog.Show()
+
+	go func() {
+		result, err := b.engine.RunBenchmark(progressLabel.SetText)
+		progressDialog.Hide()
+		if err != nil {
+			dialog.ShowError(i18n.Errorf("性能测试失败: %v", err), b.window)
+			return
+		}
+
+		report := widget.NewLabelWithStyle(result.Report(), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		dialog.ShowCustomConfirm(i18n.T("性能测试结果"), i18n.T("应用推荐的并发复制数"), i18n.T("关闭"), container.NewPadded(report), func(apply bool) {
+			if !apply {
+				return
+			}
+			b.config.CopyWorkers = result.RecommendedWorkers
+			b.saveConfig()
+			b.createUI()
+			b.updateStatus(i18n.Sprintf("并发复制数已设为 %d", result.RecommendedWorkers))
+		}, b.window)
+		b.updateStatus(i18n.T("性能测试完成"))
+	}()
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 暂停时段内推迟监控触发的备份，时段结束后补做一次。返回 true 表示已推迟
+func (j *job) deferForBlackout() bool {
+	period, ok := j.config.ActiveBlackout(time.Now())
+	if !ok {
+		return false
+	}
+	end := period.EndAfter(time.Now().In(j.config.Location()))
+	j.status(i18n.Sprintf("处于暂停时段 %s，自动备份推迟到 %s", period.Name, end.Format("2006-01-02 15:04")))
+	if j.watcher != nil {
+		j.watcher.Schedule(time.Until(end))
+	}
+	return true
+}
+
+// 显示暂停时段设置对话框
+func (b *BackupApp) showBlackoutDialog() {
+	var list *widget.List
+	list = widget.NewList(
+		func() int { return len(b.config.Blackouts) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil,
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+				widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(b.config.Blackouts[id].String())
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				b.config.Blackouts = append(b.config.Blackouts[:id], b.config.Blackouts[id+1:]...)
+				list.Refresh()
+			}
+		},
+	)
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder(i18n.T("例如：视频渲染"))
+	startEntry := widget.NewEntry()
+	startEntry.SetPlaceHolder("2006-01-02")
+	endEntry := widget.NewEntry()
+	endEntry.SetPlaceHolder("2006-01-02")
+	yearlyCheck := widget.NewCheck(i18n.T("每年重复"), nil)
+
+	addBtn := widget.NewButtonWithIcon(i18n.T("添加"), theme.ContentAddIcon(), func() {
+		start, err := time.ParseInLocation(engine.BlackoutDateLayout, strings.TrimSpace(startEntry.Text), time.Local)
+		if err != nil {
+			dialog.ShowError(i18n.Errorf("开始日期格式错误，应为 YYYY-MM-DD"), b.window)
+			return
+		}
+		end := start
+		if strings.TrimSpace(endEntry.Text) != "" {
+			end, err = time.ParseInLocation(engine.BlackoutDateLayout, strings.TrimSpace(endEntry.Text), time.Local)
+			if err != nil {
+				dialog.ShowError(i18n.Errorf("结束日期格式错误，应为 YYYY-MM-DD"), b.window)
+				return
+			}
+		}
+		if end.Before(start) && !yearlyCheck.Checked {
+			dialog.ShowError(i18n.Errorf("结束日期不能早于开始日期"), b.window)
+			return
+		}
+		name := strings.TrimSpace(nameEntry.Text)
+		if name == "" {
+			name = i18n.T("暂停备份")
+		}
+		b.config.Blackouts = append(b.config.Blackouts, engine.BlackoutPeriod{
+			Name: name, Start: start, End: end, Yearly: yearlyCheck.Checked,
+		})
+		nameEntry.SetText("")
+		startEntry.SetText("")
+		endEntry.SetText("")
+		yearlyCheck.SetChecked(false)
+		list.Refresh()
+	})
+
+	importBtn := widget.NewButtonWithIcon(i18n.T("导入节假日日历 (.ics)"), theme.FileIcon(), func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(i18n.Errorf("读取日历失败: %v", err), b.window)
+				return
+			}
+			periods, err := engine.ParseICS(bytes.NewReader(data))
+			if err != nil {
+				dialog.ShowError(i18n.Errorf("解析日历失败: %v", err), b.window)
+				return
+			}
+			b.config.Blackouts = append(b.config.Blackouts, periods...)
+			list.Refresh()
+			b.updateStatus(i18n.Sprintf("已导入 %d 个暂停时段", len(periods)))
+		}, b.window)
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".ics"}))
+		fileDialog.Show()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem(i18n.T("名称"), nameEntry),
+		widget.NewFormItem(i18n.T("开始日期"), startEntry),
+		widget.NewFormItem(i18n.T("结束日期"), endEntry),
+		widget.NewFormItem("", yearlyCheck),
+	)
+
+	content := container.NewBorder(
+		widget.NewLabel(i18n.T("暂停时段内不执行自动备份，时段结束后补做一次备份。手动备份不受影响。")),
+		container.NewVBox(
+			widget.NewSeparator(),
+			form,
+			container.NewHBox(addBtn, importBtn),
+		),
+		nil, nil,
+		list,
+	)
+
+	blackoutDialog := dialog.NewCustom(i18n.T("暂停时段"), i18n.T("关闭"), content, b.window)
+	blackoutDialog.SetOnClosed(func() {
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+		}
+	})
+	blackoutDialog.Resize(fyne.NewSize(560, 480))
+	blackoutDialog.Show()
+}
@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	fynestorage "fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 预览读取的内容：文本只显示开头，图片不超过该大小时才读取完整内容
+const (
+	textPreviewLimit  = 64 * 1024
+	imagePreviewLimit = 16 * 1024 * 1024
+)
+
+// 快照浏览器：按需展开快照中的目录，显示大小和修改时间，预览文本和图片，右键还原或打开所在的文件夹
+func (b *BackupApp) showSnapshotBrowser(record history.Record) {
+	browser, err := b.engine.BrowseSnapshot(record)
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	loc := b.config.Location()
+
+	// 已展开目录的内容，节点 ID 为相对快照根目录的路径，根节点为空字符串
+	entries := map[string]engine.SnapshotEntry{"": {IsDir: true}}
+	children := make(map[string][]string)
+	list := func(dir string) []string {
+		if ids, ok := children[dir]; ok {
+			return ids
+		}
+		listed, err := browser.List(dir)
+		if err != nil {
+			b.updateStatus(err.Error())
+		}
+		ids := make([]string, len(listed))
+		for i, entry := range listed {
+			entries[entry.Path] = entry
+			ids[i] = entry.Path
+		}
+		children[dir] = ids
+		return ids
+	}
+
+	// 预览区：文件信息和内容
+	title := widget.NewLabelWithStyle(i18n.T("选择文件查看内容"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	title.Truncation = fyne.TextTruncateEllipsis
+	details := widget.NewLabel("")
+	preview := container.NewStack()
+	showPreview := func(object fyne.CanvasObject) {
+		preview.Objects = []fyne.CanvasObject{object}
+		preview.Refresh()
+	}
+	selected := ""
+	loadPreview := func(entry engine.SnapshotEntry) {
+		data, truncated, err := browser.Preview(entry.Path, textPreviewLimit)
+		if err == nil && truncated && strings.HasPrefix(http.DetectContentType(data), "image/") && entry.Size <= imagePreviewLimit {
+			data, truncated, err = browser.Preview(entry.Path, imagePreviewLimit)
+		}
+		if selected != entry.Path {
+			return // 读取期间选择了其他文件
+		}
+		if err != nil {
+			showPreview(widget.NewLabel(err.Error()))
+			return
+		}
+		showPreview(previewContent(data, truncated))
+	}
+
+	tree := widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			return list(id)
+		},
+		func(id widget.TreeNodeID) bool {
+			return entries[id].IsDir
+		},
+		func(branch bool) fyne.CanvasObject {
+			return newBrowserRow()
+		},
+		func(id widget.TreeNodeID, branch bool, item fyne.CanvasObject) {
+			entry := entries[id]
+			row := item.(*browserRow)
+			icon := theme.FileIcon()
+			info := entry.ModTime.In(loc).Format("2006-01-02 15:04")
+			if entry.IsDir {
+				icon = theme.FolderIcon()
+			} else {
+				info = formatBytes(entry.Size) + "  " + info
+			}
+			row.icon.SetResource(icon)
+			row.name.SetText(entry.Name)
+			row.info.SetText(info)
+			row.menu = func(pos fyne.Position) {
+				b.showBrowserMenu(record, entry, pos)
+			}
+		},
+	)
+	tree.OnSelected = func(id widget.TreeNodeID) {
+		entry := entries[id]
+		selected = id
+		title.SetText(entry.Name)
+		text := i18n.Sprintf("路径: %s\n修改时间: %s", entry.Path, entry.ModTime.In(loc).Format(history.TimeLayout))
+		if !entry.IsDir {
+			text += i18n.Sprintf("\n大小: %s", formatBytes(entry.Size))
+		}
+		details.SetText(text)
+		if entry.IsDir {
+			showPreview(widget.NewLabel(i18n.T("右键单击可以还原整个目录")))
+			return
+		}
+		showPreview(container.NewCenter(widget.NewActivity()))
+		go loadPreview(entry)
+	}
+
+	split := container.NewHSplit(tree, container.NewBorder(container.NewVBox(title, details, widget.NewSeparator()), nil, nil, nil, preview))
+	split.Offset = 0.45
+	hint := widget.NewLabel(i18n.T("右键单击文件或目录可以还原或打开所在的文件夹"))
+	browserDialog := dialog.NewCustom(i18n.Sprintf("浏览快照 %s", record.FormatTime(loc)), i18n.T("关闭"),
+		container.NewBorder(hint, nil, nil, nil, split), b.window)
+	browserDialog.Resize(fyne.NewSize(960, 620))
+	browserDialog.Show()
+}
+
+// 预览内容：图片按比例缩放显示，文本以等宽字体显示，其他文件只提示无法预览
+func previewContent(data []byte, truncated bool) fyne.CanvasObject {
+	if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		picture := canvas.NewImageFromImage(img)
+		picture.FillMode = canvas.ImageFillContain
+		return picture
+	}
+	if truncated {
+		// 截断处可能是不完整的字符
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if len(data) > 0 && (!utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0) {
+		return widget.NewLabel(i18n.T("该文件无法预览"))
+	}
+	text := string(data)
+	if truncated {
+		text += "\n" + i18n.Sprintf("……只显示前 %s", formatBytes(int64(len(data))))
+	}
+	label := widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	return container.NewScroll(label)
+}
+
+// 快照浏览器中文件或目录的右键菜单
+func (b *BackupApp) showBrowserMenu(record history.Record, entry engine.SnapshotEntry, pos fyne.Position) {
+	relPaths := []string{entry.Path}
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem(i18n.T("还原到源文件夹"), func() {
+			b.confirmRestore(record, relPaths, engine.ExpandPathTemplate(record.SourcePath, record.Timestamp))
+		}),
+		fyne.NewMenuItem(i18n.T("还原到其他位置..."), func() {
+			b.showFolderDialog(i18n.T("选择还原位置"), func(path string) {
+				b.confirmRestore(record, relPaths, path)
+			})
+		}),
+	}
+	// 远程快照没有本机文件夹可以打开；归档快照打开归档所在的文件夹
+	if !storage.IsRemote(record.DestPath) {
+		folder := filepath.Dir(record.DestPath)
+		if !engine.IsArchiveSnapshot(record.DestPath) {
+			folder = filepath.Dir(filepath.Join(record.DestPath, entry.Path))
+		}
+		items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem(i18n.T("打开所在的文件夹"), func() {
+			u, err := url.Parse(fynestorage.NewFileURI(folder).String())
+			if err == nil {
+				err = fyne.CurrentApp().OpenURL(u)
+			}
+			if err != nil {
+				dialog.ShowError(i18n.Errorf("打开文件夹失败: %v", err), b.window)
+			}
+		}))
+	}
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), b.window.Canvas(), pos)
+}
+
+// 快照浏览器中的一行：图标、名称以及大小和修改时间，右键单击弹出操作菜单
+type browserRow struct {
+	widget.BaseWidget
+	icon *widget.Icon
+	name *widget.Label
+	info *widget.Label
+	menu func(pos fyne.Position)
+}
+
+func newBrowserRow() *browserRow {
+	r := &browserRow{icon: widget.NewIcon(theme.FileIcon()), name: widget.NewLabel(""), info: widget.NewLabel("")}
+	r.name.Truncation = fyne.TextTruncateEllipsis
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *browserRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewBorder(nil, nil, r.icon, r.info, r.name))
+}
+
+// 只处理右键单击，左键单击仍由文件树选中该行
+func (r *browserRow) TappedSecondary(event *fyne.PointEvent) {
+	if r.menu != nil {
+		r.menu(event.AbsolutePosition)
+	}
+}
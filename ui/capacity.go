@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 检查目标磁盘使用率，每升高到一个新的阈值提醒一次，降到最低阈值以下后重新开始。
+// 在 loop 中执行
+func (j *job) checkDestinationCapacity() {
+	if j.config.DestinationPath == "" {
+		return
+	}
+	total, free, err := j.engine.Destination().Usage()
+	if err != nil || total == 0 {
+		if err != nil {
+			slog.Warn("读取目标磁盘容量失败", "err", err)
+		}
+		return
+	}
+	usage := int((total - free) * 100 / total)
+
+	thresholds := j.config.Thresholds()
+	level := 0
+	for _, t := range thresholds {
+		if usage >= t {
+			level = t
+		}
+	}
+	if level == 0 {
+		j.capacityNotified = 0
+		return
+	}
+	if level <= j.capacityNotified {
+		return
+	}
+	j.capacityNotified = level
+
+	plan := engine.PlanPrune(j.config.History, total, free, thresholds[0])
+	message := i18n.Sprintf("备份磁盘已使用 %d%%，剩余 %.1f GB", usage, float64(free)/(1024*1024*1024))
+	if len(plan.Snapshots) > 0 {
+		message += i18n.Sprintf("。删除最旧的 %d 个快照可释放约 %.1f GB",
+			len(plan.Snapshots), float64(plan.Freed)/(1024*1024*1024))
+	}
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(i18n.T("备份磁盘空间不足"), message))
+	j.engine.Notify(notify.LevelWarning, i18n.T("备份磁盘空间不足"), message)
+	j.status(message)
+	j.showPrunePlan(message, plan)
+}
+
+// 显示清理建议，确认后删除建议的快照
+func (j *job) showPrunePlan(message string, plan engine.PrunePlan) {
+	if len(plan.Snapshots) == 0 {
+		dialog.ShowInformation(i18n.T("备份磁盘空间不足"), message+i18n.T("\n没有可以清理的旧快照。"), j.app.window)
+		return
+	}
+
+	lines := make([]string, len(plan.Snapshots))
+	for i, record := range plan.Snapshots {
+		lines[i] = fmt.Sprintf("%s  %.2f MB  %s",
+			record.FormatTime(j.config.Location()),
+			float64(record.TotalSize)/(1024*1024),
+			record.DestPath)
+	}
+	list := widget.NewLabel(strings.Join(lines, "\n"))
+	content := container.NewBorder(
+		widget.NewLabel(message+i18n.T("\n\n建议删除以下快照（最近一次快照始终保留）:")),
+		nil, nil, nil,
+		container.NewVScroll(list),
+	)
+
+	confirm := dialog.NewCustomConfirm(i18n.T("备份磁盘空间不足"), i18n.T("删除这些快照"), i18n.T("稍后"), content, func(ok bool) {
+		if ok {
+			j.do(func() {
+				j.pruneSnapshots(plan.Snapshots)
+			})
+		}
+	}, j.app.window)
+	confirm.Resize(fyne.NewSize(600, 400))
+	confirm.Show()
+}
+
+// 删除建议的快照并保存历史记录，在 loop 中执行
+func (j *job) pruneSnapshots(snapshots []history.Record) {
+	count, err := j.engine.PruneSnapshots(snapshots)
+	if err != nil {
+		dialog.ShowError(err, j.app.window)
+	}
+	if err := j.config.Save(); err != nil {
+		dialog.ShowError(err, j.app.window)
+	}
+	if j.current() {
+		j.app.refreshHistoryView()
+	}
+	j.status(i18n.Sprintf("已清理 %d 个旧快照", count))
+	j.capacityNotified = 0
+	j.checkDestinationCapacity()
+}
+
+// 显示容量提醒设置对话框
+func (b *BackupApp) showCapacityDialog() {
+	parts := make([]string, 0, 3)
+	for _, t := range b.config.Thresholds() {
+		parts = append(parts, strconv.Itoa(t))
+	}
+	thresholdEntry := widget.NewEntry()
+	thresholdEntry.SetText(strings.Join(parts, ","))
+	versionsEntry := widget.NewEntry()
+	versionsEntry.SetText(strconv.Itoa(b.config.KeepVersions))
+
+	usageText := i18n.T("无法读取目标磁盘容量")
+	if b.config.DestinationPath == "" {
+		usageText = i18n.T("未选择目标文件夹")
+	} else if total, free, err := b.engine.Destination().Usage(); err == nil && total > 0 {
+		usageText = i18n.Sprintf("已使用 %d%%，剩余 %.1f GB / 共 %.1f GB",
+			(total-free)*100/total, float64(free)/(1024*1024*1024), float64(total)/(1024*1024*1024))
+	}
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("目标磁盘"), Widget: widget.NewLabel(usageText)},
+		{Text: i18n.T("提醒阈值（%）"), Widget: thresholdEntry, HintText: i18n.T("用逗号分隔，使用率每达到一个阈值提醒一次")},
+		{Text: i18n.T("每个文件保留版本数"), Widget: versionsEntry, HintText: i18n.T("清理快照前把每个文件最近的几个版本复制到 -versions 目录，0 表示不保留")},
+	}
+	dialog.ShowForm(i18n.T("容量提醒"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		var thresholds []int
+		for _, part := range strings.Split(thresholdEntry.Text, ",") {
+			part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "%"))
+			if part == "" {
+				continue
+			}
+			t, err := strconv.Atoi(part)
+			if err != nil || t <= 0 || t > 100 {
+				dialog.ShowError(i18n.Errorf("阈值必须是 1 到 100 之间的整数: %s", part), b.window)
+				return
+			}
+			thresholds = append(thresholds, t)
+		}
+		keepVersions, err := strconv.Atoi(strings.TrimSpace(versionsEntry.Text))
+		if err != nil || keepVersions < 0 {
+			dialog.ShowError(i18n.Errorf("保留版本数必须是非负整数: %s", versionsEntry.Text), b.window)
+			return
+		}
+		b.config.CapacityThresholds = thresholds
+		b.config.KeepVersions = keepVersions
+		b.capacityNotified = 0
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("容量提醒设置已保存"))
+		b.do(b.checkDestinationCapacity)
+	}, b.window)
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 取回速度的显示名称
+var retrievalTiers = []struct{ tier, label string }{
+	{storage.RetrievalExpedited, "加急"},
+	{storage.RetrievalStandard, "标准"},
+	{storage.RetrievalBulk, "批量"},
+}
+
+// 取回的副本默认保留的天数
+const retrievalDays = 7
+
+// 取回需要的时间，例如 5 分钟、12 小时
+func formatRetrievalTime(d time.Duration) string {
+	if d < time.Hour {
+		return i18n.Sprintf("%d 分钟", int(d.Minutes()))
+	}
+	return i18n.Sprintf("%d 小时", int(d.Hours()))
+}
+
+// 冷存储中的快照先查询取回状态：全部取回后调用 proceed 继续还原，
+// 正在取回时提示等待，还没有取回时说明需要的时间并可以选择速度发起取回
+func (b *BackupApp) checkRetrieval(snapshot history.Record, proceed func()) {
+	b.updateStatus(i18n.T("正在查询冷存储中的快照..."))
+	go func() {
+		status, err := b.engine.SnapshotRetrieval(snapshot)
+		switch {
+		case err != nil:
+			b.updateStatus(err.Error())
+			dialog.ShowError(err, b.window)
+		case status.Complete():
+			proceed()
+		case status.Pending == 0:
+			b.updateStatus(i18n.T("快照正在从冷存储取回"))
+			dialog.ShowInformation(i18n.T("正在取回"),
+				i18n.Sprintf("快照中的 %d 个文件正在从冷存储取回，已完成 %d 个。取回完成后再还原", status.Files, status.Ready), b.window)
+		default:
+			b.showRetrievalDialog(snapshot, status)
+		}
+	}()
+}
+
+// 说明快照在冷存储中，选择取回速度和取回的副本保留的天数后发起取回
+func (b *BackupApp) showRetrievalDialog(snapshot history.Record, status storage.Retrieval) {
+	var tiers, labels []string
+	for _, option := range retrievalTiers {
+		if d, ok := storage.RetrievalTime(status.Class, option.tier); ok {
+			tiers = append(tiers, option.tier)
+			labels = append(labels, i18n.Sprintf("%s（通常 %s 内完成）", i18n.T(option.label), formatRetrievalTime(d)))
+		}
+	}
+	tierSelect := widget.NewSelect(labels, nil)
+	tierSelect.SetSelectedIndex(len(labels) / 2)
+	daysEntry := widget.NewEntry()
+	daysEntry.SetText(strconv.Itoa(retrievalDays))
+	message := widget.NewLabel(i18n.Sprintf("该快照已转入冷存储（%s），其中 %d 个文件要先取回才能还原。取回在云端进行，期间可以关闭程序，完成后再次还原即可。越快的取回费用越高。",
+		status.Class, status.Pending))
+	message.Wrapping = fyne.TextWrapWord
+
+	items := []*widget.FormItem{
+		{Text: "", Widget: message},
+		{Text: i18n.T("取回速度"), Widget: tierSelect},
+		{Text: i18n.T("保留天数"), Widget: daysEntry, HintText: i18n.T("取回的副本保留多少天，过期后需要重新取回")},
+	}
+	form := dialog.NewForm(i18n.T("从冷存储取回"), i18n.T("取回"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(daysEntry.Text))
+		if err != nil || days <= 0 {
+			dialog.ShowError(i18n.Errorf("保留天数必须是正整数: %s", daysEntry.Text), b.window)
+			return
+		}
+		index := tierSelect.SelectedIndex()
+		if index < 0 {
+			return
+		}
+		tier := tiers[index]
+		b.updateStatus(i18n.T("正在请求取回快照..."))
+		go func() {
+			count, err := b.engine.RetrieveSnapshot(snapshot, tier, days)
+			if err != nil {
+				b.updateStatus(err.Error())
+				dialog.ShowError(err, b.window)
+				return
+			}
+			d, _ := storage.RetrievalTime(status.Class, tier)
+			b.updateStatus(i18n.Sprintf("已请求取回 %d 个文件", count))
+			dialog.ShowInformation(i18n.T("已请求取回"),
+				i18n.Sprintf("已请求取回 %d 个文件，通常 %s 内完成。完成后再次还原即可，取回的副本保留 %d 天", count, formatRetrievalTime(d), days), b.window)
+		}()
+	}, b.window)
+	form.Resize(fyne.NewSize(520, 320))
+	form.Show()
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"errors"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/version"
+)
+
+// 处理启动时的配置加载错误：配置损坏且有可用备份时提示用户恢复
+func (b *BackupApp) handleConfigLoadError(err error) {
+	var corrupt *engine.CorruptError
+	if !errors.As(err, &corrupt) || corrupt.Backup == "" {
+		dialog.ShowError(err, b.window)
+		return
+	}
+
+	backupTime := ""
+	if info, err := os.Stat(corrupt.Backup); err == nil {
+		backupTime = info.ModTime().Format("2006-01-02 15:04:05")
+	}
+	message := i18n.Sprintf("配置文件 %s 已损坏，无法读取。\n\n是否从 %s 保存的备份恢复？\n损坏的文件将被保留以便排查。",
+		corrupt.Path, backupTime)
+
+	dialog.ShowConfirm(i18n.T("配置文件已损坏"), message, func(ok bool) {
+		if !ok {
+			b.updateStatus(i18n.T("配置文件已损坏，使用默认配置"))
+			return
+		}
+		if err := engine.RestoreConfigBackup(corrupt); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if err := b.loadConfig(); err != nil {
+			b.handleConfigLoadError(err)
+			return
+		}
+		b.createUI()
+		b.applyAppearance()
+		b.updateStatus(i18n.T("已从备份恢复配置"))
+	}, b.window)
+}
+
+// 显示回滚包列表：可以手动保存当前配置，或把配置、历史记录和密钥还原到升级前的状态
+func (b *BackupApp) showRollbackDialog() {
+	rollbacks, err := engine.ListRollbacks()
+	if err != nil {
+		dialog.ShowError(i18n.Errorf("读取回滚包失败: %v", err), b.window)
+		return
+	}
+
+	selected := -1
+	list := widget.NewList(
+		func() int { return len(rollbacks) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(rollbacks[id].String())
+		},
+	)
+	revertBtn := widget.NewButtonWithIcon(i18n.T("还原所选"), theme.MediaReplayIcon(), nil)
+	revertBtn.Disable()
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		revertBtn.Enable()
+	}
+	createBtn := widget.NewButtonWithIcon(i18n.T("保存当前配置"), theme.DocumentSaveIcon(), func() {
+		if _, err := engine.CreateRollback(i18n.T("手动保存")); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		rollbacks, _ = engine.ListRollbacks()
+		list.UnselectAll()
+		list.Refresh()
+		b.updateStatus(i18n.T("已保存当前配置的回滚包"))
+	})
+
+	hint := widget.NewLabel(i18n.Sprintf("当前版本 %s（配置格式 %d）。升级或迁移配置前会自动保存回滚包，\n包含所有任务的配置、历史记录、本机设置和加密密钥。", version.Get().Version, engine.ConfigVersion))
+	content := container.NewBorder(hint, container.NewHBox(createBtn, revertBtn), nil, nil, list)
+	d := dialog.NewCustom(i18n.T("配置回滚"), i18n.T("关闭"), content, b.window)
+
+	revertBtn.OnTapped = func() {
+		if selected < 0 || selected >= len(rollbacks) {
+			return
+		}
+		r := rollbacks[selected]
+		message := i18n.Sprintf("是否把配置还原到 %s 保存的状态？\n当前的配置会先保存为新的回滚包，之后也可以撤销。\n还原后所有任务的监控停止。", r.Created.Format("2006-01-02 15:04:05"))
+		dialog.ShowConfirm(i18n.T("还原配置"), message, func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := engine.RevertRollback(r); err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			d.Hide()
+			if err := b.loadConfig(); err != nil {
+				b.handleConfigLoadError(err)
+				return
+			}
+			b.createUI()
+			b.applyAppearance()
+			b.updateStatus(i18n.Sprintf("已还原到版本 %s 的配置，使用旧版本程序时请重新安装该版本", r.AppVersion))
+		}, b.window)
+	}
+
+	d.Resize(fyne.NewSize(700, 400))
+	d.Show()
+}
@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 比较两个快照：列出新增、删除和修改的文件及大小变化，选中文本文件时显示逐行差异
+func (b *BackupApp) showSnapshotDiffDialog() {
+	// 当前筛选条件下的快照，从新到旧
+	var snapshots []history.Record
+	var labels []string
+	visible := b.visibleHistory()
+	loc := b.config.Location()
+	for i := len(visible) - 1; i >= 0; i-- {
+		record := visible[i]
+		if !record.HasSnapshot() {
+			continue
+		}
+		snapshots = append(snapshots, record)
+		label := record.FormatTime(loc) + "  " + filepath.Base(record.DestPath)
+		if record.Note != "" {
+			label += "  " + record.Note
+		}
+		labels = append(labels, label)
+	}
+	if len(snapshots) < 2 {
+		dialog.ShowInformation(i18n.T("比较快照"), i18n.T("至少需要两个快照才能比较"), b.window)
+		return
+	}
+
+	// 默认比较最新的快照和同一源文件夹的上一个快照
+	olderSelect := widget.NewSelect(labels, nil)
+	newerSelect := widget.NewSelect(labels, nil)
+	newerSelect.SetSelectedIndex(0)
+	olderSelect.SetSelectedIndex(1)
+	for i, record := range snapshots[1:] {
+		if record.SourcePath == snapshots[0].SourcePath {
+			olderSelect.SetSelectedIndex(i + 1)
+			break
+		}
+	}
+
+	var older, newer history.Record
+	var entries []engine.DiffEntry
+	summary := widget.NewLabel(i18n.T("选择两个快照后点击「比较」"))
+	diffText := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	diffView := container.NewScroll(diffText)
+	selected := -1
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(entries) {
+				return
+			}
+			entry := entries[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s  %s  %s", history.Change{Op: entry.Op}.OpName(), entry.Path, formatDiffSize(entry)))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		if id >= len(entries) {
+			return
+		}
+		selected = id
+		entry := entries[id]
+		diffText.SetText(i18n.T("正在比较文件内容..."))
+		go func() {
+			text, err := b.engine.TextDiff(older, newer, entry)
+			if selected != id {
+				return // 比较期间选择了其他文件
+			}
+			switch {
+			case err != nil:
+				text = err.Error()
+			case text == "":
+				text = i18n.T("内容相同，只有修改时间不同")
+			}
+			diffText.SetText(text)
+			diffView.ScrollToTop()
+		}()
+	}
+
+	var compareBtn *widget.Button
+	compareBtn = widget.NewButton(i18n.T("比较"), func() {
+		oldIndex, newIndex := olderSelect.SelectedIndex(), newerSelect.SelectedIndex()
+		if oldIndex < 0 || newIndex < 0 || oldIndex == newIndex {
+			dialog.ShowInformation(i18n.T("比较快照"), i18n.T("请选择两个不同的快照"), b.window)
+			return
+		}
+		// 列表从新到旧，序号大的是较早的快照
+		older, newer = snapshots[max(oldIndex, newIndex)], snapshots[min(oldIndex, newIndex)]
+		compareBtn.Disable()
+		summary.SetText(i18n.T("正在比较快照..."))
+		go func() {
+			defer compareBtn.Enable()
+			diff, err := b.engine.DiffSnapshots(older, newer)
+			if err != nil {
+				summary.SetText(err.Error())
+				return
+			}
+			added, removed, modified, delta := 0, 0, 0, int64(0)
+			for _, entry := range diff {
+				switch entry.Op {
+				case history.ChangeNew:
+					added++
+				case history.ChangeDeleted:
+					removed++
+				default:
+					modified++
+				}
+				delta += entry.SizeDelta()
+			}
+			entries, selected = diff, -1
+			list.UnselectAll()
+			list.Refresh()
+			diffText.SetText("")
+			summary.SetText(i18n.Sprintf("%s → %s：新增 %d，删除 %d，修改 %d，大小变化 %s",
+				older.FormatTime(loc), newer.FormatTime(loc), added, removed, modified, formatSizeDelta(delta)))
+		}()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem(i18n.T("较早的快照"), olderSelect),
+		widget.NewFormItem(i18n.T("较新的快照"), newerSelect),
+	)
+	top := container.NewVBox(form, container.NewBorder(nil, nil, nil, compareBtn, summary))
+	split := container.NewHSplit(list, diffView)
+	split.Offset = 0.4
+	diffDialog := dialog.NewCustom(i18n.T("比较快照"), i18n.T("关闭"), container.NewBorder(top, nil, nil, nil, split), b.window)
+	diffDialog.Resize(fyne.NewSize(1000, 640))
+	diffDialog.Show()
+}
+
+// 文件在两个快照中的大小，修改的文件显示前后大小和变化
+func formatDiffSize(entry engine.DiffEntry) string {
+	switch entry.Op {
+	case history.ChangeNew:
+		return formatBytes(entry.NewSize)
+	case history.ChangeDeleted:
+		return formatBytes(entry.OldSize)
+	}
+	return fmt.Sprintf("%s → %s（%s）", formatBytes(entry.OldSize), formatBytes(entry.NewSize), formatSizeDelta(entry.SizeDelta()))
+}
+
+// 带符号的大小变化
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatBytes(-delta)
+	}
+	return "+" + formatBytes(delta)
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 客户端加密设置：启用后快照内容在写入目标之前加密，密码短语只保存在本机
+func (b *BackupApp) showEncryptionDialog() {
+	settings := b.config.Encryption
+	enabledCheck := widget.NewCheck(i18n.T("加密备份内容"), nil)
+	enabledCheck.SetChecked(settings.Enabled)
+	namesCheck := widget.NewCheck(i18n.T("同时加密文件名"), nil)
+	namesCheck.SetChecked(settings.ObfuscateNames)
+	passphraseEntry := widget.NewPasswordEntry()
+	passphraseEntry.SetText(settings.Passphrase)
+	confirmEntry := widget.NewPasswordEntry()
+	confirmEntry.SetText(settings.Passphrase)
+	warning := widget.NewLabel(i18n.T("密码短语遗失后无法还原加密的快照，请另行妥善保存。\n同一目标中的快照必须使用同一个密码短语。"))
+	warning.Wrapping = fyne.TextWrapWord
+
+	items := []*widget.FormItem{
+		{Text: "", Widget: enabledCheck},
+		{Text: "", Widget: namesCheck, HintText: i18n.T("快照文件夹名称不加密，其中的文件名和目录名加密；zip 归档中的文件名不加密")},
+		{Text: i18n.T("密码短语"), Widget: passphraseEntry},
+		{Text: i18n.T("确认密码短语"), Widget: confirmEntry},
+		{Text: "", Widget: warning},
+	}
+	dialog.ShowForm(i18n.T("加密"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if enabledCheck.Checked && passphraseEntry.Text == "" {
+			dialog.ShowError(i18n.Errorf("启用加密前请填写密码短语"), b.window)
+			return
+		}
+		if passphraseEntry.Text != confirmEntry.Text {
+			dialog.ShowError(i18n.Errorf("两次输入的密码短语不一致"), b.window)
+			return
+		}
+		b.config.Encryption.Enabled = enabledCheck.Checked
+		b.config.Encryption.ObfuscateNames = namesCheck.Checked
+		b.config.Encryption.Passphrase = passphraseEntry.Text
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if b.config.Encryption.Enabled {
+			b.updateStatus(i18n.T("已启用备份加密"))
+		} else {
+			b.updateStatus(i18n.T("已关闭备份加密"))
+		}
+	}, b.window)
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 导出快照为 zip 或 tar.gz 归档，按保存的文件扩展名选择格式
+func (b *BackupApp) exportSnapshotDialog(record history.Record) {
+	if _, err := os.Stat(record.DestPath); err != nil {
+		dialog.ShowError(i18n.Errorf("快照不存在: %v", err), b.window)
+		return
+	}
+	if engine.IsArchiveSnapshot(record.DestPath) {
+		dialog.ShowInformation(i18n.T("导出快照"), i18n.T("该快照已经是归档文件，可以直接复制:\n")+record.DestPath, b.window)
+		return
+	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+
+		format := "zip"
+		name := strings.ToLower(writer.URI().Name())
+		if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") {
+			format = "tar.gz"
+		}
+
+		total, err := engine.SnapshotSize(record.DestPath)
+		if err != nil {
+			writer.Close()
+			dialog.ShowError(i18n.Errorf("读取快照失败: %v", err), b.window)
+			return
+		}
+
+		progressBar := widget.NewProgressBar()
+		progressLabel := widget.NewLabel(i18n.Sprintf("共 %.2f MB", float64(total)/(1024*1024)))
+		progressDialog := dialog.NewCustomWithoutButtons(i18n.T("导出快照"), container.NewVBox(progressBar, progressLabel), b.window)
+		progressDialog.Resize(fyne.NewSize(400, 120))
+		progressDialog.Show()
+
+		go func() {
+			var shown int64
+			err := engine.ExportSnapshot(record.DestPath, writer, format, func(done int64) {
+				// 每写入 1 MB 刷新一次界面
+				if done-shown < 1024*1024 && done < total {
+					return
+				}
+				shown = done
+				if total > 0 {
+					progressBar.SetValue(float64(done) / float64(total))
+				}
+				progressLabel.SetText(i18n.Sprintf("已打包 %.2f / %.2f MB",
+					float64(done)/(1024*1024), float64(total)/(1024*1024)))
+			})
+			closeErr := writer.Close()
+			progressDialog.Hide()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+
+			size := i18n.T("未知")
+			if info, statErr := os.Stat(writer.URI().Path()); statErr == nil {
+				size = fmt.Sprintf("%.2f MB", float64(info.Size())/(1024*1024))
+			}
+			dialog.ShowInformation(i18n.T("导出完成"), i18n.Sprintf("快照已导出到 %s\n归档大小: %s", writer.URI().Path(), size), b.window)
+			b.updateStatus(i18n.T("快照已导出"))
+		}()
+	}, b.window)
+	saveDialog.SetFileName(filepath.Base(record.DestPath) + ".zip")
+	saveDialog.Show()
+}
@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/ignore"
+)
+
+// 排除规则预览最多扫描的源文件夹条目数和显示的路径数
+const (
+	rulePreviewScanLimit = 5000
+	rulePreviewShown     = 20
+)
+
+// 备份范围设置：隐藏文件、系统文件和点文件的排除，排除规则，以及是否保留目录
+func (b *BackupApp) showFileFilterDialog() {
+	hiddenCheck := widget.NewCheck("", nil)
+	hiddenCheck.SetChecked(b.config.ExcludeHidden)
+	systemCheck := widget.NewCheck("", nil)
+	systemCheck.SetChecked(b.config.ExcludeSystem)
+	dotfilesCheck := widget.NewCheck("", nil)
+	dotfilesCheck.SetChecked(b.config.ExcludeDotfiles)
+	dirsCheck := widget.NewCheck("", nil)
+	dirsCheck.SetChecked(!b.config.SkipDirectories)
+
+	rulesEntry := widget.NewMultiLineEntry()
+	rulesEntry.SetPlaceHolder("*.log\nnode_modules/\n.cache/**\n!important.log")
+	rulesEntry.SetText(strings.Join(b.config.FilterRules, "\n"))
+	rulesEntry.SetMinRowsVisible(5)
+	preview := widget.NewLabel("")
+	preview.Wrapping = fyne.TextWrapWord
+
+	// 规则修改后在后台扫描源文件夹，只显示最近一次修改的结果
+	var generation atomic.Int64
+	source := b.engine.SourcePath()
+	updatePreview := func(text string) {
+		gen := generation.Add(1)
+		go func() {
+			result := previewRules(source, strings.Split(text, "\n"))
+			if generation.Load() == gen {
+				preview.SetText(result)
+			}
+		}()
+	}
+	rulesEntry.OnChanged = updatePreview
+	updatePreview(rulesEntry.Text)
+
+	ignoreFilesCheck := widget.NewCheck(i18n.T("遵守 .gitignore 和 .syncsafeignore"), nil)
+	ignoreFilesCheck.SetChecked(b.config.UseIgnoreFiles)
+	ignorePreview := widget.NewLabel("")
+	ignorePreview.Wrapping = fyne.TextWrapWord
+	go func() {
+		ignorePreview.SetText(previewIgnoreFiles(source))
+	}()
+
+	hiddenHint := i18n.T("以 . 开头的文件和目录")
+	switch runtime.GOOS {
+	case "windows":
+		hiddenHint = i18n.T("设置了隐藏属性的文件和目录")
+	case "darwin":
+		hiddenHint = i18n.T("以 . 开头或在访达中隐藏的文件和目录")
+	}
+	items := []*widget.FormItem{
+		{Text: i18n.T("排除隐藏文件"), Widget: hiddenCheck, HintText: hiddenHint},
+		{Text: i18n.T("排除系统文件"), Widget: systemCheck, HintText: i18n.T("设置了系统属性的文件和目录（仅 Windows）")},
+		{Text: i18n.T("排除点文件"), Widget: dotfilesCheck, HintText: i18n.T("以 . 开头的文件和目录，例如 .env、.cache")},
+		{Text: i18n.T("保留目录"), Widget: dirsCheck, HintText: i18n.T("备份空目录，并保留目录的权限和修改时间")},
+		{Text: i18n.T("排除规则"), Widget: rulesEntry, HintText: i18n.T("每行一条，语法与 .gitignore 相同，以 ! 开头重新包含")},
+		{Text: i18n.T("匹配预览"), Widget: container.NewGridWrap(fyne.NewSize(420, 160), container.NewVScroll(preview))},
+		{Text: i18n.T("规则文件"), Widget: ignoreFilesCheck, HintText: i18n.T("同时使用源文件夹根目录中规则文件的排除规则，跳过构建产物和 node_modules 等依赖目录，上面的规则可以用 ! 重新包含")},
+		{Text: i18n.T("将跳过的目录"), Widget: container.NewGridWrap(fyne.NewSize(420, 80), container.NewVScroll(ignorePreview))},
+	}
+	dialog.ShowForm(i18n.T("备份范围"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		var rules []string
+		for _, line := range strings.Split(rulesEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				rules = append(rules, line)
+			}
+		}
+		if _, err := ignore.Compile(rules); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.ExcludeHidden = hiddenCheck.Checked
+		b.config.ExcludeSystem = systemCheck.Checked
+		b.config.ExcludeDotfiles = dotfilesCheck.Checked
+		b.config.SkipDirectories = !dirsCheck.Checked
+		b.config.FilterRules = rules
+		b.config.UseIgnoreFiles = ignoreFilesCheck.Checked
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		// 监控按新的规则重新添加目录
+		if b.watcher != nil {
+			b.stopWatching()
+			if err := b.startWatching(); err != nil {
+				b.updateStatus(i18n.T("重新开始监控失败: ") + err.Error())
+				b.setWatchButton(false)
+				return
+			}
+		}
+		b.updateStatus(i18n.T("备份范围设置已保存，下次备份生效"))
+	}, b.window)
+}
+
+// 列出源文件夹中的规则文件会跳过的顶层目录
+func previewIgnoreFiles(source string) string {
+	if source == "" {
+		return i18n.T("未选择源文件夹")
+	}
+	lines := engine.ReadIgnoreFiles(source)
+	if lines == nil {
+		return i18n.T("源文件夹中没有 .gitignore 或 .syncsafeignore")
+	}
+	rules, _ := ignore.Compile(lines)
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err.Error()
+	}
+	var skipped []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != ".git" && rules.Match(entry.Name(), true) {
+			skipped = append(skipped, entry.Name()+string(filepath.Separator))
+		}
+	}
+	if len(skipped) == 0 {
+		return i18n.T("不会跳过任何顶层目录")
+	}
+	return i18n.Sprintf("将跳过 %d 个顶层目录:", len(skipped)) + "\n" + strings.Join(skipped, "\n")
+}
+
+// 扫描源文件夹，列出被排除规则排除的文件和目录
+func previewRules(source string, lines []string) string {
+	rules, err := ignore.Compile(lines)
+	if err != nil {
+		return err.Error()
+	}
+	if rules.Empty() {
+		return i18n.T("没有排除规则")
+	}
+	if source == "" {
+		return i18n.T("未选择源文件夹")
+	}
+
+	var excluded []string
+	scanned, count := 0, 0
+	filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if scanned++; scanned > rulePreviewScanLimit {
+			return filepath.SkipAll
+		}
+		relPath, relErr := filepath.Rel(source, path)
+		if relErr != nil || relPath == "." {
+			return nil
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !rules.Match(relPath, info.IsDir()) {
+			return nil
+		}
+		count++
+		if len(excluded) < rulePreviewShown {
+			if info.IsDir() {
+				relPath += string(filepath.Separator)
+			}
+			excluded = append(excluded, relPath)
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	summary := i18n.Sprintf("将排除 %d 个文件或目录", count)
+	if scanned > rulePreviewScanLimit {
+		summary += i18n.Sprintf("（只扫描了前 %d 项）", rulePreviewScanLimit)
+	}
+	if len(excluded) == 0 {
+		return summary
+	}
+	if count > len(excluded) {
+		excluded = append(excluded, "...")
+	}
+	return summary + ":\n" + strings.Join(excluded, "\n")
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 查找文件时最多列出的版本数
+const findFileLimit = 500
+
+// 查找文件：在当前任务的所有快照中按文件名或通配符查找，列出每个文件的所有版本，可以直接还原其中一个
+func (b *BackupApp) showFindFileDialog() {
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder(i18n.T("文件名、路径片段或通配符，例如 report*.docx，按回车查找"))
+	results := container.NewVBox()
+
+	queryEntry.OnSubmitted = func(pattern string) {
+		results.RemoveAll()
+		results.Add(widget.NewLabel(i18n.T("查找中...")))
+		// 读取所有快照清单可能需要一些时间
+		go func() {
+			versions, err := b.config.FindFile(pattern, findFileLimit)
+			var rows []fyne.CanvasObject
+			switch {
+			case err != nil:
+				rows = append(rows, widget.NewLabel(err.Error()))
+			case len(versions) == 0:
+				rows = append(rows, widget.NewLabel(i18n.T("没有找到匹配的文件")))
+			case len(versions) >= findFileLimit:
+				rows = append(rows, widget.NewLabel(i18n.Sprintf("只显示前 %d 个版本，请输入更具体的文件名", findFileLimit)))
+			}
+			for i, version := range versions {
+				if i == 0 || versions[i-1].Path != version.Path || versions[i-1].Record.SourcePath != version.Record.SourcePath {
+					header := widget.NewLabelWithStyle(version.Path, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+					header.Truncation = fyne.TextTruncateEllipsis
+					rows = append(rows, header)
+				}
+				rows = append(rows, b.fileVersionRow(version))
+			}
+			results.Objects = rows
+			results.Refresh()
+		}()
+	}
+
+	content := container.NewBorder(queryEntry, nil, nil, nil, container.NewVScroll(results))
+	findDialog := dialog.NewCustom(i18n.T("查找文件"), i18n.T("关闭"), content, b.window)
+	findDialog.Resize(fyne.NewSize(760, 520))
+	findDialog.Show()
+	b.window.Canvas().Focus(queryEntry)
+}
+
+// 一个文件版本：修改时间、大小和包含它的快照，行末按钮还原该版本
+func (b *BackupApp) fileVersionRow(version engine.FileVersion) fyne.CanvasObject {
+	loc := b.config.Location()
+	record := version.Record
+	text := i18n.Sprintf("修改于 %s，%s\n快照 %s，共 %d 个快照包含此版本",
+		version.ModTime.In(loc).Format("2006-01-02 15:04:05"), formatBytes(version.Size), record.FormatTime(loc), version.Snapshots)
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+
+	restore := func(dest string) {
+		relPath, err := b.engine.SnapshotPath(record, version.Path)
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.confirmRestore(record, []string{relPath}, dest)
+	}
+	restoreBtn := widget.NewButtonWithIcon(i18n.T("还原"), theme.HistoryIcon(), func() {
+		restore(engine.ExpandPathTemplate(record.SourcePath, record.Timestamp))
+	})
+	otherBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		b.showFolderDialog(i18n.T("选择还原位置"), restore)
+	})
+	return container.NewBorder(nil, nil, widget.NewIcon(theme.FileIcon()), container.NewHBox(restoreBtn, otherBtn), label)
+}
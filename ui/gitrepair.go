@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/gitsync"
+	"syncsafe/i18n"
+)
+
+// 显示 Git 仓库修复向导
+func (b *BackupApp) showGitRepairDialog() {
+	if b.config.SourcePath == "" {
+		dialog.ShowError(i18n.Errorf("请先选择源文件夹"), b.window)
+		return
+	}
+
+	repo := b.engine.Git()
+	diagnosis := gitsync.Diagnose(repo.Dir)
+
+	problemText := i18n.T("未发现问题，仓库状态正常。")
+	if problems := diagnosis.Problems(); len(problems) > 0 {
+		problemText = "- " + strings.Join(problems, "\n- ")
+	}
+
+	output := widget.NewMultiLineEntry()
+	output.Wrapping = fyne.TextWrapWord
+	output.SetMinRowsVisible(6)
+	logLine := func(line string) {
+		output.SetText(output.Text + line + "\n")
+	}
+
+	runAction := func(name, warning string, action func() error) {
+		dialog.ShowConfirm(name, warning, func(ok bool) {
+			if !ok {
+				return
+			}
+			logLine("== " + name + " ==")
+			go func() {
+				if err := action(); err != nil {
+					logLine(i18n.T("失败: ") + err.Error())
+					return
+				}
+				logLine(i18n.T("完成"))
+				b.updateStatus(i18n.T("Git 仓库已修复"))
+			}()
+		}, b.window)
+	}
+
+	recommitBtn := widget.NewButtonWithIcon(i18n.T("重新提交工作区"), theme.DocumentSaveIcon(), func() {
+		runAction(i18n.T("重新提交工作区"), i18n.T("放弃未完成的合并/变基，回到 master 分支，并把源文件夹当前内容提交为新版本。\n工作区文件不会被修改。"), func() error {
+			return repo.RecommitWorkingTree(diagnosis, logLine)
+		})
+	})
+	resetBtn := widget.NewButtonWithIcon(i18n.T("重置到远程版本"), theme.ViewRefreshIcon(), func() {
+		runAction(i18n.T("重置到远程版本"), i18n.T("把本地分支重置到远程 origin/master，本地未推送的提交将被丢弃。\n工作区文件不会被修改，下次备份会重新提交。"), func() error {
+			return repo.ResetToRemote(diagnosis, logLine)
+		})
+	})
+	if !diagnosis.HasRemote {
+		resetBtn.Disable()
+	}
+	recloneBtn := widget.NewButtonWithIcon(i18n.T("重新克隆"), theme.DownloadIcon(), func() {
+		runAction(i18n.T("重新克隆"), i18n.T("把现有 .git 目录改名保留，重新初始化仓库并从远程获取历史。\n工作区文件不会被修改。"), func() error {
+			return repo.Reclone(logLine)
+		})
+	})
+
+	content := container.NewVBox(
+		container.NewHBox(
+			widget.NewIcon(theme.WarningIcon()),
+			widget.NewLabelWithStyle(i18n.T("诊断结果"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		),
+		widget.NewLabel(problemText),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle(i18n.T("修复操作"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewGridWithColumns(3, recommitBtn, resetBtn, recloneBtn),
+		widget.NewLabel(i18n.T("输出:")),
+		output,
+	)
+
+	repairDialog := dialog.NewCustom(i18n.T("修复 Git 仓库"), i18n.T("关闭"), container.NewPadded(content), b.window)
+	repairDialog.Resize(fyne.NewSize(600, 450))
+	repairDialog.Show()
+}
+
+// Git 备份失败时提示错误并提供修复向导入口
+func (j *job) showGitFailure(err error) {
+	dialog.ShowConfirm(i18n.T("Git 备份失败"), i18n.Sprintf("%s: %v\n\n是否打开修复向导？", j.config.ProfileName(), err), func(ok bool) {
+		if ok {
+			// 修复向导针对当前任务
+			j.app.selectJob(j)
+			j.app.showGitRepairDialog()
+		}
+	}, j.app.window)
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"time"
+
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 按 Git 提交计划安排下一次批量提交，替换之前安排的提交。错过的提交（例如程序没有运行）立即补上
+func (j *job) startGitSchedule() {
+	after := j.config.LastGitCommit
+	if after.IsZero() {
+		after = time.Now()
+	}
+	j.scheduleGit(after)
+}
+
+// 安排 after 之后的下一次 Git 提交
+func (j *job) scheduleGit(after time.Time) {
+	j.stopGitSchedule()
+	next, err := j.config.NextGitCommit(after)
+	if err != nil {
+		j.status(i18n.T("Git 提交计划无效: ") + err.Error())
+	}
+	if !next.IsZero() {
+		j.gitTimer = time.AfterFunc(time.Until(next), func() { j.runScheduledGit(next) })
+	}
+}
+
+// 取消已安排的 Git 提交
+func (j *job) stopGitSchedule() {
+	if j.gitTimer != nil {
+		j.gitTimer.Stop()
+		j.gitTimer = nil
+	}
+}
+
+// 在 loop 中提交并推送上次提交以来的所有变化，然后安排下一次。
+// 失败时只提示，变化留到下一次提交
+func (j *job) runScheduledGit(at time.Time) {
+	j.do(func() {
+		if !j.config.Git.Enabled || j.config.Git.Schedule == "" {
+			return
+		}
+		j.status(i18n.T("开始定时提交到 Git 仓库"))
+		if err := j.engine.CommitGit(); err != nil {
+			j.notifyFailure(notify.LevelError, i18n.T("定时提交 Git 失败"), err)
+			j.status(i18n.T("定时提交 Git 失败: ") + err.Error())
+			return
+		}
+		if err := j.config.Save(); err != nil {
+			j.status(i18n.T("保存配置失败: ") + err.Error())
+			return
+		}
+		j.status(i18n.T("定时提交 Git 完成"))
+	})
+	j.scheduleGit(scheduledAfter(at))
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 哈希设置：快速模式只按大小和修改时间判断文件是否变化，适合照片、视频等不会原地修改的媒体库；
+// 按目录的设置可以在快速模式下保留文档文件夹的哈希，或在其他模式下跳过媒体目录
+func (b *BackupApp) showHashDialog() {
+	trustCheck := widget.NewCheck("", nil)
+	trustCheck.SetChecked(b.config.TrustMetadata)
+
+	hashEntry := widget.NewMultiLineEntry()
+	hashEntry.SetPlaceHolder("Documents\nProjects/notes")
+	hashEntry.SetText(strings.Join(b.config.HashDirs, "\n"))
+	hashEntry.SetMinRowsVisible(4)
+	trustEntry := widget.NewMultiLineEntry()
+	trustEntry.SetPlaceHolder("Photos\nVideos")
+	trustEntry.SetText(strings.Join(b.config.TrustDirs, "\n"))
+	trustEntry.SetMinRowsVisible(4)
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("快速模式"), Widget: trustCheck, HintText: i18n.T("不计算哈希，只按大小和修改时间判断；按内容比较和备份后校验只对下面的目录生效")},
+		{Text: i18n.T("计算哈希的目录"), Widget: hashEntry, HintText: i18n.T("每行一个相对源文件夹的目录，快速模式下仍按内容比较和校验")},
+		{Text: i18n.T("不计算哈希的目录"), Widget: trustEntry, HintText: i18n.T("每行一个目录，始终只按大小和修改时间判断；目录重叠时更深的一条优先")},
+	}
+	dialog.ShowForm(i18n.T("哈希设置"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		hashDirs, err := engine.CleanHashDirs(strings.Split(hashEntry.Text, "\n"))
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		trustDirs, err := engine.CleanHashDirs(strings.Split(trustEntry.Text, "\n"))
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.TrustMetadata = trustCheck.Checked
+		b.config.HashDirs = hashDirs
+		b.config.TrustDirs = trustDirs
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("哈希设置已保存，下次备份生效"))
+	}, b.window)
+}
@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+const (
+	healthCheckDelay    = time.Minute // 启动后首次检查的延迟
+	healthCheckInterval = time.Hour   // 定期检查的间隔
+	healthReportLimit   = 50          // 提示中最多列出的问题数
+)
+
+// 启动定期健康检查，检查所有任务的源文件夹
+func (b *BackupApp) startHealthChecks() {
+	go func() {
+		time.Sleep(healthCheckDelay)
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+		for {
+			for _, j := range b.jobs {
+				j.runHealthCheck()
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// 执行一次健康检查，只对新出现的问题发出提醒
+func (j *job) runHealthCheck() {
+	if j.config.SourcePath == "" {
+		return
+	}
+	root := j.engine.SourcePath()
+
+	problems, err := engine.ScanSourceHealth(root)
+	if err != nil {
+		slog.Warn("源文件夹健康检查失败", "err", err)
+		if !j.healthKnown[root] {
+			j.healthKnown = map[string]bool{root: true}
+			j.warnHealth(err.Error(), nil)
+		}
+		return
+	}
+
+	known := make(map[string]bool, len(problems))
+	var fresh []engine.HealthProblem
+	for _, p := range problems {
+		known[p.Path] = true
+		if !j.healthKnown[p.Path] {
+			fresh = append(fresh, p)
+		}
+	}
+	j.healthKnown = known
+
+	if len(fresh) == 0 {
+		return
+	}
+	message := i18n.Sprintf("源文件夹中有 %d 个新出现的无法读取的路径", len(fresh))
+	if j.config.ElevatedRead {
+		message += i18n.T("，备份时将尝试通过提权读取")
+	}
+	j.warnHealth(message, fresh)
+}
+
+// 通过系统通知、状态栏和对话框提示健康检查发现的问题
+func (j *job) warnHealth(message string, problems []engine.HealthProblem) {
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(i18n.T("源文件夹健康检查"), message))
+	j.engine.Notify(notify.LevelWarning, i18n.T("源文件夹健康检查"), message)
+	j.status(message)
+
+	lines := make([]string, 0, len(problems))
+	for i, p := range problems {
+		if i == healthReportLimit {
+			lines = append(lines, i18n.Sprintf("... 以及另外 %d 个", len(problems)-healthReportLimit))
+			break
+		}
+		lines = append(lines, p.Err)
+	}
+	content := container.NewBorder(widget.NewLabel(message), nil, nil, nil,
+		container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n"))))
+
+	warning := dialog.NewCustom(i18n.T("源文件夹健康检查"), i18n.T("确定"), content, j.app.window)
+	warning.Resize(fyne.NewSize(600, 400))
+	warning.Show()
+}
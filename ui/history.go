@@ -0,0 +1,523 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+func (b *BackupApp) createHistoryTab() *fyne.Container {
+	// 创建标题
+	title := widget.NewLabelWithStyle(i18n.T("备份历史记录"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	// 创建统计信息卡片
+	successColor := &color.NRGBA{R: 0, G: 180, B: 0, A: 255}
+	failedColor := &color.NRGBA{R: 180, G: 0, B: 0, A: 255}
+
+	// 创建带颜色的文本，统计在历史记录加载完成后填写
+	b.totalBackupText = canvas.NewText("-", color.Black)
+	b.totalBackupText.Alignment = fyne.TextAlignCenter
+
+	b.successBackupText = canvas.NewText("-", *successColor)
+	b.successBackupText.Alignment = fyne.TextAlignCenter
+
+	b.failedBackupText = canvas.NewText("-", *failedColor)
+	b.failedBackupText.Alignment = fyne.TextAlignCenter
+
+	b.successRateText = canvas.NewText("-", *successColor)
+	b.successRateText.Alignment = fyne.TextAlignCenter
+
+	statsContainer := container.NewHBox(
+		widget.NewCard("", "", container.NewVBox(
+			widget.NewLabelWithStyle(i18n.T("总备份次数"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			b.totalBackupText,
+		)),
+		widget.NewCard("", "", container.NewVBox(
+			widget.NewLabelWithStyle(i18n.T("成功次数"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			b.successBackupText,
+		)),
+		widget.NewCard("", "", container.NewVBox(
+			widget.NewLabelWithStyle(i18n.T("失败次数"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			b.failedBackupText,
+		)),
+		widget.NewCard("", "", container.NewVBox(
+			widget.NewLabelWithStyle(i18n.T("成功率"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			b.successRateText,
+		)),
+	)
+
+	// 创建源文件夹筛选
+	b.historySelect = widget.NewSelect(nil, func(selected string) {
+		if selected == i18n.T(historyFilterAll) {
+			selected = ""
+		}
+		if selected == b.historyFilter {
+			return
+		}
+		b.historyFilter = selected
+		b.refreshHistoryView()
+	})
+	b.updateHistorySelectOptions()
+	b.historySelect.SetSelected(i18n.T(historyFilterAll))
+
+	// 创建搜索框，匹配备注、错误信息和路径
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder(i18n.T("搜索备注、错误信息或路径"))
+	searchEntry.SetText(b.historySearch)
+	searchEntry.OnChanged = b.filterHistoryList
+
+	// 创建历史列表，可以用键盘操作
+	b.historyList = newHistoryList(b,
+		func() fyne.CanvasObject {
+			return widget.NewCard("", "", container.NewVBox(
+				// 标题栏
+				container.NewHBox(
+					newProfileBadge("", ""),
+					widget.NewIcon(theme.InfoIcon()),
+					canvas.NewText("", color.Black),
+					canvas.NewText("", color.Black),
+				),
+				// 路径信息
+				container.NewVBox(
+					container.NewHBox(
+						widget.NewLabelWithStyle(i18n.T("源路径:"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+						widget.NewLabel(""),
+					),
+					container.NewHBox(
+						widget.NewLabelWithStyle(i18n.T("目标路径:"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+						widget.NewLabel(""),
+					),
+				),
+				// 基本信息
+				container.NewHBox(
+					container.NewVBox(
+						widget.NewLabelWithStyle(i18n.T("文件统计"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+						widget.NewLabel(""),
+					),
+					container.NewVBox(
+						widget.NewLabelWithStyle(i18n.T("文件变更"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+						container.NewHBox(
+							widget.NewLabel(""),
+							widget.NewLabel(""),
+							widget.NewLabel(""),
+						),
+					),
+					container.NewVBox(
+						widget.NewLabelWithStyle(i18n.T("备份信息"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+						widget.NewLabel(""),
+					),
+				),
+				// 备注
+				container.NewBorder(nil, nil,
+					widget.NewLabelWithStyle(i18n.T("备注:"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+					container.NewHBox(
+						widget.NewButtonWithIcon(i18n.T("浏览"), theme.FolderOpenIcon(), nil),
+						widget.NewButtonWithIcon(i18n.T("导出快照"), theme.DownloadIcon(), nil),
+						widget.NewButtonWithIcon(i18n.T("校验"), theme.ConfirmIcon(), nil),
+						widget.NewButtonWithIcon(i18n.T("编辑备注"), theme.DocumentCreateIcon(), nil),
+						widget.NewButtonWithIcon(i18n.T("详情"), theme.InfoIcon(), nil),
+					),
+					widget.NewLabel(""),
+				),
+			))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			b.historyMutex.Lock()
+			if id >= len(b.historyRows) {
+				b.historyMutex.Unlock()
+				return
+			}
+			record := b.historyRows[id]
+			b.historyMutex.Unlock()
+			card := item.(*widget.Card)
+			content := card.Content.(*fyne.Container)
+
+			// 设置标题和图标
+			header := content.Objects[0].(*fyne.Container)
+			updateProfileBadge(header.Objects[0].(*fyne.Container), record.Icon, record.Color)
+			headerIcon := header.Objects[1].(*widget.Icon)
+			headerText := header.Objects[2].(*canvas.Text)
+			var statusText string
+			if record.Cancelled {
+				headerIcon.SetResource(theme.CancelIcon())
+				headerText.Color = *failedColor
+				statusText = i18n.T("已取消")
+			} else if record.Success {
+				headerIcon.SetResource(theme.ConfirmIcon())
+				headerText.Color = *successColor
+				statusText = i18n.T("成功")
+				if record.DryRun {
+					headerIcon.SetResource(theme.VisibilityIcon())
+					statusText = i18n.T("模拟（未写入）")
+				}
+			} else {
+				headerIcon.SetResource(theme.ErrorIcon())
+				headerText.Color = *failedColor
+				statusText = i18n.Sprintf("失败\n%s", record.ErrorMessage)
+			}
+			if record.Pruned {
+				statusText += i18n.T("（快照已清理）")
+			}
+			if previous, ok := history.Previous(b.config.History, record); ok && record.SameContent(previous) {
+				statusText += i18n.T("（内容与上一个快照相同）")
+			}
+			if record.SizeAnomaly != "" {
+				statusText += "\n" + record.SizeAnomaly
+			}
+			if record.Attempt > 1 {
+				statusText = i18n.Sprintf("第 %d 次尝试 %s", record.Attempt, statusText)
+			}
+			headerText.Text = record.FormatTime(b.config.Location())
+			headerText.Refresh()
+
+			// 备份后校验的结果
+			verifiedText := header.Objects[3].(*canvas.Text)
+			switch {
+			case record.Verified:
+				verifiedText.Text = i18n.T("已校验")
+				verifiedText.Color = *successColor
+			case len(record.Mismatches) > 0:
+				verifiedText.Text = i18n.Sprintf("校验发现 %d 个问题", len(record.Mismatches))
+				verifiedText.Color = *failedColor
+				lines := record.Mismatches
+				if len(lines) > mismatchListLimit {
+					lines = append(lines[:mismatchListLimit:mismatchListLimit], i18n.Sprintf("... 以及另外 %d 个", len(lines)-mismatchListLimit))
+				}
+				statusText += "\n" + strings.Join(lines, "\n")
+			default:
+				verifiedText.Text = ""
+			}
+			verifiedText.Refresh()
+
+			// 设置路径信息
+			pathInfo := content.Objects[1].(*fyne.Container)
+			pathInfo.Objects[0].(*fyne.Container).Objects[1].(*widget.Label).SetText(record.SourcePath)
+			pathInfo.Objects[1].(*fyne.Container).Objects[1].(*widget.Label).SetText(record.DestPath)
+
+			// 设置基本信息
+			infoContainer := content.Objects[2].(*fyne.Container)
+			// 文件统计
+			fileStats := infoContainer.Objects[0].(*fyne.Container)
+			fileStatsText := i18n.Sprintf("总文件: %d\n大小: %.2f MB",
+				record.FileCount,
+				float64(record.TotalSize)/(1024*1024),
+			)
+			if record.LinkedFiles > 0 {
+				fileStatsText += i18n.Sprintf("\n硬链接: %d", record.LinkedFiles)
+			}
+			if record.PooledFiles > 0 {
+				fileStatsText += i18n.Sprintf("\n存储池中已有: %d", record.PooledFiles)
+			}
+			if record.FailedFiles > 0 {
+				fileStatsText += i18n.Sprintf("\n复制失败: %d", record.FailedFiles)
+			}
+			if record.StreamFiles > 0 {
+				fileStatsText += i18n.Sprintf("\n附加数据流: %d", record.StreamFiles)
+			}
+			if record.StreamsLost > 0 {
+				fileStatsText += i18n.Sprintf("\n附加数据流未备份: %d", record.StreamsLost)
+			}
+			fileStats.Objects[1].(*widget.Label).SetText(fileStatsText)
+
+			// 文件变更
+			changeStats := infoContainer.Objects[1].(*fyne.Container)
+			changeBox := changeStats.Objects[1].(*fyne.Container)
+			changeBox.Objects[0].(*widget.Label).SetText(i18n.Sprintf("新增: %d", record.NewFiles))
+			changeBox.Objects[1].(*widget.Label).SetText(i18n.Sprintf("修改: %d", record.ModifiedFiles))
+			changeBox.Objects[2].(*widget.Label).SetText(i18n.Sprintf("删除: %d", record.DeletedFiles))
+
+			// 备份信息
+			backupInfo := infoContainer.Objects[2].(*fyne.Container)
+			backupInfo.Objects[1].(*widget.Label).SetText(i18n.Sprintf("耗时: %v\n峰值内存: %.1f MB\n状态: %s",
+				record.Duration.Round(time.Millisecond),
+				float64(record.PeakMemory)/(1024*1024),
+				statusText,
+			))
+
+			// 备注
+			noteRow := content.Objects[3].(*fyne.Container)
+			noteLabel := noteRow.Objects[0].(*widget.Label)
+			noteLabel.Wrapping = fyne.TextWrapWord
+			noteLabel.SetText(record.Note)
+			noteButtons := noteRow.Objects[2].(*fyne.Container)
+			browseBtn := noteButtons.Objects[0].(*widget.Button)
+			browseBtn.OnTapped = func() {
+				b.showSnapshotBrowser(record)
+			}
+			exportBtn := noteButtons.Objects[1].(*widget.Button)
+			exportBtn.OnTapped = func() {
+				b.exportSnapshotDialog(record)
+			}
+			verifyBtn := noteButtons.Objects[2].(*widget.Button)
+			verifyBtn.OnTapped = func() {
+				b.verifySnapshotDialog(record)
+			}
+			// 只有实际写入且未被清理的快照可以浏览、导出和校验
+			if record.HasSnapshot() {
+				browseBtn.Enable()
+				exportBtn.Enable()
+				verifyBtn.Enable()
+			} else {
+				browseBtn.Disable()
+				exportBtn.Disable()
+				verifyBtn.Disable()
+			}
+			noteButtons.Objects[3].(*widget.Button).OnTapped = func() {
+				b.showNoteDialog(record)
+			}
+			noteButtons.Objects[4].(*widget.Button).OnTapped = func() {
+				b.showRecordDetails(record, func() {})
+			}
+		},
+	)
+
+	// 加载中的提示，显示在列表下方
+	activity := widget.NewActivity()
+	activity.Start()
+	b.historyLoading = container.NewCenter(container.NewHBox(activity, widget.NewLabel(i18n.T("正在加载历史记录..."))))
+
+	// 创建按钮容器
+	buttonContainer := container.NewHBox(
+		widget.NewButtonWithIcon(i18n.T("清除历史记录"), theme.DeleteIcon(), func() {
+			message := i18n.T("是否要清除所有历史记录？")
+			if b.historySearch != "" {
+				message = i18n.Sprintf("是否要清除符合搜索条件的 %d 条历史记录？", len(b.visibleHistory()))
+			} else if b.historyFilter != "" {
+				message = i18n.Sprintf("是否要清除 %s 的历史记录？", b.historyFilter)
+			}
+			dialog.ShowConfirm(i18n.T("确认"), message, func(ok bool) {
+				if ok {
+					b.clearVisibleHistory()
+				}
+			}, b.window)
+		}),
+		widget.NewButtonWithIcon(i18n.T("导出历史记录"), theme.DocumentSaveIcon(), func() {
+			b.exportHistory()
+		}),
+		widget.NewButtonWithIcon(i18n.T("比较快照"), theme.ListIcon(), func() {
+			b.showSnapshotDiffDialog()
+		}),
+		widget.NewButtonWithIcon(i18n.T("查找文件"), theme.SearchIcon(), func() {
+			b.showFindFileDialog()
+		}),
+	)
+
+	// 创建主容器
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewPadded(title),
+			container.NewPadded(container.NewBorder(nil, nil, widget.NewLabel(i18n.T("源文件夹:")), nil, b.historySelect)),
+			container.NewPadded(container.NewBorder(nil, nil, widget.NewLabel(i18n.T("搜索:")), nil, searchEntry)),
+			container.NewPadded(statsContainer),
+			container.NewPadded(buttonContainer),
+		),
+		b.historyLoading,
+		nil,
+		nil,
+		container.NewPadded(container.NewVScroll(b.historyList)),
+	)
+
+	// 历史记录很多时逐批填充列表，不阻塞窗口显示
+	b.refreshHistoryView()
+	return content
+}
+
+// 历史记录筛选中表示全部的选项
+const historyFilterAll = "全部"
+
+// 历史记录中最多列出的校验问题数
+const mismatchListLimit = 5
+
+// 当前的筛选和搜索条件
+func (b *BackupApp) historyFilterState() history.Filter {
+	return history.Filter{Source: b.historyFilter, Search: b.historySearch, Location: b.config.Location()}
+}
+
+// 当前筛选条件下的历史记录，按时间顺序排列
+func (b *BackupApp) visibleHistory() []history.Record {
+	return b.historyFilterState().Apply(b.config.History)
+}
+
+// 清除当前筛选条件下的历史记录，备份进行中时等备份结束后再清除
+func (b *BackupApp) clearVisibleHistory() {
+	j, filter := b.job, b.historyFilterState()
+	j.do(func() {
+		if err := j.config.RemoveHistory(filter.Apply(j.config.History)); err != nil {
+			dialog.ShowError(err, b.window)
+		}
+		if !j.current() {
+			return
+		}
+		b.historyFilter = ""
+		b.updateHistorySelectOptions()
+		if b.historySelect != nil {
+			b.historySelect.SetSelected(i18n.T(historyFilterAll))
+		}
+		b.refreshHistoryView()
+		b.refreshResultBadge()
+	})
+}
+
+// 用历史记录中出现过的源文件夹更新筛选选项
+func (b *BackupApp) updateHistorySelectOptions() {
+	if b.historySelect == nil {
+		return
+	}
+	b.historySelect.Options = append([]string{i18n.T(historyFilterAll)}, history.Sources(b.config.History)...)
+	b.historySelect.Refresh()
+}
+
+func (b *BackupApp) filterHistoryList(searchText string) {
+	b.historySearch = strings.ToLower(strings.TrimSpace(searchText))
+	b.refreshHistoryView()
+}
+
+// 修改历史记录的备注
+func (b *BackupApp) setRecordNote(record history.Record, note string) {
+	j := b.job
+	j.do(func() {
+		history.SetNote(j.config.History, record, note)
+		if err := j.config.Save(); err != nil {
+			dialog.ShowError(err, b.window)
+		}
+		if j.current() {
+			b.refreshHistoryView()
+		}
+	})
+}
+
+// 显示备注编辑对话框
+func (b *BackupApp) showNoteDialog(record history.Record) {
+	noteEntry := widget.NewMultiLineEntry()
+	noteEntry.SetPlaceHolder(i18n.T("例如：重装系统前的备份"))
+	noteEntry.SetText(record.Note)
+	noteEntry.SetMinRowsVisible(4)
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("备份时间"), Widget: widget.NewLabel(record.FormatTime(b.config.Location()))},
+		{Text: i18n.T("备注"), Widget: noteEntry},
+	}
+	dialog.ShowForm(i18n.T("编辑备注"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if ok {
+			b.setRecordNote(record, strings.TrimSpace(noteEntry.Text))
+		}
+	}, b.window)
+}
+
+// 每批加入历史列表的记录数
+const historyBatchSize = 200
+
+// 在后台按当前筛选条件重新填充历史列表：从最新的记录开始逐批加入并刷新，
+// 记录很多时列表先显示最近的部分，加载期间显示提示，完成后更新统计卡片
+func (b *BackupApp) refreshHistoryView() {
+	if b.historyList == nil {
+		return
+	}
+	records := append([]history.Record(nil), b.config.History...)
+	config := b.config
+	filter := b.historyFilterState()
+	b.historyMutex.Lock()
+	b.historyLoad++
+	load := b.historyLoad
+	b.historyMutex.Unlock()
+	b.historyLoading.Show()
+
+	go func() {
+		// 按源文件夹筛选时使用数据库的索引，只读取该文件夹的记录
+		if filter.Source != "" {
+			if indexed, err := config.QueryHistory(filter); err == nil {
+				records = indexed
+			} else {
+				slog.Warn("查询历史记录失败，改为在内存中筛选", "err", err)
+			}
+		}
+		rows := make([]history.Record, 0, len(records))
+		for end := len(records); end > 0; end -= historyBatchSize {
+			batch := filter.Apply(records[max(end-historyBatchSize, 0):end])
+			for i := len(batch) - 1; i >= 0; i-- {
+				rows = append(rows, batch[i])
+			}
+			b.historyMutex.Lock()
+			if load != b.historyLoad {
+				b.historyMutex.Unlock()
+				return
+			}
+			b.historyRows = rows
+			b.historyMutex.Unlock()
+			b.historyList.Refresh()
+		}
+
+		b.historyMutex.Lock()
+		if load != b.historyLoad {
+			b.historyMutex.Unlock()
+			return
+		}
+		b.historyRows = rows
+		b.historyMutex.Unlock()
+		b.historyList.Refresh()
+		b.historyLoading.Hide()
+		b.refreshHistoryStats(rows)
+	}()
+}
+
+// 更新统计卡片
+func (b *BackupApp) refreshHistoryStats(records []history.Record) {
+	success, failed := history.Counts(records)
+	if b.totalBackupText != nil {
+		b.totalBackupText.Text = fmt.Sprintf("%d", len(records))
+		b.totalBackupText.Refresh()
+	}
+	if b.successBackupText != nil {
+		b.successBackupText.Text = fmt.Sprintf("%d", success)
+		b.successBackupText.Refresh()
+	}
+	if b.failedBackupText != nil {
+		b.failedBackupText.Text = fmt.Sprintf("%d", failed)
+		b.failedBackupText.Refresh()
+	}
+	if b.successRateText != nil {
+		b.successRateText.Text = history.SuccessRate(records)
+		b.successRateText.Refresh()
+	}
+}
+
+func (b *BackupApp) exportHistory() {
+	dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if err := history.WriteCSV(writer, b.visibleHistory(), b.config.Location()); err != nil {
+			dialog.ShowError(i18n.Errorf("导出历史记录失败: %v", err), b.window)
+		}
+	}, b.window)
+}
+
+func (j *job) addBackupRecord(record history.Record) {
+	j.config.History = append(j.config.History, record)
+	j.engine.SendWebhooks(record)
+	// 先保存，历史列表按源文件夹筛选时从数据库查询
+	j.config.Save()
+	if j.current() {
+		j.app.updateHistorySelectOptions()
+		j.app.refreshHistoryView()
+		j.app.refreshResultBadge()
+	}
+}
@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 历史列表：Tab 键可以移到列表，上下方向键移动焦点，空格选中，Enter 展开记录详情，
+// 菜单键或 Shift+F10 打开操作菜单。焦点移动时在状态栏显示记录摘要。
+// Fyne 还没有提供屏幕阅读器接口，复制到剪贴板的摘要是目前能提供给读屏软件的文本
+type historyList struct {
+	widget.List
+	app   *BackupApp
+	focus widget.ListItemID // 与 widget.List 内部的焦点保持一致
+}
+
+var actionMenuShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyF10, Modifier: fyne.KeyModifierShift}
+
+func newHistoryList(b *BackupApp, createItem func() fyne.CanvasObject, updateItem func(widget.ListItemID, fyne.CanvasObject)) *historyList {
+	l := &historyList{app: b}
+	l.Length = func() int {
+		b.historyMutex.Lock()
+		defer b.historyMutex.Unlock()
+		return len(b.historyRows)
+	}
+	l.CreateItem = createItem
+	l.UpdateItem = updateItem
+	// 鼠标点击时 widget.List 把焦点移到点击的记录并选中
+	l.OnSelected = func(id widget.ListItemID) {
+		l.focus = id
+	}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// 焦点所在的记录
+func (l *historyList) focusedRecord() (history.Record, bool) {
+	l.app.historyMutex.Lock()
+	defer l.app.historyMutex.Unlock()
+	if l.focus < 0 || l.focus >= len(l.app.historyRows) {
+		return history.Record{}, false
+	}
+	return l.app.historyRows[l.focus], true
+}
+
+func (l *historyList) FocusGained() {
+	l.List.FocusGained()
+	l.announce()
+}
+
+func (l *historyList) TypedKey(event *fyne.KeyEvent) {
+	switch event.Name {
+	case fyne.KeyReturn, fyne.KeyEnter:
+		l.showDetails()
+	case desktop.KeyMenu:
+		l.showActions()
+	case fyne.KeyDown:
+		l.List.TypedKey(event)
+		if l.focus < l.Length()-1 {
+			l.focus++
+			l.announce()
+		}
+	case fyne.KeyUp:
+		l.List.TypedKey(event)
+		if l.focus > 0 {
+			l.focus--
+			l.announce()
+		}
+	default:
+		l.List.TypedKey(event)
+	}
+}
+
+func (l *historyList) TypedShortcut(shortcut fyne.Shortcut) {
+	if shortcut.ShortcutName() == actionMenuShortcut.ShortcutName() {
+		l.showActions()
+	}
+}
+
+// 在状态栏显示焦点所在记录的位置和摘要
+func (l *historyList) announce() {
+	record, ok := l.focusedRecord()
+	if !ok {
+		return
+	}
+	l.app.updateStatus(i18n.Sprintf("第 %d/%d 条：%s", l.focus+1, l.Length(), historySummary(record, l.app.config.Location())))
+}
+
+// 焦点所在记录的详情
+func (l *historyList) showDetails() {
+	if record, ok := l.focusedRecord(); ok {
+		l.app.showRecordDetails(record, func() { l.app.window.Canvas().Focus(l) })
+	}
+}
+
+// 在列表左上角弹出焦点所在记录的操作菜单，菜单同样可以用方向键和 Enter 操作
+func (l *historyList) showActions() {
+	record, ok := l.focusedRecord()
+	if !ok {
+		return
+	}
+	refocus := func() { l.app.window.Canvas().Focus(l) }
+	menu := fyne.NewMenu("", l.app.recordMenuItems(record, refocus)...)
+	widget.ShowPopUpMenuAtRelativePosition(menu, l.app.window.Canvas(), fyne.NewPos(theme.Padding()*4, theme.Padding()*4), l)
+}
+
+// 一条记录可以执行的操作，done 在操作的对话框关闭或操作完成后调用
+func (b *BackupApp) recordMenuItems(record history.Record, done func()) []*fyne.MenuItem {
+	details := fyne.NewMenuItem(i18n.T("展开详情"), func() { b.showRecordDetails(record, done) })
+	export := fyne.NewMenuItem(i18n.T("导出快照"), func() { b.exportSnapshotDialog(record) })
+	verify := fyne.NewMenuItem(i18n.T("校验"), func() { b.verifySnapshotDialog(record) })
+	// 只有实际写入且未被清理的快照可以导出和校验
+	export.Disabled = !record.HasSnapshot()
+	verify.Disabled = !record.HasSnapshot()
+	exportChanges := fyne.NewMenuItem(i18n.T("导出变更清单"), func() { b.exportRecordChanges(record) })
+	exportChanges.Disabled = len(record.Changes) == 0
+	note := fyne.NewMenuItem(i18n.T("编辑备注"), func() { b.showNoteDialog(record) })
+	copySummary := fyne.NewMenuItem(i18n.T("复制摘要"), func() {
+		b.window.Clipboard().SetContent(historyDetails(record, b.config.Location()))
+		b.updateStatus(i18n.T("已复制备份记录摘要"))
+		done()
+	})
+	return []*fyne.MenuItem{details, export, verify, exportChanges, note, copySummary}
+}
+
+// 把一条记录中变化的文件导出为 CSV
+func (b *BackupApp) exportRecordChanges(record history.Record) {
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if err := history.WriteChangesCSV(writer, []history.Record{record}, b.config.Location()); err != nil {
+			dialog.ShowError(i18n.Errorf("导出变更清单失败: %v", err), b.window)
+			return
+		}
+		b.updateStatus(i18n.T("变更清单已导出"))
+	}, b.window)
+	saveDialog.SetFileName("changes-" + record.Timestamp.In(b.config.Location()).Format("2006-01-02_15-04-05") + ".csv")
+	saveDialog.Show()
+}
+
+// 显示一条记录的全部信息，包括完整的错误信息和所有校验问题
+func (b *BackupApp) showRecordDetails(record history.Record, onClosed func()) {
+	text := widget.NewLabel(historyDetails(record, b.config.Location()))
+	text.Wrapping = fyne.TextWrapWord
+
+	var buttons []fyne.CanvasObject
+	for _, item := range b.recordMenuItems(record, func() {})[1:] {
+		btn := widget.NewButton(item.Label, item.Action)
+		if item.Disabled {
+			btn.Disable()
+		}
+		buttons = append(buttons, btn)
+	}
+	var center fyne.CanvasObject = container.NewVScroll(text)
+	if len(record.Changes) > 0 {
+		center = container.NewAppTabs(
+			container.NewTabItem(i18n.T("概要"), center),
+			container.NewTabItem(i18n.Sprintf("变更的文件（%d）", len(record.Changes)+record.ChangesOmitted), changeList(record)),
+		)
+	}
+	content := container.NewBorder(nil, container.NewHBox(buttons...), nil, nil, center)
+	detailsDialog := dialog.NewCustom(i18n.T("备份详情"), i18n.T("关闭"), content, b.window)
+	detailsDialog.SetOnClosed(onClosed)
+	detailsDialog.Resize(fyne.NewSize(620, 480))
+	detailsDialog.Show()
+}
+
+// 记录中变化的文件列表，每行为操作、路径和大小
+func changeList(record history.Record) fyne.CanvasObject {
+	list := widget.NewList(
+		func() int { return len(record.Changes) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			change := record.Changes[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s  %s  %s", change.OpName(), change.Path, formatBytes(change.Size)))
+		},
+	)
+	if record.ChangesOmitted == 0 {
+		return list
+	}
+	omitted := widget.NewLabel(i18n.Sprintf("另有 %d 个变化没有记录，完整的文件列表见快照清单", record.ChangesOmitted))
+	return container.NewBorder(nil, omitted, nil, nil, list)
+}
+
+// 记录的状态
+func historyStatus(record history.Record) string {
+	switch {
+	case record.Cancelled:
+		return i18n.T("已取消")
+	case record.Success && record.DryRun:
+		return i18n.T("模拟（未写入）")
+	case record.Success:
+		return i18n.T("成功")
+	}
+	return i18n.T("失败")
+}
+
+// 一行的记录摘要：时间（按 loc 时区）、状态、源文件夹和文件变化
+func historySummary(record history.Record, loc *time.Location) string {
+	summary := i18n.Sprintf("%s %s，%s，共 %d 个文件，新增 %d、修改 %d、删除 %d",
+		record.FormatTime(loc), historyStatus(record), record.SourcePath,
+		record.FileCount, record.NewFiles, record.ModifiedFiles, record.DeletedFiles)
+	if record.Note != "" {
+		summary += i18n.T("，备注：") + record.Note
+	}
+	return summary
+}
+
+// 记录的完整文本，用于详情和复制
+func historyDetails(record history.Record, loc *time.Location) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, i18n.T("时间: %s\n"), record.FormatTime(loc))
+	fmt.Fprintf(&sb, i18n.T("状态: %s\n"), historyStatus(record))
+	if record.Attempt > 0 {
+		fmt.Fprintf(&sb, i18n.T("自动备份尝试: 第 %d 次\n"), record.Attempt)
+	}
+	fmt.Fprintf(&sb, i18n.T("源路径: %s\n"), record.SourcePath)
+	fmt.Fprintf(&sb, i18n.T("目标路径: %s\n"), record.DestPath)
+	if record.Pruned {
+		sb.WriteString(i18n.T("快照已清理\n"))
+	}
+	fmt.Fprintf(&sb, i18n.T("文件: 共 %d 个，%.2f MB\n"), record.FileCount, float64(record.TotalSize)/(1024*1024))
+	fmt.Fprintf(&sb, i18n.T("变更: 新增 %d、修改 %d、删除 %d\n"), record.NewFiles, record.ModifiedFiles, record.DeletedFiles)
+	if record.LinkedFiles > 0 {
+		fmt.Fprintf(&sb, i18n.T("硬链接: %d\n"), record.LinkedFiles)
+	}
+	if record.PooledFiles > 0 {
+		fmt.Fprintf(&sb, i18n.T("存储池中已有: %d\n"), record.PooledFiles)
+	}
+	if record.FailedFiles > 0 {
+		fmt.Fprintf(&sb, i18n.T("复制失败: %d\n"), record.FailedFiles)
+	}
+	if record.StreamFiles > 0 {
+		fmt.Fprintf(&sb, i18n.T("附加数据流: %d\n"), record.StreamFiles)
+	}
+	if record.StreamsLost > 0 {
+		fmt.Fprintf(&sb, i18n.T("附加数据流未备份: %d\n"), record.StreamsLost)
+	}
+	fmt.Fprintf(&sb, i18n.T("耗时: %v\n"), record.Duration.Round(time.Millisecond))
+	if record.ContentHash != "" {
+		fmt.Fprintf(&sb, i18n.T("内容哈希: %s\n"), record.ContentHash)
+	}
+	if record.Encrypted {
+		sb.WriteString(i18n.T("已加密\n"))
+	}
+	if record.Verified {
+		if record.VerifiedAt.IsZero() {
+			sb.WriteString(i18n.T("备份后校验通过\n"))
+		} else {
+			fmt.Fprintf(&sb, i18n.T("校验通过: %s\n"), record.VerifiedAt.In(loc).Format(history.TimeLayout))
+		}
+	}
+	if record.Note != "" {
+		fmt.Fprintf(&sb, i18n.T("备注: %s\n"), record.Note)
+	}
+	if record.ErrorMessage != "" {
+		fmt.Fprintf(&sb, i18n.T("\n错误信息:\n%s\n"), record.ErrorMessage)
+	}
+	if record.SizeAnomaly != "" {
+		fmt.Fprintf(&sb, i18n.T("\n大小异常: %s\n"), record.SizeAnomaly)
+	}
+	if len(record.Mismatches) > 0 {
+		fmt.Fprintf(&sb, i18n.T("\n校验发现 %d 个问题:\n%s\n"), len(record.Mismatches), strings.Join(record.Mismatches, "\n"))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 源文件夹中的收件箱目录，保存的剪贴板内容和截图随源文件夹一起备份
+const inboxDirName = "SyncSafe 收件箱"
+
+// 收件箱快捷键（窗口获得焦点时有效）
+var (
+	inboxClipboardShortcut  = &desktop.CustomShortcut{KeyName: fyne.KeyV, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+	inboxScreenshotShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+)
+
+// 创建收件箱目录并返回新文件的路径
+func (b *BackupApp) inboxFile(prefix, ext string) (string, error) {
+	if b.config.SourcePath == "" {
+		return "", i18n.Errorf("请先选择源文件夹")
+	}
+	dir := filepath.Join(b.engine.SourcePath(), inboxDirName)
+	if !b.engine.Simulated("创建目录 %s", dir) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", i18n.Errorf("创建收件箱失败: %v", err)
+		}
+	}
+	name := fmt.Sprintf("%s-%s%s", prefix, time.Now().Format("2006-01-02_15-04-05"), ext)
+	return filepath.Join(dir, name), nil
+}
+
+// 把剪贴板中的文本保存到收件箱并立即备份
+func (b *BackupApp) saveClipboardToInbox() {
+	content := b.window.Clipboard().Content()
+	if content == "" {
+		dialog.ShowError(i18n.Errorf("剪贴板中没有文本内容"), b.window)
+		return
+	}
+	path, err := b.inboxFile(i18n.T("剪贴板"), ".txt")
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	if !b.engine.Simulated("保存剪贴板到 %s", path) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			dialog.ShowError(i18n.Errorf("保存剪贴板失败: %v", err), b.window)
+			return
+		}
+	}
+	b.updateStatus(i18n.T("剪贴板已保存到收件箱: ") + filepath.Base(path))
+	go b.performBackup()
+}
+
+// 截取全屏保存到收件箱并立即备份
+func (b *BackupApp) saveScreenshotToInbox() {
+	path, err := b.inboxFile(i18n.T("截图"), ".png")
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	go func() {
+		if !b.engine.Simulated("截图保存到 %s", path) {
+			if err := b.takeScreenshot(path); err != nil {
+				dialog.ShowError(i18n.Errorf("截图失败: %v", err), b.window)
+				return
+			}
+		}
+		b.updateStatus(i18n.T("截图已保存到收件箱: ") + filepath.Base(path))
+		b.performBackup()
+	}()
+}
+
+// 调用系统自带的截图工具截取全屏
+func (b *BackupApp) takeScreenshot(path string) error {
+	dir := filepath.Dir(path)
+	switch runtime.GOOS {
+	case "windows":
+		script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms,System.Drawing;`+
+			`$s=[System.Windows.Forms.SystemInformation]::VirtualScreen;`+
+			`$bmp=New-Object System.Drawing.Bitmap $s.Width,$s.Height;`+
+			`$g=[System.Drawing.Graphics]::FromImage($bmp);`+
+			`$g.CopyFromScreen($s.Left,$s.Top,0,0,$bmp.Size);`+
+			`$bmp.Save('%s',[System.Drawing.Imaging.ImageFormat]::Png)`, strings.ReplaceAll(path, "'", "''"))
+		_, err := b.engine.Exec(dir, nil, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		return err
+	case "darwin":
+		_, err := b.engine.Exec(dir, nil, "screencapture", "-x", path)
+		return err
+	}
+
+	// Linux 上依次尝试常见的截图工具
+	tools := [][]string{
+		{"grim", path},
+		{"gnome-screenshot", "-f", path},
+		{"spectacle", "-b", "-n", "-f", "-o", path},
+		{"scrot", "--overwrite", path},
+		{"import", "-window", "root", path},
+	}
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		_, err := b.engine.Exec(dir, nil, tool[0], tool[1:]...)
+		return err
+	}
+	return i18n.Errorf("未找到可用的截图工具（grim、gnome-screenshot、spectacle、scrot 或 import）")
+}
+
+// 收件箱按钮：弹出保存剪贴板和截图的菜单
+func (b *BackupApp) createInboxButton() *widget.Button {
+	var btn *widget.Button
+	btn = widget.NewButtonWithIcon(i18n.T("收件箱"), theme.ContentPasteIcon(), func() {
+		menu := fyne.NewMenu("",
+			fyne.NewMenuItem(i18n.T("保存剪贴板 (Ctrl+Shift+V)"), b.saveClipboardToInbox),
+			fyne.NewMenuItem(i18n.T("保存截图 (Ctrl+Shift+S)"), b.saveScreenshotToInbox),
+		)
+		position := fyne.CurrentApp().Driver().AbsolutePositionForObject(btn)
+		widget.ShowPopUpMenuAtPosition(menu, b.window.Canvas(), position.Add(fyne.NewPos(0, btn.Size().Height)))
+	})
+	return btn
+}
+
+// 注册收件箱快捷键
+func (b *BackupApp) registerInboxShortcuts() {
+	canvas := b.window.Canvas()
+	canvas.AddShortcut(inboxClipboardShortcut, func(fyne.Shortcut) {
+		b.saveClipboardToInbox()
+	})
+	canvas.AddShortcut(inboxScreenshotShortcut, func(fyne.Shortcut) {
+		b.saveScreenshotToInbox()
+	})
+}
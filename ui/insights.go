@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 使用统计页：只根据本机的历史记录计算，不收集或上传任何数据。
+// 返回页面内容和刷新函数，切换到该页时刷新
+func (b *BackupApp) createInsightsTab() (fyne.CanvasObject, func()) {
+	frequency := widget.NewLabel("")
+	duration := widget.NewLabel("")
+	failures := widget.NewLabel("")
+	failures.Wrapping = fyne.TextWrapWord
+	growth := widget.NewLabel("")
+
+	refresh := func() {
+		in := history.ComputeInsights(b.config.History, time.Now())
+
+		frequency.SetText(i18n.Sprintf("自动备份 %d 次（平均每天 %.1f 次），手动备份 %d 次",
+			in.AutoBackups, in.AutoPerDay, in.ManualBackups))
+
+		if in.AverageDuration > 0 {
+			duration.SetText(in.AverageDuration.Round(time.Millisecond).String())
+		} else {
+			duration.SetText(i18n.T("暂无成功的备份"))
+		}
+
+		if in.Failures == 0 {
+			failures.SetText(i18n.T("没有失败的备份"))
+		} else {
+			lines := []string{i18n.Sprintf("共失败 %d 次，最常见的原因:", in.Failures)}
+			for _, cause := range in.FailureCauses {
+				lines = append(lines, i18n.Sprintf("%d 次  %s", cause.Count, cause.Cause))
+			}
+			failures.SetText(strings.Join(lines, "\n"))
+		}
+
+		lines := []string{i18n.Sprintf("现有快照共 %s，最近平均每天新增 %s",
+			formatBytes(in.StoredBytes), formatBytes(int64(in.BytesPerDay)))}
+		for i, size := range in.Projection {
+			month := time.Now().AddDate(0, i+1, 0).Format("2006-01")
+			lines = append(lines, i18n.Sprintf("%s  约 %s", month, formatBytes(size)))
+		}
+		growth.SetText(strings.Join(lines, "\n"))
+	}
+	refresh()
+
+	content := container.NewVScroll(container.NewVBox(
+		container.NewBorder(nil, nil,
+			widget.NewLabel(i18n.T("根据本机的历史记录统计，不收集或上传任何数据")),
+			widget.NewButtonWithIcon(i18n.T("刷新"), theme.ViewRefreshIcon(), refresh),
+		),
+		widget.NewForm(
+			widget.NewFormItem(i18n.T("最近 30 天"), frequency),
+			widget.NewFormItem(i18n.T("平均耗时"), duration),
+			widget.NewFormItem(i18n.T("失败原因"), failures),
+			widget.NewFormItem(i18n.T("存储增长预测"), growth),
+		),
+	))
+	return content, refresh
+}
+
+// 以合适的单位显示字节数
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.2f GB", float64(size)/(1024*1024*1024))
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+	default:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 导出布局的显示顺序，空字符串表示不自动导出
+var interopLayoutOrder = []string{"", engine.LayoutRsync, engine.LayoutSyncthing}
+
+func interopLayoutLabel(layout string) string {
+	if layout == "" {
+		return i18n.T("不导出")
+	}
+	return i18n.T(engine.LayoutLabels[layout])
+}
+
+// 导出给 rsync 或 Syncthing：每次备份后自动导出，也可以立即导出最新的快照
+func (b *BackupApp) showInteropDialog() {
+	options := make([]string, len(interopLayoutOrder))
+	for i, layout := range interopLayoutOrder {
+		options[i] = interopLayoutLabel(layout)
+	}
+	layoutSelect := widget.NewSelect(options, nil)
+	layoutSelect.SetSelected(interopLayoutLabel(b.config.InteropLayout))
+
+	targetEntry := widget.NewEntry()
+	targetEntry.SetText(b.config.InteropTarget)
+	browseBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err == nil && uri != nil {
+				targetEntry.SetText(uri.Path())
+			}
+		}, b.window)
+	})
+
+	selectedLayout := func() string {
+		for _, layout := range interopLayoutOrder {
+			if interopLayoutLabel(layout) == layoutSelect.Selected {
+				return layout
+			}
+		}
+		return ""
+	}
+
+	exportBtn := widget.NewButtonWithIcon(i18n.T("立即导出最新快照"), theme.UploadIcon(), func() {
+		layout := selectedLayout()
+		if layout == "" {
+			dialog.ShowError(i18n.Errorf("请先选择导出布局"), b.window)
+			return
+		}
+		record, ok := latestSnapshot(b.config.History)
+		if !ok {
+			dialog.ShowError(i18n.Errorf("还没有可以导出的快照"), b.window)
+			return
+		}
+		b.runInteropExport(record, strings.TrimSpace(targetEntry.Text), layout)
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("每次备份后"), Widget: layoutSelect,
+			HintText: i18n.T("rsync: 可作为 rsync --link-dest 的基准；Syncthing: 把目录添加为 Syncthing 共享文件夹")},
+		{Text: i18n.T("导出目录"), Widget: container.NewBorder(nil, nil, nil, browseBtn, targetEntry),
+			HintText: i18n.T("Syncthing 布局会删除快照中没有的文件，只能使用空目录或已有的 Syncthing 文件夹")},
+		{Text: "", Widget: exportBtn},
+	}
+	dialog.ShowForm(i18n.T("导出到同步工具"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		layout := selectedLayout()
+		target := strings.TrimSpace(targetEntry.Text)
+		if layout != "" && target == "" {
+			dialog.ShowError(i18n.Errorf("请先选择导出目录"), b.window)
+			return
+		}
+		b.config.InteropLayout = layout
+		b.config.InteropTarget = target
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("导出设置已保存"))
+	}, b.window)
+}
+
+// 最近一个仍然存在的快照
+func latestSnapshot(records []history.Record) (history.Record, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].HasSnapshot() {
+			return records[i], true
+		}
+	}
+	return history.Record{}, false
+}
+
+// 在后台导出快照并显示结果
+func (b *BackupApp) runInteropExport(record history.Record, target, layout string) {
+	b.updateStatus(i18n.T("正在导出快照..."))
+	go func() {
+		result, err := engine.ExportLayout(record.DestPath, target, layout)
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			b.updateStatus(i18n.T("导出失败"))
+			return
+		}
+		dialog.ShowInformation(i18n.T("导出完成"), i18n.Sprintf("快照已导出到 %s\n复制 %d 个文件，硬链接 %d 个，删除 %d 个",
+			result.Path, result.Copied, result.Linked, result.Removed), b.window)
+		b.updateStatus(i18n.T("快照已导出"))
+	}()
+}
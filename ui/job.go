@@ -0,0 +1,343 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+	"syncsafe/watcher"
+)
+
+// 一个备份任务的运行状态。每个任务有自己的引擎和监控，
+// 不在界面中显示的任务也可以继续监控和备份。
+//
+// 备份、历史记录、重试计时器和容量提醒只由任务自己的 goroutine（loop）修改，
+// 其他 goroutine 通过通道提交请求，手动备份和自动备份不会同时进行
+type job struct {
+	app              *BackupApp
+	config           *engine.Config
+	engine           *engine.Engine
+	watcher          *watcher.Watcher
+	retryTimer       *time.Timer
+	scheduleTimer    *time.Timer
+	nextScheduled    time.Time       // 下一次定时备份的时间，没有安排时为零值
+	scrubTimer       *time.Timer     // 定期数据巡检
+	gitTimer         *time.Timer     // 按计划批量提交 Git
+	capacityNotified int             // 已提醒过的最高容量阈值
+	healthKnown      map[string]bool // 上次健康检查发现的无法读取的路径
+	progress         engine.Progress // 当前或最近一次备份的进度
+	progressMutex    sync.Mutex
+	changes          pendingChanges // 监控到的变化，备份前提示中列出
+	prompt           *watchPrompt   // 显示中的备份前提示
+	promptMutex      sync.Mutex
+
+	requests chan backupRequest
+	finished chan backupResult
+	cancels  chan chan bool     // 取消进行中和排队的备份，回复是否有备份被取消
+	waits    chan chan struct{} // 任务空闲时关闭收到的通道
+	calls    chan func()        // 任务空闲时在 loop 中执行
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// 备份的触发方式
+type backupTrigger int
+
+const (
+	triggerManual backupTrigger = iota // 手动备份，出错时立即提示
+	triggerWatch                       // 监控到的文件变化已平静
+	triggerAuto                        // 定时备份或失败重试
+	triggerPower                       // 睡眠或关机前
+)
+
+type backupRequest struct {
+	trigger backupTrigger
+	attempt int        // 自动备份的第几次尝试，手动备份为 0
+	done    chan error // 不为 nil 时在备份结束后收到结果，需要有缓冲
+}
+
+// 把结果发送给等待的提交者
+func (r backupRequest) reply(err error) {
+	if r.done != nil {
+		r.done <- err
+	}
+}
+
+type backupResult struct {
+	request backupRequest
+	record  *history.Record
+	err     error
+}
+
+func (b *BackupApp) newJob(config *engine.Config) *job {
+	j := &job{
+		app:      b,
+		config:   config,
+		requests: make(chan backupRequest),
+		finished: make(chan backupResult, 1),
+		cancels:  make(chan chan bool),
+		waits:    make(chan chan struct{}),
+		calls:    make(chan func()),
+		stop:     make(chan struct{}),
+	}
+	j.engine = engine.New(config, engine.Hooks{
+		Status: j.status,
+		Output: b.appendOutput,
+		Progress: func(ev engine.Event) {
+			b.handleProgress(j, ev)
+		},
+	})
+	go j.loop()
+	return j
+}
+
+// 任务的状态机：同一时间只执行一个备份，其余请求排队。
+// 备份进行中时推迟 do 提交的修改，引擎读取的历史记录不会被同时修改
+func (j *job) loop() {
+	var (
+		queue      []backupRequest
+		cancel     context.CancelFunc // 进行中的备份，空闲时为 nil
+		lastBackup time.Time
+		calls      []func()
+		waiters    []chan struct{}
+	)
+	// 空闲时先执行推迟的修改，再开始队列中的下一个备份
+	next := func() {
+		for cancel == nil {
+			for _, call := range calls {
+				call()
+			}
+			calls = nil
+			if len(queue) == 0 {
+				for _, w := range waiters {
+					close(w)
+				}
+				waiters = nil
+				return
+			}
+			req := queue[0]
+			queue = queue[1:]
+			cancel = j.start(req)
+		}
+	}
+
+	for {
+		select {
+		case req := <-j.requests:
+			// 自动暂停期间只执行手动备份
+			if j.config.AutoPaused && req.trigger != triggerManual {
+				j.status(i18n.T("任务已自动暂停，跳过本次自动备份"))
+				req.reply(errAutoPaused)
+				continue
+			}
+			// 刚刚备份过，监控到的变化已经包含在内
+			if req.trigger == triggerWatch && cancel == nil && time.Since(lastBackup) < j.app.watchDebounce() {
+				continue
+			}
+			if queued(queue, req) {
+				j.status(i18n.T("已有备份正在等待中..."))
+				continue
+			}
+			if cancel != nil {
+				j.status(i18n.T("已有备份正在进行中，完成后开始下一次备份"))
+			}
+			queue = append(queue, req)
+			next()
+		case result := <-j.finished:
+			cancel()
+			cancel = nil
+			lastBackup = time.Now()
+			j.finish(result)
+			next()
+		case reply := <-j.cancels:
+			reply <- cancel != nil || len(queue) > 0
+			for _, req := range queue {
+				req.reply(engine.ErrCancelled)
+			}
+			queue = nil
+			if cancel != nil {
+				cancel()
+			}
+		case done := <-j.waits:
+			waiters = append(waiters, done)
+			next()
+		case call := <-j.calls:
+			calls = append(calls, call)
+			next()
+		case <-j.stop:
+			if cancel != nil {
+				cancel()
+			}
+			if j.retryTimer != nil {
+				j.retryTimer.Stop()
+			}
+			for _, req := range queue {
+				req.reply(engine.ErrCancelled)
+			}
+			for _, w := range waiters {
+				close(w)
+			}
+			return
+		}
+	}
+}
+
+// 队列中是否已有同样的请求。需要结果的请求不合并
+func queued(queue []backupRequest, req backupRequest) bool {
+	if req.done != nil {
+		return false
+	}
+	for _, other := range queue {
+		if other.done == nil && other.trigger == req.trigger && other.attempt == req.attempt {
+			return true
+		}
+	}
+	return false
+}
+
+// 在新的 goroutine 中执行备份，结束后把结果发送给 loop
+func (j *job) start(req backupRequest) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.app.trayBackupStarted(j)
+	go func() {
+		record, err := j.engine.Backup(ctx, req.attempt)
+		j.finished <- backupResult{request: req, record: record, err: err}
+	}()
+	return cancel
+}
+
+// 在 loop 中处理备份结果：记录历史，手动备份的失败立即提示，自动备份的失败安排重试
+func (j *job) finish(result backupResult) {
+	err := j.recordBackup(result.record, result.err)
+	j.app.trayBackupFinished(j, err)
+	if j.current() {
+		go j.app.refreshSourceStats()
+	}
+	result.request.reply(err)
+	// 备份成功说明导致暂停的问题已经解决
+	if result.err == nil {
+		j.resumeAutoPaused()
+	}
+
+	switch result.request.trigger {
+	case triggerManual:
+		if err != nil {
+			if !j.pauseIfFailing(err) {
+				j.notifyFailure(notify.LevelError, i18n.T("备份失败"), err)
+				j.emailFailure(err, 0)
+			}
+			j.alertBackupError(err)
+		}
+	case triggerWatch, triggerAuto:
+		j.handleAutoResult(result.request.attempt, err)
+	}
+}
+
+// 把备份记录加入历史并发送完成通知，返回需要提示的错误
+func (j *job) recordBackup(record *history.Record, err error) error {
+	// 取消的备份记录到历史，但不提示失败也不重试
+	if errors.Is(err, engine.ErrCancelled) {
+		if record != nil {
+			j.addBackupRecord(*record)
+		}
+		return nil
+	}
+	if record == nil {
+		slog.Error("备份失败", "source", j.config.SourcePath, "err", err)
+		return err
+	}
+
+	j.addBackupRecord(*record)
+	if err != nil {
+		slog.Error("备份失败", "source", record.SourcePath, "err", err)
+		return &backupRecordedError{Err: err}
+	}
+	slog.Info("备份完成", "source", record.SourcePath, "dest", record.DestPath, "files", record.FileCount,
+		"new", record.NewFiles, "modified", record.ModifiedFiles, "deleted", record.DeletedFiles, "dryRun", record.DryRun)
+	if !record.DryRun {
+		j.engine.Notify(notify.LevelInfo, i18n.T("备份完成"), i18n.Sprintf("%s\n共 %d 个文件，新增 %d、修改 %d、删除 %d",
+			record.SourcePath, record.FileCount, record.NewFiles, record.ModifiedFiles, record.DeletedFiles))
+		if record.SizeAnomaly != "" {
+			j.alertSizeAnomaly(*record)
+		}
+		j.applyRetention()
+		j.checkDestinationCapacity()
+	}
+	return nil
+}
+
+// 提交一次备份，由 loop 按顺序执行
+func (j *job) requestBackup(req backupRequest) {
+	select {
+	case j.requests <- req:
+	case <-j.stop:
+		req.reply(engine.ErrCancelled)
+	}
+}
+
+// 手动备份，出错时立即提示
+func (j *job) performBackup() {
+	j.requestBackup(backupRequest{trigger: triggerManual})
+}
+
+// 执行一次备份并等待结束，返回备份的错误
+func (j *job) backupAndWait(trigger backupTrigger) error {
+	done := make(chan error, 1)
+	j.requestBackup(backupRequest{trigger: trigger, done: done})
+	return <-done
+}
+
+// 取消进行中和排队的备份，返回是否有备份被取消
+func (j *job) cancelBackups() bool {
+	reply := make(chan bool, 1)
+	select {
+	case j.cancels <- reply:
+		return <-reply
+	case <-j.stop:
+		return false
+	}
+}
+
+// 等待进行中和排队的备份全部结束
+func (j *job) wait() {
+	done := make(chan struct{})
+	select {
+	case j.waits <- done:
+		<-done
+	case <-j.stop:
+	}
+}
+
+// 在 loop 中执行 fn，备份进行中时推迟到备份结束。用于修改历史记录等备份期间不能改动的状态
+func (j *job) do(fn func()) {
+	select {
+	case j.calls <- fn:
+	case <-j.stop:
+	}
+}
+
+// 停止任务：取消进行中的备份和待重试的备份，之后的请求不再执行
+func (j *job) close() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+}
+
+// 是否为界面中当前显示的任务
+func (j *job) current() bool {
+	return j.app.job == j
+}
+
+// 显示任务的状态，后台任务的状态带上任务名称
+func (j *job) status(message string) {
+	if !j.current() {
+		message = "[" + j.config.ProfileName() + "] " + message
+	}
+	j.app.updateStatus(message)
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 在用户的 applications 目录写入 syncsafe.desktop，桌面环境在程序图标的右键菜单中显示快捷操作
+func registerQuickActions(actions []quickAction) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dir := filepath.Join(dataHome, "applications")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return i18n.Errorf("创建 applications 目录失败: %v", err)
+	}
+
+	var sb strings.Builder
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = action.name
+	}
+	fmt.Fprintf(&sb, "[Desktop Entry]\nType=Application\nName=SyncSafe\nComment=%s\nExec=%s\nTerminal=false\nCategories=Utility;\nActions=%s;\n",
+		i18n.T("文件备份"), desktopExec(exe), strings.Join(names, ";"))
+	for _, action := range actions {
+		fmt.Fprintf(&sb, "\n[Desktop Action %s]\nName=%s\nExec=%s %s %s\n", action.name, i18n.T(action.label), desktopExec(exe), QuickActionFlag, action.name)
+	}
+
+	// 内容不变时不重写，避免桌面环境每次启动都重新扫描
+	path := filepath.Join(dir, "syncsafe.desktop")
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, []byte(sb.String())) {
+		return nil
+	}
+	return storage.WriteFileAtomic(path, []byte(sb.String()), 0644)
+}
+
+// 按 Desktop Entry 规范给 Exec 中的程序路径加引号和转义
+func desktopExec(path string) string {
+	replacer := strings.NewReplacer(`\`, `\\\\`, `"`, `\\"`, "`", "\\\\`", "$", `\\$`, "%", "%%")
+	return `"` + replacer.Replace(path) + `"`
+}
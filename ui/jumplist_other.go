@@ -0,0 +1,14 @@
+//go:build !windows && !linux
+
+package ui
+
+import (
+	"fmt"
+
+	"syncsafe/i18n"
+)
+
+// macOS 的程序坞菜单由 GLFW 的应用代理管理，无法追加菜单项，同样的操作在托盘菜单中
+func registerQuickActions(actions []quickAction) error {
+	return i18n.Errorf("当前系统不支持程序坞快捷操作")
+}
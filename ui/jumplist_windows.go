@@ -0,0 +1,218 @@
+//go:build windows
+
+package ui
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"syncsafe/i18n"
+)
+
+var (
+	modole32             = syscall.NewLazyDLL("ole32.dll")
+	procCoInitializeEx   = modole32.NewProc("CoInitializeEx")
+	procCoUninitialize   = modole32.NewProc("CoUninitialize")
+	procCoCreateInstance = modole32.NewProc("CoCreateInstance")
+)
+
+const (
+	coinitApartmentThreaded = 0x2
+	clsctxInprocServer      = 0x1
+	rpcEChangedMode         = 0x80010106
+	vtLPWStr                = 31
+)
+
+type guid struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+type propertyKey struct {
+	fmtid guid
+	pid   uint32
+}
+
+// 只用到字符串值的 PROPVARIANT，大小与系统定义相同
+type propVariant struct {
+	vt       uint16
+	reserved [3]uint16
+	val      uintptr
+	pad      uintptr
+}
+
+var (
+	clsidDestinationList            = guid{0x77f10cf0, 0x3db5, 0x4966, [8]byte{0xb5, 0x20, 0xb7, 0xc5, 0x4f, 0xd3, 0x5e, 0xd6}}
+	iidCustomDestinationList        = guid{0x6332debf, 0x87b5, 0x4670, [8]byte{0x90, 0xc0, 0x5e, 0x57, 0xb4, 0x08, 0xa4, 0x9e}}
+	clsidEnumerableObjectCollection = guid{0x2d3468c1, 0x36a7, 0x43b6, [8]byte{0xac, 0x24, 0xd3, 0xf0, 0x2f, 0xd9, 0x60, 0x7a}}
+	iidObjectCollection             = guid{0x5632b1a4, 0xe38a, 0x400a, [8]byte{0x92, 0x8a, 0xd4, 0xcd, 0x63, 0x23, 0x02, 0x95}}
+	iidObjectArray                  = guid{0x92ca9dcd, 0x5622, 0x4bba, [8]byte{0xa8, 0x05, 0x5e, 0x9f, 0x54, 0x1b, 0xd8, 0xc9}}
+	clsidShellLink                  = guid{0x00021401, 0x0000, 0x0000, [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidShellLinkW                   = guid{0x000214f9, 0x0000, 0x0000, [8]byte{0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}}
+	iidPropertyStore                = guid{0x886d8eeb, 0x8cf2, 0x4446, [8]byte{0x8d, 0x02, 0xcd, 0xba, 0x1d, 0xbd, 0xcf, 0x99}}
+	pkeyTitle                       = propertyKey{guid{0xf29f85e0, 0x4ff9, 0x1068, [8]byte{0xab, 0x91, 0x08, 0x00, 0x2b, 0x27, 0xb3, 0xd9}}, 2}
+)
+
+// COM 接口指针，第一个字段指向虚函数表
+type comObject struct {
+	vtbl *[32]uintptr
+}
+
+// 虚函数表中用到的方法序号
+const (
+	methodQueryInterface = 0
+	methodRelease        = 2
+
+	destListBeginList    = 4 // ICustomDestinationList
+	destListAddUserTasks = 7
+	destListCommitList   = 8
+
+	collectionAddObject = 5 // IObjectCollection
+
+	shellLinkSetDescription  = 7 // IShellLinkW
+	shellLinkSetArguments    = 11
+	shellLinkSetIconLocation = 17
+	shellLinkSetPath         = 20
+
+	propStoreSetValue = 6 // IPropertyStore
+	propStoreCommit   = 7
+)
+
+func (o *comObject) call(method int, args ...uintptr) error {
+	hr, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(hr) < 0 {
+		return i18n.Errorf("COM 调用失败: 0x%08x", uint32(hr))
+	}
+	return nil
+}
+
+func (o *comObject) release() {
+	if o != nil {
+		syscall.SyscallN(o.vtbl[methodRelease], uintptr(unsafe.Pointer(o)))
+	}
+}
+
+func (o *comObject) queryInterface(iid *guid) (*comObject, error) {
+	var out *comObject
+	err := o.call(methodQueryInterface, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out)))
+	return out, err
+}
+
+func createInstance(clsid, iid *guid) (*comObject, error) {
+	var out *comObject
+	hr, _, _ := procCoCreateInstance.Call(uintptr(unsafe.Pointer(clsid)), 0, clsctxInprocServer,
+		uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out)))
+	if int32(hr) < 0 {
+		return nil, i18n.Errorf("创建 COM 对象失败: 0x%08x", uint32(hr))
+	}
+	return out, nil
+}
+
+// 转换为 UTF-16 字符串，keep 保存指针，调用结束前不会被回收
+func utf16Ptr(keep *[]*uint16, s string) uintptr {
+	p, _ := syscall.UTF16PtrFromString(s)
+	*keep = append(*keep, p)
+	return uintptr(unsafe.Pointer(p))
+}
+
+// 把快捷操作注册为任务栏跳转列表中的「任务」，每个任务以 --action 参数启动本程序
+func registerQuickActions(actions []quickAction) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// COM 对象只能在初始化 COM 的线程上使用
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	hr, _, _ := procCoInitializeEx.Call(0, coinitApartmentThreaded)
+	if int32(hr) >= 0 {
+		defer procCoUninitialize.Call()
+	} else if uint32(hr) != rpcEChangedMode {
+		return i18n.Errorf("初始化 COM 失败: 0x%08x", uint32(hr))
+	}
+
+	list, err := createInstance(&clsidDestinationList, &iidCustomDestinationList)
+	if err != nil {
+		return err
+	}
+	defer list.release()
+	var slots uint32
+	var removed *comObject
+	if err := list.call(destListBeginList, uintptr(unsafe.Pointer(&slots)), uintptr(unsafe.Pointer(&iidObjectArray)), uintptr(unsafe.Pointer(&removed))); err != nil {
+		return err
+	}
+	defer removed.release()
+
+	tasks, err := createInstance(&clsidEnumerableObjectCollection, &iidObjectCollection)
+	if err != nil {
+		return err
+	}
+	defer tasks.release()
+	for _, action := range actions {
+		link, err := newTaskLink(exe, action)
+		if err != nil {
+			return err
+		}
+		err = tasks.call(collectionAddObject, uintptr(unsafe.Pointer(link)))
+		link.release()
+		if err != nil {
+			return err
+		}
+	}
+
+	array, err := tasks.queryInterface(&iidObjectArray)
+	if err != nil {
+		return err
+	}
+	defer array.release()
+	if err := list.call(destListAddUserTasks, uintptr(unsafe.Pointer(array))); err != nil {
+		return err
+	}
+	return list.call(destListCommitList)
+}
+
+// 创建启动快捷操作的快捷方式，跳转列表显示的名称来自 PKEY_Title
+func newTaskLink(exe string, action quickAction) (*comObject, error) {
+	link, err := createInstance(&clsidShellLink, &iidShellLinkW)
+	if err != nil {
+		return nil, err
+	}
+	var strs []*uint16
+	defer runtime.KeepAlive(strs)
+	for _, step := range []struct {
+		method int
+		args   []uintptr
+	}{
+		{shellLinkSetPath, []uintptr{utf16Ptr(&strs, exe)}},
+		{shellLinkSetArguments, []uintptr{utf16Ptr(&strs, QuickActionFlag+" "+action.name)}},
+		{shellLinkSetIconLocation, []uintptr{utf16Ptr(&strs, exe), 0}},
+		{shellLinkSetDescription, []uintptr{utf16Ptr(&strs, i18n.T(action.label))}},
+	} {
+		if err := link.call(step.method, step.args...); err != nil {
+			link.release()
+			return nil, err
+		}
+	}
+
+	store, err := link.queryInterface(&iidPropertyStore)
+	if err != nil {
+		link.release()
+		return nil, err
+	}
+	defer store.release()
+	value := propVariant{vt: vtLPWStr, val: utf16Ptr(&strs, i18n.T(action.label))}
+	err = store.call(propStoreSetValue, uintptr(unsafe.Pointer(&pkeyTitle)), uintptr(unsafe.Pointer(&value)))
+	if err == nil {
+		err = store.call(propStoreCommit)
+	}
+	if err != nil {
+		link.release()
+		return nil, err
+	}
+	return link, nil
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/logging"
+)
+
+// 日志页最多显示的条数，只显示最新的
+const logViewLimit = 2000
+
+// 级别选项，按从低到高排列
+var logLevelOptions = []struct {
+	label string
+	level slog.Level
+	name  string // 程序设置中的名称
+}{
+	{"调试", slog.LevelDebug, "debug"},
+	{"信息", slog.LevelInfo, "info"},
+	{"警告", slog.LevelWarn, "warn"},
+	{"错误", slog.LevelError, "error"},
+}
+
+// 显示名称对应的记录级别名称
+func logLevelName(label string) string {
+	for _, option := range logLevelOptions {
+		if i18n.T(option.label) == label {
+			return option.name
+		}
+	}
+	return ""
+}
+
+// 日志页：按级别和关键字筛选程序日志，并设置记录级别。
+// 返回页面内容和刷新函数，切换到该页时刷新
+func (b *BackupApp) createLogsTab() (fyne.CanvasObject, func()) {
+	var entries []logging.Entry
+	filter := logging.Filter{MinLevel: slog.LevelInfo}
+	summary := widget.NewLabel("")
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			// 最新的日志显示在最上面
+			entry := entries[len(entries)-1-id]
+			text := fmt.Sprintf("%s  [%s]  %s", entry.Time.Local().Format("2006-01-02 15:04:05"),
+				logging.LevelName(entry.Level), entry.Message)
+			if entry.Attrs != "" {
+				text += "  " + entry.Attrs
+			}
+			obj.(*widget.Label).SetText(text)
+		},
+	)
+
+	refresh := func() {
+		var err error
+		entries, err = logging.Read(engine.LogDir(), filter, logViewLimit)
+		if err != nil {
+			summary.SetText(err.Error())
+		} else if len(entries) == 0 {
+			summary.SetText(i18n.T("没有符合条件的日志"))
+		} else {
+			summary.SetText(i18n.Sprintf("共 %d 条日志（最多显示最新的 %d 条）", len(entries), logViewLimit))
+		}
+		list.Refresh()
+	}
+
+	labels := make([]string, len(logLevelOptions))
+	for i, option := range logLevelOptions {
+		labels[i] = i18n.T(option.label)
+	}
+
+	// 显示不低于所选级别的日志
+	filterSelect := widget.NewSelect(labels, func(selected string) {
+		for _, option := range logLevelOptions {
+			if i18n.T(option.label) == selected {
+				filter.MinLevel = option.level
+			}
+		}
+		refresh()
+	})
+	filterSelect.SetSelected(logging.LevelName(filter.MinLevel))
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder(i18n.T("搜索消息或字段"))
+	search.OnChanged = func(text string) {
+		filter.Search = text
+		refresh()
+	}
+
+	// 记录级别保存在程序设置中，修改后立即生效
+	preferences := b.profiles.Preferences()
+	levelSelect := widget.NewSelect(labels, nil)
+	levelSelect.SetSelected(logging.LevelName(logging.ParseLevel(preferences.LogLevel)))
+	levelSelect.OnChanged = func(selected string) {
+		preferences.LogLevel = logLevelName(selected)
+		if err := b.profiles.SavePreferences(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		logging.SetLevel(preferences.LogLevel)
+		b.updateStatus(i18n.T("日志记录级别已设置为") + selected)
+	}
+
+	refresh()
+	toolbar := container.NewBorder(nil, nil,
+		container.NewHBox(widget.NewLabel(i18n.T("显示")), filterSelect),
+		container.NewHBox(
+			widget.NewLabel(i18n.T("记录级别")), levelSelect,
+			widget.NewButtonWithIcon(i18n.T("刷新"), theme.ViewRefreshIcon(), refresh),
+		),
+		search,
+	)
+	// 记录级别可能已在设置中修改，切换到该页时重新显示
+	return container.NewBorder(container.NewVBox(toolbar, summary), nil, nil, nil, list), func() {
+		levelSelect.Selected = logging.LevelName(logging.ParseLevel(preferences.LogLevel))
+		levelSelect.Refresh()
+		refresh()
+	}
+}
@@ -0,0 +1,413 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 推送服务的显示名称
+var notifyServiceLabels = map[string]string{
+	notify.ServiceNtfy:   "ntfy",
+	notify.ServiceGotify: "Gotify",
+}
+
+// 通知级别的显示顺序
+var notifyLevelOrder = []notify.Level{notify.LevelInfo, notify.LevelWarning, notify.LevelError}
+
+// 手机推送设置：ntfy 或 Gotify，按严重程度过滤
+func (b *BackupApp) showNotifyDialog() {
+	enabledCheck := widget.NewCheck("", nil)
+	enabledCheck.SetChecked(b.config.Notify.Enabled)
+
+	serviceSelect := widget.NewSelect([]string{notifyServiceLabels[notify.ServiceNtfy], notifyServiceLabels[notify.ServiceGotify]}, nil)
+	service := b.config.Notify.Service
+	if service == "" {
+		service = notify.ServiceNtfy
+	}
+	serviceSelect.SetSelected(notifyServiceLabels[service])
+
+	serverEntry := widget.NewEntry()
+	serverEntry.SetPlaceHolder(notify.DefaultNtfyServer)
+	serverEntry.SetText(b.config.Notify.Server)
+	topicEntry := widget.NewEntry()
+	topicEntry.SetText(b.config.Notify.Topic)
+	tokenEntry := widget.NewPasswordEntry()
+	tokenEntry.SetText(b.config.Notify.Token)
+
+	levelOptions := make([]string, len(notifyLevelOrder))
+	for i, level := range notifyLevelOrder {
+		levelOptions[i] = i18n.T(notify.LevelLabels[level])
+	}
+	levelSelect := widget.NewSelect(levelOptions, nil)
+	levelSelect.SetSelected(i18n.T(notify.LevelLabels[b.config.Notify.MinLevel]))
+
+	// 从输入框读取当前设置
+	current := func() notify.Config {
+		config := notify.Config{
+			Enabled: enabledCheck.Checked,
+			Server:  serverEntry.Text,
+			Topic:   topicEntry.Text,
+			Token:   tokenEntry.Text,
+		}
+		for service, label := range notifyServiceLabels {
+			if label == serviceSelect.Selected {
+				config.Service = service
+			}
+		}
+		for _, level := range notifyLevelOrder {
+			if i18n.T(notify.LevelLabels[level]) == levelSelect.Selected {
+				config.MinLevel = level
+			}
+		}
+		return config
+	}
+
+	testBtn := widget.NewButtonWithIcon(i18n.T("发送测试通知"), theme.MailSendIcon(), func() {
+		config := current()
+		b.updateStatus(i18n.T("正在发送测试通知..."))
+		go func() {
+			if err := notify.Send(config, notify.LevelInfo, i18n.T("SyncSafe 测试通知"), i18n.T("推送设置正常")); err != nil {
+				b.updateStatus(err.Error())
+				return
+			}
+			b.updateStatus(i18n.T("测试通知已发送"))
+		}()
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("启用推送"), Widget: enabledCheck},
+		{Text: i18n.T("推送服务"), Widget: serviceSelect},
+		{Text: i18n.T("服务器地址"), Widget: serverEntry, HintText: i18n.T("ntfy 留空时使用 ") + notify.DefaultNtfyServer},
+		{Text: i18n.T("ntfy 主题"), Widget: topicEntry, HintText: i18n.T("Gotify 不需要填写")},
+		{Text: i18n.T("访问令牌"), Widget: tokenEntry, HintText: i18n.T("ntfy 访问令牌（可选）或 Gotify 应用令牌，只保存在本机")},
+		{Text: i18n.T("推送级别"), Widget: levelSelect},
+		{Text: "", Widget: testBtn},
+	}
+	dialog.ShowForm(i18n.T("手机推送"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		b.config.Notify = current()
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("推送设置已保存"))
+	}, b.window)
+}
+
+// 备份失败时推送通知
+func (j *job) notifyFailure(level notify.Level, title string, err error) {
+	j.engine.Notify(level, title, fmt.Sprintf("%s\n%v", j.config.SourcePath, err))
+}
+
+// 备份最终失败时发送邮件。已记录到历史的失败使用最新的记录，
+// 在开始复制前就失败的备份只包含错误信息。只在 loop 中调用
+func (j *job) emailFailure(err error, attempt int) {
+	record := history.Record{
+		Timestamp:    time.Now(),
+		SourcePath:   j.config.SourcePath,
+		ErrorMessage: err.Error(),
+		Attempt:      attempt,
+	}
+	var recorded *backupRecordedError
+	if n := len(j.config.History); n > 0 && errors.As(err, &recorded) {
+		record = j.config.History[n-1]
+	}
+	j.engine.EmailFailure(record)
+}
+
+// 邮件加密方式的显示名称
+var emailSecurityLabels = map[string]string{
+	notify.SecurityStartTLS: "STARTTLS",
+	notify.SecurityTLS:      "SSL/TLS",
+	notify.SecurityNone:     "不加密",
+}
+
+// 邮件加密方式的显示顺序
+var emailSecurityOrder = []string{notify.SecurityStartTLS, notify.SecurityTLS, notify.SecurityNone}
+
+// 邮件通知设置：备份最终失败时通过 SMTP 发送失败摘要，适合无人值守的电脑
+func (b *BackupApp) showEmailDialog() {
+	config := b.config.Email
+	enabledCheck := widget.NewCheck("", nil)
+	enabledCheck.SetChecked(config.Enabled)
+	hostEntry := widget.NewEntry()
+	hostEntry.SetPlaceHolder("smtp.example.com")
+	hostEntry.SetText(config.Host)
+	portEntry := widget.NewEntry()
+	if config.Port > 0 {
+		portEntry.SetText(strconv.Itoa(config.Port))
+	}
+
+	securityOptions := make([]string, len(emailSecurityOrder))
+	for i, security := range emailSecurityOrder {
+		securityOptions[i] = i18n.T(emailSecurityLabels[security])
+	}
+	securitySelect := widget.NewSelect(securityOptions, func(label string) {
+		for _, security := range emailSecurityOrder {
+			if i18n.T(emailSecurityLabels[security]) == label {
+				portEntry.SetPlaceHolder(strconv.Itoa(notify.DefaultPorts[security]))
+			}
+		}
+	})
+	security := config.Security
+	if security == "" {
+		security = notify.SecurityStartTLS
+	}
+	securitySelect.SetSelected(i18n.T(emailSecurityLabels[security]))
+
+	userEntry := widget.NewEntry()
+	userEntry.SetText(config.Username)
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetText(config.Password)
+	fromEntry := widget.NewEntry()
+	fromEntry.SetPlaceHolder(i18n.T("与用户名相同"))
+	fromEntry.SetText(config.From)
+	toEntry := widget.NewEntry()
+	toEntry.SetPlaceHolder("admin@example.com, ops@example.com")
+	toEntry.SetText(strings.Join(config.Recipients, ", "))
+
+	// 从输入框读取当前设置
+	current := func() (notify.EmailConfig, error) {
+		config := notify.EmailConfig{
+			Enabled:  enabledCheck.Checked,
+			Host:     strings.TrimSpace(hostEntry.Text),
+			Username: strings.TrimSpace(userEntry.Text),
+			Password: passwordEntry.Text,
+			From:     strings.TrimSpace(fromEntry.Text),
+		}
+		for _, security := range emailSecurityOrder {
+			if i18n.T(emailSecurityLabels[security]) == securitySelect.Selected {
+				config.Security = security
+			}
+		}
+		if text := strings.TrimSpace(portEntry.Text); text != "" {
+			port, err := strconv.Atoi(text)
+			if err != nil || port <= 0 || port > 65535 {
+				return config, i18n.Errorf("端口必须是 1-65535 之间的整数: %s", text)
+			}
+			config.Port = port
+		}
+		config.Recipients = strings.FieldsFunc(toEntry.Text, func(r rune) bool { return r == ',' || r == ';' || r == ' ' })
+		return config, nil
+	}
+
+	testBtn := widget.NewButtonWithIcon(i18n.T("发送测试邮件"), theme.MailSendIcon(), func() {
+		config, err := current()
+		if err == nil {
+			err = config.Validate()
+		}
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("正在发送测试邮件..."))
+		go func() {
+			if err := notify.SendEmail(config, i18n.T("[SyncSafe] 测试邮件"), i18n.T("邮件通知设置正常，备份失败时会发送到这个地址。")); err != nil {
+				b.updateStatus(err.Error())
+				return
+			}
+			b.updateStatus(i18n.T("测试邮件已发送"))
+		}()
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("备份失败时发送邮件"), Widget: enabledCheck},
+		{Text: i18n.T("SMTP 服务器"), Widget: hostEntry},
+		{Text: i18n.T("加密方式"), Widget: securitySelect},
+		{Text: i18n.T("端口"), Widget: portEntry, HintText: i18n.T("留空时使用加密方式的默认端口")},
+		{Text: i18n.T("用户名"), Widget: userEntry, HintText: i18n.T("服务器不需要登录时留空")},
+		{Text: i18n.T("密码"), Widget: passwordEntry, HintText: i18n.T("通常为邮箱的授权码，只保存在本机")},
+		{Text: i18n.T("发件人"), Widget: fromEntry},
+		{Text: i18n.T("收件人"), Widget: toEntry, HintText: i18n.T("多个地址用逗号分隔")},
+		{Text: "", Widget: testBtn},
+	}
+	dialog.ShowForm(i18n.T("邮件通知"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		config, err := current()
+		if err == nil && config.Enabled {
+			err = config.Validate()
+		}
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.Email = config
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("邮件通知设置已保存"))
+	}, b.window)
+}
+
+// Webhook 格式的显示名称
+var webhookFormatLabels = map[string]string{
+	notify.WebhookJSON:    "JSON",
+	notify.WebhookSlack:   "Slack",
+	notify.WebhookDiscord: "Discord",
+}
+
+// Webhook 格式的显示顺序
+var webhookFormatOrder = []string{notify.WebhookJSON, notify.WebhookSlack, notify.WebhookDiscord}
+
+func webhookFormatLabel(format string) string {
+	if format == "" {
+		format = notify.WebhookJSON
+	}
+	return webhookFormatLabels[format]
+}
+
+// Webhook 设置：每次备份后把备份摘要 POST 到列表中的地址，发送失败的稍后自动重试
+func (b *BackupApp) showWebhookDialog() {
+	var list *widget.List
+	list = widget.NewList(
+		func() int { return len(b.config.Webhooks) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, nil,
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+				label)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			hook := b.config.Webhooks[id]
+			text := fmt.Sprintf("[%s] %s", webhookFormatLabel(hook.Format), hook.URL)
+			if hook.FailuresOnly {
+				text += i18n.T("（仅失败）")
+			}
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(text)
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				b.config.Webhooks = append(b.config.Webhooks[:id], b.config.Webhooks[id+1:]...)
+				list.Refresh()
+			}
+		},
+	)
+
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://hooks.slack.com/services/...")
+	formatOptions := make([]string, len(webhookFormatOrder))
+	for i, format := range webhookFormatOrder {
+		formatOptions[i] = webhookFormatLabels[format]
+	}
+	formatSelect := widget.NewSelect(formatOptions, nil)
+	formatSelect.SetSelected(webhookFormatLabels[notify.WebhookJSON])
+	failuresCheck := widget.NewCheck(i18n.T("只发送失败的备份"), nil)
+
+	// 从输入框读取要添加的 Webhook
+	current := func() (notify.Webhook, error) {
+		hook := notify.Webhook{URL: strings.TrimSpace(urlEntry.Text), FailuresOnly: failuresCheck.Checked}
+		for _, format := range webhookFormatOrder {
+			if webhookFormatLabels[format] == formatSelect.Selected {
+				hook.Format = format
+			}
+		}
+		return hook, hook.Validate()
+	}
+
+	addBtn := widget.NewButtonWithIcon(i18n.T("添加"), theme.ContentAddIcon(), func() {
+		hook, err := current()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.Webhooks = append(b.config.Webhooks, hook)
+		urlEntry.SetText("")
+		failuresCheck.SetChecked(false)
+		list.Refresh()
+	})
+
+	testBtn := widget.NewButtonWithIcon(i18n.T("发送测试"), theme.MailSendIcon(), func() {
+		hook, err := current()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		ev := notify.WebhookEvent{
+			Event:       notify.EventSucceeded,
+			Profile:     b.config.ProfileName(),
+			Source:      b.config.SourcePath,
+			Destination: b.config.DestinationPath,
+			Time:        time.Now(),
+			Success:     true,
+			Note:        i18n.T("SyncSafe 测试消息"),
+		}
+		b.updateStatus(i18n.T("正在发送测试 Webhook..."))
+		go func() {
+			payload, err := notify.WebhookPayload(hook, ev)
+			if err == nil {
+				err = notify.PostWebhook(hook.URL, payload)
+			}
+			if err != nil {
+				b.updateStatus(err.Error())
+				return
+			}
+			b.updateStatus(i18n.T("测试 Webhook 已发送"))
+		}()
+	})
+
+	pendingLabel := widget.NewLabel("")
+	retryBtn := widget.NewButtonWithIcon(i18n.T("立即重试"), theme.ViewRefreshIcon(), nil)
+	refreshPending := func() {
+		if pending := engine.PendingWebhooks(); pending > 0 {
+			pendingLabel.SetText(i18n.Sprintf("有 %d 条发送失败的 Webhook 等待重试", pending))
+			retryBtn.Show()
+		} else {
+			pendingLabel.SetText("")
+			retryBtn.Hide()
+		}
+	}
+	retryBtn.OnTapped = func() {
+		retryBtn.Disable()
+		go func() {
+			sent, pending := engine.RetryWebhooks()
+			b.updateStatus(i18n.Sprintf("已重新发送 %d 条 Webhook，%d 条仍在等待", sent, pending))
+			retryBtn.Enable()
+			refreshPending()
+		}()
+	}
+	refreshPending()
+
+	form := widget.NewForm(
+		widget.NewFormItem(i18n.T("地址"), urlEntry),
+		widget.NewFormItem(i18n.T("格式"), formatSelect),
+		widget.NewFormItem("", failuresCheck),
+	)
+	content := container.NewBorder(
+		widget.NewLabel(i18n.T("每次备份后把备份摘要发送到这些地址。地址中通常含有密钥，只保存在本机。")),
+		container.NewVBox(
+			widget.NewSeparator(),
+			form,
+			container.NewHBox(addBtn, testBtn),
+			container.NewHBox(pendingLabel, retryBtn),
+		),
+		nil, nil,
+		list,
+	)
+	webhookDialog := dialog.NewCustom("Webhook", i18n.T("关闭"), content, b.window)
+	webhookDialog.SetOnClosed(func() {
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+		}
+	})
+	webhookDialog.Resize(fyne.NewSize(620, 480))
+	webhookDialog.Show()
+}
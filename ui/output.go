@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 输出面板最多保留的行数
+const outputPanelMaxLines = 2000
+
+// 外部命令输出面板：实时显示 Git 等命令的标准输出和标准错误
+type OutputPanel struct {
+	mu     sync.Mutex
+	lines  []string
+	grid   *widget.TextGrid
+	scroll *container.Scroll
+}
+
+func newOutputPanel() *OutputPanel {
+	p := &OutputPanel{grid: widget.NewTextGrid()}
+	p.scroll = container.NewVScroll(p.grid)
+	p.scroll.SetMinSize(fyne.NewSize(0, 160))
+	return p
+}
+
+// 追加一行输出并滚动到底部
+func (p *OutputPanel) Append(line string) {
+	p.mu.Lock()
+	p.lines = append(p.lines, line)
+	if len(p.lines) > outputPanelMaxLines {
+		p.lines = p.lines[len(p.lines)-outputPanelMaxLines:]
+	}
+	text := strings.Join(p.lines, "\n")
+	p.mu.Unlock()
+
+	p.grid.SetText(text)
+	p.scroll.ScrollToBottom()
+}
+
+// 面板中的全部文本
+func (p *OutputPanel) Text() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return strings.Join(p.lines, "\n")
+}
+
+// 清空输出
+func (p *OutputPanel) Clear() {
+	p.mu.Lock()
+	p.lines = nil
+	p.mu.Unlock()
+	p.grid.SetText("")
+}
+
+// 创建可展开的输出面板，带复制和清空按钮
+func (b *BackupApp) createOutputPanel() fyne.CanvasObject {
+	if b.output == nil {
+		b.output = newOutputPanel()
+	}
+
+	copyBtn := widget.NewButtonWithIcon(i18n.T("复制"), theme.ContentCopyIcon(), func() {
+		b.window.Clipboard().SetContent(b.output.Text())
+		b.updateStatus(i18n.T("已复制命令输出"))
+	})
+	clearBtn := widget.NewButtonWithIcon(i18n.T("清空"), theme.ContentClearIcon(), func() {
+		b.output.Clear()
+	})
+
+	content := container.NewBorder(
+		container.NewHBox(copyBtn, clearBtn),
+		nil, nil, nil,
+		b.output.scroll,
+	)
+	return widget.NewAccordion(widget.NewAccordionItem(i18n.T("命令输出"), content))
+}
+
+// 追加一行到输出面板，面板尚未创建时写入日志
+func (b *BackupApp) appendOutput(line string) {
+	if b.output != nil {
+		b.output.Append(line)
+	} else {
+		slog.Info("命令输出", "line", line)
+	}
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/peer"
+)
+
+// 按本机的接收端设置开始或停止接收快照
+func (b *BackupApp) startReceiver() {
+	if b.receiver != nil {
+		b.receiver.Close()
+		b.receiver = nil
+	}
+	settings, err := engine.LoadReceiverSettings()
+	if err != nil {
+		b.updateStatus(err.Error())
+		return
+	}
+	if !settings.Enabled {
+		return
+	}
+	receiver, err := engine.StartReceiver(settings, func(received peer.Received) {
+		slog.Info("已收到快照", "machine", received.Machine, "snapshot", received.Snapshot, "files", received.Files, "path", received.Path)
+		b.updateStatus(i18n.Sprintf("已收到 %s 的快照，共 %d 个文件", received.Machine, received.Files))
+	}, func(remote string, err error) {
+		slog.Warn("接收快照失败", "remote", remote, "err", err)
+		b.updateStatus(i18n.Sprintf("接收 %s 的快照失败: %v", remote, err))
+	})
+	if err != nil {
+		b.updateStatus(i18n.T("启动局域网接收失败: ") + err.Error())
+		return
+	}
+	b.receiver = receiver
+}
+
+// 局域网同步设置：推送到另一台设备，或作为接收端
+func (b *BackupApp) showPeerDialog() {
+	// 推送
+	pushCheck := widget.NewCheck("", nil)
+	pushCheck.SetChecked(b.config.Peer.Enabled)
+	addressEntry := widget.NewEntry()
+	addressEntry.SetPlaceHolder(fmt.Sprintf("192.168.1.20:%d", peer.DefaultPort))
+	addressEntry.SetText(b.config.Peer.Address)
+	codeEntry := widget.NewPasswordEntry()
+	codeEntry.SetPlaceHolder("1234-5678-ABCD-EFGH-IJKL-MNOP")
+	codeEntry.SetText(b.config.Peer.Code)
+	fingerprint := b.config.Peer.Fingerprint
+	fingerprintLabel := widget.NewLabel(shortFingerprint(fingerprint))
+	repairBtn := widget.NewButtonWithIcon(i18n.T("重新配对"), theme.ViewRefreshIcon(), func() {
+		fingerprint = ""
+		fingerprintLabel.SetText(shortFingerprint(fingerprint))
+	})
+	pushForm := widget.NewForm(
+		&widget.FormItem{Text: i18n.T("备份后推送"), Widget: pushCheck},
+		&widget.FormItem{Text: i18n.T("接收端地址"), Widget: addressEntry, HintText: i18n.T("接收端设置中显示的地址")},
+		&widget.FormItem{Text: i18n.T("配对码"), Widget: codeEntry, HintText: i18n.T("接收端显示的完整配对码，只保存在本机")},
+		&widget.FormItem{Text: i18n.T("接收端指纹"), Widget: container.NewHBox(fingerprintLabel, repairBtn),
+			HintText: i18n.T("首次推送时记录，接收端更换证书后需要重新配对")},
+	)
+
+	// 接收
+	settings, err := engine.LoadReceiverSettings()
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	if settings.Code == "" {
+		settings.Code = peer.NewPairingCode()
+	}
+	// 配对码包含证书指纹，没有证书时先生成
+	cert, err := peer.LoadCertificate(engine.PeerDir())
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	receiverFingerprint := peer.CertificateFingerprint(cert)
+	receiveCheck := widget.NewCheck("", nil)
+	receiveCheck.SetChecked(settings.Enabled)
+	dirEntry := widget.NewEntry()
+	dirEntry.SetText(settings.Dir)
+	dirBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err == nil && uri != nil {
+				dirEntry.SetText(uri.Path())
+			}
+		}, b.window)
+	})
+	portEntry := widget.NewEntry()
+	portEntry.SetText(strconv.Itoa(settings.Port))
+	code := settings.Code
+	codeLabel := widget.NewLabelWithStyle(peer.PairingCode(code, receiverFingerprint), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true, Bold: true})
+	newCodeBtn := widget.NewButtonWithIcon(i18n.T("重新生成"), theme.ViewRefreshIcon(), func() {
+		code = peer.NewPairingCode()
+		codeLabel.SetText(peer.PairingCode(code, receiverFingerprint))
+	})
+	addresses := peer.LocalAddresses(settings.Port)
+	if len(addresses) == 0 {
+		addresses = []string{i18n.T("未找到局域网地址")}
+	}
+	receiveForm := widget.NewForm(
+		&widget.FormItem{Text: i18n.T("接收快照"), Widget: receiveCheck},
+		&widget.FormItem{Text: i18n.T("保存到"), Widget: container.NewBorder(nil, nil, nil, dirBtn, dirEntry),
+			HintText: i18n.T("按发送端的机器名称分子目录保存")},
+		&widget.FormItem{Text: i18n.T("端口"), Widget: portEntry},
+		&widget.FormItem{Text: i18n.T("配对码"), Widget: container.NewHBox(codeLabel, newCodeBtn), HintText: i18n.T("在发送端完整输入，重新生成后原来的配对码失效")},
+		&widget.FormItem{Text: i18n.T("本机地址"), Widget: widget.NewLabel(strings.Join(addresses, "\n"))},
+		&widget.FormItem{Text: i18n.T("证书指纹"), Widget: widget.NewLabel(shortFingerprint(receiverFingerprint)), HintText: i18n.T("可与发送端记录的指纹核对")},
+	)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem(i18n.T("推送到其他设备"), pushForm),
+		container.NewTabItem(i18n.T("作为接收端"), receiveForm),
+	)
+	d := dialog.NewCustomConfirm(i18n.T("局域网同步"), i18n.T("保存"), i18n.T("取消"), tabs, func(ok bool) {
+		if !ok {
+			return
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(portEntry.Text))
+		if err != nil || port <= 0 || port > 65535 {
+			dialog.ShowError(i18n.Errorf("端口必须是 1 到 65535 之间的数字"), b.window)
+			return
+		}
+		if receiveCheck.Checked && strings.TrimSpace(dirEntry.Text) == "" {
+			dialog.ShowError(i18n.Errorf("请先选择接收目录"), b.window)
+			return
+		}
+
+		b.config.Peer = peer.Config{
+			Enabled:     pushCheck.Checked,
+			Address:     strings.TrimSpace(addressEntry.Text),
+			Code:        strings.TrimSpace(codeEntry.Text),
+			Fingerprint: fingerprint,
+		}
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+
+		settings = peer.ReceiverSettings{
+			Enabled: receiveCheck.Checked,
+			Dir:     strings.TrimSpace(dirEntry.Text),
+			Port:    port,
+			Code:    code,
+		}
+		if err := settings.Save(engine.PeerDir()); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.startReceiver()
+		b.updateStatus(i18n.T("局域网同步设置已保存"))
+	}, b.window)
+	d.Resize(fyne.NewSize(560, 420))
+	d.Show()
+}
+
+// 指纹太长，只显示前 16 位
+func shortFingerprint(fp string) string {
+	if fp == "" {
+		return i18n.T("尚未配对")
+	}
+	if len(fp) > 16 {
+		return fp[:16] + "…"
+	}
+	return fp
+}
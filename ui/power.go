@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"syncsafe/i18n"
+)
+
+// 关机或睡眠前的操作
+const (
+	powerActionNone    = ""        // 不处理
+	powerActionPending = "pending" // 仅备份等待防抖的更改
+	powerActionBackup  = "backup"  // 执行一次快速备份
+)
+
+// 下拉框中显示的选项
+var powerActionLabels = map[string]string{
+	powerActionNone:    "不处理",
+	powerActionPending: "备份待处理的更改",
+	powerActionBackup:  "执行快速备份",
+}
+
+var powerActionOrder = []string{powerActionNone, powerActionPending, powerActionBackup}
+
+// 系统电源事件
+type powerEvent int
+
+const (
+	powerSleep powerEvent = iota
+	powerShutdown
+)
+
+func (e powerEvent) String() string {
+	if e == powerShutdown {
+		return i18n.T("关机")
+	}
+	return i18n.T("睡眠")
+}
+
+// 注册系统电源事件，平台不支持时只处理终止信号
+func (b *BackupApp) startPowerMonitor() {
+	if err := watchPowerEvents(b.handlePowerEvent); err != nil {
+		slog.Warn("无法监听系统电源事件", "err", err)
+	}
+
+	// 注销或关机时系统会先向进程发送 SIGTERM
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	go func() {
+		<-signals
+		b.handlePowerEvent(powerShutdown)
+		fyne.CurrentApp().Quit()
+	}()
+}
+
+// 在睡眠或关机前依次处理所有任务，返回前平台实现会一直推迟睡眠/关机
+func (b *BackupApp) handlePowerEvent(event powerEvent) {
+	for _, j := range b.jobs {
+		j.handlePowerEvent(event)
+	}
+}
+
+// 在睡眠或关机前执行任务设置的操作
+func (j *job) handlePowerEvent(event powerEvent) {
+	action := j.config.PowerAction
+	if action == powerActionNone {
+		return
+	}
+	// 暂停时段内同样不执行自动备份，待处理的更改留到时段结束
+	if _, ok := j.config.ActiveBlackout(time.Now()); ok {
+		return
+	}
+
+	// 等待正在进行的备份完成
+	j.wait()
+
+	pending := j.watcher != nil && j.watcher.CancelPending()
+	if action == powerActionPending && !pending {
+		return
+	}
+	if j.config.SourcePath == "" || j.config.DestinationPath == "" {
+		return
+	}
+
+	slog.Info("电源事件前开始备份", "event", event.String())
+	j.status(i18n.Sprintf("即将%s，正在备份...", event))
+	if err := j.backupAndWait(triggerPower); err != nil {
+		slog.Warn("电源事件前备份失败", "event", event.String(), "err", err)
+	}
+
+	// 关机前保存索引，下次启动时可以从日志位置继续
+	if idx := j.engine.LoadedIndex(); event == powerShutdown && idx != nil {
+		idx.MarkJournal()
+		if err := idx.Save(); err != nil {
+			slog.Warn("保存索引失败", "err", err)
+		}
+	}
+}
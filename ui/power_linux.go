@@ -0,0 +1,84 @@
+//go:build linux
+
+package ui
+
+import (
+	"bufio"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"syncsafe/i18n"
+)
+
+// 通过 systemd-logind 的延迟锁推迟睡眠/关机：持有锁期间系统会等待
+// （最长 InhibitDelayMaxSec），处理完成后释放
+type logindDelayLock struct {
+	cmd *exec.Cmd
+}
+
+func acquireDelayLock() *logindDelayLock {
+	cmd := exec.Command("systemd-inhibit",
+		"--what=sleep:shutdown", "--mode=delay",
+		"--who=SyncSafe", "--why="+i18n.T("关机或睡眠前完成备份"),
+		"sleep", "infinity")
+	// 放入独立进程组，释放时连同 sleep 子进程一起结束
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		slog.Warn("获取 systemd 延迟锁失败", "err", err)
+		return nil
+	}
+	return &logindDelayLock{cmd: cmd}
+}
+
+func (l *logindDelayLock) Release() {
+	if l == nil {
+		return
+	}
+	syscall.Kill(-l.cmd.Process.Pid, syscall.SIGKILL)
+	l.cmd.Wait()
+}
+
+// 监听 logind 的 PrepareForSleep / PrepareForShutdown 信号
+func watchPowerEvents(handler func(powerEvent)) error {
+	if _, err := exec.LookPath("gdbus"); err != nil {
+		return i18n.Errorf("未找到 gdbus: %v", err)
+	}
+	cmd := exec.Command("gdbus", "monitor", "--system",
+		"--dest", "org.freedesktop.login1",
+		"--object-path", "/org/freedesktop/login1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return i18n.Errorf("启动 gdbus 失败: %v", err)
+	}
+
+	lock := acquireDelayLock()
+	go func() {
+		defer cmd.Wait()
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.Contains(line, "PrepareForSleep (true,)"):
+				handler(powerSleep)
+				lock.Release()
+				lock = nil
+			case strings.Contains(line, "PrepareForSleep (false,)"):
+				// 唤醒后重新获取锁，为下一次睡眠做准备
+				if lock == nil {
+					lock = acquireDelayLock()
+				}
+			case strings.Contains(line, "PrepareForShutdown (true,)"):
+				handler(powerShutdown)
+				lock.Release()
+				lock = nil
+			}
+		}
+		lock.Release()
+	}()
+	return nil
+}
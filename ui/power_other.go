@@ -0,0 +1,14 @@
+//go:build !windows && !linux
+
+package ui
+
+import (
+	"fmt"
+
+	"syncsafe/i18n"
+)
+
+// 其他平台暂不支持电源事件，关机时只能依靠 SIGTERM
+func watchPowerEvents(handler func(powerEvent)) error {
+	return i18n.Errorf("当前平台不支持电源事件")
+}
@@ -0,0 +1,117 @@
+//go:build windows
+
+package ui
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"syncsafe/i18n"
+)
+
+var (
+	moduser32                      = syscall.NewLazyDLL("user32.dll")
+	modkernel32                    = syscall.NewLazyDLL("kernel32.dll")
+	procRegisterClassExW           = moduser32.NewProc("RegisterClassExW")
+	procCreateWindowExW            = moduser32.NewProc("CreateWindowExW")
+	procDefWindowProcW             = moduser32.NewProc("DefWindowProcW")
+	procGetMessageW                = moduser32.NewProc("GetMessageW")
+	procTranslateMessage           = moduser32.NewProc("TranslateMessage")
+	procDispatchMessageW           = moduser32.NewProc("DispatchMessageW")
+	procShutdownBlockReasonCreate  = moduser32.NewProc("ShutdownBlockReasonCreate")
+	procShutdownBlockReasonDestroy = moduser32.NewProc("ShutdownBlockReasonDestroy")
+	procGetModuleHandleW           = modkernel32.NewProc("GetModuleHandleW")
+)
+
+const (
+	wmQueryEndSession = 0x0011
+	wmPowerBroadcast  = 0x0218
+	pbtAPMSuspend     = 0x0004
+)
+
+type wndClassEx struct {
+	Size       uint32
+	Style      uint32
+	WndProc    uintptr
+	ClsExtra   int32
+	WndExtra   int32
+	Instance   syscall.Handle
+	Icon       syscall.Handle
+	Cursor     syscall.Handle
+	Background syscall.Handle
+	MenuName   *uint16
+	ClassName  *uint16
+	IconSm     syscall.Handle
+}
+
+type winMessage struct {
+	Hwnd    syscall.Handle
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	X, Y    int32
+}
+
+// 创建一个隐藏的顶层窗口接收 WM_POWERBROADCAST 和 WM_QUERYENDSESSION。
+// 仅消息窗口 (HWND_MESSAGE) 收不到广播消息，所以这里使用不显示的普通窗口
+func watchPowerEvents(handler func(powerEvent)) error {
+	ready := make(chan error, 1)
+	go func() {
+		// 窗口消息只会投递到创建窗口的线程
+		runtime.LockOSThread()
+
+		wndProc := syscall.NewCallback(func(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr {
+			switch msg {
+			case wmPowerBroadcast:
+				if wParam == pbtAPMSuspend {
+					handler(powerSleep)
+				}
+				return 1
+			case wmQueryEndSession:
+				// 备份期间在关机界面中显示原因，阻止系统直接结束进程
+				reason, _ := syscall.UTF16PtrFromString(i18n.T("SyncSafe 正在完成备份"))
+				procShutdownBlockReasonCreate.Call(uintptr(hwnd), uintptr(unsafe.Pointer(reason)))
+				handler(powerShutdown)
+				procShutdownBlockReasonDestroy.Call(uintptr(hwnd))
+				return 1
+			}
+			ret, _, _ := procDefWindowProcW.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+			return ret
+		})
+
+		instance, _, _ := procGetModuleHandleW.Call(0)
+		className, _ := syscall.UTF16PtrFromString("SyncSafePowerMonitor")
+		class := wndClassEx{
+			WndProc:   wndProc,
+			Instance:  syscall.Handle(instance),
+			ClassName: className,
+		}
+		class.Size = uint32(unsafe.Sizeof(class))
+		if atom, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&class))); atom == 0 {
+			ready <- i18n.Errorf("注册窗口类失败: %v", err)
+			return
+		}
+
+		hwnd, _, err := procCreateWindowExW.Call(0,
+			uintptr(unsafe.Pointer(className)), uintptr(unsafe.Pointer(className)),
+			0, 0, 0, 0, 0, 0, 0, instance, 0)
+		if hwnd == 0 {
+			ready <- i18n.Errorf("创建窗口失败: %v", err)
+			return
+		}
+		ready <- nil
+
+		var msg winMessage
+		for {
+			ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(ret) <= 0 {
+				return
+			}
+			procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+	return <-ready
+}
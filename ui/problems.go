@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 修改该设置的对话框，没有单独对话框的设置返回 nil。对话框修改的是当前任务
+func (b *BackupApp) settingDialog(setting engine.Setting) func() {
+	switch setting {
+	case engine.SettingSource:
+		return b.chooseSourceFolder
+	case engine.SettingDestination:
+		return b.chooseDestinationFolder
+	case engine.SettingWebDAV:
+		return b.showWebDAVDialog
+	case engine.SettingS3:
+		return b.showS3Dialog
+	case engine.SettingEncryption:
+		return b.showEncryptionDialog
+	case engine.SettingSync:
+		return b.showTwoWayDialog
+	case engine.SettingFilter:
+		return b.showFileFilterDialog
+	case engine.SettingSchedule:
+		return b.showScheduleDialog
+	case engine.SettingGit:
+		return b.showGitConfigDialog
+	case engine.SettingNotify:
+		return b.showNotifyDialog
+	case engine.SettingEmail:
+		return b.showEmailDialog
+	case engine.SettingWebhooks:
+		return b.showWebhookDialog
+	case engine.SettingPeer:
+		return b.showPeerDialog
+	case engine.SettingInterop:
+		return b.showInteropDialog
+	case engine.SettingReplicas:
+		return b.showReplicaDialog
+	}
+	return nil
+}
+
+// 启动时检查所有任务的配置，有问题时列出。还没有选择任何文件夹的任务不检查
+func (b *BackupApp) checkConfigs() {
+	var jobs []*job
+	for _, j := range b.jobs {
+		if j.config.SourcePath != "" || j.config.DestinationPath != "" {
+			jobs = append(jobs, j)
+		}
+	}
+	b.showConfigProblems(jobs, false)
+}
+
+// 在一个面板中按任务列出配置问题，每个问题可以直接打开对应的设置。
+// 没有问题时 always 为 true 才提示
+func (b *BackupApp) showConfigProblems(jobs []*job, always bool) {
+	list := container.NewVBox()
+	var problemsDialog dialog.Dialog
+	count := 0
+	for _, j := range jobs {
+		problems := j.config.Validate()
+		if len(problems) == 0 {
+			continue
+		}
+		count += len(problems)
+		if len(b.jobs) > 1 {
+			list.Add(widget.NewLabelWithStyle(j.config.ProfileName(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		}
+		for _, problem := range problems {
+			var icon fyne.Resource = theme.NewWarningThemedResource(theme.WarningIcon())
+			if problem.Blocking {
+				icon = theme.NewErrorThemedResource(theme.ErrorIcon())
+			}
+			text := widget.NewLabel(problem.String())
+			text.Wrapping = fyne.TextWrapWord
+			var action fyne.CanvasObject
+			if open := b.settingDialog(problem.Setting); open != nil {
+				action = widget.NewButton(i18n.T("修改"), func() {
+					problemsDialog.Hide()
+					b.selectJob(j)
+					open()
+				})
+			}
+			list.Add(container.NewBorder(nil, nil, widget.NewIcon(icon), action, text))
+		}
+	}
+	if count == 0 {
+		if always {
+			dialog.ShowInformation(i18n.T("配置检查"), i18n.T("没有发现配置问题"), b.window)
+		}
+		return
+	}
+
+	hint := widget.NewLabel(i18n.T("红色标记的问题会阻止备份，其他问题只会使对应的功能不生效。"))
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(hint, nil, nil, nil, container.NewVScroll(list))
+	problemsDialog = dialog.NewCustom(i18n.Sprintf("配置问题（%d）", count), i18n.T("关闭"), content, b.window)
+	problemsDialog.Resize(fyne.NewSize(560, 420))
+	problemsDialog.Show()
+}
@@ -0,0 +1,251 @@
+package ui
+
+import (
+	"log/slog"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 切换界面中显示的任务，其他任务的监控不受影响
+func (b *BackupApp) selectJob(j *job) {
+	if b.job == j {
+		return
+	}
+	b.job = j
+	b.profiles.Active = j.config.ProfileName()
+	if err := b.profiles.SaveActive(); err != nil {
+		slog.Warn("保存当前任务失败", "err", err)
+	}
+
+	// 历史记录和各项设置都属于任务，重建界面
+	b.historyFilter = ""
+	b.historySearch = ""
+	b.createUI()
+	b.applyAppearance()
+	b.setWatchButton(j.config.IsWatching)
+	b.updateStatus(i18n.T("已切换到任务: ") + j.config.ProfileName())
+}
+
+// 按名称查找任务
+func (b *BackupApp) jobNamed(name string) *job {
+	for _, j := range b.jobs {
+		if j.config.ProfileName() == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// 按任务在 profiles.List 中的位置把任务插入 b.jobs，保持两者顺序一致
+func (b *BackupApp) insertJob(j *job) {
+	i := 0
+	for _, config := range b.profiles.List {
+		if config == j.config {
+			break
+		}
+		if !config.Archived {
+			i++
+		}
+	}
+	b.jobs = slices.Insert(b.jobs, min(i, len(b.jobs)), j)
+}
+
+// 任务选择栏：切换、新建、重命名和删除任务
+func (b *BackupApp) createProfileBar() fyne.CanvasObject {
+	profileSelect := widget.NewSelect(b.profiles.Names(), nil)
+	profileSelect.SetSelected(b.config.ProfileName())
+	profileSelect.OnChanged = func(name string) {
+		if j := b.jobNamed(name); j != nil {
+			b.selectJob(j)
+		}
+	}
+
+	addBtn := widget.NewButtonWithIcon(i18n.T("新建任务"), theme.ContentAddIcon(), func() {
+		b.showProfileNameDialog(i18n.T("新建任务"), "", func(name string) error {
+			config, err := b.profiles.Add(name)
+			if err != nil {
+				return err
+			}
+			j := b.newJob(config)
+			b.jobs = append(b.jobs, j)
+			b.selectJob(j)
+			return nil
+		})
+	})
+
+	renameBtn := widget.NewButtonWithIcon(i18n.T("重命名"), theme.DocumentCreateIcon(), func() {
+		j := b.job
+		b.showProfileNameDialog(i18n.T("重命名任务"), j.config.ProfileName(), func(name string) error {
+			if err := b.profiles.Rename(j.config, name); err != nil {
+				return err
+			}
+			b.createUI()
+			b.applyAppearance()
+			b.setWatchButton(j.config.IsWatching)
+			return nil
+		})
+	})
+
+	deleteBtn := widget.NewButtonWithIcon(i18n.T("删除"), theme.DeleteIcon(), func() {
+		b.confirmRemoveJob(b.job)
+	})
+	archiveBtn := widget.NewButtonWithIcon(i18n.T("归档"), theme.StorageIcon(), func() {
+		b.confirmArchiveJob(b.job)
+	})
+	archivedBtn := widget.NewButtonWithIcon(i18n.Sprintf("已归档（%d）", len(b.profiles.ArchivedList())), theme.FolderIcon(), func() {
+		b.showArchivedProfiles()
+	})
+	if len(b.profiles.ArchivedList()) == 0 {
+		archivedBtn.Hide()
+	}
+
+	// 搜索所有任务
+	searchBtn := widget.NewButtonWithIcon(i18n.T("全局搜索"), theme.SearchIcon(), func() {
+		b.showGlobalSearch()
+	})
+	if b.config.IsDefaultProfile() {
+		deleteBtn.Disable()
+		archiveBtn.Disable()
+	}
+
+	return container.NewBorder(nil, nil,
+		widget.NewLabel(i18n.T("备份任务:")),
+		container.NewHBox(addBtn, renameBtn, archiveBtn, deleteBtn, archivedBtn, searchBtn),
+		profileSelect,
+	)
+}
+
+// 输入任务名称，callback 返回错误时保持对话框内容并提示
+func (b *BackupApp) showProfileNameDialog(title, name string, callback func(string) error) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(name)
+	nameEntry.SetPlaceHolder(i18n.T("例如：工作文档"))
+	items := []*widget.FormItem{
+		{Text: i18n.T("任务名称"), Widget: nameEntry},
+	}
+	dialog.ShowForm(title, i18n.T("确定"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := callback(nameEntry.Text); err != nil {
+			dialog.ShowError(err, b.window)
+		}
+	}, b.window)
+}
+
+// 确认后停止任务的监控并删除任务，快照保留在目标文件夹中
+func (b *BackupApp) confirmRemoveJob(j *job) {
+	message := i18n.Sprintf("是否删除任务 %s？\n任务的设置和历史记录将被删除，已有的快照不会删除。", j.config.ProfileName())
+	dialog.ShowConfirm(i18n.T("删除任务"), message, func(ok bool) {
+		if !ok {
+			return
+		}
+		b.stopJob(j)
+		if err := b.profiles.Remove(j.config); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.selectJob(b.jobNamed(b.profiles.Active))
+	}, b.window)
+}
+
+// 停止任务的监控、定时备份和重试，从界面中移除
+func (b *BackupApp) stopJob(j *job) {
+	if j.watcher != nil {
+		j.stopWatching()
+	}
+	j.stopSchedule()
+	j.stopScrubSchedule()
+	j.stopGitSchedule()
+	j.close()
+	for i, other := range b.jobs {
+		if other == j {
+			b.jobs = append(b.jobs[:i], b.jobs[i+1:]...)
+			break
+		}
+	}
+	b.trayRemoveJob(j)
+}
+
+// 确认后归档任务：停止监控和定时备份，从任务列表中隐藏，设置、历史记录和快照都保留
+func (b *BackupApp) confirmArchiveJob(j *job) {
+	message := i18n.Sprintf("是否归档任务 %s？\n归档后停止监控和定时备份，任务不再显示在列表中；设置、历史记录和快照都会保留，可以在「已归档」中随时恢复。", j.config.ProfileName())
+	dialog.ShowConfirm(i18n.T("归档任务"), message, func(ok bool) {
+		if !ok {
+			return
+		}
+		// 先保存归档状态，保存失败时任务继续运行
+		if err := b.profiles.Archive(j.config); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.stopJob(j)
+		b.selectJob(b.jobNamed(b.profiles.Active))
+		b.updateStatus(i18n.Sprintf("任务 %s 已归档", j.config.ProfileName()))
+	}, b.window)
+}
+
+// 已归档的任务：恢复后重新出现在任务列表中并启动定时备份，也可以彻底删除
+func (b *BackupApp) showArchivedProfiles() {
+	list := container.NewVBox()
+	var archivedDialog dialog.Dialog
+	var rebuild func()
+	rebuild = func() {
+		list.RemoveAll()
+		archived := b.profiles.ArchivedList()
+		if len(archived) == 0 {
+			list.Add(widget.NewLabel(i18n.T("没有已归档的任务")))
+		}
+		for _, config := range archived {
+			label := widget.NewLabel(i18n.Sprintf("%s\n%s → %s，%d 条历史记录",
+				config.ProfileName(), config.SourcePath, config.DestinationPath, len(config.History)))
+			label.Wrapping = fyne.TextWrapWord
+			restoreBtn := widget.NewButtonWithIcon(i18n.T("恢复"), theme.ViewRefreshIcon(), func() {
+				if err := b.profiles.Unarchive(config); err != nil {
+					dialog.ShowError(err, b.window)
+					return
+				}
+				j := b.newJob(config)
+				b.insertJob(j)
+				j.startSchedule()
+				j.startScrubSchedule()
+				j.startGitSchedule()
+				go j.checkScheduledTask()
+				archivedDialog.Hide()
+				b.selectJob(j)
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				message := i18n.Sprintf("是否删除任务 %s？\n任务的设置和历史记录将被删除，已有的快照不会删除。", config.ProfileName())
+				dialog.ShowConfirm(i18n.T("删除任务"), message, func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := b.profiles.Remove(config); err != nil {
+						dialog.ShowError(err, b.window)
+						return
+					}
+					rebuild()
+				}, b.window)
+			})
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(restoreBtn, deleteBtn), label))
+		}
+		list.Refresh()
+	}
+	rebuild()
+	archivedDialog = dialog.NewCustom(i18n.T("已归档的任务"), i18n.T("关闭"), container.NewVScroll(list), b.window)
+	archivedDialog.SetOnClosed(func() {
+		// 刷新任务栏中已归档任务的数量
+		b.createUI()
+		b.applyAppearance()
+		b.setWatchButton(b.config.IsWatching)
+	})
+	archivedDialog.Resize(fyne.NewSize(600, 400))
+	archivedDialog.Show()
+}
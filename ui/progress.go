@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 进度条刷新复制进度的最小间隔
+const progressStatusInterval = 500 * time.Millisecond
+
+// 备份进度面板：进度条、当前文件、速度和剩余时间，只在当前任务备份时显示
+func (b *BackupApp) createProgressPanel() fyne.CanvasObject {
+	b.progressBar = widget.NewProgressBar()
+	b.progressLabel = widget.NewLabel("")
+	b.progressLabel.Truncation = fyne.TextTruncateEllipsis
+	cancelBtn := widget.NewButtonWithIcon(i18n.T("取消备份"), theme.CancelIcon(), func() {
+		if b.job.cancelBackups() {
+			b.updateStatus(i18n.T("正在取消备份..."))
+		}
+	})
+	b.progressBox = container.NewVBox(
+		container.NewBorder(nil, nil, nil, cancelBtn, b.progressBar),
+		b.progressLabel,
+	)
+	b.showProgress(b.job)
+	return b.progressBox
+}
+
+// 按任务的进度刷新进度面板，任务没有在备份时隐藏
+func (b *BackupApp) showProgress(j *job) {
+	if b.progressBox == nil {
+		return
+	}
+	j.progressMutex.Lock()
+	progress := j.progress
+	j.progressMutex.Unlock()
+
+	if progress.Stage == "" || progress.Stage == engine.StageDone {
+		b.progressBox.Hide()
+		return
+	}
+	b.progressBar.SetValue(progress.Fraction())
+	text := i18n.T(engine.StageLabels[progress.Stage]) + "  " + progress.String()
+	if progress.Current != "" {
+		text += "\n" + filepath.Base(progress.Current)
+	}
+	b.progressLabel.SetText(text)
+	b.progressBox.Show()
+}
+
+// 处理备份进度事件：阶段变化和错误写入日志，复制进度节流后显示在进度面板
+func (b *BackupApp) handleProgress(j *job, ev engine.Event) {
+	j.progressMutex.Lock()
+	j.progress.Update(ev)
+	j.progressMutex.Unlock()
+
+	switch ev.Kind {
+	case engine.EventError:
+		slog.Warn("备份出错", "profile", j.config.ProfileName(), "event", ev.String())
+	case engine.EventStage, engine.EventScanned, engine.EventNoChanges:
+		slog.Info("备份进度", "profile", j.config.ProfileName(), "event", ev.String())
+	case engine.EventFileCopied, engine.EventFileSkipped:
+		if time.Since(b.progressShown) < progressStatusInterval {
+			return
+		}
+		b.progressShown = time.Now()
+	}
+	if j.current() {
+		b.showProgress(j)
+	}
+}
@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 快捷操作：Windows 任务栏跳转列表和 Linux 桌面环境的程序菜单（.desktop Actions）中的操作，
+// 以 syncsafe --action <操作> 启动。已有实例在运行时转交给它执行，不会打开新的窗口
+const QuickActionFlag = "--action"
+
+const (
+	actionBackup  = "backup"  // 备份所有任务
+	actionPause   = "pause"   // 暂停或恢复所有任务的监控
+	actionHistory = "history" // 打开历史记录
+)
+
+// 一个快捷操作及其在菜单中显示的名称
+type quickAction struct {
+	name  string
+	label string
+}
+
+var quickActions = []quickAction{
+	{actionBackup, "立即备份"},
+	{actionPause, "暂停/恢复监控"},
+	{actionHistory, "打开历史记录"},
+}
+
+// 转交快捷操作的等待时间
+const quickActionTimeout = 3 * time.Second
+
+// 运行中的实例接收快捷操作的地址，令牌防止其他程序冒充
+type instanceInfo struct {
+	Port  int
+	Token string
+}
+
+func instancePath() string {
+	return filepath.Join(engine.DataDir, "instance.json")
+}
+
+// 执行快捷操作：已有实例在运行时转交给它，否则启动程序后执行
+func RunQuickAction(action string) {
+	if err := sendQuickAction(action); err == nil {
+		return
+	}
+	run(action, false)
+}
+
+// 把快捷操作发送给运行中的实例
+func sendQuickAction(action string) error {
+	data, err := os.ReadFile(instancePath())
+	if err != nil {
+		return err
+	}
+	var info instanceInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", info.Port), quickActionTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(quickActionTimeout))
+	if _, err := fmt.Fprintf(conn, "%s %s\n", info.Token, action); err != nil {
+		return err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reply) != "ok" {
+		return i18n.Errorf("快捷操作被拒绝: %s", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// 在本机回环地址上接收其他进程转交的快捷操作，并在系统中注册快捷操作
+func (b *BackupApp) startQuickActions() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		slog.Warn("无法接收快捷操作", "err", err)
+		return
+	}
+	token := make([]byte, 16)
+	rand.Read(token)
+	info := instanceInfo{Port: listener.Addr().(*net.TCPAddr).Port, Token: hex.EncodeToString(token)}
+	data, _ := json.Marshal(info)
+	if err := os.MkdirAll(engine.DataDir, 0755); err == nil {
+		err = storage.WriteFileAtomic(instancePath(), data, 0600)
+	}
+	if err != nil {
+		slog.Warn("保存实例信息失败", "err", err)
+		listener.Close()
+		return
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.handleQuickActionConn(conn, info.Token)
+		}
+	}()
+	go func() {
+		if err := registerQuickActions(quickActions); err != nil {
+			slog.Warn("注册快捷操作失败", "err", err)
+		}
+	}()
+}
+
+func (b *BackupApp) handleQuickActionConn(conn net.Conn, token string) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(quickActionTimeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != token {
+		fmt.Fprintln(conn, "denied")
+		return
+	}
+	if !b.runQuickAction(fields[1]) {
+		fmt.Fprintln(conn, "unknown")
+		return
+	}
+	fmt.Fprintln(conn, "ok")
+}
+
+// 执行快捷操作，未知的操作返回 false。备份和暂停监控不把窗口调到前台
+func (b *BackupApp) runQuickAction(action string) bool {
+	switch action {
+	case actionBackup:
+		b.backupAll()
+		b.updateStatus(i18n.T("已开始备份所有任务"))
+	case actionPause:
+		if b.watchPaused() {
+			b.resumeWatching()
+		} else {
+			b.pauseWatching()
+		}
+	case actionHistory:
+		b.window.Show()
+		b.window.RequestFocus()
+		b.tabs.SelectIndex(1)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 快照格式的显示名称，目录为空字符串
+func snapshotFormatLabel(format string) string {
+	if format == "" {
+		return i18n.T("目录")
+	}
+	return format
+}
+
+// 附加目标设置表：第一行是主目标，之后每行一个附加目标，分别选择快照格式、是否加密和是否启用
+func (b *BackupApp) showReplicaDialog() {
+	formats := []string{snapshotFormatLabel(""), storage.ArchiveTarGz, storage.ArchiveZip}
+	replicas := append([]engine.Replica(nil), b.config.Replicas...)
+
+	grid := container.NewVBox()
+	var rebuild func()
+	rebuild = func() {
+		bold := fyne.TextStyle{Bold: true}
+		grid.Objects = []fyne.CanvasObject{replicaRow(
+			widget.NewLabelWithStyle(i18n.T("目标"), fyne.TextAlignLeading, bold),
+			widget.NewLabelWithStyle(i18n.T("快照格式"), fyne.TextAlignLeading, bold),
+			widget.NewLabelWithStyle(i18n.T("加密"), fyne.TextAlignLeading, bold),
+			widget.NewLabelWithStyle(i18n.T("启用"), fyne.TextAlignLeading, bold),
+			widget.NewLabel(""),
+		)}
+
+		// 主目标的格式和加密在主界面和加密设置中修改，这里只显示
+		primary := widget.NewLabel(b.config.DestinationPath)
+		primary.Truncation = fyne.TextTruncateEllipsis
+		encrypted := i18n.T("否")
+		if b.config.Encryption.Enabled {
+			encrypted = i18n.T("是")
+		}
+		grid.Add(replicaRow(primary, widget.NewLabel(snapshotFormatLabel(b.config.ArchiveFormat)),
+			widget.NewLabel(encrypted), widget.NewLabel(i18n.T("主目标")), widget.NewLabel("")))
+
+		for i := range replicas {
+			replica := &replicas[i]
+			pathEntry := widget.NewEntry()
+			pathEntry.SetPlaceHolder(i18n.T("文件夹或 WebDAV 地址"))
+			pathEntry.SetText(replica.Path)
+			pathEntry.OnChanged = func(text string) {
+				replica.Path = strings.TrimSpace(text)
+			}
+			browseBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+				dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+					if err == nil && uri != nil {
+						pathEntry.SetText(uri.Path())
+					}
+				}, b.window)
+			})
+			formatSelect := widget.NewSelect(formats, func(selected string) {
+				if selected == formats[0] {
+					selected = ""
+				}
+				replica.ArchiveFormat = selected
+			})
+			formatSelect.SetSelected(snapshotFormatLabel(replica.ArchiveFormat))
+			encryptCheck := widget.NewCheck("", func(value bool) {
+				replica.Encrypt = value
+			})
+			encryptCheck.SetChecked(replica.Encrypt)
+			enabledCheck := widget.NewCheck("", func(value bool) {
+				replica.Disabled = !value
+			})
+			enabledCheck.SetChecked(!replica.Disabled)
+			loginBtn := widget.NewButtonWithIcon("", theme.AccountIcon(), func() {
+				b.showReplicaLogin(replica)
+			})
+			removeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				replicas = append(replicas[:i:i], replicas[i+1:]...)
+				rebuild()
+			})
+			grid.Add(replicaRow(container.NewBorder(nil, nil, nil, browseBtn, pathEntry), formatSelect,
+				encryptCheck, enabledCheck, container.NewHBox(loginBtn, removeBtn)))
+		}
+		grid.Refresh()
+	}
+	rebuild()
+
+	addBtn := widget.NewButtonWithIcon(i18n.T("添加目标"), theme.ContentAddIcon(), func() {
+		replicas = append(replicas, engine.Replica{})
+		rebuild()
+	})
+	hint := widget.NewLabel(i18n.T("每次备份成功后，快照按各目标的格式和加密设置再复制一份，例如外接硬盘上保存普通目录，云端保存加密的归档。\n加密使用加密设置中的密码短语；附加目标中的快照不会自动清理。WebDAV 目标用行末的登录按钮填写用户名和密码，只支持目录格式。"))
+	hint.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(hint, container.NewHBox(addBtn), nil, nil, container.NewVScroll(grid))
+	replicaDialog := dialog.NewCustomConfirm(i18n.T("附加目标"), i18n.T("保存"), i18n.T("取消"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		var saved []engine.Replica
+		for _, replica := range replicas {
+			if replica.Path != "" {
+				saved = append(saved, replica)
+			}
+		}
+		b.config.Replicas = saved
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.Sprintf("已保存 %d 个附加目标", len(saved)))
+	}, b.window)
+	replicaDialog.Resize(fyne.NewSize(900, 480))
+	replicaDialog.Show()
+}
+
+// 设置表中的一行：目标占据剩余的宽度，其他列固定宽度，各行对齐
+func replicaRow(target, format, encrypt, enabled, actions fyne.CanvasObject) fyne.CanvasObject {
+	cell := func(width float32, object fyne.CanvasObject) fyne.CanvasObject {
+		return container.NewGridWrap(fyne.NewSize(width, object.MinSize().Height), object)
+	}
+	return container.NewBorder(nil, nil, nil,
+		container.NewHBox(cell(120, format), cell(60, encrypt), cell(80, enabled), cell(90, actions)), target)
+}
+
+// WebDAV 附加目标的登录信息
+func (b *BackupApp) showReplicaLogin(replica *engine.Replica) {
+	userEntry := widget.NewEntry()
+	userEntry.SetText(replica.WebDAV.Username)
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetText(replica.WebDAV.Password)
+	items := []*widget.FormItem{
+		{Text: i18n.T("用户名"), Widget: userEntry},
+		{Text: i18n.T("密码"), Widget: passwordEntry, HintText: i18n.T("建议使用应用专用密码，只保存在本机")},
+	}
+	dialog.ShowForm(i18n.T("WebDAV 登录信息"), i18n.T("确定"), i18n.T("取消"), items, func(ok bool) {
+		if ok {
+			replica.WebDAV = storage.WebDAVConfig{Username: strings.TrimSpace(userEntry.Text), Password: passwordEntry.Text}
+		}
+	}, b.window)
+}
@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 还原冲突提示中最多列出的文件数
+const restoreConflictsShown = 15
+
+// 还原目标的显示名称
+const (
+	restoreToSource = "原位置（源文件夹）"
+	restoreToOther  = "其他位置"
+)
+
+// 还原页：选择快照，浏览并勾选其中的文件和目录，复制回源文件夹或其他位置
+func (b *BackupApp) createRestoreTab() fyne.CanvasObject {
+	var snapshots []history.Record
+	var snapshot history.Record
+	var archiveTree *engine.ArchiveTree // 归档快照和远程快照的目录结构，本地目录快照为 nil
+	selected := make(map[string]bool)
+	target := ""
+
+	// 快照中的文件树，节点 ID 为相对快照根目录的路径，根节点为空字符串
+	tree := widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			if snapshot.DestPath == "" {
+				return nil
+			}
+			if archiveTree != nil {
+				var children []widget.TreeNodeID
+				entries := archiveTree.Children(id)
+				for _, dirs := range []bool{true, false} {
+					for _, entry := range entries {
+						if entry.IsDir() == dirs {
+							children = append(children, filepath.Join(id, entry.Name()))
+						}
+					}
+				}
+				return children
+			}
+			entries, err := os.ReadDir(filepath.Join(snapshot.DestPath, id))
+			if err != nil {
+				return nil
+			}
+			// 目录排在文件之前
+			sort.SliceStable(entries, func(i, j int) bool { return entries[i].IsDir() && !entries[j].IsDir() })
+			children := make([]widget.TreeNodeID, len(entries))
+			for i, entry := range entries {
+				children[i] = filepath.Join(id, entry.Name())
+			}
+			return children
+		},
+		func(id widget.TreeNodeID) bool {
+			if id == "" {
+				return true
+			}
+			if archiveTree != nil {
+				return archiveTree.IsDir(id)
+			}
+			info, err := os.Stat(filepath.Join(snapshot.DestPath, id))
+			return err == nil && info.IsDir()
+		},
+		func(branch bool) fyne.CanvasObject {
+			return container.NewHBox(widget.NewCheck("", nil), widget.NewIcon(theme.FileIcon()), widget.NewLabel(""))
+		},
+		func(id widget.TreeNodeID, branch bool, item fyne.CanvasObject) {
+			row := item.(*fyne.Container)
+			check := row.Objects[0].(*widget.Check)
+			check.OnChanged = nil
+			check.SetChecked(selected[id])
+			check.OnChanged = func(checked bool) {
+				if checked {
+					selected[id] = true
+				} else {
+					delete(selected, id)
+				}
+			}
+			icon := theme.FileIcon()
+			if branch {
+				icon = theme.FolderIcon()
+			}
+			row.Objects[1].(*widget.Icon).SetResource(icon)
+			row.Objects[2].(*widget.Label).SetText(b.engine.SnapshotName(snapshot, filepath.Base(id)))
+		},
+	)
+
+	snapshotLabels := func() []string {
+		snapshots = snapshots[:0]
+		var labels []string
+		for i := len(b.config.History) - 1; i >= 0; i-- {
+			record := b.config.History[i]
+			if !record.HasSnapshot() {
+				continue
+			}
+			snapshots = append(snapshots, record)
+			label := record.FormatTime(b.config.Location()) + "  " + filepath.Base(record.DestPath)
+			if record.ColdStorage {
+				label += "  " + i18n.T("[冷存储]")
+			}
+			if record.Note != "" {
+				label += "  " + record.Note
+			}
+			labels = append(labels, label)
+		}
+		return labels
+	}
+	snapshotSelect := widget.NewSelect(snapshotLabels(), nil)
+	snapshotSelect.PlaceHolder = i18n.T("选择要还原的快照")
+	snapshotSelect.OnChanged = func(string) {
+		index := snapshotSelect.SelectedIndex()
+		if index < 0 || index >= len(snapshots) {
+			return
+		}
+		snapshot = snapshots[index]
+		archiveTree = nil
+		// 远程快照只列出文件，勾选的文件在还原时才逐个下载
+		var loaded *engine.ArchiveTree
+		var err error
+		switch {
+		case storage.IsRemote(snapshot.DestPath):
+			loaded, err = b.engine.LoadRemoteTree(snapshot.DestPath)
+		case engine.IsArchiveSnapshot(snapshot.DestPath):
+			loaded, err = b.engine.LoadArchiveTree(snapshot.DestPath)
+		}
+		if err != nil {
+			dialog.ShowError(err, b.window)
+		}
+		archiveTree = loaded
+		selected = make(map[string]bool)
+		tree.CloseAllBranches()
+		tree.Refresh()
+	}
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+		snapshotSelect.Options = snapshotLabels()
+		snapshotSelect.ClearSelected()
+		snapshot = history.Record{}
+		archiveTree = nil
+		tree.Refresh()
+	})
+
+	// 还原目标
+	targetLabel := widget.NewLabel("")
+	chooseBtn := widget.NewButtonWithIcon(i18n.T("选择"), theme.FolderOpenIcon(), func() {
+		b.showFolderDialog(i18n.T("选择还原位置"), func(path string) {
+			target = path
+			targetLabel.SetText(path)
+		})
+	})
+	chooseBtn.Disable()
+	targetRadio := widget.NewRadioGroup([]string{i18n.T(restoreToSource), i18n.T(restoreToOther)}, func(choice string) {
+		if choice == i18n.T(restoreToOther) {
+			chooseBtn.Enable()
+			targetLabel.SetText(target)
+		} else {
+			chooseBtn.Disable()
+			targetLabel.SetText("")
+		}
+	})
+	targetRadio.Horizontal = true
+	targetRadio.SetSelected(i18n.T(restoreToSource))
+
+	restoreBtn := widget.NewButtonWithIcon(i18n.T("还原选中的文件"), theme.DownloadIcon(), func() {
+		if snapshot.DestPath == "" {
+			dialog.ShowError(i18n.Errorf("请先选择快照"), b.window)
+			return
+		}
+		var relPaths []string
+		for relPath := range selected {
+			relPaths = append(relPaths, relPath)
+		}
+		if len(relPaths) == 0 {
+			dialog.ShowError(i18n.Errorf("请勾选要还原的文件或目录"), b.window)
+			return
+		}
+		dest := engine.ExpandPathTemplate(snapshot.SourcePath, snapshot.Timestamp)
+		if targetRadio.Selected == i18n.T(restoreToOther) {
+			if target == "" {
+				dialog.ShowError(i18n.Errorf("请选择还原位置"), b.window)
+				return
+			}
+			dest = target
+		}
+		b.confirmRestore(snapshot, relPaths, dest)
+	})
+	restoreBtn.Importance = widget.HighImportance
+
+	return container.NewBorder(
+		container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel(i18n.T("快照:")), refreshBtn, snapshotSelect),
+			widget.NewLabel(i18n.T("勾选要还原的文件或目录，勾选目录会还原其中的所有内容:")),
+		),
+		container.NewVBox(
+			widget.NewSeparator(),
+			container.NewHBox(widget.NewLabel(i18n.T("还原到:")), targetRadio, chooseBtn, targetLabel, layout.NewSpacer(), restoreBtn),
+		),
+		nil, nil,
+		tree,
+	)
+}
+
+// 检查冲突，有已存在的文件时询问覆盖还是跳过，然后在后台还原。冷存储中的快照先确认已经取回
+func (b *BackupApp) confirmRestore(snapshot history.Record, relPaths []string, dest string) {
+	if snapshot.ColdStorage {
+		b.checkRetrieval(snapshot, func() {
+			retrieved := snapshot
+			retrieved.ColdStorage = false
+			b.confirmRestore(retrieved, relPaths, dest)
+		})
+		return
+	}
+	conflicts, err := b.engine.RestoreConflicts(snapshot.DestPath, relPaths, dest)
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	if len(conflicts) == 0 {
+		go b.runRestore(snapshot, relPaths, dest, false)
+		return
+	}
+
+	shown := conflicts
+	if len(shown) > restoreConflictsShown {
+		shown = append(shown[:restoreConflictsShown:restoreConflictsShown], "...")
+	}
+	list := widget.NewLabel(strings.Join(shown, "\n"))
+	content := container.NewBorder(
+		widget.NewLabel(i18n.Sprintf("%s 中已有 %d 个同名文件:", dest, len(conflicts))),
+		nil, nil, nil,
+		container.NewVScroll(list),
+	)
+	conflictDialog := dialog.NewCustom(i18n.T("文件已存在"), i18n.T("取消"), content, b.window)
+	conflictDialog.SetButtons([]fyne.CanvasObject{
+		widget.NewButton(i18n.T("取消"), conflictDialog.Hide),
+		widget.NewButton(i18n.T("跳过已有文件"), func() {
+			conflictDialog.Hide()
+			go b.runRestore(snapshot, relPaths, dest, false)
+		}),
+		&widget.Button{Text: i18n.T("全部覆盖"), Importance: widget.DangerImportance, OnTapped: func() {
+			conflictDialog.Hide()
+			go b.runRestore(snapshot, relPaths, dest, true)
+		}},
+	})
+	conflictDialog.Resize(fyne.NewSize(560, 380))
+	conflictDialog.Show()
+}
+
+// 执行还原并显示结果
+func (b *BackupApp) runRestore(snapshot history.Record, relPaths []string, dest string, overwrite bool) {
+	b.updateStatus(i18n.Sprintf("正在还原 %s 的快照...", snapshot.FormatTime(b.config.Location())))
+	result, err := b.engine.Restore(snapshot.DestPath, relPaths, dest, overwrite)
+	if err != nil {
+		b.updateStatus(i18n.T("还原失败: ") + err.Error())
+		dialog.ShowError(err, b.window)
+		return
+	}
+	message := i18n.Sprintf("已还原 %d 个文件（%.2f MB）到 %s", result.Files, float64(result.Bytes)/(1024*1024), dest)
+	if result.Skipped > 0 {
+		message += i18n.Sprintf("，跳过 %d 个已有文件", result.Skipped)
+	}
+	if result.Streams > 0 {
+		message += i18n.Sprintf("，写回 %d 个文件的附加数据流", result.Streams)
+	}
+	if result.Lost > 0 {
+		message += i18n.Sprintf("。%d 个文件的附加数据流（备用数据流、资源分支或标签）无法写回，目标文件系统可能不支持", result.Lost)
+	}
+	b.updateStatus(message)
+	dialog.ShowInformation(i18n.T("还原完成"), message, b.window)
+}
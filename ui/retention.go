@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 备份完成后按保留策略清理快照，在 loop 中执行
+func (j *job) applyRetention() {
+	if !j.config.Retention.Enabled() {
+		return
+	}
+	count, err := j.engine.ApplyRetention()
+	if errors.Is(err, engine.ErrRetentionHeld) {
+		slog.Info("快照大小异常，暂停按保留策略清理", "source", j.config.SourcePath)
+		return
+	}
+	if errors.Is(err, engine.ErrRetentionUnverified) {
+		// 推迟清理时提醒校验快照，其余的快照照常清理
+		slog.Warn("较新的快照没有通过校验，推迟清理", "source", j.config.SourcePath, "err", err)
+		title := i18n.T("推迟清理旧快照")
+		message := err.Error() + "\n" + i18n.T("在历史记录中校验最新的快照，或开启备份后校验")
+		fyne.CurrentApp().SendNotification(fyne.NewNotification(title, message))
+		j.engine.Notify(notify.LevelWarning, title, fmt.Sprintf("%s\n%s", j.config.SourcePath, message))
+		j.status(err.Error())
+	} else if err != nil {
+		slog.Warn("按保留策略清理快照失败", "err", err)
+		j.status(i18n.T("按保留策略清理快照失败: ") + err.Error())
+	}
+	if count == 0 {
+		return
+	}
+	if err := j.config.Save(); err != nil {
+		slog.Warn("保存历史记录失败", "err", err)
+	}
+	if j.current() {
+		j.app.refreshHistoryView()
+	}
+	j.status(i18n.Sprintf("已按保留策略清理 %d 个旧快照", count))
+}
+
+// 清理计划的摘要，列出每个快照的时间（按 loc 时区）和大小
+func retentionPreview(plan engine.PrunePlan, loc *time.Location) string {
+	if len(plan.Snapshots) == 0 {
+		return i18n.T("现有的快照都会保留")
+	}
+	lines := []string{i18n.Sprintf("将清理 %d 个快照，释放约 %s:", len(plan.Snapshots), formatBytes(plan.Freed))}
+	for _, record := range plan.Snapshots {
+		lines = append(lines, fmt.Sprintf("%s  %s", record.FormatTime(loc), formatBytes(record.TotalSize)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 保留策略的输入框，返回表单项和按输入内容生成策略的函数，输入无效时返回错误
+func retentionForm(policy engine.RetentionPolicy, onChanged func(string)) ([]*widget.FormItem, func() (engine.RetentionPolicy, error)) {
+	fields := []struct {
+		label, hint string
+		value       *int
+	}{
+		{i18n.T("保留最近"), i18n.T("个快照"), &policy.KeepLast},
+		{i18n.T("每天保留"), i18n.T("天，每天最后一个快照"), &policy.KeepDaily},
+		{i18n.T("每周保留"), i18n.T("周，每周最后一个快照"), &policy.KeepWeekly},
+		{i18n.T("每月保留"), i18n.T("个月，每月最后一个快照"), &policy.KeepMonthly},
+	}
+
+	entries := make([]*widget.Entry, len(fields))
+	parse := func() (engine.RetentionPolicy, error) {
+		for i, field := range fields {
+			text := strings.TrimSpace(entries[i].Text)
+			n := 0
+			if text != "" {
+				var err error
+				n, err = strconv.Atoi(text)
+				if err != nil || n < 0 {
+					return policy, i18n.Errorf("%s必须是非负整数: %s", field.label, text)
+				}
+			}
+			*field.value = n
+		}
+		return policy, nil
+	}
+
+	items := make([]*widget.FormItem, len(fields))
+	for i, field := range fields {
+		entries[i] = widget.NewEntry()
+		entries[i].SetText(strconv.Itoa(*field.value))
+		entries[i].OnChanged = onChanged
+		items[i] = &widget.FormItem{Text: field.label, Widget: entries[i], HintText: field.hint}
+	}
+	return items, parse
+}
+
+// 显示保留策略对话框。修改时预览会被清理的快照，保存前确认
+func (b *BackupApp) showRetentionDialog() {
+	preview := widget.NewLabel("")
+	preview.Wrapping = fyne.TextWrapWord
+	var parse func() (engine.RetentionPolicy, error)
+	update := func(string) {
+		policy, err := parse()
+		switch {
+		case err != nil:
+			preview.SetText(err.Error())
+		case !policy.Enabled():
+			preview.SetText(i18n.T("没有设置保留策略，不会自动清理快照"))
+		default:
+			plan := engine.PlanRetention(b.config.History, policy, b.config.Location())
+			text := retentionPreview(plan, b.config.Location())
+			if _, deferred := engine.GateByVerification(b.config.History, plan, policy, time.Now()); deferred > 0 {
+				text += "\n\n" + i18n.Sprintf("其中 %d 个快照要等较新的快照通过校验后才会清理", deferred)
+			}
+			preview.SetText(text)
+		}
+	}
+
+	items, parse := retentionForm(b.config.Retention, update)
+	swingCheck := widget.NewCheck(i18n.T("快照大小骤变时提醒并暂停清理"), nil)
+	swingCheck.SetChecked(!b.config.IgnoreSizeSwings)
+	items = append(items, &widget.FormItem{Text: i18n.T("异常检测"), Widget: swingCheck,
+		HintText: i18n.T("文件数或大小不到最近几次备份平均值的一半或超过其三倍时提醒，确认正常之前不清理旧快照")})
+	verifyCheck := widget.NewCheck(i18n.T("清理前要求较新的快照已通过校验"), func(bool) { update("") })
+	verifyCheck.SetChecked(b.config.Retention.VerifyBeforePrune)
+	withinEntry := widget.NewEntry()
+	withinEntry.SetText(strconv.Itoa(b.config.Retention.VerifiedWithinDays))
+	withinEntry.OnChanged = update
+	items = append(items,
+		&widget.FormItem{Text: i18n.T("校验保护"), Widget: verifyCheck,
+			HintText: i18n.T("备份后校验或手动校验通过的快照才能作为依据，避免删除仅有的完好副本")},
+		&widget.FormItem{Text: i18n.T("校验有效期"), Widget: withinEntry, HintText: i18n.T("天内通过的校验才算数，0 表示不限")})
+	// 在保留策略之外加上校验要求
+	parseCounts := parse
+	parse = func() (engine.RetentionPolicy, error) {
+		policy, err := parseCounts()
+		if err != nil {
+			return policy, err
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(withinEntry.Text))
+		if err != nil || days < 0 {
+			return policy, i18n.Errorf("%s必须是非负整数: %s", i18n.T("校验有效期"), withinEntry.Text)
+		}
+		policy.VerifyBeforePrune = verifyCheck.Checked
+		policy.VerifiedWithinDays = days
+		return policy, nil
+	}
+	update("")
+
+	top := container.NewVBox(widget.NewForm(items...))
+	var retentionDialog dialog.Dialog
+	// 最近一次备份的大小异常还没有确认，清理已暂停
+	if latest, held := engine.HeldBySizeAnomaly(b.config.History); held && !b.config.IgnoreSizeSwings {
+		notice := widget.NewLabel(engine.ErrRetentionHeld.Error() + "\n" + latest.SizeAnomaly)
+		notice.Wrapping = fyne.TextWrapWord
+		j := b.job
+		top.Add(container.NewBorder(nil, nil, nil, widget.NewButton(i18n.T("确认正常"), func() {
+			retentionDialog.Hide()
+			j.acknowledgeAnomaly(latest)
+		}), notice))
+	}
+
+	content := container.NewBorder(
+		top,
+		nil, nil, nil,
+		container.NewVScroll(preview),
+	)
+	save := func(policy engine.RetentionPolicy) {
+		b.config.Retention = policy
+		b.config.IgnoreSizeSwings = !swingCheck.Checked
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("保留策略已保存"))
+	}
+	retentionDialog = dialog.NewCustomConfirm(i18n.T("保留策略"), i18n.T("保存"), i18n.T("取消"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		policy, err := parse()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		// 新的策略会清理现有的快照时先确认，避免误删大量历史。校验要求推迟的快照不计入
+		plan := engine.PlanRetention(b.config.History, policy, b.config.Location())
+		plan.Snapshots, _ = engine.GateByVerification(b.config.History, plan, policy, time.Now())
+		plan.Freed = 0
+		for _, record := range plan.Snapshots {
+			plan.Freed += record.TotalSize
+		}
+		if len(plan.Snapshots) == 0 {
+			save(policy)
+			return
+		}
+		j := b.job
+		dialog.ShowConfirm(i18n.T("确认清理快照"),
+			i18n.Sprintf("新的保留策略会立即清理 %d 个快照，释放约 %s，删除后无法恢复。是否继续？",
+				len(plan.Snapshots), formatBytes(plan.Freed)),
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				save(policy)
+				j.do(func() {
+					snapshots, _ := engine.GateByVerification(j.config.History,
+						engine.PlanRetention(j.config.History, j.config.Retention, j.config.Location()), j.config.Retention, time.Now())
+					j.pruneSnapshots(snapshots)
+				})
+			}, b.window)
+	}, b.window)
+	retentionDialog.Resize(fyne.NewSize(520, 480))
+	retentionDialog.Show()
+}
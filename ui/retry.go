@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 默认重试间隔（分钟）
+const defaultRetryDelay = 5
+
+// 备份失败且已记录到历史
+type backupRecordedError struct {
+	Err error
+}
+
+func (e *backupRecordedError) Error() string { return e.Err.Error() }
+func (e *backupRecordedError) Unwrap() error { return e.Err }
+
+// 提示备份错误：Git 失败提供修复向导，配置问题列在问题面板中，已记录到历史的失败只显示在状态栏
+func (j *job) alertBackupError(err error) {
+	var gitErr *engine.GitError
+	var recorded *backupRecordedError
+	var problems *engine.ProblemsError
+	switch {
+	case errors.As(err, &gitErr):
+		j.showGitFailure(gitErr.Err)
+	case errors.As(err, &problems):
+		// 配置问题在面板中列出，可以直接打开对应的设置
+		j.app.showConfigProblems([]*job{j}, true)
+	case errors.As(err, &recorded):
+	default:
+		dialog.ShowError(err, j.app.window)
+	}
+}
+
+// 重试间隔
+func (j *job) retryDelay() time.Duration {
+	if j.config.RetryDelay <= 0 {
+		return defaultRetryDelay * time.Minute
+	}
+	return time.Duration(j.config.RetryDelay) * time.Minute
+}
+
+// 自动备份（定时备份或失败重试），attempt 为第几次尝试
+func (j *job) autoBackup(attempt int) {
+	j.requestBackup(backupRequest{trigger: triggerAuto, attempt: attempt})
+}
+
+// 在 loop 中处理自动备份的结果。失败时按设置延迟重试，
+// 每次尝试都记录到历史，只在最后一次失败后提示
+func (j *job) handleAutoResult(attempt int, err error) {
+	if j.retryTimer != nil {
+		j.retryTimer.Stop()
+		j.retryTimer = nil
+	}
+	if err == nil {
+		return
+	}
+
+	// 在开始复制前就失败的尝试没有生成记录，这里补记
+	var recorded *backupRecordedError
+	if !errors.As(err, &recorded) {
+		j.addBackupRecord(history.Record{
+			Timestamp:    time.Now(),
+			SourcePath:   j.config.SourcePath,
+			ErrorMessage: err.Error(),
+			Icon:         j.config.Icon,
+			Color:        j.config.Color,
+			Attempt:      attempt,
+		})
+	}
+	if j.pauseIfFailing(err) {
+		return
+	}
+
+	if attempt > j.config.RetryAttempts {
+		if j.config.RetryAttempts > 0 {
+			slog.Error("自动备份重试后仍然失败", "source", j.config.SourcePath, "attempts", attempt, "err", err)
+			j.status(i18n.Sprintf("自动备份重试 %d 次后仍然失败", j.config.RetryAttempts))
+		}
+		j.notifyFailure(notify.LevelError, i18n.Sprintf("自动备份失败（共尝试 %d 次）", attempt), err)
+		j.emailFailure(err, attempt)
+		var gitErr *engine.GitError
+		if errors.As(err, &gitErr) {
+			j.showGitFailure(gitErr.Err)
+		} else {
+			dialog.ShowError(i18n.Errorf("自动备份失败（共尝试 %d 次）: %v", attempt, err), j.app.window)
+		}
+		return
+	}
+
+	delay := j.retryDelay()
+	slog.Warn("自动备份失败，稍后重试", "source", j.config.SourcePath, "attempt", attempt, "delay", delay.String(), "err", err)
+	j.status(i18n.Sprintf("备份失败，%s 后进行第 %d 次重试", delay, attempt))
+	j.notifyFailure(notify.LevelWarning, i18n.Sprintf("备份失败，%s 后重试", delay), err)
+	j.retryTimer = time.AfterFunc(delay, func() {
+		j.autoBackup(attempt + 1)
+	})
+}
+
+// 显示失败重试设置对话框
+func (b *BackupApp) showRetryDialog() {
+	attemptsEntry := widget.NewEntry()
+	attemptsEntry.SetText(strconv.Itoa(b.config.RetryAttempts))
+	delayEntry := widget.NewEntry()
+	delay := b.config.RetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	delayEntry.SetText(strconv.Itoa(delay))
+	pauseEntry := widget.NewEntry()
+	pauseEntry.SetText(strconv.Itoa(b.config.FailureLimit()))
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("重试次数"), Widget: attemptsEntry, HintText: i18n.T("监控触发的备份失败后自动重试的次数，0 表示不重试")},
+		{Text: i18n.T("重试间隔（分钟）"), Widget: delayEntry, HintText: i18n.T("每次重试前等待的时间")},
+		{Text: i18n.T("连续失败后暂停"), Widget: pauseEntry, HintText: i18n.T("连续失败这么多次（重试只算一次）后自动暂停任务，0 表示从不暂停")},
+	}
+	dialog.ShowForm(i18n.T("失败重试"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		attempts, err := strconv.Atoi(attemptsEntry.Text)
+		if err != nil || attempts < 0 {
+			dialog.ShowError(i18n.Errorf("重试次数必须是非负整数"), b.window)
+			return
+		}
+		delay, err := strconv.Atoi(delayEntry.Text)
+		if err != nil || delay <= 0 {
+			dialog.ShowError(i18n.Errorf("重试间隔必须是正整数"), b.window)
+			return
+		}
+		pauseAfter, err := strconv.Atoi(pauseEntry.Text)
+		if err != nil || pauseAfter < 0 {
+			dialog.ShowError(i18n.Errorf("连续失败次数必须是非负整数"), b.window)
+			return
+		}
+		if pauseAfter == 0 {
+			pauseAfter = -1
+		}
+		b.config.RetryAttempts = attempts
+		b.config.RetryDelay = delay
+		b.config.PauseAfterFailures = pauseAfter
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("失败重试设置已保存"))
+	}, b.window)
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 复查队列中问题类型的名称，显示时翻译
+var issueLabels = map[string]string{
+	engine.IssueCopyFailed: "复制失败",
+	engine.IssueMismatch:   "校验不一致",
+	engine.IssueCollision:  "大小写冲突",
+}
+
+// 复查队列：列出当前任务备份中没有中断备份的问题，每个问题可以重试、永久忽略或上报
+func (b *BackupApp) showReviewDialog() {
+	j := b.job
+	items := append([]engine.ReviewItem(nil), j.config.Review...)
+	if len(items) == 0 {
+		dialog.ShowInformation(i18n.T("复查队列"), i18n.T("没有等待复查的问题"), b.window)
+		return
+	}
+
+	var reviewDialog dialog.Dialog
+	// 队列在任务空闲时修改，备份不会同时更新队列
+	apply := func(message string, change func()) {
+		reviewDialog.Hide()
+		j.do(func() {
+			change()
+			if err := j.config.Save(); err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			if j.current() {
+				b.refreshResultBadge()
+			}
+			j.status(message)
+		})
+	}
+	retry := func(items ...engine.ReviewItem) {
+		apply(i18n.Sprintf("%d 个问题将在本次备份中重试", len(items)), func() {
+			j.engine.RetryReview(items...)
+		})
+		go j.performBackup()
+	}
+
+	loc := j.config.Location()
+	list := container.NewVBox()
+	for _, item := range items {
+		text := i18n.Sprintf("[%s] %s\n%s\n共 %d 次，最近一次 %s", i18n.T(issueLabels[item.Kind]), item.Path, item.Message,
+			item.Count, item.LastSeen.In(loc).Format("2006-01-02 15:04"))
+		if item.Escalated {
+			text += i18n.T("，已上报")
+		}
+		label := widget.NewLabel(text)
+		label.Wrapping = fyne.TextWrapWord
+
+		retryBtn := widget.NewButtonWithIcon(i18n.T("重试"), theme.ViewRefreshIcon(), func() {
+			retry(item)
+		})
+		ignoreBtn := widget.NewButtonWithIcon(i18n.T("忽略"), theme.VisibilityOffIcon(), func() {
+			dialog.ShowConfirm(i18n.T("永久忽略"), i18n.Sprintf("以后不再备份 %s？\n将在排除规则中添加该路径，可以在「备份范围」中删除。", item.Path), func(ok bool) {
+				if ok {
+					apply(i18n.Sprintf("已在排除规则中添加 %s", item.Path), func() {
+						j.config.IgnoreReview(item)
+					})
+				}
+			}, b.window)
+		})
+		escalateBtn := widget.NewButtonWithIcon(i18n.T("上报"), theme.MailSendIcon(), func() {
+			go func() {
+				if err := j.engine.EscalateReview(item); err != nil {
+					dialog.ShowError(err, b.window)
+					return
+				}
+				apply(i18n.Sprintf("已上报 %s", item.Path), func() {
+					j.config.MarkEscalated(item)
+				})
+			}()
+		})
+		icon := widget.NewIcon(theme.NewWarningThemedResource(theme.WarningIcon()))
+		list.Add(container.NewBorder(nil, nil, icon, container.NewHBox(retryBtn, ignoreBtn, escalateBtn), label))
+	}
+
+	hint := widget.NewLabel(i18n.T("这些问题没有中断备份。重试会在下一次备份中重新读取并复制该文件；之后的备份没有再发现问题时自动移出队列。"))
+	hint.Wrapping = fyne.TextWrapWord
+	retryAll := widget.NewButtonWithIcon(i18n.T("全部重试"), theme.ViewRefreshIcon(), func() {
+		retry(items...)
+	})
+	content := container.NewBorder(hint, container.NewHBox(retryAll), nil, nil, container.NewVScroll(list))
+	reviewDialog = dialog.NewCustom(i18n.Sprintf("复查队列（%d）", len(items)), i18n.T("关闭"), content, b.window)
+	reviewDialog.Resize(fyne.NewSize(680, 460))
+	reviewDialog.Show()
+}
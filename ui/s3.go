@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 把备份目标设为 S3 兼容的对象存储（Amazon S3、MinIO 等），选择存储类别和转入冷存储的时间，保存前可以测试连接
+func (b *BackupApp) showS3Dialog() {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("s3://bucket/Backups")
+	if storage.IsS3(b.config.DestinationPath) {
+		urlEntry.SetText(b.config.DestinationPath)
+	}
+	endpointEntry := widget.NewEntry()
+	endpointEntry.SetPlaceHolder("https://s3.amazonaws.com")
+	endpointEntry.SetText(b.config.S3.Endpoint)
+	regionEntry := widget.NewEntry()
+	regionEntry.SetPlaceHolder("us-east-1")
+	regionEntry.SetText(b.config.S3.Region)
+	accessEntry := widget.NewEntry()
+	accessEntry.SetText(b.config.S3.AccessKey)
+	secretEntry := widget.NewPasswordEntry()
+	secretEntry.SetText(b.config.S3.SecretKey)
+	classSelect := widget.NewSelect([]string{storage.ClassStandard, storage.ClassStandardIA, storage.ClassGlacierIR}, nil)
+	classSelect.SetSelected(b.config.S3.StorageClass)
+	if classSelect.Selected == "" {
+		classSelect.SetSelected(storage.ClassStandard)
+	}
+	coldDaysEntry := widget.NewEntry()
+	coldDaysEntry.SetText(strconv.Itoa(b.config.S3.ColdAfterDays))
+	coldSelect := widget.NewSelect([]string{storage.ClassGlacier, storage.ClassDeepArchive}, nil)
+	coldSelect.SetSelected(b.config.S3.Cold())
+
+	settings := func() (string, storage.S3Config, error) {
+		address := strings.TrimSpace(urlEntry.Text)
+		if !storage.IsS3(address) {
+			return "", storage.S3Config{}, i18n.Errorf("请填写以 s3:// 开头的地址，例如 s3://存储桶/Backups")
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(coldDaysEntry.Text))
+		if err != nil || days < 0 {
+			return "", storage.S3Config{}, i18n.Errorf("转入冷存储的天数必须是非负整数: %s", coldDaysEntry.Text)
+		}
+		config := storage.S3Config{
+			Endpoint:      strings.TrimSpace(endpointEntry.Text),
+			Region:        strings.TrimSpace(regionEntry.Text),
+			AccessKey:     strings.TrimSpace(accessEntry.Text),
+			SecretKey:     secretEntry.Text,
+			StorageClass:  classSelect.Selected,
+			ColdClass:     coldSelect.Selected,
+			ColdAfterDays: days,
+		}
+		return address, config, storage.NewS3(address, config).Err()
+	}
+
+	testBtn := widget.NewButtonWithIcon(i18n.T("测试连接"), theme.ConfirmIcon(), func() {
+		address, config, err := settings()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("正在连接 S3..."))
+		go func() {
+			if err := storage.NewS3(address, config).Check(); err != nil {
+				dialog.ShowError(i18n.Errorf("连接 S3 失败: %v", err), b.window)
+				b.updateStatus(i18n.T("连接 S3 失败"))
+				return
+			}
+			dialog.ShowInformation("S3", i18n.T("连接成功，存储桶已就绪"), b.window)
+			b.updateStatus(i18n.T("S3 连接成功"))
+		}()
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("地址"), Widget: urlEntry, HintText: i18n.T("存储桶和其中保存快照的前缀")},
+		{Text: i18n.T("服务地址"), Widget: endpointEntry, HintText: i18n.T("MinIO 等自建服务填写服务地址，Amazon S3 留空")},
+		{Text: i18n.T("区域"), Widget: regionEntry, HintText: i18n.T("留空时自动查询存储桶所在的区域")},
+		{Text: i18n.T("访问密钥 ID"), Widget: accessEntry},
+		{Text: i18n.T("私有访问密钥"), Widget: secretEntry, HintText: i18n.T("只保存在本机")},
+		{Text: i18n.T("存储类别"), Widget: classSelect, HintText: i18n.T("新快照的存储类别")},
+		{Text: i18n.T("转入冷存储"), Widget: coldDaysEntry, HintText: i18n.T("快照保存多少天后转入冷存储，0 表示不转入。冷存储费用更低，但还原前要先取回，需要几分钟到两天")},
+		{Text: i18n.T("冷存储类别"), Widget: coldSelect},
+		{Text: "", Widget: testBtn},
+	}
+	dialog.ShowForm(i18n.T("备份到 S3"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		address, config, err := settings()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.DestinationPath = address
+		b.config.S3 = config
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.destLabel.SetText(address)
+		b.destFolder.SetText(address)
+		b.updateStatus(i18n.T("已选择 S3 备份目标: ") + address)
+	}, b.window)
+}
@@ -0,0 +1,189 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/schedule"
+)
+
+// 按定时表达式安排下一次备份，替换之前安排的备份
+func (j *job) startSchedule() {
+	j.scheduleBackup(time.Now())
+}
+
+// 安排 after 之后的下一次定时备份
+func (j *job) scheduleBackup(after time.Time) {
+	j.stopSchedule()
+	next, err := j.config.NextScheduledBackup(after)
+	if err != nil {
+		j.status(i18n.T("定时表达式无效: ") + err.Error())
+	}
+	if !next.IsZero() {
+		j.scheduleTimer = time.AfterFunc(time.Until(next), func() { j.runScheduled(next) })
+	}
+	j.nextScheduled = next
+	if j.current() {
+		j.app.refreshScheduleLabel()
+	}
+}
+
+// 取消已安排的定时备份
+func (j *job) stopSchedule() {
+	if j.scheduleTimer != nil {
+		j.scheduleTimer.Stop()
+		j.scheduleTimer = nil
+	}
+	j.nextScheduled = time.Time{}
+}
+
+// 执行计划在 at 的定时备份并安排下一次，暂停时段内跳过本次
+func (j *job) runScheduled(at time.Time) {
+	if period, ok := j.config.ActiveBlackout(time.Now()); ok {
+		j.status(i18n.Sprintf("处于暂停时段 %s，跳过本次定时备份", period.Name))
+	} else {
+		j.status(i18n.T("开始定时备份"))
+		j.autoBackup(1)
+	}
+	j.scheduleBackup(scheduledAfter(at))
+}
+
+// 定时任务运行后计算下一次的起点。计时器可能比计划时间略早触发，
+// 从计划时间之后算起，避免同一时间运行两次
+func scheduledAfter(at time.Time) time.Time {
+	if now := time.Now(); now.After(at) {
+		return now
+	}
+	return at
+}
+
+// 在状态栏显示当前任务的下一次定时备份时间
+func (b *BackupApp) refreshScheduleLabel() {
+	if b.scheduleLabel == nil {
+		return
+	}
+	switch {
+	case b.config.Schedule == "":
+		b.scheduleLabel.SetText("")
+	case b.config.SchedulePaused:
+		b.scheduleLabel.SetText(i18n.T("定时备份已暂停"))
+	case b.nextScheduled.IsZero():
+		b.scheduleLabel.SetText("")
+	default:
+		b.scheduleLabel.SetText(i18n.T("下次定时备份: ") + b.nextScheduled.In(b.config.Location()).Format("2006-01-02 15:04"))
+	}
+}
+
+// 时区下拉列表中的常用时区，也可以输入其他 IANA 时区名称
+var commonTimeZones = []string{
+	"UTC",
+	"Asia/Shanghai", "Asia/Hong_Kong", "Asia/Taipei", "Asia/Tokyo", "Asia/Singapore",
+	"Europe/London", "Europe/Berlin", "Europe/Paris",
+	"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles",
+	"Australia/Sydney",
+}
+
+// 显示定时备份设置对话框：cron 表达式、时区、暂停开关和接下来几次运行时间的预览
+func (b *BackupApp) showScheduleDialog() {
+	exprEntry := widget.NewEntry()
+	exprEntry.SetPlaceHolder(i18n.T("例如 0 2 * * * 表示每天 2:00"))
+	exprEntry.SetText(b.config.Schedule)
+	pausedCheck := widget.NewCheck(i18n.T("暂停"), nil)
+	pausedCheck.SetChecked(b.config.SchedulePaused)
+	zoneEntry := widget.NewSelectEntry(commonTimeZones)
+	zoneEntry.SetPlaceHolder(i18n.Sprintf("本机时区（%s）", time.Local.String()))
+	zoneEntry.SetText(b.config.TimeZone)
+	preview := widget.NewLabel("")
+	scrubEntry := widget.NewEntry()
+	scrubEntry.SetPlaceHolder(i18n.T("例如 0 3 * * 0 表示每周日 3:00，为空不巡检"))
+	scrubEntry.SetText(b.config.ScrubSchedule)
+	scrubBtn := widget.NewButton(i18n.T("立即巡检"), func() {
+		go b.job.runScrub()
+	})
+
+	updatePreview := func(string) {
+		expr := strings.TrimSpace(exprEntry.Text)
+		if expr == "" {
+			preview.SetText(i18n.T("不定时备份"))
+			return
+		}
+		s, err := schedule.Parse(expr)
+		if err != nil {
+			preview.SetText(err.Error())
+			return
+		}
+		loc, err := engine.LoadTimeZone(strings.TrimSpace(zoneEntry.Text))
+		if err != nil {
+			preview.SetText(err.Error())
+			return
+		}
+		var lines []string
+		next := time.Now().In(loc)
+		for i := 0; i < 3; i++ {
+			if next = s.Next(next); next.IsZero() {
+				break
+			}
+			lines = append(lines, next.Format("2006-01-02 15:04 Mon MST"))
+		}
+		if len(lines) == 0 {
+			preview.SetText(i18n.T("该表达式不会运行"))
+			return
+		}
+		preview.SetText(strings.Join(lines, "\n"))
+	}
+	exprEntry.OnChanged = updatePreview
+	zoneEntry.OnChanged = updatePreview
+	updatePreview("")
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("定时表达式"), Widget: exprEntry, HintText: i18n.T("分钟 小时 日 月 星期，也可以使用 @hourly、@daily、@weekly")},
+		{Text: "", Widget: pausedCheck},
+		{Text: i18n.T("时区"), Widget: zoneEntry, HintText: i18n.T("定时计划、暂停时段和历史记录中的时间都按该时区，为空表示本机时区")},
+		{Text: i18n.T("接下来运行"), Widget: preview},
+		{Text: i18n.T("数据巡检"), Widget: scrubEntry, HintText: i18n.T("定期读出目标文件夹中的所有快照，发现坏扇区和静默损坏并尽量从其他副本修复")},
+		{Text: "", Widget: scrubBtn},
+	}
+	dialog.ShowForm(i18n.T("定时备份"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		expr := strings.TrimSpace(exprEntry.Text)
+		scrubExpr := strings.TrimSpace(scrubEntry.Text)
+		zone := strings.TrimSpace(zoneEntry.Text)
+		if _, err := engine.LoadTimeZone(zone); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		for _, e := range []string{expr, scrubExpr} {
+			if e == "" {
+				continue
+			}
+			if _, err := schedule.Parse(e); err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+		}
+		b.config.Schedule = expr
+		b.config.SchedulePaused = pausedCheck.Checked
+		b.config.ScrubSchedule = scrubExpr
+		zoneChanged := zone != b.config.TimeZone
+		b.config.TimeZone = zone
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.startSchedule()
+		b.startScrubSchedule()
+		if zoneChanged {
+			b.startGitSchedule()
+			b.refreshHistoryView()
+			b.refreshResultBadge()
+		}
+		b.updateStatus(i18n.T("定时备份设置已保存"))
+	}, b.window)
+}
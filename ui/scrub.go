@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+	"syncsafe/notify"
+)
+
+// 按巡检表达式安排下一次数据巡检，替换之前安排的巡检
+func (j *job) startScrubSchedule() {
+	j.scheduleScrub(time.Now())
+}
+
+// 安排 after 之后的下一次数据巡检
+func (j *job) scheduleScrub(after time.Time) {
+	j.stopScrubSchedule()
+	next, err := j.config.NextScheduledScrub(after)
+	if err != nil {
+		j.status(i18n.T("数据巡检表达式无效: ") + err.Error())
+	}
+	if !next.IsZero() {
+		j.scrubTimer = time.AfterFunc(time.Until(next), func() { j.runScheduledScrub(next) })
+	}
+}
+
+// 取消已安排的数据巡检
+func (j *job) stopScrubSchedule() {
+	if j.scrubTimer != nil {
+		j.scrubTimer.Stop()
+		j.scrubTimer = nil
+	}
+}
+
+// 执行计划在 at 的数据巡检并安排下一次
+func (j *job) runScheduledScrub(at time.Time) {
+	j.runScrub()
+	j.scheduleScrub(scheduledAfter(at))
+}
+
+// 在 loop 中复制历史记录，备份进行中时等到备份结束。任务已停止时返回 nil
+func (j *job) historySnapshot() []history.Record {
+	records := make(chan []history.Record, 1)
+	j.do(func() {
+		records <- append([]history.Record(nil), j.config.History...)
+	})
+	select {
+	case r := <-records:
+		return r
+	case <-j.stop:
+		return nil
+	}
+}
+
+// 执行一次数据巡检，同一源文件夹的其他任务的快照也用作修复来源。
+// 巡检期间可以继续备份，任务停止时巡检随之取消
+func (j *job) runScrub() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-j.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	records := j.historySnapshot()
+	var copies []history.Record
+	for _, other := range j.app.jobs {
+		if other != j && other.config.SourcePath == j.config.SourcePath {
+			copies = append(copies, other.historySnapshot()...)
+		}
+	}
+	j.status(i18n.T("开始数据巡检"))
+	result, err := j.engine.Scrub(ctx, records, copies)
+	if err != nil {
+		j.status(err.Error())
+		return
+	}
+	switch {
+	case !result.OK():
+		j.warnScrub(result)
+	case len(result.Repaired) > 0:
+		j.engine.Notify(notify.LevelWarning, i18n.T("数据巡检"), result.String())
+		j.status(result.String())
+	default:
+		j.status(result.String())
+	}
+}
+
+// 通过系统通知、推送通知、状态栏和对话框提示无法修复的文件
+func (j *job) warnScrub(result engine.ScrubResult) {
+	message := result.String()
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(i18n.T("数据巡检"), message))
+	j.engine.Notify(notify.LevelError, i18n.T("数据巡检"), fmt.Sprintf("%s\n%s", j.config.DestinationPath, message))
+	j.status(message)
+
+	lines := append([]string(nil), result.Unrepairable...)
+	for _, path := range result.Repaired {
+		lines = append(lines, path+i18n.T(": 已修复"))
+	}
+	title := widget.NewLabelWithStyle(message, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	content := container.NewBorder(title, nil, nil, nil, container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n"))))
+	warning := dialog.NewCustom(i18n.T("数据巡检"), i18n.T("确定"), content, j.app.window)
+	warning.Resize(fyne.NewSize(700, 400))
+	warning.Show()
+}
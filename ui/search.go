@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 每个任务最多显示的搜索结果数
+const searchLimit = 50
+
+// 全局搜索快捷键
+var globalSearchShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyF, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+
+// 注册全局搜索快捷键
+func (b *BackupApp) registerSearchShortcut() {
+	b.window.Canvas().AddShortcut(globalSearchShortcut, func(fyne.Shortcut) {
+		b.showGlobalSearch()
+	})
+}
+
+// 全局搜索：同时搜索所有任务（包括已归档的任务）的历史记录和快照中的文件，按任务分组显示
+func (b *BackupApp) showGlobalSearch() {
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder(i18n.T("搜索所有任务的备注、错误信息、路径和快照中的文件名，按回车搜索"))
+	results := container.NewVBox()
+
+	var searchDialog dialog.Dialog
+	queryEntry.OnSubmitted = func(query string) {
+		results.RemoveAll()
+		results.Add(widget.NewLabel(i18n.T("搜索中...")))
+		// 读取快照清单可能需要一些时间
+		go func() {
+			groups := make([]fyne.CanvasObject, 0)
+			for _, config := range b.profiles.List {
+				found := config.Search(query, searchLimit)
+				if len(found) == 0 {
+					continue
+				}
+				title := i18n.Sprintf("%s（%d）", config.ProfileName(), len(found))
+				if config.Archived {
+					title = i18n.Sprintf("%s（已归档，%d）", config.ProfileName(), len(found))
+				}
+				groups = append(groups, widget.NewLabelWithStyle(title,
+					fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+				for _, result := range found {
+					groups = append(groups, b.searchResultRow(config, result, func() { searchDialog.Hide() }))
+				}
+			}
+			if len(groups) == 0 {
+				groups = append(groups, widget.NewLabel(i18n.T("没有找到匹配的结果")))
+			}
+			results.Objects = groups
+			results.Refresh()
+		}()
+	}
+
+	content := container.NewBorder(queryEntry, nil, nil, nil, container.NewVScroll(results))
+	searchDialog = dialog.NewCustom(i18n.T("全局搜索"), i18n.T("关闭"), content, b.window)
+	searchDialog.Resize(fyne.NewSize(700, 500))
+	searchDialog.Show()
+	b.window.Canvas().Focus(queryEntry)
+}
+
+// 一条搜索结果：快照中的文件或历史记录，点击跳转到对应任务的历史记录。
+// 已归档的任务没有界面，需要先在「已归档」中恢复才能跳转
+func (b *BackupApp) searchResultRow(config *engine.Config, result engine.SearchResult, hide func()) fyne.CanvasObject {
+	record := result.Record
+	timestamp := record.FormatTime(config.Location())
+	text := fmt.Sprintf("%s  %s", timestamp, record.SourcePath)
+	icon := theme.HistoryIcon()
+	if result.File != "" {
+		text = i18n.Sprintf("%s\n快照 %s", result.File, timestamp)
+		icon = theme.FileIcon()
+	} else if record.Note != "" {
+		text += i18n.T("\n备注: ") + record.Note
+	} else if !record.Success {
+		text += i18n.T("\n失败: ") + record.ErrorMessage
+	}
+
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	jumpBtn := widget.NewButtonWithIcon(i18n.T("跳转"), theme.NavigateNextIcon(), func() {
+		j := b.jobNamed(config.ProfileName())
+		if j == nil {
+			return
+		}
+		hide()
+		b.showRecord(j, record)
+	})
+	if config.Archived {
+		jumpBtn.Disable()
+	}
+	return container.NewBorder(nil, nil, widget.NewIcon(icon), jumpBtn, label)
+}
+
+// 切换到任务的历史记录页，只显示该记录
+func (b *BackupApp) showRecord(j *job, record history.Record) {
+	b.selectJob(j)
+	b.historyFilter = ""
+	b.historySearch = record.FormatTime(j.config.Location())
+	b.createUI()
+	b.applyAppearance()
+	b.setWatchButton(j.config.IsWatching)
+	b.tabs.SelectIndex(1)
+}
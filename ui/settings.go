@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+	"syncsafe/logging"
+	"syncsafe/watcher"
+)
+
+// 主题选项，名称在显示时翻译
+var themeOptions = []struct {
+	mode, label string
+}{
+	{engine.ThemeSystem, "跟随系统"},
+	{engine.ThemeLight, "浅色"},
+	{engine.ThemeDark, "深色"},
+}
+
+// 开机自动启动登记的命令行参数，以此区分手动打开和登录时启动
+const AutostartFlag = "--autostart"
+
+// 在创建界面前读取程序设置，应用界面语言、主题和日志记录级别
+func loadPreferences() engine.Preferences {
+	preferences, err := engine.LoadPreferences()
+	if err != nil {
+		slog.Warn("读取程序设置失败", "err", err)
+	}
+	i18n.SetLanguage(preferences.Language)
+	applyTheme(preferences.Theme)
+	logging.SetLevel(preferences.LogLevel)
+	// 程序可能已移动到其他位置，重新登记开机启动的路径
+	if preferences.StartOnLogin {
+		go func() {
+			if err := setAutostart(true); err != nil {
+				slog.Warn("更新开机自动启动失败", "err", err)
+			}
+		}()
+	}
+	return preferences
+}
+
+// 按明暗模式设置主题，已显示的界面立即刷新
+func applyTheme(mode string) {
+	fyne.CurrentApp().Settings().SetTheme(&CustomTheme{Theme: theme.DefaultTheme(), Mode: mode})
+}
+
+// 监控到变化后等待的时间
+func (b *BackupApp) watchDebounce() time.Duration {
+	if debounce := b.profiles.Preferences().WatchDebounce(); debounce > 0 {
+		return debounce
+	}
+	return watcher.DefaultDebounce
+}
+
+// 程序设置：对所有任务生效，保存在默认任务的配置中，修改后立即生效
+func (b *BackupApp) showSettingsDialog() {
+	current := *b.profiles.Preferences()
+	preferences := current
+
+	names := make([]string, len(i18n.Languages))
+	for i, language := range i18n.Languages {
+		names[i] = language.Name
+	}
+	languageSelect := widget.NewSelect(names, func(selected string) {
+		for _, language := range i18n.Languages {
+			if language.Name == selected {
+				preferences.Language = language.Code
+			}
+		}
+	})
+	for _, language := range i18n.Languages {
+		if language.Code == i18n.Current() {
+			languageSelect.SetSelected(language.Name)
+		}
+	}
+
+	themeNames := make([]string, len(themeOptions))
+	for i, option := range themeOptions {
+		themeNames[i] = option.label
+	}
+	themeSelect := newTranslatedSelect(themeNames, func(name string) {
+		for _, option := range themeOptions {
+			if option.label == name {
+				preferences.Theme = option.mode
+			}
+		}
+	})
+	for _, option := range themeOptions {
+		if option.mode == preferences.Theme {
+			themeSelect.SetSelected(i18n.T(option.label))
+		}
+	}
+
+	debounceEntry := widget.NewEntry()
+	debounceEntry.SetText(strconv.Itoa(int(b.watchDebounce().Seconds())))
+
+	// 并发复制数，自动表示使用 CPU 核数
+	workerSelect := widget.NewSelect([]string{i18n.T("自动"), "1", "2", "4", "8", "16"}, func(selected string) {
+		preferences.CopyWorkers, _ = strconv.Atoi(selected)
+	})
+	if preferences.CopyWorkers > 0 {
+		workerSelect.SetSelected(strconv.Itoa(preferences.CopyWorkers))
+	} else {
+		workerSelect.SetSelected(i18n.T("自动"))
+	}
+
+	minimizedCheck := widget.NewCheck(i18n.T("开机自动启动时只显示托盘图标"), func(checked bool) {
+		preferences.StartMinimized = checked
+	})
+	minimizedCheck.SetChecked(preferences.StartMinimized)
+	loginCheck := widget.NewCheck(i18n.T("登录系统时自动启动"), func(checked bool) {
+		preferences.StartOnLogin = checked
+	})
+	loginCheck.SetChecked(preferences.StartOnLogin)
+
+	labels := make([]string, len(logLevelOptions))
+	for i, option := range logLevelOptions {
+		labels[i] = i18n.T(option.label)
+	}
+	levelSelect := widget.NewSelect(labels, func(selected string) {
+		preferences.LogLevel = logLevelName(selected)
+	})
+	levelSelect.SetSelected(logging.LevelName(logging.ParseLevel(preferences.LogLevel)))
+
+	retentionItems, parseRetention := retentionForm(preferences.Retention, nil)
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("界面语言"), Widget: languageSelect},
+		{Text: i18n.T("主题"), Widget: themeSelect},
+		{Text: i18n.T("监控延迟（秒）"), Widget: debounceEntry, HintText: i18n.T("最后一次文件变化后等待多久开始备份")},
+		{Text: i18n.T("并发复制数"), Widget: workerSelect, HintText: i18n.T("没有单独设置并发复制数的任务使用该值")},
+		{Text: i18n.T("启动"), Widget: container.NewVBox(minimizedCheck, loginCheck)},
+		{Text: i18n.T("日志记录级别"), Widget: levelSelect},
+		widget.NewFormItem("", widget.NewLabelWithStyle(i18n.T("新建任务的默认保留策略"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})),
+	}
+	items = append(items, retentionItems...)
+
+	settingsDialog := dialog.NewForm(i18n.T("设置"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(debounceEntry.Text))
+		if err != nil || seconds <= 0 {
+			dialog.ShowError(i18n.Errorf("监控延迟必须是正整数"), b.window)
+			return
+		}
+		if time.Duration(seconds)*time.Second != b.watchDebounce() {
+			preferences.Debounce = seconds
+		}
+		if preferences.Retention, err = parseRetention(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.applyPreferences(current, preferences)
+	}, b.window)
+	settingsDialog.Resize(fyne.NewSize(520, 0))
+	settingsDialog.Show()
+}
+
+// 保存程序设置，应用与之前不同的部分
+func (b *BackupApp) applyPreferences(previous, preferences engine.Preferences) {
+	if preferences.StartOnLogin != previous.StartOnLogin {
+		if err := setAutostart(preferences.StartOnLogin); err != nil {
+			dialog.ShowError(err, b.window)
+			preferences.StartOnLogin = previous.StartOnLogin
+		}
+	}
+	*b.profiles.Preferences() = preferences
+	if err := b.profiles.SavePreferences(); err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+
+	logging.SetLevel(preferences.LogLevel)
+	if preferences.Theme != previous.Theme {
+		applyTheme(preferences.Theme)
+	}
+	// 正在监控的任务按新的延迟重新开始监控
+	if preferences.Debounce != previous.Debounce {
+		for _, j := range b.jobs {
+			if j.watcher == nil {
+				continue
+			}
+			j.stopWatching()
+			if err := j.startWatching(); err != nil {
+				j.status(i18n.T("重新开始监控失败: ") + err.Error())
+				j.setWatchButton(false)
+			}
+		}
+	}
+	if preferences.Language != previous.Language {
+		i18n.SetLanguage(preferences.Language)
+		b.applyLanguage()
+		return
+	}
+	b.updateStatus(i18n.T("设置已保存"))
+}
+
+// 切换语言后重建界面（包括窗口标题）、托盘菜单和系统快捷操作，之后的提示和错误信息使用新的语言
+func (b *BackupApp) applyLanguage() {
+	b.createUI()
+	b.applyAppearance()
+	b.setWatchButton(b.config.IsWatching)
+	b.refreshTray()
+	go func() {
+		if err := registerQuickActions(quickActions); err != nil {
+			slog.Warn("注册快捷操作失败", "err", err)
+		}
+	}()
+	b.updateStatus(i18n.T("界面语言已切换"))
+}
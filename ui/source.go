@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+	"syncsafe/watcher"
+)
+
+// 检查源文件夹是否仍然可用的间隔
+const sourceCheckInterval = watcher.DefaultCheckInterval
+
+// 源文件夹在监控期间被删除、重命名或卸载：停止监控并提示用户
+func (j *job) handleSourceLost(w *watcher.Watcher, root string) {
+	// 用户已经手动停止或重新开始了监控
+	if j.watcher != w {
+		return
+	}
+	j.stopWatching()
+	j.setWatchButton(false)
+	j.status(i18n.T("源文件夹不可用，监控已停止: ") + root)
+	j.showSourceLostDialog(root)
+}
+
+// 显示源文件夹丢失的提示，提供重新选择和自动恢复两种处理方式
+func (j *job) showSourceLostDialog(root string) {
+	autoResume := widget.NewCheck(i18n.T("路径恢复后自动继续监控"), nil)
+	autoResume.SetChecked(true)
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(i18n.T("监控的源文件夹已被删除、重命名或卸载"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel(root),
+		widget.NewLabel(i18n.T("监控已停止，在处理之前不会再进行自动备份。")),
+		autoResume,
+	)
+
+	lostDialog := dialog.NewCustom(i18n.T("源文件夹不可用"), i18n.T("确定"), content, j.app.window)
+
+	repointBtn := widget.NewButtonWithIcon(i18n.T("重新选择源文件夹"), customFolderIcon, func() {
+		lostDialog.Hide()
+		j.app.showFolderDialog(i18n.T("选择源文件夹"), func(path string) {
+			if path == "" {
+				return
+			}
+			j.setSourcePath(path)
+			if err := j.startWatching(); err != nil {
+				dialog.ShowError(err, j.app.window)
+				return
+			}
+			j.setWatchButton(true)
+		})
+	})
+	repointBtn.Importance = widget.HighImportance
+
+	okBtn := widget.NewButton(i18n.T("确定"), func() {
+		lostDialog.Hide()
+		if autoResume.Checked {
+			go j.waitForSource(root)
+		}
+	})
+
+	lostDialog.SetButtons([]fyne.CanvasObject{okBtn, repointBtn})
+	lostDialog.Show()
+}
+
+// 等待源文件夹重新出现后自动恢复监控
+func (j *job) waitForSource(root string) {
+	j.status(i18n.Sprintf("等待源文件夹恢复: %s", root))
+
+	ticker := time.NewTicker(sourceCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// 用户已切换源文件夹或手动开始了监控，不再等待
+		if filepath.Clean(j.engine.SourcePath()) != root || j.config.IsWatching {
+			return
+		}
+		if !watcher.Available(root) {
+			continue
+		}
+		if err := j.startWatching(); err != nil {
+			j.status(i18n.T("自动恢复监控失败: ") + err.Error())
+			return
+		}
+		j.setWatchButton(true)
+		j.status(i18n.T("源文件夹已恢复，继续监控"))
+		return
+	}
+}
+
+// 根据索引刷新源文件夹的文件数量和大小显示
+func (b *BackupApp) refreshSourceStats() {
+	if b.sourceStats == nil {
+		return
+	}
+	idx, err := b.engine.SourceIndex()
+	if err != nil {
+		b.sourceStats.SetText("")
+		return
+	}
+	if idx.Empty() {
+		b.sourceStats.SetText(i18n.T("正在建立索引..."))
+		if err := idx.Rebuild(); err != nil {
+			b.sourceStats.SetText(err.Error())
+			return
+		}
+		if err := idx.Save(); err != nil {
+			slog.Warn("保存索引失败", "err", err)
+		}
+	}
+	fileCount, totalSize := idx.Stats()
+	b.sourceStats.SetText(i18n.Sprintf("文件数: %d  总大小: %.2f MB  (索引更新于 %s)",
+		fileCount,
+		float64(totalSize)/(1024*1024),
+		idx.Updated().Format("2006-01-02 15:04:05"),
+	))
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+// 单独的文件：只备份源文件夹中的几个文件，例如密码数据库或游戏存档。
+// 监控时只监控这些文件所在的目录，其他文件的变化不会触发备份
+func (b *BackupApp) showSourceFilesDialog() {
+	var list *widget.List
+	list = widget.NewList(
+		func() int { return len(b.config.SourceFiles) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil,
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+				widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(b.config.SourceFiles[id])
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				b.config.SourceFiles = append(b.config.SourceFiles[:id], b.config.SourceFiles[id+1:]...)
+				list.Refresh()
+			}
+		},
+	)
+
+	addBtn := widget.NewButtonWithIcon(i18n.T("添加文件"), theme.ContentAddIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			reader.Close()
+			if err := b.config.AddSourceFile(reader.URI().Path()); err != nil {
+				dialog.ShowError(err, b.window)
+				return
+			}
+			list.Refresh()
+		}, b.window)
+	})
+
+	content := container.NewBorder(
+		widget.NewLabel(i18n.T("只备份源文件夹中的这些文件，列表为空时备份整个文件夹。\n其他文件夹中的文件请新建一个任务。")),
+		container.NewHBox(addBtn),
+		nil, nil,
+		list,
+	)
+
+	filesDialog := dialog.NewCustom(i18n.T("单独的文件"), i18n.T("关闭"), content, b.window)
+	filesDialog.SetOnClosed(func() {
+		// 还没有源文件夹时添加的第一个文件决定源文件夹，同时刷新显示和统计
+		if b.config.SourcePath != "" {
+			b.setSourcePath(b.config.SourcePath)
+		}
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		// 监控按新的文件列表重新添加目录
+		if b.watcher != nil {
+			b.stopWatching()
+			if err := b.startWatching(); err != nil {
+				b.updateStatus(i18n.T("重新开始监控失败: ") + err.Error())
+				b.setWatchButton(false)
+			}
+		}
+	})
+	filesDialog.Resize(fyne.NewSize(520, 400))
+	filesDialog.Show()
+}
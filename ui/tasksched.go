@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 计划任务运行频率的显示名称
+var taskFrequencies = []struct{ value, label string }{
+	{engine.TaskDaily, "每天"},
+	{engine.TaskHourly, "每小时"},
+	{engine.TaskLogon, "登录时"},
+}
+
+// 在 Windows 任务计划程序中注册当前任务，程序没有打开时也按计划执行备份
+func (b *BackupApp) showTaskDialog() {
+	labels := make([]string, len(taskFrequencies))
+	for i, f := range taskFrequencies {
+		labels[i] = i18n.T(f.label)
+	}
+	timeEntry := widget.NewEntry()
+	timeEntry.SetPlaceHolder("02:00")
+	timeEntry.SetText(b.config.TaskTime)
+	frequencySelect := widget.NewSelect(labels, func(selected string) {
+		if selected == labels[0] {
+			timeEntry.Enable()
+		} else {
+			timeEntry.Disable()
+		}
+	})
+	frequencySelect.SetSelected(labels[0])
+	for _, f := range taskFrequencies {
+		if f.value == b.config.TaskFrequency {
+			frequencySelect.SetSelected(i18n.T(f.label))
+		}
+	}
+
+	statusLabel := widget.NewLabel(i18n.T("正在查询..."))
+	refreshStatus := func() {
+		go func() {
+			status, err := engine.QueryTask(b.config)
+			if err != nil {
+				statusLabel.SetText(err.Error())
+				return
+			}
+			statusLabel.SetText(status.String())
+		}()
+	}
+	refreshStatus()
+
+	config := b.config
+	unregisterBtn := widget.NewButton(i18n.T("取消注册"), func() {
+		if err := engine.UnregisterTask(config); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		config.TaskFrequency, config.TaskTime = "", ""
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+		}
+		refreshStatus()
+		b.updateStatus(i18n.T("已从任务计划程序中删除"))
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("运行频率"), Widget: frequencySelect},
+		{Text: i18n.T("运行时间"), Widget: timeEntry, HintText: i18n.T("每天运行的时间，格式为 HH:MM")},
+		{Text: i18n.T("状态"), Widget: statusLabel},
+		{Text: "", Widget: unregisterBtn},
+	}
+	dialog.ShowForm(i18n.T("任务计划程序"), i18n.T("注册"), i18n.T("关闭"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		frequency := taskFrequencies[frequencySelect.SelectedIndex()].value
+		at := strings.TrimSpace(timeEntry.Text)
+		if at == "" {
+			at = "02:00"
+		}
+		if err := engine.RegisterTask(config, frequency, at); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		config.TaskFrequency, config.TaskTime = frequency, at
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.Sprintf("已注册到任务计划程序：%s", frequencySelect.Selected))
+	}, b.window)
+}
+
+// 启动时检查已注册的计划任务，上次运行失败时在状态栏提示
+func (j *job) checkScheduledTask() {
+	if !engine.TaskSchedulerSupported || j.config.TaskFrequency == "" {
+		return
+	}
+	status, err := engine.QueryTask(j.config)
+	switch {
+	case err != nil:
+		j.status(err.Error())
+	case !status.Registered:
+		j.status(i18n.T("任务计划程序中的备份任务已被删除"))
+	case !status.LastRun.IsZero() && status.LastResult != 0:
+		j.status(i18n.T("计划任务") + status.String())
+	}
+}
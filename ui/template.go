@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 源文件夹显示文本，使用模板时同时显示展开后的路径
+func (b *BackupApp) sourceDisplay() string {
+	display := b.config.SourcePath
+	if engine.HasPathTemplate(b.config.SourcePath) {
+		display = i18n.Sprintf("%s\n当前: %s", b.config.SourcePath, b.engine.SourcePath())
+	}
+	if len(b.config.SourceFiles) > 0 {
+		display += i18n.Sprintf("\n只备份其中 %d 个文件", len(b.config.SourceFiles))
+	}
+	return display
+}
+
+// 显示源路径模板编辑对话框
+func (b *BackupApp) showSourceTemplateDialog() {
+	pathEntry := widget.NewEntry()
+	pathEntry.SetPlaceHolder("/var/log/app/{yyyy-MM}")
+	pathEntry.SetText(b.config.SourcePath)
+
+	preview := widget.NewLabel("")
+	updatePreview := func(path string) {
+		preview.SetText(i18n.T("当前展开为: ") + engine.ExpandPathTemplate(path, time.Now()))
+	}
+	pathEntry.OnChanged = updatePreview
+	updatePreview(pathEntry.Text)
+
+	help := widget.NewLabel(i18n.T("可以在路径中使用日期占位符，每次备份时按当前时间展开：\n") +
+		i18n.T("{yyyy} 年  {yy} 两位年  {MM} 月  {dd} 日  {HH} 时\n") +
+		i18n.T("也可以组合使用，例如 {yyyy-MM} 或 {yyyy}/{MM}"))
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("源路径"), Widget: pathEntry},
+		{Text: "", Widget: preview},
+		{Text: "", Widget: help},
+	}
+	dialog.ShowForm(i18n.T("源路径模板"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		path := strings.TrimSpace(pathEntry.Text)
+		if path == "" {
+			dialog.ShowError(i18n.Errorf("源路径不能为空"), b.window)
+			return
+		}
+		b.setSourcePath(path)
+	}, b.window)
+}
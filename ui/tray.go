@@ -0,0 +1,280 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 托盘图标的状态
+type trayIconState int
+
+const (
+	trayIdle    trayIconState = iota // 空闲
+	trayRunning                      // 有任务正在备份
+	trayError                        // 有任务最近一次备份失败
+)
+
+// 托盘图标按状态着色，颜色与主题和结果徽章一致
+var trayIconColors = map[trayIconState]color.NRGBA{
+	trayIdle:    {R: 44, G: 193, B: 219, A: 255},
+	trayRunning: {R: 255, G: 160, B: 0, A: 255},
+	trayError:   {R: 180, G: 0, B: 0, A: 255},
+}
+
+// 系统托盘：关闭窗口后程序留在托盘中继续监控和备份
+type trayState struct {
+	desk     desktop.App
+	mutex    sync.Mutex
+	running  map[*job]bool // 正在备份的任务
+	failed   map[*job]bool // 最近一次备份失败的任务
+	hintSent bool          // 是否已提示过程序在后台运行
+	icons    map[trayIconState]fyne.Resource
+}
+
+// 平台支持系统托盘时创建托盘菜单和图标，关闭窗口改为隐藏到托盘
+func (b *BackupApp) setupTray() {
+	desk, ok := fyne.CurrentApp().(desktop.App)
+	if !ok {
+		b.window.SetCloseIntercept(b.quit)
+		return
+	}
+	b.tray = &trayState{
+		desk:    desk,
+		running: make(map[*job]bool),
+		failed:  make(map[*job]bool),
+		icons:   make(map[trayIconState]fyne.Resource),
+	}
+	b.window.SetCloseIntercept(b.hideToTray)
+	b.refreshTray()
+}
+
+// 隐藏主窗口，第一次隐藏时提示程序仍在后台运行
+func (b *BackupApp) hideToTray() {
+	b.window.Hide()
+	b.tray.mutex.Lock()
+	hint := !b.tray.hintSent
+	b.tray.hintSent = true
+	b.tray.mutex.Unlock()
+	if hint {
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("SyncSafe", i18n.T("程序仍在后台运行，可以从系统托盘打开或退出")))
+	}
+}
+
+// 记录任务开始备份，托盘图标切换为备份中
+func (b *BackupApp) trayBackupStarted(j *job) {
+	if b.tray == nil {
+		return
+	}
+	b.tray.mutex.Lock()
+	b.tray.running[j] = true
+	b.tray.mutex.Unlock()
+	b.refreshTray()
+}
+
+// 记录任务备份结束，err 为需要提示的错误，有错误时托盘图标切换为出错
+func (b *BackupApp) trayBackupFinished(j *job, err error) {
+	if b.tray == nil {
+		return
+	}
+	b.tray.mutex.Lock()
+	delete(b.tray.running, j)
+	if err != nil {
+		b.tray.failed[j] = true
+	} else {
+		delete(b.tray.failed, j)
+	}
+	b.tray.mutex.Unlock()
+	b.refreshTray()
+}
+
+// 删除任务后清除它在托盘中的状态和暂停记录
+func (b *BackupApp) trayRemoveJob(j *job) {
+	b.pauseMutex.Lock()
+	for i, other := range b.pausedJobs {
+		if other == j {
+			b.pausedJobs = append(b.pausedJobs[:i], b.pausedJobs[i+1:]...)
+			break
+		}
+	}
+	b.pauseMutex.Unlock()
+	if b.tray == nil {
+		return
+	}
+	b.tray.mutex.Lock()
+	delete(b.tray.running, j)
+	delete(b.tray.failed, j)
+	b.tray.mutex.Unlock()
+	b.refreshTray()
+}
+
+// 按当前状态重新生成托盘菜单和图标
+func (b *BackupApp) refreshTray() {
+	tray := b.tray
+	if tray == nil {
+		return
+	}
+	tray.mutex.Lock()
+	state := trayIdle
+	switch {
+	case len(tray.running) > 0:
+		state = trayRunning
+	case len(tray.failed) > 0:
+		state = trayError
+	}
+	tray.mutex.Unlock()
+
+	status := fyne.NewMenuItem(b.trayStatus(state), nil)
+	status.Disabled = true
+	last := fyne.NewMenuItem(b.lastBackupText(), nil)
+	last.Disabled = true
+	backup := fyne.NewMenuItem(i18n.T("立即备份"), b.backupAll)
+	backup.Disabled = state == trayRunning
+	watch := fyne.NewMenuItem(i18n.T("暂停监控"), b.pauseWatching)
+	if b.watchPaused() {
+		watch = fyne.NewMenuItem(i18n.T("恢复监控"), b.resumeWatching)
+	} else {
+		watch.Disabled = !b.anyWatching()
+	}
+	quit := fyne.NewMenuItem(i18n.T("退出"), b.quit)
+	quit.IsQuit = true
+
+	tray.desk.SetSystemTrayMenu(fyne.NewMenu("SyncSafe",
+		status,
+		last,
+		fyne.NewMenuItemSeparator(),
+		backup,
+		watch,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem(i18n.T("显示窗口"), func() {
+			b.window.Show()
+			b.window.RequestFocus()
+		}),
+		quit,
+	))
+	tray.desk.SetSystemTrayIcon(tray.icon(state))
+}
+
+// 托盘菜单第一行显示的状态
+func (b *BackupApp) trayStatus(state trayIconState) string {
+	switch state {
+	case trayRunning:
+		return i18n.T("正在备份...")
+	case trayError:
+		return i18n.T("最近的备份失败")
+	}
+	return i18n.T("空闲")
+}
+
+// 所有任务中最近一次备份的时间
+func (b *BackupApp) lastBackupText() string {
+	var latest history.Record
+	var loc *time.Location
+	found := false
+	for _, j := range b.jobs {
+		record, ok := history.Latest(j.config.History, j.config.SourcePath)
+		if ok && (!found || record.Timestamp.After(latest.Timestamp)) {
+			latest, loc, found = record, j.config.Location(), true
+		}
+	}
+	if !found {
+		return i18n.T("尚未备份")
+	}
+	return i18n.Sprintf("上次备份: %s", latest.Timestamp.In(loc).Format("2006-01-02 15:04"))
+}
+
+// 备份所有已设置源文件夹和目标文件夹的任务
+func (b *BackupApp) backupAll() {
+	for _, j := range b.jobs {
+		if j.config.SourcePath != "" && j.config.DestinationPath != "" {
+			go j.performBackup()
+		}
+	}
+}
+
+// 是否有任务正在监控
+func (b *BackupApp) anyWatching() bool {
+	for _, j := range b.jobs {
+		if j.watcher != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否通过托盘或快捷操作暂停了监控
+func (b *BackupApp) watchPaused() bool {
+	b.pauseMutex.Lock()
+	defer b.pauseMutex.Unlock()
+	return len(b.pausedJobs) > 0
+}
+
+// 暂停所有任务的监控，恢复时只重新开始这些任务的监控
+func (b *BackupApp) pauseWatching() {
+	var paused []*job
+	for _, j := range b.jobs {
+		if j.watcher != nil {
+			j.stopWatching()
+			j.setWatchButton(false)
+			paused = append(paused, j)
+		}
+	}
+	b.pauseMutex.Lock()
+	b.pausedJobs = paused
+	b.pauseMutex.Unlock()
+	b.updateStatus(i18n.T("已暂停监控"))
+	b.refreshTray()
+}
+
+func (b *BackupApp) resumeWatching() {
+	b.pauseMutex.Lock()
+	paused := b.pausedJobs
+	b.pausedJobs = nil
+	b.pauseMutex.Unlock()
+	for _, j := range paused {
+		if j.watcher != nil {
+			continue
+		}
+		if err := j.startWatching(); err != nil {
+			j.status(i18n.T("恢复监控失败: ") + err.Error())
+			continue
+		}
+		j.setWatchButton(true)
+	}
+	b.refreshTray()
+}
+
+// 状态对应的托盘图标：一个实心圆点。托盘在部分平台上不支持 SVG，这里生成 PNG
+func (t *trayState) icon(state trayIconState) fyne.Resource {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if res, ok := t.icons[state]; ok {
+		return res
+	}
+	const size = 32
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	c := trayIconColors[state]
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)-size/2+0.5, float64(y)-size/2+0.5
+			if dx*dx+dy*dy <= (size/2-2)*(size/2-2) {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	res := fyne.NewStaticResource(fmt.Sprintf("tray-%d.png", state), buf.Bytes())
+	t.icons[state] = res
+	return res
+}
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/engine"
+	"syncsafe/i18n"
+)
+
+// 冲突处理方式在界面中的名称
+var conflictPolicyOrder = []string{engine.ConflictManual, engine.ConflictNewest, engine.ConflictBoth}
+
+func conflictPolicyLabel(policy string) string {
+	switch policy {
+	case engine.ConflictNewest:
+		return i18n.T("保留较新的版本")
+	case engine.ConflictBoth:
+		return i18n.T("两个版本都保留")
+	}
+	return i18n.T("手动选择")
+}
+
+// 双向同步设置：另一侧的文件夹和冲突的处理方式
+func (b *BackupApp) showTwoWayDialog() {
+	pathEntry := widget.NewEntry()
+	pathEntry.SetPlaceHolder(i18n.T("另一台电脑也在同步的文件夹，例如 NAS 上的共享文件夹"))
+	pathEntry.SetText(b.config.SyncPath)
+	browseBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err == nil && uri != nil {
+				pathEntry.SetText(uri.Path())
+			}
+		}, b.window)
+	})
+
+	options := make([]string, len(conflictPolicyOrder))
+	for i, policy := range conflictPolicyOrder {
+		options[i] = conflictPolicyLabel(policy)
+	}
+	policySelect := widget.NewSelect(options, nil)
+	policySelect.SetSelected(conflictPolicyLabel(b.config.SyncConflicts))
+
+	conflictsBtn := widget.NewButtonWithIcon(i18n.Sprintf("处理冲突（%d）", len(b.engine.SyncConflicts())), theme.WarningIcon(), func() {
+		b.showSyncConflictsDialog()
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("同步文件夹"), Widget: container.NewBorder(nil, nil, nil, browseBtn, pathEntry),
+			HintText: i18n.T("每次备份前与源文件夹互相同步，两侧的新增、修改和删除都会传到另一侧；为空表示不同步")},
+		{Text: i18n.T("两侧都修改时"), Widget: policySelect,
+			HintText: i18n.T("两个都保留时，同步文件夹中的版本改名为「冲突副本」")},
+		{Text: "", Widget: conflictsBtn},
+	}
+	dialog.ShowForm(i18n.T("双向同步"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		path := strings.TrimSpace(pathEntry.Text)
+		if path != "" && path == b.config.SourcePath {
+			dialog.ShowError(i18n.Errorf("同步文件夹不能是源文件夹本身"), b.window)
+			return
+		}
+		b.config.SyncPath = path
+		for _, policy := range conflictPolicyOrder {
+			if conflictPolicyLabel(policy) == policySelect.Selected {
+				b.config.SyncConflicts = policy
+			}
+		}
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("双向同步设置已保存，下次备份生效"))
+	}, b.window)
+}
+
+// 逐个处理上次同步留下的冲突：保留本机、保留同步文件夹或两个都保留。
+// 处理在任务空闲时进行，不会与进行中的备份同时修改文件
+func (b *BackupApp) showSyncConflictsDialog() {
+	j := b.job
+	conflicts := j.engine.SyncConflicts()
+	if len(conflicts) == 0 {
+		dialog.ShowInformation(i18n.T("双向同步"), i18n.T("没有等待处理的冲突"), b.window)
+		return
+	}
+
+	var list *widget.List
+	resolve := func(relPath, choice string) {
+		go j.do(func() {
+			if err := j.engine.ResolveSyncConflict(relPath, choice); err != nil {
+				dialog.ShowError(i18n.Errorf("处理冲突失败: %v", err), b.window)
+				return
+			}
+			conflicts = j.engine.SyncConflicts()
+			list.Refresh()
+			j.status(i18n.Sprintf("已处理冲突 %s，还有 %d 个", relPath, len(conflicts)))
+		})
+	}
+
+	list = widget.NewList(
+		func() int { return len(conflicts) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(
+					widget.NewButton(i18n.T("保留本机"), nil),
+					widget.NewButton(i18n.T("保留同步文件夹"), nil),
+					widget.NewButton(i18n.T("都保留"), nil),
+				),
+				widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			conflict := conflicts[id]
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(i18n.Sprintf("%s\n本机: %s，%s\n同步文件夹: %s，%s",
+				conflict.RelPath,
+				formatBytes(conflict.Local.Size), conflict.Local.ModTime.Format("2006-01-02 15:04:05"),
+				formatBytes(conflict.Remote.Size), conflict.Remote.ModTime.Format("2006-01-02 15:04:05")))
+			buttons := row.Objects[1].(*fyne.Container).Objects
+			for i, choice := range []string{engine.ResolveLocal, engine.ResolveRemote, engine.ResolveBoth} {
+				choice := choice
+				buttons[i].(*widget.Button).OnTapped = func() { resolve(conflict.RelPath, choice) }
+			}
+		},
+	)
+
+	newestBtn := widget.NewButtonWithIcon(i18n.T("全部保留较新的版本"), theme.HistoryIcon(), func() {
+		pending := append([]engine.SyncConflict(nil), conflicts...)
+		for _, conflict := range pending {
+			choice := engine.ResolveRemote
+			if conflict.Local.ModTime.After(conflict.Remote.ModTime) {
+				choice = engine.ResolveLocal
+			}
+			resolve(conflict.RelPath, choice)
+		}
+	})
+
+	content := container.NewBorder(
+		widget.NewLabel(i18n.T("以下文件在本机和同步文件夹中都被修改过，处理之前两侧保持不变：")),
+		container.NewHBox(newestBtn),
+		nil, nil,
+		list,
+	)
+	conflictsDialog := dialog.NewCustom(i18n.T("处理同步冲突"), i18n.T("关闭"), content, b.window)
+	conflictsDialog.Resize(fyne.NewSize(720, 480))
+	conflictsDialog.Show()
+}
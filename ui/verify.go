@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/history"
+	"syncsafe/i18n"
+)
+
+// 校验快照能否完整读出，加密的快照完整解密一遍，不写入任何文件
+func (b *BackupApp) verifySnapshotDialog(record history.Record) {
+	progressDialog := dialog.NewCustomWithoutButtons(i18n.T("校验快照"), widget.NewProgressBarInfinite(), b.window)
+	progressDialog.Resize(fyne.NewSize(400, 100))
+	progressDialog.Show()
+
+	go func() {
+		result, err := b.engine.VerifySnapshot(record)
+		progressDialog.Hide()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.setRecordVerified(record, result.OK())
+		if result.OK() {
+			dialog.ShowInformation(i18n.T("校验快照"), result.String(), b.window)
+			return
+		}
+		lines := append([]string(nil), result.Damaged...)
+		for _, relPath := range result.Missing {
+			lines = append(lines, relPath+i18n.T(": 缺失"))
+		}
+		details := widget.NewLabel(strings.Join(lines, "\n"))
+		content := container.NewBorder(widget.NewLabel(result.String()), nil, nil, nil, container.NewVScroll(details))
+		resultDialog := dialog.NewCustom(i18n.T("校验快照"), i18n.T("关闭"), content, b.window)
+		resultDialog.Resize(fyne.NewSize(600, 400))
+		resultDialog.Show()
+	}()
+}
+
+// 记录手动校验的结果。校验通过的快照可以作为清理旧快照的依据，之前推迟的清理随即执行
+func (b *BackupApp) setRecordVerified(record history.Record, ok bool) {
+	var at time.Time
+	if ok {
+		at = time.Now()
+	}
+	j := b.job
+	j.do(func() {
+		if !history.SetVerified(j.config.History, record, ok, at) {
+			return
+		}
+		if err := j.config.Save(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if j.current() {
+			b.refreshHistoryView()
+		}
+		if ok && j.config.Retention.VerifyBeforePrune {
+			j.applyRetention()
+		}
+	})
+}
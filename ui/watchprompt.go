@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+)
+
+const (
+	watchPromptDelay = 10 * time.Second // 提示显示多久后开始备份
+	watchPromptFiles = 8                // 提示中最多列出的文件数
+	maxPendingPaths  = 1000             // 最多记录的变化路径，超过后只统计数量
+)
+
+// 监控到、还没有备份的变化
+type pendingChanges struct {
+	mu    sync.Mutex
+	paths map[string]bool
+	more  int  // 超过 maxPendingPaths 后未记录的变化数
+	storm bool // 发生过事件风暴，变化的文件不完整
+}
+
+func (c *pendingChanges) add(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paths == nil {
+		c.paths = make(map[string]bool)
+	}
+	if c.paths[path] {
+		return
+	}
+	if len(c.paths) >= maxPendingPaths {
+		c.more++
+		return
+	}
+	c.paths[path] = true
+}
+
+func (c *pendingChanges) markStorm() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storm = true
+}
+
+// 取出记录的变化并清空
+func (c *pendingChanges) take() (paths []string, more int, storm bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	more, storm = c.more, c.storm
+	c.paths, c.more, c.storm = nil, 0, false
+	return paths, more, storm
+}
+
+// 显示中的变化提示
+type watchPrompt struct {
+	popup *widget.PopUp
+	paths []string // 提示中的变化，被新的提示替换时合并过去
+	done  chan struct{}
+	once  sync.Once
+}
+
+// 关闭提示，返回是否由本次调用关闭
+func (p *watchPrompt) dismiss() bool {
+	dismissed := false
+	p.once.Do(func() {
+		close(p.done)
+		p.popup.Hide()
+		dismissed = true
+	})
+	return dismissed
+}
+
+// 监控的变化平静后备份；开启了备份前提示时先在窗口角落列出变化的文件，
+// 10 秒内可以跳过本次备份，例如变化只是临时文件
+func (j *job) settled() {
+	if !j.config.ConfirmWatchBackup {
+		j.changes.take()
+		j.requestBackup(backupRequest{trigger: triggerWatch, attempt: 1})
+		return
+	}
+
+	// 上一个提示还没有结束时合并到新的提示中
+	j.promptMutex.Lock()
+	previous := j.prompt
+	j.prompt = nil
+	j.promptMutex.Unlock()
+	if previous != nil && previous.dismiss() {
+		for _, path := range previous.paths {
+			j.changes.add(path)
+		}
+	}
+	j.showWatchPrompt(j.changes.take())
+}
+
+// 在窗口右下角显示变化的文件和倒计时
+func (j *job) showWatchPrompt(paths []string, more int, storm bool) {
+	root := j.engine.SourcePath()
+	var lines []string
+	for i, path := range paths {
+		if i == watchPromptFiles {
+			break
+		}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = rel
+		}
+		lines = append(lines, path)
+	}
+	total := len(paths) + more
+	if total > len(lines) {
+		lines = append(lines, i18n.Sprintf("……等 %d 个文件", total))
+	}
+	if storm {
+		lines = append(lines, i18n.T("检测到大量文件变化，列表不完整"))
+	}
+
+	countdown := widget.NewLabel("")
+	prompt := &watchPrompt{done: make(chan struct{}), paths: paths}
+	skipBtn := widget.NewButtonWithIcon(i18n.T("跳过本次"), theme.CancelIcon(), func() {
+		if prompt.dismiss() {
+			j.clearPrompt(prompt)
+			j.status(i18n.T("已跳过本次监控触发的备份"))
+		}
+	})
+	nowBtn := widget.NewButtonWithIcon(i18n.T("立即备份"), theme.ConfirmIcon(), func() {
+		if prompt.dismiss() {
+			j.clearPrompt(prompt)
+			j.requestBackup(backupRequest{trigger: triggerWatch, attempt: 1})
+		}
+	})
+	nowBtn.Importance = widget.HighImportance
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(i18n.Sprintf("[%s] %d 个文件有变化", j.config.ProfileName(), total),
+			fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel(strings.Join(lines, "\n")),
+		container.NewHBox(countdown, skipBtn, nowBtn),
+	)
+	canvas := j.app.window.Canvas()
+	prompt.popup = widget.NewPopUp(content, canvas)
+	size := prompt.popup.MinSize()
+	padding := theme.Padding() * 4
+	prompt.popup.ShowAtPosition(fyne.NewPos(
+		canvas.Size().Width-size.Width-padding, canvas.Size().Height-size.Height-padding))
+
+	j.promptMutex.Lock()
+	j.prompt = prompt
+	j.promptMutex.Unlock()
+
+	go func() {
+		deadline := time.Now().Add(watchPromptDelay)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			remaining := time.Until(deadline).Round(time.Second)
+			if remaining <= 0 {
+				break
+			}
+			countdown.SetText(i18n.Sprintf("%d 秒后备份", int(remaining.Seconds())))
+			select {
+			case <-prompt.done:
+				return
+			case <-ticker.C:
+			}
+		}
+		if prompt.dismiss() {
+			j.clearPrompt(prompt)
+			j.requestBackup(backupRequest{trigger: triggerWatch, attempt: 1})
+		}
+	}()
+}
+
+// 提示结束后不再作为当前提示
+func (j *job) clearPrompt(prompt *watchPrompt) {
+	j.promptMutex.Lock()
+	defer j.promptMutex.Unlock()
+	if j.prompt == prompt {
+		j.prompt = nil
+	}
+}
+
+// 停止监控时关闭提示，不再备份
+func (j *job) dismissPrompt() {
+	j.promptMutex.Lock()
+	prompt := j.prompt
+	j.prompt = nil
+	j.promptMutex.Unlock()
+	if prompt != nil {
+		prompt.dismiss()
+	}
+}
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"syncsafe/i18n"
+	"syncsafe/storage"
+)
+
+// 把备份目标设为 WebDAV（Nextcloud、ownCloud 等），保存前可以测试连接
+func (b *BackupApp) showWebDAVDialog() {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder(i18n.T("https://cloud.example.com/remote.php/dav/files/用户名/Backups"))
+	if storage.IsWebDAV(b.config.DestinationPath) {
+		urlEntry.SetText(b.config.DestinationPath)
+	}
+	userEntry := widget.NewEntry()
+	userEntry.SetText(b.config.WebDAV.Username)
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetText(b.config.WebDAV.Password)
+
+	settings := func() (string, storage.WebDAVConfig, error) {
+		address := strings.TrimSpace(urlEntry.Text)
+		if !storage.IsWebDAV(address) {
+			return "", storage.WebDAVConfig{}, i18n.Errorf("请填写以 http:// 或 https:// 开头的 WebDAV 地址")
+		}
+		return address, storage.WebDAVConfig{Username: strings.TrimSpace(userEntry.Text), Password: passwordEntry.Text}, nil
+	}
+
+	testBtn := widget.NewButtonWithIcon(i18n.T("测试连接"), theme.ConfirmIcon(), func() {
+		address, config, err := settings()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.updateStatus(i18n.T("正在连接 WebDAV..."))
+		go func() {
+			if err := storage.NewWebDAV(address, config).Check(); err != nil {
+				dialog.ShowError(i18n.Errorf("连接 WebDAV 失败: %v", err), b.window)
+				b.updateStatus(i18n.T("连接 WebDAV 失败"))
+				return
+			}
+			dialog.ShowInformation("WebDAV", i18n.T("连接成功，备份目录已就绪"), b.window)
+			b.updateStatus(i18n.T("WebDAV 连接成功"))
+		}()
+	})
+
+	items := []*widget.FormItem{
+		{Text: i18n.T("地址"), Widget: urlEntry, HintText: i18n.T("备份目录的 WebDAV 地址，Nextcloud 可在“文件设置”中找到")},
+		{Text: i18n.T("用户名"), Widget: userEntry},
+		{Text: i18n.T("密码"), Widget: passwordEntry, HintText: i18n.T("建议使用应用专用密码，只保存在本机")},
+		{Text: "", Widget: testBtn},
+	}
+	dialog.ShowForm(i18n.T("备份到 WebDAV"), i18n.T("保存"), i18n.T("取消"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		address, config, err := settings()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.DestinationPath = address
+		b.config.WebDAV = config
+		if err := b.saveConfig(); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.destLabel.SetText(address)
+		b.destFolder.SetText(address)
+		b.updateStatus(i18n.T("已选择 WebDAV 备份目标: ") + address)
+	}, b.window)
+}
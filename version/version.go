@@ -0,0 +1,93 @@
+// Package version 是程序版本信息的唯一来源。发布时通过
+// -ldflags "-X syncsafe/version.Version=1.2.0 -X syncsafe/version.Commit=<提交> -X syncsafe/version.Date=<构建时间>"
+// 设置，没有设置的项从 Go 写入程序的构建信息（模块版本和 VCS 信息）中读取。
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// 构建时设置的版本信息
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Go 生成的伪版本中的时间戳和提交，例如 v0.0.0-20240501100000-3f2a9c1d0b4e
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}$`)
+
+// 程序的版本和构建环境
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // 构建时工作区有未提交的修改
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// 当前程序的版本信息
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	// 只采用标签版本。开发时 Go 按提交生成的伪版本每次提交都不同，会被当作升级
+	if info.Version == "dev" && strings.HasPrefix(build.Main.Version, "v") && !strings.Contains(build.Main.Version, "+") &&
+		!pseudoVersion.MatchString(build.Main.Version) {
+		info.Version = strings.TrimPrefix(build.Main.Version, "v")
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// 提交的简短形式
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+// 一行的版本说明，例如 1.2.0（3f2a9c1d0b4e，2024-05-01T10:00:00Z）
+func (i Info) String() string {
+	var details []string
+	if commit := i.ShortCommit(); commit != "" {
+		if i.Modified {
+			commit += "（有未提交的修改）"
+		}
+		details = append(details, commit)
+	}
+	if i.Date != "" {
+		details = append(details, i.Date)
+	}
+	if len(details) == 0 {
+		return i.Version
+	}
+	return fmt.Sprintf("%s（%s）", i.Version, strings.Join(details, "，"))
+}
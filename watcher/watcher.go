@@ -0,0 +1,391 @@
+// Package watcher 递归监控源文件夹的变化，对连续的变化做防抖处理，
+// 并在源文件夹被删除、重命名或卸载时发出通知。
+//
+// fsnotify 只监控单个目录，监控开始后新建的子目录在创建时加入监控，
+// 删除或重命名的目录连同其下的子目录移出监控。
+//
+// 防抖时间随变化的频繁程度调整：偶尔的修改在 Debounce 后很快备份，持续写入的文件夹
+// （渲染、构建）每多一秒有变化就延长等待，直到变化平息，最长 MaxDebounce。
+//
+// 事件频率超过 StormThreshold（例如构建过程短时间内生成大量文件）时进入风暴状态：
+// 不再逐个处理事件，等变化平静 StormDelay 后只做一次完整的重新扫描。
+package watcher
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"syncsafe/i18n"
+)
+
+// 默认值
+const (
+	DefaultDebounce       = 2 * time.Second  // 没有持续写入时，最后一次变化后等待的时间
+	DefaultMaxDebounce    = time.Minute      // 持续写入时最长的等待时间
+	DefaultCheckInterval  = 10 * time.Second // 检查源文件夹是否仍然可用的间隔
+	DefaultStormThreshold = 1000             // 每秒超过该数量的事件视为事件风暴
+	DefaultStormDelay     = 30 * time.Second // 风暴中最后一个事件后等待的时间
+)
+
+// 监控选项，回调均在监控协程中调用，可以为 nil
+type Options struct {
+	Debounce       time.Duration
+	MaxDebounce    time.Duration
+	CheckInterval  time.Duration
+	StormThreshold int
+	StormDelay     time.Duration
+	// 每个写入、创建、删除或重命名事件
+	OnChange func(path string)
+	// 变化停止后（按变化的频繁程度等待 Debounce 到 MaxDebounce）调用一次
+	OnSettled func()
+	// 源文件夹被删除、重命名或卸载，之后监控自动关闭
+	OnLost func()
+	// 每次定期检查时调用，返回 false 时停止监控（例如需要切换到新的目录）
+	OnCheck func() bool
+	// 进入事件风暴，之后的事件不再调用 OnChange
+	OnStorm func()
+	// 风暴平静后调用一次，需要完整重新扫描源文件夹，随后调用 OnSettled
+	OnRescan func()
+	// 返回 true 的目录不添加监控，返回 true 的路径的事件被忽略
+	Ignore func(path string, isDir bool) bool
+}
+
+// 源文件夹监控
+type Watcher struct {
+	root  string
+	fs    *fsnotify.Watcher
+	opts  Options
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+	once  sync.Once
+	// 最近 activityWindow 内有变化的秒（Unix 时间），按时间顺序
+	active []int64
+	// 已添加监控的目录
+	dirs map[string]bool
+}
+
+// 统计变化频繁程度的时间范围
+const activityWindow = 30 * time.Second
+
+// 源文件夹是否存在且是目录
+func Available(root string) bool {
+	info, err := os.Stat(root)
+	return err == nil && info.IsDir()
+}
+
+// 开始递归监控 root 及其所有子目录（跳过 .git）
+func New(root string, opts Options) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.MaxDebounce < opts.Debounce {
+		opts.MaxDebounce = max(DefaultMaxDebounce, opts.Debounce)
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultCheckInterval
+	}
+	if opts.StormThreshold <= 0 {
+		opts.StormThreshold = DefaultStormThreshold
+	}
+	if opts.StormDelay <= 0 {
+		opts.StormDelay = DefaultStormDelay
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, i18n.Errorf("创建监控失败: %v", err)
+	}
+
+	w := &Watcher{
+		root: filepath.Clean(root),
+		fs:   fsWatcher,
+		opts: opts,
+		done: make(chan struct{}),
+		dirs: make(map[string]bool),
+	}
+	// 递归添加所有子目录
+	if err := w.addTree(w.root, nil); err != nil {
+		fsWatcher.Close()
+		return nil, i18n.Errorf("设置监控失败: %v", err)
+	}
+	go w.run()
+	return w, nil
+}
+
+// 添加 dir 及其所有子目录的监控（跳过 .git 和被排除的目录），
+// found 不为 nil 时对其下已有的每个文件和子目录调用
+func (w *Watcher) addTree(dir string, found func(path string)) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && found != nil {
+			if w.opts.Ignore != nil && w.opts.Ignore(path, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			found(path)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		// 跳过.git目录和被排除的目录
+		if filepath.Base(path) == ".git" {
+			return filepath.SkipDir
+		}
+		if w.opts.Ignore != nil && path != w.root && w.opts.Ignore(path, true) {
+			return filepath.SkipDir
+		}
+		if err := w.fs.Add(path); err != nil {
+			return i18n.Errorf("添加监控目录失败 %s: %v", path, err)
+		}
+		w.mu.Lock()
+		w.dirs[path] = true
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+// 移除 dir 及其所有子目录的监控，dir 不是监控的目录时什么都不做
+func (w *Watcher) removeTree(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// 子目录只在父目录监控时才会添加，dir 不在其中时也没有需要移除的子目录
+	if !w.dirs[dir] {
+		return
+	}
+	prefix := dir + string(filepath.Separator)
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			// 已删除的目录的监控已被系统移除，忽略错误
+			w.fs.Remove(path)
+			delete(w.dirs, path)
+		}
+	}
+}
+
+// 当前监控的所有目录，按路径排序
+func (w *Watcher) Dirs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dirs := make([]string, 0, len(w.dirs))
+	for path := range w.dirs {
+		dirs = append(dirs, path)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// 监控的目录
+func (w *Watcher) Root() string {
+	return w.root
+}
+
+// 停止监控并取消等待中的 OnSettled，可以在回调中调用
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		w.CancelPending()
+		err = w.fs.Close()
+	})
+	return err
+}
+
+// 在 delay 之后调用 OnSettled，替换之前等待中的调用
+func (w *Watcher) Schedule(delay time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	if w.opts.OnSettled != nil {
+		w.timer = time.AfterFunc(delay, w.opts.OnSettled)
+	}
+}
+
+// 取消等待中的 OnSettled，返回是否有被取消的调用
+func (w *Watcher) CancelPending() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer == nil {
+		return false
+	}
+	stopped := w.timer.Stop()
+	w.timer = nil
+	return stopped
+}
+
+// 记录一次变化，返回新的防抖时间
+func (w *Watcher) touch(now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	second := now.Unix()
+	if n := len(w.active); n == 0 || w.active[n-1] != second {
+		w.active = append(w.active, second)
+	}
+	return w.delayLocked(now)
+}
+
+// 当前的防抖时间：最近 30 秒内每有一秒发生过变化，等待时间增加 Debounce 的一半
+func (w *Watcher) Delay() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.delayLocked(time.Now())
+}
+
+func (w *Watcher) delayLocked(now time.Time) time.Duration {
+	oldest := now.Add(-activityWindow).Unix()
+	i := 0
+	for i < len(w.active) && w.active[i] <= oldest {
+		i++
+	}
+	w.active = w.active[i:]
+	delay := w.opts.Debounce + time.Duration(len(w.active))*w.opts.Debounce/2
+	return min(delay, w.opts.MaxDebounce)
+}
+
+// 源文件夹不可用：关闭监控并通知
+func (w *Watcher) lost() {
+	w.Close()
+	if w.opts.OnLost != nil {
+		w.opts.OnLost()
+	}
+}
+
+// 按事件更新监控的目录：新建的目录（包括移入的目录）递归加入监控，
+// 在加入监控之前就已写入其中的文件也报告为变化；删除或移出的目录移出监控
+func (w *Watcher) updateDirs(event fsnotify.Event, inStorm bool) {
+	path := filepath.Clean(event.Name)
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		w.removeTree(path)
+	}
+	if event.Op&fsnotify.Create == 0 {
+		return
+	}
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+	var found func(string)
+	if !inStorm && w.opts.OnChange != nil {
+		found = w.opts.OnChange
+	}
+	if err := w.addTree(path, found); err != nil {
+		// 目录在添加过程中又被删除时也会失败，其余的目录仍然监控
+		slog.Warn("添加新目录的监控失败", "path", path, "err", err)
+	}
+}
+
+// 事件的路径是否被排除，已删除的路径按文件判断
+func (w *Watcher) ignored(path string) bool {
+	if w.opts.Ignore == nil {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return w.opts.Ignore(path, err == nil && info.IsDir())
+}
+
+func (w *Watcher) run() {
+	// 定期检查源文件夹是否仍然存在（卸载时不一定会产生事件）
+	sourceCheck := time.NewTicker(w.opts.CheckInterval)
+	defer sourceCheck.Stop()
+
+	// 按秒统计事件数量，超过阈值时进入风暴状态
+	var windowStart, lastEvent time.Time
+	var windowCount int
+	inStorm := false
+	storm := time.NewTimer(w.opts.StormDelay)
+	storm.Stop()
+	defer storm.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			// 源文件夹本身被删除或重命名
+			if filepath.Clean(event.Name) == w.root &&
+				(event.Op&fsnotify.Remove == fsnotify.Remove || event.Op&fsnotify.Rename == fsnotify.Rename) {
+				w.lost()
+				return
+			}
+			if event.Op&fsnotify.Write == fsnotify.Write ||
+				event.Op&fsnotify.Create == fsnotify.Create ||
+				event.Op&fsnotify.Remove == fsnotify.Remove ||
+				event.Op&fsnotify.Rename == fsnotify.Rename {
+				if w.ignored(event.Name) {
+					continue
+				}
+				w.updateDirs(event, inStorm)
+				now := time.Now()
+				if now.Sub(windowStart) >= time.Second {
+					windowStart = now
+					windowCount = 0
+				}
+				windowCount++
+				lastEvent = now
+				if !inStorm && windowCount > w.opts.StormThreshold {
+					inStorm = true
+					w.CancelPending()
+					storm.Reset(w.opts.StormDelay)
+					slog.Warn("检测到事件风暴，平静后重新扫描", "threshold", w.opts.StormThreshold)
+					if w.opts.OnStorm != nil {
+						w.opts.OnStorm()
+					}
+				}
+				if inStorm {
+					continue
+				}
+				if w.opts.OnChange != nil {
+					w.opts.OnChange(event.Name)
+				}
+				// 防抖动：重新开始计时，变化越频繁等待越久
+				w.Schedule(w.touch(now))
+			}
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("监控错误", "err", err)
+		case <-storm.C:
+			// 风暴仍在继续时推迟到最后一个事件后 StormDelay
+			if quiet := time.Since(lastEvent); quiet < w.opts.StormDelay {
+				storm.Reset(w.opts.StormDelay - quiet)
+				continue
+			}
+			inStorm = false
+			if w.opts.OnRescan != nil {
+				w.opts.OnRescan()
+			}
+			w.Schedule(0)
+		case <-sourceCheck.C:
+			if w.opts.OnCheck != nil && !w.opts.OnCheck() {
+				w.Close()
+				return
+			}
+			if !Available(w.root) {
+				w.lost()
+				return
+			}
+		}
+	}
+}